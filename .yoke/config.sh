@@ -28,4 +28,4 @@ YOKE_REVIEWER_AGENT="codex"
 YOKE_REVIEW_CMD='codex exec --full-auto --cd "$ROOT_DIR" "You are the reviewer agent for issue $ISSUE_ID. Read .yoke/prompts/reviewer.md, inspect bd show $ISSUE_ID and local diffs/tests, then run yoke review $ISSUE_ID --approve if there are no blocking issues, otherwise run yoke review $ISSUE_ID --reject \"Blocking issue found\"."'
 
 # Pull request template path.
-YOKE_PR_TEMPLATE=".github/pull_request_template.md"
+YOKE_PR_TEMPLATE=".yoke/pr_template.md"
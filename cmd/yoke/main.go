@@ -4,31 +4,71 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
 	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/tabwriter"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
-	defaultBaseBranch = "main"
-	defaultCheckCmd   = ".yoke/checks.sh"
-	defaultPRTemplate = ".github/pull_request_template.md"
-	defaultBDPrefix   = "bd"
-	defaultDaemonPoll = 30 * time.Second
-	reviewQueueLabel  = "yoke:in_review"
-	daemonFocusFile   = "daemon-focus"
-	epicPassCount     = 5
-	minEpicPassCount  = 0
+	defaultBaseBranch       = "main"
+	defaultCheckCmd         = ".yoke/checks.sh"
+	defaultPRTemplate       = ".yoke/pr_template.md"
+	defaultBDPrefix         = "bd"
+	defaultIssueBackend     = "bd"
+	defaultJiraReviewStatus = "In Review"
+	defaultMerge            = "squash"
+	defaultSync             = "rebase"
+	defaultOverlap          = "warn"
+	defaultOutputFilters    = "strip-ansi,collapse-repeats,max-bytes=1048576"
+	defaultDaemonPoll       = 30 * time.Second
+	reviewQueueLabel        = "yoke:in_review"
+	budgetLabel             = "yoke:budget-exceeded"
+	reviewLockLabel         = "yoke:review-lock"
+	humanReviewLabel        = "yoke:review-me"
+	escalatedLabel          = "yoke:priority-escalated"
+	mergeQueuedLabel        = "yoke:merge-queued"
+	baseConflictLabel       = "yoke:base-conflict"
+	auditPendingLabel       = "yoke:audit-pending"
+	auditedLabel            = "yoke:audited"
+	securityBlockedLabel    = "yoke:security-blocked"
+	awaitingApprovalLabel   = "yoke:awaiting-approval"
+	needsHumanLabel         = "yoke:needs-human"
+	defaultMaxRejections    = 5
+	reviewLockTTL           = 2 * time.Hour
+	daemonFocusFile         = "daemon-focus"
+	clarificationWaitFile   = "clarification-wait.json"
+	defaultEpicPassPlan     = "writer,reviewer,writer,reviewer,writer"
+	allEpicPasses           = -1
 
 	epicImprovementCompleteLabel = "yoke:epic-improvement-complete"
 	epicImprovementRunningLabel  = "yoke:epic-improvement-running"
@@ -40,6 +80,12 @@ const (
 //go:embed prompts/epic-improvement-cycle.md
 var epicImprovementPromptTemplate string
 
+//go:embed prompts/security-review.md
+var securityReviewPromptTemplate string
+
+//go:embed web/dashboard.html
+var dashboardHTML string
+
 var (
 	assignPattern   = regexp.MustCompile(`^([A-Z0-9_]+)\s*=\s*(.+)$`)
 	anyIssuePattern = regexp.MustCompile(`[a-z0-9][a-z0-9._-]*-[a-z0-9]+(?:\.[a-z0-9]+)*`)
@@ -72,15 +118,72 @@ var supportedAgents = []agentSpec{
 }
 
 type config struct {
-	BaseBranch    string
-	CheckCmd      string
-	BDPrefix      string
-	WriterAgent   string
-	WriterCmd     string
-	ReviewerAgent string
-	ReviewCmd     string
-	PRTemplate    string
-	Path          string
+	BaseBranch         string
+	CheckCmd           string
+	Checks             []namedCheck
+	AffectedChecks     bool
+	CoverageCmd        string
+	CoverageMin        float64
+	BDPrefix           string
+	IssueBackend       string
+	JiraURL            string
+	JiraProject        string
+	JiraReviewStatus   string
+	WriterAgent        string
+	WriterCmd          string
+	ReviewerAgent      string
+	ReviewCmd          string
+	PRTemplate         string
+	BackupCmd          string
+	BackupKeep         int
+	MaxIssueCost       float64
+	EpicSizeWarning    int
+	MergeStrategy      string
+	SyncBase           bool
+	SyncStrategy       string
+	InheritPriority    bool
+	ClaimPolicy        []string
+	OverlapMode        string
+	MergeQueue         bool
+	SyncInterval       time.Duration
+	SubmitCommit       bool
+	CommitAgentBody    bool
+	CommitStyle        string
+	Signoff            string
+	AutoMerge          bool
+	SkipLabels         []string
+	OnlyLabels         []string
+	SkipTypes          []string
+	OnlyTypes          []string
+	SkipPaths          []string
+	OnlyPaths          []string
+	WaitCI             bool
+	ReviewSample       int
+	LowRiskLabels      []string
+	AuditModel         string
+	Actor              string
+	Assignee           string
+	LeaseTTL           time.Duration
+	EpicRetro          bool
+	EpicArchive        bool
+	EpicPassPlan       []epicPassStep
+	SecurityReview     bool
+	ApprovalPolicy     []approvalRule
+	RequireHumanFor    []string
+	MaxRejections      int
+	NotifyCmd          string
+	NotifyRoutes       []notifyRoute
+	SlackChannel       string
+	ReviewDiffMaxBytes int
+	ReviewDiffExclude  []string
+	OutputFilters      []outputFilter
+	Path               string
+	// Root is the repository root the config was loaded for.
+	Root string
+	// Workspace and WorkspaceRepo are set by yoke daemon/status --workspace:
+	// selection only picks issues routed to WorkspaceRepo.
+	Workspace     []workspaceRepo
+	WorkspaceRepo string
 }
 
 func main() {
@@ -90,11 +193,40 @@ func main() {
 }
 
 func run(args []string) error {
+	args = hoistGlobalFlags(args)
 	cmd := "help"
 	if len(args) > 0 {
 		cmd = args[0]
 		args = args[1:]
 	}
+	if cmd == "--help-json" {
+		return printHelpJSON("")
+	}
+	level, err := logLevelFromEnv()
+	if err != nil {
+		return err
+	}
+	args, level, err = splitGlobalFlags(args, level)
+	if err != nil {
+		return err
+	}
+	setVerbosity(level)
+	args, noColor := stripFlag(args, "--no-color")
+	setColor(noColor)
+	if dryRunCommands[cmd] {
+		dryRunMode = wantsDryRun(cmd, args)
+	}
+	for _, arg := range args {
+		if arg == "--help-json" {
+			return printHelpJSON(cmd)
+		}
+	}
+
+	reporter, err := progressReporterFromEnv()
+	if err != nil {
+		return err
+	}
+	progress = reporter
 
 	switch cmd {
 	case "init":
@@ -106,11 +238,55 @@ func run(args []string) error {
 	case "daemon":
 		return cmdDaemon(args)
 	case "claim":
-		return cmdClaim(args)
+		return timedCommand("claim", args, cmdClaim)
 	case "submit":
-		return cmdSubmit(args)
+		return timedCommand("submit", args, cmdSubmit)
 	case "review":
-		return cmdReview(args)
+		return timedCommand("review", args, cmdReview)
+	case "epic":
+		return cmdEpic(args)
+	case "improve":
+		return timedCommand("improve", args, cmdImprove)
+	case "abandon":
+		return cmdAbandon(args)
+	case "resume":
+		return cmdResume(args)
+	case "queue":
+		return cmdQueue(args)
+	case "tui":
+		return cmdTUI(args)
+	case "clarify":
+		return cmdClarify(args)
+	case "answer":
+		return cmdAnswer(args)
+	case "list":
+		return cmdList(args)
+	case "merge":
+		return cmdMerge(args)
+	case "cleanup":
+		return cmdCleanup(args)
+	case "log":
+		return cmdLog(args)
+	case "onboard":
+		return cmdOnboard(args)
+	case "metrics":
+		return cmdMetrics(args)
+	case "new":
+		return cmdNew(args)
+	case "sync-base":
+		return cmdSyncBase(args)
+	case "env":
+		return cmdEnv(args)
+	case "config":
+		return cmdConfig(args)
+	case "audit-review":
+		return cmdAuditReview(args)
+	case "serve":
+		return cmdServe(args)
+	case "listen":
+		return cmdListen(args)
+	case "completion":
+		return cmdCompletion(args)
 	case "help", "-h", "--help":
 		return cmdHelp(args)
 	default:
@@ -125,29 +301,18 @@ func cmdHelp(args []string) error {
 	}
 
 	if len(args) > 1 {
-		return errors.New("usage: yoke help [command]")
+		return errors.New("usage: yoke help [command|topic]")
 	}
 
-	switch args[0] {
-	case "init":
-		printInitUsage()
-	case "doctor":
-		printDoctorUsage()
-	case "status":
-		printStatusUsage()
-	case "daemon":
-		printDaemonUsage()
-	case "claim":
-		printClaimUsage()
-	case "submit":
-		printSubmitUsage()
-	case "review":
-		printReviewUsage()
-	default:
-		return fmt.Errorf("unknown help topic: %s", args[0])
+	if printer, ok := commandUsagePrinters[args[0]]; ok {
+		printer()
+		return nil
 	}
-
-	return nil
+	if topic, ok := findHelpTopic(args[0]); ok {
+		fmt.Print(formatHelpTopic(topic))
+		return nil
+	}
+	return fmt.Errorf("unknown help topic: %s", args[0])
 }
 
 func cmdInit(args []string) error {
@@ -156,47 +321,44 @@ func cmdInit(args []string) error {
 		reviewerOverride string
 		bdPrefixOverride string
 		noPrompt         bool
+		detectChecks     bool
 	)
 
-	for i := 0; i < len(args); i++ {
-		switch args[i] {
+	parsed, err := parseCommandArgs("init", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printInitUsage()
+		return nil
+	}
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown init argument: %s", parsed.Positionals[0])
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
 		case "--writer-agent":
-			i++
-			if i >= len(args) {
-				return errors.New("--writer-agent requires a value")
-			}
-			normalized, ok := normalizeAgentID(args[i])
+			normalized, ok := normalizeAgentID(flag.Value)
 			if !ok {
-				return fmt.Errorf("unsupported writer agent: %s", args[i])
+				return fmt.Errorf("unsupported writer agent: %s", flag.Value)
 			}
 			writerOverride = normalized
 		case "--reviewer-agent":
-			i++
-			if i >= len(args) {
-				return errors.New("--reviewer-agent requires a value")
-			}
-			normalized, ok := normalizeAgentID(args[i])
+			normalized, ok := normalizeAgentID(flag.Value)
 			if !ok {
-				return fmt.Errorf("unsupported reviewer agent: %s", args[i])
+				return fmt.Errorf("unsupported reviewer agent: %s", flag.Value)
 			}
 			reviewerOverride = normalized
 		case "--bd-prefix":
-			i++
-			if i >= len(args) {
-				return errors.New("--bd-prefix requires a value")
-			}
-			normalized, err := normalizeBDPrefix(args[i])
+			normalized, err := normalizeBDPrefix(flag.Value)
 			if err != nil {
 				return err
 			}
 			bdPrefixOverride = normalized
 		case "--no-prompt":
 			noPrompt = true
-		case "-h", "--help":
-			printInitUsage()
-			return nil
-		default:
-			return fmt.Errorf("unknown init argument: %s", args[i])
+		case "--detect-checks":
+			detectChecks = true
 		}
 	}
 
@@ -296,16 +458,12 @@ func cmdInit(args []string) error {
 		return err
 	}
 
-	checksPath := filepath.Join(root, ".yoke", "checks.sh")
-	if !fileExists(checksPath) {
-		content := `#!/usr/bin/env bash
-set -euo pipefail
-echo "No checks configured. Edit .yoke/checks.sh."
-`
-		if err := os.WriteFile(checksPath, []byte(content), 0o755); err != nil {
-			return err
-		}
-		note("Created .yoke/checks.sh")
+	checksNote, err := scaffoldChecksScript(root, detectChecks)
+	if err != nil {
+		return err
+	}
+	if checksNote != "" {
+		note(checksNote)
 	}
 
 	note("Initialized yoke scaffold.")
@@ -320,13 +478,59 @@ echo "No checks configured. Edit .yoke/checks.sh."
 	return nil
 }
 
-func cmdDoctor(args []string) error {
-	if len(args) > 0 {
-		if len(args) == 1 && (args[0] == "-h" || args[0] == "--help") {
-			printDoctorUsage()
-			return nil
+const defaultChecksScript = `#!/usr/bin/env bash
+set -euo pipefail
+echo "No checks configured. Edit .yoke/checks.sh."
+`
+
+// scaffoldChecksScript creates .yoke/checks.sh. With detect, the project's
+// build and test commands replace the placeholder script, including one an
+// earlier init left behind; a script someone edited is never overwritten.
+func scaffoldChecksScript(root string, detect bool) (string, error) {
+	checksPath := filepath.Join(root, ".yoke", "checks.sh")
+	existing, err := os.ReadFile(checksPath)
+	missing := errors.Is(err, os.ErrNotExist)
+	if err != nil && !missing {
+		return "", err
+	}
+	placeholder := missing || string(existing) == defaultChecksScript
+
+	if detect {
+		if !placeholder {
+			return ".yoke/checks.sh already has checks; left unchanged.", nil
+		}
+		if profile := analyzeRepository(root); len(profile.CheckCmds) > 0 {
+			if err := os.WriteFile(checksPath, []byte(renderOnboardChecks(profile)), 0o755); err != nil {
+				return "", err
+			}
+			return "Created .yoke/checks.sh with detected checks: " + strings.Join(profile.CheckCmds, " && "), nil
 		}
-		return fmt.Errorf("unknown doctor argument: %s", args[0])
+	}
+	if missing {
+		if err := os.WriteFile(checksPath, []byte(defaultChecksScript), 0o755); err != nil {
+			return "", err
+		}
+	}
+	if detect {
+		return "No check commands detected (go.mod, package.json, Cargo.toml, pyproject.toml, Makefile); edit .yoke/checks.sh.", nil
+	}
+	if !missing {
+		return "", nil
+	}
+	return "Created .yoke/checks.sh", nil
+}
+
+func cmdDoctor(args []string) error {
+	parsed, err := parseCommandArgs("doctor", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printDoctorUsage()
+		return nil
+	}
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown doctor argument: %s", parsed.Positionals[0])
 	}
 
 	root, err := ensureRepoRoot()
@@ -340,41 +544,47 @@ func cmdDoctor(args []string) error {
 	}
 
 	failures := 0
-	for _, name := range []string{"git", "bd"} {
-		if commandExists(name) {
-			note("ok: " + name)
-		} else {
-			note("missing: " + name)
-			failures++
-		}
+	if commandExists("git") {
+		printCheckLine("ok: git")
+	} else {
+		printCheckLine("missing: git")
+		failures++
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		printCheckLine("missing: issue backend " + cfg.IssueBackend + " (" + err.Error() + ")")
+		failures++
+	} else if cfg.IssueBackend == "bd" {
+		printCheckLine("ok: " + valueOrFallback(bdVersion(), "bd (version unknown)"))
+	} else {
+		printCheckLine("ok: issue backend " + cfg.IssueBackend)
 	}
 
 	if commandExists("gh") {
-		note("ok: gh")
+		printCheckLine("ok: gh")
 	} else {
-		note("warning: gh missing (PR automation disabled)")
+		printCheckLine("warning: gh missing (PR automation disabled)")
 	}
 
 	if fileExists(cfg.Path) {
-		note("ok: config " + cfg.Path)
+		printCheckLine("ok: config " + cfg.Path)
 	} else {
-		note("warning: config missing (" + cfg.Path + ")")
+		printCheckLine("warning: config missing (" + cfg.Path + ")")
 	}
 
-	note("bd prefix: " + cfg.BDPrefix)
+	printCheckLine("bd prefix: " + cfg.BDPrefix)
 
 	if cfg.WriterAgent != "" {
-		note(fmt.Sprintf("writer agent: %s (%s)", cfg.WriterAgent, agentAvailabilityStatus(cfg.WriterAgent)))
+		printCheckLine(fmt.Sprintf("writer agent: %s (%s)", cfg.WriterAgent, agentAvailabilityStatus(cfg.WriterAgent)))
 	} else {
-		note("writer agent: unset")
+		printCheckLine("writer agent: unset")
 	}
 	if cfg.ReviewerAgent != "" {
-		note(fmt.Sprintf("reviewer agent: %s (%s)", cfg.ReviewerAgent, agentAvailabilityStatus(cfg.ReviewerAgent)))
+		printCheckLine(fmt.Sprintf("reviewer agent: %s (%s)", cfg.ReviewerAgent, agentAvailabilityStatus(cfg.ReviewerAgent)))
 	} else {
-		note("reviewer agent: unset")
+		printCheckLine("reviewer agent: unset")
 	}
-	note("writer command: " + commandConfigStatus(cfg.WriterCmd))
-	note("reviewer command: " + commandConfigStatus(cfg.ReviewCmd))
+	printCheckLine("writer command: " + commandConfigStatus(cfg.WriterCmd))
+	printCheckLine("reviewer command: " + commandConfigStatus(cfg.ReviewCmd))
 
 	if failures > 0 {
 		return errors.New("doctor failed")
@@ -383,12 +593,21 @@ func cmdDoctor(args []string) error {
 }
 
 func cmdStatus(args []string) error {
-	if len(args) > 0 {
-		if len(args) == 1 && (args[0] == "-h" || args[0] == "--help") {
-			printStatusUsage()
-			return nil
+	parsed, err := parseCommandArgs("status", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printStatusUsage()
+		return nil
+	}
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown status argument: %s", parsed.Positionals[0])
+	}
+	for _, flag := range parsed.Flags {
+		if flag.Name == "--workspace" {
+			return printWorkspaceStatus(flag.Value)
 		}
-		return fmt.Errorf("unknown status argument: %s", args[0])
 	}
 
 	root, err := ensureRepoRoot()
@@ -401,8 +620,22 @@ func cmdStatus(args []string) error {
 		return err
 	}
 
+	for _, field := range collectStatus(root, cfg) {
+		fmt.Println(statusLine(field.Key, field.Value))
+	}
+	return nil
+}
+
+type statusField struct {
+	Key   string
+	Value string
+}
+
+// collectStatus gathers the yoke status snapshot in display order; the CLI
+// prints it as key: value lines and the HTTP API returns it as an object.
+func collectStatus(root string, cfg config) []statusField {
 	branch := strings.TrimSpace(commandCombinedOutput("git", "rev-parse", "--abbrev-ref", "HEAD"))
-	bdAvailable := commandExists("bd")
+	bdAvailable := useIssueTracker(cfg) == nil
 
 	bdFocus := "unavailable"
 	bdNext := "unavailable"
@@ -412,23 +645,236 @@ func cmdStatus(args []string) error {
 			focusIssue = focusedIssueByWorkflowStatus(root, cfg.BDPrefix, "in_review")
 		}
 		bdFocus = issueOrNone(focusIssue)
-		bdNext = issueOrNone(nextIssueID(cfg.BDPrefix))
-	}
-
-	note("repo_root: " + root)
-	note("current_branch: " + valueOrFallback(branch, "unknown"))
-	note("bd_prefix: " + cfg.BDPrefix)
-	note("writer_agent: " + valueOrUnset(cfg.WriterAgent))
-	note("writer_agent_status: " + configuredAgentStatus(cfg.WriterAgent))
-	note("writer_command: " + commandConfigStatus(cfg.WriterCmd))
-	note("reviewer_agent: " + valueOrUnset(cfg.ReviewerAgent))
-	note("reviewer_agent_status: " + configuredAgentStatus(cfg.ReviewerAgent))
-	note("reviewer_command: " + commandConfigStatus(cfg.ReviewCmd))
-	note("bd_focus: " + bdFocus)
-	note("bd_next: " + bdNext)
-	note("tool_git: " + availabilityLabel(commandExists("git")))
-	note("tool_bd: " + availabilityLabel(bdAvailable))
-	note("tool_gh: " + availabilityLabel(commandExists("gh")))
+		bdNext = issueOrNone(nextClaimIssueID(cfg, false))
+	}
+
+	fields := []statusField{
+		{"repo_root", root},
+		{"current_branch", valueOrFallback(branch, "unknown")},
+		{"bd_prefix", cfg.BDPrefix},
+		{"writer_agent", valueOrUnset(cfg.WriterAgent)},
+		{"writer_agent_status", configuredAgentStatus(cfg.WriterAgent)},
+		{"writer_command", commandConfigStatus(cfg.WriterCmd)},
+		{"reviewer_agent", valueOrUnset(cfg.ReviewerAgent)},
+		{"reviewer_agent_status", configuredAgentStatus(cfg.ReviewerAgent)},
+		{"reviewer_command", commandConfigStatus(cfg.ReviewCmd)},
+		{"bd_focus", bdFocus},
+		{"bd_next", bdNext},
+	}
+	usageRecords, usageErr := loadUsageRecords(root)
+	if usageErr != nil {
+		fields = append(fields, statusField{"usage_total", "unavailable"})
+	} else {
+		fields = append(fields, statusField{"usage_total", formatUsageTotals(sumUsage(usageRecords, nil))})
+		if focusIssue := bdFocus; focusIssue != "none" && focusIssue != "unavailable" {
+			fields = append(fields, statusField{"usage_focus", formatUsageTotals(sumUsage(usageRecords, func(record usageRecord) bool {
+				return strings.EqualFold(record.IssueID, focusIssue)
+			}))})
+		}
+	}
+	return append(fields,
+		statusField{"tool_git", availabilityLabel(commandExists("git"))},
+		statusField{"tool_bd", availabilityLabel(bdAvailable)},
+		statusField{"tool_gh", availabilityLabel(commandExists("gh"))},
+	)
+}
+
+// workspaceRepo is one repository of a workspace file. Issues carrying one
+// of Labels, or mentioning a path under one of Paths, are routed to it.
+type workspaceRepo struct {
+	Name   string
+	Root   string
+	Labels []string
+	Paths  []string
+}
+
+// loadWorkspace reads a workspace file: one repository per line as
+// "<name> <path> [label=LABEL]... [path=PREFIX]...", with # comments and
+// paths relative to the file. A repository without routes gets label
+// repo:<name> and path prefix <name>.
+func loadWorkspace(path string) ([]workspaceRepo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	repos := []workspaceRepo{}
+	for number, line := range strings.Split(string(data), "\n") {
+		if hash := strings.Index(line, "#"); hash >= 0 {
+			line = line[:hash]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: want \"<name> <path> [label=LABEL] [path=PREFIX]\"", path, number+1)
+		}
+		repo := workspaceRepo{Name: fields[0], Root: fields[1]}
+		if !filepath.IsAbs(repo.Root) {
+			repo.Root = filepath.Join(dir, repo.Root)
+		}
+		for _, route := range fields[2:] {
+			key, value, _ := strings.Cut(route, "=")
+			switch {
+			case key == "label" && value != "":
+				repo.Labels = append(repo.Labels, value)
+			case key == "path" && value != "":
+				repo.Paths = append(repo.Paths, value)
+			default:
+				return nil, fmt.Errorf("%s:%d: unknown route %q (want label=LABEL or path=PREFIX)", path, number+1, route)
+			}
+		}
+		if len(repo.Labels) == 0 && len(repo.Paths) == 0 {
+			repo.Labels, repo.Paths = []string{"repo:" + repo.Name}, []string{repo.Name}
+		}
+		for _, existing := range repos {
+			if strings.EqualFold(existing.Name, repo.Name) {
+				return nil, fmt.Errorf("%s:%d: duplicate repository %q", path, number+1, repo.Name)
+			}
+		}
+		repos = append(repos, repo)
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("%s lists no repositories", path)
+	}
+	return repos, nil
+}
+
+// workspaceRoute names the repository an issue belongs to: the first whose
+// labels match, else the first whose paths the issue mentions, else "".
+func workspaceRoute(repos []workspaceRepo, issue bdListIssue) string {
+	for _, repo := range repos {
+		for _, label := range repo.Labels {
+			if hasLabel(issue.Labels, label) {
+				return repo.Name
+			}
+		}
+	}
+	paths := issuePaths(issue)
+	for _, repo := range repos {
+		for _, prefix := range repo.Paths {
+			if anyPathOwned(paths, prefix) {
+				return repo.Name
+			}
+		}
+	}
+	return ""
+}
+
+// routedHere reports whether issue belongs to the repository being served;
+// outside workspace mode every issue does.
+func routedHere(cfg config, issue bdListIssue) bool {
+	return len(cfg.Workspace) == 0 || strings.EqualFold(workspaceRoute(cfg.Workspace, issue), cfg.WorkspaceRepo)
+}
+
+// inWorkspaceRepo runs fn from the repository's checkout with its config,
+// tracker, and routing loaded, then returns to the original directory.
+func inWorkspaceRepo(repos []workspaceRepo, repo workspaceRepo, fn func(root string, cfg config) error) error {
+	previous, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(repo.Root); err != nil {
+		return fmt.Errorf("workspace repository %s: %w", repo.Name, err)
+	}
+	defer os.Chdir(previous)
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return fmt.Errorf("workspace repository %s: %w", repo.Name, err)
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return fmt.Errorf("workspace repository %s: %w", repo.Name, err)
+	}
+	cfg.Workspace, cfg.WorkspaceRepo = repos, repo.Name
+	return fn(root, cfg)
+}
+
+// runWorkspaceDaemon is yoke daemon --workspace: each iteration runs one
+// daemon iteration in every repository, in workspace file order, and the
+// daemon sleeps only when every repository was idle.
+func runWorkspaceDaemon(options daemonLoopOptions) error {
+	repos, err := loadWorkspace(options.Workspace)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(os.Getenv("YOKE_ROLE")) == "" {
+		os.Setenv("YOKE_ROLE", "daemon")
+	}
+	exportDesktopNotify()
+	note("Workspace daemon started.")
+	for _, repo := range repos {
+		note("  " + repo.Name + ": " + repo.Root)
+	}
+	note("  poll interval: " + options.Interval.String())
+
+	for iteration := 1; ; iteration++ {
+		idle := true
+		for _, repo := range repos {
+			var action string
+			err := inWorkspaceRepo(repos, repo, func(root string, cfg config) error {
+				if err := useIssueTracker(cfg); err != nil {
+					return err
+				}
+				writerCmd := valueOrFallback(strings.TrimSpace(options.WriterCmd), cfg.WriterCmd)
+				reviewerCmd := valueOrFallback(strings.TrimSpace(options.ReviewerCmd), cfg.ReviewCmd)
+				if strings.TrimSpace(writerCmd) == "" || strings.TrimSpace(reviewerCmd) == "" {
+					return fmt.Errorf("workspace repository %s: YOKE_WRITER_CMD and YOKE_REVIEW_CMD are required in %s", repo.Name, cfg.Path)
+				}
+				if options.Budget > 0 {
+					cfg.MaxIssueCost = options.Budget
+				}
+				var err error
+				action, err = runDaemonIteration(root, cfg, writerCmd, reviewerCmd)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if action != "idle" {
+				progress.Info("daemon.iteration", fmt.Sprintf("Iteration %d: [%s] %s", iteration, repo.Name, action))
+			}
+			if action != "idle" && !strings.HasPrefix(action, daemonWaitingPrefix) {
+				idle = false
+			}
+		}
+
+		if options.Once {
+			note("Workspace daemon completed single iteration.")
+			return nil
+		}
+		if options.MaxIterations > 0 && iteration >= options.MaxIterations {
+			note(fmt.Sprintf("Workspace daemon reached max iterations (%d); exiting.", options.MaxIterations))
+			return nil
+		}
+		if idle {
+			time.Sleep(options.Interval)
+		}
+	}
+}
+
+// printWorkspaceStatus is yoke status --workspace: every repository's
+// snapshot, each line prefixed with its name, with bd_next routed.
+func printWorkspaceStatus(path string) error {
+	repos, err := loadWorkspace(path)
+	if err != nil {
+		return err
+	}
+	for _, repo := range repos {
+		err := inWorkspaceRepo(repos, repo, func(root string, cfg config) error {
+			for _, field := range collectStatus(root, cfg) {
+				fmt.Println("[" + repo.Name + "] " + statusLine(field.Key, field.Value))
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Println("[" + repo.Name + "] " + paint(styleRed, "error:") + " " + err.Error())
+		}
+	}
 	return nil
 }
 
@@ -438,6 +884,9 @@ type daemonLoopOptions struct {
 	MaxIterations int
 	WriterCmd     string
 	ReviewerCmd   string
+	Budget        float64
+	ReviewPRs     bool
+	Workspace     string
 }
 
 func cmdDaemon(args []string) error {
@@ -445,52 +894,51 @@ func cmdDaemon(args []string) error {
 		Interval: defaultDaemonPoll,
 	}
 
-	for i := 0; i < len(args); i++ {
-		switch args[i] {
+	parsed, err := parseCommandArgs("daemon", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printDaemonUsage()
+		return nil
+	}
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown daemon argument: %s", parsed.Positionals[0])
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
 		case "--once":
 			options.Once = true
 		case "--interval":
-			i++
-			if i >= len(args) {
-				return errors.New("--interval requires a value")
-			}
-			interval, err := parseDaemonInterval(args[i])
+			interval, err := parseDaemonInterval(flag.Value)
 			if err != nil {
 				return err
 			}
 			options.Interval = interval
 		case "--max-iterations":
-			i++
-			if i >= len(args) {
-				return errors.New("--max-iterations requires a value")
-			}
-			parsed, err := strconv.Atoi(args[i])
-			if err != nil || parsed <= 0 {
-				return fmt.Errorf("invalid --max-iterations value: %s", args[i])
+			value, err := strconv.Atoi(flag.Value)
+			if err != nil || value <= 0 {
+				return fmt.Errorf("invalid --max-iterations value: %s", flag.Value)
 			}
-			options.MaxIterations = parsed
+			options.MaxIterations = value
 		case "--writer-cmd":
-			i++
-			if i >= len(args) {
-				return errors.New("--writer-cmd requires a value")
-			}
-			options.WriterCmd = args[i]
+			options.WriterCmd = flag.Value
 		case "--reviewer-cmd":
-			i++
-			if i >= len(args) {
-				return errors.New("--reviewer-cmd requires a value")
+			options.ReviewerCmd = flag.Value
+		case "--budget":
+			budget, err := parseBudget(flag.Value)
+			if err != nil {
+				return fmt.Errorf("invalid --budget value: %w", err)
 			}
-			options.ReviewerCmd = args[i]
-		case "-h", "--help":
-			printDaemonUsage()
-			return nil
-		default:
-			return fmt.Errorf("unknown daemon argument: %s", args[i])
+			options.Budget = budget
+		case "--review-prs":
+			options.ReviewPRs = true
+		case "--workspace":
+			options.Workspace = flag.Value
 		}
 	}
-
-	if !commandExists("bd") {
-		return fmt.Errorf("missing required command: bd")
+	if options.Workspace != "" {
+		return runWorkspaceDaemon(options)
 	}
 
 	root, err := ensureRepoRoot()
@@ -501,6 +949,15 @@ func cmdDaemon(args []string) error {
 	if err != nil {
 		return err
 	}
+	// The daemon's own writes (claims, fast-track reviews, CI returns) are
+	// automation too; role commands it starts override this.
+	if strings.TrimSpace(os.Getenv("YOKE_ROLE")) == "" {
+		os.Setenv("YOKE_ROLE", "daemon")
+	}
+	exportDesktopNotify()
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
 
 	if strings.TrimSpace(options.WriterCmd) == "" {
 		options.WriterCmd = cfg.WriterCmd
@@ -514,6 +971,9 @@ func cmdDaemon(args []string) error {
 	if strings.TrimSpace(options.ReviewerCmd) == "" {
 		return errors.New("YOKE_REVIEW_CMD is empty in .yoke/config.sh (required for yoke daemon)")
 	}
+	if options.Budget > 0 {
+		cfg.MaxIssueCost = options.Budget
+	}
 
 	note("Daemon started.")
 	note("  poll interval: " + options.Interval.String())
@@ -525,12 +985,33 @@ func cmdDaemon(args []string) error {
 	if options.MaxIterations > 0 {
 		note(fmt.Sprintf("  max iterations: %d", options.MaxIterations))
 	}
+	if cfg.MaxIssueCost > 0 {
+		note("  budget per issue: $" + formatBudget(cfg.MaxIssueCost))
+	}
+	if options.ReviewPRs {
+		note("  human PR review: enabled (label " + humanReviewLabel + ")")
+	}
 
 	for iteration := 1; ; iteration++ {
 		action, err := runDaemonIteration(root, cfg, options.WriterCmd, options.ReviewerCmd)
 		if err != nil {
+			notifyDaemonError(root, cfg, err)
 			return err
 		}
+		if action == "idle" && options.ReviewPRs {
+			reviewed, err := reviewNextHumanPR(root, cfg)
+			if err != nil {
+				notifyDaemonError(root, cfg, err)
+				return err
+			}
+			if reviewed != "" {
+				action = "reviewed PR #" + reviewed
+			}
+		}
+
+		if action != "idle" {
+			progress.Info("daemon.iteration", fmt.Sprintf("Iteration %d: %s", iteration, action))
+		}
 
 		if options.Once {
 			note("Daemon completed single iteration: " + action)
@@ -544,12 +1025,21 @@ func cmdDaemon(args []string) error {
 			return nil
 		}
 
-		if action == "idle" {
+		if action == "idle" || strings.HasPrefix(action, daemonWaitingPrefix) {
 			time.Sleep(options.Interval)
 		}
 	}
 }
 
+// notifyDaemonError reports why the daemon stopped. A budget stop has
+// already sent its own budget-exceeded notification.
+func notifyDaemonError(root string, cfg config, err error) {
+	if errors.Is(err, errBudgetExceeded) {
+		return
+	}
+	notifyEvent(root, cfg, "daemon-error", "", "yoke daemon stopped: "+err.Error())
+}
+
 func parseDaemonInterval(raw string) (time.Duration, error) {
 	value := strings.TrimSpace(raw)
 	if value == "" {
@@ -572,2939 +1062,21207 @@ func parseDaemonInterval(raw string) (time.Duration, error) {
 }
 
 func runDaemonIteration(root string, cfg config, writerCmd, reviewerCmd string) (string, error) {
+	daemonSlackSync(root, cfg)
+	invalidateStaleApprovals(root)
+	restackMergedParents(root, cfg)
+	if cfg.MergeQueue {
+		advanceMergeQueue(root, cfg)
+	}
+
 	reviewable := focusedIssueByWorkflowStatus(root, cfg.BDPrefix, "in_review")
 	if reviewable == "" {
-		reviewable = firstReviewableIssueID(cfg.BDPrefix)
+		reviewable = nextReviewableIssueID(cfg)
+	}
+	if reviewable != "" && cfg.WaitCI {
+		state, failed, ciErr := issueCIState(reviewable)
+		switch state {
+		case "fail":
+			prNumber, _, _, _, err := openPRForIssue(reviewable)
+			if err != nil {
+				progress.Info("daemon.review", "warning: skipping the CI failure PR comment: "+err.Error())
+			}
+			if err := returnIssueForCIFailure(root, reviewable, prNumber, failed); err != nil {
+				return "", err
+			}
+			return "ci-failed " + reviewable, nil
+		case "pending":
+			if ciErr != nil {
+				progress.Info("daemon.review", "Deferring review of "+reviewable+": could not read required checks: "+ciErr.Error())
+			} else {
+				progress.Info("daemon.review", "Deferring review of "+reviewable+": required checks are still running.")
+			}
+			reviewable = ""
+		}
 	}
 	if reviewable != "" {
+		if err := enforceIssueBudget(root, cfg, reviewable, cfg.MaxIssueCost); err != nil {
+			return daemonBudgetStop(reviewable, err)
+		}
 		worktreePath, err := ensureIssueWorktree(root, cfg, reviewable)
 		if err != nil {
 			return "", err
 		}
-		if err := runDaemonRoleCommand("reviewer", reviewable, reviewerCmd, worktreePath, root, cfg.BDPrefix); err != nil {
+		if _, err := acquireReviewLock(root, reviewable); err != nil {
 			return "", err
 		}
-		return "reviewed " + reviewable, nil
-	}
+		if lowRiskSampledOut(cfg, reviewable) {
+			result, err := fastTrackReview(root, cfg, reviewable, worktreePath)
+			releaseReviewLock(root, reviewable)
+			return result, err
+		}
+		if blocked, err := daemonSecurityReview(root, cfg, reviewable, worktreePath); err != nil || blocked {
+			releaseReviewLock(root, reviewable)
+			if err != nil {
+				return "", err
+			}
+			return "security-rejected " + reviewable, nil
+		}
+		progress.Start("daemon.review", "Running reviewer for "+reviewable)
+		runErr := runDaemonRoleCommand(context.Background(), "reviewer", reviewable, reviewerCmd, worktreePath, root, cfg)
+		releaseReviewLock(root, reviewable)
+		if runErr != nil {
+			progress.Fail("daemon.review", runErr)
+			return "", runErr
+		}
+		progress.Finish("daemon.review", "")
+		if err := enforceIssueBudget(root, cfg, reviewable, cfg.MaxIssueCost); err != nil {
+			return daemonBudgetStop(reviewable, err)
+		}
+		return "reviewed " + reviewable, nil
+	}
 
-	inProgress, err := focusedOrInProgressIssueID(root, cfg.BDPrefix)
+	inProgress, err := daemonInProgressIssueID(root, cfg)
 	if err != nil {
 		return "", err
 	}
 	if inProgress != "" {
+		if err := enforceIssueBudget(root, cfg, inProgress, cfg.MaxIssueCost); err != nil {
+			return daemonBudgetStop(inProgress, err)
+		}
+		if err := ensureNotReviewLocked(root, inProgress); err != nil {
+			progress.Info("daemon.write", "Waiting: "+err.Error())
+			return "idle", nil
+		}
+		if wait := checkWriterOverlaps(root, cfg, inProgress); wait != "" {
+			progress.Info("daemon.write", "Waiting: "+wait)
+			return "idle", nil
+		}
 		worktreePath, err := ensureIssueWorktree(root, cfg, inProgress)
 		if err != nil {
 			return "", err
 		}
-		if err := runDaemonRoleCommand("writer", inProgress, writerCmd, worktreePath, root, cfg.BDPrefix); err != nil {
+		if cfg.SyncInterval > 0 {
+			periodicBaseSync(root, worktreePath, cfg, inProgress, time.Now())
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		stopLease := holdIssueLease(cfg, inProgress, cancel)
+		err = progressStep("daemon.write", "Running writer for "+inProgress, func() error {
+			return runDaemonRoleCommand(ctx, "writer", inProgress, writerCmd, worktreePath, root, cfg)
+		})
+		stopLease()
+		leaseLost := ctx.Err() != nil
+		cancel()
+		if leaseLost {
+			return "lost-lease " + inProgress, nil
+		}
+		if err != nil {
 			return "", err
 		}
+		if err := enforceIssueBudget(root, cfg, inProgress, cfg.MaxIssueCost); err != nil {
+			return daemonBudgetStop(inProgress, err)
+		}
 		return "wrote " + inProgress, nil
 	}
 
-	next := nextIssueID(cfg.BDPrefix)
+	next := nextClaimIssueID(cfg, true)
 	if next != "" {
-		note("Daemon claiming next issue: " + next)
-		if err := cmdClaim([]string{next}); err != nil {
-			return "", err
+		if stillWaitingForClarification(root, next) {
+			return daemonWaitingAction([]string{next}), nil
+		}
+		progress.Info("daemon.claim", "Claiming next issue: "+next)
+		claimArgs := []string{next}
+		if cfg.MaxIssueCost > 0 {
+			claimArgs = append(claimArgs, "--budget", formatBudget(cfg.MaxIssueCost))
+		}
+		if err := cmdClaim(claimArgs); err != nil {
+			var wait *clarificationWaitError
+			if errors.As(err, &wait) {
+				waitForClarification(root, cfg, wait.Epic, wait.Tasks)
+				return daemonWaitingAction([]string{wait.Epic}), nil
+			}
+			return daemonBudgetStop(next, err)
 		}
 		return "claimed " + next, nil
 	}
 
+	if epics := daemonClarificationWait(root, cfg); len(epics) > 0 {
+		return daemonWaitingAction(epics), nil
+	}
 	return "idle", nil
 }
 
-func runDaemonRoleCommand(role, issue, shellCommand, worktreeRoot, mainRoot, bdPrefix string) error {
-	previousStatus, err := issueStatus(issue)
-	if err != nil {
-		return err
+// daemonBudgetStop turns a budget stop into an iteration result so the
+// daemon moves on to other work. The issue is labeled budgetLabel by then,
+// and selection skips it until a person raises the budget or drops the label.
+func daemonBudgetStop(issue string, err error) (string, error) {
+	if !errors.Is(err, errBudgetExceeded) {
+		return "", err
 	}
+	progress.Info("daemon.budget", "Skipping "+issue+": "+err.Error())
+	return "budget-exceeded " + issue, nil
+}
 
-	augmentedCommand := daemonCommandWithExtraWritableDir(shellCommand)
-	note(fmt.Sprintf("Daemon running %s command for %s", role, issue))
-	cmd := exec.Command("bash", "-lc", augmentedCommand)
-	filteredOutput := newDaemonLogFilterWriter(os.Stdout)
-	cmd.Stdout = filteredOutput
-	cmd.Stderr = filteredOutput
-	cmd.Dir = worktreeRoot
-	cmd.Env = daemonCommandEnv(os.Environ(), issue, worktreeRoot, mainRoot, bdPrefix, role)
-	runErr := cmd.Run()
-	flushErr := filteredOutput.Flush()
-	if runErr != nil {
-		return runErr
-	}
-	if flushErr != nil {
-		return flushErr
+// daemonSlackSync routes clarification tasks through Slack when it is
+// configured. Slack outages only warn; they never stop the daemon.
+func daemonSlackSync(root string, cfg config) {
+	client := newSlackClient(cfg)
+	if client == nil {
+		return
 	}
-
-	currentStatus, err := issueStatus(issue)
+	posted, ingested, err := syncSlackClarifications(root, client)
 	if err != nil {
-		return err
+		note("warning: Slack clarification sync failed: " + err.Error())
 	}
-	if currentStatus == previousStatus {
-		return fmt.Errorf("%s command did not advance issue %s (still %s); ensure the command transitions bd state", role, issue, currentStatus)
+	if posted > 0 || ingested > 0 {
+		progress.Info("daemon.slack", fmt.Sprintf("Slack: posted %d clarification task(s), copied %d reply(ies) to bd.", posted, ingested))
 	}
-
-	note(fmt.Sprintf("Daemon observed %s status transition: %s -> %s", issue, previousStatus, currentStatus))
-	return nil
 }
 
-func daemonCommandEnv(base []string, issue, worktreeRoot, mainRoot, bdPrefix, role string) []string {
-	env := append([]string{}, base...)
-	env = append(env,
-		"ISSUE_ID="+issue,
-		"ROOT_DIR="+worktreeRoot,
-		"YOKE_MAIN_ROOT="+mainRoot,
-		"BD_PREFIX="+bdPrefix,
-		"YOKE_ROLE="+role,
-	)
-	binDirs := []string{
-		filepath.Join(mainRoot, "bin"),
-		filepath.Join(worktreeRoot, "bin"),
-	}
-	env = appendOrPrependPath(env, binDirs...)
-	return env
+const daemonWaitingPrefix = "waiting-for-human "
+
+func daemonWaitingAction(epics []string) string {
+	return daemonWaitingPrefix + strings.Join(epics, ", ")
 }
 
-func appendOrPrependPath(env []string, entries ...string) []string {
-	filtered := make([]string, 0, len(entries))
+// daemonClarificationWait runs when the daemon has nothing to claim. Epics
+// with unanswered clarification tasks are reported as waiting on a person
+// instead of leaving the daemon idle without saying why.
+func daemonClarificationWait(root string, cfg config) []string {
+	entries, err := openClarificationEntries()
+	if err != nil {
+		note("warning: failed to list clarification tasks: " + err.Error())
+		return nil
+	}
+	byEpic := map[string][]clarificationEntry{}
+	epics := []string{}
 	for _, entry := range entries {
-		if strings.TrimSpace(entry) == "" {
+		if entry.Comments > 0 || entry.Epic == "" {
 			continue
 		}
-		filtered = append(filtered, entry)
+		if _, ok := byEpic[entry.Epic]; !ok {
+			epics = append(epics, entry.Epic)
+		}
+		byEpic[entry.Epic] = append(byEpic[entry.Epic], entry)
 	}
-	if len(filtered) == 0 {
-		return env
+	for _, epic := range epics {
+		waitForClarification(root, cfg, epic, byEpic[epic])
 	}
+	return epics
+}
 
-	pathIndex := -1
-	pathValue := ""
-	for i, item := range env {
-		if strings.HasPrefix(item, "PATH=") {
-			pathIndex = i
-			pathValue = strings.TrimPrefix(item, "PATH=")
-			break
+func clarificationWaitPath(root string) string {
+	return filepath.Join(root, ".yoke", clarificationWaitFile)
+}
+
+// loadClarificationWaits returns the clarification task ids last announced
+// for each waiting epic.
+func loadClarificationWaits(root string) map[string][]string {
+	waits := map[string][]string{}
+	data, err := os.ReadFile(clarificationWaitPath(root))
+	if err != nil {
+		return waits
+	}
+	if err := json.Unmarshal(data, &waits); err != nil {
+		return map[string][]string{}
+	}
+	return waits
+}
+
+func saveClarificationWaits(root string, waits map[string][]string) error {
+	if len(waits) == 0 {
+		if err := os.Remove(clarificationWaitPath(root)); err != nil && !os.IsNotExist(err) {
+			return err
 		}
+		return nil
 	}
-	joined := strings.Join(filtered, string(os.PathListSeparator))
-	if strings.TrimSpace(pathValue) != "" {
-		joined = joined + string(os.PathListSeparator) + pathValue
+	data, err := json.MarshalIndent(waits, "", "  ")
+	if err != nil {
+		return err
 	}
-	if pathIndex >= 0 {
-		env[pathIndex] = "PATH=" + joined
-		return env
+	if err := os.MkdirAll(filepath.Dir(clarificationWaitPath(root)), 0o755); err != nil {
+		return err
 	}
-	return append(env, "PATH="+joined)
+	return os.WriteFile(clarificationWaitPath(root), append(data, '\n'), 0o644)
 }
 
-func daemonCommandWithExtraWritableDir(shellCommand string) string {
-	trimmed := strings.TrimSpace(shellCommand)
-	if trimmed == "" {
-		return shellCommand
+// waitForClarification puts an epic in the waiting-for-human state. The
+// summary comment and notification go out only when the set of open
+// questions changes, so polling does not repeat them.
+func waitForClarification(root string, cfg config, epic string, tasks []clarificationEntry) {
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	sort.Strings(ids)
+	waits := loadClarificationWaits(root)
+	if strings.Join(waits[epic], ",") == strings.Join(ids, ",") {
+		return
 	}
-	if !strings.HasPrefix(trimmed, "codex ") {
-		return shellCommand
+
+	progress.Info("daemon.clarify", fmt.Sprintf("Epic %s is waiting for answers to %d clarification task(s).", epic, len(ids)))
+	comment := formatClarificationWaitComment(epic, tasks)
+	if err := tracker.Comment(epic, comment); err != nil {
+		note("warning: failed to post clarification summary on " + epic + ": " + err.Error())
+		return
 	}
-	if strings.Contains(trimmed, "--add-dir") {
-		return shellCommand
+	notifyEvent(root, cfg, "waiting-for-human", epic, comment)
+	waits[epic] = ids
+	if err := saveClarificationWaits(root, waits); err != nil {
+		note("warning: failed to record clarification wait: " + err.Error())
 	}
-	if strings.HasPrefix(trimmed, "codex exec ") {
-		return strings.Replace(trimmed, "codex exec ", "codex exec --add-dir \"$YOKE_MAIN_ROOT\" ", 1)
+}
+
+// stillWaitingForClarification reports whether epic is in the
+// waiting-for-human state with every announced question still unanswered.
+// Any answer, close, or lookup failure clears the state so the next claim
+// re-evaluates the epic.
+func stillWaitingForClarification(root, epic string) bool {
+	waits := loadClarificationWaits(root)
+	ids, ok := waits[epic]
+	if !ok {
+		return false
 	}
-	if trimmed == "codex exec" {
-		return "codex exec --add-dir \"$YOKE_MAIN_ROOT\""
+	for _, id := range ids {
+		details, err := issueDetails(id)
+		if err != nil || details.CommentCount > 0 || workflowStatusForIssue(details) == "closed" {
+			delete(waits, epic)
+			if err := saveClarificationWaits(root, waits); err != nil {
+				note("warning: failed to clear clarification wait: " + err.Error())
+			}
+			return false
+		}
 	}
-	return shellCommand
+	return true
 }
 
-type daemonLogFilterWriter struct {
-	dst         io.Writer
-	buf         bytes.Buffer
-	inDiffFence bool
-	inRawDiff   bool
-	inRawHunk   bool
-	mu          sync.Mutex
+const clarificationWaitCommentPrefix = "yoke: waiting for a person to answer clarification tasks"
+
+func formatClarificationWaitComment(epic string, tasks []clarificationEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s on %s.\n\n", clarificationWaitCommentPrefix, epic)
+	b.WriteString("The remaining work cannot start until these are answered:\n\n")
+	for _, task := range tasks {
+		fmt.Fprintf(&b, "- %s: %s\n", task.ID, task.Question)
+	}
+	b.WriteString("\nAnswer with `yoke answer <task-id> \"...\"`; the daemon resumes on its next poll.\n")
+	return b.String()
 }
 
-func newDaemonLogFilterWriter(dst io.Writer) *daemonLogFilterWriter {
-	return &daemonLogFilterWriter{dst: dst}
+// notification is one event people may want to hear about outside bd.
+type notification struct {
+	Event   string `json:"event"`
+	Issue   string `json:"issue,omitempty"`
+	Message string `json:"message"`
+	Time    string `json:"time"`
 }
 
-func (w *daemonLogFilterWriter) Write(p []byte) (int, error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+func (n notification) text() string {
+	return strings.TrimSpace("yoke: "+n.Event+" "+n.Issue) + "\n\n" + n.Message
+}
 
-	total := len(p)
-	for len(p) > 0 {
-		newline := bytes.IndexByte(p, '\n')
-		if newline < 0 {
-			_, err := w.buf.Write(p)
-			return total, err
+// notifier delivers notifications to one channel.
+type notifier interface {
+	Notify(n notification) error
+}
+
+// notifyRoute sends Event ("*" for every event) to the named channels.
+type notifyRoute struct {
+	Event    string
+	Channels []string
+}
+
+var (
+	notifyEvents   = []string{"submitted", "approved", "daemon-error", "budget-exceeded", "waiting-for-human"}
+	notifyChannels = []string{"command", "slack", "discord", "webhook", "desktop"}
+	// Without YOKE_NOTIFY_ROUTES, events where automation stops for a person
+	// go to every configured team channel, and the desktop gets what someone
+	// supervising a local daemon needs to act on.
+	defaultNotifyRoutes = []notifyRoute{
+		{Event: "daemon-error", Channels: []string{"command", "slack", "discord", "webhook", "desktop"}},
+		{Event: "budget-exceeded", Channels: []string{"command", "slack", "discord", "webhook"}},
+		{Event: "waiting-for-human", Channels: []string{"command", "slack", "discord", "webhook"}},
+		{Event: "submitted", Channels: []string{"desktop"}},
+	}
+)
+
+const (
+	notifyTimeout      = 10 * time.Second
+	discordContentMax  = 2000
+	notifyWebhookAgent = "yoke"
+)
+
+func parseNotifyRoutes(raw string) ([]notifyRoute, error) {
+	routes := []notifyRoute{}
+	for _, item := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		event, value, ok := strings.Cut(item, "=")
+		event = strings.ToLower(event)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("route %q needs channels, for example approved=slack|discord", item)
 		}
-		_, _ = w.buf.Write(p[:newline])
-		if err := w.processBufferedLine(true); err != nil {
-			return total, err
+		if event != "*" && !containsString(notifyEvents, event) {
+			return nil, fmt.Errorf("unknown event %q (want %s, or *)", event, strings.Join(notifyEvents, ", "))
 		}
-		p = p[newline+1:]
+		route := notifyRoute{Event: event}
+		for _, channel := range strings.Split(strings.ToLower(value), "|") {
+			if !containsString(notifyChannels, channel) {
+				return nil, fmt.Errorf("unknown channel %q (want %s)", channel, strings.Join(notifyChannels, ", "))
+			}
+			route.Channels = append(route.Channels, channel)
+		}
+		routes = append(routes, route)
 	}
-	return total, nil
+	return routes, nil
 }
 
-func (w *daemonLogFilterWriter) Flush() error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	if w.buf.Len() == 0 {
-		return nil
+func formatNotifyRoutes(routes []notifyRoute) string {
+	items := make([]string, 0, len(routes))
+	for _, route := range routes {
+		items = append(items, route.Event+"="+strings.Join(route.Channels, "|"))
 	}
-	return w.processBufferedLine(false)
+	return strings.Join(items, ",")
 }
 
-func (w *daemonLogFilterWriter) processBufferedLine(withNewline bool) error {
-	raw := strings.TrimSuffix(w.buf.String(), "\r")
-	w.buf.Reset()
-
-	suppress, reprocess := w.shouldSuppressLine(raw)
-	if reprocess {
-		suppress, _ = w.shouldSuppressLine(raw)
-	}
-	if suppress {
-		return nil
+// notifyChannelsFor lists the channels an event goes to, in
+// notifyChannels order so delivery order does not depend on the routes.
+func notifyChannelsFor(routes []notifyRoute, event string) []string {
+	if len(routes) == 0 {
+		routes = defaultNotifyRoutes
+	}
+	wanted := map[string]bool{}
+	for _, route := range routes {
+		if route.Event == event || route.Event == "*" {
+			for _, channel := range route.Channels {
+				wanted[channel] = true
+			}
+		}
 	}
-
-	if withNewline {
-		_, err := io.WriteString(w.dst, raw+"\n")
-		return err
+	channels := []string{}
+	for _, channel := range notifyChannels {
+		if wanted[channel] {
+			channels = append(channels, channel)
+		}
 	}
-	_, err := io.WriteString(w.dst, raw)
-	return err
+	return channels
 }
 
-func (w *daemonLogFilterWriter) shouldSuppressLine(raw string) (suppress bool, reprocess bool) {
-	if strings.Contains(raw, "state db missing rollout path for thread") {
-		return true, false
+// configuredNotifiers returns the channels that are set up. Slack, Discord,
+// and webhook URLs come from the environment so they stay out of
+// .yoke/config.sh.
+func configuredNotifiers(root string, cfg config) map[string]notifier {
+	notifiers := map[string]notifier{}
+	if strings.TrimSpace(cfg.NotifyCmd) != "" {
+		notifiers["command"] = commandNotifier{root: root, command: cfg.NotifyCmd}
 	}
-
-	trimmed := strings.TrimSpace(raw)
-	lowerTrimmed := strings.ToLower(trimmed)
-	if w.inDiffFence {
-		if strings.HasPrefix(trimmed, "```") {
-			w.inDiffFence = false
-		}
-		return true, false
+	if client := newSlackClient(cfg); client != nil {
+		notifiers["slack"] = slackNotifier{client: client}
 	}
-	if strings.HasPrefix(lowerTrimmed, "```diff") {
-		w.inDiffFence = true
-		return true, false
+	if url := strings.TrimSpace(os.Getenv("DISCORD_WEBHOOK_URL")); url != "" {
+		notifiers["discord"] = discordNotifier{url: url}
 	}
+	if url := strings.TrimSpace(os.Getenv("YOKE_NOTIFY_WEBHOOK_URL")); url != "" {
+		notifiers["webhook"] = webhookNotifier{url: url}
+	}
+	if desktop, ok := newDesktopNotifier(); ok {
+		notifiers["desktop"] = desktop
+	}
+	return notifiers
+}
 
-	if w.inRawDiff {
-		if raw == "" {
-			w.inRawDiff = false
-			w.inRawHunk = false
-			return false, true
+// notifyEvent sends an event to the channels YOKE_NOTIFY_ROUTES picks for
+// it. Routes to unconfigured channels are skipped, and delivery failures are
+// reported but never stop automation.
+func notifyEvent(root string, cfg config, event, issue, message string) {
+	if dryRunMode {
+		logDryRun(issue, "notify "+event, []string{message})
+		return
+	}
+	n := notification{Event: event, Issue: issue, Message: message, Time: time.Now().UTC().Format(time.RFC3339)}
+	available := configuredNotifiers(root, cfg)
+	for _, channel := range notifyChannelsFor(cfg.NotifyRoutes, event) {
+		target, ok := available[channel]
+		if !ok {
+			continue
 		}
-		switch {
-		case isRawDiffMetadataLine(raw):
-			if strings.HasPrefix(raw, "@@") {
-				w.inRawHunk = true
-			}
-			return true, false
-		case w.inRawHunk && isRawDiffHunkLine(raw):
-			return true, false
-		default:
-			w.inRawDiff = false
-			w.inRawHunk = false
-			return false, true
+		if err := target.Notify(n); err != nil {
+			note("warning: " + channel + " notification failed: " + err.Error())
 		}
 	}
+}
 
-	if isRawDiffStartLine(raw) {
-		w.inRawDiff = true
-		w.inRawHunk = strings.HasPrefix(raw, "@@")
-		return true, false
+// commandNotifier runs YOKE_NOTIFY_CMD with the message on stdin.
+type commandNotifier struct {
+	root    string
+	command string
+}
+
+func (c commandNotifier) Notify(n notification) error {
+	cmd := exec.Command("bash", "-lc", c.command)
+	cmd.Stdin = strings.NewReader(n.Message)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = c.root
+	cmd.Env = append(os.Environ(),
+		"ROOT_DIR="+c.root,
+		"YOKE_NOTIFY_EVENT="+n.Event,
+		"ISSUE_ID="+n.Issue,
+	)
+	return cmd.Run()
+}
+
+// slackNotifier posts through the same client as clarification tasks.
+type slackNotifier struct {
+	client *slackClient
+}
+
+func (s slackNotifier) Notify(n notification) error {
+	_, err := s.client.post(n.text())
+	return err
+}
+
+// discordNotifier posts to a Discord channel webhook.
+type discordNotifier struct {
+	url string
+}
+
+func (d discordNotifier) Notify(n notification) error {
+	content := n.text()
+	if len(content) > discordContentMax {
+		cut := discordContentMax - len("...")
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		content = content[:cut] + "..."
 	}
+	return postNotification(d.url, map[string]string{"content": content, "username": notifyWebhookAgent})
+}
 
-	return false, false
+// webhookNotifier posts the notification itself as JSON, for receivers
+// yoke has no dedicated format for.
+type webhookNotifier struct {
+	url string
 }
 
-func isRawDiffStartLine(line string) bool {
-	return strings.HasPrefix(line, "diff --git ") ||
-		strings.HasPrefix(line, "--- a/") ||
-		strings.HasPrefix(line, "+++ b/") ||
-		strings.HasPrefix(line, "@@")
+func (w webhookNotifier) Notify(n notification) error {
+	return postNotification(w.url, n)
 }
 
-func isRawDiffMetadataLine(line string) bool {
-	prefixes := []string{
-		"diff --git ",
-		"index ",
-		"--- ",
-		"+++ ",
-		"@@",
-		"new file mode ",
-		"deleted file mode ",
-		"old mode ",
-		"new mode ",
-		"similarity index ",
-		"rename from ",
-		"rename to ",
-		"Binary files ",
-		"GIT binary patch",
+const desktopMessageMax = 300
+
+// desktopNotifier shows a native notification through osascript on macOS or
+// notify-send on Linux.
+type desktopNotifier struct {
+	command string
+}
+
+// newDesktopNotifier is set up only for someone watching: YOKE_DESKTOP_NOTIFY
+// forces it on (1/true) or off (0/false); unset, it follows whether stdout
+// is a terminal.
+func newDesktopNotifier() (desktopNotifier, bool) {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("YOKE_DESKTOP_NOTIFY"))) {
+	case "1", "true", "yes", "on":
+	case "":
+		if !isInteractiveTerminal(os.Stdout) {
+			return desktopNotifier{}, false
+		}
+	default:
+		return desktopNotifier{}, false
 	}
-	for _, prefix := range prefixes {
-		if strings.HasPrefix(line, prefix) {
-			return true
+	command := "notify-send"
+	if runtime.GOOS == "darwin" {
+		command = "osascript"
+	}
+	if _, err := exec.LookPath(command); err != nil {
+		return desktopNotifier{}, false
+	}
+	return desktopNotifier{command: command}, true
+}
+
+// exportDesktopNotify lets a daemon started on a terminal notify from the
+// yoke commands its agents run, whose output is captured rather than a
+// terminal.
+func exportDesktopNotify() {
+	if strings.TrimSpace(os.Getenv("YOKE_DESKTOP_NOTIFY")) == "" && isInteractiveTerminal(os.Stdout) {
+		os.Setenv("YOKE_DESKTOP_NOTIFY", "1")
+	}
+}
+
+func (d desktopNotifier) Notify(n notification) error {
+	title := strings.TrimSpace("yoke: " + n.Event + " " + n.Issue)
+	message := truncateForPrompt(n.Message, desktopMessageMax)
+	return exec.Command(d.command, desktopNotifyArgs(d.command, title, message)...).Run()
+}
+
+// desktopNotifyArgs passes the text to osascript as arguments rather than
+// script source, so it needs no AppleScript quoting.
+func desktopNotifyArgs(command, title, message string) []string {
+	if command == "osascript" {
+		return []string{
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, message,
 		}
 	}
-	return false
+	return []string{"--app-name=yoke", title, message}
 }
 
-func isRawDiffHunkLine(line string) bool {
-	if strings.HasPrefix(line, "\\ No newline at end of file") {
-		return true
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
 	}
-	return strings.HasPrefix(line, "+") ||
-		strings.HasPrefix(line, "-") ||
-		strings.HasPrefix(line, " ")
+	return false
 }
 
-func notifyDaemonMaxIterationsReached(prefix string, maxIterations int) error {
-	issue, status, err := unresolvedConsensusIssue(prefix)
+func postNotification(url string, body any) error {
+	payload, err := json.Marshal(body)
 	if err != nil {
 		return err
 	}
-	if issue == "" {
-		return nil
+	client := &http.Client{Timeout: notifyTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("POST %s: %d %s", resp.Request.URL.Host, resp.StatusCode, valueOrFallback(strings.TrimSpace(string(data)), http.StatusText(resp.StatusCode)))
 	}
+	return nil
+}
 
-	note(fmt.Sprintf("warning: max iterations (%d) reached before consensus on %s (status: %s)", maxIterations, issue, status))
-	note("warning: leaving PR in draft/open state for manual intervention")
+// reviewSampleBucket maps an issue to a stable 0-99 bucket so a sampling
+// decision is the same on every daemon iteration and can be re-derived later.
+func reviewSampleBucket(issue string) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(strings.ToLower(strings.TrimSpace(issue))))
+	return int(hash.Sum32() % 100)
+}
 
-	number, _, isDraft, ok := openPRForIssue(issue)
-	if !ok {
-		return nil
+// lowRiskSampledOut reports whether a low-risk issue falls outside
+// YOKE_REVIEW_SAMPLE_RATE and is approved without a full agent review.
+func lowRiskSampledOut(cfg config, issue string) bool {
+	if cfg.ReviewSample >= 100 || len(cfg.LowRiskLabels) == 0 {
+		return false
 	}
-	if !isDraft {
-		note(fmt.Sprintf("warning: PR #%s is already ready (not draft) for %s", number, issue))
-		return nil
+	details, err := issueDetails(issue)
+	if err != nil {
+		return false
 	}
-
-	body := formatDaemonNoConsensusPRComment(issue, status, maxIterations)
-	if err := runCommand("gh", "pr", "comment", number, "--body", body); err != nil {
-		note("warning: failed to post no-consensus PR comment: " + err.Error())
-		return nil
+	lowRisk := false
+	for _, label := range cfg.LowRiskLabels {
+		if hasLabel(details.Labels, label) {
+			lowRisk = true
+			break
+		}
 	}
-	note("Posted no-consensus daemon comment to PR #" + number)
-	return nil
+	return lowRisk && reviewSampleBucket(issue) >= cfg.ReviewSample
 }
 
-func unresolvedConsensusIssue(prefix string) (string, string, error) {
-	reviewable := firstReviewableIssueID(prefix)
-	if reviewable != "" {
-		return reviewable, "in_review", nil
+// daemonSecurityReview runs the security pass ahead of the reviewer command
+// when YOKE_SECURITY_REVIEW is on, or when an earlier pass left the issue
+// labeled yoke:security-blocked. Critical or high findings reject the issue
+// back to the writer and report blocked.
+func daemonSecurityReview(root string, cfg config, issue, worktreePath string) (bool, error) {
+	if !cfg.SecurityReview {
+		details, err := issueDetails(issue)
+		if err != nil || !hasLabel(details.Labels, securityBlockedLabel) {
+			return false, nil
+		}
 	}
-
-	inProgress, err := firstIssueByStatus(prefix, "in_progress")
+	progress.Start("daemon.security", "Running security review for "+issue)
+	report, err := runSecurityReview(root, cfg, issue, worktreePath, true)
 	if err != nil {
-		return "", "", err
+		progress.Fail("daemon.security", err)
+		return false, err
 	}
-	if inProgress != "" {
-		return inProgress, "in_progress", nil
+	blocking := report.blockingFindings()
+	if len(blocking) == 0 {
+		progress.Finish("daemon.security", "No critical or high security findings.")
+		return false, nil
 	}
-	return "", "", nil
+	progress.Finish("daemon.security", fmt.Sprintf("%d critical/high security finding(s); returning %s to the writer.", len(blocking), issue))
+	reasons := make([]string, 0, len(blocking))
+	for _, finding := range blocking {
+		reasons = append(reasons, formatSecurityFinding(finding))
+	}
+	return true, cmdReview([]string{issue, "--reject", "Security review: " + strings.Join(reasons, "; ")})
 }
 
-func focusedOrInProgressIssueID(root, prefix string) (string, error) {
-	focused := focusedIssueByWorkflowStatus(root, prefix, "in_progress")
-	if focused != "" {
-		return focused, nil
+// fastTrackReview approves a sampled-out low-risk issue once checks pass in
+// its worktree, labeling it yoke:audit-pending for a later batch audit.
+// Failing checks reject it back to the writer as a full review would.
+func fastTrackReview(root string, cfg config, issue, worktreePath string) (string, error) {
+	progress.Info("daemon.review", fmt.Sprintf("Fast-tracking low-risk %s (sample rate %d%%): running checks instead of the reviewer agent.", issue, cfg.ReviewSample))
+	if results, err := runCachedCheckPlan(worktreePath, issueCheckPlan(root, worktreePath, cfg, issue, ""), true); err != nil {
+		err = recordCheckFailure(root, issue, results, err)
+		reason, _, _ := strings.Cut(err.Error(), "\n")
+		if rejectErr := cmdReview([]string{issue, "--reject", "Fast-track checks failed: " + reason}); rejectErr != nil {
+			return "", rejectErr
+		}
+		return "rejected " + issue, nil
 	}
-	return firstIssueByStatus(prefix, "in_progress")
+	if err := tracker.Update(issue, issueChange{AddLabels: []string{auditPendingLabel}}); err != nil {
+		return "", err
+	}
+	auditNote := fmt.Sprintf("Fast-tracked without agent review: low-risk issue outside the %d%% review sample; checks passed. Pending batch audit (label %s).", cfg.ReviewSample, auditPendingLabel)
+	if err := cmdReview([]string{issue, "--approve", "--note", auditNote}); err != nil {
+		return "", err
+	}
+	return "fast-tracked " + issue, nil
 }
 
-func focusedIssueByWorkflowStatus(root, prefix, desiredStatus string) string {
-	branchIssue := currentBranchIssue(prefix)
-	if branchIssue != "" {
-		status, err := issueStatus(branchIssue)
-		if err == nil && status == desiredStatus {
-			return branchIssue
+const maxHumanReviewDiffBytes = 200 * 1024
+
+// reviewNextHumanPR reviews the oldest open PR labeled yoke:review-me with the
+// configured reviewer agent. No bd issue is involved; the label is removed
+// once the review is posted, so re-adding it requests another pass.
+func reviewNextHumanPR(root string, cfg config) (string, error) {
+	if !commandExists("gh") || !hasOriginRemote() {
+		return "", nil
+	}
+	client, err := githubAPI()
+	if err != nil {
+		return "", nil
+	}
+	pulls, err := client.listPulls(url.Values{"state": {"open"}}, nil)
+	if err != nil {
+		return "", fmt.Errorf("list open PRs: %w", err)
+	}
+	prs := []prListEntry{}
+	for _, pull := range pulls {
+		if hasLabel(pull.labelNames(), humanReviewLabel) {
+			prs = append(prs, pull.entry())
 		}
 	}
+	if len(prs) == 0 {
+		return "", nil
+	}
+	sort.Slice(prs, func(i, j int) bool { return prs[i].Number < prs[j].Number })
+	pr := prs[0]
+	prNumber := strconv.Itoa(pr.Number)
 
-	focused := daemonFocusedIssue(root)
-	if focused == "" {
-		return ""
+	agentID, err := agentIDForRole(cfg, "reviewer")
+	if err != nil {
+		return "", err
 	}
-	status, err := issueStatus(focused)
+	title := strings.TrimSpace(commandCombinedOutput("gh", "pr", "view", prNumber, "--json", "title", "--jq", ".title"))
+	diff, err := commandOutput("gh", "pr", "diff", prNumber)
 	if err != nil {
-		clearDaemonFocusIssue(root)
-		return ""
+		return "", fmt.Errorf("load diff for PR #%s: %w", prNumber, err)
 	}
-	if status == desiredStatus {
-		return focused
+
+	note(fmt.Sprintf("Daemon reviewing human PR #%s (%s) with %s", prNumber, valueOrFallback(title, pr.HeadRefName), agentID))
+	issueKey := "pr-" + prNumber
+	started := time.Now()
+	review, runErr := runAgentPrompt(agentID, root, buildHumanPRReviewPrompt(prNumber, title, diff), []string{
+		"ROOT_DIR=" + root,
+		"YOKE_ROLE=reviewer",
+		"YOKE_PR_NUMBER=" + prNumber,
+	}, "[daemon][pr #"+prNumber+"] ")
+	recordSessionLog(root, sessionLogEntry{IssueID: issueKey, Role: "reviewer", Source: "human-pr-review", AgentID: agentID}, started, review, runErr)
+	recordAgentUsage(root, usageRecord{IssueID: issueKey, Role: "reviewer", AgentID: agentID}, review)
+	if runErr != nil {
+		return "", fmt.Errorf("reviewer agent failed for PR #%s: %w", prNumber, runErr)
 	}
-	if status != "in_progress" && status != "in_review" {
-		clearDaemonFocusIssue(root)
+
+	verdict := parseHumanPRReviewVerdict(review)
+	event := "--comment"
+	if verdict == "request-changes" {
+		event = "--request-changes"
 	}
-	return ""
+	if err := runCommand("gh", "pr", "review", prNumber, event, "--body", formatHumanPRReview(verdict, processAgentOutput(review))); err != nil {
+		return "", err
+	}
+	if err := runCommand("gh", "pr", "edit", prNumber, "--remove-label", humanReviewLabel); err != nil {
+		note("warning: failed to remove " + humanReviewLabel + " from PR #" + prNumber + ": " + err.Error())
+	}
+	return prNumber, nil
 }
 
-func daemonFocusPath(root string) string {
-	return filepath.Join(root, ".yoke", daemonFocusFile)
+func buildHumanPRReviewPrompt(prNumber, title, diff string) string {
+	truncated := ""
+	if len(diff) > maxHumanReviewDiffBytes {
+		diff = diff[:maxHumanReviewDiffBytes]
+		truncated = "\n(diff truncated; review what is shown and say so)\n"
+	}
+	lines := []string{
+		"You are reviewing a human-authored pull request. Do not modify files, push, or run yoke commands.",
+		fmt.Sprintf("PR #%s: %s", prNumber, valueOrFallback(title, "(untitled)")),
+		"",
+		"Respond in exactly this structure:",
+		"Verdict: approve | request-changes | comment",
+		"Summary: one paragraph",
+		"Findings:",
+		"- file:line - problem and suggested fix (or \"- none\")",
+		"",
+		"Diff:",
+		"```diff",
+		diff,
+		"```" + truncated,
+	}
+	return strings.Join(lines, "\n")
 }
 
-func daemonFocusedIssue(root string) string {
-	path := daemonFocusPath(root)
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return ""
+func parseHumanPRReviewVerdict(review string) string {
+	for _, line := range strings.Split(review, "\n") {
+		lower := strings.ToLower(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "*#- ")))
+		lower = strings.ReplaceAll(lower, "*", "")
+		if !strings.HasPrefix(lower, "verdict:") {
+			continue
+		}
+		value := strings.Trim(strings.TrimSpace(strings.TrimPrefix(lower, "verdict:")), "`. ")
+		switch {
+		case strings.HasPrefix(value, "request-changes"), strings.HasPrefix(value, "request changes"):
+			return "request-changes"
+		case strings.HasPrefix(value, "approve"):
+			return "approve"
+		default:
+			return "comment"
+		}
 	}
-	return strings.ToLower(strings.TrimSpace(string(data)))
+	return "comment"
 }
 
-func writeDaemonFocusIssue(root, issue string) error {
-	path := daemonFocusPath(root)
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return err
-	}
-	value := strings.ToLower(strings.TrimSpace(issue))
-	if value == "" {
-		return errors.New("focus issue cannot be empty")
+func formatHumanPRReview(verdict, review string) string {
+	lines := []string{
+		"## Reviewer Agent",
+		"",
+		"- Verdict: " + verdict,
+		"",
+		strings.TrimSpace(review),
+		"",
+		"_Posted automatically by `yoke daemon --review-prs`; re-add `" + humanReviewLabel + "` to request another pass._",
 	}
-	return os.WriteFile(path, []byte(value+"\n"), 0o644)
+	return strings.Join(lines, "\n")
 }
 
-func clearDaemonFocusIssue(root string) {
-	_ = os.Remove(daemonFocusPath(root))
+const defaultAuditSample = 3
+
+// mergedPREntry is one row of gh pr list --state merged.
+type mergedPREntry struct {
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	HeadRefName string `json:"headRefName"`
+	MergedAt    string `json:"mergedAt"`
 }
 
-func worktreePathForIssue(root, issue string) string {
-	return filepath.Join(root, ".yoke", "worktrees", sanitizePathSegment(issue))
+type auditCandidate struct {
+	PR     mergedPREntry
+	Issue  string
+	Labels []string
+	Reason string
 }
 
-type gitWorktreeEntry struct {
-	Path   string
-	Branch string
+type auditResult struct {
+	PR       int      `json:"pr"`
+	Issue    string   `json:"issue"`
+	Title    string   `json:"title"`
+	MergedAt string   `json:"merged_at"`
+	Reason   string   `json:"reason"`
+	Verdict  string   `json:"verdict"`
+	Findings []string `json:"findings"`
+	Filed    []string `json:"filed,omitempty"`
+	Error    string   `json:"error,omitempty"`
 }
 
-func parseGitWorktreeListEntries(raw string) []gitWorktreeEntry {
-	trimmed := strings.TrimSpace(raw)
-	if trimmed == "" {
+func cmdAuditReview(args []string) error {
+	var (
+		since    = 7 * 24 * time.Hour
+		sample   = defaultAuditSample
+		agentArg string
+		model    string
+		dryRun   bool
+		jsonOut  bool
+	)
+	parsed, err := parseCommandArgs("audit-review", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printAuditReviewUsage()
 		return nil
 	}
-
-	lines := strings.Split(trimmed, "\n")
-	entries := make([]gitWorktreeEntry, 0)
-	current := gitWorktreeEntry{}
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		switch {
-		case strings.HasPrefix(line, "worktree "):
-			if strings.TrimSpace(current.Path) != "" {
-				entries = append(entries, current)
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown audit-review argument: %s", parsed.Positionals[0])
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--since":
+			window, err := parseRetention(flag.Value)
+			if err != nil {
+				return fmt.Errorf("invalid --since value: %q", flag.Value)
 			}
-			current = gitWorktreeEntry{
-				Path: strings.TrimSpace(strings.TrimPrefix(line, "worktree ")),
+			since = window
+		case "--sample":
+			n, err := strconv.Atoi(flag.Value)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid --sample value: %q", flag.Value)
 			}
-		case strings.HasPrefix(line, "branch "):
-			ref := strings.TrimSpace(strings.TrimPrefix(line, "branch "))
-			current.Branch = strings.TrimPrefix(ref, "refs/heads/")
-		default:
-			continue
+			sample = n
+		case "--agent":
+			agentArg = flag.Value
+		case "--model":
+			model = flag.Value
+		case "--dry-run":
+			dryRun = true
+		case "--json":
+			jsonOut = true
 		}
 	}
-	if strings.TrimSpace(current.Path) != "" {
-		entries = append(entries, current)
-	}
-	return entries
-}
 
-func parseGitWorktreeListPorcelain(raw string) []string {
-	entries := parseGitWorktreeListEntries(raw)
-	paths := make([]string, 0, len(entries))
-	for _, entry := range entries {
-		if strings.TrimSpace(entry.Path) == "" {
-			continue
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+	if !commandExists("gh") {
+		return fmt.Errorf("missing required command: gh")
+	}
+	if !hasOriginRemote() {
+		return errors.New("audit-review needs an origin remote to find merged PRs")
+	}
+	agentID := agentArg
+	if agentID == "" {
+		if agentID, err = agentIDForRole(cfg, "reviewer"); err != nil {
+			return err
 		}
-		paths = append(paths, entry.Path)
 	}
-	return paths
-}
-
-func worktreeRegistered(root, path string) bool {
-	output := commandCombinedOutput("git", "-C", root, "worktree", "list", "--porcelain")
-	paths := parseGitWorktreeListPorcelain(output)
+	if model == "" {
+		model = cfg.AuditModel
+	}
 
-	targetAbs, err := filepath.Abs(path)
+	cutoff := time.Now().Add(-since)
+	client, err := githubAPI()
 	if err != nil {
-		targetAbs = filepath.Clean(path)
+		return err
 	}
-	for _, listed := range paths {
-		listedAbs, pathErr := filepath.Abs(listed)
-		if pathErr != nil {
-			listedAbs = filepath.Clean(listed)
+	pulls, err := client.listPulls(url.Values{"state": {"closed"}, "sort": {"updated"}, "direction": {"desc"}}, func(pull githubPull) bool {
+		updated, err := time.Parse(time.RFC3339, pull.Updated)
+		return err == nil && updated.Before(cutoff)
+	})
+	if err != nil {
+		return fmt.Errorf("list merged PRs: %w", err)
+	}
+	merged := []mergedPREntry{}
+	for _, pull := range pulls {
+		if pull.MergedAt != "" {
+			merged = append(merged, mergedPREntry{Number: pull.Number, Title: pull.Title, HeadRefName: pull.Head.Ref, MergedAt: pull.MergedAt})
 		}
-		if listedAbs == targetAbs {
-			return true
+	}
+	candidates := []auditCandidate{}
+	for _, pr := range merged {
+		if !strings.HasPrefix(pr.HeadRefName, "yoke/") {
+			continue
+		}
+		if mergedAt, err := time.Parse(time.RFC3339, pr.MergedAt); err == nil && mergedAt.Before(cutoff) {
+			continue
+		}
+		issue := strings.TrimPrefix(pr.HeadRefName, "yoke/")
+		details, err := issueDetails(issue)
+		if err != nil {
+			continue
 		}
+		candidates = append(candidates, auditCandidate{PR: pr, Issue: issue, Labels: details.Labels})
 	}
-	return false
-}
+	selected := selectAuditCandidates(candidates, sample)
 
-func worktreePathForBranch(root, branch string) string {
-	output := commandCombinedOutput("git", "-C", root, "worktree", "list", "--porcelain")
-	entries := parseGitWorktreeListEntries(output)
-	for _, entry := range entries {
-		if strings.EqualFold(strings.TrimSpace(entry.Branch), strings.TrimSpace(branch)) {
-			return strings.TrimSpace(entry.Path)
+	if dryRun || len(selected) == 0 {
+		if jsonOut {
+			results := make([]auditResult, 0, len(selected))
+			for _, candidate := range selected {
+				results = append(results, auditResult{PR: candidate.PR.Number, Issue: candidate.Issue, Title: candidate.PR.Title, MergedAt: candidate.PR.MergedAt, Reason: candidate.Reason, Verdict: "pending", Findings: []string{}})
+			}
+			return printAuditResultsJSON(results)
+		}
+		if len(selected) == 0 {
+			note(fmt.Sprintf("No merged yoke PRs to audit since %s.", cutoff.Format("2006-01-02")))
+			return nil
 		}
+		note(fmt.Sprintf("Would audit %d PR(s) with %s%s:", len(selected), agentID, auditModelSuffix(model)))
+		for _, candidate := range selected {
+			fmt.Printf("  #%d %s (%s) %s\n", candidate.PR.Number, candidate.Issue, candidate.Reason, candidate.PR.Title)
+		}
+		return nil
 	}
-	return ""
-}
 
-func localOrRemoteRef(branch string) string {
-	ref := strings.TrimSpace(branch)
-	if ref == "" {
-		return ""
+	results := make([]auditResult, 0, len(selected))
+	for _, candidate := range selected {
+		results = append(results, auditMergedPR(root, agentID, model, candidate))
 	}
-	if refExists("refs/heads/" + ref) {
-		return ref
-	}
-	if refExists("refs/remotes/origin/" + ref) {
-		return "origin/" + ref
+	if jsonOut {
+		return printAuditResultsJSON(results)
 	}
-	return ""
+	fmt.Print(formatAuditReport(results))
+	return nil
 }
 
-func ensureLocalBranch(root, branch, defaultStart string) error {
-	target := strings.TrimSpace(branch)
-	if target == "" {
-		return errors.New("branch name cannot be empty")
-	}
-	if refExists("refs/heads/" + target) {
-		return nil
-	}
-	if refExists("refs/remotes/origin/" + target) {
-		return runCommand("git", "-C", root, "branch", target, "origin/"+target)
-	}
-
-	startPoint := localOrRemoteRef(defaultStart)
-	if startPoint == "" {
-		startPoint = "HEAD"
+// selectAuditCandidates keeps every fast-tracked (yoke:audit-pending) PR and
+// fills up to sample more from the most recently merged PRs not yet audited.
+func selectAuditCandidates(candidates []auditCandidate, sample int) []auditCandidate {
+	sorted := append([]auditCandidate(nil), candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].PR.MergedAt > sorted[j].PR.MergedAt })
+	selected := []auditCandidate{}
+	sampled := 0
+	for _, candidate := range sorted {
+		switch {
+		case hasLabel(candidate.Labels, auditPendingLabel):
+			candidate.Reason = "audit-pending"
+		case hasLabel(candidate.Labels, auditedLabel) || sampled >= sample:
+			continue
+		default:
+			candidate.Reason = "sampled"
+			sampled++
+		}
+		selected = append(selected, candidate)
 	}
-	return runCommand("git", "-C", root, "branch", target, startPoint)
+	return selected
 }
 
-func issueBranchStartPoint(root string, cfg config, issue string) (string, error) {
-	epicID, err := epicAncestorID(issue)
+func auditMergedPR(root, agentID, model string, candidate auditCandidate) auditResult {
+	prNumber := strconv.Itoa(candidate.PR.Number)
+	result := auditResult{PR: candidate.PR.Number, Issue: candidate.Issue, Title: candidate.PR.Title, MergedAt: candidate.PR.MergedAt, Reason: candidate.Reason, Findings: []string{}}
+	diff, err := commandOutput("gh", "pr", "diff", prNumber)
 	if err != nil {
-		return "", err
-	}
-	if epicID != "" && !strings.EqualFold(strings.TrimSpace(epicID), strings.TrimSpace(issue)) {
-		epicBranch := branchForIssue(epicID)
-		if err := ensureLocalBranch(root, epicBranch, cfg.BaseBranch); err != nil {
-			return "", err
-		}
-		return epicBranch, nil
-	}
-
-	startPoint := localOrRemoteRef(cfg.BaseBranch)
-	if startPoint == "" {
-		startPoint = "HEAD"
+		result.Verdict = "error"
+		result.Error = "load diff: " + err.Error()
+		return result
 	}
-	return startPoint, nil
-}
 
-func ensureIssueWorktree(root string, cfg config, issue string) (string, error) {
-	branch := branchForIssue(issue)
-	worktreePath := worktreePathForIssue(root, issue)
-
-	if err := os.MkdirAll(filepath.Dir(worktreePath), 0o755); err != nil {
-		return "", err
+	note(fmt.Sprintf("Auditing PR #%s (%s) with %s%s", prNumber, candidate.Issue, agentID, auditModelSuffix(model)))
+	started := time.Now()
+	output, runErr := runAgentPromptWithModel(agentID, model, root, buildAuditReviewPrompt(candidate, diff), []string{
+		"ROOT_DIR=" + root,
+		"ISSUE_ID=" + candidate.Issue,
+		"YOKE_ROLE=auditor",
+		"YOKE_PR_NUMBER=" + prNumber,
+	}, "[audit]["+candidate.Issue+"] ")
+	recordSessionLog(root, sessionLogEntry{IssueID: candidate.Issue, Role: "auditor", Source: "audit-review", AgentID: agentID}, started, output, runErr)
+	recordAgentUsage(root, usageRecord{IssueID: candidate.Issue, Role: "auditor", AgentID: agentID}, output)
+	if runErr != nil {
+		result.Verdict = "error"
+		result.Error = "audit agent failed: " + runErr.Error()
+		return result
 	}
 
-	if existingPath := worktreePathForBranch(root, branch); strings.TrimSpace(existingPath) != "" {
-		return existingPath, nil
+	result.Findings = parseAuditFindings(output)
+	result.Verdict = "clean"
+	if len(result.Findings) > 0 {
+		result.Verdict = "problems"
 	}
-
-	if !worktreeRegistered(root, worktreePath) {
-		if refExists("refs/heads/" + branch) {
-			if err := runCommand("git", "-C", root, "worktree", "add", worktreePath, branch); err != nil {
-				return "", err
-			}
-		} else {
-			startPoint, err := issueBranchStartPoint(root, cfg, issue)
-			if err != nil {
-				return "", err
-			}
-			if err := runCommand("git", "-C", root, "worktree", "add", "-b", branch, worktreePath, startPoint); err != nil {
-				return "", err
-			}
+	for _, finding := range result.Findings {
+		filed, err := fileAuditFinding(candidate, finding)
+		if err != nil {
+			note("warning: failed to file audit finding for " + candidate.Issue + ": " + err.Error())
+			continue
 		}
-		return worktreePath, nil
+		result.Filed = append(result.Filed, filed)
+	}
+	if err := tracker.Comment(candidate.Issue, formatAuditComment(result)); err != nil {
+		note("warning: failed to record audit result on " + candidate.Issue + ": " + err.Error())
 	}
+	if err := tracker.Update(candidate.Issue, issueChange{AddLabels: []string{auditedLabel}, RemoveLabels: []string{auditPendingLabel}}); err != nil {
+		note("warning: failed to update audit labels on " + candidate.Issue + ": " + err.Error())
+	}
+	return result
+}
 
-	current := strings.TrimSpace(commandCombinedOutput("git", "-C", worktreePath, "rev-parse", "--abbrev-ref", "HEAD"))
-	if current == "" {
-		return "", fmt.Errorf("could not determine current branch for worktree %s", worktreePath)
+func buildAuditReviewPrompt(candidate auditCandidate, diff string) string {
+	truncated := ""
+	if len(diff) > maxHumanReviewDiffBytes {
+		diff = diff[:maxHumanReviewDiffBytes]
+		truncated = "\n(diff truncated; audit what is shown and say so)\n"
 	}
-	if current == branch {
-		return worktreePath, nil
+	lines := []string{
+		"You are auditing a change that has already been merged. Do not modify files, push, or run yoke commands.",
+		fmt.Sprintf("Issue %s, PR #%d: %s", candidate.Issue, candidate.PR.Number, valueOrFallback(candidate.PR.Title, "(untitled)")),
+		"Look for correctness bugs, security problems, missing tests, and regressions. Ignore style nits.",
+		"",
+		"Respond in exactly this structure:",
+		"Summary: one paragraph",
+		"Findings:",
+		"- file:line - problem and suggested fix (or \"- none\")",
+		"",
+		"Diff:",
+		"```diff",
+		diff,
+		"```" + truncated,
 	}
+	return strings.Join(lines, "\n")
+}
 
-	if refExists("refs/heads/" + branch) {
-		if err := runCommand("git", "-C", worktreePath, "switch", branch); err != nil {
-			return "", err
+// parseAuditFindings returns the bullet items under the Findings: heading,
+// dropping a lone "none".
+func parseAuditFindings(output string) []string {
+	findings := []string{}
+	inFindings := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		heading := strings.ToLower(strings.ReplaceAll(strings.TrimLeft(trimmed, "#* "), "*", ""))
+		if strings.HasPrefix(heading, "findings:") {
+			inFindings = true
+			continue
 		}
-	} else {
-		if err := runCommand("git", "-C", worktreePath, "switch", "-c", branch); err != nil {
-			return "", err
+		if !inFindings || trimmed == "" {
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "-") && !strings.HasPrefix(trimmed, "*") {
+			if strings.Contains(heading, ":") && !strings.HasPrefix(heading, "- ") {
+				inFindings = false
+			}
+			continue
 		}
+		item := strings.TrimSpace(strings.TrimLeft(trimmed, "-* "))
+		if item == "" || strings.EqualFold(strings.Trim(item, "."), "none") {
+			continue
+		}
+		findings = append(findings, item)
 	}
-	return worktreePath, nil
-}
-
-type bdListIssue struct {
-	ID             string   `json:"id"`
-	Title          string   `json:"title"`
-	Status         string   `json:"status"`
-	IssueType      string   `json:"issue_type"`
-	Parent         string   `json:"parent"`
-	Labels         []string `json:"labels"`
-	CommentCount   int      `json:"comment_count"`
-	DependencyType string   `json:"dependency_type"`
-}
-
-type bdDependencyEdge struct {
-	IssueID     string `json:"issue_id"`
-	DependsOnID string `json:"depends_on_id"`
-	Type        string `json:"type"`
+	return findings
 }
 
-type bdIssueWithDependencies struct {
-	ID           string             `json:"id"`
-	Dependencies []bdDependencyEdge `json:"dependencies"`
-}
-
-type bdComment struct {
-	ID        int    `json:"id"`
-	IssueID   string `json:"issue_id"`
-	Author    string `json:"author"`
-	Text      string `json:"text"`
-	CreatedAt string `json:"created_at"`
-}
-
-type clarificationContext struct {
-	IssueID  string
-	Title    string
-	Comments []bdComment
-}
-
-func firstIssueByStatus(prefix, status string) (string, error) {
-	if strings.EqualFold(strings.TrimSpace(status), "in_review") {
-		return firstReviewableIssueID(prefix), nil
-	}
-
-	output := commandCombinedOutput("bd", "list", "--status", status, "--json", "--limit", "20")
-	issues, err := parseBDListIssuesJSON(output)
+func fileAuditFinding(candidate auditCandidate, finding string) (string, error) {
+	title := truncateTitle("Audit: " + sanitizeCommentLine(finding))
+	description := strings.Join([]string{
+		fmt.Sprintf("Found by yoke audit-review in PR #%d (%s, merged %s).", candidate.PR.Number, candidate.Issue, valueOrFallback(candidate.PR.MergedAt, "unknown")),
+		"",
+		finding,
+	}, "\n")
+	created, err := tracker.Create(newIssue{Title: title, Type: "bug", Description: description})
 	if err != nil {
 		return "", err
 	}
-	return firstMatchingIssueID(issues, prefix, status), nil
+	return created.ID, nil
 }
 
-func parseBDListIssuesJSON(raw string) ([]bdListIssue, error) {
-	trimmed := strings.TrimSpace(raw)
-	if trimmed == "" || trimmed == "null" {
-		return nil, nil
-	}
-
-	var issues []bdListIssue
-	if err := json.Unmarshal([]byte(trimmed), &issues); err != nil {
-		return nil, fmt.Errorf("parse bd list json: %w", err)
+func formatAuditComment(result auditResult) string {
+	lines := []string{
+		"Batch audit (yoke audit-review):",
+		fmt.Sprintf("- PR: #%d", result.PR),
+		"- Selected: " + result.Reason,
+		"- Verdict: " + result.Verdict,
 	}
-	return issues, nil
-}
-
-func parseBDCommentsJSON(raw string) ([]bdComment, error) {
-	trimmed := strings.TrimSpace(raw)
-	if trimmed == "" || trimmed == "null" {
-		return nil, nil
+	if len(result.Filed) > 0 {
+		lines = append(lines, "- Filed: "+strings.Join(result.Filed, ", "))
 	}
-
-	var comments []bdComment
-	if err := json.Unmarshal([]byte(trimmed), &comments); err != nil {
-		return nil, fmt.Errorf("parse bd comments json: %w", err)
+	for _, finding := range result.Findings {
+		lines = append(lines, "- Finding: "+sanitizeCommentLine(finding))
 	}
-	return comments, nil
+	return strings.Join(lines, "\n")
 }
 
-func firstMatchingIssueID(issues []bdListIssue, prefix, status string) string {
-	targetStatus := strings.ToLower(strings.TrimSpace(status))
-	for _, issue := range issues {
-		issueID := strings.ToLower(strings.TrimSpace(issue.ID))
-		issueStatus := workflowStatusForIssue(issue)
-		if issueID == "" {
-			continue
-		}
-		if targetStatus != "" && issueStatus != targetStatus {
-			continue
+func formatAuditReport(results []auditResult) string {
+	var b strings.Builder
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Verdict]++
+		line := fmt.Sprintf("#%d %s [%s] %s", result.PR, result.Issue, result.Reason, result.Verdict)
+		switch {
+		case result.Error != "":
+			line += ": " + result.Error
+		case len(result.Findings) > 0:
+			line += fmt.Sprintf(": %d finding(s)", len(result.Findings))
+			if len(result.Filed) > 0 {
+				line += ", filed " + strings.Join(result.Filed, ", ")
+			}
 		}
-		if looksLikeIssueID(issueID, prefix) {
-			return issueID
+		b.WriteString(line + "\n")
+		for _, finding := range result.Findings {
+			b.WriteString("  - " + finding + "\n")
 		}
 	}
-	return ""
+	b.WriteString(fmt.Sprintf("Audited %d PR(s): %d clean, %d with problems, %d failed.\n", len(results), counts["clean"], counts["problems"], counts["error"]))
+	return b.String()
 }
 
-func issueStatus(issue string) (string, error) {
-	output := commandCombinedOutput("bd", "show", issue, "--json")
-	return parseIssueStatusJSON(output)
+func printAuditResultsJSON(results []auditResult) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
 }
 
-func issueDetails(issue string) (bdListIssue, error) {
-	output := commandCombinedOutput("bd", "show", issue, "--json")
-	return parseBDShowIssueJSON(output)
+func auditModelSuffix(model string) string {
+	if model == "" {
+		return ""
+	}
+	return " (model " + model + ")"
 }
 
-func epicAncestorID(issue string) (string, error) {
-	current := strings.TrimSpace(issue)
-	if current == "" {
-		return "", nil
+func runDaemonRoleCommand(ctx context.Context, role, issue, shellCommand, worktreeRoot, mainRoot string, cfg config) error {
+	previousStatus, err := issueStatus(issue)
+	if err != nil {
+		return err
 	}
 
-	visited := make(map[string]struct{})
-	for depth := 0; depth < 50; depth++ {
-		if _, ok := visited[current]; ok {
-			return "", fmt.Errorf("detected parent-cycle while resolving epic ancestor for %s", issue)
-		}
-		visited[current] = struct{}{}
+	augmentedCommand := daemonCommandWithExtraWritableDir(shellCommand)
+	note(fmt.Sprintf("Daemon running %s command for %s", role, issue))
+	cmd := exec.CommandContext(ctx, "bash", "-lc", augmentedCommand)
+	// Agents that outlive a cancelled command must not hold the output
+	// pipes open.
+	cmd.WaitDelay = 5 * time.Second
+	filteredOutput := newDaemonLogFilterWriter(os.Stdout)
+	var captured synchronizedBuffer
+	live := openLiveAgentLog(mainRoot, issue, role, "daemon")
+	cmd.Stdout = io.MultiWriter(filteredOutput, &captured, live)
+	cmd.Stderr = io.MultiWriter(filteredOutput, &captured, live)
+	cmd.Dir = worktreeRoot
+	cmd.Env = roleCommandEnv(os.Environ(), cfg, role, issue, worktreeRoot, mainRoot)
+	started := time.Now()
+	runErr := cmd.Run()
+	live.Finish(runErr)
+	flushErr := filteredOutput.Flush()
+	recordSessionLog(mainRoot, sessionLogEntry{IssueID: issue, Role: role, Source: "daemon", Command: shellCommand}, started, captured.String(), runErr)
+	epicID, _ := epicAncestorID(issue)
+	recordAgentUsage(mainRoot, usageRecord{IssueID: issue, EpicID: epicID, Role: role}, captured.String())
+	if runErr != nil {
+		return runErr
+	}
+	if flushErr != nil {
+		return flushErr
+	}
 
-		details, err := issueDetails(current)
+	currentStatus, err := issueStatus(issue)
+	if err != nil {
+		return err
+	}
+	if currentStatus == previousStatus && role == "reviewer" {
+		verdict, ok, err := parseReviewVerdict(captured.String())
 		if err != nil {
-			return "", err
+			return fmt.Errorf("invalid reviewer verdict for %s: %w", issue, err)
 		}
-		if strings.EqualFold(strings.TrimSpace(details.IssueType), "epic") {
-			return strings.TrimSpace(details.ID), nil
+		if ok {
+			note(fmt.Sprintf("Daemon applying reviewer verdict for %s: %s", issue, verdict.Decision))
+			if err := applyReviewVerdict(mainRoot, issue, verdict); err != nil {
+				return err
+			}
+			if currentStatus, err = issueStatus(issue); err != nil {
+				return err
+			}
 		}
-		parent := strings.TrimSpace(details.Parent)
-		if parent == "" {
-			return "", nil
+	}
+	if currentStatus == previousStatus && role == "reviewer" {
+		if details, err := issueDetails(issue); err == nil && hasLabel(details.Labels, awaitingApprovalLabel) {
+			note(fmt.Sprintf("Daemon left %s for human approval (label %s)", issue, awaitingApprovalLabel))
+			return nil
 		}
-		current = parent
 	}
-	return "", fmt.Errorf("parent chain too deep while resolving epic ancestor for %s", issue)
-}
-
-func parseIssueStatusJSON(raw string) (string, error) {
-	issue, err := parseBDShowIssueJSON(raw)
-	if err != nil {
-		return "", err
+	if currentStatus == previousStatus {
+		return fmt.Errorf("%s command did not advance issue %s (still %s); ensure the command transitions bd state or emits a verdict", role, issue, currentStatus)
 	}
 
-	status := workflowStatusForIssue(issue)
-	if status == "" {
-		return "", errors.New("issue payload missing status")
-	}
-	return status, nil
+	note(fmt.Sprintf("Daemon observed %s status transition: %s -> %s", issue, previousStatus, currentStatus))
+	return nil
 }
 
-func parseBDShowIssueJSON(raw string) (bdListIssue, error) {
-	trimmed := strings.TrimSpace(raw)
-	if trimmed == "" {
-		return bdListIssue{}, errors.New("empty issue payload")
+// roleCommandEnv is the environment yoke daemon runs a writer or reviewer
+// command with, and the one yoke env prints: daemonCommandEnv plus, for
+// reviewers, the review diff.
+func roleCommandEnv(base []string, cfg config, role, issue, worktreeRoot, mainRoot string) []string {
+	env := daemonCommandEnv(base, issue, worktreeRoot, mainRoot, cfg.BDPrefix, role)
+	if role == "reviewer" {
+		env = append(env, reviewDiffEnv(mainRoot, cfg, issue, worktreeRoot)...)
 	}
+	return env
+}
 
-	var listPayload []bdListIssue
-	if err := json.Unmarshal([]byte(trimmed), &listPayload); err == nil {
-		if len(listPayload) == 0 {
-			return bdListIssue{}, errors.New("issue payload missing issue data")
-		}
-		return listPayload[0], nil
-	}
-
-	var singlePayload bdListIssue
-	if err := json.Unmarshal([]byte(trimmed), &singlePayload); err != nil {
-		return bdListIssue{}, fmt.Errorf("parse bd show json: %w", err)
+func daemonCommandEnv(base []string, issue, worktreeRoot, mainRoot, bdPrefix, role string) []string {
+	env := append([]string{}, base...)
+	env = append(env,
+		"ISSUE_ID="+issue,
+		"ROOT_DIR="+worktreeRoot,
+		"YOKE_MAIN_ROOT="+mainRoot,
+		"BD_PREFIX="+bdPrefix,
+		"YOKE_ROLE="+role,
+	)
+	if role == "reviewer" {
+		env = append(env, "YOKE_REVIEW_VERDICT_SCHEMA="+reviewVerdictSchema)
 	}
-	if strings.TrimSpace(singlePayload.ID) == "" {
-		return bdListIssue{}, errors.New("issue payload missing issue data")
+	binDirs := []string{
+		filepath.Join(mainRoot, "bin"),
+		filepath.Join(worktreeRoot, "bin"),
 	}
-	return singlePayload, nil
+	env = appendOrPrependPath(env, binDirs...)
+	return env
 }
 
-func listIssuesByStatus(status string, readyOnly bool) ([]bdListIssue, error) {
-	args := []string{"list", "--status", status, "--json", "--limit", "0"}
-	if readyOnly {
-		args = append(args, "--ready")
+func appendOrPrependPath(env []string, entries ...string) []string {
+	filtered := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	if len(filtered) == 0 {
+		return env
 	}
 
-	output := commandCombinedOutput("bd", args...)
-	return parseBDListIssuesJSON(output)
+	pathIndex := -1
+	pathValue := ""
+	for i, item := range env {
+		if strings.HasPrefix(item, "PATH=") {
+			pathIndex = i
+			pathValue = strings.TrimPrefix(item, "PATH=")
+			break
+		}
+	}
+	joined := strings.Join(filtered, string(os.PathListSeparator))
+	if strings.TrimSpace(pathValue) != "" {
+		joined = joined + string(os.PathListSeparator) + pathValue
+	}
+	if pathIndex >= 0 {
+		env[pathIndex] = "PATH=" + joined
+		return env
+	}
+	return append(env, "PATH="+joined)
 }
 
-func listChildIssues(parent string) ([]bdListIssue, error) {
-	output := commandCombinedOutput("bd", "children", parent, "--json")
-	return parseBDListIssuesJSON(output)
-}
+// sensitiveEnvKeyMarkers flag variables whose values yoke env redacts.
+var sensitiveEnvKeyMarkers = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL", "API_KEY", "PRIVATE_KEY", "AUTH", "WEBHOOK"}
 
-func listIssueComments(issueID string) ([]bdComment, error) {
-	output := commandCombinedOutput("bd", "comments", issueID, "--json")
-	return parseBDCommentsJSON(output)
-}
+// urlCredentialsPattern matches the user:password@ part of a URL, which
+// yoke env redacts whatever the variable is called.
+var urlCredentialsPattern = regexp.MustCompile(`(://)[^/@\s]+@`)
 
-func hasOpenBlockingDependencies(dependencies []bdListIssue) bool {
-	for _, dep := range dependencies {
-		if !strings.EqualFold(strings.TrimSpace(dep.DependencyType), "blocks") {
-			continue
-		}
-		if workflowStatusForIssue(dep) != "closed" {
+func sensitiveEnvKey(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, marker := range sensitiveEnvKeyMarkers {
+		if strings.Contains(upper, marker) {
 			return true
 		}
 	}
 	return false
 }
 
-func hasDependencyTypeEntries(dependencies []bdListIssue) bool {
-	for _, dep := range dependencies {
-		if strings.TrimSpace(dep.DependencyType) != "" {
-			return true
+// effectiveEnv collapses duplicate keys the way os/exec does (last value
+// wins) and returns the entries sorted by key.
+func effectiveEnv(env []string) []string {
+	values := map[string]string{}
+	for _, item := range env {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok || key == "" {
+			continue
 		}
+		values[key] = value
 	}
-	return false
-}
-
-func issueHasOpenBlockingDependencies(issueID string) (bool, error) {
-	output := commandCombinedOutput("bd", "dep", "list", issueID, "--json")
-
-	dependencyIssues, depErr := parseBDListIssuesJSON(output)
-	if depErr == nil && hasDependencyTypeEntries(dependencyIssues) {
-		return hasOpenBlockingDependencies(dependencyIssues), nil
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
+	out := make([]string, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, key+"="+values[key])
+	}
+	return out
+}
 
-	dependencyEdges, edgeErr := parseBDDependencyEdgesJSON(output)
-	if edgeErr != nil {
-		if depErr != nil {
-			return false, depErr
+func redactEnv(env []string) []string {
+	out := make([]string, 0, len(env))
+	for _, item := range env {
+		key, value, _ := strings.Cut(item, "=")
+		if value != "" && sensitiveEnvKey(key) {
+			item = key + "=<redacted>"
+		} else if urlCredentialsPattern.MatchString(value) {
+			item = key + "=" + urlCredentialsPattern.ReplaceAllString(value, "${1}<redacted>@")
 		}
-		return false, edgeErr
+		out = append(out, item)
 	}
-	return hasOpenBlockingDependencyEdges(issueID, dependencyEdges, issueStatus)
+	return out
 }
 
-func hasOpenBlockingDependencyEdges(issueID string, edges []bdDependencyEdge, statusLookup func(string) (string, error)) (bool, error) {
-	normalizedIssueID := strings.TrimSpace(issueID)
-	statusByIssueID := make(map[string]string)
-	for _, edge := range edges {
-		if !strings.EqualFold(strings.TrimSpace(edge.Type), "blocks") {
-			continue
+type roleEnvironment struct {
+	Role    string   `json:"role"`
+	IssueID string   `json:"issue_id"`
+	Command string   `json:"command"`
+	Dir     string   `json:"dir"`
+	Set     []string `json:"set_by_yoke"`
+	Env     []string `json:"env"`
+}
+
+// buildRoleEnvironment reproduces the command, directory, and environment
+// yoke daemon (writer/reviewer) or yoke submit (check) would use for issue.
+func buildRoleEnvironment(root string, cfg config, role, issue string, base []string) (roleEnvironment, error) {
+	dir := worktreePathForBranch(root, branchForIssue(issue))
+	if dir == "" {
+		dir = worktreePathForIssue(root, issue)
+	}
+	result := roleEnvironment{Role: role, IssueID: issue, Dir: dir}
+
+	var env []string
+	switch role {
+	case "writer", "reviewer":
+		command := cfg.WriterCmd
+		if role == "reviewer" {
+			command = cfg.ReviewCmd
 		}
-		edgeIssueID := strings.TrimSpace(edge.IssueID)
-		if edgeIssueID != "" && normalizedIssueID != "" && !strings.EqualFold(edgeIssueID, normalizedIssueID) {
-			continue
+		result.Command = daemonCommandWithExtraWritableDir(command)
+		env = roleCommandEnv(base, cfg, role, issue, dir, root)
+		// Everything after base was added by yoke; PATH is also changed in
+		// place when base already has one.
+		setPath := false
+		for _, item := range env[len(base):] {
+			key, _, _ := strings.Cut(item, "=")
+			result.Set = append(result.Set, key)
+			setPath = setPath || key == "PATH"
 		}
-		blockerID := strings.TrimSpace(edge.DependsOnID)
-		if blockerID == "" {
-			continue
+		if !setPath {
+			result.Set = append(result.Set, "PATH")
 		}
-		status, ok := statusByIssueID[blockerID]
-		if !ok {
-			resolvedStatus, err := statusLookup(blockerID)
-			if err != nil {
-				return false, err
+	case "check":
+		result.Command = valueOrFallback(strings.TrimSpace(cfg.CheckCmd), defaultCheckCmd)
+		if len(cfg.Checks) > 0 {
+			commands := make([]string, 0, len(cfg.Checks))
+			for _, check := range cfg.Checks {
+				commands = append(commands, check.Command)
 			}
-			status = resolvedStatus
-			statusByIssueID[blockerID] = status
+			result.Command = strings.Join(commands, " & ")
 		}
-		if status != "closed" {
-			return true, nil
+		env = append([]string{}, base...)
+	default:
+		return result, fmt.Errorf("unknown role %q (want writer, reviewer, or check)", role)
+	}
+	result.Env = redactEnv(effectiveEnv(env))
+	return result, nil
+}
+
+func formatRoleEnvironment(r roleEnvironment) string {
+	lines := []string{
+		fmt.Sprintf("# yoke env %s %s", r.Role, r.IssueID),
+		"# command: " + valueOrFallback(r.Command, "(not configured)"),
+		"# dir: " + r.Dir,
+	}
+	if len(r.Set) > 0 {
+		lines = append(lines, "# set by yoke: "+strings.Join(r.Set, " "))
+	} else {
+		lines = append(lines, "# set by yoke: (none; inherits the caller's environment)")
+	}
+	for _, item := range r.Env {
+		// Multi-line values such as YOKE_REVIEW_DIFF stay on one line.
+		if key, value, _ := strings.Cut(item, "="); strings.Contains(value, "\n") {
+			item = key + "=" + strconv.Quote(value)
 		}
+		lines = append(lines, item)
 	}
-	return false, nil
+	return strings.Join(lines, "\n") + "\n"
 }
 
-func parseBDDependencyEdgesJSON(raw string) ([]bdDependencyEdge, error) {
-	trimmed := strings.TrimSpace(raw)
-	if trimmed == "" || trimmed == "null" {
-		return nil, nil
+func cmdEnv(args []string) error {
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
 	}
 
-	var edgePayload []bdDependencyEdge
-	if err := json.Unmarshal([]byte(trimmed), &edgePayload); err == nil {
-		edges := filterValidDependencyEdges(edgePayload)
-		if len(edges) > 0 {
-			return edges, nil
+	parsed, err := parseCommandArgs("env", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printEnvUsage()
+		return nil
+	}
+	asJSON := len(parsed.Flags) > 0
+	var role, issue string
+	for _, arg := range parsed.Positionals {
+		switch {
+		case role == "":
+			role = arg
+		case issue == "" && (looksLikeIssueID(arg, cfg.BDPrefix) || looksLikeIssueIDAnyPrefix(arg)):
+			issue = arg
+		default:
+			return fmt.Errorf("unknown env argument: %s", arg)
 		}
 	}
-
-	var issuePayload []bdIssueWithDependencies
-	if err := json.Unmarshal([]byte(trimmed), &issuePayload); err != nil {
-		return nil, fmt.Errorf("parse bd dep list json: %w", err)
+	if role == "" {
+		return errors.New("usage: yoke env <writer|reviewer|check> [<prefix>-issue-id] [--json]")
+	}
+	if issue == "" {
+		issue = currentBranchIssue(cfg.BDPrefix)
+	}
+	if issue == "" {
+		return errors.New("could not infer issue id from branch; pass issue id explicitly")
 	}
 
-	edges := make([]bdDependencyEdge, 0)
-	for _, issue := range issuePayload {
-		for _, dep := range issue.Dependencies {
-			edge := bdDependencyEdge{
-				IssueID:     strings.TrimSpace(dep.IssueID),
-				DependsOnID: strings.TrimSpace(dep.DependsOnID),
-				Type:        strings.TrimSpace(dep.Type),
-			}
-			if edge.IssueID == "" {
-				edge.IssueID = strings.TrimSpace(issue.ID)
-			}
-			if edge.IssueID == "" || edge.DependsOnID == "" || edge.Type == "" {
-				continue
-			}
-			edges = append(edges, edge)
+	result, err := buildRoleEnvironment(root, cfg, role, issue, os.Environ())
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
 		}
+		fmt.Println(string(data))
+		return nil
 	}
+	fmt.Print(formatRoleEnvironment(result))
+	return nil
+}
 
-	return edges, nil
+// configLintFinding is one problem yoke config lint found in an agent
+// command template.
+type configLintFinding struct {
+	Key      string `json:"key"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
 }
 
-func filterValidDependencyEdges(edges []bdDependencyEdge) []bdDependencyEdge {
-	valid := make([]bdDependencyEdge, 0, len(edges))
-	for _, edge := range edges {
-		if strings.TrimSpace(edge.IssueID) == "" {
-			continue
-		}
-		if strings.TrimSpace(edge.DependsOnID) == "" {
-			continue
-		}
-		if strings.TrimSpace(edge.Type) == "" {
-			continue
-		}
-		valid = append(valid, edge)
-	}
-	return valid
+// configLintResult is the lint outcome for one of YOKE_WRITER_CMD or
+// YOKE_REVIEW_CMD, including the command as the daemon would expand it.
+type configLintResult struct {
+	Key      string              `json:"key"`
+	Role     string              `json:"role"`
+	Command  string              `json:"command"`
+	Expanded string              `json:"expanded"`
+	Findings []configLintFinding `json:"findings"`
 }
 
-func collectDescendantIssues(root string) ([]bdListIssue, error) {
-	visited := map[string]bool{}
-	var descendants []bdListIssue
+// shellVarRef is a $NAME or ${NAME} reference found in a command template.
+type shellVarRef struct {
+	Name  string
+	Quote byte // 0 unquoted, '"' inside double quotes, '\'' inside single quotes
+}
 
-	var visit func(string) error
-	visit = func(parent string) error {
-		children, err := listChildIssues(parent)
-		if err != nil {
-			return err
-		}
-		for _, child := range children {
-			id := strings.TrimSpace(child.ID)
-			if id == "" || visited[id] {
-				continue
-			}
-			visited[id] = true
-			descendants = append(descendants, child)
+var daemonCommandVars = map[string]bool{"ISSUE_ID": true, "ROOT_DIR": true, "YOKE_MAIN_ROOT": true, "BD_PREFIX": true, "YOKE_ROLE": true, "YOKE_REVIEW_VERDICT_SCHEMA": true, "YOKE_REVIEW_BASE": true, "YOKE_REVIEW_DIFF": true, "YOKE_REVIEW_DIFF_FILE": true}
 
-			if err := visit(id); err != nil {
-				return err
-			}
-		}
-		return nil
-	}
+var yokeSubcommandPattern = regexp.MustCompile(`(?:^|[\s;&|("'` + "`" + `])yoke\s+([A-Za-z][A-Za-z0-9-]*)`)
 
-	if err := visit(root); err != nil {
-		return nil, err
+func cmdConfig(args []string) error {
+	if len(args) == 0 {
+		printConfigUsage()
+		return errors.New("usage: yoke config lint [options]")
 	}
 
-	return descendants, nil
+	switch args[0] {
+	case "lint":
+		return cmdConfigLint(args[1:])
+	case "-h", "--help":
+		printConfigUsage()
+		return nil
+	default:
+		return fmt.Errorf("unknown config subcommand: %s", args[0])
+	}
 }
 
-func collectClarificationContext(rootIssue string) ([]clarificationContext, error) {
-	descendants, err := collectDescendantIssues(rootIssue)
+func cmdConfigLint(args []string) error {
+	parsed, err := parseCommandArgs("config", args)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	context := make([]clarificationContext, 0)
-	for _, issue := range descendants {
-		if !clarificationTaskReadyForAutoClose(issue) {
-			continue
+	if parsed.Help {
+		printConfigUsage()
+		return nil
+	}
+	if len(parsed.Positionals) > 0 {
+		return errors.New("usage: yoke config lint [--issue ID] [--json]")
+	}
+	asJSON := false
+	issue := ""
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--json":
+			asJSON = true
+		case "--issue":
+			issue = strings.TrimSpace(flag.Value)
 		}
+	}
 
-		comments, err := listIssueComments(issue.ID)
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	if issue == "" {
+		issue = valueOrFallback(cfg.BDPrefix, "bd") + "-example"
+	}
+
+	env := daemonCommandEnv(os.Environ(), issue, worktreePathForIssue(root, issue), root, cfg.BDPrefix, "writer")
+	results := []configLintResult{
+		lintAgentCommand("YOKE_WRITER_CMD", "writer", cfg.WriterCmd, env),
+		lintAgentCommand("YOKE_REVIEW_CMD", "reviewer", cfg.ReviewCmd, env),
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
 		if err != nil {
-			return nil, fmt.Errorf("load comments for %s: %w", issue.ID, err)
-		}
-		if len(comments) == 0 {
-			continue
+			return err
 		}
-		context = append(context, clarificationContext{
-			IssueID:  issue.ID,
-			Title:    issue.Title,
-			Comments: comments,
-		})
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(formatConfigLintResults(results))
 	}
 
-	return context, nil
-}
-
-func isClarificationNeededTitle(title string) bool {
-	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(title)), "clarification needed:")
+	errorCount := 0
+	for _, result := range results {
+		for _, finding := range result.Findings {
+			if finding.Severity == "error" {
+				errorCount++
+			}
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("config lint found %d error(s)", errorCount)
+	}
+	return nil
 }
 
-func clarificationTaskReadyForAutoClose(issue bdListIssue) bool {
-	if !isClarificationNeededTitle(issue.Title) {
-		return false
+// lintAgentCommand checks a daemon command template for mistakes that only
+// surface after an agent run is wasted. env is the environment the daemon
+// would pass; YOKE_ROLE is overridden with role.
+func lintAgentCommand(key, role, command string, env []string) configLintResult {
+	result := configLintResult{Key: key, Role: role, Command: command, Findings: []configLintFinding{}}
+	add := func(severity, message string) {
+		result.Findings = append(result.Findings, configLintFinding{Key: key, Severity: severity, Message: message})
 	}
-	if issue.CommentCount <= 0 {
-		return false
+	if strings.TrimSpace(command) == "" {
+		add("error", "not set; yoke daemon refuses to start without it")
+		return result
 	}
-	return workflowStatusForIssue(issue) != "closed"
-}
 
-func closeClarificationTasksWithComments(rootIssue string) (int, error) {
-	descendants, err := collectDescendantIssues(rootIssue)
-	if err != nil {
-		return 0, err
+	vars := map[string]string{}
+	for _, entry := range effectiveEnv(env) {
+		name, value, _ := strings.Cut(entry, "=")
+		vars[name] = value
 	}
+	vars["YOKE_ROLE"] = role
 
-	closed := 0
-	for _, issue := range descendants {
-		if !clarificationTaskReadyForAutoClose(issue) {
+	runCommand := daemonCommandWithExtraWritableDir(command)
+	expanded, refs, unterminated := expandShellTemplate(runCommand, vars)
+	result.Expanded = expanded
+	if unterminated != 0 {
+		add("error", fmt.Sprintf("unterminated %c quote; bash will fail to parse the command", unterminated))
+	}
+
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		key := ref.Name + string(ref.Quote)
+		if seen[key] {
 			continue
 		}
-		claimNote("Auto-closing clarification task with comments: " + issue.ID)
-		if err := runCommand("bd", "close", issue.ID, "--reason", "clarified-by-comment"); err != nil {
-			return closed, err
+		seen[key] = true
+		yokeVar := daemonCommandVars[ref.Name]
+		switch {
+		case ref.Quote == '\'':
+			if yokeVar {
+				add("error", "$"+ref.Name+" is inside single quotes and will not expand; use double quotes")
+			}
+		case ref.Quote == 0 && yokeVar:
+			add("warning", "$"+ref.Name+" is unquoted; write \"$"+ref.Name+"\" so paths and ids with spaces or globs stay one argument")
+		case !yokeVar:
+			if _, ok := vars[ref.Name]; !ok {
+				add("warning", "$"+ref.Name+" is not set by yoke or the current environment and will expand to empty")
+			}
 		}
-		closed++
 	}
-	return closed, nil
-}
 
-func collectEpicWorkItemIDs(descendants []bdListIssue) map[string]struct{} {
-	workItemIDs := make(map[string]struct{})
-	for _, issue := range descendants {
-		id := strings.TrimSpace(issue.ID)
-		if id == "" {
-			continue
-		}
-		if strings.EqualFold(strings.TrimSpace(issue.IssueType), "epic") {
-			continue
+	known := map[string]bool{}
+	for _, entry := range commandHelpIndex {
+		known[entry.Name] = true
+	}
+	mentioned := map[string]bool{}
+	for _, match := range yokeSubcommandPattern.FindAllStringSubmatch(command, -1) {
+		sub := match[1]
+		mentioned[sub] = true
+		if !known[sub] {
+			add("error", "references `yoke "+sub+"`, which is not a yoke command")
 		}
-		workItemIDs[id] = struct{}{}
 	}
-	return workItemIDs
-}
 
-func filterClaimCandidatesForEpic(candidates []bdListIssue, workItemIDs map[string]struct{}, hasOpenDeps func(string) (bool, error)) ([]bdListIssue, []string, int, error) {
-	filtered := make([]bdListIssue, 0, len(candidates))
-	skippedBlocked := make([]string, 0)
-	ignoredOutsideEpic := 0
-	for _, candidate := range candidates {
-		id := strings.TrimSpace(candidate.ID)
-		if id == "" {
-			continue
-		}
-		if _, ok := workItemIDs[id]; !ok {
-			ignoredOutsideEpic++
-			continue
+	if !strings.Contains(command, "$ISSUE_ID") && !strings.Contains(command, "${ISSUE_ID}") {
+		add("warning", "never references $ISSUE_ID; the agent cannot tell which issue to work on")
+	}
+	switch role {
+	case "writer":
+		if !mentioned["submit"] {
+			add("warning", "no `yoke submit` step; the daemon stops with a no-progress error when the issue stays in_progress")
 		}
-		hasDeps, err := hasOpenDeps(id)
-		if err != nil {
-			return nil, nil, ignoredOutsideEpic, err
+	case "reviewer":
+		if strings.Contains(command, "YOKE_REVIEW_VERDICT_SCHEMA") {
+			break
 		}
-		if hasDeps {
-			skippedBlocked = append(skippedBlocked, id)
-			continue
+		if !mentioned["review"] {
+			add("warning", "no `yoke review --approve/--reject` step or $YOKE_REVIEW_VERDICT_SCHEMA verdict; the daemon stops with a no-progress error when the issue stays in review")
+		} else if !strings.Contains(command, "--approve") && !strings.Contains(command, "--reject") {
+			add("warning", "`yoke review` without --approve or --reject does not move the issue out of review")
 		}
-		filtered = append(filtered, candidate)
 	}
-	return filtered, skippedBlocked, ignoredOutsideEpic, nil
+	return result
 }
 
-func pickEpicChildToClaim(descendants, inProgress, ready []bdListIssue) (string, bool) {
-	workItems := map[string]bdListIssue{}
-	for _, issue := range descendants {
-		id := strings.TrimSpace(issue.ID)
-		if id == "" {
+// expandShellTemplate expands $NAME and ${NAME} the way bash would for a
+// simple command: single-quoted text stays literal and backslash escapes a
+// dollar sign. It returns every reference with its quoting context and the
+// quote character left open at the end, if any.
+func expandShellTemplate(command string, vars map[string]string) (string, []shellVarRef, byte) {
+	var out strings.Builder
+	refs := []shellVarRef{}
+	var quote byte
+	for i := 0; i < len(command); i++ {
+		ch := command[i]
+		switch {
+		case quote == '\'':
+			if ch == '\'' {
+				quote = 0
+			}
+			if ch == '$' {
+				if name, _ := shellVarName(command[i+1:]); name != "" {
+					refs = append(refs, shellVarRef{Name: name, Quote: '\''})
+				}
+			}
+			out.WriteByte(ch)
 			continue
-		}
-		if strings.EqualFold(strings.TrimSpace(issue.IssueType), "epic") {
+		case ch == '\\' && i+1 < len(command):
+			out.WriteByte(ch)
+			out.WriteByte(command[i+1])
+			i++
 			continue
+		case ch == '\'' && quote == 0, ch == '"' && quote == 0:
+			quote = ch
+		case ch == '"' && quote == '"':
+			quote = 0
+		case ch == '$':
+			name, width := shellVarName(command[i+1:])
+			if name != "" {
+				refs = append(refs, shellVarRef{Name: name, Quote: quote})
+				out.WriteString(vars[name])
+				i += width
+				continue
+			}
 		}
-		workItems[id] = issue
-	}
-
-	if len(workItems) == 0 {
-		return "", true
+		out.WriteByte(ch)
 	}
+	return out.String(), refs, quote
+}
 
-	for _, issue := range inProgress {
-		id := strings.TrimSpace(issue.ID)
-		if _, ok := workItems[id]; ok {
-			return id, false
+// shellVarName reads a variable name after '$', returning it and the number
+// of bytes consumed (including braces).
+func shellVarName(rest string) (string, int) {
+	if strings.HasPrefix(rest, "{") {
+		end := strings.IndexByte(rest, '}')
+		if end <= 1 {
+			return "", 0
 		}
+		return rest[1:end], end + 1
 	}
-
-	for _, issue := range ready {
-		id := strings.TrimSpace(issue.ID)
-		if _, ok := workItems[id]; ok {
-			return id, false
+	width := 0
+	for width < len(rest) {
+		ch := rest[width]
+		if ch == '_' || ch >= 'A' && ch <= 'Z' || ch >= 'a' && ch <= 'z' || width > 0 && ch >= '0' && ch <= '9' {
+			width++
+			continue
 		}
+		break
 	}
+	return rest[:width], width
+}
 
-	for _, issue := range workItems {
-		if workflowStatusForIssue(issue) != "closed" {
-			return "", false
+func formatConfigLintResults(results []configLintResult) string {
+	lines := []string{}
+	for _, result := range results {
+		lines = append(lines, result.Key+" ("+result.Role+"):")
+		if strings.TrimSpace(result.Command) != "" {
+			lines = append(lines, "  command:  "+result.Command)
+			lines = append(lines, "  expands:  "+result.Expanded)
+		}
+		if len(result.Findings) == 0 {
+			lines = append(lines, "  ok")
+		}
+		for _, finding := range result.Findings {
+			lines = append(lines, "  "+finding.Severity+": "+finding.Message)
 		}
 	}
+	return strings.Join(lines, "\n") + "\n"
+}
 
-	return "", true
+const (
+	defaultServeAddr = "127.0.0.1:7717"
+	apiInfoFile      = "api.json"
+)
+
+// apiInfo is written to .yoke/api.json while yoke serve runs so editor
+// extensions can discover the address and token.
+type apiInfo struct {
+	Addr          string `json:"addr"`
+	Token         string `json:"token"`
+	PID           int    `json:"pid"`
+	DashboardAddr string `json:"dashboard_addr,omitempty"`
 }
 
-func resolveClaimIssue(root string, cfg config, issue string, passLimit int) (string, bool, error) {
-	claimNote("Loading issue details for " + issue)
-	details, err := issueDetails(issue)
-	if err != nil {
-		return "", false, err
-	}
-	claimNote(fmt.Sprintf("Issue %s resolved as type=%s status=%s", details.ID, details.IssueType, workflowStatusForIssue(details)))
-	if !strings.EqualFold(strings.TrimSpace(details.IssueType), "epic") {
-		claimNote("Issue is not an epic; proceeding with direct claim.")
-		return issue, false, nil
-	}
-	if workflowStatusForIssue(details) == "closed" {
-		claimNote("Epic is already closed; no child task to claim.")
-		return "", true, nil
-	}
-	if passLimit == 0 {
-		claimNote("Issue is an epic; improvement pass limit is 0, skipping epic improvement cycle.")
-	} else {
-		claimNote(fmt.Sprintf("Issue is an epic; running epic improvement cycle (limit=%d pass(es)) before selecting a child task.", passLimit))
-		if err := runEpicImprovementCycle(root, cfg, details, passLimit); err != nil {
-			return "", false, err
-		}
-	}
-	claimNote("Auto-resolving clarification tasks that have comments.")
-	autoClosedCount, err := closeClarificationTasksWithComments(issue)
-	if err != nil {
-		return "", false, err
-	}
-	if autoClosedCount == 0 {
-		claimNote("No clarification tasks required auto-close.")
-	} else {
-		claimNote(fmt.Sprintf("Auto-closed %d clarification task(s) from user comments.", autoClosedCount))
-	}
-	claimNote("Collecting epic descendants for claim selection.")
+// apiServer answers the localhost HTTP API. Mutating routes re-run the yoke
+// binary so each request sees fresh config and gets its own output.
+type apiServer struct {
+	root  string
+	token string
+	yoke  func(args []string) (string, error)
+	mu    sync.Mutex
+}
 
-	descendants, err := collectDescendantIssues(issue)
-	if err != nil {
-		return "", false, err
-	}
-	claimNote(fmt.Sprintf("Collected %d descendant issue(s).", len(descendants)))
-	workItemIDs := collectEpicWorkItemIDs(descendants)
-	claimNote(fmt.Sprintf("Epic work item candidates: %d", len(workItemIDs)))
+type apiActionResult struct {
+	OK     bool   `json:"ok"`
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+}
 
-	claimNote("Loading in-progress issues for possible resume.")
-	inProgress, err := listIssuesByStatus("in_progress", false)
-	if err != nil {
-		return "", false, err
-	}
-	claimNote(fmt.Sprintf("Found %d in-progress issue(s).", len(inProgress)))
-	filteredInProgress, skippedInProgress, ignoredInProgress, err := filterClaimCandidatesForEpic(inProgress, workItemIDs, issueHasOpenBlockingDependencies)
-	if err != nil {
-		return "", false, err
-	}
-	if ignoredInProgress > 0 {
-		claimNote(fmt.Sprintf("Ignoring %d in-progress issue(s) outside this epic.", ignoredInProgress))
-	}
-	if len(skippedInProgress) > 0 {
-		claimNote("Skipping blocked in-progress issue(s): " + strings.Join(skippedInProgress, ", "))
-	}
-	claimNote(fmt.Sprintf("Claimable in-progress issue(s): %d", len(filteredInProgress)))
-	claimNote("Loading ready open issues for fallback selection.")
-	ready, err := listIssuesByStatus("open", true)
-	if err != nil {
-		return "", false, err
-	}
-	claimNote(fmt.Sprintf("Found %d ready open issue(s).", len(ready)))
-	filteredReady, skippedReady, ignoredReady, err := filterClaimCandidatesForEpic(ready, workItemIDs, issueHasOpenBlockingDependencies)
-	if err != nil {
-		return "", false, err
-	}
-	if ignoredReady > 0 {
-		claimNote(fmt.Sprintf("Ignoring %d ready issue(s) outside this epic.", ignoredReady))
-	}
-	if len(skippedReady) > 0 {
-		claimNote("Skipping blocked ready issue(s): " + strings.Join(skippedReady, ", "))
-	}
-	claimNote(fmt.Sprintf("Claimable ready open issue(s): %d", len(filteredReady)))
+// apiEpic is one epic in GET /v1/epics; GET /v1/epic adds its tree and
+// improvement pass reports.
+type apiEpic struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+}
 
-	target, epicComplete := pickEpicChildToClaim(descendants, filteredInProgress, filteredReady)
-	if target != "" {
-		claimNote("Selected claimable child task: " + target)
-		return target, false, nil
-	}
-	if epicComplete {
-		claimNote("All non-epic descendants are closed; closing epic.")
-		currentStatus, err := issueStatus(issue)
-		if err != nil {
-			return "", false, err
-		}
-		if currentStatus != "closed" {
-			claimNote("Closing epic " + issue + " with reason all-child-tasks-closed.")
-			if err := runCommand("bd", "close", issue, "--reason", "all-child-tasks-closed"); err != nil {
-				return "", false, err
-			}
-		} else {
-			claimNote("Epic already closed; no close command needed.")
-		}
-		return "", true, nil
-	}
+type apiEpicDetail struct {
+	Epic    apiEpic             `json:"epic"`
+	Tree    epicTreeSnapshot    `json:"tree"`
+	Reports []epicArchiveReport `json:"reports"`
+}
 
-	claimNote("No claimable child task found; remaining work is blocked or already claimed.")
-	return "", false, fmt.Errorf("epic %s has no claimable child tasks (all remaining children are blocked or already claimed)", issue)
+type apiIssueContext struct {
+	Issue    bdListIssue `json:"issue"`
+	Branch   string      `json:"branch"`
+	Worktree string      `json:"worktree"`
+	PR       string      `json:"pr,omitempty"`
+	Comments []bdComment `json:"comments"`
 }
 
-type epicImprovementPassReport struct {
-	Pass    int
-	Role    string
-	AgentID string
-	Output  string
+type apiSubmitRequest struct {
+	Issue     string `json:"issue"`
+	Done      string `json:"done"`
+	Remaining string `json:"remaining"`
+	Decision  string `json:"decision"`
+	Uncertain string `json:"uncertain"`
+	Commit    bool   `json:"commit"`
+	NoPush    bool   `json:"no_push"`
+	NoPR      bool   `json:"no_pr"`
 }
 
-func runEpicImprovementCycle(root string, cfg config, epic bdListIssue, passLimit int) error {
-	if passLimit < minEpicPassCount || passLimit > epicPassCount {
-		return fmt.Errorf("improvement pass limit must be between %d and %d", minEpicPassCount, epicPassCount)
+type apiReviewRequest struct {
+	Issue    string `json:"issue"`
+	Action   string `json:"action"`
+	Reason   string `json:"reason"`
+	Note     string `json:"note"`
+	Agent    bool   `json:"agent"`
+	WaitCI   bool   `json:"wait_ci"`
+	Security bool   `json:"security"`
+}
+
+func cmdServe(args []string) error {
+	addr := defaultServeAddr
+	token := strings.TrimSpace(os.Getenv("YOKE_API_TOKEN"))
+	dashboardAddr := ""
+	dashboardToken := strings.TrimSpace(os.Getenv("YOKE_DASHBOARD_TOKEN"))
+	parsed, err := parseCommandArgs("serve", args)
+	if err != nil {
+		return err
 	}
-	if passLimit == 0 {
-		claimNote("Epic improvement pass limit is 0; skipping epic improvement cycle.")
+	if parsed.Help {
+		printServeUsage()
 		return nil
 	}
-	if strings.TrimSpace(epicImprovementPromptTemplate) == "" {
-		return errors.New("epic improvement prompt template is empty")
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown serve argument: %s", parsed.Positionals[0])
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--addr":
+			addr = flag.Value
+		case "--token":
+			token = strings.TrimSpace(flag.Value)
+		case "--dashboard-addr":
+			dashboardAddr = flag.Value
+		case "--dashboard-token":
+			dashboardToken = strings.TrimSpace(flag.Value)
+		}
 	}
-	claimNote("Checking for clarification tasks with comments before starting passes.")
-	clarificationContext, err := collectClarificationContext(epic.ID)
-	if err != nil {
+	if err := validateLoopbackAddr(addr); err != nil {
 		return err
 	}
-	if hasLabel(epic.Labels, epicImprovementCompleteLabel) {
-		if len(clarificationContext) == 0 {
-			claimNote("Epic improvement cycle already complete (label present); skipping rerun.")
-			return nil
+	if dashboardAddr != "" && dashboardToken == "" {
+		if dashboardToken, err = generateAPIToken(); err != nil {
+			return err
 		}
-		claimNote(fmt.Sprintf("Epic improvement already marked complete, but found %d clarification task(s) with comments; re-running improvement cycle.", len(clarificationContext)))
 	}
-	if len(clarificationContext) == 0 {
-		claimNote("No clarification tasks with comments found.")
-	} else {
-		claimNote(fmt.Sprintf("Found %d clarification task(s) with comments; injecting context into prompts.", len(clarificationContext)))
-		for _, item := range clarificationContext {
-			claimNote(fmt.Sprintf("Loaded clarification context: %s (%d comment(s))", item.IssueID, len(item.Comments)))
-		}
+	if dashboardToken != "" && dashboardToken == token {
+		return errors.New("the dashboard token must differ from the API token")
 	}
 
-	claimNote(fmt.Sprintf("Starting epic improvement cycle for %s (%d pass(es)).", epic.ID, passLimit))
-	reportsDir := filepath.Join(root, ".yoke", "epic-improvement-reports", sanitizePathSegment(epic.ID))
-	claimNote("Improvement reports directory: " + reportsDir)
-	if err := os.MkdirAll(reportsDir, 0o755); err != nil {
+	root, err := ensureRepoRoot()
+	if err != nil {
 		return err
 	}
-	claimNote("Marking epic as improvement-running.")
-	if err := runCommand("bd", "update", epic.ID, "--add-label", epicImprovementRunningLabel); err != nil {
+	cfg, err := loadConfig(root)
+	if err != nil {
 		return err
 	}
-
-	reports := make([]epicImprovementPassReport, 0, passLimit)
-	for pass := 1; pass <= passLimit; pass++ {
-		role := roleForPass(pass)
-		agentID, err := agentIDForRole(cfg, role)
-		if err != nil {
+	// Handlers share the tracker, so it is chosen once here.
+	if err := useIssueTracker(cfg); err != nil {
+		note("warning: " + err.Error() + "; issue routes will fail until it is fixed and yoke serve restarts")
+	}
+	if token == "" {
+		if token, err = generateAPIToken(); err != nil {
 			return err
 		}
-		claimNote(fmt.Sprintf("Improvement pass %d/%d starting (role=%s, agent=%s).", pass, passLimit, role, agentID))
-
-		prompt := buildEpicImprovementPassPrompt(epic.ID, pass, passLimit, role, clarificationContext)
-		output, runErr := runAgentPrompt(agentID, root, prompt, []string{
-			"ISSUE_ID=" + epic.ID,
-			"ROOT_DIR=" + root,
-			"BD_PREFIX=" + cfg.BDPrefix,
-			"YOKE_ROLE=" + role,
-			"YOKE_EPIC_IMPROVEMENT_PASS=" + strconv.Itoa(pass),
-		}, fmt.Sprintf("[claim][pass %d/%d %s] ", pass, passLimit, role))
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
 
-		reportPath := filepath.Join(reportsDir, fmt.Sprintf("pass-%02d-%s.md", pass, role))
-		if err := writeEpicImprovementPassReport(reportPath, epic.ID, pass, role, agentID, output, runErr); err != nil {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	api := newAPIServer(root, token, func(args []string) (string, error) {
+		cmd := exec.Command(executable, args...)
+		cmd.Dir = root
+		output, err := cmd.CombinedOutput()
+		return strings.TrimSpace(string(output)), err
+	})
+	server := &http.Server{Handler: api.routes(), ReadHeaderTimeout: 10 * time.Second}
+	info := apiInfo{Addr: listener.Addr().String(), Token: token, PID: os.Getpid()}
+
+	// The dashboard listener may face the network: it only serves the
+	// read-only routes, behind a token that cannot submit or review.
+	var dashboard *http.Server
+	var dashboardListener net.Listener
+	if dashboardAddr != "" {
+		if dashboardListener, err = net.Listen("tcp", dashboardAddr); err != nil {
+			_ = listener.Close()
 			return err
 		}
-		claimNote("Saved improvement pass report: " + reportPath)
-		if runErr != nil {
-			claimNote(fmt.Sprintf("Improvement pass %d failed; see report: %s", pass, reportPath))
-			return fmt.Errorf("epic improvement pass %d (%s) failed: %w (report: %s)", pass, role, runErr, reportPath)
-		}
-		claimNote(fmt.Sprintf("Improvement pass %d/%d completed.", pass, passLimit))
-
-		reports = append(reports, epicImprovementPassReport{
-			Pass:    pass,
-			Role:    role,
-			AgentID: agentID,
-			Output:  output,
-		})
+		dashboard = &http.Server{Handler: api.dashboardRoutes(dashboardToken), ReadHeaderTimeout: 10 * time.Second}
+		info.DashboardAddr = dashboardListener.Addr().String()
 	}
 
-	summaryAgentID, err := agentIDForRole(cfg, "reviewer")
+	infoPath := filepath.Join(root, ".yoke", apiInfoFile)
+	data, err := json.Marshal(info)
 	if err != nil {
 		return err
 	}
-	claimNote("Generating final improvement summary with reviewer agent " + summaryAgentID + ".")
-	summaryPrompt := buildEpicImprovementSummaryPrompt(epic, reports)
-	summary, runErr := runAgentPrompt(summaryAgentID, root, summaryPrompt, []string{
-		"ISSUE_ID=" + epic.ID,
-		"ROOT_DIR=" + root,
-		"BD_PREFIX=" + cfg.BDPrefix,
-		"YOKE_ROLE=reviewer",
-		"YOKE_EPIC_IMPROVEMENT_SUMMARY=1",
-	}, "[claim][summary] ")
-	summaryPath := filepath.Join(reportsDir, "summary.md")
-	if err := writeEpicImprovementSummary(summaryPath, epic.ID, summaryAgentID, summary, runErr); err != nil {
+	if err := os.MkdirAll(filepath.Dir(infoPath), 0o755); err != nil {
 		return err
 	}
-	claimNote("Saved improvement summary report: " + summaryPath)
-	if runErr != nil {
-		claimNote("Improvement summary generation failed; see report: " + summaryPath)
-		return fmt.Errorf("epic improvement summary failed: %w (report: %s)", runErr, summaryPath)
+	if err := os.WriteFile(infoPath, data, 0o600); err != nil {
+		return err
 	}
+	defer os.Remove(infoPath)
 
-	claimNote("Posting improvement summary comment to epic " + epic.ID + ".")
-	comment := formatEpicImprovementSummaryComment(epic, summary, passLimit, reportsDir)
-	if err := runCommand("bd", "comments", "add", epic.ID, comment); err != nil {
-		return err
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if dashboard != nil {
+			_ = dashboard.Shutdown(shutdown)
+		}
+		_ = server.Shutdown(shutdown)
+	}()
+
+	note(fmt.Sprintf("Serving yoke API on http://%s (token in %s)", listener.Addr(), infoPath))
+	if dashboard != nil {
+		note(fmt.Sprintf("Serving the read-only dashboard on http://%s/#token=%s", dashboardListener.Addr(), dashboardToken))
+		go func() {
+			if err := dashboard.Serve(dashboardListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				note("warning: dashboard stopped: " + err.Error())
+			}
+		}()
 	}
-	claimNote("Marking epic improvement complete and clearing running label.")
-	if err := runCommand("bd", "update", epic.ID,
-		"--add-label", epicImprovementCompleteLabel,
-		"--remove-label", epicImprovementRunningLabel,
-	); err != nil {
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
-
-	note(fmt.Sprintf("Completed epic improvement cycle for %s; reports saved in %s", epic.ID, reportsDir))
 	return nil
 }
 
-func roleForPass(pass int) string {
-	if pass%2 == 1 {
-		return "writer"
-	}
-	return "reviewer"
+const (
+	defaultListenPort  = 7719
+	webhookMaxBody     = 5 << 20
+	webhookRecentLimit = 256
+)
+
+// webhookAction is what one GitHub event asks yoke to do to an issue. PR is
+// set when the event names a pull request but not its branch; SHA is the
+// commit an approval was given for.
+type webhookAction struct {
+	Kind   string `json:"action"`
+	Issue  string `json:"issue,omitempty"`
+	PR     string `json:"pr,omitempty"`
+	SHA    string `json:"sha,omitempty"`
+	Text   string `json:"-"`
+	Sender string `json:"-"`
 }
 
-func agentIDForRole(cfg config, role string) (string, error) {
-	switch role {
-	case "writer":
-		if strings.TrimSpace(cfg.WriterAgent) != "" {
-			return cfg.WriterAgent, nil
+// githubWebhookPayload holds the fields yoke reads from pull_request_review,
+// issue_comment, and check_suite events.
+type githubWebhookPayload struct {
+	Action string `json:"action"`
+	Sender struct {
+		Login string `json:"login"`
+		Type  string `json:"type"`
+	} `json:"sender"`
+	Review struct {
+		State             string `json:"state"`
+		Body              string `json:"body"`
+		AuthorAssociation string `json:"author_association"`
+		CommitID          string `json:"commit_id"`
+	} `json:"review"`
+	PullRequest struct {
+		Number int `json:"number"`
+		Head   struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	Issue struct {
+		Number      int             `json:"number"`
+		PullRequest json.RawMessage `json:"pull_request"`
+	} `json:"issue"`
+	Comment struct {
+		Body              string `json:"body"`
+		AuthorAssociation string `json:"author_association"`
+	} `json:"comment"`
+	CheckSuite struct {
+		HeadBranch   string `json:"head_branch"`
+		Conclusion   string `json:"conclusion"`
+		PullRequests []struct {
+			Number int `json:"number"`
+		} `json:"pull_requests"`
+	} `json:"check_suite"`
+}
+
+// webhookActionFor converts a GitHub event into a yoke action, or explains
+// why it is ignored. Bots and yoke's own PR comments are ignored so yoke
+// does not feed its output back into bd, and reviews and comments from
+// people without push access are ignored because notes reach the writer
+// agent's prompt.
+func webhookActionFor(event string, payload githubWebhookPayload) (webhookAction, string) {
+	if payload.Sender.Type == "Bot" {
+		return webhookAction{}, "sent by a bot"
+	}
+	sender := "@" + payload.Sender.Login
+	switch event {
+	case "pull_request_review":
+		if payload.Action != "submitted" {
+			return webhookAction{}, "review " + payload.Action
 		}
-	case "reviewer":
-		if strings.TrimSpace(cfg.ReviewerAgent) != "" {
-			return cfg.ReviewerAgent, nil
+		issue, ok := issueForYokeBranch(payload.PullRequest.Head.Ref)
+		if !ok {
+			return webhookAction{}, "not a yoke branch"
 		}
-		if strings.TrimSpace(cfg.WriterAgent) != "" {
-			return cfg.WriterAgent, nil
+		if !trustedReviewAssociation(payload.Review.AuthorAssociation) {
+			return webhookAction{}, "reviewer is " + valueOrFallback(payload.Review.AuthorAssociation, "NONE") + ", not a collaborator"
+		}
+		action := webhookAction{Issue: issue, PR: strconv.Itoa(payload.PullRequest.Number), Text: strings.TrimSpace(payload.Review.Body), Sender: sender}
+		switch strings.ToLower(payload.Review.State) {
+		case "changes_requested":
+			action.Kind = "reject"
+		case "approved":
+			action.Kind, action.SHA = "approve", strings.TrimSpace(payload.Review.CommitID)
+		case "commented":
+			if action.Text == "" {
+				return webhookAction{}, "empty review comment"
+			}
+			action.Kind = "note"
+		default:
+			return webhookAction{}, "review state " + payload.Review.State
+		}
+		if action.Kind == "approve" && (action.SHA == "" || action.SHA != strings.TrimSpace(payload.PullRequest.Head.SHA)) {
+			// An approval covers the commit it was given on, not whatever
+			// was pushed after it.
+			return webhookAction{}, "approval is for " + shortSHA(action.SHA) + ", not the PR head " + shortSHA(payload.PullRequest.Head.SHA)
+		}
+		return action, ""
+	case "issue_comment":
+		if payload.Action != "created" || len(payload.Issue.PullRequest) == 0 {
+			return webhookAction{}, "not a new pull request comment"
+		}
+		text := strings.TrimSpace(payload.Comment.Body)
+		if text == "" || strings.Contains(text, "_Posted automatically by `yoke") {
+			return webhookAction{}, "posted by yoke"
+		}
+		if !trustedReviewAssociation(payload.Comment.AuthorAssociation) {
+			return webhookAction{}, "commenter is " + valueOrFallback(payload.Comment.AuthorAssociation, "NONE") + ", not a collaborator"
 		}
+		return webhookAction{Kind: "note", PR: strconv.Itoa(payload.Issue.Number), Text: text, Sender: sender}, ""
+	case "check_suite":
+		if payload.Action != "completed" {
+			return webhookAction{}, "check suite " + payload.Action
+		}
+		switch payload.CheckSuite.Conclusion {
+		case "failure", "timed_out", "action_required":
+		default:
+			return webhookAction{}, "check suite " + payload.CheckSuite.Conclusion
+		}
+		issue, ok := issueForYokeBranch(payload.CheckSuite.HeadBranch)
+		if !ok {
+			return webhookAction{}, "not a yoke branch"
+		}
+		action := webhookAction{Kind: "ci-failed", Issue: issue}
+		if len(payload.CheckSuite.PullRequests) > 0 {
+			action.PR = strconv.Itoa(payload.CheckSuite.PullRequests[0].Number)
+		}
+		return action, ""
+	case "ping":
+		return webhookAction{}, "ping"
 	}
-	return "", fmt.Errorf("no %s agent configured; run yoke init or set agent config in .yoke/config.sh", role)
+	return webhookAction{}, "unhandled event " + event
 }
 
-func agentBinaryForID(agentID string) (string, string, error) {
-	normalized, ok := normalizeAgentID(agentID)
-	if !ok {
-		return "", "", fmt.Errorf("unsupported agent id: %s", agentID)
+// trustedReviewAssociation reports whether a review or comment's
+// author_association lets it reach yoke: repository owners, organization
+// members, and collaborators.
+func trustedReviewAssociation(association string) bool {
+	switch strings.ToUpper(association) {
+	case "OWNER", "MEMBER", "COLLABORATOR":
+		return true
 	}
-	for _, spec := range supportedAgents {
-		if spec.ID != normalized {
-			continue
+	return false
+}
+
+func issueForYokeBranch(branch string) (string, bool) {
+	issue, ok := strings.CutPrefix(branch, "yoke/")
+	if !ok || !looksLikeIssueIDAnyPrefix(issue) {
+		return "", false
+	}
+	return issue, true
+}
+
+// webhookActionArgs is the yoke command for review actions; ci-failed is
+// applied in process because no command returns an issue for CI.
+func webhookActionArgs(action webhookAction) []string {
+	switch action.Kind {
+	case "reject":
+		reason := "Changes requested on GitHub by " + action.Sender
+		if action.Text != "" {
+			reason += ":\n\n" + action.Text
 		}
-		for _, binary := range spec.Binaries {
-			if commandExists(binary) {
-				return normalized, binary, nil
-			}
+		return []string{"review", action.Issue, "--reject", reason}
+	case "approve":
+		args := []string{"review", action.Issue, "--approve", "--no-pr-comment"}
+		if action.Text != "" {
+			args = append(args, "--note", "Approved on GitHub by "+action.Sender+":\n\n"+action.Text)
 		}
-		break
+		return args
+	case "note":
+		return []string{"review", action.Issue, "--note", "GitHub comment from " + action.Sender + ":\n\n" + action.Text}
 	}
-	return "", "", fmt.Errorf("agent %s is not available on PATH", normalized)
+	return nil
 }
 
-func runAgentPrompt(agentID, root, prompt string, extraEnv []string, streamPrefix string) (string, error) {
-	normalized, binary, err := agentBinaryForID(agentID)
+// webhookListener verifies GitHub deliveries and queues their actions for a
+// single worker, so GitHub gets an answer at once and actions never overlap.
+type webhookListener struct {
+	secret  string
+	actions chan webhookAction
+	mu      sync.Mutex
+	recent  []string
+}
+
+func newWebhookListener(secret string) *webhookListener {
+	return &webhookListener{secret: secret, actions: make(chan webhookAction, 64)}
+}
+
+func (l *webhookListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, webhookMaxBody))
 	if err != nil {
-		return "", err
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
 	}
+	if !validWebhookSignature(l.secret, body, r.Header.Get("X-Hub-Signature-256")) {
+		writeAPIError(w, http.StatusUnauthorized, "missing or invalid X-Hub-Signature-256")
+		return
+	}
+	if l.seen(r.Header.Get("X-GitHub-Delivery")) {
+		writeAPIJSON(w, http.StatusOK, map[string]string{"ignored": "duplicate delivery"})
+		return
+	}
+	var payload githubWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON payload: "+err.Error())
+		return
+	}
+	event := r.Header.Get("X-GitHub-Event")
+	action, ignored := webhookActionFor(event, payload)
+	if ignored != "" {
+		writeAPIJSON(w, http.StatusOK, map[string]string{"ignored": ignored})
+		return
+	}
+	select {
+	case l.actions <- action:
+		progress.Info("listen.event", fmt.Sprintf("%s: queued %s for %s", event, action.Kind, valueOrFallback(action.Issue, "PR #"+action.PR)))
+		writeAPIJSON(w, http.StatusAccepted, action)
+	default:
+		writeAPIError(w, http.StatusServiceUnavailable, "action queue is full; GitHub can redeliver this event")
+	}
+}
+
+// seen remembers recent delivery ids so a redelivered event runs once.
+func (l *webhookListener) seen(delivery string) bool {
+	if delivery == "" {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range l.recent {
+		if id == delivery {
+			return true
+		}
+	}
+	l.recent = append(l.recent, delivery)
+	if len(l.recent) > webhookRecentLimit {
+		l.recent = l.recent[1:]
+	}
+	return false
+}
+
+func validWebhookSignature(secret string, body []byte, header string) bool {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	presented, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(presented, mac.Sum(nil))
+}
+
+// applyWebhookAction resolves the issue of a PR-only action, checks it is
+// still in a state the action applies to, and runs it.
+func applyWebhookAction(root string, action webhookAction, yoke func(args []string) (string, error)) error {
+	if action.Issue == "" {
+		branch, err := pullBranch(action.PR)
+		if err != nil {
+			return err
+		}
+		issue, ok := issueForYokeBranch(branch)
+		if !ok {
+			progress.Info("listen.action", fmt.Sprintf("Ignoring PR #%s: %s is not a yoke branch.", action.PR, branch))
+			return nil
+		}
+		action.Issue = issue
+	}
+	status, err := issueStatus(action.Issue)
+	if err != nil {
+		return err
+	}
+	if action.Kind != "note" && status != "in_review" {
+		progress.Info("listen.action", fmt.Sprintf("Ignoring %s for %s: it is %s, not in review.", action.Kind, action.Issue, status))
+		return nil
+	}
+	if action.Kind == "approve" {
+		if head := branchHeadSHA(root, branchForIssue(action.Issue)); head == "" || head != action.SHA {
+			progress.Info("listen.action", fmt.Sprintf("Ignoring approval for %s: it was given on %s, but %s is at %s.", action.Issue, shortSHA(action.SHA), branchForIssue(action.Issue), shortSHA(head)))
+			return nil
+		}
+	}
+	if action.Kind == "ci-failed" {
+		state, failed, err := issueCIState(action.Issue)
+		if err != nil {
+			return err
+		}
+		if state != "fail" {
+			progress.Info("listen.action", fmt.Sprintf("Ignoring failed check suite for %s: required checks are %s.", action.Issue, state))
+			return nil
+		}
+		return returnIssueForCIFailure(root, action.Issue, action.PR, failed)
+	}
+	args := webhookActionArgs(action)
+	progress.Info("listen.action", "Running yoke "+strings.Join(args[:3], " "))
+	output, err := yoke(args)
+	if output != "" {
+		note(output)
+	}
+	return err
+}
+
+func pullBranch(number string) (string, error) {
+	client, err := githubAPI()
+	if err != nil {
+		return "", err
+	}
+	var pull githubPull
+	if _, err := client.do(http.MethodGet, fmt.Sprintf("/repos/%s/%s/pulls/%s", client.owner, client.repo, number), nil, &pull); err != nil {
+		return "", err
+	}
+	return pull.Head.Ref, nil
+}
+
+func cmdListen(args []string) error {
+	parsed, err := parseCommandArgs("listen", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printListenUsage()
+		return nil
+	}
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown listen argument: %s", parsed.Positionals[0])
+	}
+	host := "127.0.0.1"
+	port := defaultListenPort
+	secret := strings.TrimSpace(os.Getenv("YOKE_WEBHOOK_SECRET"))
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--port":
+			if port, err = strconv.Atoi(flag.Value); err != nil || port < 0 || port > 65535 {
+				return fmt.Errorf("invalid --port value: %q", flag.Value)
+			}
+		case "--host":
+			host = flag.Value
+		case "--secret":
+			secret = strings.TrimSpace(flag.Value)
+		}
+	}
+	if secret == "" {
+		return errors.New("yoke listen needs the webhook secret: set YOKE_WEBHOOK_SECRET (or --secret) to the secret configured on the GitHub webhook")
+	}
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	yoke := func(args []string) (string, error) {
+		cmd := exec.Command(executable, args...)
+		cmd.Dir = root
+		// Verdicts arriving here are applied unattended, so they go through
+		// YOKE_REQUIRE_HUMAN_FOR and YOKE_APPROVAL_POLICY like any other
+		// automated approval.
+		cmd.Env = append(os.Environ(), "YOKE_ROLE=webhook")
+		output, err := cmd.CombinedOutput()
+		return strings.TrimSpace(string(output)), err
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	hooks := newWebhookListener(secret)
+	server := &http.Server{Handler: hooks, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		for action := range hooks.actions {
+			if err := applyWebhookAction(root, action, yoke); err != nil {
+				progress.Fail("listen.action", fmt.Errorf("%s %s: %w", action.Kind, valueOrFallback(action.Issue, "PR #"+action.PR), err))
+			}
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdown)
+	}()
+
+	note(fmt.Sprintf("Listening for GitHub webhooks on http://%s (pull_request_review, issue_comment, check_suite)", listener.Addr()))
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// validateLoopbackAddr keeps the API off the network: it has no TLS and can
+// trigger submits and reviews.
+func validateLoopbackAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid --addr value: %q", addr)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("yoke serve only listens on loopback addresses, not %q", host)
+}
+
+func generateAPIToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func newAPIServer(root, token string, yoke func(args []string) (string, error)) *apiServer {
+	return &apiServer{root: root, token: token, yoke: yoke}
+}
+
+func (s *apiServer) routes() http.Handler {
+	mux := s.readRoutes()
+	mux.HandleFunc("/v1/audit", s.handleAudit)
+	mux.HandleFunc("/v1/stream", s.handleStream)
+	mux.HandleFunc("/v1/submit", s.handleSubmit)
+	mux.HandleFunc("/v1/review", s.handleReview)
+	return s.authorize(mux, s.token)
+}
+
+// dashboardRoutes serves the HTML dashboard and the read-only routes behind
+// token, for yoke serve --dashboard-addr. That listener may be on the
+// network without TLS, so raw agent output (/v1/stream) and audit arguments
+// (/v1/audit) stay on the loopback API.
+func (s *apiServer) dashboardRoutes(token string) http.Handler {
+	return s.authorize(s.readRoutes(), token)
+}
+
+func (s *apiServer) readRoutes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", s.handleDashboard)
+	mux.HandleFunc("/v1/status", s.handleStatus)
+	mux.HandleFunc("/v1/issue", s.handleIssue)
+	mux.HandleFunc("/v1/queue", s.handleQueue)
+	mux.HandleFunc("/v1/epics", s.handleEpics)
+	mux.HandleFunc("/v1/epic", s.handleEpic)
+	mux.HandleFunc("/v1/pipelines", s.handlePipelines)
+	return mux
+}
+
+// authorize requires token on every route but the dashboard page, which is
+// static and takes the token from the URL fragment so it never reaches the
+// server or its logs.
+func (s *apiServer) authorize(next http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		presented := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if presented == "" && r.URL.Path == "/v1/stream" {
+			// Browsers cannot set headers on websockets; the stream is only
+			// served on the loopback API.
+			presented = r.URL.Query().Get("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *apiServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	cfg, err := loadConfig(s.root)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	status := map[string]string{}
+	for _, field := range collectStatus(s.root, cfg) {
+		status[field.Key] = field.Value
+	}
+	writeAPIJSON(w, http.StatusOK, status)
+}
+
+// handleDashboard serves the HTML page; it keeps the token from the URL
+// fragment in session storage and calls the JSON routes with it.
+func (s *apiServer) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = io.WriteString(w, dashboardHTML)
+}
+
+func (s *apiServer) handleQueue(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	cfg, err := loadConfig(s.root)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	entries, err := collectReviewQueue(cfg)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, entries)
+}
+
+func (s *apiServer) handleEpics(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	epics := []apiEpic{}
+	for _, status := range []string{"in_progress", "open"} {
+		issues, err := tracker.List(issueQuery{Status: status})
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, issue := range issues {
+			if strings.EqualFold(issue.IssueType, "epic") {
+				epics = append(epics, apiEpic{ID: issue.ID, Title: issue.Title, Status: workflowStatusForIssue(issue)})
+			}
+		}
+	}
+	writeAPIJSON(w, http.StatusOK, epics)
+}
+
+func (s *apiServer) handleEpic(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	id := strings.TrimSpace(r.URL.Query().Get("id"))
+	if id == "" {
+		writeAPIError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+	if !looksLikeIssueIDAnyPrefix(id) {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid issue id: %q", id))
+		return
+	}
+	epic, err := issueDetails(id)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	tree, err := snapshotEpicTree(epic.ID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	reports, err := loadEpicImprovementReports(s.root, epic.ID)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if reports == nil {
+		reports = []epicArchiveReport{}
+	}
+	writeAPIJSON(w, http.StatusOK, apiEpicDetail{
+		Epic:    apiEpic{ID: epic.ID, Title: epic.Title, Status: workflowStatusForIssue(epic)},
+		Tree:    tree,
+		Reports: reports,
+	})
+}
+
+func (s *apiServer) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	limit := 100
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			writeAPIError(w, http.StatusBadRequest, "limit must be a non-negative number")
+			return
+		}
+		limit = value
+	}
+	entries, err := loadAuditEntries(s.root)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, filterAuditEntries(entries, strings.TrimSpace(r.URL.Query().Get("issue")), limit))
+}
+
+func (s *apiServer) handleIssue(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	cfg, err := loadConfig(s.root)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	issue := strings.TrimSpace(r.URL.Query().Get("id"))
+	if issue != "" && !looksLikeIssueIDAnyPrefix(issue) {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid issue id: %q", issue))
+		return
+	}
+	if issue == "" {
+		issue = focusedIssueByWorkflowStatus(s.root, cfg.BDPrefix, "in_progress")
+	}
+	if issue == "" {
+		issue = focusedIssueByWorkflowStatus(s.root, cfg.BDPrefix, "in_review")
+	}
+	if issue == "" {
+		writeAPIError(w, http.StatusNotFound, "no current issue; pass ?id=<issue>")
+		return
+	}
+	details, err := issueDetails(issue)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	comments, err := listIssueComments(issue)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	issueContext := apiIssueContext{
+		Issue:    details,
+		Branch:   branchForIssue(issue),
+		Worktree: worktreePathForIssue(s.root, issue),
+		PR:       prURLForBranch(branchForIssue(issue)),
+		Comments: writerContextComments(comments),
+	}
+	writeAPIJSON(w, http.StatusOK, issueContext)
+}
+
+func (s *apiServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req apiSubmitRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	args, err := submitArgsForAPI(req)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.runAction(w, args)
+}
+
+func (s *apiServer) handleReview(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	var req apiReviewRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	args, err := reviewArgsForAPI(req)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.runAction(w, args)
+}
+
+// runAction serializes state-changing commands so two editor windows cannot
+// submit or review at the same time.
+func (s *apiServer) runAction(w http.ResponseWriter, args []string) {
+	s.mu.Lock()
+	output, err := s.yoke(args)
+	s.mu.Unlock()
+	if err != nil {
+		writeAPIJSON(w, http.StatusUnprocessableEntity, apiActionResult{Output: output, Error: err.Error()})
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, apiActionResult{OK: true, Output: output})
+}
+
+func submitArgsForAPI(req apiSubmitRequest) ([]string, error) {
+	if strings.TrimSpace(req.Done) == "" || strings.TrimSpace(req.Remaining) == "" {
+		return nil, errors.New("done and remaining are required")
+	}
+	args := []string{"submit"}
+	if issue := strings.TrimSpace(req.Issue); issue != "" {
+		if !looksLikeIssueIDAnyPrefix(issue) {
+			return nil, fmt.Errorf("invalid issue id: %q", issue)
+		}
+		args = append(args, issue)
+	}
+	args = append(args, "--done", req.Done, "--remaining", req.Remaining)
+	if req.Decision != "" {
+		args = append(args, "--decision", req.Decision)
+	}
+	if req.Uncertain != "" {
+		args = append(args, "--uncertain", req.Uncertain)
+	}
+	if req.Commit {
+		args = append(args, "--commit")
+	}
+	if req.NoPush {
+		args = append(args, "--no-push")
+	}
+	if req.NoPR {
+		args = append(args, "--no-pr")
+	}
+	return args, nil
+}
+
+func reviewArgsForAPI(req apiReviewRequest) ([]string, error) {
+	args := []string{"review"}
+	if issue := strings.TrimSpace(req.Issue); issue != "" {
+		if !looksLikeIssueIDAnyPrefix(issue) {
+			return nil, fmt.Errorf("invalid issue id: %q", issue)
+		}
+		args = append(args, issue)
+	}
+	if req.Agent {
+		args = append(args, "--agent")
+	}
+	if req.Security {
+		args = append(args, "--security")
+	}
+	if req.Note != "" {
+		args = append(args, "--note", req.Note)
+	}
+	switch req.Action {
+	case "approve":
+		args = append(args, "--approve")
+		if req.WaitCI {
+			args = append(args, "--wait-ci")
+		}
+	case "reject":
+		if strings.TrimSpace(req.Reason) == "" {
+			return nil, errors.New("reject requires a reason")
+		}
+		args = append(args, "--reject", req.Reason)
+	case "":
+		if req.Note == "" && !req.Agent && !req.Security {
+			return nil, errors.New("action must be approve or reject, or pass note, agent, or security")
+		}
+	default:
+		return nil, fmt.Errorf("unknown review action: %q (want approve or reject)", req.Action)
+	}
+	return args, nil
+}
+
+func requireMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeAPIError(w, http.StatusMethodNotAllowed, "use "+method)
+	return false
+}
+
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, target any) bool {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"error": message})
+}
+
+const (
+	liveLogDir          = "live"
+	liveLogHeaderPrefix = "# yoke live: "
+	websocketGUID       = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	liveTailInterval    = 250 * time.Millisecond
+)
+
+// liveAgentLog tees in-flight agent output to <shared state>/live/<pipeline>.log
+// so yoke serve can stream it while the run is still going. The file is
+// truncated at the start of each run and ends with a finished marker.
+type liveAgentLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func liveLogRoot(root string) string {
+	return filepath.Join(yokeSharedStateDir(root), liveLogDir)
+}
+
+func liveLogPath(root, pipeline string) string {
+	return filepath.Join(liveLogRoot(root), sanitizePathSegment(strings.ToLower(pipeline))+".log")
+}
+
+// openLiveAgentLog never fails the run it observes: on error it returns a
+// log that discards writes.
+func openLiveAgentLog(root, pipeline, role, source string) *liveAgentLog {
+	live := &liveAgentLog{}
+	if strings.TrimSpace(pipeline) == "" {
+		return live
+	}
+	path := liveLogPath(root, pipeline)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return live
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return live
+	}
+	live.file = file
+	_, _ = fmt.Fprintf(file, "%spipeline=%s role=%s source=%s started=%s\n", liveLogHeaderPrefix, pipeline, role, source, time.Now().Format(time.RFC3339))
+	return live
+}
+
+func (l *liveAgentLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		_, _ = l.file.Write(p)
+	}
+	return len(p), nil
+}
+
+func (l *liveAgentLog) Finish(runErr error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+	exit := "success"
+	if runErr != nil {
+		exit = "error: " + runErr.Error()
+	}
+	_, _ = fmt.Fprintf(l.file, "\n%sfinished exit=%s\n", liveLogHeaderPrefix, exit)
+	_ = l.file.Close()
+	l.file = nil
+}
+
+// livePipelineFromEnv names the pipeline of an agent run from the environment
+// yoke passes it: the issue, or pr-<n> for human PR reviews.
+func livePipelineFromEnv(env []string) string {
+	if issue := envValue(env, "ISSUE_ID"); issue != "" {
+		return issue
+	}
+	if prNumber := envValue(env, "YOKE_PR_NUMBER"); prNumber != "" {
+		return "pr-" + prNumber
+	}
+	return ""
+}
+
+func envValue(env []string, key string) string {
+	value := ""
+	for _, entry := range env {
+		if k, v, ok := strings.Cut(entry, "="); ok && k == key {
+			value = v
+		}
+	}
+	return value
+}
+
+type livePipeline struct {
+	Pipeline  string `json:"pipeline"`
+	Role      string `json:"role"`
+	Source    string `json:"source"`
+	StartedAt string `json:"started_at"`
+	UpdatedAt string `json:"updated_at"`
+	Running   bool   `json:"running"`
+	Bytes     int64  `json:"bytes"`
+}
+
+func listLivePipelines(root string) []livePipeline {
+	present := map[string]bool{}
+	defer pruneLiveLogScans(liveLogRoot(root), present)
+	entries, err := os.ReadDir(liveLogRoot(root))
+	if err != nil {
+		return []livePipeline{}
+	}
+	pipelines := []livePipeline{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".log" {
+			continue
+		}
+		path := filepath.Join(liveLogRoot(root), entry.Name())
+		present[path] = true
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		pipeline := scanLivePipeline(path)
+		if pipeline.Pipeline == "" {
+			pipeline.Pipeline = strings.TrimSuffix(entry.Name(), ".log")
+		}
+		pipeline.UpdatedAt = info.ModTime().Format(time.RFC3339)
+		pipeline.Bytes = info.Size()
+		pipelines = append(pipelines, pipeline)
+	}
+	sort.Slice(pipelines, func(i, j int) bool { return pipelines[i].UpdatedAt > pipelines[j].UpdatedAt })
+	return pipelines
+}
+
+// liveLogScan is how far listLivePipelines has read one running live log, so
+// each poll reads only what was appended since the last one.
+type liveLogScan struct {
+	offset   int64
+	header   string
+	tail     string
+	finished bool
+}
+
+// liveLogScans holds a scan per running pipeline. Finished and deleted logs
+// are evicted, so a long-running yoke serve keeps only the runs in flight.
+var liveLogScans = struct {
+	sync.Mutex
+	byPath map[string]liveLogScan
+}{byPath: map[string]liveLogScan{}}
+
+// liveLogPeekBytes bounds how much of a log's head and tail is read to
+// recognise a finished run without scanning the whole file.
+const liveLogPeekBytes = 4096
+
+// scanLivePipeline reads a live log from where the previous scan stopped. A
+// new run truncates the file and writes a new header, so a shorter file or a
+// changed header line starts the scan over. A log that is not being scanned
+// yet is first peeked at, so finished runs never enter the cache.
+func scanLivePipeline(path string) livePipeline {
+	liveLogScans.Lock()
+	defer liveLogScans.Unlock()
+	scan, ok := liveLogScans.byPath[path]
+	if !ok {
+		if header, finished := peekLiveLog(path); finished {
+			pipeline := parseLivePipeline(header)
+			pipeline.Running = false
+			return pipeline
+		}
+	}
+	if scan.header != "" && !liveLogStartsWith(path, scan.header+"\n") {
+		scan = liveLogScan{}
+	}
+	chunk, next := readLiveLogFrom(path, scan.offset)
+	if next < scan.offset {
+		scan = liveLogScan{}
+	}
+	if len(chunk) > 0 {
+		text := scan.tail + string(chunk)
+		if scan.header == "" {
+			if header, _, ok := strings.Cut(text, "\n"); ok {
+				scan.header = header
+			}
+		}
+		marker := "\n" + liveLogHeaderPrefix + "finished "
+		if strings.Contains(text, marker) {
+			scan.finished = true
+		}
+		if scan.header == "" {
+			scan.tail = text
+		} else {
+			scan.tail = text[max(0, len(text)-len(marker)):]
+		}
+		scan.offset = next
+	}
+	if scan.finished {
+		delete(liveLogScans.byPath, path)
+	} else {
+		liveLogScans.byPath[path] = scan
+	}
+	pipeline := parseLivePipeline(scan.header)
+	pipeline.Running = !scan.finished
+	return pipeline
+}
+
+// peekLiveLog reads the first line and the last few KiB of a live log and
+// reports whether the run has written its finished marker.
+func peekLiveLog(path string) (string, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", false
+	}
+	head := make([]byte, min(info.Size(), liveLogPeekBytes))
+	n, _ := file.ReadAt(head, 0)
+	header, _, _ := strings.Cut(string(head[:n]), "\n")
+	start := max(0, info.Size()-liveLogPeekBytes)
+	tail := make([]byte, info.Size()-start)
+	n, _ = file.ReadAt(tail, start)
+	return header, strings.Contains(string(tail[:n]), "\n"+liveLogHeaderPrefix+"finished ")
+}
+
+// pruneLiveLogScans drops scans of logs under dir that are no longer on disk.
+func pruneLiveLogScans(dir string, present map[string]bool) {
+	liveLogScans.Lock()
+	defer liveLogScans.Unlock()
+	for path := range liveLogScans.byPath {
+		if filepath.Dir(path) == dir && !present[path] {
+			delete(liveLogScans.byPath, path)
+		}
+	}
+}
+
+func liveLogStartsWith(path, prefix string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	data := make([]byte, len(prefix))
+	n, _ := file.ReadAt(data, 0)
+	return string(data[:n]) == prefix
+}
+
+// parseLivePipeline reads the header fields of a live log; a run is still
+// going until the finished marker is written.
+func parseLivePipeline(raw string) livePipeline {
+	pipeline := livePipeline{Running: true}
+	header, _, _ := strings.Cut(raw, "\n")
+	for _, field := range strings.Fields(strings.TrimPrefix(header, liveLogHeaderPrefix)) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "pipeline":
+			pipeline.Pipeline = value
+		case "role":
+			pipeline.Role = value
+		case "source":
+			pipeline.Source = value
+		case "started":
+			pipeline.StartedAt = value
+		}
+	}
+	if strings.Contains(raw, "\n"+liveLogHeaderPrefix+"finished ") {
+		pipeline.Running = false
+	}
+	return pipeline
+}
+
+func (s *apiServer) handlePipelines(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, listLivePipelines(s.root))
+}
+
+// handleStream upgrades to a websocket and sends the pipeline's live log as
+// text messages until the client closes. Each poll sends whatever arrived,
+// partial lines included, so spinners and prompts show up as they are drawn.
+func (s *apiServer) handleStream(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	pipeline := strings.TrimSpace(r.URL.Query().Get("pipeline"))
+	if pipeline == "" {
+		writeAPIError(w, http.StatusBadRequest, "pipeline is required")
+		return
+	}
+	conn, rw, err := upgradeWebSocket(w, r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer conn.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			opcode, payload, err := readWebSocketFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			switch opcode {
+			case 0x8:
+				_ = writeWebSocketFrame(conn, 0x8, payload)
+				return
+			case 0x9:
+				_ = writeWebSocketFrame(conn, 0xA, payload)
+			}
+		}
+	}()
+
+	path := liveLogPath(s.root, pipeline)
+	var offset int64
+	if r.URL.Query().Get("from") == "end" {
+		if info, err := os.Stat(path); err == nil {
+			offset = info.Size()
+		}
+	}
+	var pending []byte
+	ticker := time.NewTicker(liveTailInterval)
+	defer ticker.Stop()
+	for {
+		chunk, next := readLiveLogFrom(path, offset)
+		if next < offset {
+			pending = nil
+		}
+		offset = next
+		pending = append(pending, chunk...)
+		if cut := completeUTF8Prefix(pending); cut > 0 {
+			if err := writeWebSocketFrame(conn, 0x1, bytes.ToValidUTF8(pending[:cut], []byte("?"))); err != nil {
+				return
+			}
+			pending = append([]byte(nil), pending[cut:]...)
+		}
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// completeUTF8Prefix returns how much of data can be sent without splitting a
+// multi-byte character that the next read will finish.
+func completeUTF8Prefix(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if utf8.FullRune(data[i:]) {
+				return len(data)
+			}
+			return i
+		}
+	}
+	return len(data)
+}
+
+// readLiveLogFrom returns bytes appended after offset and the new offset. A
+// file shorter than offset was truncated by a new run, so it restarts at 0.
+func readLiveLogFrom(path string, offset int64) ([]byte, int64) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, offset
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, offset
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if info.Size() == offset {
+		return nil, offset
+	}
+	data := make([]byte, info.Size()-offset)
+	n, _ := file.ReadAt(data, offset)
+	return data[:n], offset + int64(n)
+}
+
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (net.Conn, *bufio.ReadWriter, error) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || !strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade") {
+		return nil, nil, errors.New("websocket upgrade required")
+	}
+	key := strings.TrimSpace(r.Header.Get("Sec-WebSocket-Key"))
+	if key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, nil, errors.New("unsupported websocket handshake")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("connection does not support websocket")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	response := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + websocketAccept(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, rw, nil
+}
+
+// writeWebSocketFrame writes one unmasked, unfragmented server frame.
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126, byte(length>>8), byte(length))
+	default:
+		header = append(header, 127)
+		for shift := 56; shift >= 0; shift -= 8 {
+			header = append(header, byte(uint64(length)>>shift))
+		}
+	}
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+// readWebSocketFrame reads one client frame and unmasks its payload. Control
+// frames are small, so data frames over 1 MiB are rejected.
+func readWebSocketFrame(r io.Reader) (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(ext[0])<<8 | uint64(ext[1])
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = 0
+		for _, b := range ext {
+			length = length<<8 | uint64(b)
+		}
+	}
+	if length > 1<<20 {
+		return 0, nil, errors.New("websocket frame too large")
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
+
+func daemonCommandWithExtraWritableDir(shellCommand string) string {
+	trimmed := strings.TrimSpace(shellCommand)
+	if trimmed == "" {
+		return shellCommand
+	}
+	if !strings.HasPrefix(trimmed, "codex ") {
+		return shellCommand
+	}
+	if strings.Contains(trimmed, "--add-dir") {
+		return shellCommand
+	}
+	if strings.HasPrefix(trimmed, "codex exec ") {
+		return strings.Replace(trimmed, "codex exec ", "codex exec --add-dir \"$YOKE_MAIN_ROOT\" ", 1)
+	}
+	if trimmed == "codex exec" {
+		return "codex exec --add-dir \"$YOKE_MAIN_ROOT\""
+	}
+	return shellCommand
+}
+
+type daemonLogFilterWriter struct {
+	dst         io.Writer
+	buf         bytes.Buffer
+	inDiffFence bool
+	inRawDiff   bool
+	inRawHunk   bool
+	mu          sync.Mutex
+}
+
+func newDaemonLogFilterWriter(dst io.Writer) *daemonLogFilterWriter {
+	return &daemonLogFilterWriter{dst: dst}
+}
+
+func (w *daemonLogFilterWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	total := len(p)
+	for len(p) > 0 {
+		newline := bytes.IndexByte(p, '\n')
+		if newline < 0 {
+			_, err := w.buf.Write(p)
+			return total, err
+		}
+		_, _ = w.buf.Write(p[:newline])
+		if err := w.processBufferedLine(true); err != nil {
+			return total, err
+		}
+		p = p[newline+1:]
+	}
+	return total, nil
+}
+
+func (w *daemonLogFilterWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	return w.processBufferedLine(false)
+}
+
+func (w *daemonLogFilterWriter) processBufferedLine(withNewline bool) error {
+	raw := strings.TrimSuffix(w.buf.String(), "\r")
+	w.buf.Reset()
+
+	suppress, reprocess := w.shouldSuppressLine(raw)
+	if reprocess {
+		suppress, _ = w.shouldSuppressLine(raw)
+	}
+	if suppress {
+		return nil
+	}
+
+	if withNewline {
+		_, err := io.WriteString(w.dst, raw+"\n")
+		return err
+	}
+	_, err := io.WriteString(w.dst, raw)
+	return err
+}
+
+func (w *daemonLogFilterWriter) shouldSuppressLine(raw string) (suppress bool, reprocess bool) {
+	if strings.Contains(raw, "state db missing rollout path for thread") {
+		return true, false
+	}
+
+	trimmed := strings.TrimSpace(raw)
+	lowerTrimmed := strings.ToLower(trimmed)
+	if w.inDiffFence {
+		if strings.HasPrefix(trimmed, "```") {
+			w.inDiffFence = false
+		}
+		return true, false
+	}
+	if strings.HasPrefix(lowerTrimmed, "```diff") {
+		w.inDiffFence = true
+		return true, false
+	}
+
+	if w.inRawDiff {
+		if raw == "" {
+			w.inRawDiff = false
+			w.inRawHunk = false
+			return false, true
+		}
+		switch {
+		case isRawDiffMetadataLine(raw):
+			if strings.HasPrefix(raw, "@@") {
+				w.inRawHunk = true
+			}
+			return true, false
+		case w.inRawHunk && isRawDiffHunkLine(raw):
+			return true, false
+		default:
+			w.inRawDiff = false
+			w.inRawHunk = false
+			return false, true
+		}
+	}
+
+	if isRawDiffStartLine(raw) {
+		w.inRawDiff = true
+		w.inRawHunk = strings.HasPrefix(raw, "@@")
+		return true, false
+	}
+
+	return false, false
+}
+
+func isRawDiffStartLine(line string) bool {
+	return strings.HasPrefix(line, "diff --git ") ||
+		strings.HasPrefix(line, "--- a/") ||
+		strings.HasPrefix(line, "+++ b/") ||
+		strings.HasPrefix(line, "@@")
+}
+
+func isRawDiffMetadataLine(line string) bool {
+	prefixes := []string{
+		"diff --git ",
+		"index ",
+		"--- ",
+		"+++ ",
+		"@@",
+		"new file mode ",
+		"deleted file mode ",
+		"old mode ",
+		"new mode ",
+		"similarity index ",
+		"rename from ",
+		"rename to ",
+		"Binary files ",
+		"GIT binary patch",
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRawDiffHunkLine(line string) bool {
+	if strings.HasPrefix(line, "\\ No newline at end of file") {
+		return true
+	}
+	return strings.HasPrefix(line, "+") ||
+		strings.HasPrefix(line, "-") ||
+		strings.HasPrefix(line, " ")
+}
+
+func notifyDaemonMaxIterationsReached(prefix string, maxIterations int) error {
+	issue, status, err := unresolvedConsensusIssue(prefix)
+	if err != nil {
+		return err
+	}
+	if issue == "" {
+		return nil
+	}
+
+	note(fmt.Sprintf("warning: max iterations (%d) reached before consensus on %s (status: %s)", maxIterations, issue, status))
+	note("warning: leaving PR in draft/open state for manual intervention")
+
+	number, _, isDraft, ok, err := openPRForIssue(issue)
+	if err != nil {
+		note("warning: " + err.Error())
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	if !isDraft {
+		note(fmt.Sprintf("warning: PR #%s is already ready (not draft) for %s", number, issue))
+		return nil
+	}
+
+	body := formatDaemonNoConsensusPRComment(issue, status, maxIterations)
+	if err := commentOnPR(number, body); err != nil {
+		note("warning: failed to post no-consensus PR comment: " + err.Error())
+		return nil
+	}
+	note("Posted no-consensus daemon comment to PR #" + number)
+	return nil
+}
+
+func unresolvedConsensusIssue(prefix string) (string, string, error) {
+	reviewable := firstReviewableIssueID(prefix)
+	if reviewable != "" {
+		return reviewable, "in_review", nil
+	}
+
+	inProgress, err := firstIssueByStatus(prefix, "in_progress")
+	if err != nil {
+		return "", "", err
+	}
+	if inProgress != "" {
+		return inProgress, "in_progress", nil
+	}
+	return "", "", nil
+}
+
+func focusedOrInProgressIssueID(root, prefix string) (string, error) {
+	focused := focusedIssueByWorkflowStatus(root, prefix, "in_progress")
+	if focused != "" {
+		return focused, nil
+	}
+	return firstIssueByStatus(prefix, "in_progress")
+}
+
+func focusedIssueByWorkflowStatus(root, prefix, desiredStatus string) string {
+	branchIssue := currentBranchIssue(prefix)
+	if branchIssue != "" {
+		status, err := issueStatus(branchIssue)
+		if err == nil && status == desiredStatus {
+			return branchIssue
+		}
+	}
+
+	focused := daemonFocusedIssue(root)
+	if focused == "" {
+		return ""
+	}
+	status, err := issueStatus(focused)
+	if err != nil {
+		clearDaemonFocusIssue(root)
+		return ""
+	}
+	if details, err := issueDetails(focused); err == nil && len(withoutHeldIssues([]bdListIssue{details})) == 0 {
+		clearDaemonFocusIssue(root)
+		return ""
+	}
+	if status == desiredStatus {
+		return focused
+	}
+	if status != "in_progress" && status != "in_review" {
+		clearDaemonFocusIssue(root)
+	}
+	return ""
+}
+
+func daemonFocusPath(root string) string {
+	return filepath.Join(root, ".yoke", daemonFocusFile)
+}
+
+func daemonFocusedIssue(root string) string {
+	path := daemonFocusPath(root)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(string(data)))
+}
+
+func writeDaemonFocusIssue(root, issue string) error {
+	if dryRunMode {
+		return nil
+	}
+	path := daemonFocusPath(root)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	value := strings.ToLower(strings.TrimSpace(issue))
+	if value == "" {
+		return errors.New("focus issue cannot be empty")
+	}
+	return os.WriteFile(path, []byte(value+"\n"), 0o644)
+}
+
+func clearDaemonFocusIssue(root string) {
+	if dryRunMode {
+		return
+	}
+	_ = os.Remove(daemonFocusPath(root))
+}
+
+func worktreePathForIssue(root, issue string) string {
+	return filepath.Join(root, ".yoke", "worktrees", sanitizePathSegment(issue))
+}
+
+type gitWorktreeEntry struct {
+	Path   string
+	Branch string
+}
+
+func parseGitWorktreeListEntries(raw string) []gitWorktreeEntry {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return nil
+	}
+
+	lines := strings.Split(trimmed, "\n")
+	entries := make([]gitWorktreeEntry, 0)
+	current := gitWorktreeEntry{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			if strings.TrimSpace(current.Path) != "" {
+				entries = append(entries, current)
+			}
+			current = gitWorktreeEntry{
+				Path: strings.TrimSpace(strings.TrimPrefix(line, "worktree ")),
+			}
+		case strings.HasPrefix(line, "branch "):
+			ref := strings.TrimSpace(strings.TrimPrefix(line, "branch "))
+			current.Branch = strings.TrimPrefix(ref, "refs/heads/")
+		default:
+			continue
+		}
+	}
+	if strings.TrimSpace(current.Path) != "" {
+		entries = append(entries, current)
+	}
+	return entries
+}
+
+func parseGitWorktreeListPorcelain(raw string) []string {
+	entries := parseGitWorktreeListEntries(raw)
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if strings.TrimSpace(entry.Path) == "" {
+			continue
+		}
+		paths = append(paths, entry.Path)
+	}
+	return paths
+}
+
+func worktreeRegistered(root, path string) bool {
+	output := commandCombinedOutput("git", "-C", root, "worktree", "list", "--porcelain")
+	paths := parseGitWorktreeListPorcelain(output)
+
+	targetAbs, err := filepath.Abs(path)
+	if err != nil {
+		targetAbs = filepath.Clean(path)
+	}
+	for _, listed := range paths {
+		listedAbs, pathErr := filepath.Abs(listed)
+		if pathErr != nil {
+			listedAbs = filepath.Clean(listed)
+		}
+		if listedAbs == targetAbs {
+			return true
+		}
+	}
+	return false
+}
+
+func worktreePathForBranch(root, branch string) string {
+	output := commandCombinedOutput("git", "-C", root, "worktree", "list", "--porcelain")
+	entries := parseGitWorktreeListEntries(output)
+	for _, entry := range entries {
+		if strings.EqualFold(strings.TrimSpace(entry.Branch), strings.TrimSpace(branch)) {
+			return strings.TrimSpace(entry.Path)
+		}
+	}
+	return ""
+}
+
+func localOrRemoteRef(branch string) string {
+	ref := strings.TrimSpace(branch)
+	if ref == "" {
+		return ""
+	}
+	if refExists("refs/heads/" + ref) {
+		return ref
+	}
+	if refExists("refs/remotes/origin/" + ref) {
+		return "origin/" + ref
+	}
+	return ""
+}
+
+func ensureLocalBranch(root, branch, defaultStart string) error {
+	target := strings.TrimSpace(branch)
+	if target == "" {
+		return errors.New("branch name cannot be empty")
+	}
+	if refExists("refs/heads/" + target) {
+		return nil
+	}
+	if refExists("refs/remotes/origin/" + target) {
+		return runCommand("git", "-C", root, "branch", target, "origin/"+target)
+	}
+
+	startPoint := localOrRemoteRef(defaultStart)
+	if startPoint == "" {
+		startPoint = "HEAD"
+	}
+	return runCommand("git", "-C", root, "branch", target, startPoint)
+}
+
+func issueBranchStartPoint(root string, cfg config, issue string) (string, error) {
+	if parent := readStackParent(root, issue); parent != "" {
+		if parentBranch := localOrRemoteRef(branchForIssue(parent)); parentBranch != "" {
+			return parentBranch, nil
+		}
+	}
+
+	epicID, err := epicAncestorID(issue)
+	if err != nil {
+		return "", err
+	}
+	if epicID != "" && !strings.EqualFold(strings.TrimSpace(epicID), strings.TrimSpace(issue)) {
+		epicBranch := branchForIssue(epicID)
+		if err := ensureLocalBranch(root, epicBranch, cfg.BaseBranch); err != nil {
+			return "", err
+		}
+		return epicBranch, nil
+	}
+
+	startPoint := localOrRemoteRef(cfg.BaseBranch)
+	if startPoint == "" {
+		startPoint = "HEAD"
+	}
+	return startPoint, nil
+}
+
+func ensureIssueWorktree(root string, cfg config, issue string) (string, error) {
+	branch := branchForIssue(issue)
+	worktreePath := worktreePathForIssue(root, issue)
+
+	if err := os.MkdirAll(filepath.Dir(worktreePath), 0o755); err != nil {
+		return "", err
+	}
+
+	if existingPath := worktreePathForBranch(root, branch); strings.TrimSpace(existingPath) != "" {
+		return existingPath, nil
+	}
+
+	if !worktreeRegistered(root, worktreePath) {
+		if refExists("refs/heads/" + branch) {
+			if err := runCommand("git", "-C", root, "worktree", "add", worktreePath, branch); err != nil {
+				return "", err
+			}
+		} else {
+			startPoint, err := issueBranchStartPoint(root, cfg, issue)
+			if err != nil {
+				return "", err
+			}
+			if err := runCommand("git", "-C", root, "worktree", "add", "-b", branch, worktreePath, startPoint); err != nil {
+				return "", err
+			}
+		}
+		return worktreePath, nil
+	}
+
+	current := strings.TrimSpace(commandCombinedOutput("git", "-C", worktreePath, "rev-parse", "--abbrev-ref", "HEAD"))
+	if current == "" {
+		return "", fmt.Errorf("could not determine current branch for worktree %s", worktreePath)
+	}
+	if current == branch {
+		return worktreePath, nil
+	}
+
+	if refExists("refs/heads/" + branch) {
+		if err := runCommand("git", "-C", worktreePath, "switch", branch); err != nil {
+			return "", err
+		}
+	} else {
+		if err := runCommand("git", "-C", worktreePath, "switch", "-c", branch); err != nil {
+			return "", err
+		}
+	}
+	return worktreePath, nil
+}
+
+type bdListIssue struct {
+	ID             string   `json:"id"`
+	Title          string   `json:"title"`
+	Description    string   `json:"description"`
+	Acceptance     string   `json:"acceptance_criteria"`
+	Status         string   `json:"status"`
+	IssueType      string   `json:"issue_type"`
+	Priority       int      `json:"priority"`
+	Estimate       int      `json:"estimated_minutes"`
+	Assignee       string   `json:"assignee"`
+	Parent         string   `json:"parent"`
+	Labels         []string `json:"labels"`
+	CommentCount   int      `json:"comment_count"`
+	DependencyType string   `json:"dependency_type"`
+	CreatedAt      string   `json:"created_at"`
+	UpdatedAt      string   `json:"updated_at"`
+	ClosedAt       string   `json:"closed_at"`
+}
+
+type bdDependencyEdge struct {
+	IssueID     string `json:"issue_id"`
+	DependsOnID string `json:"depends_on_id"`
+	Type        string `json:"type"`
+}
+
+type bdComment struct {
+	ID        int    `json:"id"`
+	IssueID   string `json:"issue_id"`
+	Author    string `json:"author"`
+	Text      string `json:"text"`
+	CreatedAt string `json:"created_at"`
+}
+
+// bd's JSON output has changed shape across releases: commands emit either a
+// bare list, a single object, or an object wrapping the list under a named key
+// (for example {"issues": [...]}). Dependencies are reported either as edges
+// (issue_id/depends_on_id/type) or as related issue objects carrying
+// dependency_type. The helpers below detect the shape explicitly and fold every
+// variant into the types the rest of yoke consumes. docs/compatibility.md
+// lists the supported shapes; output in any other shape fails with
+// errUnsupportedBDOutput naming the installed bd version.
+
+var errUnsupportedBDOutput = errors.New("unsupported bd output")
+
+var (
+	bdVersionOnce  sync.Once
+	bdVersionValue string
+)
+
+// bdVersion returns the first line of bd --version, read once per process,
+// or "" when bd cannot report it.
+func bdVersion() string {
+	bdVersionOnce.Do(func() {
+		if !commandExists("bd") {
+			return
+		}
+		output, err := commandOutput("bd", "--version")
+		if err != nil {
+			return
+		}
+		first, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+		bdVersionValue = strings.TrimSpace(first)
+	})
+	return bdVersionValue
+}
+
+// bdDecodeError explains a payload yoke could not read: the command, the
+// installed bd version, and where the supported shapes are listed.
+func bdDecodeError(command string, err error) error {
+	version := valueOrFallback(bdVersion(), "bd (version unknown)")
+	return fmt.Errorf("%w from %s: %s returned JSON in a shape yoke does not support (see docs/compatibility.md, bd JSON): %w", errUnsupportedBDOutput, version, command, err)
+}
+
+type bdPayloadShape int
+
+const (
+	bdShapeEmpty bdPayloadShape = iota
+	bdShapeList
+	bdShapeObject
+)
+
+func detectBDPayloadShape(raw string) (bdPayloadShape, string, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" || trimmed == "null" {
+		return bdShapeEmpty, "", nil
+	}
+	switch trimmed[0] {
+	case '[':
+		return bdShapeList, trimmed, nil
+	case '{':
+		return bdShapeObject, trimmed, nil
+	default:
+		return bdShapeEmpty, "", fmt.Errorf("unexpected payload (not JSON): %s", truncateForPrompt(trimmed, 80))
+	}
+}
+
+// decodeBDCollection decodes a list payload into target. Object payloads are
+// accepted when they wrap the list under wrapperKey. found reports whether any
+// payload was present.
+func decodeBDCollection(raw, wrapperKey string, target any) (found bool, err error) {
+	shape, trimmed, err := detectBDPayloadShape(raw)
+	if err != nil {
+		return false, err
+	}
+	switch shape {
+	case bdShapeEmpty:
+		return false, nil
+	case bdShapeObject:
+		var wrapper map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(trimmed), &wrapper); err != nil {
+			return true, err
+		}
+		inner, ok := wrapper[wrapperKey]
+		if !ok {
+			return true, fmt.Errorf("object payload missing %q list", wrapperKey)
+		}
+		trimmed = strings.TrimSpace(string(inner))
+		if trimmed == "" || trimmed == "null" {
+			return false, nil
+		}
+	}
+	return true, json.Unmarshal([]byte(trimmed), target)
+}
+
+// bdIssuePayload is the superset of issue fields emitted by bd versions.
+type bdIssuePayload struct {
+	bdListIssue
+	Dependencies []bdIssueDependency `json:"dependencies"`
+}
+
+// bdIssueDependency covers both dependency encodings: edge records
+// (issue_id/depends_on_id/type) and related issue objects (id/dependency_type).
+type bdIssueDependency struct {
+	ID             string `json:"id"`
+	IssueID        string `json:"issue_id"`
+	DependsOnID    string `json:"depends_on_id"`
+	Type           string `json:"type"`
+	DependencyType string `json:"dependency_type"`
+}
+
+func (d bdIssueDependency) edge(ownerID string) bdDependencyEdge {
+	edge := bdDependencyEdge{
+		IssueID:     strings.TrimSpace(d.IssueID),
+		DependsOnID: strings.TrimSpace(d.DependsOnID),
+		Type:        strings.TrimSpace(d.Type),
+	}
+	if edge.IssueID == "" {
+		edge.IssueID = strings.TrimSpace(ownerID)
+	}
+	if edge.DependsOnID == "" {
+		edge.DependsOnID = strings.TrimSpace(d.ID)
+	}
+	if edge.Type == "" {
+		edge.Type = strings.TrimSpace(d.DependencyType)
+	}
+	return edge
+}
+
+func (p bdIssuePayload) dependencyEdges() []bdDependencyEdge {
+	edges := make([]bdDependencyEdge, 0, len(p.Dependencies))
+	for _, dep := range p.Dependencies {
+		edge := dep.edge(p.ID)
+		if edge.IssueID == "" || edge.DependsOnID == "" || edge.Type == "" {
+			continue
+		}
+		edges = append(edges, edge)
+	}
+	return edges
+}
+
+func decodeBDIssuePayloads(raw, wrapperKey string) ([]bdIssuePayload, error) {
+	shape, trimmed, err := detectBDPayloadShape(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch shape {
+	case bdShapeEmpty:
+		return nil, nil
+	case bdShapeList:
+		var payloads []bdIssuePayload
+		if err := json.Unmarshal([]byte(trimmed), &payloads); err != nil {
+			return nil, err
+		}
+		return payloads, nil
+	default:
+		var wrapper map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(trimmed), &wrapper); err != nil {
+			return nil, err
+		}
+		if _, ok := wrapper[wrapperKey]; ok {
+			var payloads []bdIssuePayload
+			if _, err := decodeBDCollection(trimmed, wrapperKey, &payloads); err != nil {
+				return nil, err
+			}
+			return payloads, nil
+		}
+		var single bdIssuePayload
+		if err := json.Unmarshal([]byte(trimmed), &single); err != nil {
+			return nil, err
+		}
+		return []bdIssuePayload{single}, nil
+	}
+}
+
+// normalizeBDIssuePayloads trims identifiers and fills Parent from a
+// parent-child dependency when the bd version omits the parent field.
+func normalizeBDIssuePayloads(payloads []bdIssuePayload) []bdListIssue {
+	if payloads == nil {
+		return nil
+	}
+	issues := make([]bdListIssue, 0, len(payloads))
+	for _, payload := range payloads {
+		issue := payload.bdListIssue
+		issue.ID = strings.TrimSpace(issue.ID)
+		issue.Parent = strings.TrimSpace(issue.Parent)
+		if issue.Parent == "" {
+			for _, edge := range payload.dependencyEdges() {
+				if strings.EqualFold(edge.Type, "parent-child") && strings.EqualFold(edge.IssueID, issue.ID) {
+					issue.Parent = edge.DependsOnID
+					break
+				}
+			}
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+type clarificationContext struct {
+	IssueID  string
+	Title    string
+	Comments []bdComment
+}
+
+func firstIssueByStatus(prefix, status string) (string, error) {
+	if strings.EqualFold(strings.TrimSpace(status), "in_review") {
+		return firstReviewableIssueID(prefix), nil
+	}
+
+	issues, err := tracker.List(issueQuery{Status: status, Limit: 0})
+	if err != nil {
+		return "", err
+	}
+	return firstMatchingIssueID(withoutHeldIssues(issues), prefix, status), nil
+}
+
+func parseBDListIssuesJSON(raw string) ([]bdListIssue, error) {
+	payloads, err := decodeBDIssuePayloads(raw, "issues")
+	if err != nil {
+		return nil, bdDecodeError("bd list", err)
+	}
+	return normalizeBDIssuePayloads(payloads), nil
+}
+
+func parseBDCommentsJSON(raw string) ([]bdComment, error) {
+	var comments []bdComment
+	found, err := decodeBDCollection(raw, "comments", &comments)
+	if err != nil {
+		return nil, bdDecodeError("bd comments", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return comments, nil
+}
+
+func firstMatchingIssueID(issues []bdListIssue, prefix, status string) string {
+	targetStatus := strings.ToLower(strings.TrimSpace(status))
+	for _, issue := range issues {
+		issueID := strings.ToLower(strings.TrimSpace(issue.ID))
+		issueStatus := workflowStatusForIssue(issue)
+		if issueID == "" {
+			continue
+		}
+		if targetStatus != "" && issueStatus != targetStatus {
+			continue
+		}
+		if looksLikeIssueID(issueID, prefix) {
+			return issueID
+		}
+	}
+	return ""
+}
+
+func issueStatus(issue string) (string, error) {
+	details, err := tracker.Show(issue)
+	if err != nil {
+		return "", err
+	}
+	status := workflowStatusForIssue(details)
+	if status == "" {
+		return "", errors.New("issue payload missing status")
+	}
+	return status, nil
+}
+
+func issueDetails(issue string) (bdListIssue, error) {
+	return tracker.Show(issue)
+}
+
+func epicAncestorID(issue string) (string, error) {
+	current := strings.TrimSpace(issue)
+	if current == "" {
+		return "", nil
+	}
+
+	visited := make(map[string]struct{})
+	for depth := 0; depth < 50; depth++ {
+		if _, ok := visited[current]; ok {
+			return "", fmt.Errorf("detected parent-cycle while resolving epic ancestor for %s", issue)
+		}
+		visited[current] = struct{}{}
+
+		details, err := issueDetails(current)
+		if err != nil {
+			return "", err
+		}
+		if strings.EqualFold(strings.TrimSpace(details.IssueType), "epic") {
+			return strings.TrimSpace(details.ID), nil
+		}
+		parent := strings.TrimSpace(details.Parent)
+		if parent == "" {
+			return "", nil
+		}
+		current = parent
+	}
+	return "", fmt.Errorf("parent chain too deep while resolving epic ancestor for %s", issue)
+}
+
+func parseIssueStatusJSON(raw string) (string, error) {
+	issue, err := parseBDShowIssueJSON(raw)
+	if err != nil {
+		return "", err
+	}
+
+	status := workflowStatusForIssue(issue)
+	if status == "" {
+		return "", errors.New("issue payload missing status")
+	}
+	return status, nil
+}
+
+func parseBDShowIssueJSON(raw string) (bdListIssue, error) {
+	if strings.TrimSpace(raw) == "" {
+		return bdListIssue{}, errors.New("empty issue payload")
+	}
+
+	payloads, err := decodeBDIssuePayloads(raw, "issues")
+	if err != nil {
+		return bdListIssue{}, bdDecodeError("bd show", err)
+	}
+	issues := normalizeBDIssuePayloads(payloads)
+	if len(issues) == 0 || strings.TrimSpace(issues[0].ID) == "" {
+		return bdListIssue{}, errors.New("issue payload missing issue data")
+	}
+	return issues[0], nil
+}
+
+// IssueTracker is the issue backend yoke drives. Workflow code goes through
+// the tracker variable; bdTracker implements it with the bd CLI and tests
+// swap in fakes.
+type IssueTracker interface {
+	List(query issueQuery) ([]bdListIssue, error)
+	Show(id string) (bdListIssue, error)
+	Create(issue newIssue) (bdListIssue, error)
+	Update(id string, change issueChange) error
+	Close(id, reason string) error
+	Comment(id, text string) error
+	Comments(id string) ([]bdComment, error)
+	Children(parent string) ([]bdListIssue, error)
+	// Blockers returns the issues id depends on with a "blocks" edge.
+	Blockers(id string) ([]bdListIssue, error)
+}
+
+// commentEditor is implemented by backends that can rewrite a comment in
+// place. bd has no comment editing, so callers fall back to Comment.
+type commentEditor interface {
+	EditComment(id string, commentID int, text string) error
+}
+
+// issueAssigner is implemented by backends that can set an issue's assignee;
+// an empty assignee clears it. Claims on other backends record the claimant
+// with a yoke:claimed-by label.
+type issueAssigner interface {
+	Assign(id, assignee string) error
+}
+
+// editOrAddComment replaces the comment with commentID when the backend can
+// edit comments, and otherwise appends text as a new comment. It reports
+// whether the comment was edited.
+func editOrAddComment(id string, commentID int, text string) (bool, error) {
+	if editor, ok := baseTracker(tracker).(commentEditor); ok && commentID > 0 {
+		err := editor.EditComment(id, commentID, text)
+		invalidateTrackerCache()
+		return err == nil, err
+	}
+	return false, tracker.Comment(id, text)
+}
+
+// issueQuery selects issues by backend status (open, in_progress, blocked,
+// closed). Limit 0 means no limit.
+type issueQuery struct {
+	Status string
+	Label  string
+	Ready  bool
+	Limit  int
+}
+
+// issueChange is a partial update; empty fields are left alone.
+type issueChange struct {
+	Status       string
+	AddLabels    []string
+	RemoveLabels []string
+}
+
+type newIssue struct {
+	Title       string
+	Type        string
+	Description string
+}
+
+var tracker IssueTracker = newBDTracker()
+
+// bdTracker drives the bd CLI. exec and sleep are swapped in tests. actor,
+// when set, is passed as --actor on every write.
+type bdTracker struct {
+	exec  func(args []string) (bdResult, error)
+	sleep func(time.Duration)
+	actor string
+}
+
+func newBDTracker() bdTracker {
+	return bdTracker{exec: execBD, sleep: time.Sleep}
+}
+
+const (
+	bdMaxAttempts  = 3
+	bdRetryBackoff = 500 * time.Millisecond
+)
+
+// bdResult is one captured bd invocation.
+type bdResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+type bdFailureKind int
+
+const (
+	bdFailureOther bdFailureKind = iota
+	bdFailureMissing
+	bdFailureUnsupported
+	bdFailureNoDatabase
+	bdFailureCorrupt
+	bdFailureTransient
+)
+
+// bdError is a failed bd call. Error() carries bd's own message plus a hint
+// for the failure kinds yoke recognizes.
+type bdError struct {
+	Args     []string
+	Result   bdResult
+	Kind     bdFailureKind
+	Attempts int
+	Err      error
+}
+
+func (e *bdError) Error() string {
+	command := bdSubcommand(e.Args)
+	if e.Kind == bdFailureMissing {
+		return "bd not found on PATH: install beads or set YOKE_ISSUE_BACKEND"
+	}
+	detail := firstNonEmptyLine(e.Result.Stderr)
+	if detail == "" {
+		detail = firstNonEmptyLine(e.Result.Stdout)
+	}
+	if detail == "" && e.Err != nil {
+		detail = e.Err.Error()
+	}
+	message := fmt.Sprintf("%s failed (exit %d)", command, e.Result.ExitCode)
+	if detail != "" {
+		message += ": " + detail
+	}
+	switch e.Kind {
+	case bdFailureUnsupported:
+		message += "; the installed bd does not support `" + command + "`, upgrade bd"
+	case bdFailureNoDatabase:
+		message += "; no bd database here, run `yoke init` or `bd init`"
+	case bdFailureCorrupt:
+		message += "; the bd database looks corrupt, run `bd doctor` or restore a copy from .yoke/" + bdBackupDir
+	case bdFailureTransient:
+		message += fmt.Sprintf("; gave up after %d attempts, check for other bd or yoke processes", e.Attempts)
+	}
+	return message
+}
+
+func (e *bdError) Unwrap() error {
+	return e.Err
+}
+
+// bdSubcommand names the failing call without its (possibly long) arguments.
+func bdSubcommand(args []string) string {
+	words := []string{"bd"}
+	if len(args) > 0 {
+		words = append(words, args[0])
+	}
+	if len(args) > 1 && (args[0] == "comments" || args[0] == "dep") && (args[1] == "add" || args[1] == "list") {
+		words = append(words, args[1])
+	}
+	return strings.Join(words, " ")
+}
+
+func firstNonEmptyLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// classifyBDFailure sorts a failed call by bd's stderr. Lock contention is
+// the only kind worth retrying.
+func classifyBDFailure(result bdResult, err error) bdFailureKind {
+	if errors.Is(err, exec.ErrNotFound) {
+		return bdFailureMissing
+	}
+	text := strings.ToLower(result.Stderr + "\n" + result.Stdout)
+	switch {
+	case strings.Contains(text, "database is locked"), strings.Contains(text, "sqlite_busy"),
+		strings.Contains(text, "resource temporarily unavailable"), strings.Contains(text, "lock held by"):
+		return bdFailureTransient
+	case strings.Contains(text, "unknown command"), strings.Contains(text, "unknown flag"),
+		strings.Contains(text, "unknown shorthand flag"):
+		return bdFailureUnsupported
+	case strings.Contains(text, "malformed"), strings.Contains(text, "not a database"),
+		strings.Contains(text, "no such table"), strings.Contains(text, "corrupt"):
+		return bdFailureCorrupt
+	case strings.Contains(text, "no beads database"), strings.Contains(text, "not initialized"),
+		strings.Contains(text, "bd init"):
+		return bdFailureNoDatabase
+	}
+	return bdFailureOther
+}
+
+// execBD runs bd once. The error is non-nil only when bd could not start;
+// a non-zero exit is reported through ExitCode.
+func execBD(args []string) (bdResult, error) {
+	defer timeCommand("bd", args)()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("bd", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	result := bdResult{Stdout: stdout.String(), Stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+		err = nil
+	}
+	return result, err
+}
+
+// run calls bd and returns its stdout, retrying transient failures.
+func (t bdTracker) run(args ...string) (string, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := t.exec(args)
+		if err == nil && result.ExitCode == 0 {
+			return result.Stdout, nil
+		}
+		failure := &bdError{Args: args, Result: result, Kind: classifyBDFailure(result, err), Attempts: attempt, Err: err}
+		if failure.Kind != bdFailureTransient || attempt >= bdMaxAttempts {
+			return "", failure
+		}
+		t.sleep(time.Duration(attempt) * bdRetryBackoff)
+	}
+}
+
+// bdActorUnsupported remembers that the installed bd rejected --actor, so
+// later writes skip it.
+var bdActorUnsupported atomic.Bool
+
+// mutate runs a state-changing bd call and records it in the audit log.
+func (t bdTracker) mutate(args ...string) (string, error) {
+	output, err := t.runAs(args...)
+	auditCommand("bd", args, err)
+	return output, err
+}
+
+// runAs is run with --actor appended when an actor is configured. A bd
+// without --actor gets the call again without it.
+func (t bdTracker) runAs(args ...string) (string, error) {
+	if t.actor == "" || bdActorUnsupported.Load() {
+		return t.run(args...)
+	}
+	output, err := t.run(append(append([]string{}, args...), "--actor", t.actor)...)
+	var failure *bdError
+	if errors.As(err, &failure) && failure.Kind == bdFailureUnsupported && strings.Contains(failure.Result.Stderr, "actor") {
+		bdActorUnsupported.Store(true)
+		return t.run(args...)
+	}
+	return output, err
+}
+
+// yokeActor expands YOKE_ACTOR for the role yoke is acting as. The role is
+// YOKE_ROLE, which yoke sets for every agent and daemon command it starts;
+// without one yoke is being run by a person and keeps their identity. agent
+// defaults to the agent configured for the role.
+func yokeActor(cfg config, role, agent string) string {
+	role = strings.TrimSpace(role)
+	if cfg.Actor == "" || role == "" {
+		return ""
+	}
+	if agent == "" {
+		agent, _ = agentIDForRole(cfg, role)
+	}
+	actor := strings.NewReplacer("{role}", role, "{agent}", strings.TrimSpace(agent)).Replace(cfg.Actor)
+	return strings.Trim(strings.TrimSpace(actor), "/")
+}
+
+// mutateEcho is mutate for calls whose confirmation bd prints for the user.
+func (t bdTracker) mutateEcho(args ...string) error {
+	output, err := t.mutate(args...)
+	fmt.Print(output)
+	return err
+}
+
+func (t bdTracker) List(query issueQuery) ([]bdListIssue, error) {
+	args := []string{"list", "--status", query.Status}
+	if query.Label != "" {
+		args = append(args, "--label", query.Label)
+	}
+	if query.Ready {
+		args = append(args, "--ready")
+	}
+	args = append(args, "--json", "--limit", strconv.Itoa(query.Limit))
+	output, err := t.run(args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseBDListIssuesJSON(output)
+}
+
+func (t bdTracker) Show(id string) (bdListIssue, error) {
+	output, err := t.run("show", id, "--json")
+	if err != nil {
+		return bdListIssue{}, err
+	}
+	return parseBDShowIssueJSON(output)
+}
+
+func (t bdTracker) Create(issue newIssue) (bdListIssue, error) {
+	output, err := t.mutate("create", issue.Title, "--type", issue.Type, "--description", issue.Description, "--json")
+	if err != nil {
+		return bdListIssue{}, err
+	}
+	return parseBDShowIssueJSON(output)
+}
+
+func (t bdTracker) Update(id string, change issueChange) error {
+	args := []string{"update", id}
+	if change.Status != "" {
+		args = append(args, "--status", change.Status)
+	}
+	for _, label := range change.RemoveLabels {
+		args = append(args, "--remove-label", label)
+	}
+	for _, label := range change.AddLabels {
+		args = append(args, "--add-label", label)
+	}
+	return t.mutateEcho(args...)
+}
+
+func (t bdTracker) Assign(id, assignee string) error {
+	return t.mutateEcho("update", id, "--assignee", assignee)
+}
+
+func (t bdTracker) Close(id, reason string) error {
+	return t.mutateEcho("close", id, "--reason", reason)
+}
+
+func (t bdTracker) Comment(id, text string) error {
+	return t.mutateEcho("comments", "add", id, text)
+}
+
+func (t bdTracker) Comments(id string) ([]bdComment, error) {
+	output, err := t.run("comments", id, "--json")
+	if err != nil {
+		return nil, err
+	}
+	return parseBDCommentsJSON(output)
+}
+
+func (t bdTracker) Children(parent string) ([]bdListIssue, error) {
+	output, err := t.run("children", parent, "--json")
+	if err != nil {
+		return nil, err
+	}
+	return parseBDListIssuesJSON(output)
+}
+
+// Blockers accepts both bd dep list shapes: dependency issues carrying
+// dependency_type, or bare edges whose targets are looked up with bd show.
+func (t bdTracker) Blockers(id string) ([]bdListIssue, error) {
+	output, err := t.run("dep", "list", id, "--json")
+	if err != nil {
+		return nil, err
+	}
+	dependencyIssues, depErr := parseBDListIssuesJSON(output)
+	if depErr == nil && hasDependencyTypeEntries(dependencyIssues) {
+		blockers := []bdListIssue{}
+		for _, dep := range dependencyIssues {
+			if strings.EqualFold(strings.TrimSpace(dep.DependencyType), "blocks") {
+				blockers = append(blockers, dep)
+			}
+		}
+		return blockers, nil
+	}
+
+	edges, edgeErr := parseBDDependencyEdgesJSON(output)
+	if edgeErr != nil {
+		if depErr != nil {
+			return nil, depErr
+		}
+		return nil, edgeErr
+	}
+	return blockersFromEdges(id, edges, t.Show)
+}
+
+// Snapshot loads every issue, closed ones included, with one bd list call.
+func (t bdTracker) Snapshot() (issueSnapshot, error) {
+	output, err := t.run("list", "--all", "--json", "--limit", "0")
+	if err != nil {
+		return issueSnapshot{}, err
+	}
+	payloads, err := decodeBDIssuePayloads(output, "issues")
+	if err != nil {
+		return issueSnapshot{}, bdDecodeError("bd list", err)
+	}
+	return newIssueSnapshot(payloads), nil
+}
+
+// bdTreeUnsupported remembers, for the rest of the process, that the
+// installed bd has no bd tree --json.
+var bdTreeUnsupported atomic.Bool
+
+// Tree fetches rootIssue's descendants with a single bd tree call. Any
+// failure reports ok=false so the caller falls back to bd children; only a
+// missing subcommand or flag is remembered.
+func (t bdTracker) Tree(rootIssue string) ([]bdListIssue, bool) {
+	if bdTreeUnsupported.Load() {
+		return nil, false
+	}
+	output, err := t.run("tree", rootIssue, "--json")
+	if err != nil {
+		var failure *bdError
+		if errors.As(err, &failure) && failure.Kind == bdFailureUnsupported {
+			bdTreeUnsupported.Store(true)
+		}
+		return nil, false
+	}
+	issues, err := parseBDTreeJSON(rootIssue, output)
+	if err != nil {
+		return nil, false
+	}
+	return issues, true
+}
+
+// bdTreeNode is one bd tree entry. Releases either nest children or emit a
+// flat depth-first list with depth (and usually parent) on each entry.
+type bdTreeNode struct {
+	bdIssuePayload
+	Depth    int          `json:"depth"`
+	Children []bdTreeNode `json:"children"`
+}
+
+// parseBDTreeJSON flattens a bd tree payload into rootIssue's descendants,
+// each with Parent filled in.
+func parseBDTreeJSON(rootIssue, raw string) ([]bdListIssue, error) {
+	shape, trimmed, err := detectBDPayloadShape(raw)
+	if err != nil {
+		return nil, bdDecodeError("bd tree", err)
+	}
+	var nodes []bdTreeNode
+	switch shape {
+	case bdShapeEmpty:
+		return nil, errors.New("empty bd tree payload")
+	case bdShapeList:
+		err = json.Unmarshal([]byte(trimmed), &nodes)
+	default:
+		var wrapper map[string]json.RawMessage
+		if err = json.Unmarshal([]byte(trimmed), &wrapper); err != nil {
+			break
+		}
+		if inner, ok := wrapper["issues"]; ok {
+			err = json.Unmarshal(inner, &nodes)
+		} else if inner, ok := wrapper["tree"]; ok {
+			err = decodeBDTreeNodes(inner, &nodes)
+		} else {
+			var single bdTreeNode
+			err = json.Unmarshal([]byte(trimmed), &single)
+			nodes = []bdTreeNode{single}
+		}
+	}
+	if err != nil {
+		return nil, bdDecodeError("bd tree", err)
+	}
+
+	payloads := []bdIssuePayload{}
+	// path holds the ids along the current branch of a flat depth-first
+	// listing, so path[depth-1] is an entry's parent.
+	var path []string
+	var walk func(nodes []bdTreeNode, parent string)
+	walk = func(nodes []bdTreeNode, parent string) {
+		for _, node := range nodes {
+			payload := node.bdIssuePayload
+			payload.ID = strings.TrimSpace(payload.ID)
+			if strings.TrimSpace(payload.Parent) == "" {
+				payload.Parent = parent
+			}
+			if parent == "" && node.Depth >= 0 && node.Depth <= len(path) {
+				if payload.Parent == "" && node.Depth > 0 {
+					payload.Parent = path[node.Depth-1]
+				}
+				path = append(path[:node.Depth], payload.ID)
+			}
+			payloads = append(payloads, payload)
+			walk(node.Children, payload.ID)
+		}
+	}
+	walk(nodes, "")
+
+	issues := []bdListIssue{}
+	for _, issue := range normalizeBDIssuePayloads(payloads) {
+		if issue.ID == "" || strings.EqualFold(issue.ID, strings.TrimSpace(rootIssue)) {
+			continue
+		}
+		// Without a parent the entry cannot be placed; trusting the rest
+		// could hide children, so let the caller fall back.
+		if issue.Parent == "" {
+			return nil, fmt.Errorf("bd tree entry %s has no parent", issue.ID)
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+// decodeBDTreeNodes accepts a single root node or a list of nodes.
+func decodeBDTreeNodes(raw json.RawMessage, nodes *[]bdTreeNode) error {
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+		return json.Unmarshal(raw, nodes)
+	}
+	var single bdTreeNode
+	if err := json.Unmarshal(raw, &single); err != nil {
+		return err
+	}
+	*nodes = []bdTreeNode{single}
+	return nil
+}
+
+// issueSnapshotter is implemented by backends that can list the whole
+// tracker in one call, letting cachingTracker answer tree and dependency
+// queries in memory.
+type issueSnapshotter interface {
+	Snapshot() (issueSnapshot, error)
+}
+
+// issueSnapshot indexes one full listing. Parent and dependency data are
+// only trusted when the listing carried them at all; older bd releases omit
+// both from bd list.
+type issueSnapshot struct {
+	issues          map[string]bdListIssue
+	children        map[string][]bdListIssue
+	edges           map[string][]bdDependencyEdge
+	hasParents      bool
+	hasDependencies bool
+}
+
+func newIssueSnapshot(payloads []bdIssuePayload) issueSnapshot {
+	snapshot := issueSnapshot{
+		issues:   map[string]bdListIssue{},
+		children: map[string][]bdListIssue{},
+		edges:    map[string][]bdDependencyEdge{},
+	}
+	issues := normalizeBDIssuePayloads(payloads)
+	for i, issue := range issues {
+		id := strings.ToLower(issue.ID)
+		snapshot.issues[id] = issue
+		if payloads[i].Dependencies != nil {
+			snapshot.hasDependencies = true
+		}
+		snapshot.edges[id] = payloads[i].dependencyEdges()
+		if issue.Parent != "" {
+			snapshot.hasParents = true
+			parent := strings.ToLower(issue.Parent)
+			snapshot.children[parent] = append(snapshot.children[parent], issue)
+		}
+	}
+	return snapshot
+}
+
+// cachingTracker memoizes reads for the length of one command run and drops
+// everything on any write, so claim's epic walks cost a handful of bd calls
+// instead of one per issue. When the backend can snapshot, children and
+// blockers come from a single full listing.
+type cachingTracker struct {
+	inner IssueTracker
+
+	mu           sync.Mutex
+	shows        map[string]bdListIssue
+	children     map[string][]bdListIssue
+	blockers     map[string][]bdListIssue
+	comments     map[string][]bdComment
+	lists        map[issueQuery][]bdListIssue
+	snapshot     *issueSnapshot
+	snapshotDone bool
+}
+
+func newCachingTracker(inner IssueTracker) *cachingTracker {
+	if cached, ok := inner.(*cachingTracker); ok {
+		return cached
+	}
+	cache := &cachingTracker{inner: inner}
+	cache.Invalidate()
+	return cache
+}
+
+// Invalidate forgets every cached read. Writes call it; so does anything
+// that lets an agent touch the tracker behind yoke's back.
+func (c *cachingTracker) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shows = map[string]bdListIssue{}
+	c.children = map[string][]bdListIssue{}
+	c.blockers = map[string][]bdListIssue{}
+	c.comments = map[string][]bdComment{}
+	c.lists = map[issueQuery][]bdListIssue{}
+	c.snapshot = nil
+	c.snapshotDone = false
+}
+
+// invalidateTrackerCache is Invalidate for code that only holds the global
+// tracker.
+func invalidateTrackerCache() {
+	if cache, ok := tracker.(*cachingTracker); ok {
+		cache.Invalidate()
+	}
+}
+
+// baseTracker unwraps a cachingTracker.
+func baseTracker(t IssueTracker) IssueTracker {
+	if cache, ok := t.(*cachingTracker); ok {
+		return cache.inner
+	}
+	return t
+}
+
+// loadSnapshot returns the full listing, fetching it on first use. It
+// returns nil when the backend cannot snapshot or the listing failed; callers
+// then fall back to per-issue queries.
+func (c *cachingTracker) loadSnapshot() *issueSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.snapshotDone {
+		c.snapshotDone = true
+		if snapshotter, ok := c.inner.(issueSnapshotter); ok {
+			if snapshot, err := snapshotter.Snapshot(); err == nil {
+				c.snapshot = &snapshot
+			}
+		}
+	}
+	return c.snapshot
+}
+
+func (c *cachingTracker) List(query issueQuery) ([]bdListIssue, error) {
+	c.mu.Lock()
+	cached, ok := c.lists[query]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+	issues, err := c.inner.List(query)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.lists[query] = issues
+	c.mu.Unlock()
+	return issues, nil
+}
+
+func (c *cachingTracker) Show(id string) (bdListIssue, error) {
+	key := strings.ToLower(strings.TrimSpace(id))
+	c.mu.Lock()
+	cached, ok := c.shows[key]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+	issue, err := c.inner.Show(id)
+	if err != nil {
+		return bdListIssue{}, err
+	}
+	c.mu.Lock()
+	c.shows[key] = issue
+	c.mu.Unlock()
+	return issue, nil
+}
+
+func (c *cachingTracker) Children(parent string) ([]bdListIssue, error) {
+	key := strings.ToLower(strings.TrimSpace(parent))
+	c.mu.Lock()
+	cached, ok := c.children[key]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+	var children []bdListIssue
+	if snapshot := c.loadSnapshot(); snapshot != nil && snapshot.hasParents {
+		children = append([]bdListIssue{}, snapshot.children[key]...)
+	} else {
+		var err error
+		if children, err = c.inner.Children(parent); err != nil {
+			return nil, err
+		}
+	}
+	c.mu.Lock()
+	c.children[key] = children
+	c.mu.Unlock()
+	return children, nil
+}
+
+func (c *cachingTracker) Blockers(id string) ([]bdListIssue, error) {
+	key := strings.ToLower(strings.TrimSpace(id))
+	c.mu.Lock()
+	cached, ok := c.blockers[key]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+	var blockers []bdListIssue
+	var err error
+	if snapshot := c.loadSnapshot(); snapshot != nil && snapshot.hasDependencies {
+		blockers, err = blockersFromEdges(id, snapshot.edges[key], func(blockerID string) (bdListIssue, error) {
+			if issue, ok := snapshot.issues[strings.ToLower(blockerID)]; ok {
+				return issue, nil
+			}
+			return c.Show(blockerID)
+		})
+	} else {
+		blockers, err = c.inner.Blockers(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.blockers[key] = blockers
+	c.mu.Unlock()
+	return blockers, nil
+}
+
+func (c *cachingTracker) Comments(id string) ([]bdComment, error) {
+	key := strings.ToLower(strings.TrimSpace(id))
+	c.mu.Lock()
+	cached, ok := c.comments[key]
+	c.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+	comments, err := c.inner.Comments(id)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.comments[key] = comments
+	c.mu.Unlock()
+	return comments, nil
+}
+
+func (c *cachingTracker) Create(issue newIssue) (bdListIssue, error) {
+	defer c.Invalidate()
+	return c.inner.Create(issue)
+}
+
+func (c *cachingTracker) Update(id string, change issueChange) error {
+	defer c.Invalidate()
+	return c.inner.Update(id, change)
+}
+
+func (c *cachingTracker) Close(id, reason string) error {
+	defer c.Invalidate()
+	return c.inner.Close(id, reason)
+}
+
+func (c *cachingTracker) Comment(id, text string) error {
+	defer c.Invalidate()
+	return c.inner.Comment(id, text)
+}
+
+// dryRunTracker prints writes as dry-run audit entries instead of making
+// them. Reads see the writes, so a flow that checks its update took effect
+// continues as it would for real.
+type dryRunTracker struct {
+	inner   IssueTracker
+	backend string
+
+	mu       sync.Mutex
+	issues   map[string]bdListIssue
+	comments map[string][]bdComment
+	created  int
+}
+
+func newDryRunTracker(inner IssueTracker, backend string) *dryRunTracker {
+	return &dryRunTracker{inner: inner, backend: backend, issues: map[string]bdListIssue{}, comments: map[string][]bdComment{}}
+}
+
+// overlay returns issue with any dry-run writes applied.
+func (d *dryRunTracker) overlay(issue bdListIssue) bdListIssue {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if changed, ok := d.issues[strings.ToLower(issue.ID)]; ok {
+		return changed
+	}
+	return issue
+}
+
+func (d *dryRunTracker) overlayAll(issues []bdListIssue) []bdListIssue {
+	result := make([]bdListIssue, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, d.overlay(issue))
+	}
+	return result
+}
+
+func (d *dryRunTracker) List(query issueQuery) ([]bdListIssue, error) {
+	issues, err := d.inner.List(query)
+	if err != nil {
+		return nil, err
+	}
+	// Issues the dry run moved into this query's status or label never
+	// come back from the inner tracker, so add them from the overlay.
+	seen := map[string]bool{}
+	candidates := d.overlayAll(issues)
+	for _, issue := range candidates {
+		seen[strings.ToLower(issue.ID)] = true
+	}
+	extra := []bdListIssue{}
+	d.mu.Lock()
+	for key, issue := range d.issues {
+		if !seen[key] {
+			extra = append(extra, issue)
+		}
+	}
+	d.mu.Unlock()
+	sort.Slice(extra, func(i, j int) bool { return extra[i].ID < extra[j].ID })
+	candidates = append(candidates, extra...)
+	matching := []bdListIssue{}
+	for _, issue := range candidates {
+		if query.Status != "" && query.Status != "all" && issue.Status != query.Status {
+			continue
+		}
+		if query.Label != "" && !hasLabel(issue.Labels, query.Label) {
+			continue
+		}
+		matching = append(matching, issue)
+	}
+	return matching, nil
+}
+
+func (d *dryRunTracker) Show(id string) (bdListIssue, error) {
+	d.mu.Lock()
+	changed, ok := d.issues[strings.ToLower(strings.TrimSpace(id))]
+	d.mu.Unlock()
+	if ok {
+		return changed, nil
+	}
+	return d.inner.Show(id)
+}
+
+func (d *dryRunTracker) Children(parent string) ([]bdListIssue, error) {
+	children, err := d.inner.Children(parent)
+	return d.overlayAll(children), err
+}
+
+func (d *dryRunTracker) Blockers(id string) ([]bdListIssue, error) {
+	blockers, err := d.inner.Blockers(id)
+	return d.overlayAll(blockers), err
+}
+
+func (d *dryRunTracker) Comments(id string) ([]bdComment, error) {
+	comments, err := d.inner.Comments(id)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append(comments, d.comments[strings.ToLower(id)]...), nil
+}
+
+func (d *dryRunTracker) Create(issue newIssue) (bdListIssue, error) {
+	d.mu.Lock()
+	d.created++
+	created := bdListIssue{ID: fmt.Sprintf("dry-run-%d", d.created), Title: issue.Title, Description: issue.Description, IssueType: issue.Type, Status: "open"}
+	d.issues[created.ID] = created
+	d.mu.Unlock()
+	logDryRun("", d.backend+" create", []string{issue.Title, "--type", issue.Type, "--description", issue.Description})
+	return created, nil
+}
+
+func (d *dryRunTracker) Update(id string, change issueChange) error {
+	issue, err := d.Show(id)
+	if err != nil {
+		return err
+	}
+	args := []string{id}
+	if change.Status != "" {
+		issue.Status = change.Status
+		args = append(args, "--status", change.Status)
+	}
+	labels := []string{}
+	for _, label := range issue.Labels {
+		if !hasLabel(change.RemoveLabels, label) {
+			labels = append(labels, label)
+		}
+	}
+	for _, label := range change.RemoveLabels {
+		args = append(args, "--remove-label", label)
+	}
+	for _, label := range change.AddLabels {
+		if !hasLabel(labels, label) {
+			labels = append(labels, label)
+		}
+		args = append(args, "--add-label", label)
+	}
+	issue.Labels = labels
+	d.store(issue)
+	logDryRun(id, d.backend+" update", args)
+	return nil
+}
+
+func (d *dryRunTracker) Close(id, reason string) error {
+	issue, err := d.Show(id)
+	if err != nil {
+		return err
+	}
+	issue.Status = "closed"
+	d.store(issue)
+	logDryRun(id, d.backend+" close", []string{id, "--reason", reason})
+	return nil
+}
+
+func (d *dryRunTracker) Comment(id, text string) error {
+	issue, err := d.Show(id)
+	if err != nil {
+		return err
+	}
+	issue.CommentCount++
+	d.store(issue)
+	d.mu.Lock()
+	key := strings.ToLower(id)
+	d.comments[key] = append(d.comments[key], bdComment{IssueID: id, Author: auditActor(), Text: text, CreatedAt: time.Now().UTC().Format(time.RFC3339)})
+	d.mu.Unlock()
+	logDryRun(id, d.backend+" comments add", []string{id, text})
+	return nil
+}
+
+func (d *dryRunTracker) store(issue bdListIssue) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.issues[strings.ToLower(issue.ID)] = issue
+}
+
+const trackerStatusLabelPrefix = "yoke:status:"
+
+// githubIssueTracker implements IssueTracker on GitHub Issues for teams
+// without bd. Ids are <prefix>-<number>. bd's in_progress and blocked
+// statuses are yoke:status:* labels on open issues, closed is the issue
+// state, and type:/priority: labels carry bd's issue type and priority.
+// Children come from sub-issues plus task-list references (- [ ] #12) in the
+// parent body; "Parent: #N" and "Blocked by #N" body lines record the other
+// links.
+type githubIssueTracker struct {
+	client *githubClient
+	prefix string
+}
+
+// githubIssue is the subset of the REST issue object yoke reads.
+type githubIssue struct {
+	Number    int    `json:"number"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	State     string `json:"state"`
+	Comments  int    `json:"comments"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	ClosedAt  string `json:"closed_at"`
+	Labels    []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Assignees []struct {
+		Login string `json:"login"`
+	} `json:"assignees"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+type githubIssueComment struct {
+	ID        int    `json:"id"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+	User      struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+var (
+	githubTaskListRefPattern = regexp.MustCompile(`(?m)^\s*[-*]\s+\[[ xX]\]\s+#(\d+)\b`)
+	githubParentRefPattern   = regexp.MustCompile(`(?im)^\s*parent:\s*#(\d+)\b`)
+	githubBlockedByPattern   = regexp.MustCompile(`(?im)^\s*(?:blocked by|depends on):?\s*(.+)$`)
+	githubIssueRefPattern    = regexp.MustCompile(`#(\d+)\b`)
+)
+
+func (t githubIssueTracker) issueID(number int) string {
+	return t.prefix + "-" + strconv.Itoa(number)
+}
+
+// number extracts the GitHub issue number from a yoke id.
+func (t githubIssueTracker) number(id string) (string, error) {
+	raw := strings.TrimPrefix(strings.TrimSpace(id), t.prefix+"-")
+	if _, err := strconv.Atoi(raw); err != nil || raw == "" {
+		return "", fmt.Errorf("%q is not a GitHub issue id (want %s-<number>)", id, t.prefix)
+	}
+	return raw, nil
+}
+
+func (t githubIssueTracker) issuePath(id string, suffix string) (string, error) {
+	number, err := t.number(id)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/repos/%s/%s/issues/%s%s", t.client.owner, t.client.repo, number, suffix), nil
+}
+
+func (t githubIssueTracker) toIssue(gi githubIssue) bdListIssue {
+	issue := bdListIssue{
+		ID:           t.issueID(gi.Number),
+		Title:        gi.Title,
+		Description:  gi.Body,
+		Status:       "open",
+		IssueType:    "task",
+		Priority:     2,
+		Labels:       []string{},
+		CommentCount: gi.Comments,
+		CreatedAt:    gi.CreatedAt,
+		UpdatedAt:    gi.UpdatedAt,
+		ClosedAt:     gi.ClosedAt,
+	}
+	if len(gi.Assignees) > 0 {
+		issue.Assignee = gi.Assignees[0].Login
+	}
+	for _, label := range gi.Labels {
+		switch {
+		case strings.HasPrefix(label.Name, trackerStatusLabelPrefix):
+			issue.Status = strings.TrimPrefix(label.Name, trackerStatusLabelPrefix)
+		case strings.HasPrefix(label.Name, "type:"):
+			issue.IssueType = strings.TrimPrefix(label.Name, "type:")
+		case strings.HasPrefix(label.Name, "priority:"):
+			if priority, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(label.Name, "priority:"), "P")); err == nil {
+				issue.Priority = priority
+			}
+			issue.Labels = append(issue.Labels, label.Name)
+		default:
+			issue.Labels = append(issue.Labels, label.Name)
+		}
+	}
+	if strings.EqualFold(gi.State, "closed") {
+		issue.Status = "closed"
+	}
+	if match := githubParentRefPattern.FindStringSubmatch(gi.Body); match != nil {
+		issue.Parent = t.prefix + "-" + match[1]
+	}
+	return issue
+}
+
+// listIssues pages through GET /issues, skipping pull requests, until limit
+// issues pass keep (limit 0 means all).
+func (t githubIssueTracker) listIssues(query url.Values, limit int, keep func(bdListIssue) bool) ([]bdListIssue, error) {
+	query.Set("per_page", "100")
+	target := fmt.Sprintf("/repos/%s/%s/issues?%s", t.client.owner, t.client.repo, query.Encode())
+	issues := []bdListIssue{}
+	err := t.client.getPages(target, func(raw json.RawMessage) (bool, error) {
+		var page []githubIssue
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return false, err
+		}
+		for _, gi := range page {
+			if gi.PullRequest != nil {
+				continue
+			}
+			issue := t.toIssue(gi)
+			if keep != nil && !keep(issue) {
+				continue
+			}
+			issues = append(issues, issue)
+			if limit > 0 && len(issues) >= limit {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+func (t githubIssueTracker) List(query issueQuery) ([]bdListIssue, error) {
+	values := url.Values{"state": {"open"}, "sort": {"created"}, "direction": {"asc"}}
+	labels := []string{}
+	switch query.Status {
+	case "closed":
+		values.Set("state", "closed")
+	case "", "open":
+	default:
+		labels = append(labels, trackerStatusLabelPrefix+query.Status)
+	}
+	if query.Label != "" {
+		labels = append(labels, query.Label)
+	}
+	if len(labels) > 0 {
+		values.Set("labels", strings.Join(labels, ","))
+	}
+	var keepErr error
+	return t.listIssues(values, query.Limit, func(issue bdListIssue) bool {
+		if query.Status != "" && issue.Status != query.Status {
+			return false
+		}
+		if !query.Ready || keepErr != nil {
+			return keepErr == nil
+		}
+		blockers, err := t.Blockers(issue.ID)
+		if err != nil {
+			keepErr = err
+			return false
+		}
+		return !hasOpenBlockingDependencies(blockers)
+	})
+}
+
+func (t githubIssueTracker) show(id string) (githubIssue, error) {
+	var gi githubIssue
+	path, err := t.issuePath(id, "")
+	if err != nil {
+		return gi, err
+	}
+	_, err = t.client.do(http.MethodGet, path, nil, &gi)
+	return gi, err
+}
+
+func (t githubIssueTracker) Show(id string) (bdListIssue, error) {
+	gi, err := t.show(id)
+	if err != nil {
+		return bdListIssue{}, err
+	}
+	return t.toIssue(gi), nil
+}
+
+func (t githubIssueTracker) Create(issue newIssue) (bdListIssue, error) {
+	body := map[string]any{"title": issue.Title, "body": issue.Description}
+	if issue.Type != "" {
+		body["labels"] = []string{"type:" + issue.Type}
+	}
+	var gi githubIssue
+	_, err := t.client.do(http.MethodPost, fmt.Sprintf("/repos/%s/%s/issues", t.client.owner, t.client.repo), body, &gi)
+	auditCommand("gh", []string{"issue", "create", "--title", issue.Title}, err)
+	if err != nil {
+		return bdListIssue{}, err
+	}
+	return t.toIssue(gi), nil
+}
+
+// Update maps a status change onto the issue state and yoke:status:* labels,
+// then applies the label edits.
+func (t githubIssueTracker) Update(id string, change issueChange) error {
+	err := t.update(id, change)
+	args := []string{"issue", "edit", id}
+	if change.Status != "" {
+		args = append(args, "--status", change.Status)
+	}
+	for _, label := range change.RemoveLabels {
+		args = append(args, "--remove-label", label)
+	}
+	for _, label := range change.AddLabels {
+		args = append(args, "--add-label", label)
+	}
+	auditCommand("gh", args, err)
+	return err
+}
+
+// Assign replaces the issue's assignees with assignee, or removes them all
+// when assignee is empty.
+func (t githubIssueTracker) Assign(id, assignee string) error {
+	path, err := t.issuePath(id, "")
+	if err == nil {
+		assignees := []string{}
+		if assignee != "" {
+			assignees = append(assignees, assignee)
+		}
+		_, err = t.client.do(http.MethodPatch, path, map[string][]string{"assignees": assignees}, nil)
+	}
+	auditCommand("gh", []string{"issue", "edit", id, "--add-assignee", assignee}, err)
+	return err
+}
+
+func (t githubIssueTracker) update(id string, change issueChange) error {
+	path, err := t.issuePath(id, "")
+	if err != nil {
+		return err
+	}
+	remove := append([]string{}, change.RemoveLabels...)
+	add := append([]string{}, change.AddLabels...)
+	if change.Status != "" {
+		current, err := t.show(id)
+		if err != nil {
+			return err
+		}
+		state := "open"
+		if change.Status == "closed" {
+			state = "closed"
+		}
+		if !strings.EqualFold(current.State, state) {
+			if _, err := t.client.do(http.MethodPatch, path, map[string]string{"state": state}, nil); err != nil {
+				return err
+			}
+		}
+		for _, label := range current.Labels {
+			if strings.HasPrefix(label.Name, trackerStatusLabelPrefix) && label.Name != trackerStatusLabelPrefix+change.Status {
+				remove = append(remove, label.Name)
+			}
+		}
+		if state == "open" && change.Status != "open" {
+			add = append(add, trackerStatusLabelPrefix+change.Status)
+		}
+	}
+	for _, label := range remove {
+		_, err := t.client.do(http.MethodDelete, path+"/labels/"+url.PathEscape(label), nil, nil)
+		var apiErr *githubAPIError
+		if err != nil && !(errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound) {
+			return err
+		}
+	}
+	if len(add) > 0 {
+		if _, err := t.client.do(http.MethodPost, path+"/labels", map[string][]string{"labels": add}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t githubIssueTracker) Close(id, reason string) error {
+	path, err := t.issuePath(id, "")
+	if err == nil {
+		_, err = t.client.do(http.MethodPatch, path, map[string]string{"state": "closed", "state_reason": "completed"}, nil)
+	}
+	if err == nil && strings.TrimSpace(reason) != "" {
+		err = t.comment(id, "yoke: closed ("+reason+")")
+	}
+	auditCommand("gh", []string{"issue", "close", id, "--reason", reason}, err)
+	return err
+}
+
+func (t githubIssueTracker) Comment(id, text string) error {
+	err := t.comment(id, text)
+	auditCommand("gh", []string{"issue", "comment", id, "--body", text}, err)
+	return err
+}
+
+func (t githubIssueTracker) comment(id, text string) error {
+	path, err := t.issuePath(id, "/comments")
+	if err != nil {
+		return err
+	}
+	_, err = t.client.do(http.MethodPost, path, map[string]string{"body": text}, nil)
+	return err
+}
+
+func (t githubIssueTracker) EditComment(id string, commentID int, text string) error {
+	_, err := t.client.do(http.MethodPatch, fmt.Sprintf("/repos/%s/%s/issues/comments/%d", t.client.owner, t.client.repo, commentID), map[string]string{"body": text}, nil)
+	auditCommand("gh", []string{"api", "-X", "PATCH", fmt.Sprintf("repos/{owner}/{repo}/issues/comments/%d", commentID), "-f", "body=" + text}, err)
+	return err
+}
+
+func (t githubIssueTracker) Comments(id string) ([]bdComment, error) {
+	number, err := t.number(id)
+	if err != nil {
+		return nil, err
+	}
+	page, err := t.client.issueComments(number)
+	if err != nil {
+		return nil, err
+	}
+	comments := make([]bdComment, 0, len(page))
+	for _, c := range page {
+		comments = append(comments, bdComment{ID: c.ID, IssueID: id, Author: c.User.Login, Text: c.Body, CreatedAt: c.CreatedAt})
+	}
+	return comments, nil
+}
+
+// Children merges the parent's sub-issues with issues referenced from task
+// lists in its body. Repos without sub-issues answer 404, which is ignored.
+func (t githubIssueTracker) Children(parent string) ([]bdListIssue, error) {
+	gi, err := t.show(parent)
+	if err != nil {
+		return nil, err
+	}
+	path, err := t.issuePath(parent, "/sub_issues?per_page=100")
+	if err != nil {
+		return nil, err
+	}
+	var subIssues []githubIssue
+	if _, err := t.client.do(http.MethodGet, path, nil, &subIssues); err != nil {
+		var apiErr *githubAPIError
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound {
+			return nil, err
+		}
+	}
+
+	seen := map[int]bool{}
+	children := []bdListIssue{}
+	for _, sub := range subIssues {
+		seen[sub.Number] = true
+		child := t.toIssue(sub)
+		child.Parent = parent
+		children = append(children, child)
+	}
+	for _, match := range githubTaskListRefPattern.FindAllStringSubmatch(gi.Body, -1) {
+		number, _ := strconv.Atoi(match[1])
+		if seen[number] {
+			continue
+		}
+		seen[number] = true
+		child, err := t.Show(t.issueID(number))
+		if err != nil {
+			return nil, err
+		}
+		child.Parent = parent
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// Blockers reads "Blocked by #N" / "Depends on #N" lines in the issue body.
+func (t githubIssueTracker) Blockers(id string) ([]bdListIssue, error) {
+	gi, err := t.show(id)
+	if err != nil {
+		return nil, err
+	}
+	edges := []bdDependencyEdge{}
+	for _, line := range githubBlockedByPattern.FindAllStringSubmatch(gi.Body, -1) {
+		for _, ref := range githubIssueRefPattern.FindAllStringSubmatch(line[1], -1) {
+			edges = append(edges, bdDependencyEdge{IssueID: id, DependsOnID: t.prefix + "-" + ref[1], Type: "blocks"})
+		}
+	}
+	return blockersFromEdges(id, edges, t.Show)
+}
+
+// jiraIssueTracker implements IssueTracker on Jira's REST API (v2, so the
+// same code serves Cloud and Data Center). The project key is the issue
+// prefix; ids are handled lowercased like every other yoke id. Statuses map
+// through Jira status categories: To Do is open, In Progress is in_progress,
+// Done is closed, and blocked is reviewStatus when the workflow has it, else
+// the yoke:status:blocked label on an in-progress issue.
+type jiraIssueTracker struct {
+	baseURL      string
+	project      string
+	reviewStatus string
+	authHeader   string
+	http         *http.Client
+	sleep        func(time.Duration)
+}
+
+type jiraStatus struct {
+	Name           string `json:"name"`
+	StatusCategory struct {
+		Key string `json:"key"`
+	} `json:"statusCategory"`
+}
+
+type jiraIssueLink struct {
+	Type struct {
+		Name string `json:"name"`
+	} `json:"type"`
+	InwardIssue *jiraIssue `json:"inwardIssue"`
+}
+
+// jiraIssue is the subset of the REST issue object yoke reads.
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string     `json:"summary"`
+		Description string     `json:"description"`
+		Status      jiraStatus `json:"status"`
+		IssueType   struct {
+			Name string `json:"name"`
+		} `json:"issuetype"`
+		Priority *struct {
+			Name string `json:"name"`
+		} `json:"priority"`
+		Parent *struct {
+			Key string `json:"key"`
+		} `json:"parent"`
+		Assignee *struct {
+			Name      string `json:"name"`
+			AccountID string `json:"accountId"`
+		} `json:"assignee"`
+		Labels         []string        `json:"labels"`
+		IssueLinks     []jiraIssueLink `json:"issuelinks"`
+		Created        string          `json:"created"`
+		Updated        string          `json:"updated"`
+		ResolutionDate string          `json:"resolutiondate"`
+	} `json:"fields"`
+}
+
+type jiraTransition struct {
+	ID string     `json:"id"`
+	To jiraStatus `json:"to"`
+}
+
+const jiraIssueFields = "summary,description,status,issuetype,priority,parent,assignee,labels,issuelinks,created,updated,resolutiondate"
+
+var jiraIssueKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*-[0-9]+$`)
+
+// newJiraIssueTracker authenticates with JIRA_API_TOKEN: basic auth with
+// JIRA_EMAIL on Jira Cloud, or as a bearer personal access token when
+// JIRA_EMAIL is unset (Data Center).
+func newJiraIssueTracker(cfg config) (*jiraIssueTracker, error) {
+	if strings.TrimSpace(cfg.JiraURL) == "" || strings.TrimSpace(cfg.JiraProject) == "" {
+		return nil, errors.New("jira issue backend needs YOKE_JIRA_URL and YOKE_JIRA_PROJECT")
+	}
+	token := strings.TrimSpace(os.Getenv("JIRA_API_TOKEN"))
+	if token == "" {
+		return nil, errors.New("jira issue backend needs JIRA_API_TOKEN")
+	}
+	auth := "Bearer " + token
+	if email := strings.TrimSpace(os.Getenv("JIRA_EMAIL")); email != "" {
+		auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(email+":"+token))
+	}
+	return &jiraIssueTracker{
+		baseURL:      strings.TrimSuffix(strings.TrimSpace(cfg.JiraURL), "/"),
+		project:      strings.ToUpper(strings.TrimSpace(cfg.JiraProject)),
+		reviewStatus: valueOrFallback(strings.TrimSpace(cfg.JiraReviewStatus), defaultJiraReviewStatus),
+		authHeader:   auth,
+		http:         &http.Client{Timeout: 30 * time.Second},
+		sleep:        time.Sleep,
+	}, nil
+}
+
+// do sends one API request, retrying 429 and 5xx responses, and decodes the
+// response into out when out is non-nil.
+func (t *jiraIssueTracker) do(method, path string, body, out any) error {
+	defer timePhase("Jira API", false)()
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = data
+	}
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest(method, t.baseURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", t.authHeader)
+		req.Header.Set("Accept", "application/json")
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := t.http.Do(req)
+		if err != nil {
+			if attempt < githubMaxAttempts {
+				t.sleep(time.Duration(attempt) * time.Second)
+				continue
+			}
+			return err
+		}
+		data, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return readErr
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if out != nil && len(data) > 0 {
+				if err := json.Unmarshal(data, out); err != nil {
+					return fmt.Errorf("decode Jira API %s %s: %w", method, req.URL.Path, err)
+				}
+			}
+			return nil
+		}
+		if attempt < githubMaxAttempts && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500) {
+			wait := time.Duration(attempt) * time.Second
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(seconds) * time.Second
+			}
+			t.sleep(wait)
+			continue
+		}
+		var message struct {
+			ErrorMessages []string          `json:"errorMessages"`
+			Errors        map[string]string `json:"errors"`
+		}
+		detail := http.StatusText(resp.StatusCode)
+		if json.Unmarshal(data, &message) == nil {
+			parts := append([]string{}, message.ErrorMessages...)
+			for field, text := range message.Errors {
+				parts = append(parts, field+": "+text)
+			}
+			sort.Strings(parts)
+			if len(parts) > 0 {
+				detail = strings.Join(parts, "; ")
+			}
+		}
+		return fmt.Errorf("jira API %s %s: %d %s", method, req.URL.Path, resp.StatusCode, detail)
+	}
+}
+
+// key turns a yoke id back into a Jira issue key in the configured project.
+func (t *jiraIssueTracker) key(id string) (string, error) {
+	key := strings.ToUpper(strings.TrimSpace(id))
+	if !jiraIssueKeyPattern.MatchString(key) || !strings.HasPrefix(key, t.project+"-") {
+		return "", fmt.Errorf("%q is not a Jira issue in project %s", id, t.project)
+	}
+	return key, nil
+}
+
+func (t *jiraIssueTracker) statusFor(status jiraStatus, labels []string) string {
+	switch status.StatusCategory.Key {
+	case "new":
+		return "open"
+	case "done":
+		return "closed"
+	}
+	if strings.EqualFold(status.Name, t.reviewStatus) || hasLabel(labels, trackerStatusLabelPrefix+"blocked") {
+		return "blocked"
+	}
+	return "in_progress"
+}
+
+// jiraPriority maps Jira's default priority scheme onto bd's 0 (highest)
+// to 4 (lowest).
+func jiraPriority(name string) int {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "highest", "blocker", "critical":
+		return 0
+	case "high", "major":
+		return 1
+	case "low", "minor":
+		return 3
+	case "lowest", "trivial":
+		return 4
+	default:
+		return 2
+	}
+}
+
+func (t *jiraIssueTracker) toIssue(ji jiraIssue) bdListIssue {
+	issue := bdListIssue{
+		ID:          strings.ToLower(ji.Key),
+		Title:       ji.Fields.Summary,
+		Description: ji.Fields.Description,
+		Status:      t.statusFor(ji.Fields.Status, ji.Fields.Labels),
+		IssueType:   strings.ToLower(valueOrFallback(ji.Fields.IssueType.Name, "task")),
+		Priority:    2,
+		Labels:      []string{},
+		CreatedAt:   ji.Fields.Created,
+		UpdatedAt:   ji.Fields.Updated,
+		ClosedAt:    ji.Fields.ResolutionDate,
+	}
+	if ji.Fields.Priority != nil {
+		issue.Priority = jiraPriority(ji.Fields.Priority.Name)
+	}
+	if ji.Fields.Parent != nil {
+		issue.Parent = strings.ToLower(ji.Fields.Parent.Key)
+	}
+	if ji.Fields.Assignee != nil {
+		issue.Assignee = valueOrFallback(ji.Fields.Assignee.Name, ji.Fields.Assignee.AccountID)
+	}
+	for _, label := range ji.Fields.Labels {
+		if !strings.HasPrefix(label, trackerStatusLabelPrefix) {
+			issue.Labels = append(issue.Labels, label)
+		}
+	}
+	return issue
+}
+
+// search pages through JQL results until limit issues pass keep (limit 0
+// means all).
+func (t *jiraIssueTracker) search(jql string, limit int, keep func(jiraIssue, bdListIssue) bool) ([]bdListIssue, error) {
+	issues := []bdListIssue{}
+	for startAt := 0; ; {
+		var page struct {
+			Issues []jiraIssue `json:"issues"`
+			Total  int         `json:"total"`
+		}
+		err := t.do(http.MethodPost, "/rest/api/2/search", map[string]any{
+			"jql":        jql,
+			"startAt":    startAt,
+			"maxResults": 100,
+			"fields":     strings.Split(jiraIssueFields, ","),
+		}, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, ji := range page.Issues {
+			issue := t.toIssue(ji)
+			if keep != nil && !keep(ji, issue) {
+				continue
+			}
+			issues = append(issues, issue)
+			if limit > 0 && len(issues) >= limit {
+				return issues, nil
+			}
+		}
+		startAt += len(page.Issues)
+		if len(page.Issues) == 0 || startAt >= page.Total {
+			return issues, nil
+		}
+	}
+}
+
+// jqlString quotes value as a JQL string literal.
+func jqlString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func (t *jiraIssueTracker) List(query issueQuery) ([]bdListIssue, error) {
+	clauses := []string{"project = " + jqlString(t.project)}
+	switch query.Status {
+	case "", "open":
+		clauses = append(clauses, `statusCategory = "To Do"`)
+	case "closed":
+		clauses = append(clauses, "statusCategory = Done")
+	default:
+		clauses = append(clauses, `statusCategory = "In Progress"`)
+	}
+	if query.Label != "" {
+		clauses = append(clauses, "labels = "+jqlString(query.Label))
+	}
+	jql := strings.Join(clauses, " AND ") + " ORDER BY created ASC"
+	return t.search(jql, query.Limit, func(ji jiraIssue, issue bdListIssue) bool {
+		if query.Status != "" && issue.Status != query.Status {
+			return false
+		}
+		return !query.Ready || !hasOpenBlockingDependencies(t.blockers(ji))
+	})
+}
+
+func (t *jiraIssueTracker) show(id string) (jiraIssue, error) {
+	var ji jiraIssue
+	key, err := t.key(id)
+	if err != nil {
+		return ji, err
+	}
+	err = t.do(http.MethodGet, "/rest/api/2/issue/"+key+"?fields="+jiraIssueFields, nil, &ji)
+	return ji, err
+}
+
+func (t *jiraIssueTracker) Show(id string) (bdListIssue, error) {
+	ji, err := t.show(id)
+	if err != nil {
+		return bdListIssue{}, err
+	}
+	return t.toIssue(ji), nil
+}
+
+// jiraIssueTypeName maps bd issue types onto Jira's default type names.
+func jiraIssueTypeName(issueType string) string {
+	switch strings.ToLower(strings.TrimSpace(issueType)) {
+	case "bug":
+		return "Bug"
+	case "epic":
+		return "Epic"
+	case "feature":
+		return "Story"
+	default:
+		return "Task"
+	}
+}
+
+func (t *jiraIssueTracker) Create(issue newIssue) (bdListIssue, error) {
+	var created struct {
+		Key string `json:"key"`
+	}
+	err := t.do(http.MethodPost, "/rest/api/2/issue", map[string]any{"fields": map[string]any{
+		"project":     map[string]string{"key": t.project},
+		"summary":     issue.Title,
+		"description": issue.Description,
+		"issuetype":   map[string]string{"name": jiraIssueTypeName(issue.Type)},
+	}}, &created)
+	auditCommand("jira", []string{"create", issue.Title}, err)
+	if err != nil {
+		return bdListIssue{}, err
+	}
+	return t.Show(created.Key)
+}
+
+func (t *jiraIssueTracker) Update(id string, change issueChange) error {
+	err := t.update(id, change)
+	args := []string{"update", strings.ToLower(id)}
+	if change.Status != "" {
+		args = append(args, "--status", change.Status)
+	}
+	for _, label := range change.RemoveLabels {
+		args = append(args, "--remove-label", label)
+	}
+	for _, label := range change.AddLabels {
+		args = append(args, "--add-label", label)
+	}
+	auditCommand("jira", args, err)
+	return err
+}
+
+func (t *jiraIssueTracker) update(id string, change issueChange) error {
+	key, err := t.key(id)
+	if err != nil {
+		return err
+	}
+	remove := append([]string{}, change.RemoveLabels...)
+	add := append([]string{}, change.AddLabels...)
+	if change.Status != "" {
+		labelled, err := t.transition(key, change.Status)
+		if err != nil {
+			return err
+		}
+		if labelled {
+			add = append(add, trackerStatusLabelPrefix+"blocked")
+		} else {
+			remove = append(remove, trackerStatusLabelPrefix+"blocked")
+		}
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return nil
+	}
+	ops := []map[string]string{}
+	for _, label := range remove {
+		ops = append(ops, map[string]string{"remove": label})
+	}
+	for _, label := range add {
+		ops = append(ops, map[string]string{"add": label})
+	}
+	return t.do(http.MethodPut, "/rest/api/2/issue/"+key, map[string]any{"update": map[string]any{"labels": ops}}, nil)
+}
+
+// transition moves key into the status category for a yoke status. It
+// reports whether blocked has to be carried by the yoke:status:blocked label
+// because the workflow has no reviewStatus.
+func (t *jiraIssueTracker) transition(key, status string) (bool, error) {
+	category := map[string]string{"open": "new", "in_progress": "indeterminate", "blocked": "indeterminate", "closed": "done"}[status]
+	if category == "" {
+		return false, fmt.Errorf("unsupported status %q for the jira issue backend", status)
+	}
+	current, err := t.show(key)
+	if err != nil {
+		return false, err
+	}
+	var available struct {
+		Transitions []jiraTransition `json:"transitions"`
+	}
+	if err := t.do(http.MethodGet, "/rest/api/2/issue/"+key+"/transitions", nil, &available); err != nil {
+		return false, err
+	}
+
+	isReview := func(s jiraStatus) bool { return strings.EqualFold(s.Name, t.reviewStatus) }
+	matches := func(s jiraStatus) bool {
+		return s.StatusCategory.Key == category && (status != "in_progress" || !isReview(s))
+	}
+	pick := func(accept func(jiraStatus) bool) *jiraTransition {
+		for i, candidate := range available.Transitions {
+			if accept(candidate.To) {
+				return &available.Transitions[i]
+			}
+		}
+		return nil
+	}
+
+	if status == "blocked" {
+		if isReview(current.Fields.Status) {
+			return false, nil
+		}
+		if chosen := pick(func(s jiraStatus) bool { return matches(s) && isReview(s) }); chosen != nil {
+			return false, t.do(http.MethodPost, "/rest/api/2/issue/"+key+"/transitions", map[string]any{"transition": map[string]string{"id": chosen.ID}}, nil)
+		}
+	}
+	if matches(current.Fields.Status) {
+		return status == "blocked", nil
+	}
+	chosen := pick(matches)
+	if chosen == nil {
+		return false, fmt.Errorf("no Jira transition from %q to a %s status for %s", current.Fields.Status.Name, category, key)
+	}
+	return status == "blocked", t.do(http.MethodPost, "/rest/api/2/issue/"+key+"/transitions", map[string]any{"transition": map[string]string{"id": chosen.ID}}, nil)
+}
+
+func (t *jiraIssueTracker) Close(id, reason string) error {
+	key, err := t.key(id)
+	if err == nil {
+		_, err = t.transition(key, "closed")
+	}
+	if err == nil && strings.TrimSpace(reason) != "" {
+		err = t.comment(key, "yoke: closed ("+reason+")")
+	}
+	auditCommand("jira", []string{"close", strings.ToLower(id), "--reason", reason}, err)
+	return err
+}
+
+func (t *jiraIssueTracker) Comment(id, text string) error {
+	key, err := t.key(id)
+	if err == nil {
+		err = t.comment(key, text)
+	}
+	auditCommand("jira", []string{"comments", "add", strings.ToLower(id), text}, err)
+	return err
+}
+
+func (t *jiraIssueTracker) comment(key, text string) error {
+	return t.do(http.MethodPost, "/rest/api/2/issue/"+key+"/comment", map[string]string{"body": text}, nil)
+}
+
+func (t *jiraIssueTracker) EditComment(id string, commentID int, text string) error {
+	key, err := t.key(id)
+	if err == nil {
+		err = t.do(http.MethodPut, fmt.Sprintf("/rest/api/2/issue/%s/comment/%d", key, commentID), map[string]string{"body": text}, nil)
+	}
+	auditCommand("jira", []string{"comments", "edit", strings.ToLower(id), strconv.Itoa(commentID), text}, err)
+	return err
+}
+
+func (t *jiraIssueTracker) Comments(id string) ([]bdComment, error) {
+	key, err := t.key(id)
+	if err != nil {
+		return nil, err
+	}
+	comments := []bdComment{}
+	for startAt := 0; ; {
+		var page struct {
+			Comments []struct {
+				ID     string `json:"id"`
+				Body   string `json:"body"`
+				Author struct {
+					DisplayName string `json:"displayName"`
+				} `json:"author"`
+				Created string `json:"created"`
+			} `json:"comments"`
+			Total int `json:"total"`
+		}
+		if err := t.do(http.MethodGet, fmt.Sprintf("/rest/api/2/issue/%s/comment?startAt=%d&maxResults=100", key, startAt), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, c := range page.Comments {
+			commentID, _ := strconv.Atoi(c.ID)
+			comments = append(comments, bdComment{ID: commentID, IssueID: strings.ToLower(key), Author: c.Author.DisplayName, Text: c.Body, CreatedAt: c.Created})
+		}
+		startAt += len(page.Comments)
+		if len(page.Comments) == 0 || startAt >= page.Total {
+			return comments, nil
+		}
+	}
+}
+
+// Children finds sub-tasks and epic children by parent. Data Center epics
+// link children through "Epic Link" instead; that clause is dropped on sites
+// without the field.
+func (t *jiraIssueTracker) Children(parent string) ([]bdListIssue, error) {
+	key, err := t.key(parent)
+	if err != nil {
+		return nil, err
+	}
+	children, err := t.search(fmt.Sprintf(`parent = %s OR "Epic Link" = %s ORDER BY created ASC`, key, key), 0, nil)
+	if err != nil {
+		children, err = t.search("parent = "+key+" ORDER BY created ASC", 0, nil)
+	}
+	for i := range children {
+		children[i].Parent = strings.ToLower(key)
+	}
+	return children, err
+}
+
+func (t *jiraIssueTracker) Blockers(id string) ([]bdListIssue, error) {
+	ji, err := t.show(id)
+	if err != nil {
+		return nil, err
+	}
+	return t.blockers(ji), nil
+}
+
+// blockers reads "is blocked by" links; the linked issue's status comes
+// embedded in the link, so no extra lookups are needed.
+func (t *jiraIssueTracker) blockers(ji jiraIssue) []bdListIssue {
+	blockers := []bdListIssue{}
+	for _, link := range ji.Fields.IssueLinks {
+		if !strings.EqualFold(link.Type.Name, "Blocks") || link.InwardIssue == nil {
+			continue
+		}
+		blocker := t.toIssue(*link.InwardIssue)
+		blocker.DependencyType = "blocks"
+		blockers = append(blockers, blocker)
+	}
+	return blockers
+}
+
+const localIssuesDir = "issues"
+
+// localIssueTracker implements IssueTracker on Markdown files under
+// .yoke/issues in the main checkout, so yoke can be evaluated without bd.
+// Each issue is <id>.md: a front-matter block (id, title, status, type,
+// priority, parent, labels, blocked_by, timestamps) followed by the
+// description. Comments live beside it in <id>.comments.jsonl.
+type localIssueTracker struct {
+	dir    string
+	prefix string
+	actor  string
+	now    func() time.Time
+}
+
+// localIssue is the on-disk form; BlockedBy lists blocker ids.
+type localIssue struct {
+	bdListIssue
+	BlockedBy []string
+}
+
+func newLocalIssueTracker(cfg config) *localIssueTracker {
+	return &localIssueTracker{
+		dir:    filepath.Join(mainWorktreeRoot(cfg.Root), ".yoke", localIssuesDir),
+		prefix: cfg.BDPrefix,
+		actor:  yokeActor(cfg, os.Getenv("YOKE_ROLE"), ""),
+		now:    time.Now,
+	}
+}
+
+func (t *localIssueTracker) path(id string) (string, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if !looksLikeIssueID(id, t.prefix) {
+		return "", fmt.Errorf("%q is not a %s issue id", id, t.prefix)
+	}
+	return filepath.Join(t.dir, id+".md"), nil
+}
+
+func (t *localIssueTracker) load(id string) (localIssue, error) {
+	path, err := t.path(id)
+	if err != nil {
+		return localIssue{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return localIssue{}, fmt.Errorf("issue %s not found in %s", id, t.dir)
+		}
+		return localIssue{}, err
+	}
+	return parseLocalIssue(string(data))
+}
+
+func (t *localIssueTracker) save(issue localIssue) error {
+	path, err := t.path(issue.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(renderLocalIssue(issue)), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (t *localIssueTracker) all() ([]localIssue, error) {
+	matches, err := filepath.Glob(filepath.Join(t.dir, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	issues := []localIssue{}
+	for _, match := range matches {
+		data, err := os.ReadFile(match)
+		if err != nil {
+			return nil, err
+		}
+		issue, err := parseLocalIssue(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", match, err)
+		}
+		issues = append(issues, issue)
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Priority != issues[j].Priority {
+			return issues[i].Priority < issues[j].Priority
+		}
+		return issues[i].CreatedAt < issues[j].CreatedAt
+	})
+	return issues, nil
+}
+
+func (t *localIssueTracker) List(query issueQuery) ([]bdListIssue, error) {
+	all, err := t.all()
+	if err != nil {
+		return nil, err
+	}
+	status := map[string]string{}
+	for _, issue := range all {
+		status[issue.ID] = issue.Status
+	}
+	issues := []bdListIssue{}
+	for _, issue := range all {
+		if query.Status != "" && issue.Status != query.Status {
+			continue
+		}
+		if query.Label != "" && !hasLabel(issue.Labels, query.Label) {
+			continue
+		}
+		if query.Ready && anyIssueOpen(issue.BlockedBy, status) {
+			continue
+		}
+		issues = append(issues, issue.bdListIssue)
+		if query.Limit > 0 && len(issues) >= query.Limit {
+			break
+		}
+	}
+	return issues, nil
+}
+
+// anyIssueOpen reports whether any id in ids is not closed; unknown ids
+// count as open so a typo never unblocks work.
+func anyIssueOpen(ids []string, status map[string]string) bool {
+	for _, id := range ids {
+		if status[id] != "closed" {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *localIssueTracker) Show(id string) (bdListIssue, error) {
+	issue, err := t.load(id)
+	return issue.bdListIssue, err
+}
+
+func (t *localIssueTracker) Create(spec newIssue) (bdListIssue, error) {
+	all, err := t.all()
+	if err != nil {
+		return bdListIssue{}, err
+	}
+	next := 1
+	for _, issue := range all {
+		if n, err := strconv.Atoi(strings.TrimPrefix(issue.ID, t.prefix+"-")); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	now := t.now().UTC().Format(time.RFC3339)
+	issue := localIssue{bdListIssue: bdListIssue{
+		ID:          fmt.Sprintf("%s-%d", t.prefix, next),
+		Title:       spec.Title,
+		Description: spec.Description,
+		Status:      "open",
+		IssueType:   valueOrFallback(spec.Type, "task"),
+		Priority:    2,
+		Labels:      []string{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}}
+	err = t.save(issue)
+	auditCommand("local", []string{"create", issue.ID, spec.Title}, err)
+	return issue.bdListIssue, err
+}
+
+func (t *localIssueTracker) Update(id string, change issueChange) error {
+	issue, err := t.load(id)
+	if err == nil {
+		if change.Status != "" {
+			issue.Status = change.Status
+			issue.ClosedAt = ""
+			if change.Status == "closed" {
+				issue.ClosedAt = t.now().UTC().Format(time.RFC3339)
+			}
+		}
+		labels := []string{}
+		for _, label := range issue.Labels {
+			if !hasLabel(change.RemoveLabels, label) {
+				labels = append(labels, label)
+			}
+		}
+		for _, label := range change.AddLabels {
+			if !hasLabel(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+		issue.Labels = labels
+		issue.UpdatedAt = t.now().UTC().Format(time.RFC3339)
+		err = t.save(issue)
+	}
+	args := []string{"update", id}
+	if change.Status != "" {
+		args = append(args, "--status", change.Status)
+	}
+	auditCommand("local", args, err)
+	return err
+}
+
+func (t *localIssueTracker) Assign(id, assignee string) error {
+	issue, err := t.load(id)
+	if err == nil {
+		issue.Assignee = assignee
+		issue.UpdatedAt = t.now().UTC().Format(time.RFC3339)
+		err = t.save(issue)
+	}
+	auditCommand("local", []string{"update", id, "--assignee", assignee}, err)
+	return err
+}
+
+func (t *localIssueTracker) Close(id, reason string) error {
+	if err := t.Update(id, issueChange{Status: "closed"}); err != nil {
+		return err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil
+	}
+	return t.Comment(id, "yoke: closed ("+reason+")")
+}
+
+func (t *localIssueTracker) commentsPath(id string) (string, error) {
+	path, err := t.path(id)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(path, ".md") + ".comments.jsonl", nil
+}
+
+func (t *localIssueTracker) Comment(id, text string) error {
+	path, err := t.commentsPath(id)
+	if err == nil {
+		_, err = t.load(id)
+	}
+	if err == nil {
+		var existing []bdComment
+		existing, err = t.Comments(id)
+		if err == nil {
+			err = appendJSONLine(path, bdComment{
+				ID:        len(existing) + 1,
+				IssueID:   strings.ToLower(id),
+				Author:    valueOrFallback(t.actor, valueOrFallback(os.Getenv("USER"), "yoke")),
+				Text:      text,
+				CreatedAt: t.now().UTC().Format(time.RFC3339),
+			})
+		}
+	}
+	auditCommand("local", []string{"comments", "add", id, text}, err)
+	return err
+}
+
+// EditComment rewrites the comments file with commentID's text replaced.
+func (t *localIssueTracker) EditComment(id string, commentID int, text string) error {
+	path, err := t.commentsPath(id)
+	var comments []bdComment
+	if err == nil {
+		comments, err = t.Comments(id)
+	}
+	if err == nil {
+		found := false
+		var b bytes.Buffer
+		for _, comment := range comments {
+			if comment.ID == commentID {
+				comment.Text = text
+				found = true
+			}
+			line, marshalErr := json.Marshal(comment)
+			if marshalErr != nil {
+				err = marshalErr
+				break
+			}
+			b.Write(append(line, '\n'))
+		}
+		switch {
+		case err != nil:
+		case !found:
+			err = fmt.Errorf("comment %d not found on %s", commentID, id)
+		default:
+			err = os.WriteFile(path, b.Bytes(), 0o644)
+		}
+	}
+	auditCommand("local", []string{"comments", "edit", id, strconv.Itoa(commentID), text}, err)
+	return err
+}
+
+func (t *localIssueTracker) Comments(id string) ([]bdComment, error) {
+	path, err := t.commentsPath(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []bdComment{}, nil
+		}
+		return nil, err
+	}
+	comments := []bdComment{}
+	for lineNumber, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var comment bdComment
+		if err := json.Unmarshal([]byte(line), &comment); err != nil {
+			return nil, fmt.Errorf("parse %s line %d: %w", path, lineNumber+1, err)
+		}
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
+
+func (t *localIssueTracker) Children(parent string) ([]bdListIssue, error) {
+	all, err := t.all()
+	if err != nil {
+		return nil, err
+	}
+	children := []bdListIssue{}
+	for _, issue := range all {
+		if strings.EqualFold(issue.Parent, parent) {
+			children = append(children, issue.bdListIssue)
+		}
+	}
+	return children, nil
+}
+
+func (t *localIssueTracker) Blockers(id string) ([]bdListIssue, error) {
+	issue, err := t.load(id)
+	if err != nil {
+		return nil, err
+	}
+	edges := []bdDependencyEdge{}
+	for _, blocker := range issue.BlockedBy {
+		edges = append(edges, bdDependencyEdge{IssueID: issue.ID, DependsOnID: blocker, Type: "blocks"})
+	}
+	return blockersFromEdges(issue.ID, edges, t.Show)
+}
+
+// parseLocalIssue reads the front matter written by renderLocalIssue. It
+// accepts the YAML subset yoke writes: scalar and quoted-string values and
+// [a, b] lists.
+func parseLocalIssue(raw string) (localIssue, error) {
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(raw, "\ufeff"), "---\n")
+	if !ok {
+		return localIssue{}, errors.New("missing front matter")
+	}
+	header, body, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		header, ok = strings.CutSuffix(rest, "\n---")
+		if !ok {
+			return localIssue{}, errors.New("unterminated front matter")
+		}
+	}
+	issue := localIssue{bdListIssue: bdListIssue{Status: "open", IssueType: "task", Priority: 2, Labels: []string{}}}
+	for _, line := range strings.Split(header, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+			value = unquoted
+		}
+		switch strings.TrimSpace(key) {
+		case "id":
+			issue.ID = strings.ToLower(value)
+		case "title":
+			issue.Title = value
+		case "status":
+			issue.Status = value
+		case "type":
+			issue.IssueType = value
+		case "priority":
+			priority, err := strconv.Atoi(value)
+			if err != nil {
+				return localIssue{}, fmt.Errorf("invalid priority %q", value)
+			}
+			issue.Priority = priority
+		case "estimated_minutes":
+			estimate, err := strconv.Atoi(value)
+			if err != nil || estimate < 0 {
+				return localIssue{}, fmt.Errorf("invalid estimated_minutes %q", value)
+			}
+			issue.Estimate = estimate
+		case "assignee":
+			issue.Assignee = value
+		case "parent":
+			issue.Parent = strings.ToLower(value)
+		case "labels":
+			issue.Labels = parseLocalIssueList(value)
+		case "blocked_by":
+			issue.BlockedBy = parseLocalIssueList(strings.ToLower(value))
+		case "created_at":
+			issue.CreatedAt = value
+		case "updated_at":
+			issue.UpdatedAt = value
+		case "closed_at":
+			issue.ClosedAt = value
+		}
+	}
+	if issue.ID == "" {
+		return localIssue{}, errors.New("front matter missing id")
+	}
+	issue.Description = strings.TrimSpace(body)
+	return issue, nil
+}
+
+func parseLocalIssueList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(strings.Trim(value, "[]"), ",") {
+		item = strings.TrimSpace(item)
+		if unquoted, err := strconv.Unquote(item); err == nil {
+			item = unquoted
+		}
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func renderLocalIssue(issue localIssue) string {
+	quoteList := func(items []string) string {
+		quoted := make([]string, 0, len(items))
+		for _, item := range items {
+			quoted = append(quoted, strconv.Quote(item))
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+	lines := []string{
+		"---",
+		"id: " + issue.ID,
+		"title: " + strconv.Quote(issue.Title),
+		"status: " + issue.Status,
+		"type: " + valueOrFallback(issue.IssueType, "task"),
+		"priority: " + strconv.Itoa(issue.Priority),
+	}
+	if issue.Estimate > 0 {
+		lines = append(lines, "estimated_minutes: "+strconv.Itoa(issue.Estimate))
+	}
+	if issue.Assignee != "" {
+		lines = append(lines, "assignee: "+strconv.Quote(issue.Assignee))
+	}
+	if issue.Parent != "" {
+		lines = append(lines, "parent: "+issue.Parent)
+	}
+	lines = append(lines, "labels: "+quoteList(issue.Labels))
+	if len(issue.BlockedBy) > 0 {
+		lines = append(lines, "blocked_by: "+quoteList(issue.BlockedBy))
+	}
+	lines = append(lines, "created_at: "+issue.CreatedAt, "updated_at: "+issue.UpdatedAt)
+	if issue.ClosedAt != "" {
+		lines = append(lines, "closed_at: "+issue.ClosedAt)
+	}
+	lines = append(lines, "---", "")
+	if description := strings.TrimSpace(issue.Description); description != "" {
+		lines = append(lines, description, "")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// showIssue prints an issue for a human: bd's own rendering for the bd
+// backend, a short summary otherwise.
+func showIssue(issue string) error {
+	if _, ok := baseTracker(tracker).(bdTracker); ok {
+		return runCommand("bd", "show", issue)
+	}
+	details, err := tracker.Show(issue)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s\nStatus: %s\n", details.ID, details.Title, workflowStatusForIssue(details))
+	if len(details.Labels) > 0 {
+		fmt.Println("Labels: " + strings.Join(details.Labels, ", "))
+	}
+	if description := strings.TrimSpace(details.Description); description != "" {
+		fmt.Println()
+		fmt.Println(description)
+	}
+	return nil
+}
+
+// useIssueTracker points tracker at the backend cfg selects
+// (YOKE_ISSUE_BACKEND) and reports whether it is usable.
+func useIssueTracker(cfg config) error {
+	switch cfg.IssueBackend {
+	case "", "bd":
+		bd := newBDTracker()
+		bd.actor = yokeActor(cfg, os.Getenv("YOKE_ROLE"), "")
+		tracker = bd
+		if !commandExists("bd") {
+			return fmt.Errorf("missing required command: bd")
+		}
+	case "github":
+		client, err := githubAPI()
+		if err != nil {
+			return fmt.Errorf("github issue backend: %w", err)
+		}
+		tracker = githubIssueTracker{client: client, prefix: cfg.BDPrefix}
+	case "jira":
+		jira, err := newJiraIssueTracker(cfg)
+		if err != nil {
+			return err
+		}
+		tracker = jira
+	case "local":
+		tracker = newLocalIssueTracker(cfg)
+	default:
+		return fmt.Errorf("unknown issue backend %q", cfg.IssueBackend)
+	}
+	if dryRunMode {
+		tracker = newDryRunTracker(tracker, valueOrFallback(cfg.IssueBackend, defaultIssueBackend))
+	}
+	return nil
+}
+
+// blockersFromEdges resolves the "blocks" edges of issueID, looking each
+// blocker up once.
+func blockersFromEdges(issueID string, edges []bdDependencyEdge, show func(string) (bdListIssue, error)) ([]bdListIssue, error) {
+	normalizedIssueID := strings.TrimSpace(issueID)
+	seen := map[string]bool{}
+	blockers := []bdListIssue{}
+	for _, edge := range edges {
+		if !strings.EqualFold(strings.TrimSpace(edge.Type), "blocks") {
+			continue
+		}
+		edgeIssueID := strings.TrimSpace(edge.IssueID)
+		if edgeIssueID != "" && normalizedIssueID != "" && !strings.EqualFold(edgeIssueID, normalizedIssueID) {
+			continue
+		}
+		blockerID := strings.TrimSpace(edge.DependsOnID)
+		if blockerID == "" || seen[blockerID] {
+			continue
+		}
+		seen[blockerID] = true
+		details, err := show(blockerID)
+		if err != nil {
+			return nil, err
+		}
+		details.DependencyType = "blocks"
+		blockers = append(blockers, details)
+	}
+	return blockers, nil
+}
+
+func listIssuesByStatus(status string, readyOnly bool) ([]bdListIssue, error) {
+	return tracker.List(issueQuery{Status: status, Ready: readyOnly})
+}
+
+func listChildIssues(parent string) ([]bdListIssue, error) {
+	return tracker.Children(parent)
+}
+
+func listIssueComments(issueID string) ([]bdComment, error) {
+	return tracker.Comments(issueID)
+}
+
+// commentQuery narrows a comment listing. bd returns every comment, so the
+// filtering happens here; callers building prompts should always set Since or
+// Limit so long-lived issues do not flood the context.
+type commentQuery struct {
+	Since  time.Time
+	Offset int
+	Limit  int
+}
+
+// commentPage is one window of an issue's comments. Total counts comments
+// matching Since before paging.
+type commentPage struct {
+	Comments []bdComment
+	Total    int
+	HasMore  bool
+}
+
+func listIssueCommentsPage(issueID string, query commentQuery) (commentPage, error) {
+	comments, err := listIssueComments(issueID)
+	if err != nil {
+		return commentPage{}, err
+	}
+	return pageComments(filterCommentsSince(comments, query.Since), query.Offset, query.Limit), nil
+}
+
+// filterCommentsSince keeps comments created after since. Comments whose
+// timestamp cannot be parsed are kept so nothing is silently dropped.
+func filterCommentsSince(comments []bdComment, since time.Time) []bdComment {
+	if since.IsZero() {
+		return comments
+	}
+	filtered := make([]bdComment, 0, len(comments))
+	for _, comment := range comments {
+		created, ok := parseBDTimestamp(comment.CreatedAt)
+		if ok && !created.After(since) {
+			continue
+		}
+		filtered = append(filtered, comment)
+	}
+	return filtered
+}
+
+func pageComments(comments []bdComment, offset, limit int) commentPage {
+	page := commentPage{Total: len(comments)}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(comments) {
+		return page
+	}
+	end := len(comments)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+		page.HasMore = true
+	}
+	page.Comments = comments[offset:end]
+	return page
+}
+
+// latestComments keeps the most recent limit comments, preserving order.
+func latestComments(comments []bdComment, limit int) []bdComment {
+	if limit <= 0 || len(comments) <= limit {
+		return comments
+	}
+	return pageComments(comments, len(comments)-limit, limit).Comments
+}
+
+func latestCommentTime(comments []bdComment) (time.Time, bool) {
+	var latest time.Time
+	found := false
+	for _, comment := range comments {
+		created, ok := parseBDTimestamp(comment.CreatedAt)
+		if ok && created.After(latest) {
+			latest = created
+			found = true
+		}
+	}
+	return latest, found
+}
+
+func commentMarkersPath(root string) string {
+	return filepath.Join(root, ".yoke", "comment-markers.json")
+}
+
+func loadCommentMarkers(root string) map[string]string {
+	markers := map[string]string{}
+	data, err := os.ReadFile(commentMarkersPath(root))
+	if err != nil {
+		return markers
+	}
+	if err := json.Unmarshal(data, &markers); err != nil {
+		return map[string]string{}
+	}
+	return markers
+}
+
+// commentMarker returns the creation time of the last comment already
+// processed for key (for example "clarification:bd-a1b2").
+func commentMarker(root, key string) time.Time {
+	marker, ok := parseBDTimestamp(loadCommentMarkers(root)[key])
+	if !ok {
+		return time.Time{}
+	}
+	return marker
+}
+
+func writeCommentMarker(root, key string, processed time.Time) error {
+	if dryRunMode {
+		return nil
+	}
+	markers := loadCommentMarkers(root)
+	markers[key] = processed.UTC().Format(time.RFC3339Nano)
+	data, err := json.MarshalIndent(markers, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(commentMarkersPath(root)), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(commentMarkersPath(root), append(data, '\n'), 0o644)
+}
+
+func clarificationMarkerKey(issueID string) string {
+	return "clarification:" + issueID
+}
+
+func hasOpenBlockingDependencies(dependencies []bdListIssue) bool {
+	for _, dep := range dependencies {
+		if !strings.EqualFold(strings.TrimSpace(dep.DependencyType), "blocks") {
+			continue
+		}
+		if workflowStatusForIssue(dep) != "closed" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDependencyTypeEntries(dependencies []bdListIssue) bool {
+	for _, dep := range dependencies {
+		if strings.TrimSpace(dep.DependencyType) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func parseBDDependencyEdgesJSON(raw string) ([]bdDependencyEdge, error) {
+	var edgePayload []bdDependencyEdge
+	found, err := decodeBDCollection(raw, "dependencies", &edgePayload)
+	if !found && err == nil {
+		return nil, nil
+	}
+	if err == nil {
+		if edges := filterValidDependencyEdges(edgePayload); len(edges) > 0 {
+			return edges, nil
+		}
+	}
+
+	payloads, issueErr := decodeBDIssuePayloads(raw, "issues")
+	if issueErr != nil {
+		return nil, bdDecodeError("bd dep list", issueErr)
+	}
+
+	edges := make([]bdDependencyEdge, 0)
+	for _, issue := range payloads {
+		edges = append(edges, issue.dependencyEdges()...)
+	}
+	return edges, nil
+}
+
+func filterValidDependencyEdges(edges []bdDependencyEdge) []bdDependencyEdge {
+	valid := make([]bdDependencyEdge, 0, len(edges))
+	for _, edge := range edges {
+		if strings.TrimSpace(edge.IssueID) == "" {
+			continue
+		}
+		if strings.TrimSpace(edge.DependsOnID) == "" {
+			continue
+		}
+		if strings.TrimSpace(edge.Type) == "" {
+			continue
+		}
+		valid = append(valid, edge)
+	}
+	return valid
+}
+
+const (
+	descendantTraversalWorkers  = 8
+	maxDescendantDepth          = 50
+	defaultEpicSizeWarningLimit = 200
+	maxClarificationComments    = 50
+	maxWriterContextComments    = 20
+)
+
+type descendantTraversal struct {
+	Issues       []bdListIssue
+	Cycles       []string
+	DepthLimited bool
+	Stopped      bool
+}
+
+// traverseDescendants walks the parent-child tree breadth-first, fetching each
+// level's children concurrently with a bounded worker pool. Issues seen twice
+// are reported as cycles instead of being revisited, and traversal stops at
+// maxDepth so corrupted trees cannot recurse forever.
+func traverseDescendants(rootIssue string, listChildren func(string) ([]bdListIssue, error), workers, maxDepth int) (descendantTraversal, error) {
+	return traverseDescendantsUntil(rootIssue, listChildren, workers, maxDepth, nil)
+}
+
+// traverseDescendantsUntil is traverseDescendants with an early exit: after
+// each level, stop receives that level's new issues and may end the walk.
+func traverseDescendantsUntil(rootIssue string, listChildren func(string) ([]bdListIssue, error), workers, maxDepth int, stop func([]bdListIssue) (bool, error)) (descendantTraversal, error) {
+	if workers <= 0 {
+		workers = 1
+	}
+	root := strings.TrimSpace(rootIssue)
+	visited := map[string]bool{root: true}
+	var result descendantTraversal
+
+	frontier := []string{root}
+	for depth := 1; len(frontier) > 0; depth++ {
+		if depth > maxDepth {
+			result.DepthLimited = true
+			break
+		}
+
+		children := make([][]bdListIssue, len(frontier))
+		errs := make([]error, len(frontier))
+		sem := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		for i, parent := range frontier {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, parent string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				children[i], errs[i] = listChildren(parent)
+			}(i, parent)
+		}
+		wg.Wait()
+
+		next := make([]string, 0)
+		levelStart := len(result.Issues)
+		for i, parent := range frontier {
+			if errs[i] != nil {
+				return result, errs[i]
+			}
+			for _, child := range children[i] {
+				id := strings.TrimSpace(child.ID)
+				if id == "" {
+					continue
+				}
+				if visited[id] {
+					result.Cycles = append(result.Cycles, parent+" -> "+id)
+					continue
+				}
+				visited[id] = true
+				result.Issues = append(result.Issues, child)
+				next = append(next, id)
+			}
+		}
+		if stop != nil {
+			done, err := stop(result.Issues[levelStart:])
+			if err != nil {
+				return result, err
+			}
+			if done {
+				result.Stopped = true
+				break
+			}
+		}
+		frontier = next
+	}
+	return result, nil
+}
+
+// issueTreeFetcher is implemented by backends that return a whole subtree in
+// one call. ok is false when the backend turns out not to support it.
+type issueTreeFetcher interface {
+	Tree(rootIssue string) (issues []bdListIssue, ok bool)
+}
+
+// descendantChildLister returns the children lookup for walking rootIssue:
+// an in-memory index of one tree fetch when the backend supports it, or
+// per-parent listChildIssues calls otherwise. Either way the walk itself,
+// with its cycle and depth guards, is unchanged.
+func descendantChildLister(rootIssue string) func(string) ([]bdListIssue, error) {
+	fetcher, ok := baseTracker(tracker).(issueTreeFetcher)
+	if !ok {
+		return listChildIssues
+	}
+	issues, ok := fetcher.Tree(rootIssue)
+	if !ok {
+		return listChildIssues
+	}
+	children := map[string][]bdListIssue{}
+	for _, issue := range issues {
+		parent := strings.ToLower(issue.Parent)
+		children[parent] = append(children[parent], issue)
+	}
+	return func(parent string) ([]bdListIssue, error) {
+		return children[strings.ToLower(strings.TrimSpace(parent))], nil
+	}
+}
+
+func collectDescendantIssues(cfg config, rootIssue string) ([]bdListIssue, error) {
+	result, err := traverseDescendants(rootIssue, descendantChildLister(rootIssue), descendantTraversalWorkers, maxDescendantDepth)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range descendantTraversalWarnings(rootIssue, result, cfg.EpicSizeWarning) {
+		note("warning: " + warning)
+	}
+	return result.Issues, nil
+}
+
+func descendantTraversalWarnings(rootIssue string, result descendantTraversal, sizeWarning int) []string {
+	warnings := make([]string, 0)
+	if len(result.Cycles) > 0 {
+		warnings = append(warnings, fmt.Sprintf("parent-child cycle(s) detected under %s: %s", rootIssue, strings.Join(result.Cycles, ", ")))
+	}
+	if result.DepthLimited {
+		warnings = append(warnings, fmt.Sprintf("descendants of %s exceed depth %d; deeper issues were not traversed", rootIssue, maxDescendantDepth))
+	}
+	if sizeWarning > 0 && len(result.Issues) > sizeWarning {
+		warnings = append(warnings, fmt.Sprintf("%s has %d descendants (warning threshold %d)", rootIssue, len(result.Issues), sizeWarning))
+	}
+	return warnings
+}
+
+func collectClarificationContext(root string, cfg config, rootIssue string) ([]clarificationContext, error) {
+	descendants, err := collectDescendantIssues(cfg, rootIssue)
+	if err != nil {
+		return nil, err
+	}
+
+	contexts := make([]clarificationContext, 0)
+	for _, issue := range descendants {
+		if !clarificationTaskReadyForAutoClose(issue) {
+			continue
+		}
+
+		page, err := listIssueCommentsPage(issue.ID, commentQuery{
+			Since: commentMarker(root, clarificationMarkerKey(issue.ID)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("load comments for %s: %w", issue.ID, err)
+		}
+		comments := latestComments(page.Comments, maxClarificationComments)
+		if len(comments) == 0 {
+			continue
+		}
+		contexts = append(contexts, clarificationContext{
+			IssueID:  issue.ID,
+			Title:    issue.Title,
+			Comments: comments,
+		})
+	}
+
+	return contexts, nil
+}
+
+// markClarificationContextProcessed records the newest comment of each
+// clarification task so later improvement runs only see newer comments.
+func markClarificationContextProcessed(root string, contexts []clarificationContext) {
+	for _, item := range contexts {
+		latest, ok := latestCommentTime(item.Comments)
+		if !ok {
+			continue
+		}
+		if err := writeCommentMarker(root, clarificationMarkerKey(item.IssueID), latest); err != nil {
+			note("warning: failed to record clarification comment marker: " + err.Error())
+		}
+	}
+}
+
+func isClarificationNeededTitle(title string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(title)), "clarification needed:")
+}
+
+func clarificationTaskReadyForAutoClose(issue bdListIssue) bool {
+	if !isClarificationNeededTitle(issue.Title) {
+		return false
+	}
+	if issue.CommentCount <= 0 {
+		return false
+	}
+	return workflowStatusForIssue(issue) != "closed"
+}
+
+func closeClarificationTasksWithComments(cfg config, rootIssue string) (int, error) {
+	descendants, err := collectDescendantIssues(cfg, rootIssue)
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, issue := range descendants {
+		if !clarificationTaskReadyForAutoClose(issue) {
+			continue
+		}
+		progress.Info("claim.clarifications", "Auto-closing clarification task with comments: "+issue.ID)
+		if err := tracker.Close(issue.ID, "clarified-by-comment"); err != nil {
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+func collectEpicWorkItemIDs(descendants []bdListIssue) map[string]struct{} {
+	workItemIDs := make(map[string]struct{})
+	for _, issue := range descendants {
+		id := strings.TrimSpace(issue.ID)
+		if id == "" {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(issue.IssueType), "epic") {
+			continue
+		}
+		workItemIDs[id] = struct{}{}
+	}
+	return workItemIDs
+}
+
+func filterClaimCandidatesForEpic(candidates []bdListIssue, workItemIDs map[string]struct{}, hasOpenDeps func(string) (bool, error)) ([]bdListIssue, []string, int, error) {
+	filtered := make([]bdListIssue, 0, len(candidates))
+	skippedBlocked := make([]string, 0)
+	ignoredOutsideEpic := 0
+	for _, candidate := range candidates {
+		id := strings.TrimSpace(candidate.ID)
+		if id == "" {
+			continue
+		}
+		if _, ok := workItemIDs[id]; !ok {
+			ignoredOutsideEpic++
+			continue
+		}
+		hasDeps, err := hasOpenDeps(id)
+		if err != nil {
+			return nil, nil, ignoredOutsideEpic, err
+		}
+		if hasDeps {
+			skippedBlocked = append(skippedBlocked, id)
+			continue
+		}
+		filtered = append(filtered, candidate)
+	}
+	return filtered, skippedBlocked, ignoredOutsideEpic, nil
+}
+
+const (
+	claimScanPageSize   = 25
+	claimLogSampleLimit = 5
+)
+
+// cachedBlockers memoizes blocker lookups for one claim, even across
+// goroutines, so dependency checks and critical-path ranking share them.
+func cachedBlockers(lookup func(string) ([]bdListIssue, error)) func(string) ([]bdListIssue, error) {
+	var mu sync.Mutex
+	cache := map[string][]bdListIssue{}
+	return func(issueID string) ([]bdListIssue, error) {
+		mu.Lock()
+		cached, ok := cache[issueID]
+		mu.Unlock()
+		if ok {
+			return cached, nil
+		}
+		blockers, err := lookup(issueID)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		cache[issueID] = blockers
+		mu.Unlock()
+		return blockers, nil
+	}
+}
+
+// cachedDependencyCheck memoizes blocking-dependency lookups so the same
+// candidate is queried at most once per claim, even across goroutines.
+func cachedDependencyCheck(lookup func(string) (bool, error)) func(string) (bool, error) {
+	var mu sync.Mutex
+	cache := map[string]bool{}
+	return func(issueID string) (bool, error) {
+		mu.Lock()
+		cached, ok := cache[issueID]
+		mu.Unlock()
+		if ok {
+			return cached, nil
+		}
+		blocked, err := lookup(issueID)
+		if err != nil {
+			return false, err
+		}
+		mu.Lock()
+		cache[issueID] = blocked
+		mu.Unlock()
+		return blocked, nil
+	}
+}
+
+func issueIDSet(lists ...[]bdListIssue) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, list := range lists {
+		for _, issue := range list {
+			if id := strings.TrimSpace(issue.ID); id != "" {
+				set[id] = struct{}{}
+			}
+		}
+	}
+	return set
+}
+
+func claimCandidatesInLevel(level []bdListIssue, candidateIDs map[string]struct{}) []string {
+	ids := make([]string, 0)
+	for _, issue := range level {
+		id := strings.TrimSpace(issue.ID)
+		if strings.EqualFold(strings.TrimSpace(issue.IssueType), "epic") {
+			continue
+		}
+		if _, ok := candidateIDs[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// epicClaimScanStop ends the epic scan at the first level holding a child
+// claim would pick. In-progress children win over ready ones wherever they
+// sit in the tree, so while any in-progress candidate exists only one of
+// those ends the scan; a shallow ready child must not strand deeper work.
+func epicClaimScanStop(inProgress, ready []bdListIssue, hasOpenDeps func(string) (bool, error)) func([]bdListIssue) (bool, error) {
+	stopIDs := issueIDSet(inProgress)
+	if len(stopIDs) == 0 {
+		stopIDs = issueIDSet(ready)
+	}
+	return func(level []bdListIssue) (bool, error) {
+		found, err := firstUnblockedIssue(claimCandidatesInLevel(level, stopIDs), hasOpenDeps, claimScanPageSize)
+		return found != "", err
+	}
+}
+
+// firstUnblockedIssue checks candidates page by page, running each page's
+// dependency lookups concurrently, and returns the first unblocked id in order.
+func firstUnblockedIssue(ids []string, hasOpenDeps func(string) (bool, error), pageSize int) (string, error) {
+	if pageSize <= 0 {
+		pageSize = len(ids)
+	}
+	for start := 0; start < len(ids); start += pageSize {
+		end := start + pageSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		page := ids[start:end]
+		blocked := make([]bool, len(page))
+		errs := make([]error, len(page))
+		var wg sync.WaitGroup
+		for i, id := range page {
+			wg.Add(1)
+			go func(i int, id string) {
+				defer wg.Done()
+				blocked[i], errs[i] = hasOpenDeps(id)
+			}(i, id)
+		}
+		wg.Wait()
+		for i, id := range page {
+			if errs[i] != nil {
+				return "", errs[i]
+			}
+			if !blocked[i] {
+				return id, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func summarizeIDs(ids []string, limit int) string {
+	if len(ids) <= limit || limit <= 0 {
+		return strings.Join(ids, ", ")
+	}
+	return fmt.Sprintf("%s (+%d more)", strings.Join(ids[:limit], ", "), len(ids)-limit)
+}
+
+func summarizeEpicClaimScan(scan descendantTraversal, workItems, inProgress, ready int, blocked []string) string {
+	coverage := "full tree"
+	if scan.Stopped {
+		coverage = "stopped early at first claimable child"
+	}
+	summary := fmt.Sprintf("Epic scan: %d descendant(s) scanned (%s), %d work item(s), %d in progress, %d ready, %d blocked",
+		len(scan.Issues), coverage, workItems, inProgress, ready, len(blocked))
+	if len(blocked) > 0 {
+		summary += ": " + summarizeIDs(blocked, claimLogSampleLimit)
+	}
+	return summary + "."
+}
+
+func pickEpicChildToClaim(descendants, inProgress, ready []bdListIssue) (string, bool) {
+	workItems := map[string]bdListIssue{}
+	for _, issue := range descendants {
+		id := strings.TrimSpace(issue.ID)
+		if id == "" {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(issue.IssueType), "epic") {
+			continue
+		}
+		workItems[id] = issue
+	}
+
+	if len(workItems) == 0 {
+		return "", true
+	}
+
+	for _, issue := range append(append([]bdListIssue{}, inProgress...), ready...) {
+		id := strings.TrimSpace(issue.ID)
+		if _, ok := workItems[id]; ok && !isClarificationNeededTitle(workItems[id].Title) {
+			return id, false
+		}
+	}
+
+	for _, issue := range workItems {
+		if workflowStatusForIssue(issue) != "closed" {
+			return "", false
+		}
+	}
+
+	return "", true
+}
+
+// criticalPathScanLimit caps the open epic tasks whose blockers are loaded to
+// rank claim candidates; larger epics keep the ready order.
+const criticalPathScanLimit = 200
+
+// criticalPathRank measures how much of an epic's remaining work waits on a
+// task: Depth is the longest chain of open tasks it blocks, Downstream the
+// number of open tasks transitively blocked by it.
+type criticalPathRank struct {
+	Depth      int
+	Downstream int
+}
+
+// rankCriticalPath ranks every open work item among an epic's scanned
+// descendants by the blocks edges between them. It returns nil when the epic
+// has more than criticalPathScanLimit open work items.
+func rankCriticalPath(descendants []bdListIssue, blockersOf func(string) ([]bdListIssue, error)) (map[string]criticalPathRank, error) {
+	open := []string{}
+	isOpen := map[string]bool{}
+	for _, issue := range descendants {
+		id := strings.ToLower(strings.TrimSpace(issue.ID))
+		if id == "" || isOpen[id] || strings.EqualFold(strings.TrimSpace(issue.IssueType), "epic") || workflowStatusForIssue(issue) == "closed" {
+			continue
+		}
+		open = append(open, id)
+		isOpen[id] = true
+	}
+	if len(open) > criticalPathScanLimit {
+		return nil, nil
+	}
+
+	blocks := map[string][]string{}
+	for _, id := range open {
+		blockers, err := blockersOf(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, blocker := range blockers {
+			blockerID := strings.ToLower(strings.TrimSpace(blocker.ID))
+			if isOpen[blockerID] && blockerID != id {
+				blocks[blockerID] = append(blocks[blockerID], id)
+			}
+		}
+	}
+
+	// Depth is memoized; a task still on the stack is part of a cycle and
+	// contributes nothing, so a malformed graph cannot recurse forever.
+	depths := map[string]int{}
+	onStack := map[string]bool{}
+	var depth func(id string) int
+	depth = func(id string) int {
+		if d, ok := depths[id]; ok {
+			return d
+		}
+		if onStack[id] {
+			return 0
+		}
+		onStack[id] = true
+		best := 0
+		for _, next := range blocks[id] {
+			if d := depth(next) + 1; d > best {
+				best = d
+			}
+		}
+		onStack[id] = false
+		depths[id] = best
+		return best
+	}
+
+	ranks := make(map[string]criticalPathRank, len(open))
+	for _, id := range open {
+		seen := map[string]bool{id: true}
+		queue := append([]string{}, blocks[id]...)
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+			if seen[next] {
+				continue
+			}
+			seen[next] = true
+			queue = append(queue, blocks[next]...)
+		}
+		ranks[id] = criticalPathRank{Depth: depth(id), Downstream: len(seen) - 1}
+	}
+	return ranks, nil
+}
+
+// orderByCriticalPath stably sorts candidates so tasks at the head of the
+// longest blocked chain come first, then those unblocking the most tasks.
+func orderByCriticalPath(issues []bdListIssue, ranks map[string]criticalPathRank) []bdListIssue {
+	ordered := append([]bdListIssue{}, issues...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a := ranks[strings.ToLower(strings.TrimSpace(ordered[i].ID))]
+		b := ranks[strings.ToLower(strings.TrimSpace(ordered[j].ID))]
+		if a.Depth != b.Depth {
+			return a.Depth > b.Depth
+		}
+		return a.Downstream > b.Downstream
+	})
+	return ordered
+}
+
+func resolveClaimIssue(root string, cfg config, issue string, passLimit int, restart bool) (string, bool, error) {
+	progress.Start("claim.resolve", "Loading issue details for "+issue)
+	details, err := issueDetails(issue)
+	if err != nil {
+		return "", false, err
+	}
+	progress.Info("claim.resolve", fmt.Sprintf("Issue %s resolved as type=%s status=%s", details.ID, details.IssueType, workflowStatusForIssue(details)))
+	if !strings.EqualFold(strings.TrimSpace(details.IssueType), "epic") {
+		progress.Finish("claim.resolve", "Issue is not an epic; proceeding with direct claim.")
+		return issue, false, nil
+	}
+	if workflowStatusForIssue(details) == "closed" {
+		progress.Finish("claim.resolve", "Epic is already closed; no child task to claim.")
+		return "", true, nil
+	}
+	if passLimit == 0 {
+		progress.Info("claim.epic-improvement", "Issue is an epic; improvement pass limit is 0, skipping epic improvement cycle.")
+	} else if dryRunMode {
+		progress.Info("claim.epic-improvement", "Issue is an epic; dry run, skipping the epic improvement cycle because its agents edit the epic.")
+	} else {
+		if err := backupBDDatabase(root, cfg, "epic-improvement-"+issue); err != nil {
+			return "", false, err
+		}
+		progress.Info("claim.epic-improvement", fmt.Sprintf("Issue is an epic; running epic improvement cycle (limit=%d pass(es)) before selecting a child task.", passLimit))
+		if err := runEpicImprovementCycle(root, cfg, details, passLimit, restart); err != nil {
+			return "", false, err
+		}
+	}
+	if err := backupBDDatabase(root, cfg, "epic-claim-"+issue); err != nil {
+		return "", false, err
+	}
+	progress.Start("claim.clarifications", "Auto-resolving clarification tasks that have comments.")
+	autoClosedCount, err := closeClarificationTasksWithComments(cfg, issue)
+	if err != nil {
+		return "", false, err
+	}
+	if autoClosedCount == 0 {
+		progress.Finish("claim.clarifications", "No clarification tasks required auto-close.")
+	} else {
+		progress.Finish("claim.clarifications", fmt.Sprintf("Auto-closed %d clarification task(s) from user comments.", autoClosedCount))
+	}
+	progress.Start("claim.candidates", "Loading in-progress issues for possible resume.")
+	inProgress, err := listIssuesByStatus("in_progress", false)
+	if err != nil {
+		return "", false, err
+	}
+	progress.Info("claim.candidates", "Loading ready open issues for fallback selection.")
+	ready, err := listIssuesByStatus("open", true)
+	if err != nil {
+		return "", false, err
+	}
+	inProgress, ready = unclaimedIssues(cfg, inProgress), unclaimedIssues(cfg, ready)
+	progress.Finish("claim.candidates", fmt.Sprintf("Found %d in-progress and %d ready open issue(s) repo-wide.", len(inProgress), len(ready)))
+
+	progress.Start("claim.epic-scan", "Scanning epic descendants for a claimable child task.")
+	blockersOf := cachedBlockers(blockingDependencies)
+	hasOpenDeps := cachedDependencyCheck(func(id string) (bool, error) {
+		blockers, err := blockersOf(id)
+		return hasOpenBlockingDependencies(blockers), err
+	})
+	scan, err := traverseDescendantsUntil(issue, descendantChildLister(issue), descendantTraversalWorkers, maxDescendantDepth, epicClaimScanStop(inProgress, ready, hasOpenDeps))
+	if err != nil {
+		return "", false, err
+	}
+	for _, warning := range descendantTraversalWarnings(issue, scan, cfg.EpicSizeWarning) {
+		progress.Info("claim.epic-scan", "warning: "+warning)
+	}
+	descendants := scan.Issues
+	workItemIDs := collectEpicWorkItemIDs(descendants)
+
+	filteredInProgress, skippedInProgress, _, err := filterClaimCandidatesForEpic(inProgress, workItemIDs, hasOpenDeps)
+	if err != nil {
+		return "", false, err
+	}
+	filteredReady, skippedReady, _, err := filterClaimCandidatesForEpic(ready, workItemIDs, hasOpenDeps)
+	if err != nil {
+		return "", false, err
+	}
+	progress.Info("claim.epic-scan", summarizeEpicClaimScan(scan, len(workItemIDs), len(filteredInProgress), len(filteredReady), append(skippedInProgress, skippedReady...)))
+	if len(filteredInProgress)+len(filteredReady) > 1 {
+		ranks, err := rankCriticalPath(descendants, blockersOf)
+		switch {
+		case err != nil:
+			progress.Info("claim.epic-scan", "warning: could not rank candidates by critical path; keeping ready order: "+err.Error())
+		case ranks == nil:
+			progress.Info("claim.epic-scan", fmt.Sprintf("More than %d open tasks; keeping ready order instead of ranking by critical path.", criticalPathScanLimit))
+		default:
+			filteredInProgress = orderByCriticalPath(filteredInProgress, ranks)
+			filteredReady = orderByCriticalPath(filteredReady, ranks)
+		}
+	}
+
+	target, epicComplete := pickEpicChildToClaim(descendants, filteredInProgress, filteredReady)
+	if target != "" {
+		progress.Finish("claim.epic-scan", "Selected claimable child task: "+target)
+		return target, false, nil
+	}
+	if epicComplete && !scan.DepthLimited {
+		progress.Finish("claim.epic-scan", "All non-epic descendants are closed; closing epic.")
+		currentStatus, err := issueStatus(issue)
+		if err != nil {
+			return "", false, err
+		}
+		if currentStatus != "closed" {
+			progress.Start("claim.epic-close", "Closing epic "+issue+" with reason all-child-tasks-closed.")
+			if err := backupBDDatabase(root, cfg, "epic-close-"+issue); err != nil {
+				return "", false, err
+			}
+			if err := tracker.Close(issue, "all-child-tasks-closed"); err != nil {
+				return "", false, err
+			}
+			if cfg.EpicRetro {
+				progress.Info("claim.epic-close", "Running epic retrospective.")
+				if path, err := runEpicRetrospective(root, cfg, issue); err != nil {
+					progress.Info("claim.epic-close", "warning: epic retrospective failed: "+err.Error())
+				} else {
+					progress.Info("claim.epic-close", "Posted epic retrospective; report saved to "+path)
+				}
+			}
+			// The archive lands in the user's working tree, so claim only
+			// writes it when YOKE_EPIC_ARCHIVE asks for it.
+			if !cfg.EpicArchive {
+				progress.Finish("claim.epic-close", "Closed epic "+issue+"; run yoke epic archive "+issue+" to save its decision record.")
+			} else if dir, err := archiveEpic(root, cfg, issue, ""); err != nil {
+				progress.Finish("claim.epic-close", "warning: failed to archive closed epic: "+err.Error())
+			} else {
+				progress.Finish("claim.epic-close", "Archived epic decision record to "+dir)
+			}
+		} else {
+			progress.Info("claim.epic-close", "Epic already closed; no close command needed.")
+		}
+		return "", true, nil
+	}
+
+	if waiting := unansweredClarificationTasks(issue, descendants); len(waiting) > 0 {
+		err = &clarificationWaitError{Epic: issue, Tasks: waiting}
+		progress.Fail("claim.epic-scan", err)
+		return "", false, err
+	}
+	err = fmt.Errorf("epic %s has no claimable child tasks (all remaining children are blocked or already claimed)", issue)
+	progress.Fail("claim.epic-scan", err)
+	return "", false, err
+}
+
+// clarificationWaitError reports an epic whose remaining work cannot start
+// until a person answers its open clarification tasks.
+type clarificationWaitError struct {
+	Epic  string
+	Tasks []clarificationEntry
+}
+
+func (e *clarificationWaitError) Error() string {
+	ids := make([]string, 0, len(e.Tasks))
+	for _, task := range e.Tasks {
+		ids = append(ids, task.ID)
+	}
+	return fmt.Sprintf("epic %s is waiting for answers to %d clarification task(s): %s (see yoke clarify)", e.Epic, len(ids), strings.Join(ids, ", "))
+}
+
+func unansweredClarificationTasks(epic string, descendants []bdListIssue) []clarificationEntry {
+	tasks := []clarificationEntry{}
+	for _, issue := range descendants {
+		if !isClarificationNeededTitle(issue.Title) || issue.CommentCount > 0 || workflowStatusForIssue(issue) == "closed" {
+			continue
+		}
+		tasks = append(tasks, newClarificationEntry(issue, epic))
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks
+}
+
+const bdBackupDir = "backups"
+
+// backupBDDatabase snapshots bd state before bulk or destructive operations.
+// A configured YOKE_BD_BACKUP_CMD takes precedence; otherwise the bd database
+// files are copied into .yoke/backups when YOKE_BD_BACKUP_KEEP is positive.
+func backupBDDatabase(root string, cfg config, reason string) error {
+	if dryRunMode {
+		return nil
+	}
+	if strings.TrimSpace(cfg.BackupCmd) != "" {
+		note("Running bd backup command (" + reason + ")")
+		cmd := exec.Command("bash", "-lc", cfg.BackupCmd)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Dir = root
+		cmd.Env = append(os.Environ(),
+			"ROOT_DIR="+root,
+			"YOKE_BACKUP_REASON="+reason,
+		)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("bd backup command failed: %w", err)
+		}
+		return nil
+	}
+	if cfg.BackupKeep <= 0 {
+		return nil
+	}
+
+	if cfg.IssueBackend != "" && cfg.IssueBackend != "bd" {
+		return nil
+	}
+
+	sources := bdDatabaseFiles(root)
+	if len(sources) == 0 {
+		note("warning: no bd database files found; skipping bd backup")
+		return nil
+	}
+
+	backupsRoot := filepath.Join(root, ".yoke", bdBackupDir)
+	target := filepath.Join(backupsRoot, time.Now().UTC().Format("20060102T150405Z")+"-"+sanitizePathSegment(reason))
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return err
+	}
+	for _, source := range sources {
+		if err := copyFile(source, filepath.Join(target, filepath.Base(source))); err != nil {
+			return fmt.Errorf("backup %s: %w", source, err)
+		}
+	}
+	note("Backed up bd database to " + target)
+	return pruneBDBackups(backupsRoot, cfg.BackupKeep)
+}
+
+func bdDatabaseFiles(root string) []string {
+	if explicit := strings.TrimSpace(os.Getenv("BEADS_DB")); explicit != "" {
+		if fileExists(explicit) {
+			return []string{explicit}
+		}
+		return nil
+	}
+	matches, err := filepath.Glob(filepath.Join(root, ".beads", "*.db"))
+	if err != nil {
+		return nil
+	}
+	return matches
+}
+
+func pruneBDBackups(backupsRoot string, keep int) error {
+	entries, err := os.ReadDir(backupsRoot)
+	if err != nil {
+		return err
+	}
+	snapshots := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			snapshots = append(snapshots, entry.Name())
+		}
+	}
+	sort.Strings(snapshots)
+	for len(snapshots) > keep {
+		if err := os.RemoveAll(filepath.Join(backupsRoot, snapshots[0])); err != nil {
+			return err
+		}
+		snapshots = snapshots[1:]
+	}
+	return nil
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+type epicImprovementPassReport struct {
+	Pass    int    `json:"pass"`
+	Role    string `json:"role"`
+	AgentID string `json:"agent"`
+	Output  string `json:"output"`
+	Changes string `json:"changes,omitempty"`
+}
+
+// epicImprovementCycleState records the passes of an unfinished improvement
+// cycle in its reports directory, so an interrupted cycle resumes at the
+// first incomplete pass. It is removed once the cycle completes.
+type epicImprovementCycleState struct {
+	Plan   string                      `json:"plan"`
+	Start  epicTreeSnapshot            `json:"start,omitempty"`
+	Passes []epicImprovementPassReport `json:"passes"`
+}
+
+const epicImprovementStateFile = "cycle-state.json"
+
+// loadEpicImprovementProgress returns the state left by an earlier run of the
+// same plan, trimmed to its completed passes; a changed plan starts over.
+func loadEpicImprovementProgress(reportsDir string, plan []epicPassStep) epicImprovementCycleState {
+	data, err := os.ReadFile(filepath.Join(reportsDir, epicImprovementStateFile))
+	if err != nil {
+		return epicImprovementCycleState{}
+	}
+	var state epicImprovementCycleState
+	if err := json.Unmarshal(data, &state); err != nil || state.Plan != formatEpicPassPlan(plan) {
+		return epicImprovementCycleState{}
+	}
+	for i, report := range state.Passes {
+		if report.Pass != i+1 || i >= len(plan) || report.Role != plan[i].Role {
+			state.Passes = state.Passes[:i]
+			break
+		}
+	}
+	return state
+}
+
+func saveEpicImprovementProgress(reportsDir string, plan []epicPassStep, start epicTreeSnapshot, reports []epicImprovementPassReport) error {
+	data, err := json.MarshalIndent(epicImprovementCycleState{Plan: formatEpicPassPlan(plan), Start: start, Passes: reports}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(reportsDir, epicImprovementStateFile), append(data, '\n'), 0o644)
+}
+
+func runEpicImprovementCycle(root string, cfg config, epic bdListIssue, passLimit int, restart bool) error {
+	plan := cfg.EpicPassPlan
+	if len(plan) == 0 {
+		plan = mustParseEpicPassPlan(defaultEpicPassPlan)
+	}
+	if passLimit == allEpicPasses {
+		passLimit = len(plan)
+	}
+	if passLimit < 0 || passLimit > len(plan) {
+		return fmt.Errorf("improvement pass limit must be between 0 and %d (the passes in YOKE_EPIC_PASS_PLAN)", len(plan))
+	}
+	if passLimit == 0 {
+		progress.Info("claim.epic-improvement", "Epic improvement pass limit is 0; skipping epic improvement cycle.")
+		return nil
+	}
+	if strings.TrimSpace(epicImprovementPromptTemplate) == "" {
+		return errors.New("epic improvement prompt template is empty")
+	}
+	progress.Info("claim.epic-improvement", "Checking for clarification tasks with comments before starting passes.")
+	clarificationContext, err := collectClarificationContext(root, cfg, epic.ID)
+	if err != nil {
+		return err
+	}
+	if hasLabel(epic.Labels, epicImprovementCompleteLabel) {
+		if len(clarificationContext) == 0 {
+			progress.Info("claim.epic-improvement", "Epic improvement cycle already complete (label present); skipping rerun.")
+			return nil
+		}
+		progress.Info("claim.epic-improvement", fmt.Sprintf("Epic improvement already marked complete, but found %d clarification task(s) with comments; re-running improvement cycle.", len(clarificationContext)))
+	}
+	if len(clarificationContext) == 0 {
+		progress.Info("claim.epic-improvement", "No clarification tasks with comments found.")
+	} else {
+		progress.Info("claim.epic-improvement", fmt.Sprintf("Found %d clarification task(s) with comments; injecting context into prompts.", len(clarificationContext)))
+		for _, item := range clarificationContext {
+			progress.Info("claim.epic-improvement", fmt.Sprintf("Loaded clarification context: %s (%d comment(s))", item.IssueID, len(item.Comments)))
+		}
+	}
+
+	templates := make([]string, passLimit)
+	for i := range templates {
+		if templates[i], err = epicPassPromptTemplate(root, plan[i].Role); err != nil {
+			return err
+		}
+		vars := promptVariables(root, cfg, epic.ID, templates[i])
+		vars["EPIC_ID"] = epic.ID
+		templates[i] = renderPromptTemplate(templates[i], vars)
+	}
+
+	progress.Start("claim.epic-improvement", fmt.Sprintf("Starting epic improvement cycle for %s (%d pass(es)).", epic.ID, passLimit))
+	reportsDir := filepath.Join(root, ".yoke", "epic-improvement-reports", sanitizePathSegment(epic.ID))
+	progress.Info("claim.epic-improvement", "Improvement reports directory: "+reportsDir)
+	if err := os.MkdirAll(reportsDir, 0o755); err != nil {
+		return err
+	}
+	reports := make([]epicImprovementPassReport, 0, passLimit)
+	var start epicTreeSnapshot
+	if restart {
+		if err := os.Remove(filepath.Join(reportsDir, epicImprovementStateFile)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else if state := loadEpicImprovementProgress(reportsDir, plan); len(state.Passes) > 0 {
+		done := state.Passes
+		if len(done) > passLimit {
+			done = done[:passLimit]
+		}
+		reports = append(reports, done...)
+		start = state.Start
+		progress.Info("claim.epic-improvement", fmt.Sprintf("Resuming interrupted cycle after %d completed pass(es); use --restart to start over.", len(done)))
+	}
+	// Snapshots of the descendant tree around each pass feed the structural
+	// diffs in the reports; a failed snapshot only drops that diff.
+	current, err := snapshotEpicTree(epic.ID)
+	if err != nil {
+		note("warning: cannot snapshot the epic tree; reports will not list bd changes: " + err.Error())
+		current = nil
+	}
+	if start == nil {
+		start = current
+	}
+	progress.Info("claim.epic-improvement", "Marking epic as improvement-running.")
+	if err := tracker.Update(epic.ID, issueChange{AddLabels: []string{epicImprovementRunningLabel}}); err != nil {
+		return err
+	}
+
+	for pass := len(reports) + 1; pass <= passLimit; pass++ {
+		step := plan[pass-1]
+		role := step.Role
+		agentID, err := agentIDForPassStep(cfg, step)
+		if err != nil {
+			return err
+		}
+		progress.Start("claim.epic-improvement.pass", fmt.Sprintf("Improvement pass %d/%d starting (role=%s, agent=%s).", pass, passLimit, role, agentID))
+
+		prompt := buildEpicImprovementPassPrompt(templates[pass-1], epic.ID, pass, passLimit, role, clarificationContext)
+		passStarted := time.Now()
+		output, runErr := runAgentPrompt(agentID, root, prompt, []string{
+			"ISSUE_ID=" + epic.ID,
+			"ROOT_DIR=" + root,
+			"BD_PREFIX=" + cfg.BDPrefix,
+			"YOKE_ROLE=" + role,
+			"YOKE_EPIC_IMPROVEMENT_PASS=" + strconv.Itoa(pass),
+		}, fmt.Sprintf("[claim][pass %d/%d %s] ", pass, passLimit, role))
+		recordSessionLog(root, sessionLogEntry{IssueID: epic.ID, Role: role, Source: fmt.Sprintf("epic-improvement-pass-%d", pass), AgentID: agentID}, passStarted, output, runErr)
+		recordAgentUsage(root, usageRecord{IssueID: epic.ID, EpicID: epic.ID, Role: role, AgentID: agentID}, output)
+
+		output = processAgentOutput(output)
+		changes := ""
+		after, snapErr := snapshotEpicTree(epic.ID)
+		if snapErr != nil {
+			note("warning: cannot snapshot the epic tree after pass " + strconv.Itoa(pass) + ": " + snapErr.Error())
+			after = nil
+		} else if current != nil {
+			changes = formatEpicTreeDiff(diffEpicTree(current, after))
+		}
+		current = after
+		reportPath := filepath.Join(reportsDir, fmt.Sprintf("pass-%02d-%s.md", pass, role))
+		if err := writeEpicImprovementPassReport(reportPath, epic.ID, pass, role, agentID, output, changes, runErr); err != nil {
+			return err
+		}
+		progress.Info("claim.epic-improvement.pass", "Saved improvement pass report: "+reportPath)
+		if runErr != nil {
+			err := fmt.Errorf("epic improvement pass %d (%s) failed: %w (report: %s)", pass, role, runErr, reportPath)
+			progress.Fail("claim.epic-improvement.pass", err)
+			return err
+		}
+		progress.Finish("claim.epic-improvement.pass", fmt.Sprintf("Improvement pass %d/%d completed.", pass, passLimit))
+		if err := enforceIssueBudget(root, cfg, epic.ID, cfg.MaxIssueCost); err != nil {
+			return err
+		}
+
+		reports = append(reports, epicImprovementPassReport{
+			Pass:    pass,
+			Role:    role,
+			AgentID: agentID,
+			Output:  output,
+			Changes: changes,
+		})
+		if err := saveEpicImprovementProgress(reportsDir, plan, start, reports); err != nil {
+			return err
+		}
+		if pass < passLimit && unchangedEpicPassStreak(reports) >= maxUnchangedEpicPasses {
+			note(fmt.Sprintf("Stopping the improvement cycle after pass %d/%d: the last %d passes changed nothing in bd.", pass, passLimit, maxUnchangedEpicPasses))
+			break
+		}
+	}
+
+	summaryAgentID, err := agentIDForRole(cfg, "reviewer")
+	if err != nil {
+		return err
+	}
+	progress.Start("claim.epic-improvement.summary", "Generating final improvement summary with reviewer agent "+summaryAgentID+".")
+	summaryPrompt := buildEpicImprovementSummaryPrompt(epic, reports)
+	summaryStarted := time.Now()
+	summary, runErr := runAgentPrompt(summaryAgentID, root, summaryPrompt, []string{
+		"ISSUE_ID=" + epic.ID,
+		"ROOT_DIR=" + root,
+		"BD_PREFIX=" + cfg.BDPrefix,
+		"YOKE_ROLE=reviewer",
+		"YOKE_EPIC_IMPROVEMENT_SUMMARY=1",
+	}, "[claim][summary] ")
+	recordSessionLog(root, sessionLogEntry{IssueID: epic.ID, Role: "reviewer", Source: "epic-improvement-summary", AgentID: summaryAgentID}, summaryStarted, summary, runErr)
+	recordAgentUsage(root, usageRecord{IssueID: epic.ID, EpicID: epic.ID, Role: "reviewer", AgentID: summaryAgentID}, summary)
+	summary = processAgentOutput(summary)
+	cycleChanges := ""
+	if start != nil && current != nil {
+		cycleChanges = formatEpicTreeDiff(diffEpicTree(start, current))
+	}
+	summaryPath := filepath.Join(reportsDir, "summary.md")
+	if err := writeEpicImprovementSummary(summaryPath, epic.ID, summaryAgentID, summary, cycleChanges, runErr); err != nil {
+		return err
+	}
+	progress.Info("claim.epic-improvement.summary", "Saved improvement summary report: "+summaryPath)
+	if runErr != nil {
+		err := fmt.Errorf("epic improvement summary failed: %w (report: %s)", runErr, summaryPath)
+		progress.Fail("claim.epic-improvement.summary", err)
+		return err
+	}
+
+	progress.Finish("claim.epic-improvement.summary", "Posting improvement summary comment to epic "+epic.ID+".")
+	usage := epicUsageTotals(root, epic.ID)
+	comment := formatEpicImprovementSummaryComment(epic, summary, len(reports), reportsDir, usage, cycleChanges)
+	if err := tracker.Comment(epic.ID, comment); err != nil {
+		return err
+	}
+	markClarificationContextProcessed(root, clarificationContext)
+	progress.Info("claim.epic-improvement", "Marking epic improvement complete and clearing running label.")
+	if err := tracker.Update(epic.ID, issueChange{AddLabels: []string{epicImprovementCompleteLabel}, RemoveLabels: []string{epicImprovementRunningLabel}}); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(reportsDir, epicImprovementStateFile)); err != nil && !os.IsNotExist(err) {
+		note("warning: failed to clear epic improvement progress: " + err.Error())
+	}
+
+	progress.Finish("claim.epic-improvement", fmt.Sprintf("Completed epic improvement cycle for %s; reports saved in %s", epic.ID, reportsDir))
+	return nil
+}
+
+// epicPassStep is one YOKE_EPIC_PASS_PLAN entry: a role name, with an
+// optional agent overriding the one configured for that role.
+type epicPassStep struct {
+	Role  string
+	Agent string
+}
+
+var epicPassRolePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// parseEpicPassPlan parses a comma-separated pass plan such as
+// "planner:claude,writer,reviewer,reviewer".
+func parseEpicPassPlan(raw string) ([]epicPassStep, error) {
+	plan := []epicPassStep{}
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		role, agent, hasAgent := strings.Cut(item, ":")
+		role = strings.ToLower(strings.TrimSpace(role))
+		if !epicPassRolePattern.MatchString(role) {
+			return nil, fmt.Errorf("invalid role %q (want lowercase letters, digits, and dashes)", role)
+		}
+		step := epicPassStep{Role: role}
+		if hasAgent {
+			normalized, ok := normalizeAgentID(strings.TrimSpace(agent))
+			if !ok {
+				return nil, fmt.Errorf("unsupported agent %q for role %s", strings.TrimSpace(agent), role)
+			}
+			step.Agent = normalized
+		}
+		plan = append(plan, step)
+	}
+	if len(plan) == 0 {
+		return nil, errors.New("plan needs at least one pass")
+	}
+	return plan, nil
+}
+
+func mustParseEpicPassPlan(raw string) []epicPassStep {
+	plan, err := parseEpicPassPlan(raw)
+	if err != nil {
+		panic(err)
+	}
+	return plan
+}
+
+func formatEpicPassPlan(plan []epicPassStep) string {
+	items := make([]string, 0, len(plan))
+	for _, step := range plan {
+		if step.Agent != "" {
+			items = append(items, step.Role+":"+step.Agent)
+			continue
+		}
+		items = append(items, step.Role)
+	}
+	return strings.Join(items, ",")
+}
+
+// agentIDForPassStep resolves the agent for an epic improvement pass: the
+// step's own agent, else the role's agent. Custom roles fall back to the
+// writer agent.
+func agentIDForPassStep(cfg config, step epicPassStep) (string, error) {
+	if step.Agent != "" {
+		return step.Agent, nil
+	}
+	switch step.Role {
+	case "writer", "reviewer":
+		return agentIDForRole(cfg, step.Role)
+	}
+	return agentIDForRole(cfg, "writer")
+}
+
+// epicPassPromptTemplate returns the prompt for an improvement pass role:
+// .yoke/prompts/epic-<role>.md, else .yoke/prompts/epic-improvement-cycle.md,
+// else the built-in improvement protocol.
+func epicPassPromptTemplate(root, role string) (string, error) {
+	base, err := loadPromptTemplate(root, promptOverride{File: "epic-improvement-cycle.md", Builtin: epicImprovementPromptTemplate, Required: []string{"$EPIC_ID"}, Vars: []string{"EPIC_ID"}})
+	if err != nil {
+		return "", err
+	}
+	return loadPromptTemplate(root, promptOverride{File: "epic-" + role + ".md", Builtin: base, Required: []string{"$EPIC_ID"}, Vars: []string{"EPIC_ID"}})
+}
+
+// promptOverride is a built-in prompt that a repository may replace with
+// .yoke/prompts/<File>. Required lists the placeholders an override must
+// keep for yoke to fill in; Vars lists the prompt's own placeholders on top
+// of promptCommonVars.
+type promptOverride struct {
+	File     string
+	Builtin  string
+	Required []string
+	Vars     []string
+}
+
+// promptCommonVars are the placeholders every prompt can use; see
+// promptVariables for their values.
+var promptCommonVars = []string{
+	"ISSUE_ID", "ISSUE_TITLE", "ISSUE_TYPE", "ISSUE_STATUS", "ISSUE_LABELS", "ISSUE_DESCRIPTION", "ISSUE_ACCEPTANCE",
+	"REPO_NAME", "REPO_ROOT", "BASE_BRANCH", "BD_PREFIX", "RECENT_COMMITS",
+}
+
+var (
+	promptPlaceholderPattern = regexp.MustCompile(`\$(\$|[A-Z][A-Z0-9_]*)`)
+	promptPartialPattern     = regexp.MustCompile(`\{\{>\s*([a-z0-9][a-z0-9_-]*)\s*\}\}`)
+)
+
+const (
+	maxPromptPartialDepth = 5
+	promptRecentCommits   = 10
+)
+
+// loadPromptTemplate returns the repository override for a prompt when one
+// exists and is non-empty, else the built-in text. Overrides have their
+// {{> name}} partials expanded and are checked up front: a missing required
+// placeholder or an unknown one is an error rather than a prompt that
+// silently lacks its context.
+func loadPromptTemplate(root string, prompt promptOverride) (string, error) {
+	path := filepath.Join(root, ".yoke", "prompts", prompt.File)
+	data, err := os.ReadFile(path)
+	if err != nil || strings.TrimSpace(string(data)) == "" {
+		return prompt.Builtin, nil
+	}
+	text, err := expandPromptPartials(root, string(data), 0)
+	if err != nil {
+		return "", fmt.Errorf("prompt override %s: %w", path, err)
+	}
+	missing := []string{}
+	for _, placeholder := range prompt.Required {
+		if !strings.Contains(text, placeholder) {
+			missing = append(missing, placeholder)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("prompt override %s is missing placeholder(s) %s", path, strings.Join(missing, ", "))
+	}
+	if unknown := unknownPromptPlaceholders(text, append(append([]string{}, promptCommonVars...), prompt.Vars...)); len(unknown) > 0 {
+		return "", fmt.Errorf("prompt override %s uses unknown placeholder(s) %s (write $$ for a literal $)", path, strings.Join(unknown, ", "))
+	}
+	return text, nil
+}
+
+// expandPromptPartials replaces each {{> name}} with the contents of
+// .yoke/prompts/partials/<name>.md. Partials may include other partials.
+func expandPromptPartials(root, text string, depth int) (string, error) {
+	if !promptPartialPattern.MatchString(text) {
+		return text, nil
+	}
+	if depth >= maxPromptPartialDepth {
+		return "", fmt.Errorf("partials nested more than %d deep (include cycle?)", maxPromptPartialDepth)
+	}
+	var expandErr error
+	expanded := promptPartialPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := promptPartialPattern.FindStringSubmatch(match)[1]
+		data, err := os.ReadFile(filepath.Join(root, ".yoke", "prompts", "partials", name+".md"))
+		if err != nil {
+			if expandErr == nil {
+				expandErr = fmt.Errorf("partial %q not found in .yoke/prompts/partials", name)
+			}
+			return match
+		}
+		return strings.TrimRight(string(data), "\n")
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	return expandPromptPartials(root, expanded, depth+1)
+}
+
+// unknownPromptPlaceholders lists the $NAME placeholders in text that are
+// not in known, in order of first use.
+func unknownPromptPlaceholders(text string, known []string) []string {
+	seen := map[string]bool{"$": true}
+	for _, name := range known {
+		seen[name] = true
+	}
+	unknown := []string{}
+	for _, match := range promptPlaceholderPattern.FindAllString(text, -1) {
+		if name := match[1:]; !seen[name] {
+			seen[name] = true
+			unknown = append(unknown, match)
+		}
+	}
+	return unknown
+}
+
+// promptVariables returns the common prompt variables for an issue. Issue
+// fields and recent commits are only looked up when the template uses them.
+func promptVariables(root string, cfg config, issue, template string) map[string]string {
+	vars := map[string]string{
+		"ISSUE_ID":    issue,
+		"REPO_NAME":   filepath.Base(root),
+		"REPO_ROOT":   root,
+		"BASE_BRANCH": cfg.BaseBranch,
+		"BD_PREFIX":   cfg.BDPrefix,
+	}
+	for _, name := range []string{"ISSUE_TITLE", "ISSUE_TYPE", "ISSUE_STATUS", "ISSUE_LABELS", "ISSUE_DESCRIPTION", "ISSUE_ACCEPTANCE", "RECENT_COMMITS"} {
+		vars[name] = ""
+	}
+	if issue != "" && strings.Contains(template, "$ISSUE_") {
+		if details, err := issueDetails(issue); err == nil {
+			vars["ISSUE_TITLE"] = strings.TrimSpace(details.Title)
+			vars["ISSUE_TYPE"] = details.IssueType
+			vars["ISSUE_STATUS"] = details.Status
+			vars["ISSUE_LABELS"] = strings.Join(details.Labels, ", ")
+			vars["ISSUE_DESCRIPTION"] = strings.TrimSpace(details.Description)
+			vars["ISSUE_ACCEPTANCE"] = strings.TrimSpace(details.Acceptance)
+		}
+	}
+	if strings.Contains(template, "$RECENT_COMMITS") {
+		ref := valueOrFallback(localOrRemoteRef(cfg.BaseBranch), "HEAD")
+		if log, err := commandOutput("git", "-C", root, "log", "--oneline", "-n", strconv.Itoa(promptRecentCommits), ref); err == nil {
+			vars["RECENT_COMMITS"] = strings.TrimSpace(log)
+		}
+	}
+	return vars
+}
+
+// renderPromptTemplate fills $NAME placeholders from vars and turns $$ into
+// $. Placeholders without a value are left as written.
+func renderPromptTemplate(template string, vars map[string]string) string {
+	return promptPlaceholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		name := match[1:]
+		if name == "$" {
+			return "$"
+		}
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+func agentIDForRole(cfg config, role string) (string, error) {
+	switch role {
+	case "writer":
+		if strings.TrimSpace(cfg.WriterAgent) != "" {
+			return cfg.WriterAgent, nil
+		}
+	case "reviewer":
+		if strings.TrimSpace(cfg.ReviewerAgent) != "" {
+			return cfg.ReviewerAgent, nil
+		}
+		if strings.TrimSpace(cfg.WriterAgent) != "" {
+			return cfg.WriterAgent, nil
+		}
+	}
+	return "", fmt.Errorf("no %s agent configured; run yoke init or set agent config in .yoke/config.sh", role)
+}
+
+func agentBinaryForID(agentID string) (string, string, error) {
+	normalized, ok := normalizeAgentID(agentID)
+	if !ok {
+		return "", "", fmt.Errorf("unsupported agent id: %s", agentID)
+	}
+	for _, spec := range supportedAgents {
+		if spec.ID != normalized {
+			continue
+		}
+		for _, binary := range spec.Binaries {
+			if commandExists(binary) {
+				return normalized, binary, nil
+			}
+		}
+		break
+	}
+	return "", "", fmt.Errorf("agent %s is not available on PATH", normalized)
+}
+
+func runAgentPrompt(agentID, root, prompt string, extraEnv []string, streamPrefix string) (string, error) {
+	return runAgentPromptWithModel(agentID, "", root, prompt, extraEnv, streamPrefix)
+}
+
+// runAgentPromptWithModel is runAgentPrompt with an explicit model; an empty
+// model leaves the choice to the agent CLI.
+func runAgentPromptWithModel(agentID, model, root, prompt string, extraEnv []string, streamPrefix string) (string, error) {
+	if dryRunMode {
+		logDryRun("", "agent "+agentID, []string{root})
+		return "", errDryRunAgent
+	}
+	defer timePhase("agent", true)()
+	normalized, binary, err := agentBinaryForID(agentID)
+	if err != nil {
+		return "", err
+	}
+
+	var cmd *exec.Cmd
+	switch normalized {
+	case "codex":
+		args := []string{"exec", "--full-auto", "--cd", root}
+		if model != "" {
+			args = append(args, "--model", model)
+		}
+		cmd = exec.Command(binary, append(args, prompt)...)
+	case "claude":
+		args := []string{"--print", "--permission-mode", "bypassPermissions"}
+		if model != "" {
+			args = append(args, "--model", model)
+		}
+		cmd = exec.Command(binary, append(args, prompt)...)
+	default:
+		return "", fmt.Errorf("unsupported agent id: %s", normalized)
+	}
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), extraEnv...)
+
+	var combined synchronizedBuffer
+	live := openLiveAgentLog(root, livePipelineFromEnv(extraEnv), envValue(extraEnv, "YOKE_ROLE"), normalized)
+	stdoutStream := io.MultiWriter(&combined, live, newLinePrefixWriter(os.Stdout, streamPrefix))
+	stderrPrefix := streamPrefix
+	if strings.TrimSpace(stderrPrefix) == "" {
+		stderrPrefix = "[agent][stderr] "
+	} else {
+		stderrPrefix += "[stderr] "
+	}
+	stderrStream := io.MultiWriter(&combined, live, newLinePrefixWriter(os.Stdout, stderrPrefix))
+	cmd.Stdout = stdoutStream
+	cmd.Stderr = stderrStream
+
+	runErr := cmd.Run()
+	live.Finish(runErr)
+	// The agent may have changed issues through bd directly.
+	invalidateTrackerCache()
+	return strings.TrimSpace(combined.String()), runErr
+}
+
+type synchronizedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *synchronizedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *synchronizedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type linePrefixWriter struct {
+	mu        sync.Mutex
+	dst       io.Writer
+	prefix    string
+	lineStart bool
+}
+
+func newLinePrefixWriter(dst io.Writer, prefix string) *linePrefixWriter {
+	return &linePrefixWriter{
+		dst:       dst,
+		prefix:    prefix,
+		lineStart: true,
+	}
+}
+
+func (w *linePrefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	written := 0
+	for len(p) > 0 {
+		if w.lineStart {
+			if _, err := io.WriteString(w.dst, w.prefix); err != nil {
+				return written, err
+			}
+			w.lineStart = false
+		}
+
+		newline := bytes.IndexByte(p, '\n')
+		if newline == -1 {
+			n, err := w.dst.Write(p)
+			written += n
+			return written, err
+		}
+
+		chunk := p[:newline+1]
+		n, err := w.dst.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		w.lineStart = true
+		p = p[newline+1:]
+	}
+
+	return written, nil
+}
+
+const (
+	sessionLogDir   = "logs"
+	sessionLogIndex = "index.jsonl"
+)
+
+type sessionLogEntry struct {
+	Timestamp  string `json:"timestamp"`
+	IssueID    string `json:"issue_id"`
+	Role       string `json:"role"`
+	Source     string `json:"source"`
+	AgentID    string `json:"agent,omitempty"`
+	Command    string `json:"command,omitempty"`
+	Path       string `json:"path"`
+	Exit       string `json:"exit"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+func sessionLogRoot(root string) string {
+	return filepath.Join(root, ".yoke", sessionLogDir)
+}
+
+func sessionLogPath(root, issue, role string, started time.Time) string {
+	name := fmt.Sprintf("%s-%s.log", started.UTC().Format("20060102T150405.000Z"), sanitizePathSegment(role))
+	return filepath.Join(sessionLogRoot(root), sanitizePathSegment(issue), name)
+}
+
+// recordSessionLog persists the combined output of an agent invocation, after
+// YOKE_AGENT_OUTPUT_FILTERS, and appends an entry to .yoke/logs/index.jsonl. Failures only warn so log
+// capture never breaks the workflow it observes.
+func recordSessionLog(root string, entry sessionLogEntry, started time.Time, output string, runErr error) {
+	if dryRunMode {
+		return
+	}
+	if err := writeSessionLog(root, entry, started, processAgentOutput(output), runErr); err != nil {
+		note("warning: failed to write agent session log: " + err.Error())
+	}
+	agent := valueOrFallback(entry.AgentID, "command")
+	args := []string{entry.Role, entry.Source}
+	if strings.TrimSpace(entry.Command) != "" {
+		args = append(args, entry.Command)
+	}
+	appendAuditEntry(root, newAuditEntry(entry.IssueID, "agent "+agent, args, runErr))
+}
+
+func writeSessionLog(root string, entry sessionLogEntry, started time.Time, output string, runErr error) error {
+	path := sessionLogPath(root, entry.IssueID, entry.Role, started)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	entry.Timestamp = started.Format(time.RFC3339)
+	entry.DurationMS = time.Since(started).Milliseconds()
+	entry.Exit = "success"
+	if runErr != nil {
+		entry.Exit = "error: " + runErr.Error()
+	}
+	if rel, err := filepath.Rel(root, path); err == nil {
+		entry.Path = rel
+	} else {
+		entry.Path = path
+	}
+
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("# issue=%s role=%s source=%s\n", entry.IssueID, entry.Role, entry.Source))
+	if entry.AgentID != "" {
+		body.WriteString("# agent=" + entry.AgentID + "\n")
+	}
+	if entry.Command != "" {
+		body.WriteString("# command=" + entry.Command + "\n")
+	}
+	body.WriteString(fmt.Sprintf("# started=%s exit=%s\n\n", entry.Timestamp, entry.Exit))
+	body.WriteString(output)
+	if !strings.HasSuffix(output, "\n") {
+		body.WriteString("\n")
+	}
+	if err := os.WriteFile(path, []byte(body.String()), 0o644); err != nil {
+		return err
+	}
+	return appendJSONLine(filepath.Join(sessionLogRoot(root), sessionLogIndex), entry)
+}
+
+func appendJSONLine(path string, value any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// outputFilter is one stage of YOKE_AGENT_OUTPUT_FILTERS. Limit is only used
+// by max-bytes.
+type outputFilter struct {
+	Name  string
+	Limit int
+}
+
+// agentOutputFilters is the loaded config's chain; loadConfig sets it so
+// session logs and reports written deep in the workflow share one setting.
+var agentOutputFilters = mustParseOutputFilters(defaultOutputFilters)
+
+var (
+	ansiEscapePattern  = regexp.MustCompile(`\x1b(?:\[[0-9;?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+	finalAnswerPattern = regexp.MustCompile(`(?im)^[#*\s]*final (?:answer|report|summary)\b[*:\s]*$`)
+)
+
+func parseOutputFilters(raw string) ([]outputFilter, error) {
+	filters := []outputFilter{}
+	for _, item := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		name, value, hasValue := strings.Cut(strings.ToLower(item), "=")
+		switch name {
+		case "strip-ansi", "collapse-repeats", "final-answer":
+			if hasValue {
+				return nil, fmt.Errorf("%s takes no value", name)
+			}
+			filters = append(filters, outputFilter{Name: name})
+		case "max-bytes":
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit <= 0 {
+				return nil, fmt.Errorf("max-bytes needs a positive size, got %q", value)
+			}
+			filters = append(filters, outputFilter{Name: name, Limit: limit})
+		default:
+			return nil, fmt.Errorf("unknown filter %q (want strip-ansi, collapse-repeats, final-answer, or max-bytes=N)", item)
+		}
+	}
+	return filters, nil
+}
+
+func mustParseOutputFilters(raw string) []outputFilter {
+	filters, err := parseOutputFilters(raw)
+	if err != nil {
+		panic(err)
+	}
+	return filters
+}
+
+func formatOutputFilters(filters []outputFilter) string {
+	names := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		if filter.Name == "max-bytes" {
+			names = append(names, fmt.Sprintf("max-bytes=%d", filter.Limit))
+			continue
+		}
+		names = append(names, filter.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// processAgentOutput runs output through the configured filters in order.
+// Raw output is still what usage accounting and verdict parsing see.
+func processAgentOutput(output string) string {
+	return applyOutputFilters(agentOutputFilters, output)
+}
+
+func applyOutputFilters(filters []outputFilter, output string) string {
+	for _, filter := range filters {
+		switch filter.Name {
+		case "strip-ansi":
+			output = stripANSI(output)
+		case "collapse-repeats":
+			output = collapseRepeatedLines(output)
+		case "final-answer":
+			output = extractFinalAnswer(output)
+		case "max-bytes":
+			output = limitOutputBytes(output, filter.Limit)
+		}
+	}
+	return output
+}
+
+// stripANSI drops terminal escape sequences and keeps only what a carriage
+// return left visible on each line, so spinners collapse to their last frame.
+func stripANSI(output string) string {
+	lines := strings.Split(ansiEscapePattern.ReplaceAllString(output, ""), "\n")
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if cut := strings.LastIndex(line, "\r"); cut >= 0 {
+			line = line[cut+1:]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// collapseRepeatedLines folds runs of identical lines into the first line
+// plus a count.
+func collapseRepeatedLines(output string) string {
+	lines := strings.Split(output, "\n")
+	collapsed := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); {
+		run := 1
+		for i+run < len(lines) && lines[i+run] == lines[i] {
+			run++
+		}
+		collapsed = append(collapsed, lines[i])
+		if run > 2 && strings.TrimSpace(lines[i]) != "" {
+			collapsed = append(collapsed, fmt.Sprintf("[previous line repeated %d more times]", run-1))
+		} else {
+			for extra := 1; extra < run; extra++ {
+				collapsed = append(collapsed, lines[i])
+			}
+		}
+		i += run
+	}
+	return strings.Join(collapsed, "\n")
+}
+
+// extractFinalAnswer keeps the text under the last "Final answer" (or final
+// report/summary) heading; output without one is returned unchanged.
+func extractFinalAnswer(output string) string {
+	markers := finalAnswerPattern.FindAllStringIndex(output, -1)
+	if len(markers) == 0 {
+		return output
+	}
+	answer := strings.TrimSpace(output[markers[len(markers)-1][1]:])
+	if answer == "" {
+		return output
+	}
+	return answer
+}
+
+// limitOutputBytes keeps the head and tail of oversized output, where the
+// task setup and the agent's conclusion live.
+func limitOutputBytes(output string, limit int) string {
+	if limit <= 0 || len(output) <= limit {
+		return output
+	}
+	half := limit / 2
+	head := strings.ToValidUTF8(output[:half], "")
+	tail := strings.ToValidUTF8(output[len(output)-half:], "")
+	return fmt.Sprintf("%s\n...[%d bytes truncated]...\n%s", head, len(output)-len(head)-len(tail), tail)
+}
+
+const (
+	auditLogFile      = "audit.jsonl"
+	maxAuditArgLength = 500
+)
+
+// auditRoot is the main checkout whose .yoke/audit.jsonl receives audit
+// entries; it is set by ensureRepoRoot and empty until then.
+var auditRoot string
+
+type auditEntry struct {
+	Timestamp string   `json:"timestamp"`
+	IssueID   string   `json:"issue_id,omitempty"`
+	Action    string   `json:"action"`
+	Args      []string `json:"args,omitempty"`
+	Actor     string   `json:"actor"`
+	Result    string   `json:"result"`
+}
+
+func auditLogPath(root string) string {
+	return filepath.Join(root, ".yoke", auditLogFile)
+}
+
+func auditActor() string {
+	user := valueOrFallback(os.Getenv("USER"), "unknown")
+	if role := strings.TrimSpace(os.Getenv("YOKE_ROLE")); role != "" {
+		user += " (" + role + ")"
+	}
+	return fmt.Sprintf("%s@%s", user, reviewLockHolder())
+}
+
+func newAuditEntry(issue, action string, args []string, err error) auditEntry {
+	trimmed := make([]string, 0, len(args))
+	for _, arg := range args {
+		if len(arg) > maxAuditArgLength {
+			arg = arg[:maxAuditArgLength] + "..."
+		}
+		trimmed = append(trimmed, arg)
+	}
+	result := "ok"
+	if err != nil {
+		result = "error: " + err.Error()
+	}
+	return auditEntry{
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		IssueID:   issue,
+		Action:    action,
+		Args:      trimmed,
+		Actor:     auditActor(),
+		Result:    result,
+	}
+}
+
+// auditedAction names the state-changing subcommands worth auditing. Reads
+// (bd show/list, gh pr view, git rev-parse, ...) are not recorded.
+func auditedAction(name string, args []string) (string, bool) {
+	sub := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+	gitArgs := args
+	for len(gitArgs) >= 2 && gitArgs[0] == "-C" {
+		gitArgs = gitArgs[2:]
+	}
+
+	switch name {
+	case "bd":
+		switch sub(0) {
+		case "update", "close", "reopen", "create", "delete":
+			return "bd " + sub(0), true
+		case "comments", "dep", "label":
+			if sub(1) == "add" || sub(1) == "remove" {
+				return "bd " + sub(0) + " " + sub(1), true
+			}
+		}
+	case "jira", "local":
+		switch sub(0) {
+		case "create", "update", "close":
+			return name + " " + sub(0), true
+		case "comments":
+			return name + " comments add", true
+		}
+	case "gh":
+		if sub(0) == "issue" {
+			switch sub(1) {
+			case "create", "comment", "edit", "close", "reopen":
+				return "gh issue " + sub(1), true
+			}
+		}
+		if sub(0) == "pr" {
+			switch sub(1) {
+			case "create", "comment", "ready", "merge", "edit", "review", "close":
+				return "gh pr " + sub(1), true
+			}
+		}
+	case "git":
+		if len(gitArgs) == 0 {
+			return "", false
+		}
+		switch gitArgs[0] {
+		case "push", "merge":
+			return "git " + gitArgs[0], true
+		case "worktree":
+			if len(gitArgs) > 1 && (gitArgs[1] == "add" || gitArgs[1] == "remove") {
+				return "git worktree " + gitArgs[1], true
+			}
+		case "branch":
+			for _, arg := range gitArgs[1:] {
+				if arg == "-D" || arg == "-d" || arg == "--delete" {
+					return "git branch delete", true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// auditIssueID finds the issue an action targets: an issue-id argument or a
+// yoke/<issue> branch name.
+func auditIssueID(args []string) string {
+	for _, arg := range args {
+		candidate := strings.TrimPrefix(arg, "yoke/")
+		if looksLikeIssueIDAnyPrefix(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func auditCommand(name string, args []string, runErr error) {
+	if auditRoot == "" {
+		return
+	}
+	action, ok := auditedAction(name, args)
+	if !ok {
+		return
+	}
+	appendAuditEntry(auditRoot, newAuditEntry(auditIssueID(args), action, args, runErr))
+}
+
+func appendAuditEntry(root string, entry auditEntry) {
+	if strings.TrimSpace(root) == "" || dryRunMode {
+		return
+	}
+	if err := appendJSONLine(auditLogPath(root), entry); err != nil {
+		note("warning: failed to append audit log entry: " + err.Error())
+	}
+}
+
+// dryRunMode is set by --dry-run on claim, submit, review, and merge. bd,
+// git, gh, and GitHub API mutations are then printed to stderr as audit
+// entries with result "dry-run" instead of being made, and yoke's own state
+// under .yoke is left alone; reads still run so the flow takes the path it
+// would take for real.
+var dryRunMode bool
+
+var dryRunCommands = map[string]bool{"claim": true, "submit": true, "review": true, "merge": true}
+
+var errDryRunAgent = errors.New("dry run: agent not started")
+
+// wantsDryRun reports whether args ask for a dry run, parsed the way the
+// command will parse them so "-n" inside a cluster like "-wn" counts too.
+// Parse errors are left for the command to report.
+func wantsDryRun(command string, args []string) bool {
+	parsed, err := parseCommandArgs(command, args)
+	if err != nil {
+		return false
+	}
+	for _, flag := range parsed.Flags {
+		if flag.Name == "--dry-run" {
+			return true
+		}
+	}
+	return false
+}
+
+// dryRunLog receives dry-run entries; tests swap it.
+var dryRunLog io.Writer = os.Stderr
+
+func logDryRun(issue, action string, args []string) {
+	entry := newAuditEntry(issue, action, args, nil)
+	entry.Result = "dry-run"
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(dryRunLog, string(data))
+}
+
+// skipForDryRun logs and reports true for a command that would change
+// state in dry-run mode.
+func skipForDryRun(name string, args []string) bool {
+	if !dryRunMode {
+		return false
+	}
+	action, ok := auditedAction(name, args)
+	if !ok {
+		action, ok = mutatingGitAction(name, args)
+	}
+	if !ok {
+		return false
+	}
+	logDryRun(auditIssueID(args), action, args)
+	return true
+}
+
+// mutatingGitAction covers the git calls that change the repository but
+// are too routine to audit (commits, branch switches, rebases).
+func mutatingGitAction(name string, args []string) (string, bool) {
+	if name != "git" {
+		return "", false
+	}
+	for len(args) >= 2 && args[0] == "-C" {
+		args = args[2:]
+	}
+	if len(args) == 0 {
+		return "", false
+	}
+	switch args[0] {
+	case "add", "commit", "rebase", "switch", "checkout", "reset", "pull", "stash", "tag", "cherry-pick", "branch":
+		return "git " + args[0], true
+	case "worktree":
+		if len(args) > 1 && args[1] != "list" {
+			return "git worktree " + args[1], true
+		}
+	}
+	return "", false
+}
+
+func parseAuditEntries(raw string) ([]auditEntry, error) {
+	entries := []auditEntry{}
+	for lineNumber, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var entry auditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("parse %s line %d: %w", auditLogFile, lineNumber+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func loadAuditEntries(root string) ([]auditEntry, error) {
+	data, err := os.ReadFile(auditLogPath(root))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return parseAuditEntries(string(data))
+}
+
+func filterAuditEntries(entries []auditEntry, issue string, limit int) []auditEntry {
+	filtered := []auditEntry{}
+	for _, entry := range entries {
+		if issue != "" && !strings.EqualFold(entry.IssueID, issue) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[len(filtered)-limit:]
+	}
+	return filtered
+}
+
+func formatAuditEntry(entry auditEntry) string {
+	parts := []string{entry.Timestamp, valueOrFallback(entry.IssueID, "-"), entry.Action}
+	if len(entry.Args) > 0 {
+		parts = append(parts, strings.Join(entry.Args, " "))
+	}
+	parts = append(parts, "["+entry.Actor+"]", entry.Result)
+	return strings.Join(parts, "  ")
+}
+
+func cmdLog(args []string) error {
+	var (
+		issue      string
+		limit      int
+		jsonOutput bool
+	)
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := parseCommandArgs("log", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printLogUsage()
+		return nil
+	}
+	if issue, err = parsed.issueArg("log", cfg.BDPrefix); err != nil {
+		return err
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--limit":
+			value, err := strconv.Atoi(flag.Value)
+			if err != nil || value <= 0 {
+				return fmt.Errorf("invalid --limit value: %s", flag.Value)
+			}
+			limit = value
+		case "--json":
+			jsonOutput = true
+		}
+	}
+
+	entries, err := loadAuditEntries(auditRoot)
+	if err != nil {
+		return err
+	}
+	entries = filterAuditEntries(entries, issue, limit)
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(entries) == 0 {
+		note("No audit entries found.")
+		return nil
+	}
+	for _, entry := range entries {
+		fmt.Println(formatAuditEntry(entry))
+	}
+	return nil
+}
+
+// issueMetrics are cycle-time measurements for one issue. Durations are in
+// seconds and zero when the corresponding events were not observed.
+type issueMetrics struct {
+	IssueID                string `json:"issue_id"`
+	ClaimToSubmitSeconds   int64  `json:"claim_to_submit_seconds"`
+	SubmitToApproveSeconds int64  `json:"submit_to_approve_seconds"`
+	LeadTimeSeconds        int64  `json:"lead_time_seconds"`
+	RejectLoops            int    `json:"reject_loops"`
+	DaemonIterations       int    `json:"daemon_iterations"`
+}
+
+type metricsAggregate struct {
+	Issues                       int   `json:"issues"`
+	MedianClaimToSubmitSeconds   int64 `json:"median_claim_to_submit_seconds"`
+	MedianSubmitToApproveSeconds int64 `json:"median_submit_to_approve_seconds"`
+	MedianLeadTimeSeconds        int64 `json:"median_lead_time_seconds"`
+	TotalRejectLoops             int   `json:"total_reject_loops"`
+	TotalDaemonIterations        int   `json:"total_daemon_iterations"`
+}
+
+type metricsReport struct {
+	Issues    []issueMetrics   `json:"issues"`
+	Aggregate metricsAggregate `json:"aggregate"`
+}
+
+func auditArgsContain(args []string, values ...string) bool {
+	for _, value := range values {
+		found := false
+		for _, arg := range args {
+			if arg == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// computeIssueMetrics derives claim/submit/approve times and loop counts from
+// an issue's audit entries (in log order).
+func computeIssueMetrics(issue string, entries []auditEntry) issueMetrics {
+	metrics := issueMetrics{IssueID: issue}
+	var claimedAt, firstSubmitAt, lastSubmitAt, approvedAt time.Time
+	for _, entry := range entries {
+		at, ok := parseBDTimestamp(entry.Timestamp)
+		if !ok {
+			continue
+		}
+		switch {
+		case entry.Action == "bd update" && auditArgsContain(entry.Args, "--status", "in_progress"):
+			if claimedAt.IsZero() {
+				claimedAt = at
+			}
+		case entry.Action == "bd update" && auditArgsContain(entry.Args, "--status", "blocked", "--add-label", reviewQueueLabel):
+			if firstSubmitAt.IsZero() {
+				firstSubmitAt = at
+			}
+			lastSubmitAt = at
+		case entry.Action == "bd comments add" && len(entry.Args) >= 4 && strings.HasPrefix(entry.Args[3], "Reviewer rejection:"):
+			metrics.RejectLoops++
+		case entry.Action == "bd close":
+			approvedAt = at
+		case strings.HasPrefix(entry.Action, "agent ") && len(entry.Args) >= 2 && entry.Args[1] == "daemon":
+			metrics.DaemonIterations++
+		}
+	}
+	if !claimedAt.IsZero() && firstSubmitAt.After(claimedAt) {
+		metrics.ClaimToSubmitSeconds = int64(firstSubmitAt.Sub(claimedAt).Seconds())
+	}
+	if !lastSubmitAt.IsZero() && approvedAt.After(lastSubmitAt) {
+		metrics.SubmitToApproveSeconds = int64(approvedAt.Sub(lastSubmitAt).Seconds())
+	}
+	return metrics
+}
+
+func medianSeconds(values []int64) int64 {
+	nonZero := []int64{}
+	for _, value := range values {
+		if value > 0 {
+			nonZero = append(nonZero, value)
+		}
+	}
+	if len(nonZero) == 0 {
+		return 0
+	}
+	sort.Slice(nonZero, func(i, j int) bool { return nonZero[i] < nonZero[j] })
+	middle := len(nonZero) / 2
+	if len(nonZero)%2 == 0 {
+		return (nonZero[middle-1] + nonZero[middle]) / 2
+	}
+	return nonZero[middle]
+}
+
+func aggregateMetrics(issues []issueMetrics) metricsAggregate {
+	aggregate := metricsAggregate{Issues: len(issues)}
+	var claimToSubmit, submitToApprove, leadTime []int64
+	for _, issue := range issues {
+		claimToSubmit = append(claimToSubmit, issue.ClaimToSubmitSeconds)
+		submitToApprove = append(submitToApprove, issue.SubmitToApproveSeconds)
+		leadTime = append(leadTime, issue.LeadTimeSeconds)
+		aggregate.TotalRejectLoops += issue.RejectLoops
+		aggregate.TotalDaemonIterations += issue.DaemonIterations
+	}
+	aggregate.MedianClaimToSubmitSeconds = medianSeconds(claimToSubmit)
+	aggregate.MedianSubmitToApproveSeconds = medianSeconds(submitToApprove)
+	aggregate.MedianLeadTimeSeconds = medianSeconds(leadTime)
+	return aggregate
+}
+
+func formatMetricsDuration(seconds int64) string {
+	if seconds <= 0 {
+		return "-"
+	}
+	return formatAgeSeconds(seconds)
+}
+
+func writeMetricsTable(out io.Writer, report metricsReport) error {
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ISSUE\tCLAIM->SUBMIT\tSUBMIT->APPROVE\tLEAD_TIME\tREJECTS\tDAEMON_RUNS")
+	for _, issue := range report.Issues {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t%d\n", issue.IssueID,
+			formatMetricsDuration(issue.ClaimToSubmitSeconds),
+			formatMetricsDuration(issue.SubmitToApproveSeconds),
+			formatMetricsDuration(issue.LeadTimeSeconds),
+			issue.RejectLoops, issue.DaemonIterations)
+	}
+	aggregate := report.Aggregate
+	fmt.Fprintf(writer, "median (%d issues)\t%s\t%s\t%s\t%d\t%d\n", aggregate.Issues,
+		formatMetricsDuration(aggregate.MedianClaimToSubmitSeconds),
+		formatMetricsDuration(aggregate.MedianSubmitToApproveSeconds),
+		formatMetricsDuration(aggregate.MedianLeadTimeSeconds),
+		aggregate.TotalRejectLoops, aggregate.TotalDaemonIterations)
+	return writer.Flush()
+}
+
+func cmdMetrics(args []string) error {
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := parseCommandArgs("metrics", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printMetricsUsage()
+		return nil
+	}
+	issueFilter, err := parsed.issueArg("metrics", cfg.BDPrefix)
+	if err != nil {
+		return err
+	}
+	jsonOutput := len(parsed.Flags) > 0
+
+	entries, err := loadAuditEntries(auditRoot)
+	if err != nil {
+		return err
+	}
+	byIssue := map[string][]auditEntry{}
+	order := []string{}
+	for _, entry := range filterAuditEntries(entries, issueFilter, 0) {
+		if entry.IssueID == "" {
+			continue
+		}
+		if _, seen := byIssue[entry.IssueID]; !seen {
+			order = append(order, entry.IssueID)
+		}
+		byIssue[entry.IssueID] = append(byIssue[entry.IssueID], entry)
+	}
+
+	report := metricsReport{Issues: []issueMetrics{}}
+	bdAvailable := useIssueTracker(cfg) == nil
+	for _, issue := range order {
+		metrics := computeIssueMetrics(issue, byIssue[issue])
+		if bdAvailable {
+			if details, err := issueDetails(issue); err == nil {
+				created, createdOK := parseBDTimestamp(details.CreatedAt)
+				closed, closedOK := parseBDTimestamp(details.ClosedAt)
+				if createdOK && closedOK && closed.After(created) {
+					metrics.LeadTimeSeconds = int64(closed.Sub(created).Seconds())
+				}
+			}
+		}
+		report.Issues = append(report.Issues, metrics)
+	}
+	report.Aggregate = aggregateMetrics(report.Issues)
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(report.Issues) == 0 {
+		note("No audited issues found in .yoke/audit.jsonl.")
+		return nil
+	}
+	return writeMetricsTable(os.Stdout, report)
+}
+
+const usageLogFile = "usage.jsonl"
+
+// codexUsageFooterPattern matches the "tokens used" footer codex exec ends
+// its output with, on one line or with the count on the next.
+var codexUsageFooterPattern = regexp.MustCompile(`(?i)^(?:\[[^\]]*\]\s*)?tokens used[:\s]+([0-9][0-9,]*)\s*$`)
+
+// claudeResult is the final object claude -p --output-format json (or
+// stream-json) prints.
+type claudeResult struct {
+	Type         string   `json:"type"`
+	TotalCostUSD *float64 `json:"total_cost_usd"`
+	CostUSD      *float64 `json:"cost_usd"`
+	Usage        *struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+type usageRecord struct {
+	Timestamp    string  `json:"timestamp"`
+	IssueID      string  `json:"issue_id"`
+	EpicID       string  `json:"epic_id,omitempty"`
+	Role         string  `json:"role"`
+	AgentID      string  `json:"agent,omitempty"`
+	InputTokens  int     `json:"input_tokens,omitempty"`
+	OutputTokens int     `json:"output_tokens,omitempty"`
+	TotalTokens  int     `json:"total_tokens"`
+	CostUSD      float64 `json:"cost_usd,omitempty"`
+}
+
+type usageTotals struct {
+	Runs        int
+	TotalTokens int
+	CostUSD     float64
+	// UnpricedRuns counts runs that reported tokens but no cost (codex).
+	UnpricedRuns int
+}
+
+func usageLogPath(root string) string {
+	return filepath.Join(root, ".yoke", usageLogFile)
+}
+
+// parseAgentUsage reads token and cost figures from the structured usage an
+// agent ends its output with: the claude --output-format json result object,
+// or codex's "tokens used" footer. Anything earlier is the agent's transcript
+// (diffs, logs, test output) and is never read for usage, so an echoed
+// "cost_usd" cannot reach the budget.
+func parseAgentUsage(output string) (usageRecord, bool) {
+	lines := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return usageRecord{}, false
+	}
+	last := lines[len(lines)-1]
+
+	var result claudeResult
+	if strings.HasPrefix(last, "{") && json.Unmarshal([]byte(last), &result) == nil && result.Type == "result" {
+		var record usageRecord
+		if result.Usage != nil {
+			record.InputTokens = result.Usage.InputTokens
+			record.OutputTokens = result.Usage.OutputTokens
+		}
+		record.TotalTokens = record.InputTokens + record.OutputTokens
+		switch {
+		case result.TotalCostUSD != nil:
+			record.CostUSD = *result.TotalCostUSD
+		case result.CostUSD != nil:
+			record.CostUSD = *result.CostUSD
+		}
+		return record, result.Usage != nil || result.TotalCostUSD != nil || result.CostUSD != nil
+	}
+
+	footer := last
+	if len(lines) > 1 && strings.EqualFold(strings.TrimRight(lines[len(lines)-2], ":"), "tokens used") {
+		footer = lines[len(lines)-2] + " " + last
+	}
+	if match := codexUsageFooterPattern.FindStringSubmatch(footer); match != nil {
+		if total, err := strconv.Atoi(strings.ReplaceAll(match[1], ",", "")); err == nil {
+			return usageRecord{TotalTokens: total}, true
+		}
+	}
+	return usageRecord{}, false
+}
+
+func recordAgentUsage(root string, base usageRecord, output string) {
+	parsed, ok := parseAgentUsage(output)
+	if !ok {
+		return
+	}
+	parsed.Timestamp = time.Now().Format(time.RFC3339)
+	parsed.IssueID = base.IssueID
+	parsed.EpicID = base.EpicID
+	parsed.Role = base.Role
+	parsed.AgentID = base.AgentID
+	if err := appendUsageRecord(root, parsed); err != nil {
+		note("warning: failed to record agent usage: " + err.Error())
+	}
+}
+
+func appendUsageRecord(root string, record usageRecord) error {
+	if dryRunMode {
+		return nil
+	}
+	return appendJSONLine(usageLogPath(root), record)
+}
+
+func loadUsageRecords(root string) ([]usageRecord, error) {
+	data, err := os.ReadFile(usageLogPath(root))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return parseUsageRecords(string(data))
+}
+
+func parseUsageRecords(raw string) ([]usageRecord, error) {
+	records := make([]usageRecord, 0)
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record usageRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("parse usage record: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+func sumUsage(records []usageRecord, include func(usageRecord) bool) usageTotals {
+	var totals usageTotals
+	for _, record := range records {
+		if include != nil && !include(record) {
+			continue
+		}
+		totals.Runs++
+		totals.TotalTokens += record.TotalTokens
+		totals.CostUSD += record.CostUSD
+		if record.CostUSD == 0 {
+			totals.UnpricedRuns++
+		}
+	}
+	return totals
+}
+
+func epicUsageTotals(root, epicID string) usageTotals {
+	records, err := loadUsageRecords(root)
+	if err != nil {
+		return usageTotals{}
+	}
+	return sumUsage(records, func(record usageRecord) bool {
+		return strings.EqualFold(record.EpicID, epicID) || strings.EqualFold(record.IssueID, epicID)
+	})
+}
+
+func parseBudget(raw string) (float64, error) {
+	value := strings.TrimPrefix(strings.TrimSpace(raw), "$")
+	budget, err := strconv.ParseFloat(value, 64)
+	if err != nil || budget <= 0 {
+		return 0, fmt.Errorf("budget must be a positive USD amount: %q", raw)
+	}
+	return budget, nil
+}
+
+func formatBudget(budget float64) string {
+	if budget <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(budget, 'f', -1, 64)
+}
+
+func issueUsageTotals(root, issue string) usageTotals {
+	records, err := loadUsageRecords(root)
+	if err != nil {
+		return usageTotals{}
+	}
+	return sumUsage(records, func(record usageRecord) bool {
+		return strings.EqualFold(record.IssueID, issue)
+	})
+}
+
+func issueCostUSD(root, issue string) float64 {
+	return issueUsageTotals(root, issue).CostUSD
+}
+
+// unpricedUsageWarned remembers the issues this process has already warned
+// about, so a daemon loop warns once per issue rather than every iteration.
+var unpricedUsageWarned sync.Map
+
+// enforceIssueBudget stops automation once an issue's recorded agent cost
+// reaches the budget. The first time the budget is crossed, the issue is
+// labeled, a bd comment explains why automation halted, and a
+// budget-exceeded notification goes out. Runs that reported no cost (codex
+// only reports tokens) cannot count toward it, so they get a warning once
+// per issue instead of silently counting as $0.
+func enforceIssueBudget(root string, cfg config, issue string, budget float64) error {
+	if budget <= 0 {
+		return nil
+	}
+	totals := issueUsageTotals(root, issue)
+	spent := totals.CostUSD
+	if totals.UnpricedRuns > 0 {
+		if _, warned := unpricedUsageWarned.LoadOrStore(strings.ToLower(issue), true); !warned {
+			note(formatUnpricedUsageWarning(issue, totals, budget))
+		}
+	}
+	if spent < budget {
+		return nil
+	}
+
+	details, err := issueDetails(issue)
+	if err != nil {
+		return err
+	}
+	if !hasLabel(details.Labels, budgetLabel) {
+		if err := tracker.Update(issue, issueChange{AddLabels: []string{budgetLabel}}); err != nil {
+			return err
+		}
+		comment := formatBudgetExceededComment(issue, spent, budget)
+		if err := tracker.Comment(issue, comment); err != nil {
+			return err
+		}
+		notifyEvent(root, cfg, "budget-exceeded", issue, comment)
+	}
+	return fmt.Errorf("%w for %s ($%.4f spent, budget $%s); automation stopped (remove label %s or raise the budget to continue)", errBudgetExceeded, issue, spent, formatBudget(budget), budgetLabel)
+}
+
+var errBudgetExceeded = errors.New("agent budget exceeded")
+
+func formatUnpricedUsageWarning(issue string, totals usageTotals, budget float64) string {
+	return fmt.Sprintf("warning: %d of %d agent run(s) for %s reported no cost (codex reports only tokens); the $%s budget does not count them, so real spend may exceed it", totals.UnpricedRuns, totals.Runs, issue, formatBudget(budget))
+}
+
+func formatBudgetExceededComment(issue string, spent, budget float64) string {
+	lines := []string{
+		"Agent budget exceeded:",
+		"- Issue: " + sanitizeCommentLine(issue),
+		fmt.Sprintf("- Spent: $%.4f", spent),
+		"- Budget: $" + formatBudget(budget),
+		"- Automation stopped; raise YOKE_MAX_COST_PER_ISSUE or pass --budget to continue.",
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatUsageTotals(totals usageTotals) string {
+	summary := fmt.Sprintf("%d tokens over %d run(s)", totals.TotalTokens, totals.Runs)
+	if totals.CostUSD > 0 {
+		summary += fmt.Sprintf(", $%.4f", totals.CostUSD)
+	}
+	return summary
+}
+
+func buildEpicImprovementPassPrompt(template, epicID string, pass, total int, role string, clarifications []clarificationContext) string {
+	replaced := strings.ReplaceAll(template, "$EPIC_ID", epicID)
+	clarificationBlock := buildClarificationPromptBlock(clarifications)
+	if clarificationBlock == "" {
+		clarificationBlock = "No clarification-task comments were found."
+	}
+	return strings.TrimSpace(fmt.Sprintf(
+		`You are the %s agent for epic %s.
+This is epic improvement pass %d of %d.
+Clarification context (resolved by user comments on "Clarification needed" tasks):
+
+%s
+
+Apply the following improvement protocol exactly and emit the report in the specified report format:
+
+%s`,
+		role, epicID, pass, total, clarificationBlock, replaced,
+	))
+}
+
+func buildClarificationPromptBlock(clarifications []clarificationContext) string {
+	if len(clarifications) == 0 {
+		return ""
+	}
+
+	var body strings.Builder
+	for _, item := range clarifications {
+		body.WriteString(fmt.Sprintf("- %s: %s\n", item.IssueID, strings.TrimSpace(item.Title)))
+		for _, comment := range item.Comments {
+			author := strings.TrimSpace(comment.Author)
+			if author == "" {
+				author = "unknown"
+			}
+			timestamp := strings.TrimSpace(comment.CreatedAt)
+			if timestamp == "" {
+				timestamp = "unknown-time"
+			}
+			text := truncateForPrompt(strings.TrimSpace(comment.Text), maxClarificationCommentChars)
+			body.WriteString(fmt.Sprintf("  - [%s @ %s] %s\n", author, timestamp, text))
+		}
+	}
+	return strings.TrimSpace(body.String())
+}
+
+func buildEpicImprovementSummaryPrompt(epic bdListIssue, reports []epicImprovementPassReport) string {
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("Epic: %s\n", epic.ID))
+	body.WriteString(fmt.Sprintf("Title: %s\n\n", strings.TrimSpace(epic.Title)))
+	body.WriteString("Summarize the pass reports below into one concise final report.\n")
+	body.WriteString("Use sections:\n")
+	body.WriteString("1) Improvements made\n")
+	body.WriteString("2) Remaining risks/questions\n")
+	body.WriteString("3) Most critical dependency chains\n")
+	body.WriteString("4) Recommended next implementation steps\n\n")
+
+	for _, report := range reports {
+		body.WriteString(fmt.Sprintf("## Pass %d (%s via %s)\n", report.Pass, report.Role, report.AgentID))
+		if report.Changes != "" {
+			body.WriteString("bd changes recorded by yoke (ground truth; the report below may claim more or less):\n")
+			body.WriteString(report.Changes)
+			body.WriteString("\n\nReport:\n")
+		}
+		body.WriteString(truncateForPrompt(report.Output, maxSummaryInputCharsPerPass))
+		body.WriteString("\n\n")
+	}
+	return body.String()
+}
+
+func truncateForPrompt(value string, maxChars int) string {
+	trimmed := strings.TrimSpace(value)
+	if maxChars <= 0 || len(trimmed) <= maxChars {
+		return trimmed
+	}
+	return trimmed[:maxChars] + "\n...[truncated]..."
+}
+
+const (
+	epicTreeNoChanges = "- No structural changes."
+	// maxUnchangedEpicPasses consecutive passes that leave the epic tree as
+	// they found it end the cycle early; more runs would repeat the analysis.
+	maxUnchangedEpicPasses = 2
+)
+
+// unchangedEpicPassStreak counts the trailing passes whose snapshot diff was
+// empty. Passes without a diff (snapshot failed) end the streak.
+func unchangedEpicPassStreak(reports []epicImprovementPassReport) int {
+	streak := 0
+	for i := len(reports) - 1; i >= 0 && reports[i].Changes == epicTreeNoChanges; i-- {
+		streak++
+	}
+	return streak
+}
+
+// epicTreeNode is the part of a descendant issue an improvement pass may
+// restructure.
+type epicTreeNode struct {
+	Title    string   `json:"title"`
+	Status   string   `json:"status"`
+	Parent   string   `json:"parent,omitempty"`
+	Blockers []string `json:"blockers,omitempty"`
+}
+
+// epicTreeSnapshot maps each descendant of an epic to its node.
+type epicTreeSnapshot map[string]epicTreeNode
+
+// snapshotEpicTree reads the epic's descendants and their blockers straight
+// from the backend, bypassing any cache: agents change bd behind yoke's back.
+func snapshotEpicTree(epicID string) (epicTreeSnapshot, error) {
+	backend := baseTracker(tracker)
+	result, err := traverseDescendants(epicID, backend.Children, descendantTraversalWorkers, maxDescendantDepth)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := epicTreeSnapshot{}
+	for _, issue := range result.Issues {
+		blockers, err := backend.Blockers(issue.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, 0, len(blockers))
+		for _, blocker := range blockers {
+			ids = append(ids, blocker.ID)
+		}
+		sort.Strings(ids)
+		snapshot[issue.ID] = epicTreeNode{Title: strings.TrimSpace(issue.Title), Status: workflowStatusForIssue(issue), Parent: issue.Parent, Blockers: ids}
+	}
+	return snapshot, nil
+}
+
+// epicTreeDiff lists structural changes between two snapshots, one
+// human-readable line per change.
+type epicTreeDiff struct {
+	Added               []string
+	Removed             []string
+	Retitled            []string
+	StatusChanged       []string
+	Reparented          []string
+	DependenciesAdded   []string
+	DependenciesRemoved []string
+}
+
+func diffEpicTree(before, after epicTreeSnapshot) epicTreeDiff {
+	var diff epicTreeDiff
+	for _, id := range sortedSnapshotIDs(after) {
+		node := after[id]
+		old, existed := before[id]
+		if !existed {
+			diff.Added = append(diff.Added, fmt.Sprintf("%s: %s", id, node.Title))
+			for _, blocker := range node.Blockers {
+				diff.DependenciesAdded = append(diff.DependenciesAdded, id+" blocked by "+blocker)
+			}
+			continue
+		}
+		if old.Title != node.Title {
+			diff.Retitled = append(diff.Retitled, fmt.Sprintf("%s: %q -> %q", id, old.Title, node.Title))
+		}
+		if old.Status != node.Status {
+			diff.StatusChanged = append(diff.StatusChanged, fmt.Sprintf("%s: %s -> %s", id, old.Status, node.Status))
+		}
+		if old.Parent != node.Parent {
+			diff.Reparented = append(diff.Reparented, fmt.Sprintf("%s: %s -> %s", id, valueOrFallback(old.Parent, "none"), valueOrFallback(node.Parent, "none")))
+		}
+		for _, blocker := range node.Blockers {
+			if !hasLabel(old.Blockers, blocker) {
+				diff.DependenciesAdded = append(diff.DependenciesAdded, id+" blocked by "+blocker)
+			}
+		}
+		for _, blocker := range old.Blockers {
+			if !hasLabel(node.Blockers, blocker) {
+				diff.DependenciesRemoved = append(diff.DependenciesRemoved, id+" blocked by "+blocker)
+			}
+		}
+	}
+	for _, id := range sortedSnapshotIDs(before) {
+		if _, ok := after[id]; !ok {
+			diff.Removed = append(diff.Removed, fmt.Sprintf("%s: %s", id, before[id].Title))
+		}
+	}
+	return diff
+}
+
+func sortedSnapshotIDs(snapshot epicTreeSnapshot) []string {
+	ids := make([]string, 0, len(snapshot))
+	for id := range snapshot {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// formatEpicTreeDiff renders a diff as a markdown list grouped by kind.
+func formatEpicTreeDiff(diff epicTreeDiff) string {
+	groups := []struct {
+		heading string
+		lines   []string
+	}{
+		{"Tasks added", diff.Added},
+		{"Tasks removed", diff.Removed},
+		{"Tasks retitled", diff.Retitled},
+		{"Status changed", diff.StatusChanged},
+		{"Moved to a new parent", diff.Reparented},
+		{"Dependencies added", diff.DependenciesAdded},
+		{"Dependencies removed", diff.DependenciesRemoved},
+	}
+	var body strings.Builder
+	for _, group := range groups {
+		if len(group.lines) == 0 {
+			continue
+		}
+		body.WriteString(fmt.Sprintf("- %s (%d):\n", group.heading, len(group.lines)))
+		for _, line := range group.lines {
+			body.WriteString("  - " + sanitizeCommentLine(line) + "\n")
+		}
+	}
+	if body.Len() == 0 {
+		return epicTreeNoChanges
+	}
+	return strings.TrimRight(body.String(), "\n")
+}
+
+func writeEpicImprovementPassReport(path, epicID string, pass int, role, agentID, output, changes string, runErr error) error {
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("# Epic Improvement Pass %d\n\n", pass))
+	body.WriteString(fmt.Sprintf("- Epic: `%s`\n", epicID))
+	body.WriteString(fmt.Sprintf("- Role: `%s`\n", role))
+	body.WriteString(fmt.Sprintf("- Agent: `%s`\n", agentID))
+	body.WriteString(fmt.Sprintf("- Timestamp: `%s`\n", time.Now().Format(time.RFC3339)))
+	if runErr != nil {
+		body.WriteString(fmt.Sprintf("- Exit: error (`%s`)\n", runErr))
+	} else {
+		body.WriteString("- Exit: success\n")
+	}
+	if changes != "" {
+		body.WriteString("\n## bd Changes\n\n")
+		body.WriteString(changes)
+		body.WriteString("\n")
+	}
+	body.WriteString("\n## Output\n\n")
+	body.WriteString(output)
+	body.WriteString("\n")
+	return os.WriteFile(path, []byte(body.String()), 0o644)
+}
+
+func writeEpicImprovementSummary(path, epicID, agentID, summary, changes string, runErr error) error {
+	var body strings.Builder
+	body.WriteString("# Epic Improvement Summary\n\n")
+	body.WriteString(fmt.Sprintf("- Epic: `%s`\n", epicID))
+	body.WriteString(fmt.Sprintf("- Agent: `%s`\n", agentID))
+	body.WriteString(fmt.Sprintf("- Timestamp: `%s`\n", time.Now().Format(time.RFC3339)))
+	if runErr != nil {
+		body.WriteString(fmt.Sprintf("- Exit: error (`%s`)\n", runErr))
+	} else {
+		body.WriteString("- Exit: success\n")
+	}
+	if changes != "" {
+		body.WriteString("\n## bd Changes (whole cycle)\n\n")
+		body.WriteString(changes)
+		body.WriteString("\n")
+	}
+	body.WriteString("\n## Output\n\n")
+	body.WriteString(summary)
+	body.WriteString("\n")
+	return os.WriteFile(path, []byte(body.String()), 0o644)
+}
+
+func formatEpicImprovementSummaryComment(epic bdListIssue, summary string, passCount int, reportsDir string, usage usageTotals, changes string) string {
+	trimmedSummary := truncateForPrompt(summary, maxSummaryCommentChars)
+	lines := []string{
+		"## Epic Improvement Cycle Complete",
+		"",
+		"- Epic: `" + sanitizeCommentLine(epic.ID) + "`",
+		"- Passes: " + strconv.Itoa(passCount),
+		"- Process: writer/reviewer alternating",
+	}
+	if usage.Runs > 0 {
+		lines = append(lines, "- Agent usage (epic total): "+formatUsageTotals(usage))
+	}
+	lines = append(lines,
+		"",
+		"### Agent Summary",
+		trimmedSummary,
+		"",
+	)
+	if changes != "" {
+		lines = append(lines, "### bd Changes", changes, "")
+	}
+	lines = append(lines, "_Local reports saved at: `"+sanitizeCommentLine(reportsDir)+"`_")
+	return strings.Join(lines, "\n")
+}
+
+func sanitizePathSegment(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return "unknown"
+	}
+	return strings.NewReplacer("/", "_", "\\", "_", " ", "_", ":", "_").Replace(trimmed)
+}
+
+func workflowStatusForIssue(issue bdListIssue) string {
+	status := strings.ToLower(strings.TrimSpace(issue.Status))
+	if status == "blocked" && hasLabel(issue.Labels, reviewQueueLabel) {
+		return "in_review"
+	}
+	return status
+}
+
+func hasLabel(labels []string, target string) bool {
+	for _, label := range labels {
+		if strings.EqualFold(strings.TrimSpace(label), target) {
+			return true
+		}
+	}
+	return false
+}
+
+// claimedByLabelPrefix labels an issue with who claimed it when yoke does not
+// set the tracker's assignee.
+const claimedByLabelPrefix = "yoke:claimed-by="
+
+// claimIdentity is who this yoke claims issues as: YOKE_ASSIGNEE, or the
+// host name so two developers' daemons tell their claims apart.
+func claimIdentity(cfg config) string {
+	if cfg.Assignee != "" {
+		return cfg.Assignee
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "unknown-host"
+	}
+	return host
+}
+
+// issueOwner returns who claimed an issue: the yoke:claimed-by label, which
+// yoke rewrites on every claim, or else the tracker assignee.
+func issueOwner(issue bdListIssue) string {
+	for _, label := range issue.Labels {
+		label = strings.TrimSpace(label)
+		if len(label) > len(claimedByLabelPrefix) && strings.EqualFold(label[:len(claimedByLabelPrefix)], claimedByLabelPrefix) {
+			return label[len(claimedByLabelPrefix):]
+		}
+	}
+	return strings.TrimSpace(issue.Assignee)
+}
+
+// claimedByOther returns the owner of an issue assigned to or claimed by
+// someone other than claimIdentity.
+func claimedByOther(cfg config, issue bdListIssue) (string, bool) {
+	if leases := issueLeases(issue); len(leases) > 0 {
+		// A leased issue belongs to its live lease holder; once every lease
+		// has expired its daemon is presumed dead and anyone may recover it.
+		lease, ok := activeLease(leases, time.Now())
+		if !ok {
+			return "", false
+		}
+		return lease.Holder, !strings.EqualFold(lease.Holder, claimIdentity(cfg))
+	}
+	owner := issueOwner(issue)
+	return owner, owner != "" && !strings.EqualFold(owner, claimIdentity(cfg))
+}
+
+// unclaimedIssues drops issues someone else owns so automatic selection
+// never takes over another developer's work.
+func unclaimedIssues(cfg config, issues []bdListIssue) []bdListIssue {
+	filtered := make([]bdListIssue, 0, len(issues))
+	for _, issue := range issues {
+		if _, other := claimedByOther(cfg, issue); !other {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered
+}
+
+// recordClaim moves issue to in_progress and records claimIdentity as its
+// owner: as the assignee when YOKE_ASSIGNEE is set and the backend can assign,
+// otherwise as a yoke:claimed-by label replacing any earlier claimant's.
+func recordClaim(cfg config, issue bdListIssue) error {
+	identity := claimIdentity(cfg)
+	change := issueChange{Status: "in_progress", RemoveLabels: []string{reviewQueueLabel}}
+	for _, label := range issue.Labels {
+		if strings.HasPrefix(strings.ToLower(label), claimedByLabelPrefix) && !strings.EqualFold(label, claimedByLabelPrefix+identity) {
+			change.RemoveLabels = append(change.RemoveLabels, label)
+		}
+	}
+	assigner, canAssign := baseTracker(tracker).(issueAssigner)
+	assign := cfg.Assignee != "" && canAssign
+	if !assign && !hasLabel(issue.Labels, claimedByLabelPrefix+identity) {
+		change.AddLabels = append(change.AddLabels, claimedByLabelPrefix+identity)
+	}
+	if err := tracker.Update(issue.ID, change); err != nil {
+		return err
+	}
+	if !assign || strings.EqualFold(issue.Assignee, identity) {
+		return nil
+	}
+	err := assigner.Assign(issue.ID, identity)
+	invalidateTrackerCache()
+	return err
+}
+
+// releaseClaim applies change to issue and undoes recordClaim: it drops every
+// yoke:claimed-by label and lease, and clears the assignee when yoke set it.
+func releaseClaim(cfg config, issue bdListIssue, change issueChange) error {
+	for _, label := range issue.Labels {
+		if strings.HasPrefix(strings.ToLower(label), claimedByLabelPrefix) {
+			change.RemoveLabels = append(change.RemoveLabels, label)
+		}
+	}
+	for _, lease := range issueLeases(issue) {
+		change.RemoveLabels = append(change.RemoveLabels, lease.Label)
+	}
+	if err := tracker.Update(issue.ID, change); err != nil {
+		return err
+	}
+	assigner, canAssign := baseTracker(tracker).(issueAssigner)
+	if cfg.Assignee == "" || !canAssign || !strings.EqualFold(issue.Assignee, claimIdentity(cfg)) {
+		return nil
+	}
+	err := assigner.Assign(issue.ID, "")
+	invalidateTrackerCache()
+	return err
+}
+
+// leaseLabelPrefix marks a lease: yoke:lease=<holder>@<expiry>, with the
+// expiry in RFC 3339 UTC. A holder renews its lease by replacing the label.
+const leaseLabelPrefix = "yoke:lease="
+
+type issueLease struct {
+	Holder  string
+	Expires time.Time
+	Label   string
+}
+
+func leaseLabel(holder string, expires time.Time) string {
+	return leaseLabelPrefix + holder + "@" + expires.UTC().Format(time.RFC3339)
+}
+
+// issueLeases parses an issue's lease labels, ignoring malformed ones.
+func issueLeases(issue bdListIssue) []issueLease {
+	leases := []issueLease{}
+	for _, label := range issue.Labels {
+		label = strings.TrimSpace(label)
+		if len(label) <= len(leaseLabelPrefix) || !strings.EqualFold(label[:len(leaseLabelPrefix)], leaseLabelPrefix) {
+			continue
+		}
+		value := label[len(leaseLabelPrefix):]
+		at := strings.LastIndex(value, "@")
+		if at <= 0 {
+			continue
+		}
+		expires, err := time.Parse(time.RFC3339, value[at+1:])
+		if err != nil {
+			continue
+		}
+		leases = append(leases, issueLease{Holder: value[:at], Expires: expires, Label: label})
+	}
+	return leases
+}
+
+// activeLease returns the unexpired lease that wins the issue. Two daemons
+// racing for an expired lease can both write one; the holder that sorts first
+// wins, so both reach the same answer.
+func activeLease(leases []issueLease, now time.Time) (issueLease, bool) {
+	var winner issueLease
+	found := false
+	for _, lease := range leases {
+		if !lease.Expires.After(now) {
+			continue
+		}
+		if !found || strings.ToLower(lease.Holder) < strings.ToLower(winner.Holder) {
+			winner, found = lease, true
+		}
+	}
+	return winner, found
+}
+
+// acquireIssueLease takes or renews this yoke's lease on id and reports
+// whether it holds the lease afterwards. Its own and expired leases are
+// replaced; a live lease held by someone else is left alone unless force is
+// set. The issue is re-read after writing so a lost race gives the lease up.
+func acquireIssueLease(cfg config, id string, force bool) (bool, error) {
+	if cfg.LeaseTTL <= 0 {
+		return true, nil
+	}
+	invalidateTrackerCache()
+	issue, err := baseTracker(tracker).Show(id)
+	if err != nil {
+		return false, err
+	}
+	identity, now := claimIdentity(cfg), time.Now()
+	if lease, ok := activeLease(issueLeases(issue), now); ok && !force && !strings.EqualFold(lease.Holder, identity) {
+		return false, nil
+	}
+	ours := leaseLabel(identity, now.Add(cfg.LeaseTTL))
+	change := issueChange{AddLabels: []string{ours}}
+	for _, lease := range issueLeases(issue) {
+		if force || strings.EqualFold(lease.Holder, identity) || !lease.Expires.After(now) {
+			change.RemoveLabels = append(change.RemoveLabels, lease.Label)
+		}
+	}
+	err = tracker.Update(id, change)
+	invalidateTrackerCache()
+	if err != nil {
+		return false, err
+	}
+	issue, err = baseTracker(tracker).Show(id)
+	if err != nil {
+		return false, err
+	}
+	if lease, ok := activeLease(issueLeases(issue), time.Now()); ok && !strings.EqualFold(lease.Holder, identity) {
+		err := tracker.Update(id, issueChange{RemoveLabels: []string{ours}})
+		invalidateTrackerCache()
+		return false, err
+	}
+	return true, nil
+}
+
+// releaseIssueLeases drops every lease on an issue that is leaving
+// in_progress, so the next claimant does not wait out the TTL.
+func releaseIssueLeases(issue bdListIssue) error {
+	leases := issueLeases(issue)
+	if len(leases) == 0 {
+		return nil
+	}
+	change := issueChange{}
+	for _, lease := range leases {
+		change.RemoveLabels = append(change.RemoveLabels, lease.Label)
+	}
+	err := tracker.Update(issue.ID, change)
+	invalidateTrackerCache()
+	return err
+}
+
+// holdIssueLease renews the lease on id every third of YOKE_LEASE_TTL until
+// the returned stop function is called. When another daemon takes the lease
+// it calls lost, which stops the running agent, and stops renewing.
+func holdIssueLease(cfg config, id string, lost func()) func() {
+	if cfg.LeaseTTL <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(cfg.LeaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				held, err := acquireIssueLease(cfg, id, false)
+				switch {
+				case err != nil:
+					progress.Info("daemon.lease", "warning: failed to renew lease on "+id+": "+err.Error())
+				case !held:
+					progress.Info("daemon.lease", "warning: lost lease on "+id+" to another daemon; stopping its writer")
+					lost()
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// daemonInProgressIssueID is focusedOrInProgressIssueID for daemons sharing a
+// backlog: it returns the first in-progress issue routed to this repository
+// that this daemon leases or can lease, recovering issues whose lease expired.
+func daemonInProgressIssueID(root string, cfg config) (string, error) {
+	if cfg.LeaseTTL <= 0 && len(cfg.Workspace) == 0 {
+		return focusedOrInProgressIssueID(root, cfg.BDPrefix)
+	}
+	candidates := []bdListIssue{}
+	if focused := focusedIssueByWorkflowStatus(root, cfg.BDPrefix, "in_progress"); focused != "" {
+		details, err := issueDetails(focused)
+		if err != nil {
+			return "", err
+		}
+		candidates = append(candidates, details)
+	}
+	inProgress, err := tracker.List(issueQuery{Status: "in_progress"})
+	if err != nil {
+		return "", err
+	}
+	candidates = append(candidates, withoutHeldIssues(inProgress)...)
+	identity := claimIdentity(cfg)
+	for _, issue := range unclaimedIssues(cfg, candidates) {
+		if !looksLikeIssueID(strings.ToLower(issue.ID), cfg.BDPrefix) || workflowStatusForIssue(issue) != "in_progress" || !routedHere(cfg, issue) {
+			continue
+		}
+		previous := ""
+		for _, lease := range issueLeases(issue) {
+			if !strings.EqualFold(lease.Holder, identity) {
+				previous = lease.Holder
+			}
+		}
+		held, err := acquireIssueLease(cfg, issue.ID, false)
+		if err != nil {
+			return "", err
+		}
+		if !held {
+			continue
+		}
+		if previous != "" {
+			note(fmt.Sprintf("Recovered %s from %s, whose lease expired", issue.ID, previous))
+		}
+		if err := writeDaemonFocusIssue(root, issue.ID); err != nil {
+			progress.Info("daemon.lease", "warning: failed to persist daemon focus issue: "+err.Error())
+		}
+		return strings.ToLower(issue.ID), nil
+	}
+	return "", nil
+}
+
+func cmdClaim(args []string) error {
+	options, err := parseClaimArgs(args)
+	if err != nil {
+		return err
+	}
+	if options.Help {
+		printClaimUsage()
+		return nil
+	}
+	progress.Info("claim.setup", "Starting claim command.")
+	issueArg, improvementPassLimit := options.Issue, options.ImprovementPasses
+	if improvementPassLimit != allEpicPasses {
+		progress.Info("claim.setup", fmt.Sprintf("Epic improvement pass limit set to %d.", improvementPassLimit))
+	}
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	progress.Info("claim.setup", "Resolved repository root: "+root)
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	progress.Info("claim.setup", "Loaded config with bd prefix: "+cfg.BDPrefix)
+	if options.Budget > 0 {
+		cfg.MaxIssueCost = options.Budget
+	}
+	if cfg.MaxIssueCost > 0 {
+		progress.Info("claim.setup", "Agent budget per issue: $"+formatBudget(cfg.MaxIssueCost))
+	}
+	if options.HasPolicy {
+		cfg.ClaimPolicy = options.Policy
+	}
+	// Command-line filters narrow this claim in place of the configured
+	// include lists; the configured skip lists still apply.
+	if len(options.Labels) > 0 {
+		cfg.OnlyLabels = options.Labels
+	}
+	if len(options.Types) > 0 {
+		cfg.OnlyTypes = options.Types
+	}
+	if len(options.Paths) > 0 {
+		cfg.OnlyPaths = options.Paths
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+	uncached := tracker
+	tracker = newCachingTracker(uncached)
+	defer func() { tracker = uncached }()
+	progress.Info("claim.setup", "Issue backend ready: "+valueOrFallback(cfg.IssueBackend, defaultIssueBackend))
+
+	issue := issueArg
+	if issue != "" {
+		progress.Info("claim.select", "Using explicit issue argument: "+issue)
+	}
+
+	if issue == "" {
+		progress.Info("claim.select", "No issue argument provided; selecting next ready open issue (policy: "+formatClaimPolicy(cfg.ClaimPolicy)+").")
+		issue = nextClaimIssueID(cfg, true)
+	}
+	if issue == "" {
+		return errors.New("no issue provided and bd ready returned nothing")
+	}
+	progress.Info("claim.select", "Requested claim target: "+issue)
+
+	requestedIssue := issue
+	resolvedIssue, epicCompleted, err := resolveClaimIssue(root, cfg, issue, improvementPassLimit, options.Restart)
+	if err != nil {
+		return err
+	}
+	if epicCompleted {
+		progress.Info("claim.resolve", "Requested epic has no remaining open child tasks.")
+		note("Epic " + requestedIssue + " is complete; closed epic.")
+		return nil
+	}
+	issue = resolvedIssue
+	if err := ensureNotReviewLocked(root, issue); err != nil {
+		return err
+	}
+	if requestedIssue != issue {
+		note("Epic " + requestedIssue + " -> claiming child task " + issue)
+	}
+	if options.Stack {
+		parent, err := stackParentForClaim(issue)
+		if err != nil {
+			return err
+		}
+		if parent != "" {
+			if refExists("refs/heads/" + branchForIssue(issue)) {
+				return fmt.Errorf("branch %s already exists; cannot restack it onto %s", branchForIssue(issue), branchForIssue(parent))
+			}
+			if err := writeStackParent(root, issue, parent); err != nil {
+				return err
+			}
+			progress.Info("claim.stack", fmt.Sprintf("Stacking %s on %s (branch %s).", issue, parent, branchForIssue(parent)))
+		}
+	}
+
+	details, err := issueDetails(issue)
+	if err != nil {
+		return err
+	}
+	if owner, other := claimedByOther(cfg, details); other {
+		if !options.Steal {
+			return fmt.Errorf("%s is claimed by %s; pass --steal to take it over", issue, owner)
+		}
+		note(fmt.Sprintf("Taking over %s from %s", issue, owner))
+	}
+	if held, err := acquireIssueLease(cfg, issue, options.Steal); err != nil {
+		return err
+	} else if !held {
+		return fmt.Errorf("%s is leased by another daemon; pass --steal to take it over", issue)
+	}
+	err = progressStep("claim.transition", "Transitioning issue to in_progress as "+claimIdentity(cfg)+" and removing review queue label if present.", func() error {
+		return recordClaim(cfg, details)
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeDaemonFocusIssue(root, issue); err != nil {
+		progress.Info("claim.focus", "warning: failed to persist daemon focus issue: "+err.Error())
+	} else {
+		progress.Info("claim.focus", "Set daemon focus issue: "+issue)
+	}
+
+	branch := branchForIssue(issue)
+	var worktreePath string
+	err = progressStep("claim.worktree", "Preparing issue worktree for branch: "+branch, func() (err error) {
+		worktreePath, err = ensureIssueWorktree(root, cfg, issue)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	note(fmt.Sprintf("Claimed %s on branch %s", issue, branch))
+	note("Worktree: " + worktreePath)
+	note(fmt.Sprintf("Next: cd %q && yoke submit %s --done \"...\" --remaining \"...\"", worktreePath, issue))
+	if options.Worktree {
+		// Last line is eval-able: eval "$(yoke claim --worktree | tail -n 1)"
+		fmt.Println(worktreeExportLine(worktreePath))
+	}
+	return nil
+}
+
+func worktreeExportLine(worktreePath string) string {
+	return "export ROOT_DIR=" + quoteShell(worktreePath)
+}
+
+type claimOptions struct {
+	Issue             string
+	ImprovementPasses int
+	Restart           bool
+	Budget            float64
+	Stack             bool
+	Worktree          bool
+	Policy            []string
+	HasPolicy         bool
+	Labels            []string
+	Types             []string
+	Paths             []string
+	Steal             bool
+	Help              bool
+}
+
+func parseClaimArgs(args []string) (claimOptions, error) {
+	options := claimOptions{ImprovementPasses: allEpicPasses}
+
+	parsed, err := parseCommandArgs("claim", args)
+	if err != nil {
+		return claimOptions{}, err
+	}
+	if parsed.Help {
+		return claimOptions{Help: true}, nil
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--improvement-passes":
+			passLimit, convErr := strconv.Atoi(flag.Value)
+			if convErr != nil || passLimit < 0 {
+				return claimOptions{}, errors.New("--improvement-passes must be a non-negative integer")
+			}
+			options.ImprovementPasses = passLimit
+		case "--budget":
+			budget, err := parseBudget(flag.Value)
+			if err != nil {
+				return claimOptions{}, fmt.Errorf("invalid --budget value: %w", err)
+			}
+			options.Budget = budget
+		case "--restart":
+			options.Restart = true
+		case "--stack":
+			options.Stack = true
+		case "--worktree":
+			options.Worktree = true
+		case "--steal":
+			options.Steal = true
+		case "--policy":
+			policy, err := parseClaimPolicy(flag.Value)
+			if err != nil {
+				return claimOptions{}, fmt.Errorf("invalid --policy value: %w", err)
+			}
+			options.Policy, options.HasPolicy = policy, true
+		case "--label":
+			options.Labels = append(options.Labels, parseLabelList(flag.Value)...)
+		case "--type":
+			options.Types = append(options.Types, parseLabelList(flag.Value)...)
+		case "--path":
+			options.Paths = append(options.Paths, parseLabelList(flag.Value)...)
+		}
+	}
+	switch len(parsed.Positionals) {
+	case 0:
+	case 1:
+		options.Issue = parsed.Positionals[0]
+	default:
+		return claimOptions{}, errors.New("usage: yoke claim [<prefix>-issue-id] [--improvement-passes N]")
+	}
+
+	return options, nil
+}
+
+func cmdSubmit(args []string) error {
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+
+	var (
+		issue       string
+		doneText    string
+		remaining   string
+		decision    string
+		uncertain   string
+		checks      string
+		noPush      bool
+		noPR        bool
+		noPRNote    bool
+		forceChecks bool
+		autoSummary bool
+		amend       bool
+		syncBase    = cfg.SyncBase
+		commit      = cfg.SubmitCommit
+		agentBody   = cfg.CommitAgentBody
+	)
+
+	parsed, err := parseCommandArgs("submit", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printSubmitUsage()
+		return nil
+	}
+	if issue, err = parsed.issueArg("submit", cfg.BDPrefix); err != nil {
+		return err
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--done":
+			doneText = flag.Value
+		case "--remaining":
+			remaining = flag.Value
+		case "--decision":
+			decision = flag.Value
+		case "--uncertain":
+			uncertain = flag.Value
+		case "--checks":
+			checks = flag.Value
+		case "--force-checks":
+			forceChecks = true
+		case "--no-push":
+			noPush = true
+		case "--no-pr":
+			noPR = true
+		case "--no-pr-comment":
+			noPRNote = true
+		case "--sync-base":
+			syncBase = true
+		case "--no-sync-base":
+			syncBase = false
+		case "--commit":
+			commit = true
+		case "--no-commit":
+			commit = false
+		case "--commit-agent":
+			commit = true
+			agentBody = true
+		case "--auto-summary":
+			autoSummary = true
+		case "--amend":
+			amend = true
+		}
+	}
+
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+	if doneText == "" && !autoSummary {
+		return errors.New("--done is required (or pass --auto-summary)")
+	}
+	if remaining == "" && !autoSummary {
+		return errors.New("--remaining is required (or pass --auto-summary)")
+	}
+
+	if issue == "" {
+		issue = currentBranchIssue(cfg.BDPrefix)
+	}
+	if issue == "" {
+		return errors.New("could not infer issue id from branch; pass issue id explicitly")
+	}
+	if err := ensureNotReviewLocked(root, issue); err != nil {
+		return err
+	}
+	if amend {
+		details, err := issueDetails(issue)
+		if err != nil {
+			return err
+		}
+		if status := workflowStatusForIssue(details); status != "in_review" {
+			return fmt.Errorf("--amend updates an issue already in the review queue; %s is %s (submit without --amend)", issue, valueOrFallback(status, "unknown"))
+		}
+	}
+
+	if autoSummary {
+		var draft handoffDraft
+		err := progressStep("submit.summary", "Drafting the handoff with the writer agent.", func() (err error) {
+			draft, err = draftHandoffSummary(root, cfg, issue)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		// Fields passed explicitly win over the draft.
+		doneText = valueOrFallback(doneText, draft.Done)
+		remaining = valueOrFallback(remaining, draft.Remaining)
+		decision = valueOrFallback(decision, draft.Decision)
+		uncertain = valueOrFallback(uncertain, draft.Uncertain)
+		ok, err := confirmHandoffDraft(handoffDraft{Done: doneText, Remaining: remaining, Decision: decision, Uncertain: uncertain})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("submit cancelled; rerun with --done and --remaining, or --auto-summary to draft again")
+		}
+	}
+
+	if commit {
+		err := progressStep("submit.commit", "Committing outstanding changes.", func() error {
+			return commitOutstandingChanges(root, cfg, issue, doneText, agentBody)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if syncBase {
+		err := progressStep("submit.sync-base", "Syncing with the base branch.", func() error {
+			_, err := syncIssueWithBase(root, cfg, issue, cfg.SyncStrategy, nil)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var checkResults []checkResult
+	err = progressStep("submit.checks", "Running checks.", func() (err error) {
+		checkResults, err = runCachedCheckPlan(root, issueCheckPlan(root, root, cfg, issue, checks), forceChecks)
+		return err
+	})
+	if err != nil {
+		return recordCheckFailure(root, issue, checkResults, err)
+	}
+	checkSummary := formatCheckSummary(checkResults)
+	if cfg.CoverageCmd != "" {
+		err := progressStep("submit.coverage", "Measuring coverage.", func() error {
+			coverage, err := runCoverageGate(root, cfg, issue)
+			checkSummary += "; " + coverage
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	handoffComment := formatIssueHandoffComment(doneText, remaining, decision, uncertain, checkSummary)
+	resolution, hasResolution := readConflictResolution(root, issue)
+	if hasResolution {
+		handoffComment += "\n" + formatConflictResolutionLine(resolution)
+	}
+	// A failed submit leaves its completed steps in .yoke/submit; a retry
+	// skips what already happened and edits, rather than repeats, handoff
+	// comments an earlier attempt posted with different content.
+	steps := loadSubmitProgress(root, issue, submitFingerprint(root, handoffComment))
+	if resumed := steps.skippable(); len(resumed) > 0 {
+		note(fmt.Sprintf("Resuming an interrupted submit of %s; already done: %s", issue, strings.Join(resumed, ", ")))
+	}
+
+	// After checks pass, the handoff comment is independent of the push/PR
+	// chain, so both run at once. The issue only moves to review once both
+	// succeed; the status update and PR comment then go out together.
+	err = runConcurrently(
+		func() error {
+			if steps.done(submitStepHandoff) {
+				return nil
+			}
+			return progressStep("submit.handoff", "Adding handoff note to "+issue+".", func() error {
+				addHandoff := tracker.Comment
+				if amend || steps.posted(submitStepHandoff) {
+					addHandoff = amendIssueHandoff
+				}
+				if err := addHandoff(issue, handoffComment); err != nil {
+					return err
+				}
+				steps.complete(submitStepHandoff)
+				if hasResolution {
+					removeConflictResolution(root, issue)
+				}
+				return nil
+			})
+		},
+		func() error {
+			if !noPush && !steps.done(submitStepPush) {
+				if err := progressStep("submit.push", "Pushing "+branchForIssue(issue)+".", func() error { return pushIssueBranch(root, issue) }); err != nil {
+					return err
+				}
+				steps.complete(submitStepPush)
+			}
+			if noPR {
+				return nil
+			}
+			handoff := prHandoff{Done: doneText, Remaining: remaining, Decision: decision, Uncertain: uncertain, Checks: checkSummary}
+			return progressStep("submit.pr", "Finding or opening the pull request.", func() error { return ensureSubmitPR(root, cfg, issue, handoff) })
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = runConcurrently(
+		func() error {
+			if amend {
+				progress.Info("submit.queue", issue+" is already in the review queue.")
+				return nil
+			}
+			if steps.done(submitStepQueue) {
+				return nil
+			}
+			return progressStep("submit.queue", "Moving "+issue+" to the review queue.", func() error {
+				if err := tracker.Update(issue, issueChange{Status: "blocked", AddLabels: []string{reviewQueueLabel}}); err != nil {
+					return err
+				}
+				if details, err := issueDetails(issue); err == nil {
+					if err := releaseIssueLeases(details); err != nil {
+						progress.Info("submit.queue", "warning: failed to release lease: "+err.Error())
+					}
+				}
+				steps.complete(submitStepQueue)
+				return nil
+			})
+		},
+		func() error {
+			if !noPRNote && !steps.done(submitStepPRComment) {
+				progress.Info("submit.pr-comment", "Posting handoff comment to the pull request.")
+				if postSubmitPRComment(issue, doneText, remaining, decision, uncertain, checkSummary, amend || steps.posted(submitStepPRComment)) {
+					steps.complete(submitStepPRComment)
+				}
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+	steps.finish()
+
+	if !amend {
+		notifyEvent(root, cfg, "submitted", issue, fmt.Sprintf("%s is ready for review: %s", issue, issueTitle(issue)))
+	}
+	note(fmt.Sprintf("Submitted %s for review.", issue))
+	note(fmt.Sprintf("Reviewer: yoke review %s", issue))
+	return nil
+}
+
+const (
+	submitProgressDir   = "submit"
+	submitStepHandoff   = "handoff"
+	submitStepPush      = "push"
+	submitStepQueue     = "queue"
+	submitStepPRComment = "pr-comment"
+)
+
+// submitProgress records the side-effecting submit steps that finished, so
+// a retried submit converges. Completed steps count only for the same
+// fingerprint (handoff text and HEAD); Posted remembers comments from any
+// attempt so a retry with new content edits them instead of adding more.
+type submitProgress struct {
+	Fingerprint string   `json:"fingerprint"`
+	Completed   []string `json:"completed"`
+	Posted      []string `json:"posted,omitempty"`
+	UpdatedAt   string   `json:"updated_at"`
+
+	mu   sync.Mutex
+	path string
+}
+
+func submitProgressPath(root, issue string) string {
+	return filepath.Join(root, ".yoke", submitProgressDir, sanitizePathSegment(strings.ToLower(issue))+".json")
+}
+
+// submitFingerprint identifies one submit attempt's content.
+func submitFingerprint(root, handoffComment string) string {
+	head, _ := commandOutput("git", "-C", root, "rev-parse", "HEAD")
+	sum := sha1.Sum([]byte(strings.TrimSpace(head) + "\n" + handoffComment))
+	return hex.EncodeToString(sum[:])
+}
+
+func loadSubmitProgress(root, issue, fingerprint string) *submitProgress {
+	state := &submitProgress{path: submitProgressPath(root, issue)}
+	if data, err := os.ReadFile(state.path); err == nil {
+		if err := json.Unmarshal(data, state); err != nil {
+			note("warning: ignoring unreadable submit progress: " + err.Error())
+			state = &submitProgress{path: state.path}
+		}
+	}
+	if state.Fingerprint != fingerprint {
+		state.Fingerprint = fingerprint
+		state.Completed = nil
+	}
+	return state
+}
+
+// done reports whether step already finished for this attempt's content.
+func (p *submitProgress) done(step string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return hasLabel(p.Completed, step)
+}
+
+// posted reports whether an earlier attempt already posted step's comment.
+func (p *submitProgress) posted(step string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return hasLabel(p.Posted, step)
+}
+
+func (p *submitProgress) skippable() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string{}, p.Completed...)
+}
+
+func (p *submitProgress) complete(step string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if dryRunMode {
+		return
+	}
+	if !hasLabel(p.Completed, step) {
+		p.Completed = append(p.Completed, step)
+	}
+	if (step == submitStepHandoff || step == submitStepPRComment) && !hasLabel(p.Posted, step) {
+		p.Posted = append(p.Posted, step)
+	}
+	p.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(p.path), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(p.path, append(data, '\n'), 0o644)
+	}
+	if err != nil {
+		note("warning: failed to record submit progress: " + err.Error())
+	}
+}
+
+// finish forgets the progress once every step succeeded.
+func (p *submitProgress) finish() {
+	if dryRunMode {
+		return
+	}
+	if err := os.Remove(p.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		note("warning: failed to clear submit progress: " + err.Error())
+	}
+}
+
+// runConcurrently runs steps in parallel and waits for all of them; the
+// result joins every step's error in step order.
+func runConcurrently(steps ...func() error) error {
+	errs := make([]error, len(steps))
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = step()
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// pushIssueBranch pushes HEAD to origin. The push is forced only when a yoke
+// rebase rewrote the branch since its last push and origin holds nothing
+// beyond the pre-rebase head; commits someone else pushed to the issue branch
+// are refused, never overwritten.
+func pushIssueBranch(root, issue string) error {
+	if !hasOriginRemote() {
+		note("No origin remote; skipping push.")
+		return nil
+	}
+	branch := branchForIssue(issue)
+	rewrittenFrom := readBranchRebase(root, issue)
+	pushArgs := []string{"-C", root, "push", "-u", "origin", "HEAD"}
+	ref := "origin/" + branch
+	if refExists("refs/remotes/" + ref) {
+		known := valueOrFallback(rewrittenFrom, "HEAD")
+		if !branchIsAncestor(root, ref, known) {
+			return fmt.Errorf("remote branch %s has commits you don't have; sync first (git pull --rebase origin %s), then rerun yoke submit", branch, branch)
+		}
+		if rewrittenFrom != "" && !branchIsAncestor(root, ref, "HEAD") {
+			lease := fmt.Sprintf("--force-with-lease=%s:%s", branch, strings.TrimSpace(commandCombinedOutput("git", "-C", root, "rev-parse", ref)))
+			pushArgs = []string{"-C", root, "push", lease, "-u", "origin", "HEAD"}
+		}
+	}
+	if err := runCommand("git", pushArgs...); err != nil {
+		return err
+	}
+	removeBranchRebase(root, issue)
+	return nil
+}
+
+// ensureSubmitPR creates the issue's PR (and its epic PR) when missing and
+// confirms an open PR exists afterwards.
+func ensureSubmitPR(root string, cfg config, issue string, handoff prHandoff) error {
+	if err := ensureEpicPRForIssue(root, cfg, issue); err != nil {
+		return err
+	}
+	baseBranch, err := issuePRBaseBranch(root, cfg, issue)
+	if err != nil {
+		return err
+	}
+	if err := createPRIfNeeded(root, cfg, issue, issueTitle(issue), baseBranch, handoff); err != nil {
+		return err
+	}
+	if _, _, _, ok, err := openPRForIssue(issue); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("no open PR found for %s after submit; expected branch %s to have an open PR", issue, branchForIssue(issue))
+	}
+	return nil
+}
+
+// formatSubmitCommitMessage builds the commit yoke submit --commit creates:
+// the subject followed by the --done text and an optional agent body.
+func formatSubmitCommitMessage(subject, doneText, body string) string {
+	paragraphs := []string{subject}
+	if done := strings.TrimSpace(doneText); done != "" {
+		paragraphs = append(paragraphs, done)
+	}
+	if body = strings.TrimSpace(body); body != "" {
+		paragraphs = append(paragraphs, body)
+	}
+	return strings.Join(paragraphs, "\n\n") + "\n"
+}
+
+const maxCommitPromptDiffBytes = 60 * 1024
+
+func buildCommitBodyPrompt(issue, title, doneText, diff string) string {
+	return strings.Join([]string{
+		fmt.Sprintf("Draft the body of a git commit message for %s (%s).", issue, valueOrFallback(title, "untitled")),
+		"Summary of the work: " + valueOrFallback(strings.TrimSpace(doneText), "(none)"),
+		"Reply with only the body: plain text paragraphs or bullets wrapped at 72 columns explaining what changed and why.",
+		"No subject line, no code fences, no trailers. Do not run git or modify files.",
+		"",
+		"Staged diff:",
+		"```diff",
+		truncateForPrompt(diff, maxCommitPromptDiffBytes),
+		"```",
+	}, "\n")
+}
+
+const maxHandoffPromptCommits = 30
+
+// handoffDraft is the writer agent's proposed handoff for
+// yoke submit --auto-summary.
+type handoffDraft struct {
+	Done      string `json:"done"`
+	Remaining string `json:"remaining"`
+	Decision  string `json:"decision"`
+	Uncertain string `json:"uncertain"`
+}
+
+func buildHandoffSummaryPrompt(issue string, details bdListIssue, commits, diff string) string {
+	return strings.Join([]string{
+		fmt.Sprintf("Draft the reviewer handoff for %s (%s).", issue, valueOrFallback(strings.TrimSpace(details.Title), "untitled")),
+		"Issue description: " + valueOrFallback(strings.TrimSpace(details.Description), "(none)"),
+		"Acceptance criteria: " + valueOrFallback(strings.TrimSpace(details.Acceptance), "(none)"),
+		"",
+		`End your reply with one JSON object on its own: {"done":"what is complete, specific to the diff","remaining":"what is left, or None","decision":"key design decision, or empty","uncertain":"open question for the reviewer, or empty"}.`,
+		"Each field is one or two plain sentences. Do not run git or modify files.",
+		"",
+		"Commits on the branch:",
+		valueOrFallback(strings.TrimSpace(commits), "(none)"),
+		"",
+		"Branch diff (including uncommitted changes):",
+		"```diff",
+		truncateForPrompt(diff, maxCommitPromptDiffBytes),
+		"```",
+	}, "\n")
+}
+
+// parseHandoffDraft reads the last JSON object in agent output that has
+// non-empty done and remaining fields.
+func parseHandoffDraft(output string) (handoffDraft, bool) {
+	var draft handoffDraft
+	found := scanJSONObjectsBackward(output, `"done"`, func(raw []byte) bool {
+		draft = handoffDraft{}
+		if json.Unmarshal(raw, &draft) != nil {
+			return false
+		}
+		draft.Done = strings.TrimSpace(draft.Done)
+		draft.Remaining = strings.TrimSpace(draft.Remaining)
+		draft.Decision = strings.TrimSpace(draft.Decision)
+		draft.Uncertain = strings.TrimSpace(draft.Uncertain)
+		return draft.Done != "" && draft.Remaining != ""
+	})
+	return draft, found
+}
+
+// branchChangeContext returns the commits and diff (working tree included)
+// since the issue branch forked from its PR base, or just the uncommitted
+// diff when the base cannot be found.
+func branchChangeContext(root string, cfg config, issue string) (string, string) {
+	forkPoint := "HEAD"
+	if base, err := issuePRBaseBranch(root, cfg, issue); err == nil {
+		if ref := localOrRemoteRef(base); ref != "" {
+			if output, err := commandOutput("git", "-C", root, "merge-base", ref, "HEAD"); err == nil {
+				forkPoint = strings.TrimSpace(output)
+			}
+		}
+	}
+	commits := ""
+	if forkPoint != "HEAD" {
+		commits = commandCombinedOutput("git", "-C", root, "log", "--format=%h %s", "-n", strconv.Itoa(maxHandoffPromptCommits), forkPoint+"..HEAD")
+	}
+	diff := commandCombinedOutput("git", "-C", root, "diff", forkPoint)
+	if untracked := strings.TrimSpace(commandCombinedOutput("git", "-C", root, "ls-files", "--others", "--exclude-standard")); untracked != "" {
+		diff += "\nUntracked files:\n" + untracked + "\n"
+	}
+	return commits, diff
+}
+
+// draftHandoffSummary runs the writer agent over the branch's commits and
+// diff to draft the --done/--remaining/--decision/--uncertain fields.
+func draftHandoffSummary(root string, cfg config, issue string) (handoffDraft, error) {
+	agentID, err := agentIDForRole(cfg, "writer")
+	if err != nil {
+		return handoffDraft{}, err
+	}
+	details, err := issueDetails(issue)
+	if err != nil {
+		return handoffDraft{}, err
+	}
+	commits, diff := branchChangeContext(root, cfg, issue)
+	started := time.Now()
+	output, runErr := runAgentPrompt(agentID, root, buildHandoffSummaryPrompt(issue, details, commits, diff), []string{
+		"ISSUE_ID=" + issue,
+		"ROOT_DIR=" + root,
+		"YOKE_ROLE=writer",
+	}, "[submit][summary] ")
+	recordSessionLog(root, sessionLogEntry{IssueID: issue, Role: "writer", Source: "handoff-summary", AgentID: agentID}, started, output, runErr)
+	recordAgentUsage(root, usageRecord{IssueID: issue, Role: "writer", AgentID: agentID}, output)
+	if runErr != nil {
+		return handoffDraft{}, fmt.Errorf("writer agent failed to draft the handoff: %w", runErr)
+	}
+	draft, ok := parseHandoffDraft(processAgentOutput(output))
+	if !ok {
+		return handoffDraft{}, errors.New("writer agent did not return a handoff with done and remaining; pass --done and --remaining instead")
+	}
+	return draft, nil
+}
+
+// confirmHandoffDraft shows the drafted handoff. A person at a terminal must
+// accept it; agents and non-interactive runs apply it directly.
+func confirmHandoffDraft(draft handoffDraft) (bool, error) {
+	fmt.Println("Drafted handoff:")
+	fmt.Println("  Done: " + draft.Done)
+	fmt.Println("  Remaining: " + draft.Remaining)
+	if draft.Decision != "" {
+		fmt.Println("  Decision: " + draft.Decision)
+	}
+	if draft.Uncertain != "" {
+		fmt.Println("  Uncertain: " + draft.Uncertain)
+	}
+	if strings.TrimSpace(os.Getenv("YOKE_ROLE")) != "" || !isInteractiveTerminal(os.Stdin) || !isInteractiveTerminal(os.Stdout) {
+		return true, nil
+	}
+	fmt.Print("Submit with this handoff? [Y/n]: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "", "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// commitOutstandingChanges stages everything in the worktree and commits it
+// with a message generated from the issue. The writer agent optionally drafts
+// the body; a failed draft falls back to the generated message.
+func commitOutstandingChanges(root string, cfg config, issue, doneText string, agentBody bool) error {
+	if err := runCommand("git", "-C", root, "add", "-A"); err != nil {
+		return err
+	}
+	if runCommandDiscard("git", "-C", root, "diff", "--cached", "--quiet") == nil {
+		note("No outstanding changes to commit.")
+		return nil
+	}
+
+	details, err := issueDetails(issue)
+	if err != nil {
+		return err
+	}
+	title := strings.TrimSpace(details.Title)
+	body := ""
+	if agentBody {
+		agentID, err := agentIDForRole(cfg, "writer")
+		if err != nil {
+			return err
+		}
+		diff := commandCombinedOutput("git", "-C", root, "diff", "--cached")
+		started := time.Now()
+		output, runErr := runAgentPrompt(agentID, root, buildCommitBodyPrompt(issue, title, doneText, diff), []string{
+			"ISSUE_ID=" + issue,
+			"ROOT_DIR=" + root,
+			"YOKE_ROLE=writer",
+		}, "[submit][commit] ")
+		recordSessionLog(root, sessionLogEntry{IssueID: issue, Role: "writer", Source: "commit-body", AgentID: agentID}, started, output, runErr)
+		recordAgentUsage(root, usageRecord{IssueID: issue, Role: "writer", AgentID: agentID}, output)
+		if runErr != nil {
+			note("warning: writer agent failed to draft a commit body; using the generated message: " + runErr.Error())
+		} else {
+			body = processAgentOutput(output)
+		}
+	}
+
+	message := formatSubmitCommitMessage(formatCommitSubject(cfg, issue, details.IssueType, title), doneText, body)
+	if err := runCommand("git", append([]string{"-C", root, "commit"}, append(gitCommitSignFlags(cfg), "-m", message)...)...); err != nil {
+		return err
+	}
+	note("Committed outstanding changes for " + issue)
+	return nil
+}
+
+type baseSyncResult struct {
+	BaseRef   string
+	Updated   bool
+	Rewritten bool
+	Resolved  []string
+}
+
+// conflictResolver resolves the unmerged files of a paused rebase or merge and
+// stages them. Returning an error aborts the sync.
+type conflictResolver func(baseRef string, files []string) error
+
+const maxConflictRounds = 20
+
+func parseSyncStrategy(raw string) (string, error) {
+	switch strategy := strings.ToLower(strings.TrimSpace(raw)); strategy {
+	case "":
+		return defaultSync, nil
+	case "rebase", "merge":
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown sync strategy %q (want rebase or merge)", raw)
+	}
+}
+
+func conflictedFiles(root string) []string {
+	output := commandCombinedOutput("git", "-C", root, "diff", "--name-only", "--diff-filter=U")
+	files := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files
+}
+
+func formatSyncConflictReport(issue, baseRef, strategy string, files []string) string {
+	lines := []string{
+		fmt.Sprintf("syncing %s with %s (%s) hit conflicts; the %s was aborted and the branch is unchanged.", issue, baseRef, strategy, strategy),
+		"Conflicting files:",
+	}
+	if len(files) == 0 {
+		lines = append(lines, "  (git reported a failure but no unmerged paths)")
+	}
+	for _, file := range files {
+		lines = append(lines, "  - "+file)
+	}
+	lines = append(lines, fmt.Sprintf("Resolve manually: git %s %s, fix the files above, then rerun yoke submit (or try yoke sync-base --resolve-with-agent).", strategy, baseRef))
+	return strings.Join(lines, "\n")
+}
+
+// syncIssueWithBase fetches the issue's PR base and rebases or merges it into
+// the current branch so checks and review run against an up-to-date base. On
+// conflict, resolve (when set) gets a chance to fix each conflicted step;
+// otherwise the operation is aborted and a report of the conflicting files is
+// returned as the error.
+func syncIssueWithBase(root string, cfg config, issue, strategy string, resolve conflictResolver) (baseSyncResult, error) {
+	strategy, err := parseSyncStrategy(strategy)
+	if err != nil {
+		return baseSyncResult{}, err
+	}
+	if dirty := strings.TrimSpace(commandCombinedOutput("git", "-C", root, "status", "--porcelain", "--untracked-files=no")); dirty != "" {
+		return baseSyncResult{}, errors.New("working tree has uncommitted changes; commit them before syncing the base branch")
+	}
+
+	base, err := issuePRBaseBranch(root, cfg, issue)
+	if err != nil {
+		return baseSyncResult{}, err
+	}
+	baseRef := base
+	if hasOriginRemote() && remoteBranchExists(root, base) {
+		if err := runCommand("git", "-C", root, "fetch", "origin", base); err != nil {
+			return baseSyncResult{}, fmt.Errorf("fetch %s: %w", base, err)
+		}
+		baseRef = "origin/" + base
+	} else if !refExists("refs/heads/" + base) {
+		return baseSyncResult{}, fmt.Errorf("base branch %s not found locally or on origin", base)
+	}
+
+	result := baseSyncResult{BaseRef: baseRef}
+	if branchIsAncestor(root, baseRef, "HEAD") {
+		note(fmt.Sprintf("%s is already up to date with %s.", issue, baseRef))
+		return result, nil
+	}
+
+	note(fmt.Sprintf("Syncing %s with %s (%s).", issue, baseRef, strategy))
+	previousHead := strings.TrimSpace(commandCombinedOutput("git", "-C", root, "rev-parse", "HEAD"))
+	var syncErr error
+	if strategy == "merge" {
+		syncErr = runCommand("git", "-C", root, "merge", "--no-edit", baseRef)
+	} else {
+		syncErr = runCommand("git", "-C", root, "rebase", baseRef)
+	}
+	for round := 1; syncErr != nil; round++ {
+		files := conflictedFiles(root)
+		if resolve == nil || len(files) == 0 || round > maxConflictRounds {
+			_ = runCommandDiscard("git", "-C", root, strategy, "--abort")
+			return result, errors.New(formatSyncConflictReport(issue, baseRef, strategy, files))
+		}
+		if err := resolve(baseRef, files); err != nil {
+			_ = runCommandDiscard("git", "-C", root, strategy, "--abort")
+			return result, fmt.Errorf("%s\nAutomatic resolution failed: %w", formatSyncConflictReport(issue, baseRef, strategy, files), err)
+		}
+		result.Resolved = append(result.Resolved, files...)
+		if strategy == "merge" {
+			syncErr = runCommand("git", append([]string{"-C", root, "commit", "--no-edit"}, gitCommitSignFlags(cfg)...)...)
+		} else {
+			syncErr = runCommand("git", "-C", root, "-c", "core.editor=true", "rebase", "--continue")
+		}
+	}
+	result.Updated = true
+	if strategy == "rebase" {
+		result.Rewritten = true
+		recordBranchRebase(root, issue, previousHead)
+	}
+	return result, nil
+}
+
+func formatSyncInterval(interval time.Duration) string {
+	if interval <= 0 {
+		return ""
+	}
+	return interval.String()
+}
+
+// Periodic base sync: the daemon keeps long-running issue branches close to
+// their base. Last attempt times live in the shared state dir so every
+// worktree agrees on when an issue is due.
+
+func baseSyncTimesPath(root string) string {
+	return filepath.Join(yokeSharedStateDir(root), "base-syncs.json")
+}
+
+func loadBaseSyncTimes(root string) map[string]string {
+	times := map[string]string{}
+	data, err := os.ReadFile(baseSyncTimesPath(root))
+	if err != nil {
+		return times
+	}
+	if err := json.Unmarshal(data, &times); err != nil {
+		return map[string]string{}
+	}
+	return times
+}
+
+func writeBaseSyncTime(root, issue string, at time.Time) error {
+	if dryRunMode {
+		return nil
+	}
+	times := loadBaseSyncTimes(root)
+	times[strings.ToLower(issue)] = at.UTC().Format(time.RFC3339)
+	if err := os.MkdirAll(filepath.Dir(baseSyncTimesPath(root)), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(times, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(baseSyncTimesPath(root), append(data, '\n'), 0o644)
+}
+
+// baseSyncDue reports whether an issue last synced at last (zero if never) is
+// due at now. An issue that was never synced starts its clock now instead of
+// syncing on the first iteration.
+func baseSyncDue(last, now time.Time, interval time.Duration) bool {
+	return !last.IsZero() && now.Sub(last) >= interval
+}
+
+// periodicBaseSync syncs the issue branch with its base once YOKE_SYNC_INTERVAL
+// has elapsed. Conflicts are escalated on the issue (comment plus
+// yoke:base-conflict label) and the writer keeps going on the unsynced branch.
+func periodicBaseSync(root, worktreePath string, cfg config, issue string, now time.Time) {
+	recorded, seen := loadBaseSyncTimes(root)[strings.ToLower(issue)]
+	if !seen {
+		_ = writeBaseSyncTime(root, issue, now)
+		return
+	}
+	last, err := time.Parse(time.RFC3339, recorded)
+	if err != nil || !baseSyncDue(last, now, cfg.SyncInterval) {
+		return
+	}
+	if dirty := strings.TrimSpace(commandCombinedOutput("git", "-C", worktreePath, "status", "--porcelain", "--untracked-files=no")); dirty != "" {
+		note("Skipping periodic base sync for " + issue + ": uncommitted changes in " + worktreePath)
+		return
+	}
+	if err := writeBaseSyncTime(root, issue, now); err != nil {
+		note("warning: failed to record base sync time: " + err.Error())
+	}
+
+	result, err := syncIssueWithBase(worktreePath, cfg, issue, cfg.SyncStrategy, nil)
+	if err != nil {
+		note("warning: periodic base sync failed for " + issue + ": " + err.Error())
+		details, detailsErr := issueDetails(issue)
+		if detailsErr == nil && !hasLabel(details.Labels, baseConflictLabel) {
+			_ = tracker.Update(issue, issueChange{AddLabels: []string{baseConflictLabel}})
+		}
+		_ = tracker.Comment(issue, "yoke: periodic base sync needs help.\n"+err.Error())
+		return
+	}
+	if details, err := issueDetails(issue); err == nil && hasLabel(details.Labels, baseConflictLabel) {
+		_ = tracker.Update(issue, issueChange{RemoveLabels: []string{baseConflictLabel}})
+	}
+	if result.Updated {
+		note(fmt.Sprintf("Periodic base sync: %s now includes %s", issue, result.BaseRef))
+	}
+}
+
+// hasConflictMarkers reports whether content still contains git conflict
+// markers at the start of a line.
+func hasConflictMarkers(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "<<<<<<< ") || strings.HasPrefix(line, ">>>>>>> ") || strings.TrimRight(line, "\r") == "=======" {
+			return true
+		}
+	}
+	return false
+}
+
+func buildConflictResolutionPrompt(issue bdListIssue, baseRef string, files []string, hunks string) string {
+	lines := []string{
+		fmt.Sprintf("You are resolving merge conflicts on the branch for %s while syncing it with %s.", issue.ID, baseRef),
+		"Edit only the conflicted files listed below. Remove every conflict marker, keeping both the base branch's changes and the issue's intent.",
+		"Do not commit, stage, push, run git rebase/merge, or run yoke commands; yoke stages and continues after you finish.",
+		"",
+		"Issue: " + valueOrFallback(issue.Title, issue.ID),
+	}
+	if description := strings.TrimSpace(issue.Description); description != "" {
+		lines = append(lines, "", "Issue description:", truncateForPrompt(description, maxClarificationCommentChars))
+	}
+	lines = append(lines, "", "Conflicted files:")
+	for _, file := range files {
+		lines = append(lines, "- "+file)
+	}
+	lines = append(lines, "", "Conflict hunks:", "```diff", truncateForPrompt(hunks, maxHumanReviewDiffBytes), "```")
+	return strings.Join(lines, "\n")
+}
+
+// agentConflictResolver hands conflicts to the writer agent, then verifies no
+// markers remain before staging the files.
+func agentConflictResolver(root string, cfg config, issue string) (conflictResolver, error) {
+	agentID, err := agentIDForRole(cfg, "writer")
+	if err != nil {
+		return nil, err
+	}
+	details, err := issueDetails(issue)
+	if err != nil {
+		return nil, err
+	}
+	return func(baseRef string, files []string) error {
+		hunks := commandCombinedOutput("git", "-C", root, "diff", "--", ".")
+		note(fmt.Sprintf("Asking %s to resolve %d conflicted file(s): %s", agentID, len(files), summarizeIDs(files, 5)))
+		started := time.Now()
+		output, runErr := runAgentPrompt(agentID, root, buildConflictResolutionPrompt(details, baseRef, files, hunks), []string{
+			"ISSUE_ID=" + issue,
+			"ROOT_DIR=" + root,
+			"BD_PREFIX=" + cfg.BDPrefix,
+			"YOKE_ROLE=writer",
+		}, "[sync-base]["+agentID+"] ")
+		recordSessionLog(root, sessionLogEntry{IssueID: issue, Role: "writer", Source: "conflict-resolution", AgentID: agentID}, started, output, runErr)
+		recordAgentUsage(root, usageRecord{IssueID: issue, Role: "writer", AgentID: agentID}, output)
+		if runErr != nil {
+			return fmt.Errorf("writer agent failed: %w", runErr)
+		}
+
+		unresolved := []string{}
+		for _, file := range files {
+			data, err := os.ReadFile(filepath.Join(root, file))
+			if err == nil && hasConflictMarkers(string(data)) {
+				unresolved = append(unresolved, file)
+			}
+		}
+		if len(unresolved) > 0 {
+			return fmt.Errorf("conflict markers remain in %s", strings.Join(unresolved, ", "))
+		}
+		return runCommand("git", append([]string{"-C", root, "add", "--"}, files...)...)
+	}, nil
+}
+
+// conflictResolution is kept until the next submit so the writer handoff
+// comment reports what was auto-resolved.
+type conflictResolution struct {
+	Issue    string   `json:"issue"`
+	BaseRef  string   `json:"base_ref"`
+	Strategy string   `json:"strategy"`
+	AgentID  string   `json:"agent"`
+	Files    []string `json:"files"`
+	Checks   string   `json:"checks"`
+	At       string   `json:"at"`
+}
+
+// A rebase record keeps the branch head from before yoke first rebased an
+// issue branch since its last push, so the next push can tell yoke's own
+// rewrite from commits someone else pushed.
+
+func branchRebasePath(root, issue string) string {
+	return filepath.Join(yokeSharedStateDir(root), "rebases", sanitizePathSegment(strings.ToLower(issue)))
+}
+
+// recordBranchRebase keeps the earliest pre-rebase head: later rebases before
+// a push rewrite commits that were never on origin.
+func recordBranchRebase(root, issue, head string) {
+	path := branchRebasePath(root, issue)
+	if dryRunMode || head == "" || fileExists(path) {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+		_ = os.WriteFile(path, []byte(head+"\n"), 0o644)
+	}
+}
+
+func readBranchRebase(root, issue string) string {
+	data, err := os.ReadFile(branchRebasePath(root, issue))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func removeBranchRebase(root, issue string) {
+	if dryRunMode {
+		return
+	}
+	_ = os.Remove(branchRebasePath(root, issue))
+}
+
+func conflictResolutionPath(root, issue string) string {
+	return filepath.Join(yokeSharedStateDir(root), "conflict-resolutions", sanitizePathSegment(strings.ToLower(issue))+".json")
+}
+
+func writeConflictResolution(root string, resolution conflictResolution) error {
+	if dryRunMode {
+		return nil
+	}
+	path := conflictResolutionPath(root, resolution.Issue)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(resolution)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func readConflictResolution(root, issue string) (conflictResolution, bool) {
+	data, err := os.ReadFile(conflictResolutionPath(root, issue))
+	if err != nil {
+		return conflictResolution{}, false
+	}
+	var resolution conflictResolution
+	if err := json.Unmarshal(data, &resolution); err != nil {
+		return conflictResolution{}, false
+	}
+	return resolution, true
+}
+
+func removeConflictResolution(root, issue string) {
+	if dryRunMode {
+		return
+	}
+	_ = os.Remove(conflictResolutionPath(root, issue))
+}
+
+func formatConflictResolutionLine(resolution conflictResolution) string {
+	return fmt.Sprintf("- Auto-resolved conflicts: %s while syncing with `%s` (%s, agent %s); checks `%s` passed afterwards",
+		strings.Join(resolution.Files, ", "), resolution.BaseRef, resolution.Strategy, valueOrFallback(resolution.AgentID, "unknown"), sanitizeCommentLine(resolution.Checks))
+}
+
+func cmdSyncBase(args []string) error {
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+
+	var (
+		issue     string
+		strategy  = cfg.SyncStrategy
+		checks    string
+		withAgent bool
+	)
+	parsed, err := parseCommandArgs("sync-base", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printSyncBaseUsage()
+		return nil
+	}
+	// sync-base works without the issue backend; it only reads issue titles
+	// and leaves comments when one is available.
+	_ = useIssueTracker(cfg)
+	if issue, err = parsed.issueArg("sync-base", cfg.BDPrefix); err != nil {
+		return err
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--strategy":
+			value, err := parseSyncStrategy(flag.Value)
+			if err != nil {
+				return err
+			}
+			strategy = value
+		case "--checks":
+			checks = flag.Value
+		case "--resolve-with-agent":
+			withAgent = true
+		}
+	}
+
+	if issue == "" {
+		issue = currentBranchIssue(cfg.BDPrefix)
+	}
+	if issue == "" {
+		return errors.New("could not infer issue id from branch; pass issue id explicitly")
+	}
+	if err := ensureNotReviewLocked(root, issue); err != nil {
+		return err
+	}
+
+	var resolve conflictResolver
+	agentID := ""
+	if withAgent {
+		if resolve, err = agentConflictResolver(root, cfg, issue); err != nil {
+			return err
+		}
+		agentID, _ = agentIDForRole(cfg, "writer")
+	}
+	result, err := syncIssueWithBase(root, cfg, issue, strategy, resolve)
+	if err != nil {
+		return err
+	}
+	if !result.Updated {
+		return nil
+	}
+
+	plan := issueCheckPlan(root, root, cfg, issue, checks)
+	if _, err := runCachedCheckPlan(root, plan, false); err != nil {
+		if len(result.Resolved) > 0 {
+			return fmt.Errorf("checks failed after auto-resolving %s; fix the branch before submitting: %w", strings.Join(result.Resolved, ", "), err)
+		}
+		return err
+	}
+
+	if len(result.Resolved) > 0 {
+		resolution := conflictResolution{
+			Issue:    issue,
+			BaseRef:  result.BaseRef,
+			Strategy: strategy,
+			AgentID:  agentID,
+			Files:    result.Resolved,
+			Checks:   describeCheckPlan(plan),
+			At:       time.Now().UTC().Format(time.RFC3339),
+		}
+		if err := writeConflictResolution(root, resolution); err != nil {
+			note("warning: failed to record conflict resolution for the handoff: " + err.Error())
+		}
+		note(fmt.Sprintf("Auto-resolved %d conflicted file(s): %s", len(result.Resolved), strings.Join(result.Resolved, ", ")))
+		note("The next yoke submit handoff comment will list the auto-resolved files.")
+	}
+	note(fmt.Sprintf("Synced %s with %s (%s).", issue, result.BaseRef, strategy))
+	return nil
+}
+
+func cmdReview(args []string) error {
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+
+	var (
+		issue        string
+		action       string
+		rejectReason string
+		noteText     string
+		runAgent     bool
+		noPRNote     bool
+		runSecurity  bool
+		waitCI       = cfg.WaitCI
+		verdict      *reviewVerdict
+	)
+
+	parsed, err := parseCommandArgs("review", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printReviewUsage()
+		return nil
+	}
+	if issue, err = parsed.issueArg("review", cfg.BDPrefix); err != nil {
+		return err
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--approve":
+			action = "approve"
+		case "--reject":
+			action = "reject"
+			rejectReason = flag.Value
+		case "--note":
+			noteText = flag.Value
+		case "--agent":
+			runAgent = true
+		case "--no-pr-comment":
+			noPRNote = true
+		case "--wait-ci":
+			waitCI = true
+		case "--security":
+			runSecurity = true
+		}
+	}
+
+	if dryRunMode && (runAgent || runSecurity) {
+		return errors.New("--agent and --security run agents, which cannot be dry-run; review without them")
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+	invalidateStaleApprovals(root)
+
+	if issue == "" {
+		issue = nextReviewableIssueID(cfg)
+	}
+	if issue == "" {
+		return errors.New("no reviewable issue found")
+	}
+
+	if runSecurity {
+		worktree := root
+		if path := worktreePathForBranch(root, branchForIssue(issue)); path != "" {
+			worktree = path
+		}
+		var report securityReport
+		err := progressStep("review.security", "Running security review for "+issue+" in "+worktree, func() (err error) {
+			report, err = runSecurityReview(root, cfg, issue, worktree, !noPRNote)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if blocking := len(report.blockingFindings()); blocking > 0 {
+			progress.Info("review.security", fmt.Sprintf("%d critical/high security finding(s); approval is blocked until a clean security review (label %s).", blocking, securityBlockedLabel))
+		}
+	}
+
+	if runAgent {
+		if strings.TrimSpace(cfg.ReviewCmd) == "" {
+			return errors.New("YOKE_REVIEW_CMD is empty in .yoke/config.sh")
+		}
+		lock, err := acquireReviewLock(root, issue)
+		if err != nil {
+			return err
+		}
+		defer releaseReviewLock(root, issue)
+		progress.Info("review.lock", fmt.Sprintf("Acquired review lock for %s at %s", issue, shortSHA(lock.SHA)))
+		agentRoot := root
+		if path := worktreePathForBranch(root, branchForIssue(issue)); path != "" {
+			agentRoot = path
+		}
+		progress.Start("review.agent", "Running reviewer agent for "+issue+" in "+agentRoot)
+		cmd := exec.Command("bash", "-lc", cfg.ReviewCmd)
+		cmd.Dir = agentRoot
+		var captured synchronizedBuffer
+		cmd.Stdout = io.MultiWriter(os.Stdout, &captured)
+		cmd.Stderr = io.MultiWriter(os.Stderr, &captured)
+		cmd.Env = append(os.Environ(),
+			"ISSUE_ID="+issue,
+			"ROOT_DIR="+agentRoot,
+			"BD_PREFIX="+cfg.BDPrefix,
+			"YOKE_ROLE=reviewer",
+			"YOKE_REVIEW_VERDICT_SCHEMA="+reviewVerdictSchema,
+		)
+		cmd.Env = append(cmd.Env, reviewDiffEnv(root, cfg, issue, agentRoot)...)
+		started := time.Now()
+		stopAgentTiming := timePhase("agent", true)
+		runErr := cmd.Run()
+		stopAgentTiming()
+		recordSessionLog(root, sessionLogEntry{IssueID: issue, Role: "reviewer", Source: "review", Command: cfg.ReviewCmd}, started, captured.String(), runErr)
+		recordAgentUsage(root, usageRecord{IssueID: issue, Role: "reviewer"}, captured.String())
+		if runErr != nil {
+			progress.Fail("review.agent", runErr)
+			return runErr
+		}
+		progress.Finish("review.agent", "")
+		// An explicit --approve/--reject wins; otherwise a verdict the agent
+		// emitted but did not apply decides.
+		if action == "" {
+			parsed, ok, err := parseReviewVerdict(captured.String())
+			if err != nil {
+				return fmt.Errorf("invalid reviewer verdict for %s: %w", issue, err)
+			}
+			if status, _ := issueStatus(issue); ok && status == "in_review" {
+				progress.Info("review.agent", "Applying reviewer verdict: "+parsed.Decision)
+				verdict = &parsed
+				action = parsed.Decision
+				if action == "reject" {
+					rejectReason = parsed.rejectReason()
+				}
+			}
+		}
+	}
+
+	if noteText != "" {
+		if err := tracker.Comment(issue, noteText); err != nil {
+			return err
+		}
+	}
+
+	reviewedSHA := ""
+	var labels []string
+	issueType := ""
+	if action != "" {
+		reviewedSHA, err = reviewedCommitSHA(root, issue)
+		if err != nil {
+			return err
+		}
+		if details, err := issueDetails(issue); err == nil {
+			labels = details.Labels
+			issueType = details.IssueType
+		}
+	}
+	// Approvals from reviewer automation (an agent verdict, or any yoke run
+	// under YOKE_ROLE) must pass YOKE_REQUIRE_HUMAN_FOR and
+	// YOKE_APPROVAL_POLICY; a person's --approve is final.
+	automated := verdict != nil || strings.TrimSpace(os.Getenv("YOKE_ROLE")) != ""
+	held := false
+
+	switch action {
+	case "approve":
+		if hasLabel(labels, securityBlockedLabel) {
+			return fmt.Errorf("cannot approve %s: its security review has critical/high findings (label %s); fix them and re-run yoke review %s --security", issue, securityBlockedLabel, issue)
+		}
+		if automated {
+			if matched := requireHumanMatch(cfg, labels, issueType); matched != "" {
+				reason := fmt.Sprintf("%s matches YOKE_REQUIRE_HUMAN_FOR", matched)
+				if err := holdForHumanApproval(root, issue, "the issue requires a human approval", []string{reason}, !noPRNote); err != nil {
+					return err
+				}
+				held = true
+				break
+			}
+		}
+		if automated && len(cfg.ApprovalPolicy) > 0 {
+			var failures []string
+			err := progressStep("review.policy", "Evaluating approval policy "+formatApprovalPolicy(cfg.ApprovalPolicy)+".", func() (err error) {
+				failures, err = checkApprovalPolicy(root, cfg, issue)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			if len(failures) > 0 {
+				if err := holdForHumanApproval(root, issue, "the approval policy did not pass", failures, !noPRNote); err != nil {
+					return err
+				}
+				held = true
+				break
+			}
+		}
+		if hasLabel(labels, awaitingApprovalLabel) {
+			if err := tracker.Update(issue, issueChange{RemoveLabels: []string{awaitingApprovalLabel}}); err != nil {
+				return err
+			}
+		}
+		prNumber, _, isDraft, ok, err := openPRForIssue(issue)
+		if err != nil {
+			return fmt.Errorf("cannot approve %s: %w", issue, err)
+		}
+		if !ok {
+			return fmt.Errorf("cannot approve %s: no open PR found for issue branch %s", issue, branchForIssue(issue))
+		}
+		if waitCI {
+			err := progressStep("review.ci", "Waiting for required CI checks on PR #"+prNumber+".", func() error {
+				return waitForCIGate(root, issue, prNumber, defaultPollOptions.Timeout)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err := tracker.Comment(issue, formatApprovalComment(reviewedSHA)); err != nil {
+			return err
+		}
+		if err := ensurePRReady(prNumber, isDraft); err != nil {
+			return err
+		}
+		if err := integrateApprovedTaskIntoEpic(root, cfg, issue); err != nil {
+			return err
+		}
+		if cfg.MergeQueue && issueTargetsBaseBranch(root, issue) {
+			if err := enqueueApprovedPR(root, issue, prNumber, reviewedSHA); err != nil {
+				return err
+			}
+			notifyEvent(root, cfg, "approved", issue, fmt.Sprintf("Approved %s at %s and queued it for merge: %s", issue, shortSHA(reviewedSHA), issueTitle(issue)))
+			break
+		}
+		if err := tracker.Close(issue, "approved-by-yoke-review"); err != nil {
+			return err
+		}
+		currentStatus, err := issueStatus(issue)
+		if err != nil {
+			return err
+		}
+		if currentStatus != "closed" {
+			return fmt.Errorf("bd close did not close %s (current status: %s)", issue, currentStatus)
+		}
+		clearDaemonFocusIssue(root)
+		releaseReviewLock(root, issue)
+		if err := writeApprovalRecord(root, approvalRecord{Issue: issue, SHA: reviewedSHA, ApprovedAt: time.Now().Format(time.RFC3339)}); err != nil {
+			progress.Info("review.approve", "warning: failed to record approved commit: "+err.Error())
+		}
+		note(fmt.Sprintf("Approved %s at %s", issue, shortSHA(reviewedSHA)))
+		notifyEvent(root, cfg, "approved", issue, fmt.Sprintf("Approved %s at %s: %s", issue, shortSHA(reviewedSHA), issueTitle(issue)))
+		if cfg.AutoMerge && issueTargetsBaseBranch(root, issue) {
+			if err := enableAutoMerge(root, cfg, issue, prNumber); err != nil {
+				progress.Info("review.approve", "warning: "+err.Error()+"; merge with yoke merge "+issue)
+			}
+		}
+	case "reject":
+		if err := tracker.Comment(issue, formatRejectionComment(rejectReason, reviewedSHA)); err != nil {
+			return err
+		}
+		removeLabels := []string{reviewQueueLabel}
+		if hasLabel(labels, awaitingApprovalLabel) {
+			removeLabels = append(removeLabels, awaitingApprovalLabel)
+		}
+		if err := tracker.Update(issue, issueChange{Status: "in_progress", RemoveLabels: removeLabels}); err != nil {
+			return err
+		}
+		currentStatus, err := issueStatus(issue)
+		if err != nil {
+			return err
+		}
+		if currentStatus != "in_progress" {
+			return fmt.Errorf("bd update did not return %s to in_progress (current status: %s)", issue, currentStatus)
+		}
+		escalated, err := escalateRejectLoop(root, cfg, issue, !noPRNote)
+		if err != nil {
+			progress.Info("review.reject", "warning: failed to check the reject loop limit: "+err.Error())
+		}
+		if !escalated {
+			if err := writeDaemonFocusIssue(root, issue); err != nil {
+				progress.Info("review.reject", "warning: failed to persist daemon focus issue: "+err.Error())
+			}
+		}
+		releaseReviewLock(root, issue)
+		note(fmt.Sprintf("Rejected %s at %s", issue, shortSHA(reviewedSHA)))
+	default:
+		if err := showIssue(issue); err != nil {
+			return err
+		}
+		note("Next:")
+		note("  yoke review " + issue + " --approve")
+		note("  yoke review " + issue + " --reject \"reason\"")
+	}
+	if held {
+		return nil
+	}
+	if verdict != nil {
+		finishReviewVerdict(issue, *verdict, reviewedSHA, !noPRNote)
+	} else if !noPRNote && (action != "" || noteText != "") {
+		postReviewPRComment(issue, action, rejectReason, noteText, runAgent, reviewedSHA)
+	}
+
+	return nil
+}
+
+const (
+	defaultReviewDiffMaxBytes = 96 * 1024
+	// maxReviewDiffBytes keeps YOKE_REVIEW_DIFF under Linux's per-variable
+	// environment limit (128 KiB).
+	maxReviewDiffBytes       = 120 * 1024
+	defaultReviewDiffExclude = "go.sum,package-lock.json,yarn.lock,pnpm-lock.yaml,Cargo.lock"
+)
+
+// reviewDiff is the change under review: git diff <base>...HEAD in the
+// issue worktree, prefixed with its --stat and capped at
+// YOKE_REVIEW_DIFF_MAX_BYTES.
+type reviewDiff struct {
+	Base      string
+	Text      string
+	Truncated bool
+}
+
+func computeReviewDiff(root string, cfg config, issue, worktree string) (reviewDiff, error) {
+	base, err := issuePRBaseBranch(root, cfg, issue)
+	if err != nil {
+		return reviewDiff{}, err
+	}
+	if !refExists("refs/heads/"+base) && refExists("refs/remotes/origin/"+base) {
+		base = "origin/" + base
+	}
+	pathspec := append([]string{"--", "."}, reviewDiffExcludes(cfg.ReviewDiffExclude)...)
+	stat, err := commandOutput("git", append([]string{"-C", worktree, "diff", "--no-color", "--stat", base + "...HEAD"}, pathspec...)...)
+	if err != nil {
+		return reviewDiff{}, fmt.Errorf("git diff %s...HEAD: %w", base, err)
+	}
+	diff, err := commandOutput("git", append([]string{"-C", worktree, "diff", "--no-color", base + "...HEAD"}, pathspec...)...)
+	if err != nil {
+		return reviewDiff{}, fmt.Errorf("git diff %s...HEAD: %w", base, err)
+	}
+	text, truncated := capReviewDiff(strings.TrimRight(stat, "\n")+"\n\n"+diff, cfg.ReviewDiffMaxBytes)
+	if truncated {
+		text += fmt.Sprintf("\n[diff truncated at %d bytes; run git diff %s...HEAD for the rest]\n", cfg.ReviewDiffMaxBytes, base)
+	}
+	return reviewDiff{Base: base, Text: text, Truncated: truncated}, nil
+}
+
+// reviewDiffExcludes turns YOKE_REVIEW_DIFF_EXCLUDE globs into git exclude
+// pathspecs. A bare file name matches at any depth.
+func reviewDiffExcludes(patterns []string) []string {
+	excludes := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			pattern = "**/" + pattern
+		}
+		excludes = append(excludes, ":(exclude,glob)"+pattern)
+	}
+	return excludes
+}
+
+// capReviewDiff cuts text to at most limit bytes, at the last line break
+// that fits.
+func capReviewDiff(text string, limit int) (string, bool) {
+	if len(text) <= limit {
+		return text, false
+	}
+	cut := text[:limit]
+	if newline := strings.LastIndexByte(cut, '\n'); newline > 0 {
+		cut = cut[:newline+1]
+	}
+	return cut, true
+}
+
+// reviewDiffEnv computes the change under review for a reviewer command and
+// returns YOKE_REVIEW_BASE, YOKE_REVIEW_DIFF, and YOKE_REVIEW_DIFF_FILE. The
+// review still runs without them when the diff cannot be computed. A dry run
+// writes no diff file, so it gets only the first two.
+func reviewDiffEnv(root string, cfg config, issue, worktree string) []string {
+	if cfg.ReviewDiffMaxBytes <= 0 {
+		return nil
+	}
+	diff, err := computeReviewDiff(root, cfg, issue, worktree)
+	if err != nil {
+		note("warning: reviewer runs without the diff: " + err.Error())
+		return nil
+	}
+	if diff.Truncated {
+		note(fmt.Sprintf("Review diff for %s truncated to %d bytes", issue, cfg.ReviewDiffMaxBytes))
+	}
+	env := []string{"YOKE_REVIEW_BASE=" + diff.Base, "YOKE_REVIEW_DIFF=" + diff.Text}
+	if dryRunMode {
+		return env
+	}
+	path := filepath.Join(yokeSharedStateDir(root), "review-diffs", sanitizePathSegment(issue)+".diff")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		note("warning: reviewer runs without the diff: " + err.Error())
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(diff.Text), 0o644); err != nil {
+		note("warning: reviewer runs without the diff: " + err.Error())
+		return nil
+	}
+	return append(env, "YOKE_REVIEW_DIFF_FILE="+path)
+}
+
+// reviewVerdictSchema is exported to reviewer commands as
+// YOKE_REVIEW_VERDICT_SCHEMA so their prompt can ask for it verbatim.
+const reviewVerdictSchema = `End your reply with one JSON object on its own: {"decision":"approve|reject","summary":"one line","findings":[{"severity":"blocker|major|minor|nit","file":"path","line":0,"message":"what is wrong"}],"follow_ups":[{"title":"short title","description":"optional detail"}]}. blocker and major findings mean reject; minor and nit findings and follow_ups become follow-up tasks when approved.`
+
+// reviewVerdict is the machine-readable result a reviewer agent ends its
+// output with. yoke applies the decision itself when the agent leaves the
+// issue in review.
+type reviewVerdict struct {
+	Decision  string           `json:"decision"`
+	Summary   string           `json:"summary"`
+	Findings  []reviewFinding  `json:"findings"`
+	FollowUps []reviewFollowUp `json:"follow_ups"`
+}
+
+type reviewFinding struct {
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+type reviewFollowUp struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// blocking reports whether a finding must be fixed before approval.
+func (f reviewFinding) blocking() bool {
+	return f.Severity == "blocker" || f.Severity == "major"
+}
+
+func (f reviewFinding) location() string {
+	file := sanitizeCommentLine(f.File)
+	if file != "" && f.Line > 0 {
+		return file + ":" + strconv.Itoa(f.Line)
+	}
+	return file
+}
+
+// parseReviewVerdict finds the last JSON object in output whose decision is
+// approve or reject. Objects with any other decision, such as the schema
+// echoed back from the prompt, are skipped. ok is false when there is none.
+func parseReviewVerdict(output string) (reviewVerdict, bool, error) {
+	var verdict reviewVerdict
+	var invalid error
+	found := scanJSONObjectsBackward(output, `"decision"`, func(raw []byte) bool {
+		verdict = reviewVerdict{}
+		if json.Unmarshal(raw, &verdict) != nil {
+			return false
+		}
+		verdict.Decision = strings.ToLower(strings.TrimSpace(verdict.Decision))
+		if verdict.Decision != "approve" && verdict.Decision != "reject" {
+			return false
+		}
+		invalid = normalizeReviewVerdict(&verdict)
+		return true
+	})
+	if !found || invalid != nil {
+		return reviewVerdict{}, false, invalid
+	}
+	return verdict, true, nil
+}
+
+// scanJSONObjectsBackward walks the JSON objects in agent output that
+// contain key, last first, until accept returns true. Agents wrap the object
+// in prose or code fences, so each candidate starts at a brace before key.
+func scanJSONObjectsBackward(output, key string, accept func(raw []byte) bool) bool {
+	end := len(output)
+	for {
+		at := strings.LastIndex(output[:end], key)
+		if at < 0 {
+			return false
+		}
+		end = at
+		start := at
+		for attempt := 0; attempt < 64; attempt++ {
+			start = strings.LastIndex(output[:start], "{")
+			if start < 0 {
+				break
+			}
+			var raw json.RawMessage
+			if err := json.NewDecoder(strings.NewReader(output[start:])).Decode(&raw); err != nil {
+				continue
+			}
+			if accept(raw) {
+				return true
+			}
+		}
+	}
+}
+
+func normalizeReviewVerdict(verdict *reviewVerdict) error {
+	for i := range verdict.Findings {
+		finding := &verdict.Findings[i]
+		finding.Severity = strings.ToLower(strings.TrimSpace(finding.Severity))
+		switch finding.Severity {
+		case "blocker", "major", "minor", "nit":
+		default:
+			return fmt.Errorf("finding %d: severity must be blocker, major, minor, or nit (got %q)", i+1, finding.Severity)
+		}
+		if strings.TrimSpace(finding.Message) == "" {
+			return fmt.Errorf("finding %d: message is empty", i+1)
+		}
+		if verdict.Decision == "approve" && finding.blocking() {
+			return fmt.Errorf("finding %d: approve verdict has a %s finding; blocker and major findings mean reject", i+1, finding.Severity)
+		}
+	}
+	for i, followUp := range verdict.FollowUps {
+		if strings.TrimSpace(followUp.Title) == "" {
+			return fmt.Errorf("follow-up %d: title is empty", i+1)
+		}
+	}
+	return nil
+}
+
+// rejectReason condenses a reject verdict into the one-line reason recorded
+// on the issue: the summary followed by the blocking findings, or by every
+// finding when none is tagged blocking.
+func (v reviewVerdict) rejectReason() string {
+	parts := []string{}
+	if summary := sanitizeCommentLine(v.Summary); summary != "" {
+		parts = append(parts, summary)
+	}
+	findings := []reviewFinding{}
+	for _, finding := range v.Findings {
+		if finding.blocking() {
+			findings = append(findings, finding)
+		}
+	}
+	if len(findings) == 0 {
+		findings = v.Findings
+	}
+	for _, finding := range findings {
+		text := "[" + finding.Severity + "] "
+		if location := finding.location(); location != "" {
+			text += location + ": "
+		}
+		parts = append(parts, text+sanitizeCommentLine(finding.Message))
+	}
+	if len(parts) == 0 {
+		return "rejected by reviewer verdict"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// applyReviewVerdict records a verdict the daemon's reviewer command left
+// unapplied, acting as the reviewer role.
+func applyReviewVerdict(root, issue string, verdict reviewVerdict) error {
+	previousRole, hadRole := os.LookupEnv("YOKE_ROLE")
+	os.Setenv("YOKE_ROLE", "reviewer")
+	defer func() {
+		if hadRole {
+			os.Setenv("YOKE_ROLE", previousRole)
+		} else {
+			os.Unsetenv("YOKE_ROLE")
+		}
+	}()
+
+	args := []string{issue, "--approve", "--no-pr-comment"}
+	if verdict.Decision == "reject" {
+		args = []string{issue, "--reject", verdict.rejectReason(), "--no-pr-comment"}
+	}
+	if err := cmdReview(args); err != nil {
+		return err
+	}
+	reviewedSHA, _ := reviewedCommitSHA(root, issue)
+	finishReviewVerdict(issue, verdict, reviewedSHA, true)
+	return nil
+}
+
+// finishReviewVerdict files follow-up tasks for an approved verdict and posts
+// the formatted verdict to the issue's PR. Failures are warnings: the
+// decision itself is already recorded.
+func finishReviewVerdict(issue string, verdict reviewVerdict, reviewedSHA string, postPR bool) {
+	followUps := []string{}
+	if verdict.Decision == "approve" {
+		followUps = fileReviewFollowUps(issue, verdict, reviewedSHA)
+	}
+	if !postPR {
+		return
+	}
+	number, _, _, ok, err := openPRForIssue(issue)
+	if err != nil {
+		note("warning: skipping reviewer verdict PR comment: " + err.Error())
+		return
+	}
+	if !ok {
+		note("warning: no open PR found for issue branch; skipping reviewer verdict PR comment")
+		return
+	}
+	if err := commentOnPR(number, formatReviewVerdictPRComment(issue, verdict, reviewedSHA, followUps)); err != nil {
+		note("warning: failed to post reviewer verdict PR comment: " + err.Error())
+		return
+	}
+	note("Posted reviewer verdict to PR #" + number)
+}
+
+// fileReviewFollowUps creates a task for every non-blocking finding and
+// suggested follow-up, returning the created ids.
+func fileReviewFollowUps(issue string, verdict reviewVerdict, reviewedSHA string) []string {
+	origin := fmt.Sprintf("Filed from the reviewer verdict on %s (reviewed commit %s).", issue, valueOrFallback(shortSHA(reviewedSHA), "unknown"))
+	requests := []newIssue{}
+	for _, finding := range verdict.Findings {
+		if finding.blocking() {
+			continue
+		}
+		detail := sanitizeCommentLine(finding.Message)
+		if location := finding.location(); location != "" {
+			detail = location + ": " + detail
+		}
+		requests = append(requests, newIssue{
+			Title:       truncateTitle("Review " + finding.Severity + ": " + sanitizeCommentLine(finding.Message)),
+			Type:        "task",
+			Description: origin + "\n\n" + detail,
+		})
+	}
+	for _, followUp := range verdict.FollowUps {
+		requests = append(requests, newIssue{
+			Title:       truncateTitle(sanitizeCommentLine(followUp.Title)),
+			Type:        "task",
+			Description: strings.TrimSpace(origin + "\n\n" + strings.TrimSpace(followUp.Description)),
+		})
+	}
+
+	created := []string{}
+	for _, request := range requests {
+		filed, err := tracker.Create(request)
+		if err != nil {
+			progress.Info("review.approve", "warning: failed to file review follow-up: "+err.Error())
+			continue
+		}
+		created = append(created, filed.ID)
+	}
+	if len(created) > 0 {
+		progress.Info("review.approve", "Filed review follow-ups: "+strings.Join(created, ", "))
+		if err := tracker.Comment(issue, "Review follow-ups filed: "+strings.Join(created, ", ")); err != nil {
+			progress.Info("review.approve", "warning: failed to record review follow-ups: "+err.Error())
+		}
+	}
+	return created
+}
+
+// truncateTitle caps a title at 120 characters, cutting on a rune boundary
+// so multi-byte text stays valid UTF-8.
+func truncateTitle(title string) string {
+	if runes := []rune(title); len(runes) > 120 {
+		return string(runes[:117]) + "..."
+	}
+	return title
+}
+
+func formatReviewVerdictPRComment(issue string, verdict reviewVerdict, reviewedSHA string, followUps []string) string {
+	lines := []string{
+		"## Reviewer Verdict",
+		"",
+		"- Issue: `" + sanitizeCommentLine(issue) + "`",
+		"- Decision: " + verdict.Decision,
+	}
+	if strings.TrimSpace(reviewedSHA) != "" {
+		lines = append(lines, "- Reviewed commit: `"+sanitizeCommentLine(reviewedSHA)+"`")
+	}
+	if summary := sanitizeCommentLine(verdict.Summary); summary != "" {
+		lines = append(lines, "- Summary: "+summary)
+	}
+	if len(verdict.Findings) > 0 {
+		lines = append(lines, "", "### Findings", "")
+		for _, finding := range verdict.Findings {
+			line := "- **" + finding.Severity + "**"
+			if location := finding.location(); location != "" {
+				line += " `" + location + "`"
+			}
+			lines = append(lines, line+" "+sanitizeCommentLine(finding.Message))
+		}
+	}
+	if len(verdict.FollowUps) > 0 {
+		lines = append(lines, "", "### Suggested follow-ups", "")
+		for _, followUp := range verdict.FollowUps {
+			lines = append(lines, "- "+sanitizeCommentLine(followUp.Title))
+		}
+	}
+	if len(followUps) > 0 {
+		lines = append(lines, "", "Filed follow-up tasks: "+strings.Join(followUps, ", "))
+	}
+	lines = append(lines, "", "_Posted automatically by `yoke review`._")
+	return strings.Join(lines, "\n")
+}
+
+// securityReport is the JSON a security-review agent ends its output with.
+type securityReport struct {
+	Summary  string            `json:"summary"`
+	Findings []securityFinding `json:"security_findings"`
+}
+
+type securityFinding struct {
+	Severity string `json:"severity"`
+	Category string `json:"category"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+var securitySeverities = []string{"critical", "high", "medium", "low"}
+
+func securitySeverity(value string) bool {
+	for _, severity := range securitySeverities {
+		if value == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// blocking reports whether a finding stops approval.
+func (f securityFinding) blocking() bool {
+	return f.Severity == "critical" || f.Severity == "high"
+}
+
+func (r securityReport) blockingFindings() []securityFinding {
+	blocking := []securityFinding{}
+	for _, finding := range r.Findings {
+		if finding.blocking() {
+			blocking = append(blocking, finding)
+		}
+	}
+	return blocking
+}
+
+// parseSecurityReport finds the last security_findings object in output.
+// Objects with an unknown severity or category, such as the example echoed
+// from the prompt, are skipped.
+func parseSecurityReport(output string) (securityReport, bool) {
+	var report securityReport
+	found := scanJSONObjectsBackward(output, `"security_findings"`, func(raw []byte) bool {
+		// The key must be present as a list; a nested object that only
+		// mentions it in a string does not count.
+		var probe struct {
+			Findings json.RawMessage `json:"security_findings"`
+		}
+		if json.Unmarshal(raw, &probe) != nil || !strings.HasPrefix(string(probe.Findings), "[") {
+			return false
+		}
+		report = securityReport{}
+		if json.Unmarshal(raw, &report) != nil {
+			return false
+		}
+		for i := range report.Findings {
+			finding := &report.Findings[i]
+			finding.Severity = strings.ToLower(strings.TrimSpace(finding.Severity))
+			finding.Category = strings.ToLower(strings.TrimSpace(finding.Category))
+			if !securitySeverity(finding.Severity) || strings.TrimSpace(finding.Message) == "" {
+				return false
+			}
+			switch finding.Category {
+			case "injection", "secrets", "authz", "dependency", "other":
+			case "":
+				finding.Category = "other"
+			default:
+				return false
+			}
+		}
+		return true
+	})
+	return report, found
+}
+
+// securityReviewPrompt renders the security review template (see
+// loadPromptTemplate)
+// for an issue and its diff; vars adds the common prompt variables.
+func securityReviewPrompt(template, issue string, diff reviewDiff, vars map[string]string) string {
+	change := diff.Text
+	if strings.TrimSpace(change) == "" {
+		change = "(no diff available; run git diff " + valueOrFallback(diff.Base, "<base>") + "...HEAD yourself)"
+	}
+	all := map[string]string{}
+	for name, value := range vars {
+		all[name] = value
+	}
+	all["ISSUE_ID"] = issue
+	all["REVIEW_BASE"] = valueOrFallback(diff.Base, "the base branch")
+	all["REVIEW_DIFF"] = "```diff\n" + strings.TrimRight(change, "\n") + "\n```"
+	return renderPromptTemplate(template, all)
+}
+
+// runSecurityReview runs the reviewer agent with the security prompt in the
+// issue worktree and records the result: a bd comment, the
+// yoke:security-blocked label while critical or high findings stand, and a
+// Security Review section on the PR. It returns an error only when the pass
+// could not produce a report.
+func runSecurityReview(root string, cfg config, issue, worktree string, postPR bool) (securityReport, error) {
+	agentID, err := agentIDForRole(cfg, "reviewer")
+	if err != nil {
+		return securityReport{}, err
+	}
+	template, err := loadPromptTemplate(root, promptOverride{File: "security-review.md", Builtin: securityReviewPromptTemplate, Required: []string{"$REVIEW_DIFF"}, Vars: []string{"REVIEW_BASE", "REVIEW_DIFF"}})
+	if err != nil {
+		return securityReport{}, err
+	}
+	diff, err := computeReviewDiff(root, cfg, issue, worktree)
+	if err != nil {
+		note("warning: security review runs without the diff: " + err.Error())
+	}
+
+	started := time.Now()
+	output, runErr := runAgentPrompt(agentID, worktree, securityReviewPrompt(template, issue, diff, promptVariables(root, cfg, issue, template)), []string{
+		"ISSUE_ID=" + issue,
+		"ROOT_DIR=" + worktree,
+		"BD_PREFIX=" + cfg.BDPrefix,
+		"YOKE_ROLE=reviewer",
+		"YOKE_SECURITY_REVIEW=1",
+	}, "[security] ")
+	recordSessionLog(root, sessionLogEntry{IssueID: issue, Role: "reviewer", Source: "security-review", AgentID: agentID}, started, output, runErr)
+	epicID, _ := epicAncestorID(issue)
+	recordAgentUsage(root, usageRecord{IssueID: issue, EpicID: epicID, Role: "reviewer", AgentID: agentID}, output)
+	if runErr != nil {
+		return securityReport{}, fmt.Errorf("security review agent failed: %w", runErr)
+	}
+	report, ok := parseSecurityReport(output)
+	if !ok {
+		return securityReport{}, errors.New("security review agent did not end with a security_findings report")
+	}
+
+	reviewedSHA, _ := reviewedCommitSHA(root, issue)
+	blocking := len(report.blockingFindings())
+	change := issueChange{RemoveLabels: []string{securityBlockedLabel}}
+	if blocking > 0 {
+		change = issueChange{AddLabels: []string{securityBlockedLabel}}
+	}
+	if err := tracker.Update(issue, change); err != nil {
+		return report, err
+	}
+	if err := tracker.Comment(issue, formatSecurityReviewIssueComment(report, reviewedSHA)); err != nil {
+		return report, err
+	}
+	if postPR {
+		if number, _, _, ok, err := openPRForIssue(issue); err != nil {
+			note("warning: skipping security review PR comment: " + err.Error())
+		} else if ok {
+			if err := commentOnPR(number, formatSecurityReviewPRComment(issue, report, reviewedSHA)); err != nil {
+				note("warning: failed to post security review PR comment: " + err.Error())
+			}
+		} else {
+			note("warning: no open PR found for issue branch; skipping security review PR comment")
+		}
+	}
+	return report, nil
+}
+
+func formatSecurityReviewIssueComment(report securityReport, reviewedSHA string) string {
+	blocking := report.blockingFindings()
+	lines := []string{fmt.Sprintf("Security review: %d finding(s), %d blocking (reviewed commit %s)", len(report.Findings), len(blocking), valueOrFallback(sanitizeCommentLine(reviewedSHA), "unknown"))}
+	for _, finding := range blocking {
+		lines = append(lines, "- "+formatSecurityFinding(finding))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatSecurityFinding(finding securityFinding) string {
+	text := "[" + finding.Severity + "/" + finding.Category + "] "
+	if location := (reviewFinding{File: finding.File, Line: finding.Line}).location(); location != "" {
+		text += location + ": "
+	}
+	return text + sanitizeCommentLine(finding.Message)
+}
+
+func formatSecurityReviewPRComment(issue string, report securityReport, reviewedSHA string) string {
+	outcome := "pass"
+	if len(report.blockingFindings()) > 0 {
+		outcome = "blocked (critical/high findings must be fixed before approval)"
+	}
+	lines := []string{
+		"## Security Review",
+		"",
+		"- Issue: `" + sanitizeCommentLine(issue) + "`",
+		"- Outcome: " + outcome,
+	}
+	if strings.TrimSpace(reviewedSHA) != "" {
+		lines = append(lines, "- Reviewed commit: `"+sanitizeCommentLine(reviewedSHA)+"`")
+	}
+	if summary := sanitizeCommentLine(report.Summary); summary != "" {
+		lines = append(lines, "- Summary: "+summary)
+	}
+	for _, severity := range securitySeverities {
+		section := []string{}
+		for _, finding := range report.Findings {
+			if finding.Severity != severity {
+				continue
+			}
+			line := "- **" + finding.Category + "**"
+			if location := (reviewFinding{File: finding.File, Line: finding.Line}).location(); location != "" {
+				line += " `" + location + "`"
+			}
+			section = append(section, line+" "+sanitizeCommentLine(finding.Message))
+		}
+		if len(section) > 0 {
+			lines = append(lines, "", "### "+strings.ToUpper(severity[:1])+severity[1:], "")
+			lines = append(lines, section...)
+		}
+	}
+	if len(report.Findings) == 0 {
+		lines = append(lines, "", "No security findings.")
+	}
+	lines = append(lines, "", "_Posted automatically by `yoke review --security`._")
+	return strings.Join(lines, "\n")
+}
+
+// approvalRule is one YOKE_APPROVAL_POLICY rule. Limit is used by max-lines
+// and Patterns by paths.
+type approvalRule struct {
+	Name     string
+	Limit    int
+	Patterns []string
+}
+
+// approvalRuleResult is one evaluated rule; Detail explains a failure.
+type approvalRuleResult struct {
+	Rule   string
+	Passed bool
+	Detail string
+}
+
+func parseApprovalPolicy(raw string) ([]approvalRule, error) {
+	rules := []approvalRule{}
+	for _, item := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		name, value, hasValue := strings.Cut(item, "=")
+		name = strings.ToLower(name)
+		switch name {
+		case "checks", "no-todos":
+			if hasValue {
+				return nil, fmt.Errorf("%s takes no value", name)
+			}
+			rules = append(rules, approvalRule{Name: name})
+		case "max-lines":
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit <= 0 {
+				return nil, fmt.Errorf("max-lines needs a positive line count, got %q", value)
+			}
+			rules = append(rules, approvalRule{Name: name, Limit: limit})
+		case "paths":
+			patterns := strings.FieldsFunc(value, func(r rune) bool { return r == '|' })
+			if len(patterns) == 0 {
+				return nil, errors.New("paths needs at least one glob, for example paths=docs/**|*.md")
+			}
+			rules = append(rules, approvalRule{Name: name, Patterns: patterns})
+		default:
+			return nil, fmt.Errorf("unknown rule %q (want checks, max-lines=N, paths=GLOB|GLOB, or no-todos)", item)
+		}
+	}
+	return rules, nil
+}
+
+func formatApprovalRule(rule approvalRule) string {
+	switch rule.Name {
+	case "max-lines":
+		return fmt.Sprintf("max-lines=%d", rule.Limit)
+	case "paths":
+		return "paths=" + strings.Join(rule.Patterns, "|")
+	default:
+		return rule.Name
+	}
+}
+
+func formatApprovalPolicy(rules []approvalRule) string {
+	names := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		names = append(names, formatApprovalRule(rule))
+	}
+	return strings.Join(names, ",")
+}
+
+// approvalChange is what the policy rules inspect: the files and line counts
+// of git diff <base>...HEAD and the lines it adds.
+type approvalChange struct {
+	Files      []string
+	Lines      int
+	AddedLines []string
+}
+
+func collectApprovalChange(root string, cfg config, issue, worktree string) (approvalChange, error) {
+	base, err := issuePRBaseBranch(root, cfg, issue)
+	if err != nil {
+		return approvalChange{}, err
+	}
+	if !refExists("refs/heads/"+base) && refExists("refs/remotes/origin/"+base) {
+		base = "origin/" + base
+	}
+	numstat, err := commandOutput("git", "-C", worktree, "diff", "--numstat", base+"...HEAD")
+	if err != nil {
+		return approvalChange{}, fmt.Errorf("git diff --numstat %s...HEAD: %w", base, err)
+	}
+	patch, err := commandOutput("git", "-C", worktree, "diff", "--no-color", "-U0", base+"...HEAD")
+	if err != nil {
+		return approvalChange{}, fmt.Errorf("git diff %s...HEAD: %w", base, err)
+	}
+	return parseApprovalChange(numstat, patch), nil
+}
+
+func parseApprovalChange(numstat, patch string) approvalChange {
+	change := approvalChange{Files: []string{}, AddedLines: []string{}}
+	for _, line := range strings.Split(numstat, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		change.Lines += added + deleted
+		change.Files = append(change.Files, fields[2])
+	}
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+			change.AddedLines = append(change.AddedLines, line[1:])
+		}
+	}
+	return change
+}
+
+var todoMarkerPattern = regexp.MustCompile(`\b(TODO|FIXME|XXX)\b`)
+
+// evaluateApprovalPolicy checks every rule, so a held approval lists all
+// failures at once. checks runs YOKE_CHECK_CMD in the worktree.
+func evaluateApprovalPolicy(rules []approvalRule, change approvalChange, runChecksFn func() error) []approvalRuleResult {
+	results := make([]approvalRuleResult, 0, len(rules))
+	for _, rule := range rules {
+		result := approvalRuleResult{Rule: formatApprovalRule(rule), Passed: true}
+		switch rule.Name {
+		case "checks":
+			if err := runChecksFn(); err != nil {
+				result.Passed, result.Detail = false, "checks failed: "+err.Error()
+			}
+		case "max-lines":
+			if change.Lines > rule.Limit {
+				result.Passed, result.Detail = false, fmt.Sprintf("%d lines changed", change.Lines)
+			}
+		case "paths":
+			outside := []string{}
+			for _, file := range change.Files {
+				if !matchesAnyPathGlob(rule.Patterns, file) {
+					outside = append(outside, file)
+				}
+			}
+			if len(outside) > 0 {
+				result.Passed, result.Detail = false, "touches "+strings.Join(limitStrings(outside, 5), ", ")
+			}
+		case "no-todos":
+			count := 0
+			for _, line := range change.AddedLines {
+				if todoMarkerPattern.MatchString(line) {
+					count++
+				}
+			}
+			if count > 0 {
+				result.Passed, result.Detail = false, fmt.Sprintf("adds %d TODO/FIXME/XXX line(s)", count)
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func limitStrings(values []string, max int) []string {
+	if len(values) <= max {
+		return values
+	}
+	return append(append([]string{}, values[:max]...), fmt.Sprintf("and %d more", len(values)-max))
+}
+
+// matchesAnyPathGlob reports whether path matches one of the globs. * and ?
+// stay within a path segment, ** spans segments, and a glob without a slash
+// matches the file name at any depth.
+func matchesAnyPathGlob(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			pattern = "**/" + pattern
+		}
+		if pathGlobPattern(pattern).MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func pathGlobPattern(glob string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			expr.WriteString("[^/]*")
+		case glob[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+// approvalPolicyFailures returns the failed rules as "rule (detail)".
+func approvalPolicyFailures(results []approvalRuleResult) []string {
+	failures := []string{}
+	for _, result := range results {
+		if !result.Passed {
+			failures = append(failures, result.Rule+" ("+result.Detail+")")
+		}
+	}
+	return failures
+}
+
+const rejectLoopCommentPrefix = "Reject loop: "
+
+// rejectionsSinceEscalation counts reviewer rejections after the last
+// reject-loop escalation, so clearing yoke:needs-human starts a fresh count.
+func rejectionsSinceEscalation(comments []bdComment) int {
+	count := 0
+	for _, comment := range comments {
+		switch {
+		case strings.HasPrefix(comment.Text, rejectLoopCommentPrefix):
+			count = 0
+		case strings.HasPrefix(comment.Text, rejectionCommentPrefix):
+			count++
+		}
+	}
+	return count
+}
+
+// escalateRejectLoop stops automation on an issue after YOKE_MAX_REJECTIONS
+// writer/reviewer round trips: it labels the issue yoke:needs-human, comments
+// on the issue and PR, and drops the daemon focus. Automation resumes when
+// a person removes the label.
+func escalateRejectLoop(root string, cfg config, issue string, postPR bool) (bool, error) {
+	if cfg.MaxRejections <= 0 {
+		return false, nil
+	}
+	comments, err := tracker.Comments(issue)
+	if err != nil {
+		return false, err
+	}
+	count := rejectionsSinceEscalation(comments)
+	if count < cfg.MaxRejections {
+		return false, nil
+	}
+	if err := tracker.Update(issue, issueChange{AddLabels: []string{needsHumanLabel}}); err != nil {
+		return false, err
+	}
+	comment := formatRejectLoopComment(count)
+	if err := tracker.Comment(issue, comment); err != nil {
+		return false, err
+	}
+	clearDaemonFocusIssue(root)
+	if postPR {
+		if number, _, _, ok, err := openPRForIssue(issue); err != nil {
+			note("warning: skipping reject loop PR comment: " + err.Error())
+		} else if ok {
+			if err := commentOnPR(number, "## Needs a Human\n\n"+comment+"\n\n_Posted automatically by `yoke review`._"); err != nil {
+				note("warning: failed to post reject loop PR comment: " + err.Error())
+			}
+		}
+	}
+	note(fmt.Sprintf("warning: %s rejected %d times; automation stopped (label %s)", issue, count, needsHumanLabel))
+	return true, nil
+}
+
+func formatRejectLoopComment(count int) string {
+	return fmt.Sprintf("%sreviewer rejected this issue %d times; writer and reviewer agents are not converging. Automation stopped (label %s): read the rejections, clarify the issue or fix it by hand, then remove the label to hand it back.", rejectLoopCommentPrefix, count, needsHumanLabel)
+}
+
+// requireHumanMatch returns the YOKE_REQUIRE_HUMAN_FOR entry matching one of
+// the issue's labels or its type, or "" when automation may approve it.
+func requireHumanMatch(cfg config, labels []string, issueType string) string {
+	for _, entry := range cfg.RequireHumanFor {
+		if hasLabel(labels, entry) || strings.EqualFold(strings.TrimSpace(issueType), entry) {
+			return entry
+		}
+	}
+	return ""
+}
+
+// holdForHumanApproval leaves an agent-approved issue in review, labeled
+// yoke:awaiting-approval so automation stops picking it, until a person runs
+// yoke review --approve or --reject. Because the issue is never closed, no
+// auto-merge or merge queue entry is made either.
+func holdForHumanApproval(root, issue, reason string, failures []string, postPR bool) error {
+	if err := tracker.Update(issue, issueChange{AddLabels: []string{awaitingApprovalLabel}}); err != nil {
+		return err
+	}
+	comment := formatApprovalHoldComment(issue, reason, failures)
+	if err := tracker.Comment(issue, comment); err != nil {
+		return err
+	}
+	clearDaemonFocusIssue(root)
+	releaseReviewLock(root, issue)
+	if postPR {
+		if number, _, _, ok, err := openPRForIssue(issue); err != nil {
+			note("warning: skipping approval hold PR comment: " + err.Error())
+		} else if ok {
+			if err := commentOnPR(number, "## Auto-approval Held\n\n"+comment+"\n\n_Posted automatically by `yoke review`._"); err != nil {
+				note("warning: failed to post approval hold PR comment: " + err.Error())
+			}
+		}
+	}
+	note(fmt.Sprintf("Held %s for human approval: %s", issue, reason))
+	return nil
+}
+
+func formatApprovalHoldComment(issue, reason string, failures []string) string {
+	lines := []string{"Reviewer agent approved, but " + reason + ":"}
+	for _, failure := range failures {
+		lines = append(lines, "- "+sanitizeCommentLine(failure))
+	}
+	lines = append(lines, "", "A person must finish the review: yoke review "+issue+" --approve (or --reject \"reason\").")
+	return strings.Join(lines, "\n")
+}
+
+// checkApprovalPolicy evaluates YOKE_APPROVAL_POLICY against the issue
+// branch and returns the rules that failed.
+func checkApprovalPolicy(root string, cfg config, issue string) ([]string, error) {
+	worktree := root
+	if path := worktreePathForBranch(root, branchForIssue(issue)); path != "" {
+		worktree = path
+	}
+	change, err := collectApprovalChange(root, cfg, issue, worktree)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate approval policy: %w", err)
+	}
+	results := evaluateApprovalPolicy(cfg.ApprovalPolicy, change, func() error {
+		_, err := runCachedCheckPlan(worktree, issueCheckPlan(root, worktree, cfg, issue, ""), true)
+		return err
+	})
+	return approvalPolicyFailures(results), nil
+}
+
+// cmdImprove runs the epic improvement cycle on its own, without claiming a
+// child task, so an epic can be refined before implementation starts.
+func cmdImprove(args []string) error {
+	parsed, err := parseCommandArgs("improve", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printImproveUsage()
+		return nil
+	}
+	passLimit, force := allEpicPasses, false
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--passes":
+			limit, convErr := strconv.Atoi(flag.Value)
+			if convErr != nil || limit < 1 {
+				return errors.New("--passes must be a positive integer")
+			}
+			passLimit = limit
+		case "--force":
+			force = true
+		}
+	}
+	if len(parsed.Positionals) != 1 {
+		return errors.New("usage: yoke improve <epic-id> [--passes N] [--force]")
+	}
+	epicID := parsed.Positionals[0]
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+	epic, err := issueDetails(epicID)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(strings.TrimSpace(epic.IssueType), "epic") {
+		return fmt.Errorf("%s is a %s, not an epic", epicID, valueOrFallback(epic.IssueType, "issue"))
+	}
+	if workflowStatusForIssue(epic) == "closed" {
+		return fmt.Errorf("epic %s is closed", epicID)
+	}
+	if force && hasLabel(epic.Labels, epicImprovementCompleteLabel) {
+		if err := tracker.Update(epicID, issueChange{RemoveLabels: []string{epicImprovementCompleteLabel}}); err != nil {
+			return err
+		}
+		labels := []string{}
+		for _, label := range epic.Labels {
+			if !strings.EqualFold(strings.TrimSpace(label), epicImprovementCompleteLabel) {
+				labels = append(labels, label)
+			}
+		}
+		epic.Labels = labels
+	}
+	if err := backupBDDatabase(root, cfg, "epic-improvement-"+epicID); err != nil {
+		return err
+	}
+	return runEpicImprovementCycle(root, cfg, epic, passLimit, force)
+}
+
+func cmdEpic(args []string) error {
+	if len(args) == 0 {
+		printEpicUsage()
+		return errors.New("usage: yoke epic <archive|retro> <epic-id> [options]")
+	}
+
+	switch args[0] {
+	case "archive":
+		return cmdEpicArchive(args[1:])
+	case "retro":
+		return cmdEpicRetro(args[1:])
+	case "-h", "--help":
+		printEpicUsage()
+		return nil
+	default:
+		return fmt.Errorf("unknown epic subcommand: %s", args[0])
+	}
+}
+
+func cmdEpicArchive(args []string) error {
+	var (
+		epicID    string
+		outputDir string
+	)
+
+	parsed, err := parseCommandArgs("epic", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printEpicUsage()
+		return nil
+	}
+	for _, flag := range parsed.Flags {
+		outputDir = flag.Value
+	}
+	if len(parsed.Positionals) > 1 {
+		return errors.New("usage: yoke epic archive <epic-id> [--output DIR]")
+	}
+	if len(parsed.Positionals) == 1 {
+		epicID = parsed.Positionals[0]
+	}
+	if epicID == "" {
+		return errors.New("usage: yoke epic archive <epic-id> [--output DIR]")
+	}
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+
+	dir, err := archiveEpic(root, cfg, epicID, outputDir)
+	if err != nil {
+		return err
+	}
+	note(fmt.Sprintf("Archived epic %s to %s", epicID, dir))
+	return nil
+}
+
+type epicArchive struct {
+	Epic       bdListIssue         `json:"epic"`
+	ArchivedAt string              `json:"archived_at"`
+	EpicPRURL  string              `json:"epic_pr_url,omitempty"`
+	EpicNotes  []bdComment         `json:"epic_comments"`
+	Issues     []epicArchiveIssue  `json:"issues"`
+	Reports    []epicArchiveReport `json:"reports"`
+}
+
+type epicArchiveIssue struct {
+	Issue    bdListIssue `json:"issue"`
+	Comments []bdComment `json:"comments"`
+	PRURL    string      `json:"pr_url,omitempty"`
+}
+
+type epicArchiveReport struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+func epicArchivePath(root, epicID string) string {
+	return filepath.Join(root, "docs", "epics", sanitizePathSegment(epicID))
+}
+
+func archiveEpic(root string, cfg config, epicID, outputDir string) (string, error) {
+	epic, err := issueDetails(epicID)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(strings.TrimSpace(epic.IssueType), "epic") {
+		return "", fmt.Errorf("%s is not an epic (type: %s)", epicID, valueOrUnset(epic.IssueType))
+	}
+
+	epicComments, err := listIssueComments(epic.ID)
+	if err != nil {
+		return "", fmt.Errorf("load comments for %s: %w", epic.ID, err)
+	}
+	descendants, err := collectDescendantIssues(cfg, epic.ID)
+	if err != nil {
+		return "", err
+	}
+
+	archive := epicArchive{
+		Epic:       epic,
+		ArchivedAt: time.Now().Format(time.RFC3339),
+		EpicPRURL:  prURLForBranch(branchForIssue(epic.ID)),
+		EpicNotes:  epicComments,
+		Issues:     make([]epicArchiveIssue, 0, len(descendants)),
+	}
+	for _, issue := range descendants {
+		comments, err := listIssueComments(issue.ID)
+		if err != nil {
+			return "", fmt.Errorf("load comments for %s: %w", issue.ID, err)
+		}
+		archive.Issues = append(archive.Issues, epicArchiveIssue{
+			Issue:    issue,
+			Comments: comments,
+			PRURL:    prURLForBranch(branchForIssue(issue.ID)),
+		})
+	}
+
+	reports, err := loadEpicImprovementReports(root, epic.ID)
+	if err != nil {
+		return "", err
+	}
+	archive.Reports = reports
+
+	dir := strings.TrimSpace(outputDir)
+	if dir == "" {
+		dir = epicArchivePath(root, epic.ID)
+	} else {
+		dir = resolveRepoPath(root, dir)
+	}
+	if err := writeEpicArchive(dir, archive); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func loadEpicImprovementReports(root, epicID string) ([]epicArchiveReport, error) {
+	reportsDir := filepath.Join(root, ".yoke", "epic-improvement-reports", sanitizePathSegment(epicID))
+	entries, err := os.ReadDir(reportsDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	reports := make([]epicArchiveReport, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(reportsDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, epicArchiveReport{
+			Name:    entry.Name(),
+			Content: string(data),
+		})
+	}
+	return reports, nil
+}
+
+func writeEpicArchive(dir string, archive epicArchive) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "archive.json"), append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "archive.md"), []byte(formatEpicArchiveMarkdown(archive)), 0o644)
+}
+
+func formatEpicArchiveMarkdown(archive epicArchive) string {
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("# Epic Archive: %s\n\n", sanitizeCommentLine(archive.Epic.Title)))
+	body.WriteString(fmt.Sprintf("- Epic: `%s`\n", archive.Epic.ID))
+	body.WriteString(fmt.Sprintf("- Status: %s\n", valueOrUnset(workflowStatusForIssue(archive.Epic))))
+	body.WriteString(fmt.Sprintf("- Archived: `%s`\n", archive.ArchivedAt))
+	if strings.TrimSpace(archive.EpicPRURL) != "" {
+		body.WriteString(fmt.Sprintf("- PR: %s\n", archive.EpicPRURL))
+	}
+	writeEpicArchiveComments(&body, archive.EpicNotes)
+
+	body.WriteString("\n## Issues\n")
+	if len(archive.Issues) == 0 {
+		body.WriteString("\nNo descendant issues.\n")
+	}
+	for _, item := range archive.Issues {
+		body.WriteString(fmt.Sprintf("\n### %s: %s\n\n", item.Issue.ID, sanitizeCommentLine(item.Issue.Title)))
+		body.WriteString(fmt.Sprintf("- Type: %s\n", valueOrUnset(item.Issue.IssueType)))
+		body.WriteString(fmt.Sprintf("- Status: %s\n", valueOrUnset(workflowStatusForIssue(item.Issue))))
+		if strings.TrimSpace(item.Issue.Parent) != "" {
+			body.WriteString(fmt.Sprintf("- Parent: `%s`\n", item.Issue.Parent))
+		}
+		if strings.TrimSpace(item.PRURL) != "" {
+			body.WriteString(fmt.Sprintf("- PR: %s\n", item.PRURL))
+		}
+		writeEpicArchiveComments(&body, item.Comments)
+	}
+
+	if len(archive.Reports) > 0 {
+		body.WriteString("\n## Improvement Reports\n")
+		for _, report := range archive.Reports {
+			body.WriteString(fmt.Sprintf("\n<details>\n<summary>%s</summary>\n\n", report.Name))
+			body.WriteString(strings.TrimSpace(report.Content))
+			body.WriteString("\n\n</details>\n")
+		}
+	}
+	return body.String()
+}
+
+func writeEpicArchiveComments(body *strings.Builder, comments []bdComment) {
+	if len(comments) == 0 {
+		return
+	}
+	body.WriteString("\nComments:\n\n")
+	for _, comment := range comments {
+		author := valueOrFallback(strings.TrimSpace(comment.Author), "unknown")
+		timestamp := valueOrFallback(strings.TrimSpace(comment.CreatedAt), "unknown-time")
+		body.WriteString(fmt.Sprintf("- **%s** @ %s\n", author, timestamp))
+		for _, line := range strings.Split(strings.TrimSpace(comment.Text), "\n") {
+			body.WriteString("  > " + line + "\n")
+		}
+	}
+}
+
+func cmdEpicRetro(args []string) error {
+	parsed, err := parseCommandArgs("epic", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printEpicUsage()
+		return nil
+	}
+	if len(parsed.Positionals) != 1 {
+		return errors.New("usage: yoke epic retro <epic-id>")
+	}
+	epicID := parsed.Positionals[0]
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+
+	path, err := runEpicRetrospective(root, cfg, epicID)
+	if err != nil {
+		return err
+	}
+	note(fmt.Sprintf("Posted retrospective to %s; report saved to %s", epicID, path))
+	return nil
+}
+
+// epicRetroIssue is what the retrospective prompt sees of one descendant.
+type epicRetroIssue struct {
+	ID         string
+	Title      string
+	Type       string
+	Status     string
+	CycleTime  time.Duration
+	Rejections []string
+}
+
+const rejectionCommentPrefix = "Reviewer rejection: "
+
+// collectEpicRetroIssues loads every descendant with its rejection history
+// and created-to-closed cycle time.
+func collectEpicRetroIssues(cfg config, epicID string) ([]epicRetroIssue, error) {
+	descendants, err := collectDescendantIssues(cfg, epicID)
+	if err != nil {
+		return nil, err
+	}
+	issues := make([]epicRetroIssue, 0, len(descendants))
+	for _, issue := range descendants {
+		comments, err := listIssueComments(issue.ID)
+		if err != nil {
+			return nil, fmt.Errorf("load comments for %s: %w", issue.ID, err)
+		}
+		item := epicRetroIssue{
+			ID:     issue.ID,
+			Title:  issue.Title,
+			Type:   issue.IssueType,
+			Status: workflowStatusForIssue(issue),
+		}
+		created, createdErr := time.Parse(time.RFC3339, strings.TrimSpace(issue.CreatedAt))
+		closed, closedErr := time.Parse(time.RFC3339, strings.TrimSpace(issue.ClosedAt))
+		if createdErr == nil && closedErr == nil && closed.After(created) {
+			item.CycleTime = closed.Sub(created)
+		}
+		for _, comment := range comments {
+			if reason, ok := strings.CutPrefix(strings.TrimSpace(comment.Text), rejectionCommentPrefix); ok {
+				item.Rejections = append(item.Rejections, reason)
+			}
+		}
+		issues = append(issues, item)
+	}
+	return issues, nil
+}
+
+func buildEpicRetroPrompt(epic bdListIssue, issues []epicRetroIssue) string {
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("Epic: %s\n", epic.ID))
+	body.WriteString(fmt.Sprintf("Title: %s\n\n", strings.TrimSpace(epic.Title)))
+	body.WriteString("This epic is closed. Write a short retrospective of how the work went, based on the child issues below.\n")
+	body.WriteString("Use sections:\n")
+	body.WriteString("1) What went well\n")
+	body.WriteString("2) What to improve\n")
+	body.WriteString("3) Suggested convention changes (concrete edits to AGENTS.md, yoke prompts, or .yoke/config.sh)\n\n")
+	body.WriteString("Ground every point in the data: cite issue ids, repeated rejection reasons, and outlier cycle times.\n\n")
+
+	rejected := 0
+	for _, issue := range issues {
+		if len(issue.Rejections) > 0 {
+			rejected++
+		}
+	}
+	body.WriteString(fmt.Sprintf("## Child issues (%d total, %d rejected at least once)\n", len(issues), rejected))
+	for _, issue := range issues {
+		cycle := "unknown"
+		if issue.CycleTime > 0 {
+			cycle = formatAgeSeconds(int64(issue.CycleTime.Seconds()))
+		}
+		body.WriteString(fmt.Sprintf("\n- %s [%s, %s] %s\n", issue.ID, valueOrUnset(issue.Type), valueOrUnset(issue.Status), sanitizeCommentLine(issue.Title)))
+		body.WriteString(fmt.Sprintf("  - Cycle time: %s\n", cycle))
+		for _, reason := range issue.Rejections {
+			body.WriteString("  - Rejected: " + truncateForPrompt(sanitizeCommentLine(reason), maxClarificationCommentChars) + "\n")
+		}
+	}
+	return body.String()
+}
+
+func formatEpicRetroComment(epic bdListIssue, report string) string {
+	lines := []string{
+		"## Epic Retrospective",
+		"",
+		"- Epic: `" + sanitizeCommentLine(epic.ID) + "`",
+		"",
+		truncateForPrompt(report, maxSummaryCommentChars),
+	}
+	return strings.Join(lines, "\n")
+}
+
+// runEpicRetrospective has the reviewer agent write a retrospective for a
+// closed epic, posts it to the epic, and saves it beside the improvement
+// reports so yoke epic archive picks it up.
+func runEpicRetrospective(root string, cfg config, epicID string) (string, error) {
+	epic, err := issueDetails(epicID)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(strings.TrimSpace(epic.IssueType), "epic") {
+		return "", fmt.Errorf("%s is not an epic (type: %s)", epicID, valueOrUnset(epic.IssueType))
+	}
+	issues, err := collectEpicRetroIssues(cfg, epic.ID)
+	if err != nil {
+		return "", err
+	}
+	agentID, err := agentIDForRole(cfg, "reviewer")
+	if err != nil {
+		return "", err
+	}
+
+	started := time.Now()
+	output, runErr := runAgentPrompt(agentID, root, buildEpicRetroPrompt(epic, issues), []string{
+		"ISSUE_ID=" + epic.ID,
+		"ROOT_DIR=" + root,
+		"BD_PREFIX=" + cfg.BDPrefix,
+		"YOKE_ROLE=reviewer",
+		"YOKE_EPIC_RETRO=1",
+	}, "[retro] ")
+	recordSessionLog(root, sessionLogEntry{IssueID: epic.ID, Role: "reviewer", Source: "epic-retro", AgentID: agentID}, started, output, runErr)
+	recordAgentUsage(root, usageRecord{IssueID: epic.ID, EpicID: epic.ID, Role: "reviewer", AgentID: agentID}, output)
+	if runErr != nil {
+		return "", fmt.Errorf("retrospective agent failed: %w", runErr)
+	}
+	report := processAgentOutput(output)
+
+	path := filepath.Join(root, ".yoke", "epic-improvement-reports", sanitizePathSegment(epic.ID), "retrospective.md")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte("# Epic Retrospective\n\n"+strings.TrimSpace(report)+"\n"), 0o644); err != nil {
+		return "", err
+	}
+	if err := tracker.Comment(epic.ID, formatEpicRetroComment(epic, report)); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func cmdAbandon(args []string) error {
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+
+	var (
+		issue          string
+		reason         string
+		removeWorktree bool
+		deleteBranch   bool
+	)
+
+	parsed, err := parseCommandArgs("abandon", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printAbandonUsage()
+		return nil
+	}
+	if issue, err = parsed.issueArg("abandon", cfg.BDPrefix); err != nil {
+		return err
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--reason":
+			reason = flag.Value
+		case "--remove-worktree":
+			removeWorktree = true
+		case "--delete-branch":
+			deleteBranch = true
+			removeWorktree = true
+		}
+	}
+
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+
+	if issue == "" {
+		issue = currentBranchIssue(cfg.BDPrefix)
+	}
+	if issue == "" {
+		issue = daemonFocusedIssue(root)
+	}
+	if issue == "" {
+		return errors.New("could not infer issue id from branch or daemon focus; pass issue id explicitly")
+	}
+
+	details, err := issueDetails(issue)
+	if err != nil {
+		return err
+	}
+	status := workflowStatusForIssue(details)
+	if status == "" {
+		return errors.New("issue payload missing status")
+	}
+	if status != "in_progress" && status != "in_review" {
+		return fmt.Errorf("cannot abandon %s: status is %s (expected in_progress or in_review)", issue, status)
+	}
+
+	if err := releaseClaim(cfg, details, issueChange{Status: "open", RemoveLabels: []string{reviewQueueLabel}}); err != nil {
+		return err
+	}
+	currentStatus, err := issueStatus(issue)
+	if err != nil {
+		return err
+	}
+	if currentStatus != "open" {
+		return fmt.Errorf("bd update did not reopen %s (current status: %s)", issue, currentStatus)
+	}
+
+	branch := branchForIssue(issue)
+	branchAction := "kept"
+	if removeWorktree {
+		if err := removeIssueWorktree(root, branch); err != nil {
+			return err
+		}
+		branchAction = "worktree removed, branch kept"
+	}
+	if deleteBranch {
+		if refExists("refs/heads/" + branch) {
+			if err := runCommand("git", "-C", root, "branch", "-D", branch); err != nil {
+				return err
+			}
+		}
+		branchAction = "worktree and branch deleted"
+	}
+
+	if err := tracker.Comment(issue, formatAbandonComment(status, reason, branch, branchAction)); err != nil {
+		return err
+	}
+	if strings.EqualFold(daemonFocusedIssue(root), issue) {
+		clearDaemonFocusIssue(root)
+	}
+
+	note(fmt.Sprintf("Abandoned %s (was %s); issue is open again.", issue, status))
+	note(fmt.Sprintf("Branch %s: %s", branch, branchAction))
+	return nil
+}
+
+func removeIssueWorktree(root, branch string) error {
+	path := worktreePathForBranch(root, branch)
+	if strings.TrimSpace(path) == "" {
+		return nil
+	}
+	rootAbs, _ := filepath.Abs(root)
+	pathAbs, _ := filepath.Abs(path)
+	if rootAbs == pathAbs {
+		return fmt.Errorf("branch %s is checked out in the main worktree; switch branches before removing it", branch)
+	}
+	return runCommand("git", "-C", root, "worktree", "remove", "--force", path)
+}
+
+func formatAbandonComment(previousStatus, reason, branch, branchAction string) string {
+	lines := []string{
+		"Issue abandoned:",
+		"- Previous status: " + sanitizeCommentLine(previousStatus),
+		"- Reason: " + sanitizeCommentLine(valueOrFallback(reason, "not provided")),
+		"- Branch: `" + sanitizeCommentLine(branch) + "` (" + sanitizeCommentLine(branchAction) + ")",
+		"- Issue returned to open for a future claim.",
+	}
+	return strings.Join(lines, "\n")
+}
+
+func cmdResume(args []string) error {
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := parseCommandArgs("resume", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printResumeUsage()
+		return nil
+	}
+	issue, err := parsed.issueArg("resume", cfg.BDPrefix)
+	if err != nil {
+		return err
+	}
+
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+
+	if issue == "" {
+		issue = currentBranchIssue(cfg.BDPrefix)
+	}
+	if issue == "" {
+		issue = daemonFocusedIssue(root)
+	}
+	if issue == "" {
+		return errors.New("could not infer issue id from branch or daemon focus; pass issue id explicitly")
+	}
+
+	status, err := issueStatus(issue)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case "in_progress":
+	case "in_review":
+		if err := ensureNotReviewLocked(root, issue); err != nil {
+			return err
+		}
+		if err := tracker.Update(issue, issueChange{Status: "in_progress", RemoveLabels: []string{reviewQueueLabel}}); err != nil {
+			return err
+		}
+		currentStatus, err := issueStatus(issue)
+		if err != nil {
+			return err
+		}
+		if currentStatus != "in_progress" {
+			return fmt.Errorf("bd update did not return %s to in_progress (current status: %s)", issue, currentStatus)
+		}
+		note("Moved " + issue + " out of the review queue back to in_progress")
+	default:
+		return fmt.Errorf("cannot resume %s: status is %s (expected in_progress or in_review); use yoke claim instead", issue, status)
+	}
+
+	worktreePath, err := ensureIssueWorktree(root, cfg, issue)
+	if err != nil {
+		return err
+	}
+	if err := writeDaemonFocusIssue(root, issue); err != nil {
+		note("warning: failed to persist daemon focus issue: " + err.Error())
+	}
+
+	details, err := issueDetails(issue)
+	if err != nil {
+		return err
+	}
+	comments, err := listIssueComments(issue)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(formatResumeContext(details, branchForIssue(issue), worktreePath, comments))
+	_ = checkWriterOverlaps(root, cfg, issue)
+	return nil
+}
+
+// writerContextComments keeps the comments a writer needs to pick work back
+// up: the latest handoff and everything reviewers added after it. Without a
+// handoff, only reviewer rejections are kept.
+func writerContextComments(comments []bdComment) []bdComment {
+	for i := len(comments) - 1; i >= 0; i-- {
+		if strings.HasPrefix(strings.TrimSpace(comments[i].Text), "Writer handoff:") {
+			return latestComments(comments[i:], maxWriterContextComments)
+		}
+	}
+	rejections := []bdComment{}
+	for _, comment := range comments {
+		if strings.HasPrefix(strings.TrimSpace(comment.Text), "Reviewer rejection:") {
+			rejections = append(rejections, comment)
+		}
+	}
+	return latestComments(rejections, maxWriterContextComments)
+}
+
+func formatResumeContext(issue bdListIssue, branch, worktreePath string, comments []bdComment) string {
+	lines := []string{
+		"issue: " + issue.ID,
+		"title: " + valueOrFallback(issue.Title, "(untitled)"),
+		"branch: " + branch,
+		"worktree: " + worktreePath,
+	}
+	writerContext := writerContextComments(comments)
+	if len(writerContext) == 0 {
+		lines = append(lines, "", "No handoff or reviewer comments yet.")
+		return strings.Join(lines, "\n")
+	}
+	lines = append(lines, "", "Writer context:")
+	for _, comment := range writerContext {
+		header := "--- " + valueOrFallback(comment.CreatedAt, "unknown time")
+		if strings.TrimSpace(comment.Author) != "" {
+			header += " by " + comment.Author
+		}
+		lines = append(lines, header, strings.TrimSpace(comment.Text))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatApprovalComment(reviewedSHA string) string {
+	return "Reviewer approval: reviewed commit " + valueOrFallback(sanitizeCommentLine(reviewedSHA), "unknown")
+}
+
+func formatRejectionComment(rejectReason, reviewedSHA string) string {
+	return rejectionCommentPrefix + valueOrFallback(sanitizeCommentLine(rejectReason), "no reason given") +
+		" (reviewed commit " + valueOrFallback(sanitizeCommentLine(reviewedSHA), "unknown") + ")"
+}
+
+// approvalRecord remembers the commit an approval applied to until the PR is
+// merged or closed, so later pushes can invalidate the verdict.
+type approvalRecord struct {
+	Issue      string `json:"issue"`
+	SHA        string `json:"sha"`
+	ApprovedAt string `json:"approved_at"`
+}
+
+func approvalsDir(root string) string {
+	return filepath.Join(yokeSharedStateDir(root), "approvals")
+}
+
+func writeApprovalRecord(root string, record approvalRecord) error {
+	if strings.TrimSpace(record.SHA) == "" || dryRunMode {
+		return nil
+	}
+	if err := os.MkdirAll(approvalsDir(root), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(approvalsDir(root), sanitizePathSegment(strings.ToLower(record.Issue))+".json"), data, 0o644)
+}
+
+func loadApprovalRecords(root string) []approvalRecord {
+	entries, err := os.ReadDir(approvalsDir(root))
+	if err != nil {
+		return nil
+	}
+	records := []approvalRecord{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(approvalsDir(root), entry.Name()))
+		if err != nil {
+			continue
+		}
+		var record approvalRecord
+		if err := json.Unmarshal(data, &record); err != nil || record.Issue == "" {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func removeApprovalRecord(root, issue string) {
+	if dryRunMode {
+		return
+	}
+	_ = os.Remove(filepath.Join(approvalsDir(root), sanitizePathSegment(strings.ToLower(issue))+".json"))
+}
+
+// prHeadSHA reads the PR's head commit through the GitHub API; it is empty
+// when the API is unavailable or the lookup fails.
+func prHeadSHA(prNumber string) string {
+	client, err := githubAPI()
+	if err != nil {
+		return ""
+	}
+	pull, err := client.pull(prNumber)
+	if err != nil || len(pull.Head.SHA) < 7 {
+		return ""
+	}
+	return pull.Head.SHA
+}
+
+// invalidateStaleApprovals returns approved issues to the review queue when
+// their PR head moved past the approved commit before merge. Records for PRs
+// that are no longer open are dropped.
+func invalidateStaleApprovals(root string) {
+	if _, err := githubAPI(); err != nil || !hasOriginRemote() {
+		return
+	}
+	for _, record := range loadApprovalRecords(root) {
+		prNumber, _, _, ok, err := openPRForIssue(record.Issue)
+		if err != nil {
+			note("warning: keeping the approval record for " + record.Issue + ": " + err.Error())
+			continue
+		}
+		if !ok {
+			removeApprovalRecord(root, record.Issue)
+			continue
+		}
+		head := prHeadSHA(prNumber)
+		if head == "" {
+			head = branchHeadSHA(root, branchForIssue(record.Issue))
+		}
+		if head == "" || head == record.SHA {
+			continue
+		}
+
+		note(fmt.Sprintf("Approval for %s was at %s but PR #%s is now at %s; returning it to the review queue", record.Issue, shortSHA(record.SHA), prNumber, shortSHA(head)))
+		if err := tracker.Update(record.Issue, issueChange{Status: "blocked", AddLabels: []string{reviewQueueLabel}}); err != nil {
+			note("warning: failed to return " + record.Issue + " to review queue: " + err.Error())
+			continue
+		}
+		comment := formatApprovalInvalidatedComment(record, head)
+		if err := tracker.Comment(record.Issue, comment); err != nil {
+			note("warning: failed to record approval invalidation: " + err.Error())
+		}
+		if err := setPRDraft(prNumber, true); err != nil {
+			note("warning: failed to return PR #" + prNumber + " to draft: " + err.Error())
+		}
+		if err := commentOnPR(prNumber, comment); err != nil {
+			note("warning: failed to post approval invalidation to PR: " + err.Error())
+		}
+		removeApprovalRecord(root, record.Issue)
+	}
+}
+
+func formatApprovalInvalidatedComment(record approvalRecord, head string) string {
+	lines := []string{
+		"Approval invalidated:",
+		"- Approved commit: `" + sanitizeCommentLine(record.SHA) + "`",
+		"- Current head: `" + sanitizeCommentLine(head) + "`",
+		"- New commits landed after approval and before merge; the issue is back in the review queue.",
+	}
+	return strings.Join(lines, "\n")
+}
+
+type reviewQueueEntry struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	Branch     string `json:"branch"`
+	PR         string `json:"pr,omitempty"`
+	QueuedAt   string `json:"queued_at,omitempty"`
+	AgeSeconds int64  `json:"age_seconds"`
+}
+
+func cmdQueue(args []string) error {
+	parsed, err := parseCommandArgs("queue", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printQueueUsage()
+		return nil
+	}
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown queue argument: %s", parsed.Positionals[0])
+	}
+	jsonOutput := len(parsed.Flags) > 0
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+	entries, err := collectReviewQueue(cfg)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(entries) == 0 {
+		note("Review queue is empty.")
+		return nil
+	}
+	return writeReviewQueueTable(os.Stdout, entries)
+}
+
+// collectReviewQueue lists the issues waiting for review, oldest first.
+func collectReviewQueue(cfg config) ([]reviewQueueEntry, error) {
+	issues, err := tracker.List(issueQuery{Status: "blocked", Label: reviewQueueLabel})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entries := []reviewQueueEntry{}
+	for _, issue := range issues {
+		if workflowStatusForIssue(issue) != "in_review" || !(looksLikeIssueID(issue.ID, cfg.BDPrefix) || looksLikeIssueIDAnyPrefix(issue.ID)) {
+			continue
+		}
+		entry := reviewQueueEntry{ID: issue.ID, Title: issue.Title, Branch: branchForIssue(issue.ID)}
+		if number, _, _, ok, err := openPRForIssue(issue.ID); err != nil {
+			note("warning: " + err.Error())
+		} else if ok {
+			entry.PR = number
+		}
+		comments, _ := listIssueComments(issue.ID)
+		if queuedAt, ok := reviewQueuedAt(issue, comments); ok {
+			entry.QueuedAt = queuedAt.UTC().Format(time.RFC3339)
+			entry.AgeSeconds = int64(now.Sub(queuedAt).Seconds())
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].AgeSeconds > entries[j].AgeSeconds
+	})
+	return entries, nil
+}
+
+// reviewQueuedAt approximates when an issue entered the review queue: the
+// latest writer handoff comment, falling back to the issue's last update.
+func reviewQueuedAt(issue bdListIssue, comments []bdComment) (time.Time, bool) {
+	for i := len(comments) - 1; i >= 0; i-- {
+		if !strings.HasPrefix(strings.TrimSpace(comments[i].Text), "Writer handoff:") {
+			continue
+		}
+		if parsed, ok := parseBDTimestamp(comments[i].CreatedAt); ok {
+			return parsed, true
+		}
+	}
+	return parseBDTimestamp(issue.UpdatedAt)
+}
+
+func parseBDTimestamp(raw string) (time.Time, bool) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+		if parsed, err := time.Parse(layout, trimmed); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func formatQueueAge(entry reviewQueueEntry) string {
+	if entry.QueuedAt == "" {
+		return "unknown"
+	}
+	return formatAgeSeconds(entry.AgeSeconds)
+}
+
+func formatAgeSeconds(seconds int64) string {
+	age := time.Duration(seconds) * time.Second
+	switch {
+	case age < time.Minute:
+		return "<1m"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh%dm", int(age.Hours()), int(age.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%dh", int(age.Hours())/24, int(age.Hours())%24)
+	}
+}
+
+func writeReviewQueueTable(out io.Writer, entries []reviewQueueEntry) error {
+	rows := [][]string{{"ISSUE", "AGE", "PR", "BRANCH", "TITLE"}}
+	for _, entry := range entries {
+		pr := "-"
+		if entry.PR != "" {
+			pr = "#" + entry.PR
+		}
+		rows = append(rows, []string{entry.ID, formatQueueAge(entry), pr, entry.Branch, entry.Title})
+	}
+	return writeColumns(out, rows, func(row, col int, cell string) string {
+		switch {
+		case row == 0:
+			return styleBold
+		case col == 1 || col == 3:
+			return styleDim
+		}
+		return ""
+	})
+}
+
+type clarificationEntry struct {
+	ID          string `json:"id"`
+	Epic        string `json:"epic,omitempty"`
+	Question    string `json:"question"`
+	Description string `json:"description,omitempty"`
+	Comments    int    `json:"comments"`
+}
+
+// cmdClarify lists open "Clarification needed:" tasks across all epics so a
+// person can answer them with yoke answer.
+func cmdClarify(args []string) error {
+	parsed, err := parseCommandArgs("clarify", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printClarifyUsage()
+		return nil
+	}
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown clarify argument: %s", parsed.Positionals[0])
+	}
+	jsonOutput, syncSlack := false, false
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--json":
+			jsonOutput = true
+		case "--sync-slack":
+			syncSlack = true
+		}
+	}
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+	if syncSlack {
+		client := newSlackClient(cfg)
+		if client == nil {
+			return errors.New("slack is not configured: set SLACK_WEBHOOK_URL, or SLACK_BOT_TOKEN with YOKE_SLACK_CHANNEL")
+		}
+		posted, ingested, err := syncSlackClarifications(root, client)
+		if err != nil {
+			return err
+		}
+		note(fmt.Sprintf("Slack: posted %d new clarification task(s), copied %d reply(ies) to bd.", posted, ingested))
+	}
+
+	entries, err := openClarificationEntries()
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(entries) == 0 {
+		note("No open clarification tasks.")
+		return nil
+	}
+	return writeClarificationTable(os.Stdout, entries)
+}
+
+// openClarificationEntries collects clarification tasks that are not closed,
+// grouped by epic.
+func openClarificationEntries() ([]clarificationEntry, error) {
+	entries := []clarificationEntry{}
+	seen := map[string]bool{}
+	for _, status := range []string{"open", "in_progress", "blocked"} {
+		issues, err := tracker.List(issueQuery{Status: status})
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			if !isClarificationNeededTitle(issue.Title) || workflowStatusForIssue(issue) == "closed" || seen[issue.ID] {
+				continue
+			}
+			seen[issue.ID] = true
+			epic, _ := epicAncestorID(issue.ID)
+			entries = append(entries, newClarificationEntry(issue, epic))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Epic != entries[j].Epic {
+			return entries[i].Epic < entries[j].Epic
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+func newClarificationEntry(issue bdListIssue, epic string) clarificationEntry {
+	return clarificationEntry{
+		ID:          issue.ID,
+		Epic:        epic,
+		Question:    clarificationQuestion(issue.Title),
+		Description: strings.TrimSpace(issue.Description),
+		Comments:    issue.CommentCount,
+	}
+}
+
+func clarificationQuestion(title string) string {
+	trimmed := strings.TrimSpace(title)
+	return strings.TrimSpace(trimmed[min(len(trimmed), len("clarification needed:")):])
+}
+
+func writeClarificationTable(out io.Writer, entries []clarificationEntry) error {
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ISSUE\tEPIC\tSTATE\tQUESTION")
+	for _, entry := range entries {
+		state := "waiting"
+		if entry.Comments > 0 {
+			state = fmt.Sprintf("answered (%d)", entry.Comments)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", entry.ID, valueOrFallback(entry.Epic, "-"), state, entry.Question)
+	}
+	return writer.Flush()
+}
+
+// slackClient posts clarification tasks to Slack. With only an incoming
+// webhook it can post; with a bot token and channel it posts through
+// chat.postMessage so thread replies can be read back as answers.
+type slackClient struct {
+	apiURL     string
+	webhookURL string
+	token      string
+	channel    string
+	http       *http.Client
+	sleep      func(time.Duration)
+}
+
+// slackMessage is the subset of a Slack message object yoke reads.
+type slackMessage struct {
+	User    string `json:"user"`
+	Text    string `json:"text"`
+	TS      string `json:"ts"`
+	BotID   string `json:"bot_id"`
+	Subtype string `json:"subtype"`
+}
+
+// slackThread tracks the Slack message posted for one clarification task and
+// the newest reply already copied to bd.
+type slackThread struct {
+	TS        string `json:"ts,omitempty"`
+	LastReply string `json:"last_reply,omitempty"`
+}
+
+const (
+	slackAPIURL           = "https://slack.com/api"
+	slackThreadsFile      = "slack-clarifications.json"
+	slackReplyCommentHead = "Answer from Slack"
+)
+
+// newSlackClient returns nil when Slack is not configured. Credentials come
+// from the environment (SLACK_BOT_TOKEN, SLACK_WEBHOOK_URL) so they stay out
+// of .yoke/config.sh.
+func newSlackClient(cfg config) *slackClient {
+	client := &slackClient{
+		apiURL:     slackAPIURL,
+		webhookURL: strings.TrimSpace(os.Getenv("SLACK_WEBHOOK_URL")),
+		channel:    strings.TrimSpace(cfg.SlackChannel),
+		http:       &http.Client{Timeout: 30 * time.Second},
+		sleep:      time.Sleep,
+	}
+	if client.channel != "" {
+		client.token = strings.TrimSpace(os.Getenv("SLACK_BOT_TOKEN"))
+	}
+	if client.token == "" && client.webhookURL == "" {
+		return nil
+	}
+	return client
+}
+
+// canReadReplies reports whether posts are threads yoke can poll.
+func (c *slackClient) canReadReplies() bool {
+	return c.token != ""
+}
+
+// do sends one request, retrying 429 and 5xx responses. Web API responses
+// carry "ok": false on failure; webhook responses are plain text.
+func (c *slackClient) do(method, target string, body any, out any) error {
+	defer timePhase("Slack API", false)()
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = data
+	}
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest(method, target, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		if target != c.webhookURL {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			if attempt < githubMaxAttempts {
+				c.sleep(time.Duration(attempt) * time.Second)
+				continue
+			}
+			return err
+		}
+		data, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return readErr
+		}
+		if attempt < githubMaxAttempts && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500) {
+			wait := time.Duration(attempt) * time.Second
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(seconds) * time.Second
+			}
+			c.sleep(wait)
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("slack %s %s: %d %s", method, req.URL.Path, resp.StatusCode, valueOrFallback(strings.TrimSpace(string(data)), http.StatusText(resp.StatusCode)))
+		}
+		if target == c.webhookURL {
+			return nil
+		}
+		var status struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(data, &status); err != nil {
+			return fmt.Errorf("decode Slack API %s: %w", req.URL.Path, err)
+		}
+		if !status.OK {
+			return fmt.Errorf("slack API %s: %s", req.URL.Path, valueOrFallback(status.Error, "request failed"))
+		}
+		if out != nil {
+			if err := json.Unmarshal(data, out); err != nil {
+				return fmt.Errorf("decode Slack API %s: %w", req.URL.Path, err)
+			}
+		}
+		return nil
+	}
+}
+
+// post sends text to the channel and returns the message timestamp, which
+// is empty for webhook posts.
+func (c *slackClient) post(text string) (string, error) {
+	if !c.canReadReplies() {
+		return "", c.do(http.MethodPost, c.webhookURL, map[string]any{"text": text}, nil)
+	}
+	var out struct {
+		TS string `json:"ts"`
+	}
+	body := map[string]any{"channel": c.channel, "text": text, "unfurl_links": false}
+	if err := c.do(http.MethodPost, c.apiURL+"/chat.postMessage", body, &out); err != nil {
+		return "", err
+	}
+	return out.TS, nil
+}
+
+// replies returns the people's replies in a thread, oldest first, skipping
+// the parent message and anything posted by a bot.
+func (c *slackClient) replies(ts string) ([]slackMessage, error) {
+	query := url.Values{"channel": {c.channel}, "ts": {ts}, "limit": {"200"}}
+	var out struct {
+		Messages []slackMessage `json:"messages"`
+	}
+	if err := c.do(http.MethodGet, c.apiURL+"/conversations.replies?"+query.Encode(), nil, &out); err != nil {
+		return nil, err
+	}
+	replies := []slackMessage{}
+	for _, message := range out.Messages {
+		if message.TS == ts || message.BotID != "" || message.Subtype != "" || strings.TrimSpace(message.Text) == "" {
+			continue
+		}
+		replies = append(replies, message)
+	}
+	return replies, nil
+}
+
+func slackThreadsPath(root string) string {
+	return filepath.Join(root, ".yoke", slackThreadsFile)
+}
+
+func loadSlackThreads(root string) map[string]slackThread {
+	threads := map[string]slackThread{}
+	data, err := os.ReadFile(slackThreadsPath(root))
+	if err != nil {
+		return threads
+	}
+	if err := json.Unmarshal(data, &threads); err != nil {
+		return map[string]slackThread{}
+	}
+	return threads
+}
+
+func saveSlackThreads(root string, threads map[string]slackThread) error {
+	data, err := json.MarshalIndent(threads, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(slackThreadsPath(root)), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(slackThreadsPath(root), append(data, '\n'), 0o644)
+}
+
+func formatSlackClarification(entry clarificationEntry, threaded bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Clarification needed* on %s (%s): %s\n", valueOrFallback(entry.Epic, "no epic"), entry.ID, entry.Question)
+	if entry.Description != "" {
+		b.WriteString(truncateForPrompt(entry.Description, maxClarificationCommentChars) + "\n")
+	}
+	if threaded {
+		b.WriteString("Reply in this thread to answer.")
+	} else {
+		fmt.Fprintf(&b, "Answer with `yoke answer %s \"...\"`.", entry.ID)
+	}
+	return b.String()
+}
+
+// syncSlackClarifications posts unanswered clarification tasks that have not
+// been posted yet and, when threads can be read, copies new replies to the
+// task as bd comments so the auto-close-on-comment flow picks them up.
+// Tasks that are no longer open are forgotten.
+func syncSlackClarifications(root string, client *slackClient) (int, int, error) {
+	entries, err := openClarificationEntries()
+	if err != nil {
+		return 0, 0, err
+	}
+	threads := loadSlackThreads(root)
+	open := map[string]bool{}
+	posted, ingested := 0, 0
+	var syncErr error
+	for _, entry := range entries {
+		open[entry.ID] = true
+		thread, seen := threads[entry.ID]
+		if !seen {
+			if entry.Comments > 0 {
+				continue
+			}
+			ts, err := client.post(formatSlackClarification(entry, client.canReadReplies()))
+			if err != nil {
+				syncErr = err
+				break
+			}
+			threads[entry.ID] = slackThread{TS: ts}
+			posted++
+			continue
+		}
+		if thread.TS == "" || !client.canReadReplies() {
+			continue
+		}
+		replies, err := client.replies(thread.TS)
+		if err != nil {
+			syncErr = err
+			break
+		}
+		for _, reply := range replies {
+			if thread.LastReply != "" && !slackTSAfter(reply.TS, thread.LastReply) {
+				continue
+			}
+			comment := fmt.Sprintf("%s (<@%s>):\n\n%s", slackReplyCommentHead, reply.User, strings.TrimSpace(reply.Text))
+			if err := tracker.Comment(entry.ID, comment); err != nil {
+				syncErr = err
+				break
+			}
+			thread.LastReply = reply.TS
+			threads[entry.ID] = thread
+			ingested++
+		}
+		if syncErr != nil {
+			break
+		}
+	}
+	if syncErr == nil {
+		for id := range threads {
+			if !open[id] {
+				delete(threads, id)
+			}
+		}
+	}
+	if err := saveSlackThreads(root, threads); err != nil && syncErr == nil {
+		syncErr = err
+	}
+	return posted, ingested, syncErr
+}
+
+// slackTSAfter compares Slack "seconds.micros" timestamps, which have a
+// fixed number of fractional digits.
+func slackTSAfter(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
+
+// cmdAnswer posts a person's answer on a clarification task. The answer is
+// picked up by the epic's next improvement cycle, after which yoke claim
+// closes the task as clarified-by-comment.
+func cmdAnswer(args []string) error {
+	parsed, err := parseCommandArgs("answer", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printAnswerUsage()
+		return nil
+	}
+	if len(parsed.Positionals) == 0 || len(parsed.Positionals) > 2 {
+		return errors.New(`usage: yoke answer <clarification-task-id> ["answer" | -]`)
+	}
+	issue := parsed.Positionals[0]
+	answer := "-"
+	if len(parsed.Positionals) == 2 {
+		answer = parsed.Positionals[1]
+	}
+	if answer == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("read answer from stdin: %w", err)
+		}
+		answer = string(data)
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return errors.New("answer is empty")
+	}
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+	details, err := issueDetails(issue)
+	if err != nil {
+		return err
+	}
+	if !isClarificationNeededTitle(details.Title) {
+		return fmt.Errorf("%s is not a clarification task (title does not start with \"Clarification needed:\")", issue)
+	}
+	if workflowStatusForIssue(details) == "closed" {
+		return fmt.Errorf("clarification task %s is already closed", issue)
+	}
+	if err := tracker.Comment(issue, answer); err != nil {
+		return err
+	}
+	note("Answered " + issue)
+	if epic, err := epicAncestorID(issue); err == nil && epic != "" {
+		note(fmt.Sprintf("Next: yoke improve %s (or yoke claim %s) folds the answer into the epic and closes the task.", epic, epic))
+	}
+	return nil
+}
+
+type managedIssueRow struct {
+	ID       string
+	State    string
+	BDStatus string
+	Labels   []string
+	Branch   string
+	Worktree string
+	PR       string
+	Title    string
+}
+
+func cmdList(args []string) error {
+	parsed, err := parseCommandArgs("list", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printListUsage()
+		return nil
+	}
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown list argument: %s", parsed.Positionals[0])
+	}
+	var statusFilter, labelFilter string
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--status":
+			statusFilter = flag.Value
+		case "--label":
+			labelFilter = flag.Value
+		}
+	}
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+
+	issues := map[string]bdListIssue{}
+	for _, status := range []string{"in_progress", "blocked"} {
+		listed, err := listIssuesByStatus(status, false)
+		if err != nil {
+			return err
+		}
+		for _, issue := range listed {
+			issues[issue.ID] = issue
+		}
+	}
+
+	branches := yokeBranches(root)
+	worktrees := parseGitWorktreeListEntries(commandCombinedOutput("git", "-C", root, "worktree", "list", "--porcelain"))
+	prs := openYokePRs()
+
+	candidates := append([]string{}, branches...)
+	for branch := range prs {
+		candidates = append(candidates, branch)
+	}
+	for _, branch := range candidates {
+		id := strings.TrimPrefix(branch, "yoke/")
+		if _, ok := issues[id]; ok {
+			continue
+		}
+		if details, err := issueDetails(id); err == nil && details.ID != "" {
+			issues[id] = details
+		} else {
+			issues[id] = bdListIssue{ID: id}
+		}
+	}
+
+	rows := filterManagedIssueRows(buildManagedIssueRows(issues, branches, worktrees, prs), statusFilter, labelFilter)
+	if len(rows) == 0 {
+		note("No yoke-managed issues found.")
+		return nil
+	}
+	return writeManagedIssueTable(os.Stdout, rows)
+}
+
+func yokeBranches(root string) []string {
+	output := commandCombinedOutput("git", "-C", root, "for-each-ref", "--format=%(refname:short)", "refs/heads/yoke/")
+	branches := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if branch := strings.TrimSpace(line); strings.HasPrefix(branch, "yoke/") {
+			branches = append(branches, branch)
+		}
+	}
+	return branches
+}
+
+func openYokePRs() map[string]prListEntry {
+	prs := map[string]prListEntry{}
+	if !hasOriginRemote() {
+		return prs
+	}
+	client, err := githubAPI()
+	if err != nil {
+		return prs
+	}
+	pulls, err := client.listPulls(url.Values{"state": {"open"}}, nil)
+	if err != nil {
+		note("warning: listing open PRs failed: " + err.Error())
+		return prs
+	}
+	for _, pull := range pulls {
+		if strings.HasPrefix(pull.Head.Ref, "yoke/") {
+			prs[pull.Head.Ref] = pull.entry()
+		}
+	}
+	return prs
+}
+
+// managedIssueState folds bd status and git/PR state into one lifecycle word.
+// Work that still has a branch or worktree but no active bd state is orphaned.
+func managedIssueState(issue bdListIssue, hasBranch, hasPR bool) string {
+	switch workflowStatusForIssue(issue) {
+	case "":
+		return "orphaned"
+	case "in_progress":
+		return "claimed"
+	case "in_review":
+		return "in_review"
+	case "blocked":
+		if hasLabel(issue.Labels, mergeQueuedLabel) {
+			return "merge_queued"
+		}
+		return "blocked"
+	case "closed":
+		if hasPR {
+			return "approved"
+		}
+		return "orphaned"
+	case "open":
+		if hasBranch || hasPR {
+			return "orphaned"
+		}
+		return "open"
+	default:
+		return workflowStatusForIssue(issue)
+	}
+}
+
+func buildManagedIssueRows(issues map[string]bdListIssue, branches []string, worktrees []gitWorktreeEntry, prs map[string]prListEntry) []managedIssueRow {
+	branchSet := map[string]struct{}{}
+	for _, branch := range branches {
+		branchSet[branch] = struct{}{}
+	}
+	worktreeByBranch := map[string]string{}
+	for _, entry := range worktrees {
+		worktreeByBranch[entry.Branch] = entry.Path
+	}
+
+	rows := []managedIssueRow{}
+	for id, issue := range issues {
+		branch := branchForIssue(id)
+		_, hasBranch := branchSet[branch]
+		pr, hasPR := prs[branch]
+		state := managedIssueState(issue, hasBranch, hasPR)
+		if state == "blocked" && !hasBranch && !hasPR {
+			continue
+		}
+
+		row := managedIssueRow{
+			ID:       id,
+			State:    state,
+			BDStatus: valueOrFallback(issue.Status, "missing"),
+			Labels:   issue.Labels,
+			Branch:   "-",
+			Worktree: valueOrFallback(worktreeByBranch[branch], "-"),
+			PR:       "-",
+			Title:    issue.Title,
+		}
+		if hasBranch {
+			row.Branch = branch
+		}
+		if hasPR {
+			row.PR = "#" + strconv.Itoa(pr.Number)
+			if pr.IsDraft {
+				row.PR += " (draft)"
+			}
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].State != rows[j].State {
+			return rows[i].State < rows[j].State
+		}
+		return rows[i].ID < rows[j].ID
+	})
+	return rows
+}
+
+func filterManagedIssueRows(rows []managedIssueRow, status, label string) []managedIssueRow {
+	status = strings.TrimSpace(status)
+	label = strings.TrimSpace(label)
+	filtered := []managedIssueRow{}
+	for _, row := range rows {
+		if status != "" && !strings.EqualFold(row.State, status) && !strings.EqualFold(row.BDStatus, status) {
+			continue
+		}
+		if label != "" && !hasLabel(row.Labels, label) {
+			continue
+		}
+		filtered = append(filtered, row)
+	}
+	return filtered
+}
+
+func writeManagedIssueTable(out io.Writer, rows []managedIssueRow) error {
+	cells := [][]string{{"ISSUE", "STATE", "BD_STATUS", "BRANCH", "WORKTREE", "PR", "TITLE"}}
+	for _, row := range rows {
+		cells = append(cells, []string{row.ID, row.State, row.BDStatus, row.Branch, row.Worktree, row.PR, row.Title})
+	}
+	return writeColumns(out, cells, func(row, col int, cell string) string {
+		switch {
+		case row == 0:
+			return styleBold
+		case col == 1:
+			return valueStyle(cell)
+		case col == 2 || col == 3 || col == 4 || cell == "-":
+			return styleDim
+		}
+		return ""
+	})
+}
+
+const (
+	tuiRefreshDefault = 5 * time.Second
+	tuiTailInterval   = 500 * time.Millisecond
+	tuiListLimit      = 50
+	tuiActivityLines  = 6
+	tuiTailBytes      = 64 * 1024
+)
+
+// tuiSnapshot is what yoke tui shows; the issue lists, review queue, and
+// daemon activity are reloaded every --interval, agent output more often.
+type tuiSnapshot struct {
+	Backlog    []bdListIssue
+	InProgress []bdListIssue
+	Queue      []reviewQueueEntry
+	Activity   []auditEntry
+	Pipelines  []livePipeline
+	Err        string
+}
+
+// tuiItem is one selectable row: an issue in the backlog, in progress, or
+// in the review queue.
+type tuiItem struct {
+	Section string
+	ID      string
+	Title   string
+}
+
+type tuiState struct {
+	Cursor int
+	// Follow pins the agent output panel to a pipeline; empty follows the
+	// selected issue, then the latest running agent.
+	Follow  string
+	Message string
+}
+
+// tuiAction is what a key asks the dashboard loop to do besides redrawing.
+// Args is a yoke command to run with the terminal handed back; Prompt asks
+// for a line of text to append to Args first.
+type tuiAction struct {
+	Quit    bool
+	Refresh bool
+	Args    []string
+	Prompt  string
+}
+
+// tuiLine is one screen line, styled after it is cut to the screen width.
+type tuiLine struct {
+	Text  string
+	Style string
+}
+
+func cmdTUI(args []string) error {
+	parsed, err := parseCommandArgs("tui", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printTUIUsage()
+		return nil
+	}
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown tui argument: %s", parsed.Positionals[0])
+	}
+	interval := tuiRefreshDefault
+	once := false
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--interval":
+			if interval, err = parseDaemonInterval(flag.Value); err != nil {
+				return err
+			}
+		case "--once":
+			once = true
+		}
+	}
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+
+	if once {
+		snapshot := collectTUISnapshot(root, cfg)
+		width, height := tuiEnvSize()
+		fmt.Println(renderTUI(snapshot, tuiState{}, tuiOutput(root, snapshot, tuiState{}), width, height))
+		return nil
+	}
+	if !isInteractiveTerminal(os.Stdin) || !isInteractiveTerminal(os.Stdout) {
+		return errors.New("yoke tui needs a terminal; use --once to print a single frame")
+	}
+	return runTUI(root, cfg, interval)
+}
+
+func runTUI(root string, cfg config, interval time.Duration) error {
+	terminal := &tuiTerminal{}
+	if err := terminal.enter(); err != nil {
+		return err
+	}
+	defer terminal.restore()
+
+	input := make(chan []byte)
+	go readTUIInput(os.Stdin, input)
+	snapshots := make(chan tuiSnapshot, 1)
+	refresh := make(chan struct{}, 1)
+	go func() {
+		for {
+			snapshots <- collectTUISnapshot(root, cfg)
+			select {
+			case <-refresh:
+			case <-time.After(interval):
+			}
+		}
+	}()
+	requestRefresh := func() {
+		select {
+		case refresh <- struct{}{}:
+		default:
+		}
+	}
+	// Interrupts during an action stop the child yoke, not the dashboard.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGWINCH)
+	defer signal.Stop(signals)
+	tail := time.NewTicker(tuiTailInterval)
+	defer tail.Stop()
+
+	snapshot := tuiSnapshot{Err: "loading..."}
+	state := tuiState{}
+	width, height := terminal.size()
+	for {
+		terminal.draw(renderTUI(snapshot, state, tuiOutput(root, snapshot, state), width, height))
+		select {
+		case snapshot = <-snapshots:
+		case <-tail.C:
+		case sig := <-signals:
+			if sig == syscall.SIGWINCH {
+				width, height = terminal.size()
+			}
+		case chunk, ok := <-input:
+			if !ok {
+				return nil
+			}
+			for _, key := range parseTUIKeys(chunk) {
+				action := handleTUIKey(key, snapshot, &state)
+				switch {
+				case action.Quit:
+					return nil
+				case action.Refresh:
+					requestRefresh()
+				case len(action.Args) > 0:
+					state.Message = runTUIAction(root, terminal, input, action)
+					width, height = terminal.size()
+					requestRefresh()
+				}
+			}
+		}
+	}
+}
+
+func collectTUISnapshot(root string, cfg config) tuiSnapshot {
+	invalidateTrackerCache()
+	snapshot := tuiSnapshot{}
+	problems := []string{}
+	backlog, err := tracker.List(issueQuery{Status: "open", Ready: true, Limit: tuiListLimit})
+	if err != nil {
+		problems = append(problems, "backlog: "+err.Error())
+	}
+	snapshot.Backlog = unclaimedIssues(cfg, eligibleIssues(cfg, backlog))
+	if snapshot.InProgress, err = tracker.List(issueQuery{Status: "in_progress", Limit: tuiListLimit}); err != nil {
+		problems = append(problems, "in progress: "+err.Error())
+	}
+	if snapshot.Queue, err = collectReviewQueue(cfg); err != nil {
+		problems = append(problems, "review queue: "+err.Error())
+	}
+	activity, err := loadAuditEntries(root)
+	if err != nil {
+		problems = append(problems, "activity: "+err.Error())
+	}
+	if len(activity) > tuiActivityLines {
+		activity = activity[len(activity)-tuiActivityLines:]
+	}
+	snapshot.Activity = activity
+	snapshot.Pipelines = listLivePipelines(root)
+	snapshot.Err = strings.Join(problems, "; ")
+	return snapshot
+}
+
+func tuiItems(snapshot tuiSnapshot) []tuiItem {
+	items := []tuiItem{}
+	for _, issue := range snapshot.Backlog {
+		items = append(items, tuiItem{Section: "backlog", ID: issue.ID, Title: issue.Title})
+	}
+	for _, issue := range snapshot.InProgress {
+		items = append(items, tuiItem{Section: "in_progress", ID: issue.ID, Title: issue.Title})
+	}
+	for _, entry := range snapshot.Queue {
+		items = append(items, tuiItem{Section: "review", ID: entry.ID, Title: entry.Title})
+	}
+	return items
+}
+
+func handleTUIKey(key string, snapshot tuiSnapshot, state *tuiState) tuiAction {
+	items := tuiItems(snapshot)
+	state.Message = ""
+	var selected tuiItem
+	if state.Cursor >= len(items) {
+		state.Cursor = max(len(items)-1, 0)
+	}
+	if len(items) > 0 {
+		selected = items[state.Cursor]
+	}
+	switch key {
+	case "q", "ctrl-c":
+		return tuiAction{Quit: true}
+	case "up", "k":
+		state.Cursor = max(state.Cursor-1, 0)
+	case "down", "j":
+		state.Cursor = min(state.Cursor+1, max(len(items)-1, 0))
+	case "g":
+		return tuiAction{Refresh: true}
+	case "tab":
+		state.Follow = nextTUIPipeline(snapshot.Pipelines, state.Follow)
+	case "c":
+		if selected.Section != "backlog" {
+			state.Message = "c claims an issue from the backlog."
+			return tuiAction{}
+		}
+		return tuiAction{Args: []string{"claim", selected.ID}}
+	case "a", "r":
+		if selected.Section != "review" {
+			state.Message = key + " works on an issue in the review queue."
+			return tuiAction{}
+		}
+		if key == "a" {
+			return tuiAction{Args: []string{"review", selected.ID, "--approve"}}
+		}
+		return tuiAction{Args: []string{"review", selected.ID, "--reject"}, Prompt: "Reject " + selected.ID + ", reason (empty cancels): "}
+	}
+	return tuiAction{}
+}
+
+// nextTUIPipeline cycles the followed pipeline: each live pipeline in turn,
+// then back to following the selection.
+func nextTUIPipeline(pipelines []livePipeline, current string) string {
+	if current == "" {
+		if len(pipelines) == 0 {
+			return ""
+		}
+		return pipelines[0].Pipeline
+	}
+	for i, pipeline := range pipelines {
+		if pipeline.Pipeline == current && i+1 < len(pipelines) {
+			return pipelines[i+1].Pipeline
+		}
+	}
+	return ""
+}
+
+// tuiPipeline picks the agent output to show: the pinned pipeline, else the
+// selected issue's, else the latest running one, else the latest.
+func tuiPipeline(snapshot tuiSnapshot, state tuiState) (livePipeline, bool) {
+	want := state.Follow
+	if want == "" {
+		if items := tuiItems(snapshot); state.Cursor < len(items) {
+			want = items[state.Cursor].ID
+		}
+	}
+	for _, pipeline := range snapshot.Pipelines {
+		if want != "" && strings.EqualFold(pipeline.Pipeline, want) {
+			return pipeline, true
+		}
+	}
+	if state.Follow != "" {
+		return livePipeline{}, false
+	}
+	for _, pipeline := range snapshot.Pipelines {
+		if pipeline.Running {
+			return pipeline, true
+		}
+	}
+	if len(snapshot.Pipelines) > 0 {
+		return snapshot.Pipelines[0], true
+	}
+	return livePipeline{}, false
+}
+
+// tuiOutput reads the tail of the shown pipeline's live log.
+func tuiOutput(root string, snapshot tuiSnapshot, state tuiState) string {
+	pipeline, ok := tuiPipeline(snapshot, state)
+	if !ok {
+		return ""
+	}
+	path := liveLogPath(root, pipeline.Pipeline)
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	data, _ := readLiveLogFrom(path, max(info.Size()-tuiTailBytes, 0))
+	return string(data)
+}
+
+func renderTUI(snapshot tuiSnapshot, state tuiState, output string, width, height int) string {
+	items := tuiItems(snapshot)
+	lines := []tuiLine{
+		{Text: fmt.Sprintf("yoke tui  backlog %d · in progress %d · review %d", len(snapshot.Backlog), len(snapshot.InProgress), len(snapshot.Queue)), Style: styleBold},
+		{Text: "↑/↓ select  c claim  a approve  r reject  tab follow output  g refresh  q quit", Style: styleDim},
+	}
+	if state.Message != "" {
+		lines = append(lines, tuiLine{Text: state.Message, Style: styleYellow})
+	} else if snapshot.Err != "" {
+		lines = append(lines, tuiLine{Text: snapshot.Err, Style: styleRed})
+	}
+
+	// The three issue lists share what the activity and output panels leave,
+	// each scrolled so the cursor stays visible.
+	listRows := max((height-len(lines)-(1+tuiActivityLines)-(1+5))/3-1, 1)
+	offset := 0
+	for _, section := range []struct{ name, title string }{{"backlog", "Backlog"}, {"in_progress", "In progress"}, {"review", "Review queue"}} {
+		sectionItems := []tuiItem{}
+		first := offset
+		for _, item := range items[offset:] {
+			if item.Section != section.name {
+				break
+			}
+			sectionItems = append(sectionItems, item)
+		}
+		offset += len(sectionItems)
+		lines = append(lines, tuiLine{Text: fmt.Sprintf("%s (%d)", section.title, len(sectionItems)), Style: styleBold})
+		if len(sectionItems) == 0 {
+			lines = append(lines, tuiLine{Text: "  none", Style: styleDim})
+			continue
+		}
+		start := 0
+		if cursor := state.Cursor - first; cursor >= listRows && cursor < len(sectionItems) {
+			start = cursor - listRows + 1
+		}
+		for i := start; i < len(sectionItems) && i < start+listRows; i++ {
+			item := sectionItems[i]
+			line := tuiLine{Text: "  " + item.ID + "  " + strings.Join(strings.Fields(item.Title), " ")}
+			if section.name == "review" {
+				line.Text = "  " + item.ID + "  " + formatQueueAge(snapshot.Queue[i]) + "  " + strings.Join(strings.Fields(item.Title), " ")
+			}
+			if first+i == state.Cursor {
+				line.Text = ">" + line.Text[1:]
+				line.Style = styleReverse
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	lines = append(lines, tuiLine{Text: "Daemon activity", Style: styleBold})
+	if len(snapshot.Activity) == 0 {
+		lines = append(lines, tuiLine{Text: "  none", Style: styleDim})
+	}
+	for _, entry := range snapshot.Activity {
+		stamp := entry.Timestamp
+		if parsed, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil {
+			stamp = parsed.Local().Format("15:04:05")
+		}
+		style := ""
+		if entry.Result != "ok" {
+			style = styleRed
+		}
+		lines = append(lines, tuiLine{Text: "  " + strings.Join(strings.Fields(strings.Join([]string{stamp, entry.Actor, entry.Action, entry.IssueID, entry.Result}, " ")), " "), Style: style})
+	}
+
+	if pipeline, ok := tuiPipeline(snapshot, state); ok {
+		status := "finished"
+		if pipeline.Running {
+			status = "running"
+		}
+		lines = append(lines, tuiLine{Text: fmt.Sprintf("Agent output: %s (%s, %s)", pipeline.Pipeline, valueOrFallback(pipeline.Role, "agent"), status), Style: styleBold})
+	} else {
+		lines = append(lines, tuiLine{Text: "Agent output", Style: styleBold}, tuiLine{Text: "  no agent has run yet", Style: styleDim})
+	}
+	outputLines := []string{}
+	for _, line := range strings.Split(strings.TrimRight(ansiEscapePattern.ReplaceAllString(output, ""), "\n"), "\n") {
+		if index := strings.LastIndex(line, "\r"); index >= 0 {
+			line = line[index+1:]
+		}
+		if output != "" && !strings.HasPrefix(line, liveLogHeaderPrefix) {
+			outputLines = append(outputLines, line)
+		}
+	}
+	if room := height - len(lines); len(outputLines) > room {
+		outputLines = outputLines[len(outputLines)-max(room, 0):]
+	}
+	for _, line := range outputLines {
+		lines = append(lines, tuiLine{Text: line})
+	}
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+
+	rendered := make([]string, len(lines))
+	for i, line := range lines {
+		text := line.Text
+		if runes := []rune(text); len(runes) > width {
+			text = string(runes[:width])
+		}
+		rendered[i] = paint(line.Style, text)
+	}
+	return strings.Join(rendered, "\n")
+}
+
+// parseTUIKeys turns terminal input into key names: arrows, tab, ctrl-c,
+// or the typed character.
+func parseTUIKeys(chunk []byte) []string {
+	keys := []string{}
+	for text := string(chunk); text != ""; {
+		switch {
+		case strings.HasPrefix(text, "\x1b[A"), strings.HasPrefix(text, "\x1bOA"):
+			keys, text = append(keys, "up"), text[3:]
+		case strings.HasPrefix(text, "\x1b[B"), strings.HasPrefix(text, "\x1bOB"):
+			keys, text = append(keys, "down"), text[3:]
+		case text[0] == '\t':
+			keys, text = append(keys, "tab"), text[1:]
+		case text[0] == 0x03:
+			keys, text = append(keys, "ctrl-c"), text[1:]
+		default:
+			r, size := utf8.DecodeRuneInString(text)
+			keys, text = append(keys, string(r)), text[size:]
+		}
+	}
+	return keys
+}
+
+func readTUIInput(in io.Reader, chunks chan<- []byte) {
+	defer close(chunks)
+	buffer := make([]byte, 256)
+	for {
+		n, err := in.Read(buffer)
+		if n > 0 {
+			chunks <- append([]byte(nil), buffer[:n]...)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// runTUIAction hands the terminal back, runs the yoke command the key asked
+// for, and waits for enter so its output can be read.
+func runTUIAction(root string, terminal *tuiTerminal, input <-chan []byte, action tuiAction) string {
+	terminal.restore()
+	defer terminal.enter()
+
+	args := action.Args
+	if action.Prompt != "" {
+		fmt.Print(action.Prompt)
+		reply := strings.TrimSpace(readTUILine(input))
+		if reply == "" {
+			return "Cancelled."
+		}
+		args = append(args, reply)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err.Error()
+	}
+	fmt.Println("$ yoke " + strings.Join(args, " "))
+	cmd := exec.Command(executable, args...)
+	cmd.Dir = root
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	result := "yoke " + strings.Join(action.Args, " ") + ": ok"
+	if err := cmd.Run(); err != nil {
+		result = "yoke " + strings.Join(action.Args, " ") + ": " + err.Error()
+	}
+	fmt.Print("\nPress enter to return to the dashboard.")
+	readTUILine(input)
+	return result
+}
+
+func readTUILine(input <-chan []byte) string {
+	var line strings.Builder
+	for chunk := range input {
+		text, _, done := strings.Cut(string(chunk), "\n")
+		line.WriteString(text)
+		if done {
+			break
+		}
+	}
+	return line.String()
+}
+
+// tuiTerminal switches the terminal to the alternate screen without line
+// buffering or echo, using stty so yoke needs no terminal library.
+type tuiTerminal struct {
+	saved string
+}
+
+func (t *tuiTerminal) enter() error {
+	saved, err := stty("-g")
+	if err != nil {
+		return fmt.Errorf("stty: %w", err)
+	}
+	t.saved = saved
+	if _, err := stty("-icanon", "-echo", "-isig", "min", "1", "time", "0"); err != nil {
+		return fmt.Errorf("stty: %w", err)
+	}
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	return nil
+}
+
+func (t *tuiTerminal) restore() {
+	fmt.Print("\x1b[?25h\x1b[?1049l")
+	if t.saved != "" {
+		_, _ = stty(t.saved)
+	}
+}
+
+func (t *tuiTerminal) draw(frame string) {
+	fmt.Print("\x1b[H" + strings.ReplaceAll(frame, "\n", "\x1b[K\n") + "\x1b[K\x1b[J")
+}
+
+func (t *tuiTerminal) size() (int, int) {
+	out, err := stty("size")
+	var rows, cols int
+	if err != nil {
+		return tuiEnvSize()
+	}
+	if _, err := fmt.Sscanf(out, "%d %d", &rows, &cols); err != nil || rows <= 0 || cols <= 0 {
+		return tuiEnvSize()
+	}
+	return cols, rows
+}
+
+// tuiEnvSize is the frame size when there is no terminal to ask: COLUMNS and
+// LINES, else 100x40.
+func tuiEnvSize() (int, int) {
+	width, height := 100, 40
+	if value, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && value > 0 {
+		width = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("LINES")); err == nil && value > 0 {
+		height = value
+	}
+	return width, height
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func parseMergeStrategy(raw string) (string, error) {
+	strategy := strings.ToLower(strings.TrimSpace(raw))
+	switch strategy {
+	case "":
+		return defaultMerge, nil
+	case "squash", "merge", "rebase":
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown merge strategy %q (expected squash, merge, or rebase)", raw)
+	}
+}
+
+func parseCommitConvention(raw string) (string, error) {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	switch value {
+	case "", "none":
+		return "", nil
+	case "conventional":
+		return value, nil
+	default:
+		return "", fmt.Errorf("invalid YOKE_COMMIT_CONVENTION value: %q (want conventional or empty)", raw)
+	}
+}
+
+// parseSignoffMode normalizes YOKE_SIGNOFF to "", "signoff", "gpg", or
+// "signoff,gpg". true/false are accepted as shorthands for signoff/off.
+func parseSignoffMode(raw string) (string, error) {
+	var signoff, gpg bool
+	for _, part := range strings.Split(strings.ToLower(raw), ",") {
+		switch strings.TrimSpace(part) {
+		case "", "false", "off", "none":
+		case "true", "signoff":
+			signoff = true
+		case "gpg":
+			gpg = true
+		default:
+			return "", fmt.Errorf("invalid YOKE_SIGNOFF value: %q (want signoff, gpg, or signoff,gpg)", raw)
+		}
+	}
+	switch {
+	case signoff && gpg:
+		return "signoff,gpg", nil
+	case signoff:
+		return "signoff", nil
+	case gpg:
+		return "gpg", nil
+	}
+	return "", nil
+}
+
+func gitCommitSignFlags(cfg config) []string {
+	var flags []string
+	if strings.Contains(cfg.Signoff, "signoff") {
+		flags = append(flags, "--signoff")
+	}
+	if strings.Contains(cfg.Signoff, "gpg") {
+		flags = append(flags, "--gpg-sign")
+	}
+	return flags
+}
+
+// conventionalCommitType maps a bd issue type to a conventional-commit type.
+func conventionalCommitType(issueType string) string {
+	switch strings.ToLower(strings.TrimSpace(issueType)) {
+	case "bug":
+		return "fix"
+	case "chore":
+		return "chore"
+	case "docs", "documentation":
+		return "docs"
+	default:
+		return "feat"
+	}
+}
+
+// formatCommitSubject is the subject for commits and squash merges yoke
+// creates for an issue: "<issue>: <title>", or "type(<issue>): <title>" with
+// YOKE_COMMIT_CONVENTION=conventional.
+func formatCommitSubject(cfg config, issue, issueType, title string) string {
+	title = strings.TrimSpace(title)
+	if cfg.CommitStyle == "conventional" {
+		return fmt.Sprintf("%s(%s): %s", conventionalCommitType(issueType), issue, valueOrFallback(title, issue))
+	}
+	if title == "" {
+		return issue
+	}
+	return issue + ": " + title
+}
+
+// squashMergeMessageArgs returns gh pr merge --subject/--body flags when a
+// commit convention or sign-off is configured; otherwise GitHub's default
+// message is kept. GitHub signs the merge commit itself, so gpg is not applied.
+func squashMergeMessageArgs(root string, cfg config, issue, prNumber, strategy string) []string {
+	if strategy == "rebase" || (cfg.CommitStyle == "" && !strings.Contains(cfg.Signoff, "signoff")) {
+		return nil
+	}
+	details, err := issueDetails(issue)
+	if err != nil {
+		return nil
+	}
+	var args []string
+	if cfg.CommitStyle != "" {
+		args = append(args, "--subject", fmt.Sprintf("%s (#%s)", formatCommitSubject(cfg, issue, details.IssueType, details.Title), prNumber))
+	}
+	if strings.Contains(cfg.Signoff, "signoff") {
+		if trailer := signoffTrailer(root); trailer != "" {
+			args = append(args, "--body", trailer)
+		}
+	}
+	return args
+}
+
+func signoffTrailer(root string) string {
+	name := strings.TrimSpace(commandCombinedOutput("git", "-C", root, "config", "user.name"))
+	email := strings.TrimSpace(commandCombinedOutput("git", "-C", root, "config", "user.email"))
+	if name == "" || email == "" {
+		return ""
+	}
+	return fmt.Sprintf("Signed-off-by: %s <%s>", name, email)
+}
+
+func cmdMerge(args []string) error {
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+
+	var (
+		issue      string
+		strategy   = cfg.MergeStrategy
+		wait       bool
+		timeout    = defaultPollOptions.Timeout
+		keepBranch bool
+		waitCI     = cfg.WaitCI
+	)
+
+	parsed, err := parseCommandArgs("merge", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printMergeUsage()
+		return nil
+	}
+	if issue, err = parsed.issueArg("merge", cfg.BDPrefix); err != nil {
+		return err
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--strategy":
+			if strategy, err = parseMergeStrategy(flag.Value); err != nil {
+				return err
+			}
+		case "--wait":
+			wait = true
+		case "--timeout":
+			if timeout, err = parseDaemonInterval(flag.Value); err != nil {
+				return err
+			}
+		case "--keep-branch":
+			keepBranch = true
+		case "--wait-ci":
+			waitCI = true
+		}
+	}
+
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+	if !commandExists("gh") {
+		return fmt.Errorf("missing required command: gh")
+	}
+	if !hasOriginRemote() {
+		return errors.New("missing git remote: origin")
+	}
+
+	if issue == "" {
+		issue = currentBranchIssue(cfg.BDPrefix)
+	}
+	if issue == "" {
+		return errors.New("could not infer issue id from branch; pass issue id explicitly")
+	}
+
+	if cfg.MergeQueue {
+		details, err := issueDetails(issue)
+		if err != nil {
+			return err
+		}
+		if hasLabel(details.Labels, mergeQueuedLabel) {
+			return followMergeQueue(root, cfg, issue, wait, timeout, keepBranch)
+		}
+	}
+
+	status, err := issueStatus(issue)
+	if err != nil {
+		return err
+	}
+	if status != "closed" {
+		return fmt.Errorf("cannot merge %s: status is %s (approve it with yoke review --approve first)", issue, status)
+	}
+
+	branch := branchForIssue(issue)
+	prNumber, _, isDraft, ok, err := openPRForIssue(issue)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no open PR found for branch %s", branch)
+	}
+	if isDraft {
+		return fmt.Errorf("PR #%s is still a draft; approve the issue before merging", prNumber)
+	}
+
+	head := prHeadSHA(prNumber)
+	for _, record := range loadApprovalRecords(root) {
+		if strings.EqualFold(record.Issue, issue) && head != "" && head != record.SHA {
+			return fmt.Errorf("PR #%s moved past the approved commit (%s -> %s); re-review before merging", prNumber, shortSHA(record.SHA), shortSHA(head))
+		}
+	}
+
+	decision := strings.ToUpper(strings.TrimSpace(commandCombinedOutput("gh", "pr", "view", prNumber, "--json", "reviewDecision", "--jq", ".reviewDecision")))
+	if decision == "CHANGES_REQUESTED" || decision == "REVIEW_REQUIRED" {
+		return fmt.Errorf("PR #%s is not approved on GitHub (review decision: %s)", prNumber, decision)
+	}
+
+	ctx := context.Background()
+	if waitCI {
+		if err := waitForCIGate(root, issue, prNumber, timeout); err != nil {
+			return err
+		}
+	}
+	if wait {
+		note(fmt.Sprintf("Waiting up to %s for PR #%s checks", timeout, prNumber))
+		opts := defaultPollOptions
+		opts.Timeout = timeout
+		if err := waitForCondition(ctx, "PR #"+prNumber+" checks", opts, prChecksGreenCondition(prNumber)); err != nil {
+			return err
+		}
+	} else {
+		green, err := prChecksGreenCondition(prNumber)(ctx)
+		if err != nil {
+			return err
+		}
+		if !green {
+			return fmt.Errorf("PR #%s checks are still pending; re-run with --wait", prNumber)
+		}
+	}
+
+	mergeArgs := append([]string{"pr", "merge", prNumber, "--" + strategy}, squashMergeMessageArgs(root, cfg, issue, prNumber, strategy)...)
+	if err := runCommand("gh", mergeArgs...); err != nil {
+		return err
+	}
+	mergeOpts := pollOptions{Timeout: 2 * time.Minute, Initial: 2 * time.Second, Max: 10 * time.Second, Factor: 2}
+	if !dryRunMode {
+		if err := waitForCondition(ctx, "PR #"+prNumber+" merge", mergeOpts, prMergedCondition(prNumber)); err != nil {
+			return err
+		}
+	}
+	mergeSHA := strings.TrimSpace(commandCombinedOutput("gh", "pr", "view", prNumber, "--json", "mergeCommit", "--jq", ".mergeCommit.oid"))
+	if err := finalizeMergedIssue(root, cfg, issue, prNumber, strategy, mergeSHA, keepBranch); err != nil {
+		return err
+	}
+
+	note(fmt.Sprintf("Merged PR #%s for %s (%s) at %s", prNumber, issue, strategy, shortSHA(mergeSHA)))
+	return nil
+}
+
+// finalizeMergedIssue cleans up after a PR has landed: branch, worktree,
+// approval and stack records, and the bd merge comment.
+func finalizeMergedIssue(root string, cfg config, issue, prNumber, strategy, mergeSHA string, keepBranch bool) error {
+	branch := branchForIssue(issue)
+	branchAction := "kept"
+	if !keepBranch {
+		if err := runCommand("git", "-C", root, "push", "origin", "--delete", branch); err != nil {
+			note("warning: failed to delete remote branch " + branch + ": " + err.Error())
+		} else if !dryRunMode {
+			opts := pollOptions{Timeout: 30 * time.Second, Initial: time.Second, Max: 5 * time.Second, Factor: 2}
+			if err := waitForCondition(context.Background(), "remote branch "+branch+" deletion", opts, remoteBranchDeletedCondition(root, branch)); err != nil {
+				note("warning: could not confirm remote branch " + branch + " was deleted: " + err.Error())
+			}
+		}
+		if err := removeIssueWorktree(root, branch); err != nil {
+			note("warning: " + err.Error())
+		} else if refExists("refs/heads/" + branch) {
+			if err := runCommand("git", "-C", root, "branch", "-D", branch); err != nil {
+				note("warning: failed to delete local branch " + branch + ": " + err.Error())
+			}
+		}
+		branchAction = "deleted"
+	}
+
+	removeApprovalRecord(root, issue)
+	restackChildren(root, cfg, issue)
+	return tracker.Comment(issue, formatMergeComment(prNumber, strategy, mergeSHA, branch, branchAction))
+}
+
+// Merge queue: with YOKE_MERGE_QUEUE, approval enqueues the PR and parks the
+// issue as blocked + yoke:merge-queued. The issue closes only when GitHub
+// reports the PR merged; a PR dropped from the queue goes back to the writer.
+
+type mergeQueueState struct {
+	PRState    string
+	Queued     bool
+	EntryState string
+	Position   int
+	AutoMerge  bool
+	MergeSHA   string
+}
+
+const mergeQueueQuery = `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      state
+      autoMergeRequest { enabledAt }
+      mergeQueueEntry { state position }
+      mergeCommit { oid }
+    }
+  }
+}`
+
+func parseMergeQueueState(raw string) (mergeQueueState, error) {
+	var payload struct {
+		Data struct {
+			Repository struct {
+				PullRequest *struct {
+					State            string `json:"state"`
+					AutoMergeRequest *struct {
+						EnabledAt string `json:"enabledAt"`
+					} `json:"autoMergeRequest"`
+					MergeQueueEntry *struct {
+						State    string `json:"state"`
+						Position int    `json:"position"`
+					} `json:"mergeQueueEntry"`
+					MergeCommit *struct {
+						OID string `json:"oid"`
+					} `json:"mergeCommit"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &payload); err != nil {
+		return mergeQueueState{}, fmt.Errorf("parse merge queue status: %w", err)
+	}
+	pr := payload.Data.Repository.PullRequest
+	if pr == nil {
+		return mergeQueueState{}, errors.New("merge queue status missing pull request")
+	}
+	state := mergeQueueState{PRState: strings.ToUpper(pr.State), AutoMerge: pr.AutoMergeRequest != nil}
+	if pr.MergeQueueEntry != nil {
+		state.Queued = true
+		state.EntryState = pr.MergeQueueEntry.State
+		state.Position = pr.MergeQueueEntry.Position
+	}
+	if pr.MergeCommit != nil {
+		state.MergeSHA = pr.MergeCommit.OID
+	}
+	return state, nil
+}
+
+// mergeQueueOutcome classifies a queued PR: landed, queued (in the queue or
+// waiting to enter it via auto-merge), or rejected.
+func mergeQueueOutcome(state mergeQueueState) string {
+	switch {
+	case state.PRState == "MERGED":
+		return "landed"
+	case state.PRState == "OPEN" && (state.Queued || state.AutoMerge):
+		return "queued"
+	default:
+		return "rejected"
+	}
+}
+
+func fetchMergeQueueState(prNumber string) (mergeQueueState, error) {
+	output, err := commandOutput("gh", "api", "graphql", "-F", "owner={owner}", "-F", "name={repo}", "-F", "number="+prNumber, "-f", "query="+mergeQueueQuery)
+	if err != nil {
+		return mergeQueueState{}, fmt.Errorf("query merge queue status for PR #%s: %w", prNumber, err)
+	}
+	return parseMergeQueueState(output)
+}
+
+// issueTargetsBaseBranch reports whether the issue's PR lands directly on
+// YOKE_BASE_BRANCH; epic children and stacked PRs land on other yoke branches.
+func issueTargetsBaseBranch(root, issue string) bool {
+	if readStackParent(root, issue) != "" {
+		return false
+	}
+	epicID, err := epicAncestorID(issue)
+	return err == nil && (epicID == "" || strings.EqualFold(epicID, issue))
+}
+
+// enableAutoMerge asks GitHub to merge the approved PR with
+// YOKE_MERGE_STRATEGY once its required checks pass.
+func enableAutoMerge(root string, cfg config, issue, prNumber string) error {
+	strategy := valueOrFallback(cfg.MergeStrategy, defaultMerge)
+	args := append([]string{"pr", "merge", prNumber, "--auto", "--" + strategy}, squashMergeMessageArgs(root, cfg, issue, prNumber, strategy)...)
+	if err := runCommand("gh", args...); err != nil {
+		return fmt.Errorf("enable auto-merge on PR #%s: %w", prNumber, err)
+	}
+	if err := tracker.Comment(issue, fmt.Sprintf("yoke: enabled auto-merge (%s) on PR #%s; it merges once required checks pass.", strategy, prNumber)); err != nil {
+		return err
+	}
+	note(fmt.Sprintf("Enabled auto-merge (%s) on PR #%s", strategy, prNumber))
+	return nil
+}
+
+func enqueueApprovedPR(root, issue, prNumber, reviewedSHA string) error {
+	if err := runCommand("gh", "pr", "merge", prNumber); err != nil {
+		return fmt.Errorf("enqueue PR #%s in the merge queue: %w", prNumber, err)
+	}
+	if err := tracker.Update(issue, issueChange{Status: "blocked", AddLabels: []string{mergeQueuedLabel}, RemoveLabels: []string{reviewQueueLabel}}); err != nil {
+		return err
+	}
+	clearDaemonFocusIssue(root)
+	releaseReviewLock(root, issue)
+	if err := writeApprovalRecord(root, approvalRecord{Issue: issue, SHA: reviewedSHA, ApprovedAt: time.Now().Format(time.RFC3339)}); err != nil {
+		note("warning: failed to record approved commit: " + err.Error())
+	}
+	if err := tracker.Comment(issue, fmt.Sprintf("yoke: approved at %s and enqueued PR #%s in the merge queue; the issue closes when the merge lands.", shortSHA(reviewedSHA), prNumber)); err != nil {
+		return err
+	}
+	note(fmt.Sprintf("Approved %s at %s; PR #%s enqueued in the merge queue", issue, shortSHA(reviewedSHA), prNumber))
+	return nil
+}
+
+func landQueuedIssue(root string, cfg config, issue, prNumber string, state mergeQueueState, keepBranch bool) error {
+	if err := tracker.Close(issue, "merged-via-merge-queue"); err != nil {
+		return err
+	}
+	if err := tracker.Update(issue, issueChange{RemoveLabels: []string{mergeQueuedLabel}}); err != nil {
+		note("warning: failed to remove " + mergeQueuedLabel + " from " + issue + ": " + err.Error())
+	}
+	if err := finalizeMergedIssue(root, cfg, issue, prNumber, "merge-queue", state.MergeSHA, keepBranch); err != nil {
+		return err
+	}
+	note(fmt.Sprintf("Merge queue landed PR #%s for %s at %s", prNumber, issue, shortSHA(state.MergeSHA)))
+	return nil
+}
+
+func rejectQueuedIssue(root, issue, prNumber string, state mergeQueueState) error {
+	removeApprovalRecord(root, issue)
+	if err := tracker.Update(issue, issueChange{Status: "in_progress", RemoveLabels: []string{mergeQueuedLabel}}); err != nil {
+		return err
+	}
+	if err := tracker.Comment(issue, formatMergeQueueRejectionComment(prNumber, state)); err != nil {
+		return err
+	}
+	if err := writeDaemonFocusIssue(root, issue); err != nil {
+		note("warning: failed to persist daemon focus issue: " + err.Error())
+	}
+	note(fmt.Sprintf("Merge queue rejected PR #%s; %s is back in progress", prNumber, issue))
+	return nil
+}
+
+func formatMergeQueueRejectionComment(prNumber string, state mergeQueueState) string {
+	reason := "it left the merge queue without merging (failed checks or a conflict with queued changes)"
+	if state.PRState == "CLOSED" {
+		reason = "it was closed without merging"
+	}
+	return fmt.Sprintf("yoke: merge queue rejected PR #%s: %s. Returning the issue to in_progress; fix the branch and resubmit with yoke submit.", prNumber, reason)
+}
+
+func formatMergeQueuePosition(state mergeQueueState) string {
+	if !state.Queued {
+		return "waiting to enter the merge queue (auto-merge enabled)"
+	}
+	return fmt.Sprintf("queued at position %d (%s)", state.Position, strings.ToLower(valueOrFallback(state.EntryState, "unknown")))
+}
+
+// advanceMergeQueue closes issues whose queued PRs landed and reopens the
+// writer loop for PRs the queue rejected.
+func advanceMergeQueue(root string, cfg config) {
+	if !commandExists("gh") || !hasOriginRemote() {
+		return
+	}
+	issues, err := tracker.List(issueQuery{Status: "blocked", Label: mergeQueuedLabel})
+	if err != nil {
+		return
+	}
+	for _, issue := range issues {
+		pr, ok, err := lookupPRForBranch(branchForIssue(issue.ID), "all")
+		if err != nil {
+			note("warning: PR lookup for " + issue.ID + " failed: " + err.Error())
+			continue
+		}
+		if !ok {
+			continue
+		}
+		prNumber := strconv.Itoa(pr.Number)
+		state, err := fetchMergeQueueState(prNumber)
+		if err != nil {
+			note("warning: " + err.Error())
+			continue
+		}
+		switch mergeQueueOutcome(state) {
+		case "landed":
+			err = landQueuedIssue(root, cfg, issue.ID, prNumber, state, false)
+		case "rejected":
+			err = rejectQueuedIssue(root, issue.ID, prNumber, state)
+		}
+		if err != nil {
+			note("warning: merge queue update for " + issue.ID + " failed: " + err.Error())
+		}
+	}
+}
+
+// followMergeQueue reports (or, with --wait, follows) a queued issue until
+// its PR lands or is rejected.
+func followMergeQueue(root string, cfg config, issue string, wait bool, timeout time.Duration, keepBranch bool) error {
+	pr, ok, err := lookupPRForBranch(branchForIssue(issue), "all")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no PR found for branch %s", branchForIssue(issue))
+	}
+	prNumber := strconv.Itoa(pr.Number)
+	state, err := fetchMergeQueueState(prNumber)
+	if err != nil {
+		return err
+	}
+	if wait && mergeQueueOutcome(state) == "queued" {
+		note(fmt.Sprintf("Waiting up to %s for PR #%s to land from the merge queue", timeout, prNumber))
+		opts := defaultPollOptions
+		opts.Timeout = timeout
+		err := waitForCondition(context.Background(), "PR #"+prNumber+" merge queue", opts, func(context.Context) (bool, error) {
+			next, err := fetchMergeQueueState(prNumber)
+			if err != nil {
+				return false, nil
+			}
+			state = next
+			return mergeQueueOutcome(state) != "queued", nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	switch mergeQueueOutcome(state) {
+	case "landed":
+		return landQueuedIssue(root, cfg, issue, prNumber, state, keepBranch)
+	case "rejected":
+		if err := rejectQueuedIssue(root, issue, prNumber, state); err != nil {
+			return err
+		}
+		return fmt.Errorf("merge queue rejected PR #%s for %s", prNumber, issue)
+	default:
+		note(fmt.Sprintf("PR #%s for %s is %s; re-run with --wait to follow it", prNumber, issue, formatMergeQueuePosition(state)))
+		return nil
+	}
+}
+
+func formatMergeComment(prNumber, strategy, mergeSHA, branch, branchAction string) string {
+	lines := []string{
+		"PR merged:",
+		"- PR: #" + sanitizeCommentLine(prNumber),
+		"- Strategy: " + sanitizeCommentLine(strategy),
+		"- Merge commit: `" + sanitizeCommentLine(valueOrFallback(mergeSHA, "unknown")) + "`",
+		"- Branch: `" + sanitizeCommentLine(branch) + "` (" + sanitizeCommentLine(branchAction) + ")",
+	}
+	return strings.Join(lines, "\n")
+}
+
+func cmdCleanup(args []string) error {
+	var (
+		dryRun          bool
+		reportRetention time.Duration
+	)
+	parsed, err := parseCommandArgs("cleanup", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printCleanupUsage()
+		return nil
+	}
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown cleanup argument: %s", parsed.Positionals[0])
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--dry-run":
+			dryRun = true
+		case "--reports-older-than":
+			retention, err := parseRetention(flag.Value)
+			if err != nil {
+				return err
+			}
+			reportRetention = retention
+		}
+	}
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+	if err := useIssueTracker(cfg); err != nil {
+		return err
+	}
+
+	action := "Removing"
+	if dryRun {
+		action = "Would remove"
+	}
+
+	pruned := 0
+	for _, branch := range yokeBranches(root) {
+		issue := strings.TrimPrefix(branch, "yoke/")
+		details, err := issueDetails(issue)
+		if err != nil || !strings.EqualFold(details.Status, "closed") {
+			continue
+		}
+		if !branchMergedForCleanup(root, cfg, branch) {
+			continue
+		}
+
+		pruned++
+		worktree := worktreePathForBranch(root, branch)
+		remote := hasOriginRemote() && strings.TrimSpace(commandCombinedOutput("git", "-C", root, "ls-remote", "--heads", "origin", branch)) != ""
+		note(fmt.Sprintf("%s %s (worktree: %s, remote: %t)", action, branch, valueOrFallback(worktree, "none"), remote))
+		if dryRun {
+			continue
+		}
+		if err := removeIssueWorktree(root, branch); err != nil {
+			note("warning: " + err.Error())
+			continue
+		}
+		if err := runCommand("git", "-C", root, "branch", "-D", branch); err != nil {
+			note("warning: failed to delete local branch " + branch + ": " + err.Error())
+		}
+		if remote {
+			if err := runCommand("git", "-C", root, "push", "origin", "--delete", branch); err != nil {
+				note("warning: failed to delete remote branch " + branch + ": " + err.Error())
+			}
+		}
+	}
+	if !dryRun {
+		_ = runCommand("git", "-C", root, "worktree", "prune")
+	}
+
+	removedReports := 0
+	if reportRetention > 0 {
+		reportsRoot := filepath.Join(root, ".yoke", "epic-improvement-reports")
+		stale, err := staleReportDirs(reportsRoot, time.Now().Add(-reportRetention))
+		if err != nil {
+			return err
+		}
+		for _, dir := range stale {
+			note(fmt.Sprintf("%s report directory %s", action, dir))
+			if dryRun {
+				continue
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				return err
+			}
+		}
+		removedReports = len(stale)
+	}
+
+	summary := "Cleanup"
+	if dryRun {
+		summary = "Cleanup dry run"
+	}
+	note(fmt.Sprintf("%s: %d branch(es), %d report director(ies).", summary, pruned, removedReports))
+	return nil
+}
+
+// branchMergedForCleanup prefers GitHub's merged-PR record (squash and rebase
+// merges are not ancestors of the base branch) and falls back to git ancestry.
+func branchMergedForCleanup(root string, cfg config, branch string) bool {
+	if _, ok, err := lookupPRForBranch(branch, "merged"); ok {
+		return true
+	} else if err != nil {
+		note("warning: PR lookup for " + branch + " failed: " + err.Error())
+	}
+	base := localOrRemoteRef(cfg.BaseBranch)
+	if base == "" {
+		return false
+	}
+	_, err := commandOutput("git", "-C", root, "merge-base", "--is-ancestor", branch, base)
+	return err == nil
+}
+
+// parseRetention accepts Go durations plus a day suffix (30d).
+func parseRetention(raw string) (time.Duration, error) {
+	trimmed := strings.TrimSpace(raw)
+	if strings.HasSuffix(trimmed, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(trimmed, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid retention %q", raw)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	duration, err := time.ParseDuration(trimmed)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid retention %q", raw)
+	}
+	return duration, nil
+}
+
+func staleReportDirs(reportsRoot string, cutoff time.Time) ([]string, error) {
+	entries, err := os.ReadDir(reportsRoot)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	stale := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		if info.ModTime().Before(cutoff) {
+			stale = append(stale, filepath.Join(reportsRoot, entry.Name()))
+		}
+	}
+	return stale, nil
+}
+
+const onboardBranch = "yoke-onboard"
+
+// repoProfile is what yoke onboard learned about a repository.
+type repoProfile struct {
+	BuildSystems []string
+	CheckCmds    []string
+	CIConfigs    []string
+	Guidelines   []string
+}
+
+type onboardFile struct {
+	Path    string
+	Content string
+	Mode    os.FileMode
+}
+
+func analyzeRepository(root string) repoProfile {
+	profile := repoProfile{}
+	exists := func(rel string) bool { return fileExists(filepath.Join(root, rel)) }
+
+	if exists("go.mod") {
+		profile.BuildSystems = append(profile.BuildSystems, "go")
+		profile.CheckCmds = append(profile.CheckCmds, "go build ./...", "go vet ./...", "go test ./...")
+	}
+	if exists("package.json") {
+		profile.BuildSystems = append(profile.BuildSystems, "npm")
+		scripts := packageJSONScripts(filepath.Join(root, "package.json"))
+		for _, script := range []string{"lint", "typecheck", "test"} {
+			if _, ok := scripts[script]; ok {
+				profile.CheckCmds = append(profile.CheckCmds, "npm run "+script)
+			}
+		}
+	}
+	if exists("Cargo.toml") {
+		profile.BuildSystems = append(profile.BuildSystems, "cargo")
+		profile.CheckCmds = append(profile.CheckCmds, "cargo build", "cargo test")
+	}
+	if exists("pyproject.toml") || exists("setup.py") {
+		profile.BuildSystems = append(profile.BuildSystems, "python")
+		profile.CheckCmds = append(profile.CheckCmds, "python -m pytest")
+	}
+	if exists("Makefile") {
+		profile.BuildSystems = append(profile.BuildSystems, "make")
+		targets := makefileTargets(filepath.Join(root, "Makefile"))
+		for _, target := range []string{"check", "test"} {
+			if _, ok := targets[target]; ok {
+				// A make check/test target already encodes the project's gate.
+				profile.CheckCmds = []string{"make " + target}
+				break
+			}
+		}
+	}
+
+	workflows, _ := filepath.Glob(filepath.Join(root, ".github", "workflows", "*.y*ml"))
+	for _, workflow := range workflows {
+		rel, _ := filepath.Rel(root, workflow)
+		profile.CIConfigs = append(profile.CIConfigs, filepath.ToSlash(rel))
+	}
+	for _, rel := range []string{".gitlab-ci.yml", ".circleci/config.yml", "Jenkinsfile", "azure-pipelines.yml"} {
+		if exists(rel) {
+			profile.CIConfigs = append(profile.CIConfigs, rel)
+		}
+	}
+	for _, rel := range []string{"CONTRIBUTING.md", "docs/CONTRIBUTING.md", ".github/CONTRIBUTING.md", "AGENTS.md", "CLAUDE.md", "CODE_OF_CONDUCT.md", ".editorconfig"} {
+		if exists(rel) {
+			profile.Guidelines = append(profile.Guidelines, rel)
+		}
+	}
+	return profile
+}
+
+func packageJSONScripts(path string) map[string]string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var parsed struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil
+	}
+	return parsed.Scripts
+}
+
+var makeTargetPattern = regexp.MustCompile(`^([A-Za-z0-9_.-]+):`)
+
+func makefileTargets(path string) map[string]struct{} {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	targets := map[string]struct{}{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if matches := makeTargetPattern.FindStringSubmatch(line); len(matches) == 2 {
+			targets[matches[1]] = struct{}{}
+		}
+	}
+	return targets
+}
+
+func renderOnboardChecks(profile repoProfile) string {
+	lines := []string{
+		"#!/usr/bin/env bash",
+		"set -euo pipefail",
+		"",
+		`ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"`,
+		`cd "$ROOT_DIR"`,
+		"",
+	}
+	if len(profile.CheckCmds) == 0 {
+		lines = append(lines, `echo "yoke onboard found no build or test commands. Edit .yoke/checks.sh."`, "exit 1")
+	} else {
+		lines = append(lines, profile.CheckCmds...)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func renderOnboardConventions(profile repoProfile) string {
+	list := func(items []string, empty string) []string {
+		if len(items) == 0 {
+			return []string{"- " + empty}
+		}
+		out := make([]string, 0, len(items))
+		for _, item := range items {
+			out = append(out, "- `"+item+"`")
+		}
+		return out
+	}
+	lines := []string{"# Repository conventions", "", "Generated by `yoke onboard`; edit freely.", "", "## Build systems"}
+	lines = append(lines, list(profile.BuildSystems, "none detected")...)
+	lines = append(lines, "", "## Checks (run by `yoke submit` via `.yoke/checks.sh`)")
+	lines = append(lines, list(profile.CheckCmds, "none detected; edit `.yoke/checks.sh`")...)
+	lines = append(lines, "", "## CI configuration")
+	lines = append(lines, list(profile.CIConfigs, "none detected")...)
+	lines = append(lines, "", "## Contribution guidelines", "", "Writers and reviewers must read and follow:")
+	lines = append(lines, list(profile.Guidelines, "none found; follow the style of neighbouring code")...)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func renderOnboardPolicy(profile repoProfile) string {
+	lines := []string{
+		"# Agent policy",
+		"",
+		"Generated by `yoke onboard`; edit freely.",
+		"",
+		"- Work only on the claimed issue, on its `yoke/<issue>` branch.",
+		"- `.yoke/checks.sh` must pass before `yoke submit`.",
+		"- Do not weaken or delete existing tests to make checks pass.",
+		"- Do not add dependencies without saying so in the handoff `--decision`.",
+	}
+	for _, ci := range profile.CIConfigs {
+		lines = append(lines, "- Do not modify `"+ci+"` unless the issue asks for it.")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// suggestedAgentCommand returns a daemon command for the agent that points it
+// at the generated conventions and policy.
+func suggestedAgentCommand(agentID, role string) string {
+	var task string
+	if role == "writer" {
+		task = "Implement $ISSUE_ID in $ROOT_DIR following .yoke/conventions.md and .yoke/policy.md, then run yoke submit $ISSUE_ID with --done and --remaining."
+	} else {
+		task = "Review $ISSUE_ID in $ROOT_DIR against .yoke/conventions.md and .yoke/policy.md; the change is in $YOKE_REVIEW_DIFF_FILE. Do not approve or reject it yourself. $YOKE_REVIEW_VERDICT_SCHEMA"
+	}
+	switch agentID {
+	case "codex":
+		return "codex exec --full-auto " + strconv.Quote(task)
+	case "claude":
+		return "claude --print --permission-mode bypassPermissions " + strconv.Quote(task)
+	default:
+		return ""
+	}
+}
+
+func onboardFiles(profile repoProfile) []onboardFile {
+	return []onboardFile{
+		{Path: ".yoke/checks.sh", Content: renderOnboardChecks(profile), Mode: 0o755},
+		{Path: ".yoke/conventions.md", Content: renderOnboardConventions(profile), Mode: 0o644},
+		{Path: ".yoke/policy.md", Content: renderOnboardPolicy(profile), Mode: 0o644},
+	}
+}
+
+func cmdOnboard(args []string) error {
+	parsed, err := parseCommandArgs("onboard", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printOnboardUsage()
+		return nil
+	}
+	if len(parsed.Positionals) > 0 {
+		return fmt.Errorf("unknown onboard argument: %s", parsed.Positionals[0])
+	}
+	var dryRun, noPR bool
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--dry-run":
+			dryRun = true
+		case "--no-pr":
+			noPR = true
+		}
+	}
+
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(root)
+	if err != nil {
+		return err
+	}
+
+	profile := analyzeRepository(root)
+	note("build_systems: " + valueOrFallback(strings.Join(profile.BuildSystems, ", "), "none"))
+	note("checks: " + valueOrFallback(strings.Join(profile.CheckCmds, " && "), "none"))
+	note("ci: " + valueOrFallback(strings.Join(profile.CIConfigs, ", "), "none"))
+	note("guidelines: " + valueOrFallback(strings.Join(profile.Guidelines, ", "), "none"))
+
+	if strings.TrimSpace(cfg.WriterCmd) == "" {
+		cfg.WriterCmd = suggestedAgentCommand(cfg.WriterAgent, "writer")
+	}
+	if strings.TrimSpace(cfg.ReviewCmd) == "" {
+		cfg.ReviewCmd = suggestedAgentCommand(cfg.ReviewerAgent, "reviewer")
+	}
+	cfg.CheckCmd = defaultCheckCmd
+	files := onboardFiles(profile)
+
+	if dryRun {
+		for _, file := range files {
+			fmt.Printf("--- %s\n%s", file.Path, file.Content)
+		}
+		fmt.Printf("--- .yoke/config.sh\n%s", renderConfig(cfg))
+		return nil
+	}
+
+	target := root
+	if !noPR {
+		if refExists("refs/heads/" + onboardBranch) {
+			return fmt.Errorf("branch %s already exists; merge or delete it before onboarding again", onboardBranch)
+		}
+		base := localOrRemoteRef(cfg.BaseBranch)
+		if base == "" {
+			return fmt.Errorf("base branch %s not found locally or on origin", cfg.BaseBranch)
+		}
+		target = filepath.Join(root, ".yoke", "worktrees", onboardBranch)
+		if err := runCommand("git", "-C", root, "worktree", "add", "-b", onboardBranch, target, base); err != nil {
+			return err
+		}
+		defer func() {
+			_ = runCommand("git", "-C", root, "worktree", "remove", "--force", target)
+		}()
+	}
+
+	for _, file := range files {
+		path := filepath.Join(target, file.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(file.Content), file.Mode); err != nil {
+			return err
+		}
+		note("Wrote " + file.Path)
+	}
+	cfg.Path = filepath.Join(target, ".yoke", "config.sh")
+	if err := writeConfig(cfg); err != nil {
+		return err
+	}
+	note("Wrote .yoke/config.sh")
+
+	if noPR {
+		note("Onboarding files written to the working tree; review and commit them.")
+		return nil
+	}
+
+	paths := []string{"add", ".yoke/config.sh"}
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	if err := runCommand("git", append([]string{"-C", target}, paths...)...); err != nil {
+		return err
+	}
+	if err := runCommand("git", append([]string{"-C", target, "commit"}, append(gitCommitSignFlags(cfg), "-m", "Add yoke onboarding setup")...)...); err != nil {
+		return err
+	}
+	var client *githubClient
+	if hasOriginRemote() {
+		client, err = githubAPI()
+	}
+	if client == nil {
+		note("Committed onboarding setup on branch " + onboardBranch + "; push it and open a PR to review.")
+		return nil
+	}
+	if err := runCommand("git", "-C", target, "push", "-u", "origin", onboardBranch); err != nil {
+		return err
+	}
+	body := "Generated by `yoke onboard`.\n\n" + renderOnboardConventions(profile)
+	title := "Add yoke onboarding setup"
+	pull, err := client.createPull(title, onboardBranch, cfg.BaseBranch, body, true)
+	auditGitHubAPI("", http.MethodPost, githubRepoPath(client, "/pulls"), strconv.Itoa(pull.Number), err, "head="+onboardBranch, "base="+cfg.BaseBranch, "title="+title, body)
+	if err != nil {
+		return fmt.Errorf("create onboarding PR: %w", err)
+	}
+	note(fmt.Sprintf("Opened onboarding PR #%d from %s: %s", pull.Number, onboardBranch, pull.HTMLURL))
+	return nil
+}
+
+// Project templates: yoke new copies a template into a fresh repository, wires
+// up .yoke and bd, and files a starter epic. Named templates live under
+// YOKE_TEMPLATES_DIR (default: <user config dir>/yoke/templates); a template
+// can also be a local directory or a git URL.
+
+const blankTemplate = "blank"
+
+type projectTemplate struct {
+	Name   string
+	Source string
+	Git    bool
+}
+
+func templatesDir() string {
+	if dir := strings.TrimSpace(os.Getenv("YOKE_TEMPLATES_DIR")); dir != "" {
+		return dir
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(base, "yoke", "templates")
+}
+
+func looksLikeGitURL(value string) bool {
+	return strings.Contains(value, "://") || strings.HasPrefix(value, "git@") || strings.HasSuffix(value, ".git")
+}
+
+func resolveProjectTemplate(name, dir string) (projectTemplate, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return projectTemplate{}, errors.New("template name is required")
+	case name == blankTemplate:
+		return projectTemplate{Name: name}, nil
+	case looksLikeGitURL(name):
+		return projectTemplate{Name: name, Source: name, Git: true}, nil
+	}
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		return projectTemplate{Name: filepath.Base(filepath.Clean(name)), Source: name}, nil
+	}
+	if dir != "" {
+		candidate := filepath.Join(dir, sanitizePathSegment(name))
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return projectTemplate{Name: name, Source: candidate}, nil
+		}
+	}
+	return projectTemplate{}, fmt.Errorf("template %q not found (looked for a directory, a git URL, and %s)", name, valueOrFallback(dir, "YOKE_TEMPLATES_DIR"))
+}
+
+// copyTemplateTree copies a template directory, leaving out its git metadata
+// so the new project starts with fresh history.
+func copyTemplateTree(source, target string) error {
+	return filepath.WalkDir(source, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		if entry.Name() == ".git" && rel != "." {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		dest := filepath.Join(target, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(dest, 0o755)
+		}
+		if entry.Type()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, dest)
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if err := copyFile(path, dest); err != nil {
+			return err
+		}
+		return os.Chmod(dest, info.Mode().Perm())
+	})
+}
+
+func materializeProjectTemplate(tmpl projectTemplate, target string) error {
+	switch {
+	case tmpl.Source == "":
+		return os.MkdirAll(target, 0o755)
+	case tmpl.Git:
+		if err := runCommand("git", "clone", "--depth", "1", tmpl.Source, target); err != nil {
+			return fmt.Errorf("clone template: %w", err)
+		}
+		return os.RemoveAll(filepath.Join(target, ".git"))
+	default:
+		return copyTemplateTree(tmpl.Source, target)
+	}
+}
+
+func starterEpicDescription(project, template string) string {
+	return fmt.Sprintf("Starter epic for %s (created by yoke new from template %s).\n\n"+
+		"Describe the goal of the project here, then break it into child tasks. "+
+		"Claiming this epic runs the improvement cycle that refines it into claimable work.", project, template)
+}
+
+func cmdNew(args []string) error {
+	var (
+		templateName string
+		directory    string
+		epicTitle    string
+		bdPrefix     string
+		noBD         bool
+	)
+	parsed, err := parseCommandArgs("new", args)
+	if err != nil {
+		return err
+	}
+	if parsed.Help {
+		printNewUsage()
+		return nil
+	}
+	for _, flag := range parsed.Flags {
+		switch flag.Name {
+		case "--epic":
+			epicTitle = strings.TrimSpace(flag.Value)
+		case "--bd-prefix":
+			normalized, err := normalizeBDPrefix(flag.Value)
+			if err != nil {
+				return err
+			}
+			bdPrefix = normalized
+		case "--no-bd":
+			noBD = true
+		}
+	}
+	for _, arg := range parsed.Positionals {
+		switch {
+		case templateName == "":
+			templateName = arg
+		case directory == "":
+			directory = arg
+		default:
+			return fmt.Errorf("unexpected new argument: %s", arg)
+		}
+	}
+	if templateName == "" || directory == "" {
+		return errors.New("usage: yoke new <template> <directory> [options]")
+	}
+	if !noBD && !commandExists("bd") {
+		return errors.New("bd is required to initialize the new project (use --no-bd to skip)")
+	}
+
+	target, err := filepath.Abs(directory)
+	if err != nil {
+		return err
+	}
+	if entries, err := os.ReadDir(target); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s already exists and is not empty", directory)
+	}
+
+	tmpl, err := resolveProjectTemplate(templateName, templatesDir())
+	if err != nil {
+		return err
+	}
+	if err := materializeProjectTemplate(tmpl, target); err != nil {
+		return err
+	}
+	note("Created " + target + " from template " + tmpl.Name)
+
+	if err := os.Chdir(target); err != nil {
+		return err
+	}
+	cfg, err := loadConfig(target)
+	if err != nil {
+		return err
+	}
+	if err := runCommand("git", "init", "--initial-branch", cfg.BaseBranch); err != nil {
+		return err
+	}
+
+	if bdPrefix != "" {
+		cfg.BDPrefix = bdPrefix
+	}
+	agents := detectAvailableAgents()
+	if cfg.WriterAgent == "" && len(agents) > 0 {
+		cfg.WriterAgent = agents[0].ID
+	}
+	if cfg.ReviewerAgent == "" {
+		cfg.ReviewerAgent = cfg.WriterAgent
+	}
+	if strings.TrimSpace(cfg.WriterCmd) == "" {
+		cfg.WriterCmd = suggestedAgentCommand(cfg.WriterAgent, "writer")
+	}
+	if strings.TrimSpace(cfg.ReviewCmd) == "" {
+		cfg.ReviewCmd = suggestedAgentCommand(cfg.ReviewerAgent, "reviewer")
+	}
+	if err := os.MkdirAll(filepath.Join(target, ".yoke", "prompts"), 0o755); err != nil {
+		return err
+	}
+	if err := writeConfig(cfg); err != nil {
+		return err
+	}
+	checksPath := filepath.Join(target, ".yoke", "checks.sh")
+	if !fileExists(checksPath) {
+		content := defaultChecksScript
+		if profile := analyzeRepository(target); len(profile.CheckCmds) > 0 {
+			content = renderOnboardChecks(profile)
+		}
+		if err := os.WriteFile(checksPath, []byte(content), 0o755); err != nil {
+			return err
+		}
+	}
+
+	epicID := ""
+	if !noBD {
+		if !fileExists(filepath.Join(target, ".beads")) {
+			if err := runCommand("bd", "init", "--prefix", cfg.BDPrefix); err != nil {
+				return err
+			}
+		}
+		if epicTitle == "" {
+			epicTitle = "Build " + filepath.Base(target)
+		}
+		epic, err := tracker.Create(newIssue{Title: epicTitle, Type: "epic", Description: starterEpicDescription(filepath.Base(target), tmpl.Name)})
+		if err != nil {
+			return fmt.Errorf("create starter epic: %w", err)
+		}
+		epicID = epic.ID
+		note("Created starter epic " + epicID + ": " + epicTitle)
+	}
+
+	if err := runCommand("git", "add", "-A"); err != nil {
+		return err
+	}
+	if err := runCommand("git", append([]string{"commit", "--quiet"}, append(gitCommitSignFlags(cfg), "-m", "Initial commit from yoke template "+tmpl.Name)...)...); err != nil {
+		return err
+	}
+
+	note("Writer agent: " + valueOrUnset(cfg.WriterAgent))
+	note("Reviewer agent: " + valueOrUnset(cfg.ReviewerAgent))
+	if cfg.WriterCmd == "" || cfg.ReviewCmd == "" {
+		note("No writer/reviewer commands configured. Set YOKE_WRITER_CMD and YOKE_REVIEW_CMD in .yoke/config.sh before running the daemon.")
+	}
+	fmt.Println("Next steps:")
+	fmt.Println("  cd " + directory)
+	if epicID != "" {
+		fmt.Println("  yoke claim " + epicID)
+	}
+	fmt.Println("  yoke daemon")
+	return nil
+}
+
+// Overlap detection: parallel writers on branches that touch the same files
+// produce merge-conflict churn. Before a writer runs, yoke compares the files
+// changed on its branch with every other active yoke branch.
+
+// branchOverlap is another active issue whose branch shares changed files.
+type branchOverlap struct {
+	Issue  string
+	Status string
+	Files  []string
+}
+
+func parseOverlapMode(raw string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(raw)); mode {
+	case "":
+		return defaultOverlap, nil
+	case "off", "warn", "serialize":
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown overlap mode %q (want off, warn, or serialize)", raw)
+	}
+}
+
+// branchChangedFiles lists files touched by commits that exist only on the
+// issue branch, excluding the base branch and the epic or stack parent branch
+// it was started from.
+func branchChangedFiles(root string, cfg config, issue string) []string {
+	branch := branchForIssue(issue)
+	if !refExists("refs/heads/" + branch) {
+		return nil
+	}
+	args := []string{"-C", root, "log", "--format=", "--name-only", branch, "--not"}
+	excluded := 0
+	for _, start := range []string{cfg.BaseBranch, stackOrEpicBranch(root, issue)} {
+		if ref := localOrRemoteRef(start); ref != "" {
+			args = append(args, ref)
+			excluded++
+		}
+	}
+	if excluded == 0 {
+		return nil
+	}
+	output, err := commandOutput("git", args...)
+	if err != nil {
+		return nil
+	}
+	seen := map[string]struct{}{}
+	files := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		file := strings.TrimSpace(line)
+		if file == "" {
+			continue
+		}
+		if _, ok := seen[file]; ok {
+			continue
+		}
+		seen[file] = struct{}{}
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func stackOrEpicBranch(root, issue string) string {
+	if parent := readStackParent(root, issue); parent != "" {
+		return branchForIssue(parent)
+	}
+	epicID, err := epicAncestorID(issue)
+	if err != nil || epicID == "" || strings.EqualFold(epicID, issue) {
+		return ""
+	}
+	return branchForIssue(epicID)
+}
+
+func sharedFiles(mine, theirs []string) []string {
+	set := make(map[string]struct{}, len(mine))
+	for _, file := range mine {
+		set[file] = struct{}{}
+	}
+	shared := []string{}
+	for _, file := range theirs {
+		if _, ok := set[file]; ok {
+			shared = append(shared, file)
+		}
+	}
+	sort.Strings(shared)
+	return shared
+}
+
+func detectBranchOverlaps(root string, cfg config, issue string) []branchOverlap {
+	mine := branchChangedFiles(root, cfg, issue)
+	if len(mine) == 0 {
+		return nil
+	}
+	overlaps := []branchOverlap{}
+	for _, branch := range yokeBranches(root) {
+		peer := strings.TrimPrefix(branch, "yoke/")
+		if strings.EqualFold(peer, issue) || !looksLikeIssueIDAnyPrefix(peer) {
+			continue
+		}
+		shared := sharedFiles(mine, branchChangedFiles(root, cfg, peer))
+		if len(shared) == 0 {
+			continue
+		}
+		details, err := issueDetails(peer)
+		if err != nil || strings.EqualFold(details.IssueType, "epic") {
+			continue
+		}
+		status := workflowStatusForIssue(details)
+		if status != "in_progress" && status != "in_review" {
+			continue
+		}
+		overlaps = append(overlaps, branchOverlap{Issue: peer, Status: status, Files: shared})
+	}
+	return overlaps
+}
+
+func formatBranchOverlap(issue string, overlap branchOverlap) string {
+	return fmt.Sprintf("%s overlaps %s (%s) on %d file(s): %s", issue, overlap.Issue, overlap.Status, len(overlap.Files), summarizeIDs(overlap.Files, 5))
+}
+
+// serializeOnOverlaps returns the in-review overlaps a writer must wait for.
+// In-progress peers only warn: making two writers wait on each other would
+// stall both.
+func serializeOnOverlaps(overlaps []branchOverlap) []string {
+	waitFor := []string{}
+	for _, overlap := range overlaps {
+		if overlap.Status == "in_review" {
+			waitFor = append(waitFor, overlap.Issue)
+		}
+	}
+	return waitFor
+}
+
+// checkWriterOverlaps warns about overlapping branches and, in serialize mode,
+// returns a reason to hold the writer back.
+func checkWriterOverlaps(root string, cfg config, issue string) string {
+	if cfg.OverlapMode == "off" {
+		return ""
+	}
+	overlaps := detectBranchOverlaps(root, cfg, issue)
+	for _, overlap := range overlaps {
+		note("warning: " + formatBranchOverlap(issue, overlap))
+	}
+	if cfg.OverlapMode != "serialize" {
+		return ""
+	}
+	if waitFor := serializeOnOverlaps(overlaps); len(waitFor) > 0 {
+		return fmt.Sprintf("%s touches the same files as in-review %s; holding the writer until it lands (YOKE_OVERLAP_MODE=serialize)", issue, strings.Join(waitFor, ", "))
+	}
+	return ""
+}
+
+// Stacked PRs: a task whose blocker is still in review can be claimed with
+// --stack, branching off the blocker's branch. The child -> parent mapping is
+// kept in the shared state dir so every worktree sees it; PR bases follow the
+// parent until it merges, then move down the stack.
+
+func stacksPath(root string) string {
+	return filepath.Join(yokeSharedStateDir(root), "stacks.json")
+}
+
+func loadStacks(root string) map[string]string {
+	stacks := map[string]string{}
+	data, err := os.ReadFile(stacksPath(root))
+	if err != nil {
+		return stacks
+	}
+	if err := json.Unmarshal(data, &stacks); err != nil {
+		return map[string]string{}
+	}
+	return stacks
+}
+
+func saveStacks(root string, stacks map[string]string) error {
+	if dryRunMode {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(stacksPath(root)), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(stacks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(stacksPath(root), append(data, '\n'), 0o644)
+}
+
+func readStackParent(root, issue string) string {
+	return loadStacks(root)[issue]
+}
+
+func writeStackParent(root, issue, parent string) error {
+	stacks := loadStacks(root)
+	if parent == "" {
+		delete(stacks, issue)
+	} else {
+		stacks[issue] = parent
+	}
+	return saveStacks(root, stacks)
+}
+
+func stackChildren(stacks map[string]string, parent string) []string {
+	children := []string{}
+	for child, candidate := range stacks {
+		if strings.EqualFold(candidate, parent) {
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+func blockingDependencies(issueID string) ([]bdListIssue, error) {
+	return tracker.Blockers(issueID)
+}
+
+// pickStackParent chooses the blocker to stack on: the single blocker that is
+// in review (or approved but unmerged) and has a branch. Other unresolved
+// blockers make stacking impossible.
+func pickStackParent(issue string, blockers []bdListIssue, stackable func(bdListIssue) bool) (string, error) {
+	parent := ""
+	for _, blocker := range blockers {
+		status := workflowStatusForIssue(blocker)
+		if stackable(blocker) {
+			if parent != "" {
+				return "", fmt.Errorf("%s has more than one unmerged blocker (%s, %s); stacks support a single parent", issue, parent, blocker.ID)
+			}
+			parent = blocker.ID
+			continue
+		}
+		if status != "closed" {
+			return "", fmt.Errorf("%s is blocked by %s (%s); only in-review blockers with a branch can be stacked on", issue, blocker.ID, status)
+		}
+	}
+	return parent, nil
+}
+
+func stackParentForClaim(issue string) (string, error) {
+	blockers, err := blockingDependencies(issue)
+	if err != nil {
+		return "", err
+	}
+	var lookupErr error
+	parent, err := pickStackParent(issue, blockers, func(blocker bdListIssue) bool {
+		switch workflowStatusForIssue(blocker) {
+		case "in_review":
+			return refExists("refs/heads/"+branchForIssue(blocker.ID)) || refExists("refs/remotes/origin/"+branchForIssue(blocker.ID))
+		case "closed":
+			_, _, _, ok, err := openPRForIssue(blocker.ID)
+			if err != nil && lookupErr == nil {
+				lookupErr = err
+			}
+			return ok
+		default:
+			return false
+		}
+	})
+	if lookupErr != nil {
+		return "", lookupErr
+	}
+	return parent, err
+}
+
+// restackChildren moves PRs stacked on a merged parent down to the parent's
+// own base and re-points the stack records.
+func restackChildren(root string, cfg config, merged string) {
+	stacks := loadStacks(root)
+	children := stackChildren(stacks, merged)
+	if len(children) == 0 {
+		return
+	}
+	grandparent := stacks[merged]
+	delete(stacks, merged)
+	for _, child := range children {
+		if grandparent == "" {
+			delete(stacks, child)
+		} else {
+			stacks[child] = grandparent
+		}
+	}
+	if err := saveStacks(root, stacks); err != nil {
+		note("warning: failed to update stack records: " + err.Error())
+		return
+	}
+
+	for _, child := range children {
+		prNumber, _, _, ok, err := openPRForIssue(child)
+		if err != nil {
+			note("warning: not restacking " + child + ": " + err.Error())
+			continue
+		}
+		if !ok {
+			continue
+		}
+		base, err := issuePRBaseBranch(root, cfg, child)
+		if err != nil {
+			note("warning: failed to resolve new PR base for " + child + ": " + err.Error())
+			continue
+		}
+		if err := runCommand("gh", "pr", "edit", prNumber, "--base", base); err != nil {
+			note("warning: failed to move PR #" + prNumber + " to base " + base + ": " + err.Error())
+			continue
+		}
+		note(fmt.Sprintf("Restacked %s: PR #%s now targets %s", child, prNumber, base))
+	}
+}
+
+// restackMergedParents catches parents merged outside yoke merge.
+func restackMergedParents(root string, cfg config) {
+	if !commandExists("gh") || !hasOriginRemote() {
+		return
+	}
+	parents := map[string]struct{}{}
+	for _, parent := range loadStacks(root) {
+		parents[parent] = struct{}{}
+	}
+	for parent := range parents {
+		if _, merged, _ := lookupPRForBranch(branchForIssue(parent), "merged"); merged {
+			restackChildren(root, cfg, parent)
+		}
+	}
+}
+
+type reviewLock struct {
+	Issue      string `json:"issue"`
+	SHA        string `json:"sha"`
+	Holder     string `json:"holder"`
+	AcquiredAt string `json:"acquired_at"`
+}
+
+// yokeSharedStateDir returns a state directory shared by the main checkout and
+// every issue worktree (inside the git common dir), so locks taken by the
+// daemon are visible to yoke commands run from worktrees.
+func yokeSharedStateDir(root string) string {
+	out, err := commandOutput("git", "-C", root, "rev-parse", "--git-common-dir")
+	dir := strings.TrimSpace(out)
+	if err != nil || dir == "" {
+		return filepath.Join(root, ".yoke")
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return filepath.Join(dir, "yoke")
+}
+
+func reviewLockPath(root, issue string) string {
+	return filepath.Join(yokeSharedStateDir(root), "review-locks", sanitizePathSegment(strings.ToLower(issue))+".json")
+}
+
+func branchHeadSHA(root, branch string) string {
+	out, err := commandOutput("git", "-C", root, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+func shortSHA(sha string) string {
+	trimmed := strings.TrimSpace(sha)
+	if trimmed == "" {
+		return "unknown commit"
+	}
+	if len(trimmed) > 12 {
+		return trimmed[:12]
+	}
+	return trimmed
+}
+
+func reviewLockHolder() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown-host"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
+
+func readReviewLock(root, issue string) (reviewLock, bool) {
+	data, err := os.ReadFile(reviewLockPath(root, issue))
+	if err != nil {
+		return reviewLock{}, false
+	}
+	var lock reviewLock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return reviewLock{}, false
+	}
+	return lock, true
+}
+
+func reviewLockExpired(lock reviewLock, now time.Time) bool {
+	acquired, err := time.Parse(time.RFC3339, lock.AcquiredAt)
+	if err != nil {
+		return true
+	}
+	return now.Sub(acquired) > reviewLockTTL
+}
+
+// acquireReviewLock labels the issue and records the branch head being
+// reviewed. An existing unexpired lock held by another process is an error.
+func acquireReviewLock(root, issue string) (reviewLock, error) {
+	if existing, ok := readReviewLock(root, issue); ok && !reviewLockExpired(existing, time.Now()) && existing.Holder != reviewLockHolder() {
+		return reviewLock{}, fmt.Errorf("%s is already locked for review by %s since %s", issue, existing.Holder, existing.AcquiredAt)
+	}
+
+	lock := reviewLock{
+		Issue:      issue,
+		SHA:        branchHeadSHA(root, branchForIssue(issue)),
+		Holder:     reviewLockHolder(),
+		AcquiredAt: time.Now().Format(time.RFC3339),
+	}
+	if dryRunMode {
+		return lock, tracker.Update(issue, issueChange{AddLabels: []string{reviewLockLabel}})
+	}
+	path := reviewLockPath(root, issue)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return reviewLock{}, err
+	}
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return reviewLock{}, err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return reviewLock{}, err
+	}
+	if err := tracker.Update(issue, issueChange{AddLabels: []string{reviewLockLabel}}); err != nil {
+		_ = os.Remove(path)
+		return reviewLock{}, err
+	}
+	return lock, nil
+}
+
+func releaseReviewLock(root, issue string) {
+	path := reviewLockPath(root, issue)
+	if !fileExists(path) || dryRunMode {
+		return
+	}
+	_ = os.Remove(path)
+	if err := tracker.Update(issue, issueChange{RemoveLabels: []string{reviewLockLabel}}); err != nil {
+		note("warning: failed to remove review lock label: " + err.Error())
+	}
+}
+
+// ensureNotReviewLocked is honored by writer paths (submit, claim, daemon
+// writer) so no new commits are handed off while a review is in flight.
+func ensureNotReviewLocked(root, issue string) error {
+	lock, ok := readReviewLock(root, issue)
+	if !ok || reviewLockExpired(lock, time.Now()) {
+		return nil
+	}
+	return fmt.Errorf("%s is locked for review by %s at %s; wait for the verdict before changing it", issue, lock.Holder, shortSHA(lock.SHA))
+}
+
+// reviewedCommitSHA returns the commit a verdict applies to. When a review
+// lock exists, the branch must not have moved since the lock was taken.
+func reviewedCommitSHA(root, issue string) (string, error) {
+	current := branchHeadSHA(root, branchForIssue(issue))
+	lock, ok := readReviewLock(root, issue)
+	if !ok || strings.TrimSpace(lock.SHA) == "" {
+		return current, nil
+	}
+	if current != "" && current != lock.SHA {
+		releaseReviewLock(root, issue)
+		return "", fmt.Errorf("branch %s moved during review (%s -> %s); re-run the review", branchForIssue(issue), shortSHA(lock.SHA), shortSHA(current))
+	}
+	return lock.SHA, nil
+}
+
+func loadConfig(root string) (config, error) {
+	path := os.Getenv("YOKE_CONFIG")
+	if path == "" {
+		path = filepath.Join(root, ".yoke", "config.sh")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+
+	cfg := config{
+		BaseBranch:         defaultBaseBranch,
+		CheckCmd:           defaultCheckCmd,
+		BDPrefix:           defaultBDPrefix,
+		IssueBackend:       defaultIssueBackend,
+		WriterAgent:        "",
+		WriterCmd:          "",
+		ReviewerAgent:      "",
+		ReviewCmd:          "",
+		PRTemplate:         defaultPRTemplate,
+		EpicSizeWarning:    defaultEpicSizeWarningLimit,
+		ReviewSample:       100,
+		MaxRejections:      defaultMaxRejections,
+		MergeStrategy:      defaultMerge,
+		SyncStrategy:       defaultSync,
+		OverlapMode:        defaultOverlap,
+		OutputFilters:      mustParseOutputFilters(defaultOutputFilters),
+		EpicPassPlan:       mustParseEpicPassPlan(defaultEpicPassPlan),
+		ReviewDiffMaxBytes: defaultReviewDiffMaxBytes,
+		ReviewDiffExclude:  parseLabelList(defaultReviewDiffExclude),
+		Path:               path,
+		Root:               root,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			agentOutputFilters = cfg.OutputFilters
+			return cfg, nil
+		}
+		return cfg, err
+	}
+
+	var checkList []string
+	checkFields := map[string]map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		matches := assignPattern.FindStringSubmatch(line)
+		if len(matches) != 3 {
+			continue
+		}
+		key := matches[1]
+		value := parseShellValue(matches[2])
+		if name, field, ok := parseCheckConfigKey(key); ok {
+			if checkFields[name] == nil {
+				checkFields[name] = map[string]string{}
+			}
+			checkFields[name][field] = value
+			continue
+		}
+
+		switch key {
+		case "YOKE_BASE_BRANCH":
+			cfg.BaseBranch = value
+		case "YOKE_CHECK_CMD":
+			cfg.CheckCmd = value
+		case "YOKE_CHECKS":
+			checkList = parseLabelList(value)
+		case "YOKE_AFFECTED_CHECKS":
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_AFFECTED_CHECKS value: %q", value)
+			}
+			cfg.AffectedChecks = enabled
+		case "YOKE_COVERAGE_CMD":
+			cfg.CoverageCmd = strings.TrimSpace(value)
+		case "YOKE_COVERAGE_MIN":
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			minimum, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+			if err != nil || minimum < 0 || minimum > 100 {
+				return cfg, fmt.Errorf("invalid YOKE_COVERAGE_MIN value: %q (want a percentage from 0 to 100)", value)
+			}
+			cfg.CoverageMin = minimum
+		case "YOKE_BD_PREFIX":
+			cfg.BDPrefix = value
+		case "YOKE_ISSUE_BACKEND":
+			backend := strings.ToLower(strings.TrimSpace(value))
+			switch backend {
+			case "":
+				backend = defaultIssueBackend
+			case "bd", "github", "jira", "local":
+			default:
+				return cfg, fmt.Errorf("invalid YOKE_ISSUE_BACKEND value: %q (want bd, github, jira, or local)", value)
+			}
+			cfg.IssueBackend = backend
+		case "YOKE_JIRA_URL":
+			cfg.JiraURL = strings.TrimSpace(value)
+		case "YOKE_JIRA_PROJECT":
+			cfg.JiraProject = strings.ToUpper(strings.TrimSpace(value))
+		case "YOKE_JIRA_REVIEW_STATUS":
+			cfg.JiraReviewStatus = strings.TrimSpace(value)
+		case "YOKE_WRITER_AGENT":
+			cfg.WriterAgent = value
+		case "YOKE_WRITER_CMD":
+			cfg.WriterCmd = value
+		case "YOKE_REVIEWER_AGENT":
+			cfg.ReviewerAgent = value
+		case "YOKE_REVIEW_CMD":
+			cfg.ReviewCmd = value
+		case "YOKE_PR_TEMPLATE":
+			cfg.PRTemplate = value
+		case "YOKE_BD_BACKUP_CMD":
+			cfg.BackupCmd = value
+		case "YOKE_BD_BACKUP_KEEP":
+			keep, err := strconv.Atoi(value)
+			if err != nil || keep < 0 {
+				return cfg, fmt.Errorf("invalid YOKE_BD_BACKUP_KEEP value: %q", value)
+			}
+			cfg.BackupKeep = keep
+		case "YOKE_MAX_COST_PER_ISSUE":
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			budget, err := parseBudget(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_MAX_COST_PER_ISSUE: %w", err)
+			}
+			cfg.MaxIssueCost = budget
+		case "YOKE_EPIC_SIZE_WARNING":
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				return cfg, fmt.Errorf("invalid YOKE_EPIC_SIZE_WARNING value: %q", value)
+			}
+			cfg.EpicSizeWarning = limit
+		case "YOKE_MERGE_STRATEGY":
+			strategy, err := parseMergeStrategy(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_MERGE_STRATEGY: %w", err)
+			}
+			cfg.MergeStrategy = strategy
+		case "YOKE_SYNC_BASE":
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_SYNC_BASE value: %q", value)
+			}
+			cfg.SyncBase = enabled
+		case "YOKE_SYNC_STRATEGY":
+			strategy, err := parseSyncStrategy(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_SYNC_STRATEGY: %w", err)
+			}
+			cfg.SyncStrategy = strategy
+		case "YOKE_PRIORITY_INHERITANCE":
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_PRIORITY_INHERITANCE value: %q", value)
+			}
+			cfg.InheritPriority = enabled
+		case "YOKE_CLAIM_POLICY":
+			policy, err := parseClaimPolicy(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_CLAIM_POLICY: %w", err)
+			}
+			cfg.ClaimPolicy = policy
+		case "YOKE_OVERLAP_MODE":
+			mode, err := parseOverlapMode(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_OVERLAP_MODE: %w", err)
+			}
+			cfg.OverlapMode = mode
+		case "YOKE_MERGE_QUEUE":
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_MERGE_QUEUE value: %q", value)
+			}
+			cfg.MergeQueue = enabled
+		case "YOKE_SYNC_INTERVAL":
+			if value = strings.TrimSpace(value); value == "" || value == "0" {
+				cfg.SyncInterval = 0
+				continue
+			}
+			interval, err := parseDaemonInterval(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_SYNC_INTERVAL: %w", err)
+			}
+			cfg.SyncInterval = interval
+		case "YOKE_COMMIT_CONVENTION":
+			style, err := parseCommitConvention(value)
+			if err != nil {
+				return cfg, err
+			}
+			cfg.CommitStyle = style
+		case "YOKE_SIGNOFF":
+			mode, err := parseSignoffMode(value)
+			if err != nil {
+				return cfg, err
+			}
+			cfg.Signoff = mode
+		case "YOKE_REVIEW_SAMPLE_RATE":
+			rate, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+			if err != nil || rate < 0 || rate > 100 {
+				return cfg, fmt.Errorf("invalid YOKE_REVIEW_SAMPLE_RATE value: %q (want 0-100)", value)
+			}
+			cfg.ReviewSample = rate
+		case "YOKE_LOW_RISK_LABELS":
+			cfg.LowRiskLabels = parseLabelList(value)
+		case "YOKE_AUDIT_MODEL":
+			cfg.AuditModel = strings.TrimSpace(value)
+		case "YOKE_ACTOR":
+			cfg.Actor = strings.TrimSpace(value)
+		case "YOKE_ASSIGNEE":
+			cfg.Assignee = strings.TrimSpace(value)
+		case "YOKE_LEASE_TTL":
+			if value = strings.TrimSpace(value); value == "" || value == "0" {
+				cfg.LeaseTTL = 0
+				continue
+			}
+			ttl, err := parseDaemonInterval(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_LEASE_TTL: %w", err)
+			}
+			cfg.LeaseTTL = ttl
+		case "YOKE_REVIEW_DIFF_MAX_BYTES":
+			limit, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || limit < 0 || limit > maxReviewDiffBytes {
+				return cfg, fmt.Errorf("invalid YOKE_REVIEW_DIFF_MAX_BYTES value: %q (want 0-%d)", value, maxReviewDiffBytes)
+			}
+			cfg.ReviewDiffMaxBytes = limit
+		case "YOKE_REVIEW_DIFF_EXCLUDE":
+			cfg.ReviewDiffExclude = parseLabelList(value)
+		case "YOKE_MAX_REJECTIONS":
+			limit, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || limit < 0 {
+				return cfg, fmt.Errorf("invalid YOKE_MAX_REJECTIONS value: %q (want 0 or more)", value)
+			}
+			cfg.MaxRejections = limit
+		case "YOKE_NOTIFY_CMD":
+			cfg.NotifyCmd = value
+		case "YOKE_NOTIFY_ROUTES":
+			routes, err := parseNotifyRoutes(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_NOTIFY_ROUTES value: %w", err)
+			}
+			cfg.NotifyRoutes = routes
+		case "YOKE_SLACK_CHANNEL":
+			cfg.SlackChannel = strings.TrimSpace(value)
+		case "YOKE_REQUIRE_HUMAN_FOR":
+			cfg.RequireHumanFor = parseLabelList(value)
+		case "YOKE_APPROVAL_POLICY":
+			rules, err := parseApprovalPolicy(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_APPROVAL_POLICY value: %w", err)
+			}
+			cfg.ApprovalPolicy = rules
+		case "YOKE_EPIC_PASS_PLAN":
+			plan, err := parseEpicPassPlan(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_EPIC_PASS_PLAN value: %w", err)
+			}
+			cfg.EpicPassPlan = plan
+		case "YOKE_AGENT_OUTPUT_FILTERS":
+			filters, err := parseOutputFilters(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid YOKE_AGENT_OUTPUT_FILTERS value: %w", err)
+			}
+			cfg.OutputFilters = filters
+		case "YOKE_SKIP_LABELS":
+			cfg.SkipLabels = parseLabelList(value)
+		case "YOKE_ONLY_LABELS":
+			cfg.OnlyLabels = parseLabelList(value)
+		case "YOKE_SKIP_TYPES":
+			cfg.SkipTypes = parseLabelList(value)
+		case "YOKE_ONLY_TYPES":
+			cfg.OnlyTypes = parseLabelList(value)
+		case "YOKE_SKIP_PATHS":
+			cfg.SkipPaths = parseLabelList(value)
+		case "YOKE_ONLY_PATHS":
+			cfg.OnlyPaths = parseLabelList(value)
+		case "YOKE_AUTO_MERGE", "YOKE_WAIT_CI", "YOKE_EPIC_RETRO", "YOKE_EPIC_ARCHIVE", "YOKE_SECURITY_REVIEW":
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s value: %q", key, value)
+			}
+			switch key {
+			case "YOKE_AUTO_MERGE":
+				cfg.AutoMerge = enabled
+			case "YOKE_WAIT_CI":
+				cfg.WaitCI = enabled
+			case "YOKE_EPIC_RETRO":
+				cfg.EpicRetro = enabled
+			case "YOKE_EPIC_ARCHIVE":
+				cfg.EpicArchive = enabled
+			default:
+				cfg.SecurityReview = enabled
+			}
+		case "YOKE_SUBMIT_COMMIT", "YOKE_COMMIT_AGENT_BODY":
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s value: %q", key, value)
+			}
+			if key == "YOKE_SUBMIT_COMMIT" {
+				cfg.SubmitCommit = enabled
+			} else {
+				cfg.CommitAgentBody = enabled
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return cfg, err
+	}
+	if len(checkList) > 0 {
+		checks, err := buildNamedChecks(checkList, checkFields)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Checks = checks
+	}
+
+	if cfg.IssueBackend == "jira" && cfg.JiraProject != "" {
+		// The Jira project key is the issue prefix.
+		cfg.BDPrefix = cfg.JiraProject
+	}
+	normalizedPrefix, err := normalizeBDPrefix(cfg.BDPrefix)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.BDPrefix = normalizedPrefix
+	agentOutputFilters = cfg.OutputFilters
+
+	return cfg, nil
+}
+
+func parseShellValue(raw string) string {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return ""
+	}
+
+	if strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) && len(value) >= 2 {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+		return strings.Trim(value, `"`)
+	}
+	if strings.HasPrefix(value, `'`) && strings.HasSuffix(value, `'`) && len(value) >= 2 {
+		return strings.Trim(value, `'`)
+	}
+
+	if idx := strings.Index(value, " #"); idx >= 0 {
+		value = value[:idx]
+	}
+	return strings.TrimSpace(value)
+}
+
+func writeConfig(cfg config) error {
+	content := renderConfig(cfg)
+	return os.WriteFile(cfg.Path, []byte(content), 0o644)
+}
+
+func renderConfig(cfg config) string {
+	return fmt.Sprintf(`# shellcheck shell=bash
+
+# Base branch for PRs created by yoke.
+YOKE_BASE_BRANCH=%s
+
+# Check command or executable path. Set to "skip" to bypass.
+YOKE_CHECK_CMD=%s
+
+# Named checks run in parallel instead of YOKE_CHECK_CMD (e.g. "lint test build").
+# Each needs YOKE_CHECK_<NAME>="command"; optional YOKE_CHECK_<NAME>_TIMEOUT=5m and
+# YOKE_CHECK_<NAME>_REQUIRED=false (reported, but never fails submit).
+YOKE_CHECKS=%s%s
+
+# Pass the paths changed against the PR base to checks: {affected} in a check
+# command becomes e.g. "./pkg/a/... ./cmd/b/..." (./... when disabled), and
+# scripts get YOKE_AFFECTED_PATHS and YOKE_CHANGED_FILES.
+YOKE_AFFECTED_CHECKS=%t
+
+# Coverage command run by yoke submit after checks (e.g. "go test -cover {affected}").
+# Its percentage is recorded in the handoff; with YOKE_COVERAGE_MIN, submit fails
+# when any reported package (or the total) is below it. Empty disables.
+YOKE_COVERAGE_CMD=%s
+YOKE_COVERAGE_MIN=%s
+
+# Prefix used for bd issue IDs (example: bd-a1b2).
+YOKE_BD_PREFIX=%s
+
+# Issue backend: bd, github (GitHub Issues on origin; ids are <prefix>-<number>),
+# jira (needs JIRA_API_TOKEN, plus JIRA_EMAIL on Jira Cloud), or local (Markdown
+# files under .yoke/issues; no bd needed).
+YOKE_ISSUE_BACKEND=%s
+
+# Jira site URL and project key for YOKE_ISSUE_BACKEND=jira; the project key
+# is the issue prefix.
+YOKE_JIRA_URL=%s
+YOKE_JIRA_PROJECT=%s
+
+# Jira status that means "in review"; without it, review is the
+# yoke:status:blocked label on an in-progress issue.
+YOKE_JIRA_REVIEW_STATUS=%s
+
+# Selected coding agent for writing (codex or claude).
+YOKE_WRITER_AGENT=%s
+
+# Optional writer command for yoke daemon loops.
+# Runs with ISSUE_ID, ROOT_DIR, BD_PREFIX, and YOKE_ROLE=writer.
+# Expected behavior: implement the issue and transition state via yoke submit.
+YOKE_WRITER_CMD=%s
+
+# Selected coding agent for reviewing (codex or claude).
+YOKE_REVIEWER_AGENT=%s
+
+# Optional reviewer agent command. Runs when using: yoke review --agent
+# and yoke daemon. Runs with ISSUE_ID, ROOT_DIR, BD_PREFIX, YOKE_ROLE=reviewer,
+# YOKE_REVIEW_VERDICT_SCHEMA, and the change under review in YOKE_REVIEW_DIFF /
+# YOKE_REVIEW_DIFF_FILE.
+# Expected behavior for daemon mode: print the JSON verdict described by
+# $YOKE_REVIEW_VERDICT_SCHEMA (yoke approves or rejects from it), or execute
+# yoke review --approve or --reject.
+# Example:
+# YOKE_REVIEW_CMD='codex exec "Review $ISSUE_ID. $YOKE_REVIEW_VERDICT_SCHEMA"'
+YOKE_REVIEW_CMD=%s
+
+# Pull request template path.
+YOKE_PR_TEMPLATE=%s
+
+# Optional bd backup command run before bulk/destructive bd operations
+# (epic improvement cycles, epic close). Runs with ROOT_DIR and YOKE_BACKUP_REASON.
+YOKE_BD_BACKUP_CMD=%s
+
+# When YOKE_BD_BACKUP_CMD is empty, copy the bd database into .yoke/backups
+# and keep this many snapshots. 0 disables snapshots.
+YOKE_BD_BACKUP_KEEP=%d
+
+# Maximum accumulated agent cost (USD) per issue before automation stops.
+# Empty disables budget enforcement.
+YOKE_MAX_COST_PER_ISSUE=%s
+
+# Warn when an epic has more descendants than this. 0 disables the warning.
+YOKE_EPIC_SIZE_WARNING=%d
+
+# How yoke merge lands approved PRs: squash, merge, or rebase.
+YOKE_MERGE_STRATEGY=%s
+
+# Bring the PR base branch into the issue branch before yoke submit runs checks
+# (same as yoke submit --sync-base).
+YOKE_SYNC_BASE=%t
+
+# How submit syncs the base branch: rebase or merge.
+YOKE_SYNC_STRATEGY=%s
+
+# Let ready issues inherit the priority of higher-priority open issues they
+# block, so claim and the daemon unblock important chains first.
+YOKE_PRIORITY_INHERITANCE=%t
+
+# Order ready issues for claim and the daemon: "ready" keeps the tracker's order;
+# otherwise a comma list of priority, oldest, smallest (estimate), applied in turn.
+YOKE_CLAIM_POLICY=%s
+
+# What to do when the writer's branch touches the same files as another
+# in-progress or in-review yoke branch: off, warn, or serialize (wait until
+# overlapping in-review work lands).
+YOKE_OVERLAP_MODE=%s
+
+# Enqueue approved PRs that target YOKE_BASE_BRANCH in the GitHub merge queue
+# instead of closing the issue; it closes once the merge lands.
+YOKE_MERGE_QUEUE=%t
+
+# How often the daemon syncs the base branch into long-running in-progress
+# issue branches (for example 24h). Empty or 0 disables periodic sync.
+YOKE_SYNC_INTERVAL=%s
+
+# Stage and commit outstanding changes in yoke submit (same as --commit).
+YOKE_SUBMIT_COMMIT=%t
+
+# Ask the writer agent to draft the body of submit commits (same as --commit-agent).
+YOKE_COMMIT_AGENT_BODY=%t
+
+# Commit subject convention for commits and squash merges yoke creates: "" or conventional.
+YOKE_COMMIT_CONVENTION=%s
+
+# Sign yoke commits: "" (off), signoff (Signed-off-by trailer), gpg, or signoff,gpg.
+YOKE_SIGNOFF=%s
+
+# After yoke review --approve, enable GitHub auto-merge (YOKE_MERGE_STRATEGY) on the PR.
+YOKE_AUTO_MERGE=%t
+
+# Issues carrying any of these labels are never picked by claim/review selection.
+YOKE_SKIP_LABELS=%s
+
+# When set, selection only picks issues carrying at least one of these labels.
+YOKE_ONLY_LABELS=%s
+
+# Same filters by bd issue type (task, bug, feature, ...).
+YOKE_SKIP_TYPES=%s
+YOKE_ONLY_TYPES=%s
+
+# Same filters by the paths an issue mentions: directory prefixes (cmd/api) or
+# globs (*.md, docs/*). With YOKE_ONLY_PATHS, issues naming no path are skipped.
+YOKE_SKIP_PATHS=%s
+YOKE_ONLY_PATHS=%s
+
+# Gate approvals and merges on the PR's required checks (same as --wait-ci).
+YOKE_WAIT_CI=%t
+
+# Issues carrying any of these labels are low-risk for review sampling.
+YOKE_LOW_RISK_LABELS=%s
+
+# Percent of low-risk issues the daemon sends to a full agent review (100 = all).
+YOKE_REVIEW_SAMPLE_RATE=%d
+
+# Model passed to the reviewer agent by yoke audit-review (empty = agent default).
+YOKE_AUDIT_MODEL=%s
+
+# Author for bd comments and updates yoke makes for an agent role; {role} and
+# {agent} expand, for example "yoke[{role}]/{agent}" (empty = bd's default).
+YOKE_ACTOR=%s
+
+# Tracker user yoke assigns issues to when it claims them (empty = label the
+# issue yoke:claimed-by=<host> instead).
+YOKE_ASSIGNEE=%s
+
+# Lease claimed issues for this long (for example 15m), renewed while the
+# daemon works, so daemons on several machines share one backlog and recover
+# issues whose daemon died. Empty or 0 disables leases.
+YOKE_LEASE_TTL=%s
+
+# Run a retrospective and post it to the epic when yoke claim closes an epic.
+YOKE_EPIC_RETRO=%t
+
+# Write the epic's decision record to docs/epics/<epic-id>/ when yoke claim
+# closes it (otherwise run yoke epic archive yourself).
+YOKE_EPIC_ARCHIVE=%t
+
+# Epic improvement passes, in order: roles with an optional :agent. Roles other than
+# writer/reviewer use the writer agent; .yoke/prompts/epic-<role>.md overrides a role's prompt
+# and .yoke/prompts/epic-improvement-cycle.md the default one.
+YOKE_EPIC_PASS_PLAN=%s
+
+# Cap on the git diff handed to reviewer commands (YOKE_REVIEW_DIFF); 0 = off.
+YOKE_REVIEW_DIFF_MAX_BYTES=%d
+
+# Paths left out of the reviewer diff; bare names match at any depth.
+YOKE_REVIEW_DIFF_EXCLUDE=%s
+
+# Run the security review pass before every daemon review (same as yoke review --security).
+YOKE_SECURITY_REVIEW=%t
+
+# Rules an agent approval must pass to close the issue, else a person approves:
+# checks, max-lines=N, paths=GLOB|GLOB, no-todos (empty = agent approvals close).
+YOKE_APPROVAL_POLICY=%s
+
+# Labels or issue types whose approval is always left to a person (e.g. security,migration).
+YOKE_REQUIRE_HUMAN_FOR=%s
+
+# Rejections before an issue is labeled yoke:needs-human and automation stops (0 = never).
+YOKE_MAX_REJECTIONS=%d
+
+# Optional command run when automation needs a person (for example an epic waiting
+# on clarification answers). Gets the message on stdin, YOKE_NOTIFY_EVENT, ISSUE_ID.
+YOKE_NOTIFY_CMD=%s
+
+# Which events go to which channels: event=channel|channel, comma-separated.
+# Events: submitted, approved, daemon-error, budget-exceeded, waiting-for-human, *.
+# Channels: command, slack, discord, webhook (URLs come from SLACK_WEBHOOK_URL,
+# DISCORD_WEBHOOK_URL, YOKE_NOTIFY_WEBHOOK_URL), desktop (on a terminal).
+# Empty = daemon-error, budget-exceeded, and waiting-for-human to every
+# configured channel, plus submitted and daemon-error on the desktop.
+YOKE_NOTIFY_ROUTES=%s
+
+# Slack channel id for clarification tasks (with SLACK_BOT_TOKEN, replies in the
+# thread become bd comments). SLACK_WEBHOOK_URL alone only posts them.
+YOKE_SLACK_CHANNEL=%s
+
+# Processing applied to agent output before it is saved or reused in prompts:
+# strip-ansi, collapse-repeats, final-answer, max-bytes=N (empty = keep raw).
+YOKE_AGENT_OUTPUT_FILTERS=%s
+`,
+		quoteShell(cfg.BaseBranch),
+		quoteShell(cfg.CheckCmd),
+		quoteShell(strings.Join(checkNames(cfg.Checks), " ")),
+		formatNamedCheckConfig(cfg.Checks),
+		cfg.AffectedChecks,
+		quoteShell(cfg.CoverageCmd),
+		quoteShell(formatCoverageMin(cfg.CoverageMin)),
+		quoteShell(cfg.BDPrefix),
+		quoteShell(valueOrFallback(cfg.IssueBackend, defaultIssueBackend)),
+		quoteShell(cfg.JiraURL),
+		quoteShell(cfg.JiraProject),
+		quoteShell(valueOrFallback(cfg.JiraReviewStatus, defaultJiraReviewStatus)),
+		quoteShell(cfg.WriterAgent),
+		quoteShell(cfg.WriterCmd),
+		quoteShell(cfg.ReviewerAgent),
+		quoteShell(cfg.ReviewCmd),
+		quoteShell(cfg.PRTemplate),
+		quoteShell(cfg.BackupCmd),
+		cfg.BackupKeep,
+		quoteShell(formatBudget(cfg.MaxIssueCost)),
+		cfg.EpicSizeWarning,
+		quoteShell(valueOrFallback(cfg.MergeStrategy, defaultMerge)),
+		cfg.SyncBase,
+		quoteShell(valueOrFallback(cfg.SyncStrategy, defaultSync)),
+		cfg.InheritPriority,
+		quoteShell(formatClaimPolicy(cfg.ClaimPolicy)),
+		quoteShell(valueOrFallback(cfg.OverlapMode, defaultOverlap)),
+		cfg.MergeQueue,
+		quoteShell(formatSyncInterval(cfg.SyncInterval)),
+		cfg.SubmitCommit,
+		cfg.CommitAgentBody,
+		quoteShell(cfg.CommitStyle),
+		quoteShell(cfg.Signoff),
+		cfg.AutoMerge,
+		quoteShell(strings.Join(cfg.SkipLabels, ",")),
+		quoteShell(strings.Join(cfg.OnlyLabels, ",")),
+		quoteShell(strings.Join(cfg.SkipTypes, ",")),
+		quoteShell(strings.Join(cfg.OnlyTypes, ",")),
+		quoteShell(strings.Join(cfg.SkipPaths, ",")),
+		quoteShell(strings.Join(cfg.OnlyPaths, ",")),
+		cfg.WaitCI,
+		quoteShell(strings.Join(cfg.LowRiskLabels, ",")),
+		cfg.ReviewSample,
+		quoteShell(cfg.AuditModel),
+		quoteShell(cfg.Actor),
+		quoteShell(cfg.Assignee),
+		quoteShell(formatSyncInterval(cfg.LeaseTTL)),
+		cfg.EpicRetro,
+		cfg.EpicArchive,
+		quoteShell(formatEpicPassPlan(cfg.EpicPassPlan)),
+		cfg.ReviewDiffMaxBytes,
+		quoteShell(strings.Join(cfg.ReviewDiffExclude, ",")),
+		cfg.SecurityReview,
+		quoteShell(formatApprovalPolicy(cfg.ApprovalPolicy)),
+		quoteShell(strings.Join(cfg.RequireHumanFor, ",")),
+		cfg.MaxRejections,
+		quoteShell(cfg.NotifyCmd),
+		quoteShell(formatNotifyRoutes(cfg.NotifyRoutes)),
+		quoteShell(cfg.SlackChannel),
+		quoteShell(formatOutputFilters(cfg.OutputFilters)),
+	)
+}
+
+func quoteShell(value string) string {
+	return strconv.Quote(value)
+}
+
+func ensureRepoRoot() (string, error) {
+	root, err := commandOutput("git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", errors.New("run inside a git repository")
+	}
+	root = strings.TrimSpace(root)
+	auditRoot = mainWorktreeRoot(root)
+	return root, nil
+}
+
+// mainWorktreeRoot maps an issue worktree back to the main checkout so shared
+// logs land in one place.
+func mainWorktreeRoot(root string) string {
+	out, err := commandOutput("git", "-C", root, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	dir := strings.TrimSpace(out)
+	if err != nil || dir == "" || filepath.Base(dir) != ".git" {
+		return root
+	}
+	return filepath.Dir(dir)
+}
+
+func detectAvailableAgents() []detectedAgent {
+	available := make([]detectedAgent, 0, len(supportedAgents))
+	for _, spec := range supportedAgents {
+		for _, binary := range spec.Binaries {
+			if commandExists(binary) {
+				available = append(available, detectedAgent{
+					ID:     spec.ID,
+					Name:   spec.Name,
+					Binary: binary,
+				})
+				break
+			}
+		}
+	}
+
+	return available
+}
+
+func normalizeAgentID(input string) (string, bool) {
+	value := strings.ToLower(strings.TrimSpace(input))
+	for _, spec := range supportedAgents {
+		if value == spec.ID {
+			return spec.ID, true
+		}
+		for _, binary := range spec.Binaries {
+			if value == strings.ToLower(binary) {
+				return spec.ID, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func normalizeBDPrefix(input string) (string, error) {
+	value := strings.ToLower(strings.TrimSpace(input))
+	if value == "" {
+		return defaultBDPrefix, nil
+	}
+
+	validPrefix := regexp.MustCompile(`^[a-z0-9](?:[a-z0-9._-]*[a-z0-9])?$`)
+	if !validPrefix.MatchString(value) {
+		return "", fmt.Errorf("invalid bd prefix %q: use letters, numbers, '.', '_' or '-', and avoid trailing separators", input)
+	}
+
+	return value, nil
+}
+
+func containsAgentID(agents []detectedAgent, id string) bool {
+	for _, agent := range agents {
+		if agent.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func promptForAgentSelection(
+	role string,
+	available []detectedAgent,
+	current string,
+	reader *bufio.Reader,
+) (string, error) {
+	if len(available) == 0 {
+		return current, nil
+	}
+
+	defaultAgent := current
+	if defaultAgent == "" || !containsAgentID(available, defaultAgent) {
+		defaultAgent = available[0].ID
+	}
+
+	for {
+		note(fmt.Sprintf("Select %s agent:", role))
+		for i, agent := range available {
+			marker := ""
+			if agent.ID == defaultAgent {
+				marker = " (default)"
+			}
+			note(fmt.Sprintf("  %d) %s [%s]%s", i+1, agent.ID, agent.Binary, marker))
+		}
+		fmt.Printf("%s agent [%s]: ", titleCase(role), defaultAgent)
+
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			return defaultAgent, nil
+		}
+
+		if index, convErr := strconv.Atoi(trimmed); convErr == nil {
+			if index >= 1 && index <= len(available) {
+				return available[index-1].ID, nil
+			}
+			note("Invalid selection. Enter a valid option number or agent name.")
+			if errors.Is(err, io.EOF) {
+				return defaultAgent, nil
+			}
+			continue
+		}
+
+		if normalized, ok := normalizeAgentID(trimmed); ok && containsAgentID(available, normalized) {
+			return normalized, nil
+		}
+
+		note("Invalid selection. Enter a valid option number or agent name.")
+		if errors.Is(err, io.EOF) {
+			return defaultAgent, nil
+		}
+	}
+}
+
+func promptForBDPrefix(current string, reader *bufio.Reader) (string, error) {
+	defaultPrefix, err := normalizeBDPrefix(current)
+	if err != nil {
+		defaultPrefix = defaultBDPrefix
+	}
+
+	for {
+		fmt.Printf("BD issue prefix [%s]: ", defaultPrefix)
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return "", readErr
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			return defaultPrefix, nil
+		}
+
+		normalized, normErr := normalizeBDPrefix(trimmed)
+		if normErr == nil {
+			return normalized, nil
+		}
+		note(normErr.Error())
+
+		if errors.Is(readErr, io.EOF) {
+			return defaultPrefix, nil
+		}
+	}
+}
+
+func isInteractiveTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func titleCase(value string) string {
+	if value == "" {
+		return ""
+	}
+	return strings.ToUpper(value[:1]) + value[1:]
+}
+
+func valueOrUnset(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "unset"
+	}
+	return value
+}
+
+func valueOrFallback(value, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
+
+func issueOrNone(issue string) string {
+	if strings.TrimSpace(issue) == "" {
+		return "none"
+	}
+	return issue
+}
+
+func configuredAgentStatus(agentID string) string {
+	if strings.TrimSpace(agentID) == "" {
+		return "unset"
+	}
+	return agentAvailabilityStatus(agentID)
+}
+
+func commandConfigStatus(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "unset"
+	}
+	return "configured"
+}
+
+func availabilityLabel(available bool) string {
+	if available {
+		return "available"
+	}
+	return "missing"
+}
+
+func agentAvailabilityStatus(agentID string) string {
+	normalized, ok := normalizeAgentID(agentID)
+	if !ok {
+		return "unknown"
+	}
+
+	for _, spec := range supportedAgents {
+		if spec.ID != normalized {
+			continue
+		}
+		for _, binary := range spec.Binaries {
+			if commandExists(binary) {
+				return "available via " + binary
+			}
+		}
+		return "not detected"
+	}
+
+	return "unknown"
+}
+
+func commandExists(name string) bool {
+	_, err := lookPath(name)
+	return err == nil
+}
+
+const timingLogFile = "timings.jsonl"
+
+// phaseTimings accumulates wall time per phase (bd, checks, git push, GitHub
+// API, agent, ...) for one claim/submit/review run. While an outer phase
+// such as checks or agent is open, commands it runs are not counted again.
+type phaseTimings struct {
+	mu      sync.Mutex
+	command string
+	started time.Time
+	order   []string
+	totals  map[string]time.Duration
+	counts  map[string]int
+	open    int
+}
+
+// timingRecord is one line of .yoke/timings.jsonl.
+type timingRecord struct {
+	Command   string           `json:"command"`
+	Args      []string         `json:"args,omitempty"`
+	StartedAt string           `json:"started_at"`
+	TotalMS   int64            `json:"total_ms"`
+	PhasesMS  map[string]int64 `json:"phases_ms"`
+	Counts    map[string]int   `json:"counts"`
+	OK        bool             `json:"ok"`
+}
+
+var activeTimings *phaseTimings
+
+// timingOutput receives the phase summary. It is stderr so stdout stays
+// clean for callers that eval it (claim --worktree); tests swap it.
+var timingOutput io.Writer = os.Stderr
+
+// timedCommand runs a claim/submit/review handler and prints and logs where
+// its time went.
+func timedCommand(name string, args []string, handler func([]string) error) error {
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			return handler(args)
+		}
+	}
+	timings := &phaseTimings{command: name, started: time.Now(), totals: map[string]time.Duration{}, counts: map[string]int{}}
+	activeTimings = timings
+	err := handler(args)
+	activeTimings = nil
+
+	total := time.Since(timings.started)
+	if verbosity != logQuiet {
+		fmt.Fprintln(timingOutput, formatPhaseTimings(timings, total))
+	}
+	if auditRoot != "" && !dryRunMode {
+		record := timingRecord{
+			Command:   name,
+			Args:      args,
+			StartedAt: timings.started.UTC().Format(time.RFC3339),
+			TotalMS:   total.Milliseconds(),
+			PhasesMS:  map[string]int64{},
+			Counts:    timings.counts,
+			OK:        err == nil,
+		}
+		for phase, spent := range timings.totals {
+			record.PhasesMS[phase] = spent.Milliseconds()
+		}
+		if logErr := appendJSONLine(filepath.Join(auditRoot, ".yoke", timingLogFile), record); logErr != nil {
+			note("warning: failed to record timings: " + logErr.Error())
+		}
+	}
+	return err
+}
+
+// timePhase starts timing phase and returns the function that stops it.
+// outer phases (checks, agent) hide the commands they run.
+func timePhase(phase string, outer bool) func() {
+	timings := activeTimings
+	if timings == nil {
+		return func() {}
+	}
+	timings.mu.Lock()
+	nested := timings.open > 0
+	if outer {
+		timings.open++
+	}
+	timings.mu.Unlock()
+	started := time.Now()
+	return func() {
+		timings.mu.Lock()
+		defer timings.mu.Unlock()
+		if outer {
+			timings.open--
+		}
+		if nested {
+			return
+		}
+		if _, ok := timings.totals[phase]; !ok {
+			timings.order = append(timings.order, phase)
+		}
+		timings.totals[phase] += time.Since(started)
+		timings.counts[phase]++
+	}
+}
+
+// timeCommand times an external command under the phase it belongs to.
+func timeCommand(name string, args []string) func() {
+	if verbosity == logVerbose {
+		fmt.Fprintln(os.Stderr, "+ "+strings.Join(append([]string{name}, args...), " "))
+	}
+	phase := name
+	switch name {
+	case "git":
+		gitArgs := args
+		for len(gitArgs) >= 2 && gitArgs[0] == "-C" {
+			gitArgs = gitArgs[2:]
+		}
+		if len(gitArgs) > 0 && (gitArgs[0] == "push" || gitArgs[0] == "fetch") {
+			phase = "git " + gitArgs[0]
+		}
+	case "bd", "gh":
+	default:
+		phase = "other commands"
+	}
+	return timePhase(phase, false)
+}
+
+func formatPhaseTimings(timings *phaseTimings, total time.Duration) string {
+	timings.mu.Lock()
+	defer timings.mu.Unlock()
+	phases := append([]string{}, timings.order...)
+	sort.SliceStable(phases, func(i, j int) bool { return timings.totals[phases[i]] > timings.totals[phases[j]] })
+	lines := []string{fmt.Sprintf("Timing (%s, %s):", timings.command, total.Round(100*time.Millisecond))}
+	var accounted time.Duration
+	for _, phase := range phases {
+		accounted += timings.totals[phase]
+		lines = append(lines, fmt.Sprintf("  %-15s %9s  (%d)", phase, timings.totals[phase].Round(100*time.Millisecond), timings.counts[phase]))
+	}
+	if rest := total - accounted; rest > 0 {
+		lines = append(lines, fmt.Sprintf("  %-15s %9s", "yoke", rest.Round(100*time.Millisecond)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func runCommand(name string, args ...string) error {
+	if skipForDryRun(name, args) {
+		return nil
+	}
+	defer timeCommand(name, args)()
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	auditCommand(name, args, err)
+	return err
+}
+
+func runCommandDiscard(name string, args ...string) error {
+	defer timeCommand(name, args)()
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	return cmd.Run()
+}
+
+func commandOutput(name string, args ...string) (string, error) {
+	defer timeCommand(name, args)()
+	cmd := exec.Command(name, args...)
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+func commandCombinedOutput(name string, args ...string) string {
+	defer timeCommand(name, args)()
+	cmd := exec.Command(name, args...)
+	out, _ := cmd.CombinedOutput()
+	return string(out)
+}
+
+func refExists(ref string) bool {
+	err := runCommandDiscard("git", "show-ref", "--verify", "--quiet", ref)
+	return err == nil
+}
+
+func issuePatternForPrefix(prefix string) *regexp.Regexp {
+	normalized, err := normalizeBDPrefix(prefix)
+	if err != nil {
+		normalized = defaultBDPrefix
+	}
+	return regexp.MustCompile(regexp.QuoteMeta(normalized) + `-[a-z0-9]+(?:\.[a-z0-9]+)*`)
+}
+
+func extractIssueID(s, prefix string) string {
+	return issuePatternForPrefix(prefix).FindString(strings.ToLower(s))
+}
+
+func extractIssueIDAnyPrefix(s string) string {
+	return anyIssuePattern.FindString(strings.ToLower(s))
+}
+
+func looksLikeIssueID(value, prefix string) bool {
+	pattern := issuePatternForPrefix(prefix)
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	return pattern.FindString(normalized) == normalized
+}
+
+func looksLikeIssueIDAnyPrefix(value string) bool {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	return anyIssuePattern.FindString(normalized) == normalized
+}
+
+func nextIssueID(cfg config) string {
+	issues, err := tracker.List(issueQuery{Status: "open", Ready: true, Limit: selectionListLimit(cfg)})
+	if err != nil {
+		return ""
+	}
+	return firstMatchingIssueID(orderByClaimPolicy(unclaimedIssues(cfg, withoutHeldIssues(eligibleIssues(cfg, issues))), cfg.ClaimPolicy), cfg.BDPrefix, "open")
+}
+
+const defaultClaimPolicy = "ready"
+
+// parseClaimPolicy reads YOKE_CLAIM_POLICY or claim --policy: "ready" (or
+// empty) keeps the tracker's ready order, otherwise a list of priority,
+// oldest, and smallest, each breaking ties left by the one before.
+func parseClaimPolicy(raw string) ([]string, error) {
+	fields := parseLabelList(strings.ToLower(raw))
+	if len(fields) == 0 || (len(fields) == 1 && fields[0] == defaultClaimPolicy) {
+		return nil, nil
+	}
+	policy := []string{}
+	for _, field := range fields {
+		switch field {
+		case "priority", "oldest", "smallest":
+			if !hasLabel(policy, field) {
+				policy = append(policy, field)
+			}
+		default:
+			return nil, fmt.Errorf("unknown claim policy %q (want ready, or a list of priority, oldest, smallest)", field)
+		}
+	}
+	return policy, nil
+}
+
+func formatClaimPolicy(policy []string) string {
+	if len(policy) == 0 {
+		return defaultClaimPolicy
+	}
+	return strings.Join(policy, ",")
+}
+
+// orderByClaimPolicy sorts ready issues by policy: priority puts P0 first,
+// oldest the earliest created, and smallest the lowest estimate. Issues
+// without a creation time or estimate sort after those with one, and full
+// ties keep the tracker's order.
+func orderByClaimPolicy(issues []bdListIssue, policy []string) []bdListIssue {
+	if len(policy) == 0 {
+		return issues
+	}
+	created := make(map[string]time.Time, len(issues))
+	for _, issue := range issues {
+		if at, ok := parseBDTimestamp(issue.CreatedAt); ok {
+			created[issue.ID] = at
+		}
+	}
+	ordered := append([]bdListIssue(nil), issues...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		for _, key := range policy {
+			switch key {
+			case "priority":
+				if a.Priority != b.Priority {
+					return a.Priority < b.Priority
+				}
+			case "oldest":
+				atA, okA := created[a.ID]
+				atB, okB := created[b.ID]
+				if okA != okB {
+					return okA
+				}
+				if !atA.Equal(atB) {
+					return atA.Before(atB)
+				}
+			case "smallest":
+				if (a.Estimate > 0) != (b.Estimate > 0) {
+					return a.Estimate > 0
+				}
+				if a.Estimate != b.Estimate {
+					return a.Estimate < b.Estimate
+				}
+			}
+		}
+		return false
+	})
+	return ordered
+}
+
+func parseLabelList(raw string) []string {
+	fields := strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// labelsEligible applies YOKE_SKIP_LABELS and YOKE_ONLY_LABELS to an issue's
+// labels; skip wins when an issue matches both.
+func labelsEligible(cfg config, labels []string) bool {
+	return selectedBy(labels, cfg.SkipLabels, cfg.OnlyLabels, hasLabel)
+}
+
+// selectedBy is the skip/only rule shared by the selection filters: values
+// matching any skip entry are rejected, and a non-empty only list must match.
+func selectedBy(values, skip, only []string, matches func(values []string, entry string) bool) bool {
+	for _, entry := range skip {
+		if matches(values, entry) {
+			return false
+		}
+	}
+	if len(only) == 0 {
+		return true
+	}
+	for _, entry := range only {
+		if matches(values, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// issueEligible applies the label, type, and path selection filters, and in
+// workspace mode the routing of issues to repositories.
+func issueEligible(cfg config, issue bdListIssue) bool {
+	if !routedHere(cfg, issue) || !labelsEligible(cfg, issue.Labels) {
+		return false
+	}
+	if !selectedBy([]string{valueOrFallback(issue.IssueType, "task")}, cfg.SkipTypes, cfg.OnlyTypes, hasLabel) {
+		return false
+	}
+	if len(cfg.SkipPaths) == 0 && len(cfg.OnlyPaths) == 0 {
+		return true
+	}
+	return selectedBy(issuePaths(issue), cfg.SkipPaths, cfg.OnlyPaths, anyPathOwned)
+}
+
+func eligibleIssues(cfg config, issues []bdListIssue) []bdListIssue {
+	filtered := make([]bdListIssue, 0, len(issues))
+	for _, issue := range issues {
+		// Clarification tasks wait on a person and are never claimed.
+		if isClarificationNeededTitle(issue.Title) || !issueEligible(cfg, issue) {
+			continue
+		}
+		filtered = append(filtered, issue)
+	}
+	return filtered
+}
+
+var issuePathPattern = regexp.MustCompile(`[A-Za-z0-9_.-]*(?:/[A-Za-z0-9_.*-]+)+/?|[A-Za-z0-9_-]+\.[A-Za-z][A-Za-z0-9]{0,5}\b`)
+
+// issuePaths lists the file and directory paths an issue's title,
+// description, and acceptance criteria mention, which is all yoke knows
+// about the code an issue touches before work starts.
+func issuePaths(issue bdListIssue) []string {
+	text := strings.Join([]string{issue.Title, issue.Description, issue.Acceptance}, "\n")
+	seen := map[string]bool{}
+	paths := []string{}
+	for _, match := range issuePathPattern.FindAllString(text, -1) {
+		path := strings.Trim(match, "/.")
+		if path == "" || seen[path] || strings.Contains(match, "//") {
+			continue
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// anyPathOwned reports whether any path falls under pattern: a directory
+// prefix (cmd/yoke, cmd/yoke/, cmd/yoke/**) or a glob (*.md, docs/*.md).
+// Globs without a slash match the file name.
+func anyPathOwned(paths []string, pattern string) bool {
+	pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "./")
+	prefix := strings.TrimSuffix(strings.TrimSuffix(pattern, "**"), "/")
+	for _, path := range paths {
+		if !strings.ContainsAny(prefix, "*?[") {
+			if prefix != "" && (path == prefix || strings.HasPrefix(path, prefix+"/")) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selectionListLimit widens the bd list window when selection filters may
+// discard the first page of candidates or a claim policy reorders them.
+func selectionListLimit(cfg config) int {
+	if len(cfg.SkipLabels) > 0 || len(cfg.OnlyLabels) > 0 || len(cfg.ClaimPolicy) > 0 ||
+		len(cfg.SkipTypes) > 0 || len(cfg.OnlyTypes) > 0 || len(cfg.SkipPaths) > 0 || len(cfg.OnlyPaths) > 0 {
+		return 0
+	}
+	return 20
+}
+
+// priorityEscalation records a ready issue that inherited the priority of an
+// open issue it blocks, directly or through a chain of blockers.
+type priorityEscalation struct {
+	Issue         string
+	From          int
+	To            int
+	InheritedFrom string
+}
+
+// nextClaimIssueID picks the next ready issue in YOKE_CLAIM_POLICY order.
+// With YOKE_PRIORITY_INHERITANCE the ready issues are then reordered by
+// inherited priority, the policy breaking ties; record posts the escalation
+// note on the chosen issue the first time it is escalated.
+func nextClaimIssueID(cfg config, record bool) string {
+	if !cfg.InheritPriority {
+		return nextIssueID(cfg)
+	}
+
+	ready, err := tracker.List(issueQuery{Status: "open", Ready: true})
+	if err != nil {
+		return ""
+	}
+	ready = orderByClaimPolicy(unclaimedIssues(cfg, withoutHeldIssues(eligibleIssues(cfg, ready))), cfg.ClaimPolicy)
+	open, err := listIssuesByStatus("open", false)
+	if err != nil {
+		return nextIssueID(cfg)
+	}
+	readyIDs := issueIDSet(ready)
+	waiting := make([]bdListIssue, 0, len(open))
+	for _, issue := range open {
+		if _, ok := readyIDs[issue.ID]; !ok {
+			waiting = append(waiting, issue)
+		}
+	}
+
+	ordered, escalations, err := inheritPriorities(ready, waiting, blockingDependencies)
+	if err != nil {
+		note("warning: priority inheritance failed; using bd ready order: " + err.Error())
+		return nextIssueID(cfg)
+	}
+	next := firstMatchingIssueID(ordered, cfg.BDPrefix, "open")
+	for _, issue := range ordered {
+		if !strings.EqualFold(issue.ID, next) {
+			continue
+		}
+		if escalation, ok := escalations[issue.ID]; ok && record && !hasLabel(issue.Labels, escalatedLabel) {
+			recordPriorityEscalation(escalation)
+		}
+		break
+	}
+	return next
+}
+
+// inheritPriorities orders ready issues by effective priority: the best of
+// their own priority and that of any waiting issue they transitively block.
+// Only waiting issues that outrank every ready issue are walked, since lower
+// ones cannot change which issue is claimed first.
+func inheritPriorities(ready, waiting []bdListIssue, blockersOf func(string) ([]bdListIssue, error)) ([]bdListIssue, map[string]priorityEscalation, error) {
+	escalations := map[string]priorityEscalation{}
+	if len(ready) == 0 {
+		return ready, escalations, nil
+	}
+	effective := map[string]int{}
+	best := ready[0].Priority
+	for _, issue := range ready {
+		effective[issue.ID] = issue.Priority
+		if issue.Priority < best {
+			best = issue.Priority
+		}
+	}
+
+	candidates := make([]bdListIssue, 0, len(waiting))
+	for _, issue := range waiting {
+		if issue.Priority < best {
+			candidates = append(candidates, issue)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Priority < candidates[j].Priority })
+
+	// Candidates are walked best-first, so a blocker reached once already holds
+	// the best priority it can inherit.
+	visited := map[string]struct{}{}
+	for _, candidate := range candidates {
+		queue := []string{candidate.ID}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			blockers, err := blockersOf(current)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, blocker := range blockers {
+				if _, seen := visited[blocker.ID]; seen || workflowStatusForIssue(blocker) == "closed" {
+					continue
+				}
+				visited[blocker.ID] = struct{}{}
+				if own, ok := effective[blocker.ID]; ok && candidate.Priority < own {
+					effective[blocker.ID] = candidate.Priority
+					escalations[blocker.ID] = priorityEscalation{Issue: blocker.ID, From: own, To: candidate.Priority, InheritedFrom: candidate.ID}
+				}
+				queue = append(queue, blocker.ID)
+			}
+		}
+	}
+
+	ordered := append([]bdListIssue(nil), ready...)
+	sort.SliceStable(ordered, func(i, j int) bool { return effective[ordered[i].ID] < effective[ordered[j].ID] })
+	return ordered, escalations, nil
+}
+
+func recordPriorityEscalation(escalation priorityEscalation) {
+	note(fmt.Sprintf("Escalating %s from P%d to P%d: it blocks %s.", escalation.Issue, escalation.From, escalation.To, escalation.InheritedFrom))
+	if err := tracker.Update(escalation.Issue, issueChange{AddLabels: []string{escalatedLabel}}); err != nil {
+		note("warning: failed to label escalated issue " + escalation.Issue + ": " + err.Error())
+	}
+	_ = tracker.Comment(escalation.Issue, formatEscalationComment(escalation))
+	_ = tracker.Comment(escalation.InheritedFrom, fmt.Sprintf("yoke: escalated blocker %s to P%d so this issue is unblocked first.", escalation.Issue, escalation.To))
+}
+
+func formatEscalationComment(escalation priorityEscalation) string {
+	return fmt.Sprintf("yoke: effective priority raised from P%d to P%d (inherited from %s, which this issue blocks). Claim ordering now treats it as P%d; its bd priority is unchanged.", escalation.From, escalation.To, escalation.InheritedFrom, escalation.To)
+}
+
+// nextReviewableIssueID picks the next issue to review, honoring label filters.
+// nextReviewableIssueID skips issues held for a person (see
+// withoutHeldIssues); they are reviewed by passing their id explicitly.
+func nextReviewableIssueID(cfg config) string {
+	issues, err := tracker.List(issueQuery{Status: "blocked", Label: reviewQueueLabel, Limit: 0})
+	if err != nil {
+		return ""
+	}
+	return firstMatchingIssueID(withoutHeldIssues(eligibleIssues(cfg, issues)), cfg.BDPrefix, "in_review")
+}
+
+func firstReviewableIssueID(prefix string) string {
+	issues, err := tracker.List(issueQuery{Status: "blocked", Label: reviewQueueLabel, Limit: 0})
+	if err != nil {
+		return ""
+	}
+	return firstMatchingIssueID(withoutHeldIssues(issues), prefix, "in_review")
+}
+
+// withoutHeldIssues drops issues automation must leave to a person: those
+// awaiting a human approval, those escalated out of a reject loop, and
+// those that used up their agent budget.
+func withoutHeldIssues(issues []bdListIssue) []bdListIssue {
+	kept := make([]bdListIssue, 0, len(issues))
+	for _, issue := range issues {
+		if !hasLabel(issue.Labels, awaitingApprovalLabel) && !hasLabel(issue.Labels, needsHumanLabel) && !hasLabel(issue.Labels, budgetLabel) {
+			kept = append(kept, issue)
+		}
+	}
+	return kept
+}
+
+func currentBranchIssue(prefix string) string {
+	output, err := commandOutput("git", "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return ""
+	}
+	if issue := extractIssueID(output, prefix); issue != "" {
+		return issue
+	}
+	return extractIssueIDAnyPrefix(output)
+}
 
-	var cmd *exec.Cmd
-	switch normalized {
-	case "codex":
-		cmd = exec.Command(binary, "exec", "--full-auto", "--cd", root, prompt)
-	case "claude":
-		cmd = exec.Command(binary, "--print", "--permission-mode", "bypassPermissions", prompt)
-	default:
-		return "", fmt.Errorf("unsupported agent id: %s", normalized)
+func branchForIssue(issue string) string {
+	return "yoke/" + issue
+}
+
+func issueTitle(issue string) string {
+	parsed, err := tracker.Show(issue)
+	if err == nil && strings.TrimSpace(parsed.Title) != "" {
+		return strings.TrimSpace(parsed.Title)
+	}
+	return issue
+}
+
+// runChecks streams the check command's output to the terminal; when
+// capture is non-nil the output is copied there too.
+func runChecks(root string, check namedCheck, capture io.Writer) error {
+	defer timePhase("checks", true)()
+	checkCmd := check.Command
+	if checkCmd == "" {
+		checkCmd = defaultCheckCmd
+	}
+	if checkCmd == "skip" {
+		note("Skipping checks (YOKE_CHECK_CMD=skip).")
+		return nil
+	}
+
+	var output io.Writer = os.Stdout
+	if capture != nil {
+		output = io.MultiWriter(os.Stdout, capture)
+	}
+	resolved := resolveRepoPath(root, checkCmd)
+	if isExecutable(resolved) {
+		note("Running checks via " + resolved)
+		defer timeCommand(resolved, nil)()
+		cmd := exec.Command(resolved)
+		cmd.Stdout = output
+		cmd.Stderr = output
+		cmd.Env = checkEnv(check)
+		err := cmd.Run()
+		auditCommand(resolved, nil, err)
+		return err
 	}
+
+	note("Running checks: " + checkCmd)
+	cmd := exec.Command("bash", "-lc", checkCmd)
+	cmd.Stdout = output
+	cmd.Stderr = output
 	cmd.Dir = root
-	cmd.Env = append(os.Environ(), extraEnv...)
+	cmd.Env = checkEnv(check)
+	return cmd.Run()
+}
 
-	var combined synchronizedBuffer
-	stdoutStream := io.MultiWriter(&combined, newLinePrefixWriter(os.Stdout, streamPrefix))
-	stderrPrefix := streamPrefix
-	if strings.TrimSpace(stderrPrefix) == "" {
-		stderrPrefix = "[agent][stderr] "
+// checkEnv returns the environment for a check command; nil inherits
+// yoke's own when the check adds nothing.
+func checkEnv(check namedCheck) []string {
+	if len(check.Env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), check.Env...)
+}
+
+const (
+	// checkOutputTailBytes is how much of a check's output is kept for the
+	// failure log; the bd and PR comments get checkFailureExcerptBytes of it.
+	checkOutputTailBytes     = 64 << 10
+	checkFailureExcerptBytes = 4 << 10
+	checkFailureErrorLines   = 20
+)
+
+// tailWriter keeps the last limit bytes written to it.
+type tailWriter struct {
+	limit int
+	buf   []byte
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	if len(w.buf) > w.limit {
+		w.buf = append([]byte(nil), w.buf[len(w.buf)-w.limit:]...)
+	}
+	return len(p), nil
+}
+
+func (w *tailWriter) String() string {
+	return string(w.buf)
+}
+
+// tailText returns the end of text within limit bytes, starting at a line
+// boundary when one is available.
+func tailText(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	tail := text[len(text)-limit:]
+	if newline := strings.IndexByte(tail, '\n'); newline >= 0 && newline < len(tail)-1 {
+		tail = tail[newline+1:]
+	}
+	return tail
+}
+
+// checkFailureError reports failed checks for an issue with the end of their
+// output, so a writer agent reading the error knows what to fix.
+type checkFailureError struct {
+	Issue   string
+	Failed  []string
+	LogPath string
+	Excerpt string
+	Err     error
+}
+
+func (e *checkFailureError) Error() string {
+	lines := strings.Split(strings.TrimRight(e.Excerpt, "\n"), "\n")
+	if len(lines) > checkFailureErrorLines {
+		lines = lines[len(lines)-checkFailureErrorLines:]
+	}
+	message := fmt.Sprintf("checks failed for %s: %s", e.Issue, strings.Join(e.Failed, ", "))
+	if e.LogPath != "" {
+		message += " (full output: " + e.LogPath + ")"
+	}
+	if excerpt := strings.TrimSpace(strings.Join(lines, "\n")); excerpt != "" {
+		message += "\nlast output:\n" + excerpt
+	}
+	return message
+}
+
+func (e *checkFailureError) Unwrap() error {
+	return e.Err
+}
+
+func checkFailureLogPath(root, issue string, at time.Time) string {
+	name := fmt.Sprintf("%s-%s.log", sanitizePathSegment(strings.ToLower(issue)), at.UTC().Format("20060102T150405Z"))
+	return filepath.Join(root, ".yoke", sessionLogDir, "checks", name)
+}
+
+// writeCheckFailureLog saves a check log and returns its path relative to
+// root, or "" when it could not be written.
+func writeCheckFailureLog(root, issue, text string) string {
+	path := checkFailureLogPath(root, issue, time.Now())
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return ""
+	}
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		note("warning: failed to write check log: " + err.Error())
+		return ""
+	}
+	if rel, err := filepath.Rel(root, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// recordCheckFailure saves the failing checks' output to
+// .yoke/logs/checks/<issue>-<ts>.log, posts an excerpt on the bd issue and
+// its open PR, and returns a checkFailureError. Logging and comment
+// failures only warn; the check failure is what gets reported.
+func recordCheckFailure(root, issue string, results []checkResult, runErr error) error {
+	if strings.TrimSpace(issue) == "" || len(results) == 0 {
+		return runErr
+	}
+	failure := &checkFailureError{Issue: issue, Err: runErr}
+	var log, excerpt strings.Builder
+	for _, result := range results {
+		if result.passed() || result.Check.Optional {
+			continue
+		}
+		name := valueOrFallback(result.Check.Name, valueOrFallback(result.Check.Command, defaultCheckCmd))
+		failure.Failed = append(failure.Failed, name)
+		output := tailText(result.Output, checkOutputTailBytes)
+		fmt.Fprintf(&log, "## %s: %s\n# command=%s\n\n%s\n", name, result.Err, result.Check.Command, strings.TrimRight(output, "\n"))
+		if len(results) > 1 {
+			fmt.Fprintf(&excerpt, "--- %s\n", name)
+		}
+		excerpt.WriteString(strings.TrimRight(tailText(output, checkFailureExcerptBytes), "\n") + "\n")
+	}
+	if len(failure.Failed) == 0 {
+		return runErr
+	}
+	failure.Excerpt = tailText(excerpt.String(), checkFailureExcerptBytes)
+
+	if !dryRunMode {
+		failure.LogPath = writeCheckFailureLog(root, issue, log.String())
+	}
+
+	if err := tracker.Comment(issue, formatCheckFailureComment(failure)); err != nil {
+		note("warning: failed to post check failure comment: " + err.Error())
+	}
+	if number, _, _, ok, err := openPRForIssue(issue); err != nil {
+		note("warning: skipping check failure PR comment: " + err.Error())
+	} else if ok {
+		if err := commentOnPR(number, "## Checks Failed\n\n"+formatCheckFailureComment(failure)); err != nil {
+			note("warning: failed to post check failure PR comment: " + err.Error())
+		}
+	}
+	return failure
+}
+
+const checkFailureCommentPrefix = "Checks failed:"
+
+func formatCheckFailureComment(failure *checkFailureError) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", checkFailureCommentPrefix, strings.Join(failure.Failed, ", "))
+	if failure.LogPath != "" {
+		fmt.Fprintf(&b, "Full output: %s\n", failure.LogPath)
+	}
+	if excerpt := strings.TrimRight(failure.Excerpt, "\n"); excerpt != "" {
+		b.WriteString("\n```\n" + strings.ReplaceAll(excerpt, "```", "` ` `") + "\n```\n")
+	}
+	return b.String()
+}
+
+// namedCheck is one entry of YOKE_CHECKS. The legacy YOKE_CHECK_CMD runs as
+// a single check with an empty name.
+type namedCheck struct {
+	Name     string
+	Command  string
+	Timeout  time.Duration
+	Optional bool
+	Env      []string
+}
+
+type checkResult struct {
+	Check    namedCheck
+	Err      error
+	TimedOut bool
+	Duration time.Duration
+	Output   string
+}
+
+func (r checkResult) passed() bool {
+	return r.Err == nil
+}
+
+var checkNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// checkPlan returns the checks to run: an explicit command override, else
+// the named YOKE_CHECKS, else YOKE_CHECK_CMD.
+func checkPlan(cfg config, override string) []namedCheck {
+	if strings.TrimSpace(override) != "" {
+		return []namedCheck{{Command: override}}
+	}
+	if len(cfg.Checks) > 0 {
+		return cfg.Checks
+	}
+	return []namedCheck{{Command: cfg.CheckCmd}}
+}
+
+// affectedPathsPlaceholder in a check command is replaced with the paths
+// the issue branch changed, or ./... when the whole repository is in scope.
+const (
+	affectedPathsPlaceholder = "{affected}"
+	allPathsScope            = "./..."
+)
+
+// checkScope is what an issue branch changed relative to its PR base.
+type checkScope struct {
+	Base  string
+	Files []string
+	Paths []string
+}
+
+// issueCheckPlan returns checkPlan scoped to the issue: with
+// YOKE_AFFECTED_CHECKS, the files changed against the PR base in dir are
+// passed to each check; otherwise, or when the diff cannot be computed, the
+// checks cover the whole repository.
+func issueCheckPlan(root, dir string, cfg config, issue, override string) []namedCheck {
+	scope := checkScope{Paths: []string{allPathsScope}}
+	if cfg.AffectedChecks {
+		base, err := issuePRBaseBranch(root, cfg, issue)
+		if err == nil {
+			scope, err = affectedCheckScope(dir, base)
+		}
+		if err != nil {
+			note("warning: running checks on the whole repository; affected paths unavailable: " + err.Error())
+			scope = checkScope{Paths: []string{allPathsScope}}
+		} else {
+			note(fmt.Sprintf("Checking paths affected by %d changed file(s) against %s: %s", len(scope.Files), scope.Base, strings.Join(scope.Paths, " ")))
+		}
+	}
+	return scopeChecks(checkPlan(cfg, override), scope)
+}
+
+// scopeChecks substitutes affectedPathsPlaceholder in each command and
+// exports the scope as YOKE_AFFECTED_PATHS, YOKE_CHANGED_FILES, and
+// YOKE_CHECK_BASE for check scripts.
+func scopeChecks(checks []namedCheck, scope checkScope) []namedCheck {
+	paths := strings.Join(scope.Paths, " ")
+	scoped := make([]namedCheck, 0, len(checks))
+	for _, check := range checks {
+		check.Command = strings.ReplaceAll(check.Command, affectedPathsPlaceholder, paths)
+		check.Env = append(append([]string{}, check.Env...),
+			"YOKE_AFFECTED_PATHS="+paths,
+			"YOKE_CHANGED_FILES="+strings.Join(scope.Files, "\n"),
+			"YOKE_CHECK_BASE="+scope.Base,
+		)
+		scoped = append(scoped, check)
+	}
+	return scoped
+}
+
+// affectedCheckScope lists the files changed in dir since it forked from
+// base, including uncommitted and untracked files but not .yoke state.
+func affectedCheckScope(dir, base string) (checkScope, error) {
+	ref := localOrRemoteRef(base)
+	if ref == "" {
+		return checkScope{}, fmt.Errorf("base branch %s not found locally or on origin", base)
+	}
+	forkPoint, err := commandOutput("git", "-C", dir, "merge-base", ref, "HEAD")
+	if err != nil {
+		return checkScope{}, fmt.Errorf("merge-base with %s: %w", ref, err)
+	}
+	changed, err := commandOutput("git", "-C", dir, "diff", "--name-only", strings.TrimSpace(forkPoint))
+	if err != nil {
+		return checkScope{}, err
+	}
+	untracked, err := commandOutput("git", "-C", dir, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return checkScope{}, err
+	}
+	seen := map[string]bool{}
+	files := []string{}
+	for _, file := range strings.Split(changed+"\n"+untracked, "\n") {
+		file = strings.TrimSpace(file)
+		if file == "" || seen[file] || file == ".yoke" || strings.HasPrefix(file, ".yoke/") {
+			continue
+		}
+		seen[file] = true
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return checkScope{Base: ref, Files: files, Paths: affectedPaths(files)}, nil
+}
+
+// affectedPaths turns changed files into ./dir/... patterns, dropping
+// directories already covered by a changed parent. A change at the
+// repository root (go.mod, package.json, Makefile) or no change at all
+// puts the whole repository in scope.
+func affectedPaths(files []string) []string {
+	dirs := map[string]bool{}
+	for _, file := range files {
+		dir := filepath.ToSlash(filepath.Dir(file))
+		if dir == "." {
+			return []string{allPathsScope}
+		}
+		dirs[dir] = true
+	}
+	if len(dirs) == 0 {
+		return []string{allPathsScope}
+	}
+	paths := []string{}
+	for dir := range dirs {
+		covered := false
+		for parent := filepath.ToSlash(filepath.Dir(dir)); parent != "."; parent = filepath.ToSlash(filepath.Dir(parent)) {
+			if dirs[parent] {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			paths = append(paths, "./"+dir+"/...")
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// runCheckPlan runs a single unnamed check in the foreground as runChecks
+// does. Named checks run in parallel, each with its own timeout; their
+// output is buffered and printed in configured order once all finish. Only
+// failing required checks fail the run.
+func runCheckPlan(root string, checks []namedCheck) ([]checkResult, error) {
+	if len(checks) == 1 && checks[0].Name == "" {
+		started := time.Now()
+		output := &tailWriter{limit: checkOutputTailBytes}
+		err := runChecks(root, checks[0], output)
+		return []checkResult{{Check: checks[0], Err: err, Duration: time.Since(started), Output: output.String()}}, err
+	}
+
+	defer timePhase("checks", true)()
+	note(fmt.Sprintf("Running %d checks in parallel: %s", len(checks), strings.Join(checkNames(checks), ", ")))
+	results := make([]checkResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runNamedCheck(root, check)
+		}()
+	}
+	wg.Wait()
+
+	failed := []string{}
+	for _, result := range results {
+		status := "passed"
+		switch {
+		case result.TimedOut:
+			status = "timed out after " + result.Check.Timeout.String()
+		case !result.passed():
+			status = "failed: " + result.Err.Error()
+		}
+		if !result.passed() && result.Check.Optional {
+			status += " (optional)"
+		}
+		fmt.Printf("--- check %s: %s (%s)\n", result.Check.Name, status, result.Duration.Round(100*time.Millisecond))
+		if output := strings.TrimRight(result.Output, "\n"); output != "" && !result.passed() {
+			fmt.Println(output)
+		}
+		if !result.passed() && !result.Check.Optional {
+			failed = append(failed, result.Check.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("required check(s) failed: %s", strings.Join(failed, ", "))
+	}
+	return results, nil
+}
+
+func runNamedCheck(root string, check namedCheck) checkResult {
+	ctx := context.Background()
+	if check.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, check.Timeout)
+		defer cancel()
+	}
+	var cmd *exec.Cmd
+	if resolved := resolveRepoPath(root, check.Command); isExecutable(resolved) {
+		cmd = exec.CommandContext(ctx, resolved)
 	} else {
-		stderrPrefix += "[stderr] "
+		cmd = exec.CommandContext(ctx, "bash", "-lc", check.Command)
 	}
-	stderrStream := io.MultiWriter(&combined, newLinePrefixWriter(os.Stdout, stderrPrefix))
-	cmd.Stdout = stdoutStream
-	cmd.Stderr = stderrStream
+	cmd.Dir = root
+	cmd.Env = checkEnv(check)
+	// Kill the whole process group on timeout so bash children do not
+	// keep the output pipe open.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+	cmd.WaitDelay = time.Second
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	started := time.Now()
+	err := cmd.Run()
+	result := checkResult{Check: check, Err: err, Duration: time.Since(started), Output: output.String()}
+	if ctx.Err() == context.DeadlineExceeded {
+		result.TimedOut = true
+		result.Err = fmt.Errorf("timed out after %s", check.Timeout)
+	}
+	return result
+}
 
-	runErr := cmd.Run()
-	return strings.TrimSpace(combined.String()), runErr
+const (
+	checkCacheFile       = "check-cache.json"
+	maxCheckCacheEntries = 20
+)
+
+// checkCacheEntry records a passing check run for one working tree.
+type checkCacheEntry struct {
+	Tree    string              `json:"tree"`
+	At      string              `json:"at"`
+	Results []cachedCheckResult `json:"results"`
 }
 
-type synchronizedBuffer struct {
-	mu  sync.Mutex
-	buf bytes.Buffer
+type cachedCheckResult struct {
+	Name     string `json:"name,omitempty"`
+	Command  string `json:"command"`
+	Optional bool   `json:"optional,omitempty"`
+	Passed   bool   `json:"passed"`
+	TimedOut bool   `json:"timed_out,omitempty"`
 }
 
-func (b *synchronizedBuffer) Write(p []byte) (int, error) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	return b.buf.Write(p)
+// checkCachePath lives in the shared state directory, outside every working
+// tree, so files an agent writes in its worktree cannot record a passing run.
+func checkCachePath(root string) string {
+	return filepath.Join(yokeSharedStateDir(root), checkCacheFile)
+}
+
+// runCachedCheckPlan skips the checks when they already passed for the
+// same working tree and check configuration, so retrying a submit after a
+// push or PR failure does not rerun the whole suite. force always runs them;
+// automated approvals pass it so they never rely on a recorded result.
+func runCachedCheckPlan(root string, checks []namedCheck, force bool) ([]checkResult, error) {
+	key, tree, err := checkCacheKey(root, checks)
+	if err != nil {
+		note("warning: check cache disabled: " + err.Error())
+		return runCheckPlan(root, checks)
+	}
+	cache := loadCheckCache(root)
+	if entry, ok := cache[key]; ok && !force {
+		note(fmt.Sprintf("Checks already passed for this working tree (%s); skipping. Use --force-checks to rerun.", entry.At))
+		return entry.checkResults(), nil
+	}
+
+	results, err := runCheckPlan(root, checks)
+	if err != nil {
+		return results, err
+	}
+	entry := checkCacheEntry{Tree: tree, At: time.Now().UTC().Format(time.RFC3339)}
+	for _, result := range results {
+		entry.Results = append(entry.Results, cachedCheckResult{
+			Name:     result.Check.Name,
+			Command:  result.Check.Command,
+			Optional: result.Check.Optional,
+			Passed:   result.passed(),
+			TimedOut: result.TimedOut,
+		})
+	}
+	cache[key] = entry
+	if err := saveCheckCache(root, cache); err != nil {
+		note("warning: failed to record check cache: " + err.Error())
+	}
+	return results, nil
+}
+
+func (e checkCacheEntry) checkResults() []checkResult {
+	results := make([]checkResult, 0, len(e.Results))
+	for _, cached := range e.Results {
+		result := checkResult{
+			Check:    namedCheck{Name: cached.Name, Command: cached.Command, Optional: cached.Optional},
+			TimedOut: cached.TimedOut,
+		}
+		if !cached.Passed {
+			result.Err = errors.New("failed (cached result)")
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkCacheKey hashes the working tree, including uncommitted and
+// untracked files but not yoke's own .yoke state, together with the check
+// configuration and the content of any check scripts it runs.
+func checkCacheKey(root string, checks []namedCheck) (string, string, error) {
+	tree, err := workingTreeHash(root)
+	if err != nil {
+		return "", "", err
+	}
+	hash := sha1.New()
+	fmt.Fprintf(hash, "tree %s\n", tree)
+	for _, check := range checks {
+		fmt.Fprintf(hash, "check %q %q %s %t %q\n", check.Name, check.Command, check.Timeout, check.Optional, check.Env)
+		if resolved := resolveRepoPath(root, valueOrFallback(check.Command, defaultCheckCmd)); isExecutable(resolved) {
+			data, err := os.ReadFile(resolved)
+			if err != nil {
+				return "", "", err
+			}
+			hash.Write(data)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), tree, nil
+}
+
+// workingTreeHash returns the git tree id the working tree would commit as
+// with git add -A, using a scratch index so the real index is untouched.
+func workingTreeHash(root string) (string, error) {
+	indexPath, err := commandOutput("git", "-C", root, "rev-parse", "--path-format=absolute", "--git-path", "index")
+	if err != nil {
+		return "", err
+	}
+	scratchDir, err := os.MkdirTemp("", "yoke-check-index-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(scratchDir)
+	scratchPath := filepath.Join(scratchDir, "index")
+	// Starting from a copy of the real index lets git reuse its stat data
+	// instead of rehashing every file.
+	if data, err := os.ReadFile(strings.TrimSpace(indexPath)); err == nil {
+		if err := os.WriteFile(scratchPath, data, 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	env := append(os.Environ(), "GIT_INDEX_FILE="+scratchPath)
+	add := exec.Command("git", "-C", root, "add", "-A", "--", ".", ":(exclude).yoke")
+	add.Env = env
+	if output, err := add.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("git add: %s", strings.TrimSpace(string(output)))
+	}
+	write := exec.Command("git", "-C", root, "write-tree")
+	write.Env = env
+	output, err := write.Output()
+	if err != nil {
+		return "", fmt.Errorf("git write-tree: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func loadCheckCache(root string) map[string]checkCacheEntry {
+	cache := map[string]checkCacheEntry{}
+	data, err := os.ReadFile(checkCachePath(root))
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return map[string]checkCacheEntry{}
+	}
+	return cache
+}
+
+// saveCheckCache keeps the newest maxCheckCacheEntries runs.
+func saveCheckCache(root string, cache map[string]checkCacheEntry) error {
+	if dryRunMode {
+		return nil
+	}
+	if len(cache) > maxCheckCacheEntries {
+		keys := make([]string, 0, len(cache))
+		for key := range cache {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return cache[keys[i]].At > cache[keys[j]].At })
+		for _, key := range keys[maxCheckCacheEntries:] {
+			delete(cache, key)
+		}
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(checkCachePath(root)), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(checkCachePath(root), append(data, '\n'), 0o644)
+}
+
+func checkNames(checks []namedCheck) []string {
+	names := make([]string, 0, len(checks))
+	for _, check := range checks {
+		names = append(names, check.Name)
+	}
+	return names
+}
+
+// describeCheckPlan names what a check plan runs, for handoff notes.
+func describeCheckPlan(checks []namedCheck) string {
+	if len(checks) == 1 && checks[0].Name == "" {
+		return valueOrFallback(checks[0].Command, defaultCheckCmd)
+	}
+	return strings.Join(checkNames(checks), ", ")
 }
 
-func (b *synchronizedBuffer) String() string {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	return b.buf.String()
+// formatCheckSummary reports check results for handoff and PR comments:
+// "`cmd` passed" for a single command, else each named check's outcome.
+func formatCheckSummary(results []checkResult) string {
+	if len(results) == 1 && results[0].Check.Name == "" {
+		return "`" + valueOrFallback(results[0].Check.Command, defaultCheckCmd) + "` passed"
+	}
+	parts := make([]string, 0, len(results))
+	for _, result := range results {
+		status := "passed"
+		switch {
+		case result.TimedOut:
+			status = "timed out"
+		case !result.passed():
+			status = "failed"
+		}
+		if !result.passed() && result.Check.Optional {
+			status += " (optional)"
+		}
+		parts = append(parts, "`"+result.Check.Name+"` "+status)
+	}
+	return strings.Join(parts, ", ")
+}
+
+var (
+	coveragePackagePattern = regexp.MustCompile(`^(?:ok\s+)?(\S+)\s.*coverage: (\d+(?:\.\d+)?)% of statements`)
+	coverageTotalPattern   = regexp.MustCompile(`^total:\s.*?(\d+(?:\.\d+)?)%`)
+	coveragePercentPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)%`)
+)
+
+// coverageReport is what YOKE_COVERAGE_CMD printed: per-package lines from
+// go test -cover, and a total from go tool cover -func or, failing that, the
+// last percentage in the output.
+type coverageReport struct {
+	Packages []packageCoverage
+	Total    float64
+	HasTotal bool
 }
 
-type linePrefixWriter struct {
-	mu        sync.Mutex
-	dst       io.Writer
-	prefix    string
-	lineStart bool
+type packageCoverage struct {
+	Package string
+	Percent float64
 }
 
-func newLinePrefixWriter(dst io.Writer, prefix string) *linePrefixWriter {
-	return &linePrefixWriter{
-		dst:       dst,
-		prefix:    prefix,
-		lineStart: true,
+func parseCoverageOutput(output string) (coverageReport, bool) {
+	var report coverageReport
+	last, hasLast := 0.0, false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if match := coveragePackagePattern.FindStringSubmatch(line); match != nil {
+			percent, _ := strconv.ParseFloat(match[2], 64)
+			report.Packages = append(report.Packages, packageCoverage{Package: match[1], Percent: percent})
+			continue
+		}
+		if match := coverageTotalPattern.FindStringSubmatch(line); match != nil {
+			report.Total, _ = strconv.ParseFloat(match[1], 64)
+			report.HasTotal = true
+			continue
+		}
+		if matches := coveragePercentPattern.FindAllStringSubmatch(line, -1); len(matches) > 0 {
+			last, _ = strconv.ParseFloat(matches[len(matches)-1][1], 64)
+			hasLast = true
+		}
 	}
+	if !report.HasTotal && len(report.Packages) == 0 && hasLast {
+		report.Total, report.HasTotal = last, true
+	}
+	return report, report.HasTotal || len(report.Packages) > 0
 }
 
-func (w *linePrefixWriter) Write(p []byte) (int, error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	written := 0
-	for len(p) > 0 {
-		if w.lineStart {
-			if _, err := io.WriteString(w.dst, w.prefix); err != nil {
-				return written, err
-			}
-			w.lineStart = false
+// below lists what falls under minimum: each reported package, or the total
+// when the command reported no packages.
+func (r coverageReport) below(minimum float64) []string {
+	failed := []string{}
+	if len(r.Packages) == 0 {
+		if r.HasTotal && r.Total < minimum {
+			failed = append(failed, "total "+formatCoveragePercent(r.Total)+"%")
 		}
-
-		newline := bytes.IndexByte(p, '\n')
-		if newline == -1 {
-			n, err := w.dst.Write(p)
-			written += n
-			return written, err
+		return failed
+	}
+	for _, pkg := range r.Packages {
+		if pkg.Percent < minimum {
+			failed = append(failed, pkg.Package+" "+formatCoveragePercent(pkg.Percent)+"%")
 		}
+	}
+	return failed
+}
 
-		chunk := p[:newline+1]
-		n, err := w.dst.Write(chunk)
-		written += n
-		if err != nil {
-			return written, err
+func formatCoverageSummary(report coverageReport, minimum float64) string {
+	summary := ""
+	switch {
+	case report.HasTotal:
+		summary = "coverage " + formatCoveragePercent(report.Total) + "%"
+	case len(report.Packages) == 1:
+		summary = fmt.Sprintf("coverage %s%% (%s)", formatCoveragePercent(report.Packages[0].Percent), report.Packages[0].Package)
+	default:
+		lowest := report.Packages[0]
+		for _, pkg := range report.Packages[1:] {
+			if pkg.Percent < lowest.Percent {
+				lowest = pkg
+			}
 		}
-		w.lineStart = true
-		p = p[newline+1:]
+		summary = fmt.Sprintf("coverage at least %s%% across %d packages (lowest: %s)", formatCoveragePercent(lowest.Percent), len(report.Packages), lowest.Package)
 	}
+	if minimum > 0 {
+		summary += ", minimum " + formatCoveragePercent(minimum) + "%"
+	}
+	return summary
+}
 
-	return written, nil
+func formatCoveragePercent(percent float64) string {
+	return strconv.FormatFloat(percent, 'f', -1, 64)
 }
 
-func buildEpicImprovementPassPrompt(epicID string, pass, total int, role string, clarifications []clarificationContext) string {
-	replaced := strings.ReplaceAll(epicImprovementPromptTemplate, "$EPIC_ID", epicID)
-	clarificationBlock := buildClarificationPromptBlock(clarifications)
-	if clarificationBlock == "" {
-		clarificationBlock = "No clarification-task comments were found."
+// formatCoverageMin renders YOKE_COVERAGE_MIN, leaving it empty when unset.
+func formatCoverageMin(minimum float64) string {
+	if minimum <= 0 {
+		return ""
 	}
-	return strings.TrimSpace(fmt.Sprintf(
-		`You are the %s agent for epic %s.
-This is epic improvement pass %d of %d.
-Clarification context (resolved by user comments on "Clarification needed" tasks):
-
-%s
-
-Apply the following improvement protocol exactly and emit the report in the specified report format:
+	return formatCoveragePercent(minimum)
+}
 
-%s`,
-		role, epicID, pass, total, clarificationBlock, replaced,
-	))
+// runCoverageGate runs YOKE_COVERAGE_CMD with the same affected scope as the
+// checks and returns the summary for the handoff. It fails when the command
+// fails, prints no percentage, or reports coverage under YOKE_COVERAGE_MIN.
+func runCoverageGate(root string, cfg config, issue string) (string, error) {
+	check := issueCheckPlan(root, root, cfg, issue, cfg.CoverageCmd)[0]
+	check.Name = "coverage"
+	note("Running coverage: " + check.Command)
+	result := runNamedCheck(root, check)
+	fmt.Print(result.Output)
+	if !result.passed() {
+		return "coverage failed", fmt.Errorf("coverage command failed: %w", result.Err)
+	}
+	report, ok := parseCoverageOutput(result.Output)
+	if !ok {
+		return "coverage unknown", errors.New("no coverage percentage found in YOKE_COVERAGE_CMD output")
+	}
+	summary := formatCoverageSummary(report, cfg.CoverageMin)
+	if cfg.CoverageMin > 0 {
+		if failed := report.below(cfg.CoverageMin); len(failed) > 0 {
+			return summary, fmt.Errorf("coverage below YOKE_COVERAGE_MIN=%s%%: %s; add tests before submitting", formatCoveragePercent(cfg.CoverageMin), strings.Join(failed, ", "))
+		}
+	}
+	return summary, nil
 }
 
-func buildClarificationPromptBlock(clarifications []clarificationContext) string {
-	if len(clarifications) == 0 {
-		return ""
+// parseCheckConfigKey splits YOKE_CHECK_<NAME>[_TIMEOUT|_REQUIRED] into the
+// lowercased check name and field; YOKE_CHECK_CMD is not a named check.
+func parseCheckConfigKey(key string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(key, "YOKE_CHECK_")
+	if !ok || key == "YOKE_CHECK_CMD" || rest == "" {
+		return "", "", false
+	}
+	field := "command"
+	for _, suffix := range []string{"_TIMEOUT", "_REQUIRED"} {
+		if name, found := strings.CutSuffix(rest, suffix); found && name != "" {
+			rest, field = name, strings.ToLower(strings.TrimPrefix(suffix, "_"))
+			break
+		}
 	}
+	return strings.ToLower(rest), field, true
+}
 
-	var body strings.Builder
-	for _, item := range clarifications {
-		body.WriteString(fmt.Sprintf("- %s: %s\n", item.IssueID, strings.TrimSpace(item.Title)))
-		for _, comment := range item.Comments {
-			author := strings.TrimSpace(comment.Author)
-			if author == "" {
-				author = "unknown"
+func checkConfigKey(name string) string {
+	return "YOKE_CHECK_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// buildNamedChecks assembles YOKE_CHECKS from the per-check keys collected
+// while reading the config file.
+func buildNamedChecks(names []string, fields map[string]map[string]string) ([]namedCheck, error) {
+	checks := make([]namedCheck, 0, len(names))
+	seen := map[string]bool{}
+	for _, raw := range names {
+		name := strings.ToLower(raw)
+		if !checkNamePattern.MatchString(name) || name == "cmd" {
+			return nil, fmt.Errorf("invalid YOKE_CHECKS name %q (use letters, digits, - and _; cmd is reserved)", raw)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate YOKE_CHECKS name %q", raw)
+		}
+		seen[name] = true
+		values := fields[strings.ReplaceAll(name, "-", "_")]
+		check := namedCheck{Name: name, Command: strings.TrimSpace(values["command"])}
+		if check.Command == "" {
+			return nil, fmt.Errorf("YOKE_CHECKS lists %s but %s is not set", name, checkConfigKey(name))
+		}
+		if raw := strings.TrimSpace(values["timeout"]); raw != "" {
+			timeout, err := parseDaemonInterval(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s_TIMEOUT: %w", checkConfigKey(name), err)
 			}
-			timestamp := strings.TrimSpace(comment.CreatedAt)
-			if timestamp == "" {
-				timestamp = "unknown-time"
+			check.Timeout = timeout
+		}
+		if raw := strings.TrimSpace(values["required"]); raw != "" {
+			required, err := strconv.ParseBool(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s_REQUIRED value: %q (want true or false)", checkConfigKey(name), raw)
 			}
-			text := truncateForPrompt(strings.TrimSpace(comment.Text), maxClarificationCommentChars)
-			body.WriteString(fmt.Sprintf("  - [%s @ %s] %s\n", author, timestamp, text))
+			check.Optional = !required
 		}
+		checks = append(checks, check)
 	}
-	return strings.TrimSpace(body.String())
+	return checks, nil
 }
 
-func buildEpicImprovementSummaryPrompt(epic bdListIssue, reports []epicImprovementPassReport) string {
-	var body strings.Builder
-	body.WriteString(fmt.Sprintf("Epic: %s\n", epic.ID))
-	body.WriteString(fmt.Sprintf("Title: %s\n\n", strings.TrimSpace(epic.Title)))
-	body.WriteString("Summarize the five pass reports below into one concise final report.\n")
-	body.WriteString("Use sections:\n")
-	body.WriteString("1) Improvements made\n")
-	body.WriteString("2) Remaining risks/questions\n")
-	body.WriteString("3) Most critical dependency chains\n")
-	body.WriteString("4) Recommended next implementation steps\n\n")
-
-	for _, report := range reports {
-		body.WriteString(fmt.Sprintf("## Pass %d (%s via %s)\n", report.Pass, report.Role, report.AgentID))
-		body.WriteString(truncateForPrompt(report.Output, maxSummaryInputCharsPerPass))
-		body.WriteString("\n\n")
+// formatNamedCheckConfig renders the per-check keys for YOKE_CHECKS.
+func formatNamedCheckConfig(checks []namedCheck) string {
+	var b strings.Builder
+	for _, check := range checks {
+		key := checkConfigKey(check.Name)
+		fmt.Fprintf(&b, "\n%s=%s", key, quoteShell(check.Command))
+		if check.Timeout > 0 {
+			fmt.Fprintf(&b, "\n%s_TIMEOUT=%s", key, quoteShell(check.Timeout.String()))
+		}
+		if check.Optional {
+			fmt.Fprintf(&b, "\n%s_REQUIRED=false", key)
+		}
 	}
-	return body.String()
+	return b.String()
 }
 
-func truncateForPrompt(value string, maxChars int) string {
-	trimmed := strings.TrimSpace(value)
-	if maxChars <= 0 || len(trimmed) <= maxChars {
-		return trimmed
+func resolveRepoPath(root, path string) string {
+	if filepath.IsAbs(path) {
+		return path
 	}
-	return trimmed[:maxChars] + "\n...[truncated]..."
+	return filepath.Join(root, path)
 }
 
-func writeEpicImprovementPassReport(path, epicID string, pass int, role, agentID, output string, runErr error) error {
-	var body strings.Builder
-	body.WriteString(fmt.Sprintf("# Epic Improvement Pass %d\n\n", pass))
-	body.WriteString(fmt.Sprintf("- Epic: `%s`\n", epicID))
-	body.WriteString(fmt.Sprintf("- Role: `%s`\n", role))
-	body.WriteString(fmt.Sprintf("- Agent: `%s`\n", agentID))
-	body.WriteString(fmt.Sprintf("- Timestamp: `%s`\n", time.Now().Format(time.RFC3339)))
-	if runErr != nil {
-		body.WriteString(fmt.Sprintf("- Exit: error (`%s`)\n", runErr))
-	} else {
-		body.WriteString("- Exit: success\n")
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
 	}
-	body.WriteString("\n## Output\n\n")
-	body.WriteString(output)
-	body.WriteString("\n")
-	return os.WriteFile(path, []byte(body.String()), 0o644)
+	return info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
 }
 
-func writeEpicImprovementSummary(path, epicID, agentID, summary string, runErr error) error {
-	var body strings.Builder
-	body.WriteString("# Epic Improvement Summary\n\n")
-	body.WriteString(fmt.Sprintf("- Epic: `%s`\n", epicID))
-	body.WriteString(fmt.Sprintf("- Agent: `%s`\n", agentID))
-	body.WriteString(fmt.Sprintf("- Timestamp: `%s`\n", time.Now().Format(time.RFC3339)))
-	if runErr != nil {
-		body.WriteString(fmt.Sprintf("- Exit: error (`%s`)\n", runErr))
-	} else {
-		body.WriteString("- Exit: success\n")
+func issuePRBaseBranch(root string, cfg config, issue string) (string, error) {
+	if parent := readStackParent(root, issue); parent != "" {
+		_, _, _, ok, err := openPRForIssue(parent)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return branchForIssue(parent), nil
+		}
 	}
-	body.WriteString("\n## Output\n\n")
-	body.WriteString(summary)
-	body.WriteString("\n")
-	return os.WriteFile(path, []byte(body.String()), 0o644)
-}
 
-func formatEpicImprovementSummaryComment(epic bdListIssue, summary string, passCount int, reportsDir string) string {
-	trimmedSummary := truncateForPrompt(summary, maxSummaryCommentChars)
-	lines := []string{
-		"## Epic Improvement Cycle Complete",
-		"",
-		"- Epic: `" + sanitizeCommentLine(epic.ID) + "`",
-		"- Passes: " + strconv.Itoa(passCount),
-		"- Process: writer/reviewer alternating",
-		"",
-		"### Agent Summary",
-		trimmedSummary,
-		"",
-		"_Local reports saved at: `" + sanitizeCommentLine(reportsDir) + "`_",
+	epicID, err := epicAncestorID(issue)
+	if err != nil {
+		return "", err
 	}
-	return strings.Join(lines, "\n")
+	if epicID != "" && !strings.EqualFold(strings.TrimSpace(epicID), strings.TrimSpace(issue)) {
+		epicBranch := branchForIssue(epicID)
+		if err := ensureLocalBranch(root, epicBranch, cfg.BaseBranch); err != nil {
+			return "", err
+		}
+		return epicBranch, nil
+	}
+	return cfg.BaseBranch, nil
 }
 
-func sanitizePathSegment(value string) string {
-	trimmed := strings.TrimSpace(value)
-	if trimmed == "" {
-		return "unknown"
+func remoteBranchExists(root, branch string) bool {
+	if strings.TrimSpace(branch) == "" {
+		return false
 	}
-	return strings.NewReplacer("/", "_", "\\", "_", " ", "_", ":", "_").Replace(trimmed)
+	cmd := exec.Command("git", "-C", root, "ls-remote", "--exit-code", "--heads", "origin", branch)
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	return cmd.Run() == nil
 }
 
-func workflowStatusForIssue(issue bdListIssue) string {
-	status := strings.ToLower(strings.TrimSpace(issue.Status))
-	if status == "blocked" && hasLabel(issue.Labels, reviewQueueLabel) {
-		return "in_review"
+func ensureEpicPRForIssue(root string, cfg config, issue string) error {
+	epicID, err := epicAncestorID(issue)
+	if err != nil {
+		return err
+	}
+	if epicID == "" || strings.EqualFold(strings.TrimSpace(epicID), strings.TrimSpace(issue)) {
+		return nil
 	}
-	return status
-}
 
-func hasLabel(labels []string, target string) bool {
-	for _, label := range labels {
-		if strings.EqualFold(strings.TrimSpace(label), target) {
-			return true
+	epicBranch := branchForIssue(epicID)
+	if err := ensureLocalBranch(root, epicBranch, cfg.BaseBranch); err != nil {
+		return err
+	}
+	if hasOriginRemote() && !remoteBranchExists(root, epicBranch) {
+		if err := runCommand("git", "-C", root, "push", "-u", "origin", epicBranch); err != nil {
+			return err
 		}
 	}
-	return false
+
+	baseRef := localOrRemoteRef(cfg.BaseBranch)
+	if baseRef == "" {
+		baseRef = cfg.BaseBranch
+	}
+	aheadCount, err := branchAheadCount(root, baseRef, epicBranch)
+	if err != nil {
+		return err
+	}
+	if aheadCount == 0 {
+		note(fmt.Sprintf("Epic branch %s has no commits beyond %s yet; deferring epic PR creation.", epicBranch, cfg.BaseBranch))
+		return nil
+	}
+
+	epicTitle := issueTitle(epicID)
+	if err := createPRForBranch(root, cfg, epicID, epicTitle, epicBranch, cfg.BaseBranch, prBodyVars(epicID, prHandoff{})); err != nil {
+		return err
+	}
+	if _, _, _, ok, err := openPRForBranch(epicBranch); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("no open epic PR found for %s after submit; expected branch %s to have an open PR", epicID, epicBranch)
+	}
+	return nil
 }
 
-func cmdClaim(args []string) error {
-	for _, arg := range args {
-		if arg == "-h" || arg == "--help" {
-			printClaimUsage()
-			return nil
-		}
+func branchAheadCount(root, baseRef, headRef string) (int, error) {
+	leftRight := fmt.Sprintf("%s...%s", strings.TrimSpace(baseRef), strings.TrimSpace(headRef))
+	out := strings.TrimSpace(commandCombinedOutput("git", "-C", root, "rev-list", "--right-only", "--count", leftRight))
+	if out == "" {
+		return 0, nil
 	}
-	claimNote("Starting claim command.")
-	issueArg, improvementPassLimit, err := parseClaimArgs(args)
+	count, err := strconv.Atoi(out)
 	if err != nil {
-		return err
+		return 0, fmt.Errorf("parse branch ahead count %q: %w", out, err)
 	}
-	claimNote(fmt.Sprintf("Epic improvement pass limit set to %d.", improvementPassLimit))
+	return count, nil
+}
 
-	root, err := ensureRepoRoot()
-	if err != nil {
-		return err
-	}
-	claimNote("Resolved repository root: " + root)
-	cfg, err := loadConfig(root)
+func branchIsAncestor(root, ancestorRef, descendantRef string) bool {
+	cmd := exec.Command("git", "-C", root, "merge-base", "--is-ancestor", ancestorRef, descendantRef)
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	return cmd.Run() == nil
+}
+
+func integrateApprovedTaskIntoEpic(root string, cfg config, issue string) error {
+	epicID, err := epicAncestorID(issue)
 	if err != nil {
 		return err
 	}
-	claimNote("Loaded config with bd prefix: " + cfg.BDPrefix)
-	if !commandExists("bd") {
-		return fmt.Errorf("missing required command: bd")
+	if epicID == "" || strings.EqualFold(strings.TrimSpace(epicID), strings.TrimSpace(issue)) {
+		return nil
 	}
-	claimNote("Verified required command: bd")
 
-	issue := issueArg
-	if issue != "" {
-		claimNote("Using explicit issue argument: " + issue)
+	epicBranch := branchForIssue(epicID)
+	taskBranch := branchForIssue(issue)
+	if err := ensureLocalBranch(root, taskBranch, cfg.BaseBranch); err != nil {
+		return err
 	}
-
-	if issue == "" {
-		claimNote("No issue argument provided; selecting next ready open issue from bd.")
-		issue = nextIssueID(cfg.BDPrefix)
+	if err := ensureLocalBranch(root, epicBranch, cfg.BaseBranch); err != nil {
+		return err
 	}
-	if issue == "" {
-		return errors.New("no issue provided and bd ready returned nothing")
+	if !branchIsAncestor(root, epicBranch, taskBranch) {
+		return fmt.Errorf("cannot fast-forward epic branch %s to %s; merge %s into %s manually", epicBranch, taskBranch, taskBranch, epicBranch)
 	}
-	claimNote("Requested claim target: " + issue)
-
-	requestedIssue := issue
-	claimNote("Resolving target with epic-aware claim logic.")
-	resolvedIssue, epicCompleted, err := resolveClaimIssue(root, cfg, issue, improvementPassLimit)
-	if err != nil {
+	if err := runCommand("git", "-C", root, "branch", "-f", epicBranch, taskBranch); err != nil {
 		return err
 	}
-	if epicCompleted {
-		claimNote("Requested epic has no remaining open child tasks.")
-		note("Epic " + requestedIssue + " is complete; closed epic.")
-		return nil
-	}
-	issue = resolvedIssue
-	if requestedIssue != issue {
-		note("Epic " + requestedIssue + " -> claiming child task " + issue)
+	if hasOriginRemote() {
+		if err := runCommand("git", "-C", root, "push", "origin", epicBranch); err != nil {
+			return err
+		}
 	}
 
-	claimNote("Transitioning issue to in_progress and removing review queue label if present.")
-	if err := runCommand("bd", "update", issue, "--status", "in_progress", "--remove-label", reviewQueueLabel); err != nil {
+	epicTitle := issueTitle(epicID)
+	if err := createPRForBranch(root, cfg, epicID, epicTitle, epicBranch, cfg.BaseBranch, prBodyVars(epicID, prHandoff{})); err != nil {
 		return err
 	}
-	claimNote("Issue state updated successfully.")
-	if err := writeDaemonFocusIssue(root, issue); err != nil {
-		claimNote("warning: failed to persist daemon focus issue: " + err.Error())
-	} else {
-		claimNote("Set daemon focus issue: " + issue)
-	}
-
-	branch := branchForIssue(issue)
-	claimNote("Preparing issue worktree for branch: " + branch)
-	worktreePath, err := ensureIssueWorktree(root, cfg, issue)
-	if err != nil {
+	if _, _, _, ok, err := openPRForBranch(epicBranch); err != nil {
 		return err
+	} else if !ok {
+		return fmt.Errorf("no open epic PR found for %s after integrating %s", epicID, issue)
 	}
-	claimNote("Worktree is ready for development: " + worktreePath)
-
-	note(fmt.Sprintf("Claimed %s on branch %s", issue, branch))
-	note("Worktree: " + worktreePath)
-	note(fmt.Sprintf("Next: cd %q && yoke submit %s --done \"...\" --remaining \"...\"", worktreePath, issue))
+	note(fmt.Sprintf("Integrated %s into epic branch %s", issue, epicBranch))
 	return nil
 }
 
-func parseClaimArgs(args []string) (issue string, improvementPassLimit int, err error) {
-	issue = ""
-	improvementPassLimit = epicPassCount
+// defaultPRBodyTemplate is used when YOKE_PR_TEMPLATE does not exist.
+const defaultPRBodyTemplate = `## {{ISSUE_ID}}: {{TITLE}}
 
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
-		switch arg {
-		case "--improvement-passes":
-			i++
-			if i >= len(args) {
-				return "", 0, errors.New("--improvement-passes requires a value")
-			}
-			passLimit, convErr := strconv.Atoi(args[i])
-			if convErr != nil || passLimit < minEpicPassCount || passLimit > epicPassCount {
-				return "", 0, fmt.Errorf("--improvement-passes must be an integer between %d and %d", minEpicPassCount, epicPassCount)
-			}
-			improvementPassLimit = passLimit
-		default:
-			if strings.HasPrefix(arg, "-") {
-				return "", 0, fmt.Errorf("unknown claim argument: %s", arg)
-			}
-			if issue != "" {
-				return "", 0, errors.New("usage: yoke claim [<prefix>-issue-id] [--improvement-passes N]")
-			}
-			issue = arg
-		}
-	}
+{{DESCRIPTION}}
 
-	return issue, improvementPassLimit, nil
-}
+## Acceptance Criteria
 
-func cmdSubmit(args []string) error {
-	root, err := ensureRepoRoot()
-	if err != nil {
-		return err
-	}
+{{ACCEPTANCE}}
 
-	cfg, err := loadConfig(root)
-	if err != nil {
-		return err
-	}
+## Done
 
-	var (
-		issue     string
-		doneText  string
-		remaining string
-		decision  string
-		uncertain string
-		checks    string
-		noPush    bool
-		noPR      bool
-		noPRNote  bool
-	)
+{{DONE}}
 
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
-		switch arg {
-		case "--done":
-			i++
-			if i >= len(args) {
-				return errors.New("--done requires text")
-			}
-			doneText = args[i]
-		case "--remaining":
-			i++
-			if i >= len(args) {
-				return errors.New("--remaining requires text")
-			}
-			remaining = args[i]
-		case "--decision":
-			i++
-			if i >= len(args) {
-				return errors.New("--decision requires text")
-			}
-			decision = args[i]
-		case "--uncertain":
-			i++
-			if i >= len(args) {
-				return errors.New("--uncertain requires text")
-			}
-			uncertain = args[i]
-		case "--checks":
-			i++
-			if i >= len(args) {
-				return errors.New("--checks requires text")
-			}
-			checks = args[i]
-		case "--no-push":
-			noPush = true
-		case "--no-pr":
-			noPR = true
-		case "--no-pr-comment":
-			noPRNote = true
-		case "-h", "--help":
-			printSubmitUsage()
-			return nil
-		default:
-			if looksLikeIssueID(arg, cfg.BDPrefix) || looksLikeIssueIDAnyPrefix(arg) {
-				if issue != "" {
-					return errors.New("multiple issue ids provided")
-				}
-				issue = arg
-				continue
-			}
-			return fmt.Errorf("unknown submit argument: %s", arg)
-		}
-	}
+## Remaining
 
-	if !commandExists("bd") {
-		return fmt.Errorf("missing required command: bd")
-	}
-	if doneText == "" {
-		return errors.New("--done is required")
-	}
-	if remaining == "" {
-		return errors.New("--remaining is required")
-	}
+{{REMAINING}}
 
-	if issue == "" {
-		issue = currentBranchIssue(cfg.BDPrefix)
+## Decisions
+
+{{DECISION}}
+
+## Uncertainties
+
+{{UNCERTAIN}}
+
+## Checks
+
+{{CHECKS}}
+`
+
+// prHandoff carries the writer's submit fields into the PR body.
+type prHandoff struct {
+	Done      string
+	Remaining string
+	Decision  string
+	Uncertain string
+	// Checks is the formatCheckSummary line for the checks that ran.
+	Checks string
+}
+
+// prBodyVars collects the template variables for an issue's PR body from
+// bd and the writer handoff. Empty fields render as "None".
+func prBodyVars(issue string, handoff prHandoff) map[string]string {
+	details, _ := issueDetails(issue)
+	checks := "None"
+	if strings.TrimSpace(handoff.Checks) != "" {
+		checks = strings.TrimSpace(handoff.Checks) + " locally."
+	}
+	return map[string]string{
+		"ISSUE_ID":    issue,
+		"TITLE":       valueOrFallback(strings.TrimSpace(details.Title), issue),
+		"DESCRIPTION": valueOrFallback(strings.TrimSpace(details.Description), "None"),
+		"ACCEPTANCE":  valueOrFallback(strings.TrimSpace(details.Acceptance), "None"),
+		"DONE":        valueOrFallback(strings.TrimSpace(handoff.Done), "None"),
+		"REMAINING":   valueOrFallback(strings.TrimSpace(handoff.Remaining), "None"),
+		"DECISION":    valueOrFallback(strings.TrimSpace(handoff.Decision), "None"),
+		"UNCERTAIN":   valueOrFallback(strings.TrimSpace(handoff.Uncertain), "None"),
+		"CHECKS":      checks,
 	}
-	if issue == "" {
-		return errors.New("could not infer issue id from branch; pass issue id explicitly")
+}
+
+// renderPRBody substitutes {{NAME}} placeholders; unknown placeholders are
+// left as written.
+func renderPRBody(template string, vars map[string]string) string {
+	pairs := make([]string, 0, len(vars)*2)
+	for name, value := range vars {
+		pairs = append(pairs, "{{"+name+"}}", value)
 	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
 
-	checkCommand := cfg.CheckCmd
-	if checks != "" {
-		checkCommand = checks
+func createPRForBranch(root string, cfg config, issue, title, headBranch, baseBranch string, bodyVars map[string]string) error {
+	if !hasOriginRemote() {
+		note("No origin remote; skipping PR creation.")
+		return nil
 	}
-	if err := runChecks(root, checkCommand); err != nil {
-		return err
+	client, err := githubAPI()
+	if err != nil {
+		note("GitHub API unavailable (" + err.Error() + "); skipping PR creation.")
+		return nil
 	}
-
-	handoffComment := formatIssueHandoffComment(doneText, remaining, decision, uncertain, checkCommand)
-	if err := runCommand("bd", "comments", "add", issue, handoffComment); err != nil {
-		return err
+	if strings.TrimSpace(headBranch) == "" {
+		return errors.New("could not determine current branch")
 	}
-
-	if !noPush {
-		if hasOriginRemote() {
-			if err := runCommand("git", "push", "-u", "origin", "HEAD"); err != nil {
-				return err
-			}
-		} else {
-			note("No origin remote; skipping push.")
-		}
+	if strings.TrimSpace(baseBranch) == "" {
+		return errors.New("could not determine PR base branch")
 	}
 
-	if !noPR {
-		if err := ensureEpicPRForIssue(root, cfg, issue); err != nil {
-			return err
-		}
-		baseBranch, err := issuePRBaseBranch(root, cfg, issue)
-		if err != nil {
-			return err
-		}
-		title := issueTitle(issue)
-		if err := createPRIfNeeded(root, cfg, issue, title, baseBranch); err != nil {
-			return err
-		}
-		if _, _, _, ok := openPRForIssue(issue); !ok {
-			return fmt.Errorf("no open PR found for %s after submit; expected branch %s to have an open PR", issue, branchForIssue(issue))
-		}
+	existing, ok, err := client.pullForBranch(headBranch, "open")
+	if err != nil {
+		return fmt.Errorf("look up PR for %s: %w", headBranch, err)
+	}
+	if ok {
+		note(fmt.Sprintf("PR #%d already exists for %s.", existing.Number, headBranch))
+		return nil
 	}
 
-	if err := runCommand("bd", "update", issue, "--status", "blocked", "--add-label", reviewQueueLabel); err != nil {
-		return err
+	template := defaultPRBodyTemplate
+	if data, err := os.ReadFile(resolveRepoPath(root, cfg.PRTemplate)); err == nil {
+		template = string(data)
 	}
-	if !noPRNote {
-		postSubmitPRComment(issue, doneText, remaining, decision, uncertain, checkCommand)
+	prTitle := fmt.Sprintf("[%s] %s", issue, title)
+	body := renderPRBody(template, bodyVars)
+	pull, err := client.createPull(prTitle, headBranch, baseBranch, body, true)
+	auditGitHubAPI(issue, http.MethodPost, githubRepoPath(client, "/pulls"), strconv.Itoa(pull.Number), err, "head="+headBranch, "base="+baseBranch, "title="+prTitle, body)
+	if err != nil {
+		return fmt.Errorf("create PR for %s: %w", headBranch, err)
 	}
-
-	note(fmt.Sprintf("Submitted %s for review.", issue))
-	note(fmt.Sprintf("Reviewer: yoke review %s", issue))
+	note(fmt.Sprintf("Opened draft PR #%d: %s", pull.Number, pull.HTMLURL))
 	return nil
 }
 
-func cmdReview(args []string) error {
-	root, err := ensureRepoRoot()
-	if err != nil {
-		return err
-	}
-
-	cfg, err := loadConfig(root)
+func createPRIfNeeded(root string, cfg config, issue, title, baseBranch string, handoff prHandoff) error {
+	branchOutput, err := commandOutput("git", "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return err
 	}
+	headBranch := strings.TrimSpace(branchOutput)
+	return createPRForBranch(root, cfg, issue, title, headBranch, baseBranch, prBodyVars(issue, handoff))
+}
 
-	var (
-		issue        string
-		action       string
-		rejectReason string
-		noteText     string
-		runAgent     bool
-		noPRNote     bool
-	)
+// pollOptions controls waitForCondition. Delays start at Initial and grow by
+// Factor up to Max; Timeout bounds the whole wait.
+type pollOptions struct {
+	Timeout time.Duration
+	Initial time.Duration
+	Max     time.Duration
+	Factor  float64
+}
 
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
-		switch arg {
-		case "--approve":
-			action = "approve"
-		case "--reject":
-			i++
-			if i >= len(args) {
-				return errors.New("--reject requires a reason")
-			}
-			action = "reject"
-			rejectReason = args[i]
-		case "--note":
-			i++
-			if i >= len(args) {
-				return errors.New("--note requires text")
-			}
-			noteText = args[i]
-		case "--agent":
-			runAgent = true
-		case "--no-pr-comment":
-			noPRNote = true
-		case "-h", "--help":
-			printReviewUsage()
-			return nil
-		default:
-			if looksLikeIssueID(arg, cfg.BDPrefix) || looksLikeIssueIDAnyPrefix(arg) {
-				if issue != "" {
-					return errors.New("multiple issue ids provided")
-				}
-				issue = arg
-				continue
-			}
-			return fmt.Errorf("unknown review argument: %s", arg)
-		}
-	}
+var defaultPollOptions = pollOptions{
+	Timeout: 30 * time.Minute,
+	Initial: 10 * time.Second,
+	Max:     2 * time.Minute,
+	Factor:  1.5,
+}
 
-	if !commandExists("bd") {
-		return fmt.Errorf("missing required command: bd")
-	}
+var errConditionTimeout = errors.New("timed out")
 
-	if issue == "" {
-		issue = firstReviewableIssueID(cfg.BDPrefix)
+func nextPollDelay(current time.Duration, opts pollOptions) time.Duration {
+	next := time.Duration(float64(current) * opts.Factor)
+	if opts.Factor <= 1 || next < current {
+		next = current
 	}
-	if issue == "" {
-		return errors.New("no reviewable issue found")
+	if opts.Max > 0 && next > opts.Max {
+		next = opts.Max
 	}
+	return next
+}
 
-	if runAgent {
-		if strings.TrimSpace(cfg.ReviewCmd) == "" {
-			return errors.New("YOKE_REVIEW_CMD is empty in .yoke/config.sh")
-		}
-		note("Running reviewer agent for " + issue)
-		cmd := exec.Command("bash", "-lc", cfg.ReviewCmd)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Env = append(os.Environ(),
-			"ISSUE_ID="+issue,
-			"ROOT_DIR="+root,
-			"BD_PREFIX="+cfg.BDPrefix,
-			"YOKE_ROLE=reviewer",
-		)
-		if err := cmd.Run(); err != nil {
-			return err
-		}
+// waitForCondition polls check until it reports done, returns an error, the
+// timeout budget is spent, or ctx is cancelled. It is the single polling loop
+// for PR checks, merges, and branch deletion.
+func waitForCondition(ctx context.Context, description string, opts pollOptions, check func(context.Context) (bool, error)) error {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
 	}
-
-	if noteText != "" {
-		if err := runCommand("bd", "comments", "add", issue, noteText); err != nil {
-			return err
-		}
+	delay := opts.Initial
+	if delay <= 0 {
+		delay = time.Second
 	}
 
-	switch action {
-	case "approve":
-		prNumber, _, isDraft, ok := openPRForIssue(issue)
-		if !ok {
-			return fmt.Errorf("cannot approve %s: no open PR found for issue branch %s", issue, branchForIssue(issue))
-		}
-		if err := ensurePRReady(prNumber, isDraft); err != nil {
-			return err
-		}
-		if err := integrateApprovedTaskIntoEpic(root, cfg, issue); err != nil {
-			return err
-		}
-		if err := runCommand("bd", "close", issue, "--reason", "approved-by-yoke-review"); err != nil {
-			return err
-		}
-		currentStatus, err := issueStatus(issue)
+	for {
+		done, err := check(ctx)
 		if err != nil {
-			return err
+			return fmt.Errorf("waiting for %s: %w", description, err)
 		}
-		if currentStatus != "closed" {
-			return fmt.Errorf("bd close did not close %s (current status: %s)", issue, currentStatus)
+		if done {
+			return nil
 		}
-		clearDaemonFocusIssue(root)
-		note("Approved " + issue)
-	case "reject":
-		if rejectReason != "" {
-			if err := runCommand("bd", "comments", "add", issue, "Reviewer rejection: "+rejectReason); err != nil {
-				return err
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("waiting for %s: %w after %s", description, errConditionTimeout, opts.Timeout)
 			}
+			return fmt.Errorf("waiting for %s: %w", description, ctx.Err())
+		case <-timer.C:
 		}
-		if err := runCommand("bd", "update", issue, "--status", "in_progress", "--remove-label", reviewQueueLabel); err != nil {
-			return err
+		delay = nextPollDelay(delay, opts)
+	}
+}
+
+type prCheckEntry struct {
+	Name   string `json:"name"`
+	Bucket string `json:"bucket"`
+}
+
+// summarizePRChecks reports whether every check passed and names the failing
+// ones; pending checks leave done false without an error.
+func summarizePRChecks(raw string) (bool, []string, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" || trimmed == "null" {
+		return true, nil, nil
+	}
+	var checks []prCheckEntry
+	if err := json.Unmarshal([]byte(trimmed), &checks); err != nil {
+		return false, nil, fmt.Errorf("parse gh pr checks output: %w", err)
+	}
+	done := true
+	failed := []string{}
+	for _, check := range checks {
+		switch strings.ToLower(strings.TrimSpace(check.Bucket)) {
+		case "pass", "skipping":
+		case "fail", "cancel":
+			failed = append(failed, valueOrFallback(check.Name, "unnamed check"))
+		default:
+			done = false
 		}
-		currentStatus, err := issueStatus(issue)
-		if err != nil {
-			return err
+	}
+	return done, failed, nil
+}
+
+// ghPRChecks runs gh pr checks with extra flags and summarizes the result.
+// gh exits non-zero while checks are pending or failing, so its JSON is still
+// read then, and "no checks reported" means the PR has none. Any other
+// failure (auth, network, rate limit) is an error, never a green result.
+func ghPRChecks(ctx context.Context, prNumber string, extra ...string) (bool, []string, error) {
+	args := append(append([]string{"pr", "checks", prNumber}, extra...), "--json", "name,bucket")
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil && strings.TrimSpace(string(output)) == "" {
+		message := strings.TrimSpace(stderr.String())
+		if strings.Contains(message, "checks reported on") {
+			return true, nil, nil
 		}
-		if currentStatus != "in_progress" {
-			return fmt.Errorf("bd update did not return %s to in_progress (current status: %s)", issue, currentStatus)
+		if message == "" {
+			message = err.Error()
 		}
-		if err := writeDaemonFocusIssue(root, issue); err != nil {
-			note("warning: failed to persist daemon focus issue: " + err.Error())
+		return false, nil, fmt.Errorf("gh pr checks %s: %s", prNumber, message)
+	}
+	return summarizePRChecks(string(output))
+}
+
+func prChecksGreenCondition(prNumber string) func(context.Context) (bool, error) {
+	return func(ctx context.Context) (bool, error) {
+		done, failed, err := ghPRChecks(ctx, prNumber)
+		if err != nil {
+			return false, err
 		}
-		note("Rejected " + issue)
-	default:
-		if err := runCommand("bd", "show", issue); err != nil {
-			return err
+		if len(failed) > 0 {
+			return false, fmt.Errorf("PR #%s checks failed: %s", prNumber, strings.Join(failed, ", "))
 		}
-		note("Next:")
-		note("  yoke review " + issue + " --approve")
-		note("  yoke review " + issue + " --reject \"reason\"")
-	}
-	if !noPRNote && (action != "" || noteText != "") {
-		postReviewPRComment(issue, action, rejectReason, noteText, runAgent)
+		return done, nil
 	}
+}
 
-	return nil
+// ciFailedError reports required checks that failed on a PR.
+type ciFailedError struct {
+	PR     string
+	Failed []string
 }
 
-func loadConfig(root string) (config, error) {
-	path := os.Getenv("YOKE_CONFIG")
-	if path == "" {
-		path = filepath.Join(root, ".yoke", "config.sh")
+func (e *ciFailedError) Error() string {
+	return fmt.Sprintf("PR #%s required checks failed: %s", e.PR, strings.Join(e.Failed, ", "))
+}
+
+// prRequiredChecksCondition is prChecksGreenCondition limited to the checks
+// branch protection requires; a PR with none required passes.
+func prRequiredChecksCondition(prNumber string) func(context.Context) (bool, error) {
+	return func(ctx context.Context) (bool, error) {
+		done, failed, err := ghPRChecks(ctx, prNumber, "--required")
+		if err != nil {
+			return false, err
+		}
+		if len(failed) > 0 {
+			return false, &ciFailedError{PR: prNumber, Failed: failed}
+		}
+		return done, nil
 	}
-	if !filepath.IsAbs(path) {
-		path = filepath.Join(root, path)
+}
+
+// issueCIState checks an issue's PR once: "pass", "pending", or "fail" with
+// the failing check names. "pass" means gh reported the required checks;
+// when gh is missing, the issue has no open PR, or the checks cannot be
+// read, the state is "pending" and the error says why.
+func issueCIState(issue string) (string, []string, error) {
+	if !commandExists("gh") {
+		return "pending", nil, errors.New("gh is not installed, so required checks cannot be read")
+	}
+	prNumber, _, _, ok, err := openPRForIssue(issue)
+	if err != nil {
+		return "pending", nil, err
 	}
+	if !ok {
+		return "pending", nil, fmt.Errorf("no open PR found for %s, so there are no checks to read", branchForIssue(issue))
+	}
+	done, err := prRequiredChecksCondition(prNumber)(context.Background())
+	var failure *ciFailedError
+	switch {
+	case errors.As(err, &failure):
+		return "fail", failure.Failed, nil
+	case err != nil:
+		return "pending", nil, err
+	case !done:
+		return "pending", nil, nil
+	}
+	return "pass", nil, nil
+}
 
-	cfg := config{
-		BaseBranch:    defaultBaseBranch,
-		CheckCmd:      defaultCheckCmd,
-		BDPrefix:      defaultBDPrefix,
-		WriterAgent:   "",
-		WriterCmd:     "",
-		ReviewerAgent: "",
-		ReviewCmd:     "",
-		PRTemplate:    defaultPRTemplate,
-		Path:          path,
+// waitForCIGate blocks until the PR's required checks pass. When one fails,
+// the issue is sent back to the writer before the error is returned.
+func waitForCIGate(root, issue, prNumber string, timeout time.Duration) error {
+	note(fmt.Sprintf("Waiting up to %s for PR #%s required checks", timeout, prNumber))
+	opts := defaultPollOptions
+	opts.Timeout = timeout
+	err := waitForCondition(context.Background(), "PR #"+prNumber+" required checks", opts, prRequiredChecksCondition(prNumber))
+	var failure *ciFailedError
+	if errors.As(err, &failure) {
+		if returnErr := returnIssueForCIFailure(root, issue, prNumber, failure.Failed); returnErr != nil {
+			return returnErr
+		}
 	}
+	return err
+}
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return cfg, nil
+func formatCIFailureComment(prNumber string, failed []string) string {
+	return fmt.Sprintf("yoke: required checks failed on PR #%s: %s. Returning the issue to the writer; fix the failures and run yoke submit again.", prNumber, strings.Join(failed, ", "))
+}
+
+// returnIssueForCIFailure comments on the PR and issue and moves the issue
+// back to in_progress (reopening it if it was already closed).
+func returnIssueForCIFailure(root, issue, prNumber string, failed []string) error {
+	comment := formatCIFailureComment(prNumber, failed)
+	if prNumber != "" {
+		if err := commentOnPR(prNumber, comment); err != nil {
+			note("warning: failed to post CI failure comment to PR: " + err.Error())
 		}
-		return cfg, err
 	}
+	if err := tracker.Comment(issue, comment); err != nil {
+		return err
+	}
+	if err := tracker.Update(issue, issueChange{Status: "in_progress", RemoveLabels: []string{reviewQueueLabel}}); err != nil {
+		return err
+	}
+	if err := writeDaemonFocusIssue(root, issue); err != nil {
+		note("warning: failed to persist daemon focus issue: " + err.Error())
+	}
+	releaseReviewLock(root, issue)
+	note(fmt.Sprintf("Returned %s to the writer: required checks failed (%s)", issue, strings.Join(failed, ", ")))
+	return nil
+}
 
-	scanner := bufio.NewScanner(bytes.NewReader(data))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+// prMergedCondition waits for the PR to merge. A failed gh pr view (gh
+// missing, auth, unknown PR) is an error rather than "not merged yet", so
+// the wait does not spin until its timeout.
+func prMergedCondition(prNumber string) func(context.Context) (bool, error) {
+	return func(ctx context.Context) (bool, error) {
+		cmd := exec.CommandContext(ctx, "gh", "pr", "view", prNumber, "--json", "state", "--jq", ".state")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		if err != nil {
+			if ctx.Err() != nil {
+				// The wait's own deadline killed gh; let it report the timeout.
+				return false, nil
+			}
+			message := strings.TrimSpace(stderr.String())
+			if message == "" {
+				message = err.Error()
+			}
+			return false, fmt.Errorf("gh pr view %s: %s", prNumber, message)
 		}
-		matches := assignPattern.FindStringSubmatch(line)
-		if len(matches) != 3 {
-			continue
+		switch strings.ToUpper(strings.TrimSpace(string(output))) {
+		case "MERGED":
+			return true, nil
+		case "CLOSED":
+			return false, fmt.Errorf("PR #%s was closed without merging", prNumber)
+		default:
+			return false, nil
 		}
-		key := matches[1]
-		value := parseShellValue(matches[2])
+	}
+}
 
-		switch key {
-		case "YOKE_BASE_BRANCH":
-			cfg.BaseBranch = value
-		case "YOKE_CHECK_CMD":
-			cfg.CheckCmd = value
-		case "YOKE_BD_PREFIX":
-			cfg.BDPrefix = value
-		case "YOKE_WRITER_AGENT":
-			cfg.WriterAgent = value
-		case "YOKE_WRITER_CMD":
-			cfg.WriterCmd = value
-		case "YOKE_REVIEWER_AGENT":
-			cfg.ReviewerAgent = value
-		case "YOKE_REVIEW_CMD":
-			cfg.ReviewCmd = value
-		case "YOKE_PR_TEMPLATE":
-			cfg.PRTemplate = value
+// remoteBranchDeletedCondition waits for origin to drop branch. A failed
+// git ls-remote (network, auth) is an error, not a branch that still exists,
+// so a transient failure is never reported as a healthy branch.
+func remoteBranchDeletedCondition(root, branch string) func(context.Context) (bool, error) {
+	return func(ctx context.Context) (bool, error) {
+		cmd := exec.CommandContext(ctx, "git", "-C", root, "ls-remote", "--heads", "origin", branch)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, nil
+			}
+			message := strings.TrimSpace(stderr.String())
+			if message == "" {
+				message = err.Error()
+			}
+			return false, fmt.Errorf("git ls-remote origin %s: %s", branch, message)
 		}
+		return strings.TrimSpace(string(output)) == "", nil
 	}
-	if err := scanner.Err(); err != nil {
-		return cfg, err
+}
+
+const (
+	githubAPIVersion       = "2022-11-28"
+	githubMaxAttempts      = 3
+	maxGitHubRateLimitWait = 2 * time.Minute
+)
+
+// githubClient talks to the GitHub REST and GraphQL APIs for the origin
+// repository with the same token gh uses, so PR lookups get typed responses
+// and real errors instead of parsed gh output.
+type githubClient struct {
+	apiURL     string
+	graphqlURL string
+	token      string
+	owner      string
+	repo       string
+	http       *http.Client
+	sleep      func(time.Duration)
+}
+
+// githubPull is the subset of the REST pull request object yoke reads.
+type githubPull struct {
+	Number   int    `json:"number"`
+	NodeID   string `json:"node_id"`
+	HTMLURL  string `json:"html_url"`
+	Title    string `json:"title"`
+	Draft    bool   `json:"draft"`
+	MergedAt string `json:"merged_at"`
+	Updated  string `json:"updated_at"`
+	Head     struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+func (p githubPull) entry() prListEntry {
+	return prListEntry{Number: p.Number, URL: p.HTMLURL, IsDraft: p.Draft, HeadRefName: p.Head.Ref}
+}
+
+func (p githubPull) labelNames() []string {
+	names := make([]string, 0, len(p.Labels))
+	for _, label := range p.Labels {
+		names = append(names, label.Name)
 	}
+	return names
+}
 
-	normalizedPrefix, err := normalizeBDPrefix(cfg.BDPrefix)
+// githubAPIError is a non-2xx response; Message is GitHub's error message.
+type githubAPIError struct {
+	Method  string
+	Path    string
+	Status  int
+	Message string
+}
+
+func (e *githubAPIError) Error() string {
+	return fmt.Sprintf("GitHub API %s %s: %d %s", e.Method, e.Path, e.Status, valueOrFallback(e.Message, http.StatusText(e.Status)))
+}
+
+var (
+	githubClientOnce   sync.Once
+	githubClientShared *githubClient
+	githubClientErr    error
+)
+
+// githubAPI returns the client for the origin remote, built once per process.
+// It fails when origin is not a GitHub remote or no token is available.
+func githubAPI() (*githubClient, error) {
+	githubClientOnce.Do(func() {
+		githubClientShared, githubClientErr = newGitHubClientForOrigin()
+	})
+	return githubClientShared, githubClientErr
+}
+
+func newGitHubClientForOrigin() (*githubClient, error) {
+	origin, err := commandOutput("git", "remote", "get-url", "origin")
 	if err != nil {
-		return cfg, err
+		return nil, errors.New("no origin remote")
 	}
-	cfg.BDPrefix = normalizedPrefix
+	host, owner, repo, ok := parseGitHubRemote(strings.TrimSpace(origin))
+	if !ok {
+		return nil, fmt.Errorf("origin %s is not a GitHub repository", strings.TrimSpace(origin))
+	}
+	token := githubToken(host)
+	if token == "" {
+		return nil, errors.New("no GitHub token: set GH_TOKEN or run gh auth login")
+	}
+	apiURL, graphqlURL := "https://api.github.com", "https://api.github.com/graphql"
+	if host != "github.com" {
+		apiURL, graphqlURL = "https://"+host+"/api/v3", "https://"+host+"/api/graphql"
+	}
+	return newGitHubClient(apiURL, graphqlURL, token, owner, repo), nil
+}
 
-	return cfg, nil
+// mutates reports whether a request changes GitHub state. GraphQL reads
+// are POSTs too, so only mutation documents count.
+func (c *githubClient) mutates(method, target string, body any) bool {
+	if method == http.MethodGet || method == http.MethodHead {
+		return false
+	}
+	if target != c.graphqlURL {
+		return true
+	}
+	request, _ := body.(map[string]any)
+	query, _ := request["query"].(string)
+	return strings.HasPrefix(strings.TrimSpace(query), "mutation")
 }
 
-func parseShellValue(raw string) string {
-	value := strings.TrimSpace(raw)
-	if value == "" {
-		return ""
+func newGitHubClient(apiURL, graphqlURL, token, owner, repo string) *githubClient {
+	return &githubClient{
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		graphqlURL: graphqlURL,
+		token:      token,
+		owner:      owner,
+		repo:       repo,
+		http:       &http.Client{Timeout: 30 * time.Second},
+		sleep:      time.Sleep,
 	}
+}
 
-	if strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) && len(value) >= 2 {
-		if unquoted, err := strconv.Unquote(value); err == nil {
-			return unquoted
+// parseGitHubRemote accepts https, ssh://, and scp-style git remotes.
+func parseGitHubRemote(remote string) (string, string, string, bool) {
+	var host, path string
+	switch {
+	case strings.Contains(remote, "://"):
+		parsed, err := url.Parse(remote)
+		if err != nil {
+			return "", "", "", false
 		}
-		return strings.Trim(value, `"`)
+		host, path = parsed.Hostname(), parsed.Path
+	case strings.Contains(remote, ":"):
+		hostPart, pathPart, _ := strings.Cut(remote, ":")
+		if at := strings.LastIndex(hostPart, "@"); at >= 0 {
+			hostPart = hostPart[at+1:]
+		}
+		host, path = hostPart, pathPart
+	default:
+		return "", "", "", false
 	}
-	if strings.HasPrefix(value, `'`) && strings.HasSuffix(value, `'`) && len(value) >= 2 {
-		return strings.Trim(value, `'`)
+	if host == "ssh.github.com" {
+		host = "github.com"
 	}
-
-	if idx := strings.Index(value, " #"); idx >= 0 {
-		value = value[:idx]
+	parts := strings.Split(strings.Trim(strings.TrimSuffix(path, ".git"), "/"), "/")
+	if host == "" || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", false
 	}
-	return strings.TrimSpace(value)
+	return host, parts[0], parts[1], true
 }
 
-func writeConfig(cfg config) error {
-	content := renderConfig(cfg)
-	return os.WriteFile(cfg.Path, []byte(content), 0o644)
+func githubToken(host string) string {
+	keys := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	if host != "github.com" {
+		keys = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	for _, key := range keys {
+		if token := strings.TrimSpace(os.Getenv(key)); token != "" {
+			return token
+		}
+	}
+	if !commandExists("gh") {
+		return ""
+	}
+	output, err := commandOutput("gh", "auth", "token", "--hostname", host)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(output)
 }
 
-func renderConfig(cfg config) string {
-	return fmt.Sprintf(`# shellcheck shell=bash
-
-# Base branch for PRs created by yoke.
-YOKE_BASE_BRANCH=%s
-
-# Check command or executable path. Set to "skip" to bypass.
-YOKE_CHECK_CMD=%s
+// do sends one API request, retrying rate-limited and 5xx responses. The
+// response body is decoded into out when out is non-nil.
+func (c *githubClient) do(method, target string, body, out any) (http.Header, error) {
+	defer timePhase("GitHub API", false)()
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = data
+	}
+	if dryRunMode && c.mutates(method, target, body) {
+		path := strings.TrimPrefix(strings.TrimPrefix(target, c.apiURL), c.graphqlURL)
+		logDryRun("", "github "+method+" "+valueOrFallback(path, "graphql"), []string{string(payload)})
+		return http.Header{}, nil
+	}
+	if !strings.HasPrefix(target, "http") {
+		target = c.apiURL + target
+	}
 
-# Prefix used for bd issue IDs (example: bd-a1b2).
-YOKE_BD_PREFIX=%s
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest(method, target, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", githubAPIVersion)
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			if attempt < githubMaxAttempts {
+				c.sleep(time.Duration(attempt) * time.Second)
+				continue
+			}
+			return nil, err
+		}
+		data, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return nil, readErr
+		}
 
-# Selected coding agent for writing (codex or claude).
-YOKE_WRITER_AGENT=%s
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if out != nil && len(data) > 0 {
+				if err := json.Unmarshal(data, out); err != nil {
+					return nil, fmt.Errorf("decode GitHub API %s %s: %w", method, req.URL.Path, err)
+				}
+			}
+			return resp.Header, nil
+		}
 
-# Optional writer command for yoke daemon loops.
-# Runs with ISSUE_ID, ROOT_DIR, BD_PREFIX, and YOKE_ROLE=writer.
-# Expected behavior: implement the issue and transition state via yoke submit.
-YOKE_WRITER_CMD=%s
+		apiErr := &githubAPIError{Method: method, Path: req.URL.Path, Status: resp.StatusCode}
+		var message struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &message) == nil {
+			apiErr.Message = message.Message
+		}
+		if attempt >= githubMaxAttempts {
+			return nil, apiErr
+		}
+		if wait, limited := githubRateLimitWait(resp, time.Now()); limited {
+			if wait > maxGitHubRateLimitWait {
+				return nil, fmt.Errorf("%w (rate limited; retry in %s)", apiErr, wait.Round(time.Second))
+			}
+			note(fmt.Sprintf("GitHub API rate limited; retrying in %s", wait.Round(time.Second)))
+			c.sleep(wait)
+			continue
+		}
+		if resp.StatusCode >= 500 {
+			c.sleep(time.Duration(attempt) * time.Second)
+			continue
+		}
+		return nil, apiErr
+	}
+}
 
-# Selected coding agent for reviewing (codex or claude).
-YOKE_REVIEWER_AGENT=%s
+// githubRateLimitWait reports whether resp is a rate-limit rejection and how
+// long to wait, from Retry-After or the X-RateLimit-Reset epoch.
+func githubRateLimitWait(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusForbidden {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, resp.StatusCode == http.StatusTooManyRequests
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return time.Minute, true
+	}
+	wait := time.Unix(reset, 0).Sub(now)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait, true
+}
 
-# Optional reviewer agent command. Runs when using: yoke review --agent
-# and yoke daemon. Runs with ISSUE_ID, ROOT_DIR, BD_PREFIX, and YOKE_ROLE=reviewer.
-# Expected behavior for daemon mode: execute yoke review --approve or --reject.
-# Example:
-# YOKE_REVIEW_CMD='codex exec "Review $ISSUE_ID and run yoke review $ISSUE_ID --approve or --reject with reason"'
-YOKE_REVIEW_CMD=%s
+var githubNextLinkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
 
-# Pull request template path.
-YOKE_PR_TEMPLATE=%s
-`,
-		quoteShell(cfg.BaseBranch),
-		quoteShell(cfg.CheckCmd),
-		quoteShell(cfg.BDPrefix),
-		quoteShell(cfg.WriterAgent),
-		quoteShell(cfg.WriterCmd),
-		quoteShell(cfg.ReviewerAgent),
-		quoteShell(cfg.ReviewCmd),
-		quoteShell(cfg.PRTemplate),
-	)
+// getPages GETs target and then each page its rel="next" Link header points
+// to, handing every page body to visit until visit returns false.
+func (c *githubClient) getPages(target string, visit func(json.RawMessage) (bool, error)) error {
+	for target != "" {
+		var page json.RawMessage
+		header, err := c.do(http.MethodGet, target, nil, &page)
+		if err != nil {
+			return err
+		}
+		more, err := visit(page)
+		if err != nil {
+			return fmt.Errorf("decode GitHub API page %s: %w", target, err)
+		}
+		if !more {
+			return nil
+		}
+		target = ""
+		if match := githubNextLinkPattern.FindStringSubmatch(header.Get("Link")); match != nil {
+			target = match[1]
+		}
+	}
+	return nil
 }
 
-func quoteShell(value string) string {
-	return strconv.Quote(value)
+// listPulls pages through GET /pulls; stop ends paging early once it returns
+// true for a pull (which is not included).
+func (c *githubClient) listPulls(query url.Values, stop func(githubPull) bool) ([]githubPull, error) {
+	query.Set("per_page", "100")
+	target := fmt.Sprintf("/repos/%s/%s/pulls?%s", c.owner, c.repo, query.Encode())
+	pulls := []githubPull{}
+	err := c.getPages(target, func(raw json.RawMessage) (bool, error) {
+		var page []githubPull
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return false, err
+		}
+		for _, pull := range page {
+			if stop != nil && stop(pull) {
+				return false, nil
+			}
+			pulls = append(pulls, pull)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pulls, nil
 }
 
-func ensureRepoRoot() (string, error) {
-	root, err := commandOutput("git", "rev-parse", "--show-toplevel")
+// pullForBranch returns the newest PR whose head is branch. state is open,
+// closed, all, or merged (closed with a merge).
+func (c *githubClient) pullForBranch(branch, state string) (githubPull, bool, error) {
+	query := url.Values{"head": {c.owner + ":" + branch}, "state": {state}}
+	if state == "merged" {
+		query.Set("state", "closed")
+	}
+	pulls, err := c.listPulls(query, nil)
 	if err != nil {
-		return "", errors.New("run inside a git repository")
+		return githubPull{}, false, err
+	}
+	for _, pull := range pulls {
+		if state == "merged" && pull.MergedAt == "" {
+			continue
+		}
+		return pull, true, nil
 	}
-	return strings.TrimSpace(root), nil
+	return githubPull{}, false, nil
 }
 
-func detectAvailableAgents() []detectedAgent {
-	available := make([]detectedAgent, 0, len(supportedAgents))
-	for _, spec := range supportedAgents {
-		for _, binary := range spec.Binaries {
-			if commandExists(binary) {
-				available = append(available, detectedAgent{
-					ID:     spec.ID,
-					Name:   spec.Name,
-					Binary: binary,
-				})
-				break
-			}
+func (c *githubClient) createPull(title, head, base, body string, draft bool) (githubPull, error) {
+	var pull githubPull
+	_, err := c.do(http.MethodPost, fmt.Sprintf("/repos/%s/%s/pulls", c.owner, c.repo), map[string]any{
+		"title": title,
+		"head":  head,
+		"base":  base,
+		"body":  body,
+		"draft": draft,
+	}, &pull)
+	return pull, err
+}
+
+func (c *githubClient) commentOnPull(number, body string) error {
+	_, err := c.do(http.MethodPost, fmt.Sprintf("/repos/%s/%s/issues/%s/comments", c.owner, c.repo, number), map[string]string{"body": body}, nil)
+	return err
+}
+
+// issueComments lists the comments on an issue or PR; GitHub serves PR
+// conversation comments from the issue comments endpoint.
+func (c *githubClient) issueComments(number string) ([]githubIssueComment, error) {
+	comments := []githubIssueComment{}
+	path := fmt.Sprintf("/repos/%s/%s/issues/%s/comments?per_page=100", c.owner, c.repo, number)
+	err := c.getPages(path, func(raw json.RawMessage) (bool, error) {
+		var page []githubIssueComment
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return false, err
 		}
+		comments = append(comments, page...)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return comments, nil
+}
 
-	return available
+func (c *githubClient) editComment(commentID int, body string) error {
+	_, err := c.do(http.MethodPatch, fmt.Sprintf("/repos/%s/%s/issues/comments/%d", c.owner, c.repo, commentID), map[string]string{"body": body}, nil)
+	return err
 }
 
-func normalizeAgentID(input string) (string, bool) {
-	value := strings.ToLower(strings.TrimSpace(input))
-	for _, spec := range supportedAgents {
-		if value == spec.ID {
-			return spec.ID, true
-		}
-		for _, binary := range spec.Binaries {
-			if value == strings.ToLower(binary) {
-				return spec.ID, true
-			}
+// upsertPullComment edits the newest PR comment starting with heading, or
+// posts body as a new comment when there is none. It returns the id of the
+// edited comment, or 0 when it posted a new one.
+func (c *githubClient) upsertPullComment(number, heading, body string) (int, error) {
+	comments, err := c.issueComments(number)
+	if err != nil {
+		return 0, err
+	}
+	for i := len(comments) - 1; i >= 0; i-- {
+		if strings.HasPrefix(strings.TrimSpace(comments[i].Body), heading) {
+			return comments[i].ID, c.editComment(comments[i].ID, body)
 		}
 	}
+	return 0, c.commentOnPull(number, body)
+}
 
-	return "", false
+func updatePRComment(number, heading, body string) (bool, error) {
+	client, err := githubAPI()
+	editedID := 0
+	if err == nil {
+		editedID, err = client.upsertPullComment(number, heading, body)
+	}
+	if editedID > 0 {
+		auditGitHubAPI("", http.MethodPatch, githubRepoPath(client, fmt.Sprintf("/issues/comments/%d", editedID)), number, err, body)
+	} else {
+		auditGitHubAPI("", http.MethodPost, githubRepoPath(client, "/issues/"+number+"/comments"), number, err, body)
+	}
+	return editedID > 0, err
 }
 
-func normalizeBDPrefix(input string) (string, error) {
-	value := strings.ToLower(strings.TrimSpace(input))
-	if value == "" {
-		return defaultBDPrefix, nil
+// auditGitHubAPI records a GitHub API change to a PR in the audit log as the
+// request yoke made: the action is the method and endpoint, as in dry-run
+// entries, and the arguments start with the PR number once there is one.
+func auditGitHubAPI(issue, method, endpoint, number string, err error, details ...string) {
+	if auditRoot == "" {
+		return
 	}
+	args := details
+	if number != "" && number != "0" {
+		args = append([]string{"pr=" + number}, details...)
+	}
+	appendAuditEntry(auditRoot, newAuditEntry(issue, "github "+method+" "+endpoint, args, err))
+}
 
-	validPrefix := regexp.MustCompile(`^[a-z0-9](?:[a-z0-9._-]*[a-z0-9])?$`)
-	if !validPrefix.MatchString(value) {
-		return "", fmt.Errorf("invalid bd prefix %q: use letters, numbers, '.', '_' or '-', and avoid trailing separators", input)
+// githubRepoPath names a repository endpoint for the audit log, with gh's
+// placeholders when there is no client to say which repository it is.
+func githubRepoPath(client *githubClient, suffix string) string {
+	if client == nil {
+		return "/repos/{owner}/{repo}" + suffix
 	}
+	return fmt.Sprintf("/repos/%s/%s%s", client.owner, client.repo, suffix)
+}
 
-	return value, nil
+func (c *githubClient) pull(number string) (githubPull, error) {
+	var pull githubPull
+	_, err := c.do(http.MethodGet, fmt.Sprintf("/repos/%s/%s/pulls/%s", c.owner, c.repo, number), nil, &pull)
+	return pull, err
 }
 
-func containsAgentID(agents []detectedAgent, id string) bool {
-	for _, agent := range agents {
-		if agent.ID == id {
-			return true
-		}
+// setPullDraft flips a PR between draft and ready; REST has no endpoint for
+// this, so it goes through the GraphQL mutations gh pr ready uses. It returns
+// the mutation it ran, or "" when the PR was already in that state.
+func (c *githubClient) setPullDraft(number string, draft bool) (string, error) {
+	pull, err := c.pull(number)
+	if err != nil {
+		return "", err
 	}
-	return false
+	if pull.Draft == draft {
+		return "", nil
+	}
+	name := "markPullRequestReadyForReview"
+	if draft {
+		name = "convertPullRequestToDraft"
+	}
+	mutation := "mutation($id: ID!) { " + name + "(input: {pullRequestId: $id}) { clientMutationId } }"
+	var result struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := c.do(http.MethodPost, c.graphqlURL, map[string]any{"query": mutation, "variables": map[string]string{"id": pull.NodeID}}, &result); err != nil {
+		return name, err
+	}
+	if len(result.Errors) > 0 {
+		return name, fmt.Errorf("GitHub GraphQL: %s", result.Errors[0].Message)
+	}
+	return name, nil
 }
 
-func promptForAgentSelection(
-	role string,
-	available []detectedAgent,
-	current string,
-	reader *bufio.Reader,
-) (string, error) {
-	if len(available) == 0 {
-		return current, nil
+// lookupPRForBranch is the error-returning PR lookup. Without an origin
+// remote there is no PR; a GitHub client that cannot be built (not GitHub, no
+// token) is an error, so callers never mistake it for a missing PR.
+func lookupPRForBranch(branch, state string) (prListEntry, bool, error) {
+	if strings.TrimSpace(branch) == "" || !hasOriginRemote() {
+		return prListEntry{}, false, nil
+	}
+	client, err := githubAPI()
+	if err != nil {
+		return prListEntry{}, false, err
+	}
+	pull, ok, err := client.pullForBranch(branch, state)
+	if err != nil || !ok {
+		return prListEntry{}, false, err
 	}
+	return pull.entry(), true, nil
+}
 
-	defaultAgent := current
-	if defaultAgent == "" || !containsAgentID(available, defaultAgent) {
-		defaultAgent = available[0].ID
+func commentOnPR(number, body string) error {
+	client, err := githubAPI()
+	if err == nil {
+		err = client.commentOnPull(number, body)
 	}
+	auditGitHubAPI("", http.MethodPost, githubRepoPath(client, "/issues/"+number+"/comments"), number, err, body)
+	return err
+}
 
-	for {
-		note(fmt.Sprintf("Select %s agent:", role))
-		for i, agent := range available {
-			marker := ""
-			if agent.ID == defaultAgent {
-				marker = " (default)"
-			}
-			note(fmt.Sprintf("  %d) %s [%s]%s", i+1, agent.ID, agent.Binary, marker))
-		}
-		fmt.Printf("%s agent [%s]: ", titleCase(role), defaultAgent)
+func setPRDraft(number string, draft bool) error {
+	client, err := githubAPI()
+	mutation := ""
+	if err == nil {
+		mutation, err = client.setPullDraft(number, draft)
+	}
+	if mutation != "" || err != nil {
+		auditGitHubAPI("", http.MethodPost, "graphql", number, err, valueOrFallback(mutation, "set draft="+strconv.FormatBool(draft)))
+	}
+	return err
+}
 
-		line, err := reader.ReadString('\n')
-		if err != nil && !errors.Is(err, io.EOF) {
-			return "", err
-		}
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" {
-			return defaultAgent, nil
-		}
+type prListEntry struct {
+	Number      int    `json:"number"`
+	URL         string `json:"url"`
+	IsDraft     bool   `json:"isDraft"`
+	HeadRefName string `json:"headRefName"`
+}
 
-		if index, convErr := strconv.Atoi(trimmed); convErr == nil {
-			if index >= 1 && index <= len(available) {
-				return available[index-1].ID, nil
-			}
-			note("Invalid selection. Enter a valid option number or agent name.")
-			if errors.Is(err, io.EOF) {
-				return defaultAgent, nil
-			}
-			continue
-		}
+func openPRForIssue(issue string) (string, string, bool, bool, error) {
+	branch := branchForIssue(issue)
+	return openPRForBranch(branch)
+}
 
-		if normalized, ok := normalizeAgentID(trimmed); ok && containsAgentID(available, normalized) {
-			return normalized, nil
-		}
+// openPRForBranch returns the branch's open PR number, URL, and draft state.
+// ok is false when there is no open PR; a failed lookup is an error, so
+// callers never mistake a GitHub outage for a missing PR.
+func openPRForBranch(branch string) (string, string, bool, bool, error) {
+	pr, ok, err := lookupPRForBranch(branch, "open")
+	if err != nil {
+		return "", "", false, false, fmt.Errorf("PR lookup for %s failed: %w", branch, err)
+	}
+	if !ok {
+		return "", "", false, false, nil
+	}
+	return strconv.Itoa(pr.Number), pr.URL, pr.IsDraft, true, nil
+}
 
-		note("Invalid selection. Enter a valid option number or agent name.")
-		if errors.Is(err, io.EOF) {
-			return defaultAgent, nil
-		}
+func prURLForBranch(branch string) string {
+	pr, _, err := lookupPRForBranch(branch, "all")
+	if err != nil {
+		note("warning: PR lookup for " + branch + " failed: " + err.Error())
 	}
+	return pr.URL
 }
 
-func promptForBDPrefix(current string, reader *bufio.Reader) (string, error) {
-	defaultPrefix, err := normalizeBDPrefix(current)
+// postSubmitPRComment posts the writer handoff to the issue's PR; amend
+// edits the previous handoff comment instead. Failures only warn; it reports
+// whether the comment landed.
+func postSubmitPRComment(issue, doneText, remaining, decision, uncertain, checks string, amend bool) bool {
+	number, _, _, ok, err := openPRForIssue(issue)
 	if err != nil {
-		defaultPrefix = defaultBDPrefix
+		note("warning: skipping writer handoff PR comment: " + err.Error())
+		return false
+	}
+	if !ok {
+		note("warning: no open PR found for issue branch; skipping writer handoff PR comment")
+		return false
 	}
 
-	for {
-		fmt.Printf("BD issue prefix [%s]: ", defaultPrefix)
-		line, readErr := reader.ReadString('\n')
-		if readErr != nil && !errors.Is(readErr, io.EOF) {
-			return "", readErr
+	body := formatWriterPRComment(issue, doneText, remaining, decision, uncertain, checks)
+	if amend {
+		edited, err := updatePRComment(number, "## Writer -> Reviewer Handoff", body)
+		if err != nil {
+			note("warning: failed to update writer handoff PR comment: " + err.Error())
+			return false
 		}
-
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" {
-			return defaultPrefix, nil
+		if edited {
+			note("Updated writer handoff comment on PR #" + number)
+			return true
 		}
+	} else if err := commentOnPR(number, body); err != nil {
+		note("warning: failed to post writer handoff PR comment: " + err.Error())
+		return false
+	}
+	note("Posted writer handoff comment to PR #" + number)
+	return true
+}
 
-		normalized, normErr := normalizeBDPrefix(trimmed)
-		if normErr == nil {
-			return normalized, nil
+// amendIssueHandoff replaces the issue's latest writer handoff comment,
+// appending a new one when there is none or the backend cannot edit.
+func amendIssueHandoff(issue, text string) error {
+	comments, err := tracker.Comments(issue)
+	if err != nil {
+		return err
+	}
+	commentID := 0
+	for i := len(comments) - 1; i >= 0; i-- {
+		if strings.HasPrefix(strings.TrimSpace(comments[i].Text), "Writer handoff:") {
+			commentID = comments[i].ID
+			break
 		}
-		note(normErr.Error())
+	}
+	edited, err := editOrAddComment(issue, commentID, text)
+	if err != nil {
+		return err
+	}
+	if edited {
+		note(fmt.Sprintf("Updated the writer handoff comment on %s.", issue))
+	} else if commentID > 0 {
+		note(fmt.Sprintf("warning: the issue tracker cannot edit comments; added a new handoff comment to %s.", issue))
+	}
+	return nil
+}
 
-		if errors.Is(readErr, io.EOF) {
-			return defaultPrefix, nil
-		}
+func postReviewPRComment(issue, action, rejectReason, noteText string, runAgent bool, reviewedSHA string) {
+	number, _, _, ok, err := openPRForIssue(issue)
+	if err != nil {
+		note("warning: skipping reviewer PR comment: " + err.Error())
+		return
+	}
+	if !ok {
+		note("warning: no open PR found for issue branch; skipping reviewer PR comment")
+		return
+	}
+
+	body := formatReviewerPRComment(issue, action, rejectReason, noteText, runAgent, reviewedSHA)
+	if err := commentOnPR(number, body); err != nil {
+		note("warning: failed to post reviewer PR comment: " + err.Error())
+		return
+	}
+	note("Posted reviewer comment to PR #" + number)
+}
+
+func formatWriterPRComment(issue, doneText, remaining, decision, uncertain, checks string) string {
+	lines := []string{
+		"## Writer -> Reviewer Handoff",
+		"",
+		"- Issue: `" + sanitizeCommentLine(issue) + "`",
+		"- Done: " + sanitizeCommentLine(doneText),
+		"- Remaining: " + sanitizeCommentLine(remaining),
+	}
+	if strings.TrimSpace(decision) != "" {
+		lines = append(lines, "- Decision: "+sanitizeCommentLine(decision))
+	}
+	if strings.TrimSpace(uncertain) != "" {
+		lines = append(lines, "- Uncertain: "+sanitizeCommentLine(uncertain))
+	}
+	lines = append(lines, "- Checks: "+sanitizeCommentLine(checks))
+	lines = append(lines, "")
+	lines = append(lines, "_Posted automatically by `yoke submit`._")
+	return strings.Join(lines, "\n")
+}
+
+func formatIssueHandoffComment(doneText, remaining, decision, uncertain, checks string) string {
+	lines := []string{
+		"Writer handoff:",
+		"- Done: " + sanitizeCommentLine(doneText),
+		"- Remaining: " + sanitizeCommentLine(remaining),
+		"- Checks: " + sanitizeCommentLine(checks),
+	}
+	if strings.TrimSpace(decision) != "" {
+		lines = append(lines, "- Decision: "+sanitizeCommentLine(decision))
+	}
+	if strings.TrimSpace(uncertain) != "" {
+		lines = append(lines, "- Uncertain: "+sanitizeCommentLine(uncertain))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatReviewerPRComment(issue, action, rejectReason, noteText string, runAgent bool, reviewedSHA string) string {
+	decision := "note"
+	if strings.TrimSpace(action) != "" {
+		decision = strings.TrimSpace(action)
+	}
+
+	lines := []string{
+		"## Reviewer Update",
+		"",
+		"- Issue: `" + sanitizeCommentLine(issue) + "`",
+		"- Decision: " + sanitizeCommentLine(decision),
+	}
+	if strings.TrimSpace(reviewedSHA) != "" {
+		lines = append(lines, "- Reviewed commit: `"+sanitizeCommentLine(reviewedSHA)+"`")
+	}
+	if decision == "reject" && strings.TrimSpace(rejectReason) != "" {
+		lines = append(lines, "- Reject reason: "+sanitizeCommentLine(rejectReason))
+	}
+	if strings.TrimSpace(noteText) != "" {
+		lines = append(lines, "- Note: "+sanitizeCommentLine(noteText))
+	}
+	if runAgent {
+		lines = append(lines, "- Reviewer command: executed")
 	}
+	lines = append(lines, "")
+	lines = append(lines, "_Posted automatically by `yoke review`._")
+	return strings.Join(lines, "\n")
 }
 
-func isInteractiveTerminal(file *os.File) bool {
-	info, err := file.Stat()
-	if err != nil {
-		return false
+func formatDaemonNoConsensusPRComment(issue, status string, maxIterations int) string {
+	lines := []string{
+		"## Daemon Notice",
+		"",
+		"- Issue: `" + sanitizeCommentLine(issue) + "`",
+		"- Status: " + sanitizeCommentLine(status),
+		"- Outcome: max daemon iterations reached without writer/reviewer consensus",
+		"- Iterations: " + strconv.Itoa(maxIterations),
+		"- PR state: left in draft for manual intervention",
+		"",
+		"_Posted automatically by `yoke daemon`._",
 	}
-	return info.Mode()&os.ModeCharDevice != 0
+	return strings.Join(lines, "\n")
 }
 
-func titleCase(value string) string {
-	if value == "" {
-		return ""
+func ensurePRReady(number string, isDraft bool) error {
+	if strings.TrimSpace(number) == "" || !isDraft {
+		return nil
 	}
-	return strings.ToUpper(value[:1]) + value[1:]
+	if err := setPRDraft(number, false); err != nil {
+		return fmt.Errorf("failed to mark PR #%s ready after approval: %w", number, err)
+	}
+	note("Marked PR #" + number + " ready for review")
+	return nil
 }
 
-func valueOrUnset(value string) string {
-	if strings.TrimSpace(value) == "" {
-		return "unset"
-	}
-	return value
+func sanitizeCommentLine(value string) string {
+	return strings.Join(strings.Fields(strings.TrimSpace(value)), " ")
 }
 
-func valueOrFallback(value, fallback string) string {
-	if strings.TrimSpace(value) == "" {
-		return fallback
-	}
-	return value
+func hasOriginRemote() bool {
+	_, err := commandOutput("git", "remote", "get-url", "origin")
+	return err == nil
 }
 
-func issueOrNone(issue string) string {
-	if strings.TrimSpace(issue) == "" {
-		return "none"
-	}
-	return issue
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
 
-func configuredAgentStatus(agentID string) string {
-	if strings.TrimSpace(agentID) == "" {
-		return "unset"
+// note prints an informational line. Under --quiet only warnings, lines
+// starting with "warning:", still print.
+func note(msg string) {
+	if verbosity == logQuiet && !isWarning(msg) {
+		return
 	}
-	return agentAvailabilityStatus(agentID)
+	fmt.Println(msg)
 }
 
-func commandConfigStatus(value string) string {
-	if strings.TrimSpace(value) == "" {
-		return "unset"
-	}
-	return "configured"
+func isWarning(msg string) bool {
+	return strings.HasPrefix(msg, "warning:")
 }
 
-func availabilityLabel(available bool) string {
-	if available {
-		return "available"
+// logLevel is how much yoke says about what it is doing. Command output
+// (lists, status, JSON) is not affected; only notes and progress lines are.
+type logLevel int
+
+const (
+	logQuiet logLevel = iota
+	logNormal
+	logVerbose
+)
+
+var logLevelNames = map[logLevel]string{logQuiet: "quiet", logNormal: "normal", logVerbose: "verbose"}
+
+// verbosity is set from -v/-q or YOKE_LOG_LEVEL when yoke starts.
+var verbosity = logNormal
+
+// globalFlags are accepted before or after any command and are stripped
+// before the command parses its own flags.
+var globalFlags = []helpFlag{
+	{Name: "--verbose", Short: "v", Description: "Also print every external command yoke runs (git, bd, gh, agents) to stderr."},
+	{Name: "--quiet", Short: "q", Description: "Print only command output, warnings, and errors."},
+	{Name: "--no-color", Description: "Print plain text even on a terminal. NO_COLOR does the same."},
+}
+
+func logLevelFromEnv() (logLevel, error) {
+	value := strings.ToLower(strings.TrimSpace(os.Getenv("YOKE_LOG_LEVEL")))
+	if value == "" {
+		return logNormal, nil
 	}
-	return "missing"
+	for level, name := range logLevelNames {
+		if name == value {
+			return level, nil
+		}
+	}
+	return logNormal, fmt.Errorf("invalid YOKE_LOG_LEVEL %q (want quiet, normal, or verbose)", value)
 }
 
-func agentAvailabilityStatus(agentID string) string {
-	normalized, ok := normalizeAgentID(agentID)
-	if !ok {
-		return "unknown"
+// hoistGlobalFlags moves global flags given before the command name, as in
+// yoke -q status, after it so the command name is always args[0].
+func hoistGlobalFlags(args []string) []string {
+	i := 0
+	for i < len(args) && (globalFlagLevel(args[i]) != logNormal || args[i] == "--no-color" || args[i] == "--dry-run") {
+		i++
+	}
+	if i == 0 {
+		return args
+	}
+	if i == len(args) {
+		return append([]string{"help"}, args...)
 	}
+	hoisted := append([]string{args[i]}, args[:i]...)
+	return append(hoisted, args[i+1:]...)
+}
 
-	for _, spec := range supportedAgents {
-		if spec.ID != normalized {
+// splitGlobalFlags removes -v/--verbose and -q/--quiet from a command's
+// arguments, up to a "--", and returns the level they select; start is the
+// level to use when neither is given.
+func splitGlobalFlags(args []string, start logLevel) ([]string, logLevel, error) {
+	level := start
+	var seen string
+	rest := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		flagLevel := globalFlagLevel(arg)
+		if flagLevel == logNormal {
+			rest = append(rest, arg)
 			continue
 		}
-		for _, binary := range spec.Binaries {
-			if commandExists(binary) {
-				return "available via " + binary
-			}
+		if seen != "" && level != flagLevel {
+			return nil, logNormal, fmt.Errorf("%s and %s cannot be combined", seen, arg)
 		}
-		return "not detected"
+		seen = arg
+		level = flagLevel
 	}
-
-	return "unknown"
-}
-
-func commandExists(name string) bool {
-	_, err := lookPath(name)
-	return err == nil
+	return rest, level, nil
 }
 
-func runCommand(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+func globalFlagLevel(arg string) logLevel {
+	switch arg {
+	case "-v", "--verbose":
+		return logVerbose
+	case "-q", "--quiet":
+		return logQuiet
+	}
+	return logNormal
 }
 
-func runCommandDiscard(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	return cmd.Run()
+// stripFlag removes every standalone name from args, up to a "--", and
+// reports whether it was there.
+func stripFlag(args []string, name string) ([]string, bool) {
+	found := false
+	rest := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if arg == name {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
 }
 
-func commandOutput(name string, args ...string) (string, error) {
-	cmd := exec.Command(name, args...)
-	out, err := cmd.Output()
-	return string(out), err
+// setVerbosity applies level and exports it as YOKE_LOG_LEVEL so yoke
+// processes started by this one (agents running yoke submit, hooks) follow
+// the same level.
+func setVerbosity(level logLevel) {
+	verbosity = level
+	if level == logNormal && os.Getenv("YOKE_LOG_LEVEL") == "" {
+		return
+	}
+	os.Setenv("YOKE_LOG_LEVEL", logLevelNames[level])
 }
 
-func commandCombinedOutput(name string, args ...string) string {
-	cmd := exec.Command(name, args...)
-	out, _ := cmd.CombinedOutput()
-	return string(out)
-}
+// colorOutput is whether status, doctor, list, and queue style their output
+// with ANSI colors: only on a terminal, and never with --no-color, NO_COLOR,
+// or TERM=dumb. The text is the same either way.
+var colorOutput = false
 
-func refExists(ref string) bool {
-	err := runCommandDiscard("git", "show-ref", "--verify", "--quiet", ref)
-	return err == nil
-}
+const (
+	styleBold    = "1"
+	styleDim     = "2"
+	styleRed     = "31"
+	styleGreen   = "32"
+	styleYellow  = "33"
+	styleReverse = "7"
+)
 
-func issuePatternForPrefix(prefix string) *regexp.Regexp {
-	normalized, err := normalizeBDPrefix(prefix)
-	if err != nil {
-		normalized = defaultBDPrefix
+func setColor(noColor bool) {
+	if noColor {
+		os.Setenv("NO_COLOR", "1")
 	}
-	return regexp.MustCompile(regexp.QuoteMeta(normalized) + `-[a-z0-9]+(?:\.[a-z0-9]+)*`)
-}
-
-func extractIssueID(s, prefix string) string {
-	return issuePatternForPrefix(prefix).FindString(strings.ToLower(s))
+	colorOutput = os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && isInteractiveTerminal(os.Stdout)
 }
 
-func extractIssueIDAnyPrefix(s string) string {
-	return anyIssuePattern.FindString(strings.ToLower(s))
+// paint wraps text in the ANSI style when color output is on.
+func paint(style, text string) string {
+	if !colorOutput || style == "" || text == "" {
+		return text
+	}
+	return "\x1b[" + style + "m" + text + "\x1b[0m"
 }
 
-func looksLikeIssueID(value, prefix string) bool {
-	pattern := issuePatternForPrefix(prefix)
-	normalized := strings.ToLower(strings.TrimSpace(value))
-	return pattern.FindString(normalized) == normalized
+// valueStyles colors the status words status, doctor, list, and queue print.
+var valueStyles = map[string]string{
+	"ok":          styleGreen,
+	"available":   styleGreen,
+	"configured":  styleGreen,
+	"approved":    styleGreen,
+	"closed":      styleGreen,
+	"merged":      styleGreen,
+	"in_progress": styleYellow,
+	"in_review":   styleYellow,
+	"warning":     styleYellow,
+	"missing":     styleRed,
+	"unavailable": styleRed,
+	"blocked":     styleRed,
+	"orphaned":    styleRed,
+	"rejected":    styleRed,
+	"unset":       styleDim,
+	"unknown":     styleDim,
+	"none":        styleDim,
+	"-":           styleDim,
 }
 
-func looksLikeIssueIDAnyPrefix(value string) bool {
-	normalized := strings.ToLower(strings.TrimSpace(value))
-	return anyIssuePattern.FindString(normalized) == normalized
+// valueStyle picks the style for a value by its first word, so
+// "available via codex" is green like "available".
+func valueStyle(value string) string {
+	word, _, _ := strings.Cut(value, " ")
+	return valueStyles[strings.TrimSuffix(word, ":")]
 }
 
-func nextIssueID(prefix string) string {
-	output := commandCombinedOutput("bd", "list", "--status", "open", "--ready", "--json", "--limit", "20")
-	issues, err := parseBDListIssuesJSON(output)
-	if err != nil {
-		return ""
-	}
-	return firstMatchingIssueID(issues, prefix, "open")
+// statusLine formats "key: value" with the key dimmed and the value colored
+// by valueStyle.
+func statusLine(key, value string) string {
+	return paint(styleDim, key+":") + " " + paint(valueStyle(value), value)
 }
 
-func firstReviewableIssueID(prefix string) string {
-	output := commandCombinedOutput("bd", "list", "--status", "blocked", "--label", reviewQueueLabel, "--json", "--limit", "20")
-	issues, err := parseBDListIssuesJSON(output)
-	if err != nil {
-		return ""
+// printCheckLine prints a doctor line such as "ok: git" with its leading
+// ok/missing/warning word colored.
+func printCheckLine(line string) {
+	word, rest, found := strings.Cut(line, ":")
+	if style := valueStyle(word); found && style != styleDim && style != "" {
+		line = paint(style, word+":") + rest
 	}
-	return firstMatchingIssueID(issues, prefix, "in_review")
+	fmt.Println(line)
 }
 
-func currentBranchIssue(prefix string) string {
-	output, err := commandOutput("git", "rev-parse", "--abbrev-ref", "HEAD")
-	if err != nil {
-		return ""
+// writeColumns writes rows as left-aligned columns two spaces apart, the
+// layout the tables used to get from tabwriter, but measures cells before
+// style(row, col) is applied so colored tables stay aligned. Row 0 is the
+// header.
+func writeColumns(out io.Writer, rows [][]string, style func(row, col int, cell string) string) error {
+	widths := []int{}
+	for _, row := range rows {
+		for col, cell := range row[:len(row)-1] {
+			if col == len(widths) {
+				widths = append(widths, 0)
+			}
+			if width := utf8.RuneCountInString(cell); width > widths[col] {
+				widths[col] = width
+			}
+		}
 	}
-	if issue := extractIssueID(output, prefix); issue != "" {
-		return issue
+	var b strings.Builder
+	for i, row := range rows {
+		for col, cell := range row {
+			b.WriteString(paint(style(i, col, cell), cell))
+			if col < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[col]-utf8.RuneCountInString(cell)+2))
+			}
+		}
+		b.WriteString("\n")
 	}
-	return extractIssueIDAnyPrefix(output)
+	_, err := io.WriteString(out, b.String())
+	return err
 }
 
-func branchForIssue(issue string) string {
-	return "yoke/" + issue
+// progressReporter receives workflow progress from claim, submit, review,
+// and the daemon as events on steps with stable ids (claim.worktree,
+// submit.push, ...). Frontends decide how to render them; the step ids and
+// event names are the contract for tools that follow along, the messages are
+// for humans and may change.
+type progressReporter interface {
+	Start(step, message string)
+	Finish(step, message string)
+	Fail(step string, err error)
+	Info(step, message string)
 }
 
-func issueTitle(issue string) string {
-	output := commandCombinedOutput("bd", "show", issue, "--json")
-	parsed, err := parseBDShowIssueJSON(output)
-	if err == nil && strings.TrimSpace(parsed.Title) != "" {
-		return strings.TrimSpace(parsed.Title)
+// progress is chosen from YOKE_PROGRESS (text or json) when yoke starts.
+var progress progressReporter = textProgress{out: os.Stdout}
+
+func progressReporterFromEnv() (progressReporter, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(os.Getenv("YOKE_PROGRESS"))); mode {
+	case "", "text":
+		return textProgress{out: os.Stdout}, nil
+	case "json":
+		return &jsonProgress{out: os.Stderr, now: time.Now}, nil
+	default:
+		return nil, fmt.Errorf("invalid YOKE_PROGRESS %q (want text or json)", mode)
 	}
-	return issue
 }
 
-func runChecks(root, checkCmd string) error {
-	if checkCmd == "" {
-		checkCmd = defaultCheckCmd
-	}
-	if checkCmd == "skip" {
-		note("Skipping checks (YOKE_CHECK_CMD=skip).")
-		return nil
-	}
+// textProgress prints one "[command] message" line per event. Finish events
+// without a message are silent, and under --quiet only failures and warnings
+// print.
+type textProgress struct {
+	out io.Writer
+}
 
-	resolved := resolveRepoPath(root, checkCmd)
-	if isExecutable(resolved) {
-		note("Running checks via " + resolved)
-		return runCommand(resolved)
-	}
+func (p textProgress) Start(step, message string) { p.print(step, message) }
 
-	note("Running checks: " + checkCmd)
-	cmd := exec.Command("bash", "-lc", checkCmd)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Dir = root
-	return cmd.Run()
+func (p textProgress) Finish(step, message string) { p.print(step, message) }
+
+func (p textProgress) Fail(step string, err error) {
+	p.write(step, fmt.Sprintf("%s failed: %v", step, err))
 }
 
-func resolveRepoPath(root, path string) string {
-	if filepath.IsAbs(path) {
-		return path
+func (p textProgress) Info(step, message string) { p.print(step, message) }
+
+func (p textProgress) print(step, message string) {
+	if verbosity == logQuiet && !isWarning(message) {
+		return
 	}
-	return filepath.Join(root, path)
+	p.write(step, message)
 }
 
-func isExecutable(path string) bool {
-	info, err := os.Stat(path)
-	if err != nil {
-		return false
+func (p textProgress) write(step, message string) {
+	if message == "" {
+		return
 	}
-	return info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+	command, _, _ := strings.Cut(step, ".")
+	fmt.Fprintf(p.out, "[%s] %s\n", command, message)
 }
 
-func issuePRBaseBranch(root string, cfg config, issue string) (string, error) {
-	epicID, err := epicAncestorID(issue)
-	if err != nil {
-		return "", err
-	}
-	if epicID != "" && !strings.EqualFold(strings.TrimSpace(epicID), strings.TrimSpace(issue)) {
-		epicBranch := branchForIssue(epicID)
-		if err := ensureLocalBranch(root, epicBranch, cfg.BaseBranch); err != nil {
-			return "", err
-		}
-		return epicBranch, nil
-	}
-	return cfg.BaseBranch, nil
+// progressEvent is one line of YOKE_PROGRESS=json output.
+type progressEvent struct {
+	Time    string `json:"time"`
+	Step    string `json:"step"`
+	Event   string `json:"event"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
 }
 
-func remoteBranchExists(root, branch string) bool {
-	if strings.TrimSpace(branch) == "" {
-		return false
-	}
-	cmd := exec.Command("git", "-C", root, "ls-remote", "--exit-code", "--heads", "origin", branch)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	return cmd.Run() == nil
+// jsonProgress writes progressEvent lines to stderr so stdout keeps the
+// command's own output.
+type jsonProgress struct {
+	mu  sync.Mutex
+	out io.Writer
+	now func() time.Time
 }
 
-func ensureEpicPRForIssue(root string, cfg config, issue string) error {
-	epicID, err := epicAncestorID(issue)
-	if err != nil {
-		return err
-	}
-	if epicID == "" || strings.EqualFold(strings.TrimSpace(epicID), strings.TrimSpace(issue)) {
-		return nil
-	}
+func (p *jsonProgress) Start(step, message string) { p.emit(step, "start", message, nil) }
 
-	epicBranch := branchForIssue(epicID)
-	if err := ensureLocalBranch(root, epicBranch, cfg.BaseBranch); err != nil {
-		return err
-	}
-	if hasOriginRemote() && !remoteBranchExists(root, epicBranch) {
-		if err := runCommand("git", "-C", root, "push", "-u", "origin", epicBranch); err != nil {
-			return err
-		}
-	}
+func (p *jsonProgress) Finish(step, message string) { p.emit(step, "finish", message, nil) }
+
+func (p *jsonProgress) Fail(step string, err error) { p.emit(step, "fail", "", err) }
+
+func (p *jsonProgress) Info(step, message string) { p.emit(step, "info", message, nil) }
 
-	baseRef := localOrRemoteRef(cfg.BaseBranch)
-	if baseRef == "" {
-		baseRef = cfg.BaseBranch
-	}
-	aheadCount, err := branchAheadCount(root, baseRef, epicBranch)
+func (p *jsonProgress) emit(step, event, message string, err error) {
+	record := progressEvent{Time: p.now().UTC().Format(time.RFC3339Nano), Step: step, Event: event, Message: message}
 	if err != nil {
-		return err
+		record.Error = err.Error()
 	}
-	if aheadCount == 0 {
-		note(fmt.Sprintf("Epic branch %s has no commits beyond %s yet; deferring epic PR creation.", epicBranch, cfg.BaseBranch))
-		return nil
+	data, marshalErr := json.Marshal(record)
+	if marshalErr != nil {
+		return
 	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintln(p.out, string(data))
+}
 
-	epicTitle := issueTitle(epicID)
-	if err := createPRForBranch(root, cfg, epicID, epicTitle, epicBranch, cfg.BaseBranch); err != nil {
+// progressStep reports step around fn: start, then finish or fail.
+func progressStep(step, message string, fn func() error) error {
+	progress.Start(step, message)
+	if err := fn(); err != nil {
+		progress.Fail(step, err)
 		return err
 	}
-	if _, _, _, ok := openPRForBranch(epicBranch); !ok {
-		return fmt.Errorf("no open epic PR found for %s after submit; expected branch %s to have an open PR", epicID, epicBranch)
-	}
+	progress.Finish(step, "")
 	return nil
 }
 
-func branchAheadCount(root, baseRef, headRef string) (int, error) {
-	leftRight := fmt.Sprintf("%s...%s", strings.TrimSpace(baseRef), strings.TrimSpace(headRef))
-	out := strings.TrimSpace(commandCombinedOutput("git", "-C", root, "rev-list", "--right-only", "--count", leftRight))
-	if out == "" {
-		return 0, nil
-	}
-	count, err := strconv.Atoi(out)
-	if err != nil {
-		return 0, fmt.Errorf("parse branch ahead count %q: %w", out, err)
-	}
-	return count, nil
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "yoke: %s\n", err)
+	os.Exit(1)
 }
 
-func branchIsAncestor(root, ancestorRef, descendantRef string) bool {
-	cmd := exec.Command("git", "-C", root, "merge-base", "--is-ancestor", ancestorRef, descendantRef)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	return cmd.Run() == nil
+// helpFlag, commandHelp, and helpTopic are the structured help metadata
+// behind yoke help, the top-level usage listing, and --help-json.
+type helpFlag struct {
+	Name        string `json:"name"`
+	Short       string `json:"short,omitempty"`
+	Value       string `json:"value,omitempty"`
+	Description string `json:"description"`
 }
 
-func integrateApprovedTaskIntoEpic(root string, cfg config, issue string) error {
-	epicID, err := epicAncestorID(issue)
-	if err != nil {
-		return err
-	}
-	if epicID == "" || strings.EqualFold(strings.TrimSpace(epicID), strings.TrimSpace(issue)) {
-		return nil
-	}
+type commandHelp struct {
+	Name    string     `json:"name"`
+	Summary string     `json:"summary"`
+	Usage   string     `json:"usage"`
+	Flags   []helpFlag `json:"flags"`
+}
 
-	epicBranch := branchForIssue(epicID)
-	taskBranch := branchForIssue(issue)
-	if err := ensureLocalBranch(root, taskBranch, cfg.BaseBranch); err != nil {
-		return err
-	}
-	if err := ensureLocalBranch(root, epicBranch, cfg.BaseBranch); err != nil {
-		return err
-	}
-	if !branchIsAncestor(root, epicBranch, taskBranch) {
-		return fmt.Errorf("cannot fast-forward epic branch %s to %s; merge %s into %s manually", epicBranch, taskBranch, taskBranch, epicBranch)
-	}
-	if err := runCommand("git", "-C", root, "branch", "-f", epicBranch, taskBranch); err != nil {
-		return err
-	}
-	if hasOriginRemote() {
-		if err := runCommand("git", "-C", root, "push", "origin", epicBranch); err != nil {
-			return err
-		}
-	}
+type helpStep struct {
+	Text    string `json:"text"`
+	Command string `json:"command,omitempty"`
+}
 
-	epicTitle := issueTitle(epicID)
-	if err := createPRForBranch(root, cfg, epicID, epicTitle, epicBranch, cfg.BaseBranch); err != nil {
-		return err
-	}
-	if _, _, _, ok := openPRForBranch(epicBranch); !ok {
-		return fmt.Errorf("no open epic PR found for %s after integrating %s", epicID, issue)
-	}
-	note(fmt.Sprintf("Integrated %s into epic branch %s", issue, epicBranch))
-	return nil
+type helpTopic struct {
+	Name    string     `json:"name"`
+	Title   string     `json:"title"`
+	Summary string     `json:"summary"`
+	Steps   []helpStep `json:"steps"`
 }
 
-func createPRForBranch(root string, cfg config, issue, title, headBranch, baseBranch string) error {
-	if !commandExists("gh") {
-		note("gh not found; skipping PR creation.")
-		return nil
-	}
-	if !hasOriginRemote() {
-		note("No origin remote; skipping PR creation.")
-		return nil
-	}
-	if strings.TrimSpace(headBranch) == "" {
-		return errors.New("could not determine current branch")
-	}
-	if strings.TrimSpace(baseBranch) == "" {
-		return errors.New("could not determine PR base branch")
-	}
+var commandHelpIndex = []commandHelp{
+	{Name: "init", Summary: "Initialize scaffold, detect available agents, and persist writer/reviewer choices.", Usage: "yoke init [options]", Flags: []helpFlag{
+		{Name: "--writer-agent", Value: "codex|claude", Description: "Set writer agent explicitly."},
+		{Name: "--reviewer-agent", Value: "codex|claude", Description: "Set reviewer agent explicitly."},
+		{Name: "--bd-prefix", Value: "PREFIX", Description: "Set bd issue prefix explicitly (default: bd)."},
+		{Name: "--no-prompt", Description: "Do not prompt; auto-select detected defaults."},
+		{Name: "--detect-checks", Description: "Write .yoke/checks.sh from detected build/test commands (go.mod, package.json, Cargo.toml, pyproject.toml, Makefile)."},
+	}},
+	{Name: "doctor", Summary: "Validate required tools/config and report agent availability.", Usage: "yoke doctor"},
+	{Name: "status", Summary: "Print current repo/task/agent status snapshot for deterministic agent consumption.", Usage: "yoke status [--workspace FILE]", Flags: []helpFlag{
+		{Name: "--workspace", Value: "FILE", Description: "Print the snapshot for every repository in a workspace file, each line prefixed with [repo]."},
+	}},
+	{Name: "daemon", Summary: "Run continuous writer/reviewer automation loop over bd issue states.", Usage: "yoke daemon [options]", Flags: []helpFlag{
+		{Name: "--once", Description: "Run a single iteration and exit."},
+		{Name: "--interval", Value: "VALUE", Description: "Poll interval for idle loops. Accepts seconds (30) or durations (30s, 1m)."},
+		{Name: "--max-iterations", Value: "N", Description: "Stop after N iterations in continuous mode."},
+		{Name: "--writer-cmd", Value: "CMD", Description: "Override writer command for this daemon run."},
+		{Name: "--reviewer-cmd", Value: "CMD", Description: "Override reviewer command for this daemon run."},
+		{Name: "--budget", Value: "USD", Description: "Stop when an issue's recorded agent cost reaches USD (default: YOKE_MAX_COST_PER_ISSUE)."},
+		{Name: "--review-prs", Description: "When idle, review open PRs labeled yoke:review-me with the reviewer agent (no bd issue required) and post a PR review."},
+		{Name: "--workspace", Value: "FILE", Description: "Serve every repository listed in a workspace file, routing each issue to its repository."},
+	}},
+	{Name: "claim", Summary: "Start work on an issue (bd update --status in_progress + ensure issue worktree).", Usage: "yoke claim [<prefix>-issue-id] [options]", Flags: []helpFlag{
+		{Name: "--improvement-passes", Value: "N", Description: "Run only the first N epic improvement passes (default: all of YOKE_EPIC_PASS_PLAN; 0 skips)."},
+		{Name: "--restart", Description: "Start the epic improvement cycle over instead of resuming after the last completed pass."},
+		{Name: "--budget", Value: "USD", Description: "Stop epic improvement when recorded agent cost reaches USD (default: YOKE_MAX_COST_PER_ISSUE)."},
+		{Name: "--stack", Description: "If a blocking dependency is still in review, branch off its yoke/<blocker> branch and target its PR (stacked PRs)."},
+		{Name: "--worktree", Description: `Print a final "export ROOT_DIR=<worktree>" line for eval. Claim always works in .yoke/worktrees/<issue> and never switches the main checkout's branch.`},
+		{Name: "--policy", Value: "POLICY", Description: "Order ready issues when no issue id is given: ready (tracker order), or a comma list of priority, oldest, smallest (default: YOKE_CLAIM_POLICY)."},
+		{Name: "--label", Value: "LABEL", Description: "Only pick issues carrying one of these labels (repeatable or comma-separated; replaces YOKE_ONLY_LABELS)."},
+		{Name: "--type", Value: "TYPE", Description: "Only pick issues of these bd types, e.g. bug (repeatable; replaces YOKE_ONLY_TYPES)."},
+		{Name: "--path", Value: "PATH", Description: "Only pick issues mentioning a path under PATH or matching a glob (repeatable; replaces YOKE_ONLY_PATHS)."},
+		{Name: "--steal", Description: "Claim the issue even if it is assigned to or claimed by someone else."},
+		{Name: "--dry-run", Short: "n", Description: "Print the bd, git, and GitHub changes as audit entries on stderr without making them."},
+	}},
+	{Name: "submit", Summary: "Run checks, add handoff comment, move issue to review queue, and open/update PR workflow.", Usage: `yoke submit [<prefix>-issue-id] --done "..." --remaining "..." [options]`, Flags: []helpFlag{
+		{Name: "--done", Short: "d", Value: "TEXT", Description: "Required. What is complete now."},
+		{Name: "--remaining", Short: "r", Value: "TEXT", Description: "Required. What remains."},
+		{Name: "--decision", Value: "TEXT", Description: "Optional. Key decision made."},
+		{Name: "--uncertain", Value: "TEXT", Description: "Optional. Open uncertainty."},
+		{Name: "--checks", Value: "CMD", Description: "Optional. Run this check command/script instead of YOKE_CHECK_CMD or YOKE_CHECKS."},
+		{Name: "--force-checks", Description: "Run checks even if they already passed for this working tree."},
+		{Name: "--no-push", Description: "Do not push branch."},
+		{Name: "--no-pr", Description: "Do not create or update PR."},
+		{Name: "--no-pr-comment", Description: "Do not post writer handoff comment to PR."},
+		{Name: "--sync-base", Description: "Sync the PR base branch into the issue branch before checks."},
+		{Name: "--no-sync-base", Description: "Skip base sync even when YOKE_SYNC_BASE=true."},
+		{Name: "--commit", Description: `Stage and commit outstanding changes before checks ("<issue>: <title>" plus the --done text).`},
+		{Name: "--no-commit", Description: "Skip the commit even when YOKE_SUBMIT_COMMIT=true."},
+		{Name: "--commit-agent", Description: "Like --commit, with the writer agent drafting the commit body."},
+		{Name: "--amend", Description: "For an issue already in the review queue: rerun checks, push, and edit the existing handoff comments in place instead of adding new ones."},
+		{Name: "--auto-summary", Description: "Have the writer agent draft --done/--remaining/--decision/--uncertain from the branch diff and commits; explicit flags win. Asks for confirmation at a terminal."},
+		{Name: "--dry-run", Short: "n", Description: "Print the bd, git, and GitHub changes as audit entries on stderr without making them."},
+	}},
+	{Name: "review", Summary: "Review an issue, optionally run reviewer automation, then approve/reject.", Usage: "yoke review [<prefix>-issue-id] [options]", Flags: []helpFlag{
+		{Name: "--agent", Short: "a", Description: "Run YOKE_REVIEW_CMD before final action."},
+		{Name: "--note", Value: "TEXT", Description: "Add reviewer note to bd issue."},
+		{Name: "--approve", Description: "Approve issue (bd close)."},
+		{Name: "--reject", Value: "TEXT", Description: "Reject issue with reason."},
+		{Name: "--no-pr-comment", Description: "Do not post reviewer update comment to PR."},
+		{Name: "--wait-ci", Description: "Before approving, wait for the PR's required checks; a failure returns the issue to the writer (default: YOKE_WAIT_CI)."},
+		{Name: "--security", Description: "Run the security review pass first; critical/high findings block approval."},
+		{Name: "--dry-run", Short: "n", Description: "Print the bd, git, and GitHub changes as audit entries on stderr without making them."},
+	}},
+	{Name: "epic", Summary: "Epic maintenance subcommands (archive the decision record, write a retrospective).", Usage: "yoke epic <archive|retro> <epic-id> [options]", Flags: []helpFlag{
+		{Name: "--output", Value: "DIR", Description: "archive: write the bundle to DIR instead of docs/epics/<epic-id>/."},
+	}},
+	{Name: "improve", Summary: "Run the epic improvement cycle on an epic without claiming a task.", Usage: "yoke improve <epic-id> [options]", Flags: []helpFlag{
+		{Name: "--passes", Value: "N", Description: "Run only the first N passes of YOKE_EPIC_PASS_PLAN (default: all)."},
+		{Name: "--force", Description: "Re-run from the first pass even when the epic is already marked improved."},
+	}},
+	{Name: "abandon", Summary: "Release a claimed issue back to open, optionally removing its branch/worktree.", Usage: "yoke abandon [<prefix>-issue-id] [options]", Flags: []helpFlag{
+		{Name: "--reason", Value: "TEXT", Description: "Explain why the issue was abandoned."},
+		{Name: "--remove-worktree", Description: "Remove the issue worktree (branch is kept)."},
+		{Name: "--delete-branch", Description: "Remove the issue worktree and delete branch yoke/<issue>."},
+	}},
+	{Name: "resume", Summary: "Re-enter an in-progress issue: restore its worktree and print handoff/reject context.", Usage: "yoke resume [<prefix>-issue-id]"},
+	{Name: "queue", Summary: "List every issue in the review queue with age, branch, and PR.", Usage: "yoke queue [--json]", Flags: []helpFlag{
+		{Name: "--json", Short: "j", Description: "Print entries as a JSON array (id, title, branch, pr, queued_at, age_seconds)."},
+	}},
+	{Name: "tui", Summary: "Full-screen dashboard of the backlog, work in progress, review queue, daemon activity, and live agent output.", Usage: "yoke tui [options]", Flags: []helpFlag{
+		{Name: "--interval", Value: "DURATION", Description: "Reload issues and the review queue every DURATION (default: 5s); agent output updates twice a second."},
+		{Name: "--once", Description: "Print one frame and exit, sized by COLUMNS and LINES (default 100x40)."},
+	}},
+	{Name: "clarify", Summary: "List open \"Clarification needed:\" tasks across epics.", Usage: "yoke clarify [--json] [--sync-slack]", Flags: []helpFlag{
+		{Name: "--json", Short: "j", Description: "Print entries as a JSON array (id, epic, question, description, comments)."},
+		{Name: "--sync-slack", Description: "Post new clarification tasks to Slack and copy thread replies to bd first."},
+	}},
+	{Name: "answer", Summary: "Answer a clarification task from the CLI.", Usage: `yoke answer <clarification-task-id> ["answer" | -]`},
+	{Name: "list", Summary: "Overview of yoke-managed issues joining bd, branch, worktree, and PR state.", Usage: "yoke list [--status STATE] [--label LABEL]", Flags: []helpFlag{
+		{Name: "--status", Value: "STATE", Description: "Only show rows whose STATE or bd status matches."},
+		{Name: "--label", Value: "LABEL", Description: "Only show issues carrying the bd label."},
+	}},
+	{Name: "merge", Summary: "Land an approved issue's PR, delete its branch, and record the merge SHA.", Usage: "yoke merge [<prefix>-issue-id] [options]", Flags: []helpFlag{
+		{Name: "--strategy", Short: "s", Value: "S", Description: "Merge strategy: squash, merge, or rebase."},
+		{Name: "--wait", Short: "w", Description: "Wait for pending PR checks instead of failing."},
+		{Name: "--timeout", Value: "VALUE", Description: "Maximum wait for checks (default 30m)."},
+		{Name: "--keep-branch", Short: "k", Description: "Keep the branch and worktree after merging."},
+		{Name: "--wait-ci", Description: "Wait for the PR's required checks; a failure reopens the issue for the writer (default: YOKE_WAIT_CI)."},
+		{Name: "--dry-run", Short: "n", Description: "Print the bd, git, and GitHub changes as audit entries on stderr without making them."},
+	}},
+	{Name: "cleanup", Summary: "Prune branches/worktrees of closed+merged issues and old epic reports.", Usage: "yoke cleanup [options]", Flags: []helpFlag{
+		{Name: "--dry-run", Short: "n", Description: "Print what would be removed without changing anything."},
+		{Name: "--reports-older-than", Value: "VALUE", Description: "Retention for epic improvement reports (30d, 72h)."},
+	}},
+	{Name: "log", Summary: "Show the audit log of state-changing actions, optionally for one issue.", Usage: "yoke log [<prefix>-issue-id] [--limit N] [--json]", Flags: []helpFlag{
+		{Name: "--limit", Short: "l", Value: "N", Description: "Show only the most recent N entries."},
+		{Name: "--json", Short: "j", Description: "Print entries as a JSON array."},
+	}},
+	{Name: "onboard", Summary: "Inspect the repo and propose a tailored .yoke setup as a PR.", Usage: "yoke onboard [options]", Flags: []helpFlag{
+		{Name: "--dry-run", Short: "n", Description: "Print the detected profile and generated files without writing."},
+		{Name: "--no-pr", Description: "Write the files into the current checkout instead of opening a PR."},
+	}},
+	{Name: "metrics", Summary: "Cycle-time statistics per issue and in aggregate from bd and the audit log.", Usage: "yoke metrics [<prefix>-issue-id] [--json]", Flags: []helpFlag{
+		{Name: "--json", Short: "j", Description: "Print {issues: [...], aggregate: {...}} for dashboards."},
+	}},
+	{Name: "new", Summary: "Create a fresh repo from a template with .yoke, bd, and a starter epic set up.", Usage: "yoke new <template> <directory> [options]", Flags: []helpFlag{
+		{Name: "--epic", Value: "TITLE", Description: `Starter epic title (default: "Build <directory name>").`},
+		{Name: "--bd-prefix", Value: "PREFIX", Description: "bd issue prefix for the new project (default: template config or bd)."},
+		{Name: "--no-bd", Description: "Skip bd init and the starter epic."},
+	}},
+	{Name: "sync-base", Summary: "Rebase/merge the PR base into the issue branch, optionally resolving conflicts with the writer agent.", Usage: "yoke sync-base [<prefix>-issue-id] [options]", Flags: []helpFlag{
+		{Name: "--resolve-with-agent", Description: "Resolve conflicts with the configured writer agent."},
+		{Name: "--strategy", Short: "s", Value: "VALUE", Description: "rebase or merge (default: YOKE_SYNC_STRATEGY)."},
+		{Name: "--checks", Value: "CMD", Description: "Override the check command run after syncing."},
+	}},
+	{Name: "env", Summary: "Print the command, directory, and environment a role would run with for an issue.", Usage: "yoke env <writer|reviewer|check> [<prefix>-issue-id] [--json]", Flags: []helpFlag{
+		{Name: "--json", Short: "j", Description: "Print the result as JSON."},
+	}},
+	{Name: "config", Summary: "Lint YOKE_WRITER_CMD/YOKE_REVIEW_CMD and show how the daemon would expand them.", Usage: "yoke config lint [options]", Flags: []helpFlag{
+		{Name: "--issue", Value: "ID", Description: "Issue id to substitute when simulating expansion (default <prefix>-example)."},
+		{Name: "--json", Short: "j", Description: "Print results as JSON."},
+	}},
+	{Name: "audit-review", Summary: "Re-review recently merged yoke PRs with a stronger model and file bd issues for problems found.", Usage: "yoke audit-review [options]", Flags: []helpFlag{
+		{Name: "--since", Value: "VALUE", Description: "Only audit PRs merged within this window (default 7d; accepts 30d, 72h)."},
+		{Name: "--sample", Value: "N", Description: "Also audit up to N merged PRs that were not fast-tracked (default 3; 0 audits only yoke:audit-pending)."},
+		{Name: "--agent", Short: "a", Value: "codex|claude", Description: "Audit agent (default: reviewer agent)."},
+		{Name: "--model", Value: "MODEL", Description: "Model for the audit agent (default: YOKE_AUDIT_MODEL)."},
+		{Name: "--dry-run", Short: "n", Description: "List the PRs that would be audited without running the agent."},
+		{Name: "--json", Short: "j", Description: "Print audit results as a JSON array."},
+	}},
+	{Name: "serve", Summary: "Serve a token-protected localhost HTTP API for editor integrations.", Usage: "yoke serve [options]", Flags: []helpFlag{
+		{Name: "--addr", Value: "HOST:PORT", Description: "Loopback address to listen on (default 127.0.0.1:7717)."},
+		{Name: "--token", Value: "TOKEN", Description: "Bearer token clients must send (default: YOKE_API_TOKEN, else generated)."},
+		{Name: "--dashboard-addr", Value: "HOST:PORT", Description: "Also serve the dashboard and read-only routes on HOST:PORT, which may be a network address."},
+		{Name: "--dashboard-token", Value: "TOKEN", Description: "Token for the --dashboard-addr listener (default: YOKE_DASHBOARD_TOKEN, else generated)."},
+	}},
+	{Name: "listen", Summary: "Turn GitHub review, comment, and check suite webhooks into yoke actions.", Usage: "yoke listen [options]", Flags: []helpFlag{
+		{Name: "--port", Value: "N", Description: "Port to listen on (default 7719)."},
+		{Name: "--host", Value: "HOST", Description: "Interface to listen on (default: 127.0.0.1; put a reverse proxy or tunnel in front for GitHub)."},
+		{Name: "--secret", Value: "SECRET", Description: "Webhook secret used to verify deliveries (default: YOKE_WEBHOOK_SECRET)."},
+	}},
+	{Name: "completion", Summary: "Print a shell completion script for bash, zsh, or fish.", Usage: "yoke completion <bash|zsh|fish>"},
+	{Name: "help", Summary: "Show help for a command or a task-oriented topic.", Usage: "yoke help [command|topic]"},
+}
 
-	if number, _, _, ok := openPRForBranch(headBranch); ok {
-		note(fmt.Sprintf("PR #%s already exists for %s.", number, headBranch))
-		return nil
-	}
+var helpTopicIndex = []helpTopic{
+	{Name: "workflow", Title: "The writer/reviewer loop", Summary: "One issue from claim to merge, by hand or under the daemon.", Steps: []helpStep{
+		{Text: "Check tools, config, and agents before starting.", Command: "doctor"},
+		{Text: "Claim the next ready issue (or a specific one); yoke creates its branch and worktree.", Command: "claim"},
+		{Text: "Write the change in the worktree, then hand it off with checks, a handoff note, and a PR.", Command: "submit"},
+		{Text: "Review the handoff and approve or reject it; rejections return the issue to the writer.", Command: "review"},
+		{Text: "Land the approved PR and record the merge.", Command: "merge"},
+		{Text: "Or let the daemon run writer and reviewer commands over the backlog.", Command: "daemon"},
+	}},
+	{Name: "recover", Title: "Recovering stuck or interrupted work", Summary: "Get an issue moving again after a crash, a bad branch, or a stale review.", Steps: []helpStep{
+		{Text: "See what yoke thinks is in progress, in review, and next.", Command: "status"},
+		{Text: "Re-enter an in-progress issue: restore its worktree and reprint handoff/reject context.", Command: "resume"},
+		{Text: "Trace the state-changing actions yoke recorded for the issue.", Command: "log"},
+		{Text: "Rebase onto the base branch when the branch has drifted or conflicts.", Command: "sync-base"},
+		{Text: "Release an issue you cannot finish back to open, optionally dropping its branch.", Command: "abandon"},
+		{Text: "Prune branches and worktrees left behind by closed issues.", Command: "cleanup"},
+	}},
+	{Name: "parallel", Title: "Working on several issues at once", Summary: "Run multiple writers without stepping on each other.", Steps: []helpStep{
+		{Text: "Claim each issue into its own worktree and point the agent at it.", Command: "claim"},
+		{Text: "Inspect the exact environment each role's command receives.", Command: "env"},
+		{Text: "Keep long-running branches current with the base branch.", Command: "sync-base"},
+		{Text: "Watch every issue's branch, worktree, and PR state together.", Command: "list"},
+		{Text: "Track what is waiting on review.", Command: "queue"},
+	}},
+}
+
+// commandUsagePrinters holds the detailed help for each command; their
+// Options sections are generated from commandHelpIndex.
+var commandUsagePrinters = map[string]func(){
+	"init":         printInitUsage,
+	"doctor":       printDoctorUsage,
+	"status":       printStatusUsage,
+	"daemon":       printDaemonUsage,
+	"claim":        printClaimUsage,
+	"submit":       printSubmitUsage,
+	"review":       printReviewUsage,
+	"epic":         printEpicUsage,
+	"improve":      printImproveUsage,
+	"abandon":      printAbandonUsage,
+	"resume":       printResumeUsage,
+	"queue":        printQueueUsage,
+	"tui":          printTUIUsage,
+	"clarify":      printClarifyUsage,
+	"answer":       printAnswerUsage,
+	"list":         printListUsage,
+	"merge":        printMergeUsage,
+	"cleanup":      printCleanupUsage,
+	"log":          printLogUsage,
+	"onboard":      printOnboardUsage,
+	"metrics":      printMetricsUsage,
+	"new":          printNewUsage,
+	"sync-base":    printSyncBaseUsage,
+	"env":          printEnvUsage,
+	"config":       printConfigUsage,
+	"audit-review": printAuditReviewUsage,
+	"serve":        printServeUsage,
+	"listen":       printListenUsage,
+	"completion":   printCompletionUsage,
+	"help":         printUsage,
+}
 
-	templatePath := resolveRepoPath(root, cfg.PRTemplate)
-	createArgs := []string{
-		"pr", "create",
-		"--draft",
-		"--base", baseBranch,
-		"--head", headBranch,
-		"--title", fmt.Sprintf("[%s] %s", issue, title),
+const helpWrapWidth = 88
+
+// formatCommandOptions renders the Options section of a command's help from
+// its flag metadata, wrapping descriptions under an aligned column.
+func formatCommandOptions(name string) string {
+	entry, ok := findCommandHelp(name)
+	if !ok || len(entry.Flags) == 0 {
+		return ""
 	}
-	if fileExists(templatePath) {
-		createArgs = append(createArgs, "--body-file", templatePath)
-	} else {
-		createArgs = append(createArgs, "--body", "")
+	labels := make([]string, len(entry.Flags))
+	width := 0
+	for i, flag := range entry.Flags {
+		label := flag.Name
+		if flag.Short != "" {
+			label = "-" + flag.Short + ", " + label
+		}
+		if flag.Value != "" {
+			label += " " + flag.Value
+		}
+		labels[i] = label
+		if len(label) > width {
+			width = len(label)
+		}
 	}
-	return runCommand("gh", createArgs...)
-}
+	indent := strings.Repeat(" ", 2+width+2)
 
-func createPRIfNeeded(root string, cfg config, issue, title, baseBranch string) error {
-	branchOutput, err := commandOutput("git", "rev-parse", "--abbrev-ref", "HEAD")
-	if err != nil {
-		return err
+	var b strings.Builder
+	b.WriteString("Options:\n")
+	for i, flag := range entry.Flags {
+		line := fmt.Sprintf("  %-*s  ", width, labels[i])
+		for j, word := range strings.Fields(flag.Description) {
+			if j > 0 && len(line)+1+len(word) > helpWrapWidth {
+				b.WriteString(line + "\n")
+				line = indent + word
+				continue
+			}
+			if j > 0 {
+				line += " "
+			}
+			line += word
+		}
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
 	}
-	headBranch := strings.TrimSpace(branchOutput)
-	return createPRForBranch(root, cfg, issue, title, headBranch, baseBranch)
+	return b.String()
 }
 
-type prListEntry struct {
-	Number  int    `json:"number"`
-	URL     string `json:"url"`
-	IsDraft bool   `json:"isDraft"`
+// cliFlag is one flag occurrence on the command line, in order; Value is set
+// only for flags that take one.
+type cliFlag struct {
+	Name  string
+	Value string
 }
 
-func openPRForIssue(issue string) (string, string, bool, bool) {
-	branch := branchForIssue(issue)
-	return openPRForBranch(branch)
+type cliArgs struct {
+	Flags       []cliFlag
+	Positionals []string
+	Help        bool
 }
 
-func openPRForBranch(branch string) (string, string, bool, bool) {
-	if strings.TrimSpace(branch) == "" {
-		return "", "", false, false
-	}
-	if !commandExists("gh") || !hasOriginRemote() {
-		return "", "", false, false
+// parseCommandArgs splits args into flags and positionals using the
+// command's flag metadata. It accepts "--flag value", "--flag=value", short
+// flags ("-w"), and combined short flags ("-wk", "-l5", "-jl 5"); "--" ends
+// flag parsing. Unknown flags are reported with the closest known flag.
+func parseCommandArgs(command string, args []string) (cliArgs, error) {
+	var parsed cliArgs
+	entry, _ := findCommandHelp(command)
+	byName := map[string]helpFlag{}
+	byShort := map[string]helpFlag{}
+	for _, flag := range entry.Flags {
+		byName[flag.Name] = flag
+		if flag.Short != "" {
+			byShort[flag.Short] = flag
+		}
 	}
 
-	output := strings.TrimSpace(commandCombinedOutput(
-		"gh", "pr", "list",
-		"--head", branch,
-		"--state", "open",
-		"--json", "number,url,isDraft",
-	))
-	return parseOpenPRFromListJSON(output)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			parsed.Positionals = append(parsed.Positionals, args[i+1:]...)
+			return parsed, nil
+		case arg == "-h" || arg == "--help":
+			parsed.Help = true
+			return parsed, nil
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg, "=")
+			flag, ok := byName[name]
+			if !ok {
+				return cliArgs{}, unknownFlagError(command, name, entry.Flags)
+			}
+			switch {
+			case flag.Value == "" && hasValue:
+				return cliArgs{}, fmt.Errorf("%s does not take a value", name)
+			case flag.Value != "" && !hasValue:
+				i++
+				if i >= len(args) {
+					return cliArgs{}, fmt.Errorf("%s requires a value", name)
+				}
+				value = args[i]
+			}
+			parsed.Flags = append(parsed.Flags, cliFlag{Name: name, Value: value})
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			cluster := arg[1:]
+			for j := 0; j < len(cluster); j++ {
+				flag, ok := byShort[cluster[j:j+1]]
+				if !ok {
+					return cliArgs{}, unknownFlagError(command, "-"+cluster[j:j+1], entry.Flags)
+				}
+				if flag.Value == "" {
+					parsed.Flags = append(parsed.Flags, cliFlag{Name: flag.Name})
+					continue
+				}
+				value := cluster[j+1:]
+				if value == "" {
+					i++
+					if i >= len(args) {
+						return cliArgs{}, fmt.Errorf("%s requires a value", flag.Name)
+					}
+					value = args[i]
+				}
+				parsed.Flags = append(parsed.Flags, cliFlag{Name: flag.Name, Value: value})
+				break
+			}
+		default:
+			parsed.Positionals = append(parsed.Positionals, arg)
+		}
+	}
+	return parsed, nil
 }
 
-func parseOpenPRFromListJSON(raw string) (string, string, bool, bool) {
-	trimmed := strings.TrimSpace(raw)
-	if trimmed == "" || trimmed == "null" || trimmed == "[]" {
-		return "", "", false, false
+func unknownFlagError(command, name string, flags []helpFlag) error {
+	best, bestDistance := "", 0
+	for _, flag := range flags {
+		distance := editDistance(strings.TrimLeft(name, "-"), strings.TrimPrefix(flag.Name, "--"))
+		if best == "" || distance < bestDistance {
+			best, bestDistance = flag.Name, distance
+		}
 	}
-
-	var list []prListEntry
-	if err := json.Unmarshal([]byte(trimmed), &list); err != nil {
-		return "", "", false, false
+	if best != "" && bestDistance <= 2 && bestDistance < len(strings.TrimLeft(name, "-")) {
+		return fmt.Errorf("unknown %s argument: %s (did you mean %s?)", command, name, best)
 	}
-	if len(list) == 0 || list[0].Number <= 0 {
-		return "", "", false, false
+	return fmt.Errorf("unknown %s argument: %s", command, name)
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
 	}
-	return strconv.Itoa(list[0].Number), strings.TrimSpace(list[0].URL), list[0].IsDraft, true
+	return previous[len(b)]
 }
 
-func postSubmitPRComment(issue, doneText, remaining, decision, uncertain, checks string) {
-	number, _, _, ok := openPRForIssue(issue)
-	if !ok {
-		note("warning: no open PR found for issue branch; skipping writer handoff PR comment")
-		return
+// issueArg returns the single issue-id positional, rejecting anything else
+// the same way the command loops always have.
+func (a cliArgs) issueArg(command, prefix string) (string, error) {
+	issue := ""
+	for _, arg := range a.Positionals {
+		if !looksLikeIssueID(arg, prefix) && !looksLikeIssueIDAnyPrefix(arg) {
+			return "", fmt.Errorf("unknown %s argument: %s", command, arg)
+		}
+		if issue != "" {
+			return "", errors.New("multiple issue ids provided")
+		}
+		issue = arg
 	}
+	return issue, nil
+}
 
-	body := formatWriterPRComment(issue, doneText, remaining, decision, uncertain, checks)
-	if err := runCommand("gh", "pr", "comment", number, "--body", body); err != nil {
-		note("warning: failed to post writer handoff PR comment: " + err.Error())
-		return
+func findCommandHelp(name string) (commandHelp, bool) {
+	for _, entry := range commandHelpIndex {
+		if entry.Name == name {
+			return entry, true
+		}
 	}
-	note("Posted writer handoff comment to PR #" + number)
+	return commandHelp{}, false
 }
 
-func postReviewPRComment(issue, action, rejectReason, noteText string, runAgent bool) {
-	number, _, _, ok := openPRForIssue(issue)
-	if !ok {
-		note("warning: no open PR found for issue branch; skipping reviewer PR comment")
-		return
+func findHelpTopic(name string) (helpTopic, bool) {
+	for _, topic := range helpTopicIndex {
+		if topic.Name == name {
+			return topic, true
+		}
 	}
+	return helpTopic{}, false
+}
 
-	body := formatReviewerPRComment(issue, action, rejectReason, noteText, runAgent)
-	if err := runCommand("gh", "pr", "comment", number, "--body", body); err != nil {
-		note("warning: failed to post reviewer PR comment: " + err.Error())
-		return
+// completionSpec is what shell completion knows about one command, derived
+// from commandHelpIndex so new commands and flags complete without edits here.
+type completionSpec struct {
+	Name    string
+	Summary string
+	Flags   []helpFlag
+	// Words are keywords accepted as positional arguments (subcommands,
+	// roles, help topics); Issues marks commands that take an issue id.
+	Words  []string
+	Issues bool
+}
+
+var usageAlternativesPattern = regexp.MustCompile(`^\[?<?([a-z][a-z-]*(?:\|[a-z][a-z-]*)+)>?\]?$`)
+var usageKeywordPattern = regexp.MustCompile(`^[a-z][a-z-]*$`)
+
+func completionSpecs() []completionSpec {
+	specs := make([]completionSpec, 0, len(commandHelpIndex))
+	for _, entry := range commandHelpIndex {
+		flags := append(append([]helpFlag{}, entry.Flags...), globalFlags...)
+		spec := completionSpec{Name: entry.Name, Summary: firstSentence(entry.Summary), Flags: flags}
+		for _, token := range strings.Fields(entry.Usage)[2:] {
+			switch {
+			case strings.Contains(token, "-id"):
+				spec.Issues = true
+			case usageAlternativesPattern.MatchString(token):
+				spec.Words = append(spec.Words, strings.Split(usageAlternativesPattern.FindStringSubmatch(token)[1], "|")...)
+			case usageKeywordPattern.MatchString(token):
+				spec.Words = append(spec.Words, token)
+			}
+		}
+		if entry.Name == "help" {
+			spec.Words = nil
+			for _, command := range commandHelpIndex {
+				spec.Words = append(spec.Words, command.Name)
+			}
+			for _, topic := range helpTopicIndex {
+				spec.Words = append(spec.Words, topic.Name)
+			}
+		}
+		specs = append(specs, spec)
 	}
-	note("Posted reviewer comment to PR #" + number)
+	return specs
 }
 
-func formatWriterPRComment(issue, doneText, remaining, decision, uncertain, checks string) string {
-	lines := []string{
-		"## Writer -> Reviewer Handoff",
-		"",
-		"- Issue: `" + sanitizeCommentLine(issue) + "`",
-		"- Done: " + sanitizeCommentLine(doneText),
-		"- Remaining: " + sanitizeCommentLine(remaining),
+func firstSentence(text string) string {
+	if end := strings.Index(text, ". "); end >= 0 {
+		return text[:end]
 	}
-	if strings.TrimSpace(decision) != "" {
-		lines = append(lines, "- Decision: "+sanitizeCommentLine(decision))
+	return strings.TrimSuffix(text, ".")
+}
+
+// flagChoices returns the fixed values a flag accepts, for VALUE
+// placeholders written as alternatives ("codex|claude").
+func flagChoices(flag helpFlag) []string {
+	if !strings.Contains(flag.Value, "|") || strings.ContainsAny(flag.Value, " <>[]") {
+		return nil
 	}
-	if strings.TrimSpace(uncertain) != "" {
-		lines = append(lines, "- Uncertain: "+sanitizeCommentLine(uncertain))
+	return strings.Split(flag.Value, "|")
+}
+
+// flagTakesFile reports whether a flag's value is a filesystem path, which
+// the shells complete as files; other values get no completion.
+func flagTakesFile(flag helpFlag) bool {
+	return flag.Value == "FILE" || flag.Value == "DIR" || flag.Value == "PATH"
+}
+
+func flagNames(flag helpFlag) []string {
+	names := []string{flag.Name}
+	if flag.Short != "" {
+		names = append(names, "-"+flag.Short)
 	}
-	lines = append(lines, "- Checks: `"+sanitizeCommentLine(checks)+"` passed")
-	lines = append(lines, "")
-	lines = append(lines, "_Posted automatically by `yoke submit`._")
-	return strings.Join(lines, "\n")
+	return names
 }
 
-func formatIssueHandoffComment(doneText, remaining, decision, uncertain, checks string) string {
-	lines := []string{
-		"Writer handoff:",
-		"- Done: " + sanitizeCommentLine(doneText),
-		"- Remaining: " + sanitizeCommentLine(remaining),
-		"- Checks: `" + sanitizeCommentLine(checks) + "` passed",
+func cmdCompletion(args []string) error {
+	parsed, err := parseCommandArgs("completion", args)
+	if err != nil {
+		return err
 	}
-	if strings.TrimSpace(decision) != "" {
-		lines = append(lines, "- Decision: "+sanitizeCommentLine(decision))
+	if parsed.Help || len(parsed.Positionals) == 0 {
+		printCompletionUsage()
+		return nil
 	}
-	if strings.TrimSpace(uncertain) != "" {
-		lines = append(lines, "- Uncertain: "+sanitizeCommentLine(uncertain))
+	if len(parsed.Positionals) > 1 {
+		return fmt.Errorf("unknown completion argument: %s", parsed.Positionals[1])
+	}
+	switch shell := parsed.Positionals[0]; shell {
+	case "bash":
+		fmt.Print(bashCompletion(completionSpecs()))
+	case "zsh":
+		fmt.Print(zshCompletion(completionSpecs()))
+	case "fish":
+		fmt.Print(fishCompletion(completionSpecs()))
+	case "issues":
+		printCompletionIssues()
+	default:
+		return fmt.Errorf("unsupported shell %q (want bash, zsh, or fish)", shell)
 	}
-	return strings.Join(lines, "\n")
+	return nil
 }
 
-func formatReviewerPRComment(issue, action, rejectReason, noteText string, runAgent bool) string {
-	decision := "note"
-	if strings.TrimSpace(action) != "" {
-		decision = strings.TrimSpace(action)
-	}
+// completionIssueLimit caps each status list behind issue-id completion so a
+// tab press stays fast on large backlogs.
+const completionIssueLimit = 200
 
-	lines := []string{
-		"## Reviewer Update",
-		"",
-		"- Issue: `" + sanitizeCommentLine(issue) + "`",
-		"- Decision: " + sanitizeCommentLine(decision),
-	}
-	if decision == "reject" && strings.TrimSpace(rejectReason) != "" {
-		lines = append(lines, "- Reject reason: "+sanitizeCommentLine(rejectReason))
+// printCompletionIssues prints "<id>\t<title>" for open, in-progress, and
+// in-review issues. Completion must never print errors into the prompt, so
+// failures print nothing.
+func printCompletionIssues() {
+	root, err := ensureRepoRoot()
+	if err != nil {
+		return
 	}
-	if strings.TrimSpace(noteText) != "" {
-		lines = append(lines, "- Note: "+sanitizeCommentLine(noteText))
+	cfg, err := loadConfig(root)
+	if err != nil || useIssueTracker(cfg) != nil {
+		return
 	}
-	if runAgent {
-		lines = append(lines, "- Reviewer command: executed")
+	for _, status := range []string{"in_progress", "blocked", "open"} {
+		issues, err := tracker.List(issueQuery{Status: status, Limit: completionIssueLimit})
+		if err != nil {
+			return
+		}
+		for _, issue := range issues {
+			title := strings.Join(strings.Fields(issue.Title), " ")
+			fmt.Printf("%s\t%s\n", issue.ID, title)
+		}
 	}
-	lines = append(lines, "")
-	lines = append(lines, "_Posted automatically by `yoke review`._")
-	return strings.Join(lines, "\n")
 }
 
-func formatDaemonNoConsensusPRComment(issue, status string, maxIterations int) string {
-	lines := []string{
-		"## Daemon Notice",
-		"",
-		"- Issue: `" + sanitizeCommentLine(issue) + "`",
-		"- Status: " + sanitizeCommentLine(status),
-		"- Outcome: max daemon iterations reached without writer/reviewer consensus",
-		"- Iterations: " + strconv.Itoa(maxIterations),
-		"- PR state: left in draft for manual intervention",
-		"",
-		"_Posted automatically by `yoke daemon`._",
-	}
-	return strings.Join(lines, "\n")
+func bashCompletion(specs []completionSpec) string {
+	var b strings.Builder
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	b.WriteString("# bash completion for yoke; generated by \"yoke completion bash\".\n")
+	b.WriteString("_yoke() {\n")
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	b.WriteString("    if [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	b.WriteString("        return\n    fi\n")
+	b.WriteString("    local flags=\"\" values=\"\" files=\"\" words=\"\" issues=\"\"\n")
+	b.WriteString("    case \"${COMP_WORDS[1]}\" in\n")
+	for _, spec := range specs {
+		flags, values, files := []string{}, []string{}, []string{}
+		for _, flag := range spec.Flags {
+			flags = append(flags, flagNames(flag)...)
+			switch {
+			case flagTakesFile(flag):
+				files = append(files, flagNames(flag)...)
+			case flag.Value != "":
+				values = append(values, flagNames(flag)...)
+			}
+		}
+		fmt.Fprintf(&b, "        %s)\n", spec.Name)
+		fmt.Fprintf(&b, "            flags=%q values=%q files=%q words=%q", strings.Join(flags, " "), " "+strings.Join(values, " ")+" ", " "+strings.Join(files, " ")+" ", strings.Join(spec.Words, " "))
+		if spec.Issues {
+			b.WriteString(" issues=1")
+		}
+		b.WriteString("\n")
+		for _, flag := range spec.Flags {
+			if choices := flagChoices(flag); len(choices) > 0 {
+				fmt.Fprintf(&b, "            case \"$prev\" in %s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;; esac\n", strings.Join(flagNames(flag), "|"), strings.Join(choices, " "))
+			}
+		}
+		b.WriteString("            ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("    case \"$files\" in *\" $prev \"*) COMPREPLY=($(compgen -f -- \"$cur\")); return ;; esac\n")
+	b.WriteString("    case \"$values\" in *\" $prev \"*) COMPREPLY=(); return ;; esac\n")
+	b.WriteString("    if [[ \"$cur\" == -* ]]; then\n")
+	b.WriteString("        COMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	b.WriteString("        return\n    fi\n")
+	b.WriteString("    if [ -n \"$issues\" ]; then\n")
+	b.WriteString("        words=\"$words $(yoke completion issues 2>/dev/null | cut -f1)\"\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    COMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	b.WriteString("}\n")
+	b.WriteString("complete -o default -F _yoke yoke\n")
+	return b.String()
 }
 
-func ensurePRReady(number string, isDraft bool) error {
-	if strings.TrimSpace(number) == "" || !isDraft {
-		return nil
-	}
-	if err := runCommand("gh", "pr", "ready", number); err != nil {
-		return fmt.Errorf("failed to mark PR #%s ready after approval: %w", number, err)
-	}
-	note("Marked PR #" + number + " ready for review")
-	return nil
+// shellQuote single-quotes text for sh-like shells.
+func shellQuote(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
 }
 
-func sanitizeCommentLine(value string) string {
-	return strings.Join(strings.Fields(strings.TrimSpace(value)), " ")
+// zshEscape escapes the characters _arguments and _describe treat specially
+// in option descriptions.
+func zshEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(text)
+}
+
+func zshCompletion(specs []completionSpec) string {
+	var b strings.Builder
+	b.WriteString("#compdef yoke\n")
+	b.WriteString("# zsh completion for yoke; generated by \"yoke completion zsh\".\n\n")
+	b.WriteString("_yoke_issues() {\n")
+	b.WriteString("    local -a issues\n")
+	b.WriteString("    issues=(${(f)\"$(yoke completion issues 2>/dev/null | tr '\\t' ':')\"})\n")
+	b.WriteString("    _describe 'issue' issues\n")
+	b.WriteString("}\n\n")
+	b.WriteString("_yoke() {\n")
+	b.WriteString("    local -a commands\n")
+	b.WriteString("    commands=(\n")
+	for _, spec := range specs {
+		fmt.Fprintf(&b, "        %s\n", shellQuote(spec.Name+":"+strings.ReplaceAll(spec.Summary, ":", `\:`)))
+	}
+	b.WriteString("    )\n")
+	b.WriteString("    if (( CURRENT == 2 )); then\n")
+	b.WriteString("        _describe 'command' commands\n")
+	b.WriteString("        return\n    fi\n")
+	b.WriteString("    shift words\n    (( CURRENT-- ))\n")
+	b.WriteString("    case $words[1] in\n")
+	for _, spec := range specs {
+		fmt.Fprintf(&b, "        %s)\n", spec.Name)
+		b.WriteString("            _arguments")
+		for _, flag := range spec.Flags {
+			action := ""
+			if flag.Value != "" {
+				action = ":" + strings.ToLower(strings.ReplaceAll(flag.Value, ":", "-")) + ": "
+				if flagTakesFile(flag) {
+					action = ":" + strings.ToLower(flag.Value) + ":_files"
+				}
+				if choices := flagChoices(flag); len(choices) > 0 {
+					action = ":" + strings.ToLower(flag.Name[2:]) + ":(" + strings.Join(choices, " ") + ")"
+				}
+			}
+			for _, name := range flagNames(flag) {
+				fmt.Fprintf(&b, " \\\n                %s", shellQuote("*"+name+"["+zshEscape(firstSentence(flag.Description))+"]"+action))
+			}
+		}
+		switch {
+		case spec.Issues && len(spec.Words) > 0:
+			fmt.Fprintf(&b, " \\\n                '1:argument:(%s)' '*:issue:_yoke_issues'", strings.Join(spec.Words, " "))
+		case spec.Issues:
+			b.WriteString(" \\\n                '*:issue:_yoke_issues'")
+		case len(spec.Words) > 0:
+			fmt.Fprintf(&b, " \\\n                '*:argument:(%s)'", strings.Join(spec.Words, " "))
+		default:
+			b.WriteString(" \\\n                '*:file:_files'")
+		}
+		b.WriteString("\n            ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n\n")
+	b.WriteString("if [ \"$funcstack[1]\" = \"_yoke\" ]; then\n    _yoke \"$@\"\nelse\n    compdef _yoke yoke\nfi\n")
+	return b.String()
 }
 
-func hasOriginRemote() bool {
-	_, err := commandOutput("git", "remote", "get-url", "origin")
-	return err == nil
+// fishQuote single-quotes text for fish, where only \ and ' are special.
+func fishQuote(text string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(text) + "'"
 }
 
-func fileExists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
+func fishCompletion(specs []completionSpec) string {
+	var b strings.Builder
+	b.WriteString("# fish completion for yoke; generated by \"yoke completion fish\".\n")
+	b.WriteString("complete -c yoke -f\n")
+	for _, spec := range specs {
+		fmt.Fprintf(&b, "complete -c yoke -n __fish_use_subcommand -a %s -d %s\n", spec.Name, fishQuote(spec.Summary))
+	}
+	for _, spec := range specs {
+		condition := fishQuote("__fish_seen_subcommand_from " + spec.Name)
+		for _, flag := range spec.Flags {
+			line := "complete -c yoke -n " + condition + " -l " + strings.TrimPrefix(flag.Name, "--")
+			if flag.Short != "" {
+				line += " -s " + flag.Short
+			}
+			if choices := flagChoices(flag); len(choices) > 0 {
+				line += " -x -a " + fishQuote(strings.Join(choices, " "))
+			} else if flagTakesFile(flag) {
+				line += " -r -F"
+			} else if flag.Value != "" {
+				line += " -x"
+			}
+			b.WriteString(line + " -d " + fishQuote(firstSentence(flag.Description)) + "\n")
+		}
+		if len(spec.Words) > 0 {
+			fmt.Fprintf(&b, "complete -c yoke -n %s -a %s\n", condition, fishQuote(strings.Join(spec.Words, " ")))
+		}
+		if spec.Issues {
+			fmt.Fprintf(&b, "complete -c yoke -n %s -a '(yoke completion issues 2>/dev/null)'\n", condition)
+		}
+	}
+	return b.String()
 }
 
-func note(msg string) {
-	fmt.Println(msg)
+func formatHelpTopic(topic helpTopic) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n%s\n\n", topic.Title, topic.Summary)
+	for i, step := range topic.Steps {
+		fmt.Fprintf(&b, "  %d) %s\n", i+1, step.Text)
+		if entry, ok := findCommandHelp(step.Command); ok {
+			fmt.Fprintf(&b, "     %s\n", entry.Usage)
+		}
+	}
+	b.WriteString("\nMore detail: yoke help <command>\n")
+	return b.String()
 }
 
-func claimNote(msg string) {
-	note("[claim] " + msg)
+type helpIndexJSON struct {
+	Commands    []commandHelp `json:"commands"`
+	Topics      []helpTopic   `json:"topics"`
+	GlobalFlags []helpFlag    `json:"global_flags"`
 }
 
-func fatal(err error) {
-	fmt.Fprintf(os.Stderr, "yoke: %s\n", err)
-	os.Exit(1)
+// printHelpJSON prints the help metadata for one command, or for every
+// command and topic when name is empty, so agent wrappers can introspect flags.
+func printHelpJSON(name string) error {
+	var payload any = helpIndexJSON{Commands: commandHelpIndex, Topics: helpTopicIndex, GlobalFlags: globalFlags}
+	if name != "" {
+		entry, ok := findCommandHelp(name)
+		if !ok {
+			return fmt.Errorf("unknown command: %s", name)
+		}
+		payload = entry
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(payload)
 }
 
 func printUsage() {
-	fmt.Print(`yoke: agent-first bd + PR harness
+	var b strings.Builder
+	b.WriteString(`yoke: agent-first bd + PR harness
 
 Purpose:
   Coordinate writer/reviewer workflows for coding agents using bd state transitions
   and git/PR boundaries.
 
 Usage:
-  yoke init [options]
-  yoke doctor
-  yoke status
-  yoke daemon [options]
-  yoke claim [<prefix>-issue-id]
-  yoke submit [<prefix>-issue-id] --done "..." --remaining "..." [options]
-  yoke review [<prefix>-issue-id] [options]
-  yoke help [command]
-
-Commands:
-  init    Initialize scaffold, detect available agents, and persist writer/reviewer choices.
-  doctor  Validate required tools/config and report agent availability.
-  status  Print current repo/task/agent status snapshot for deterministic agent consumption.
-  daemon  Run continuous writer/reviewer automation loop over bd issue states.
-  claim   Start work on an issue (bd update --status in_progress + ensure issue worktree).
-  submit  Run checks, add handoff comment, move issue to review queue, and open/update PR workflow.
-  review  Review an issue, optionally run reviewer automation, then approve/reject.
-
+`)
+	for _, entry := range commandHelpIndex {
+		fmt.Fprintf(&b, "  %s\n", entry.Usage)
+	}
+	b.WriteString("\nCommands:\n")
+	for _, entry := range commandHelpIndex {
+		if entry.Name == "help" {
+			continue
+		}
+		fmt.Fprintf(&b, "  %-9s %s\n", entry.Name, entry.Summary)
+	}
+	b.WriteString("\nHelp topics:\n")
+	for _, topic := range helpTopicIndex {
+		fmt.Fprintf(&b, "  %-9s %s\n", topic.Name, topic.Summary)
+	}
+	b.WriteString("\nGlobal flags:\n")
+	for _, flag := range globalFlags {
+		label := flag.Name
+		if flag.Short != "" {
+			label = "-" + flag.Short + ", " + label
+		}
+		fmt.Fprintf(&b, "  %-13s  %s\n", label, flag.Description)
+	}
+	b.WriteString(`
 Help discovery:
   yoke <command> --help
   yoke help <command>
+  yoke help <topic>
+  yoke --help-json
+  yoke <command> --help-json
 `)
+	fmt.Print(b.String())
 }
 
 func printInitUsage() {
@@ -3521,15 +22279,14 @@ Behavior:
   4) In interactive terminals, prompts for writer and reviewer selection.
      Writer and reviewer may be the same agent.
   5) Writes selections to .yoke/config.sh.
+  6) Creates .yoke/checks.sh. With --detect-checks it runs the project's detected
+     build/test commands (e.g. go test ./..., npm run test, cargo test, make check)
+     instead of a placeholder; a checks.sh someone edited is left alone.
 
-Options:
-  --writer-agent codex|claude     Set writer agent explicitly.
-  --reviewer-agent codex|claude   Set reviewer agent explicitly.
-  --bd-prefix PREFIX              Set bd issue prefix explicitly (default: bd).
-  --no-prompt                     Do not prompt; auto-select detected defaults.
-
+` + formatCommandOptions("init") + `
 Examples:
   yoke init
+  yoke init --detect-checks
   yoke init --writer-agent codex --reviewer-agent codex
   yoke init --no-prompt --writer-agent codex --reviewer-agent claude --bd-prefix bd
 
@@ -3569,7 +22326,7 @@ Example:
 
 func printStatusUsage() {
 	fmt.Print(`Usage:
-  yoke status
+  yoke status [--workspace FILE]
 
 Purpose:
   Print a deterministic status snapshot that coding agents can parse before acting.
@@ -3583,6 +22340,8 @@ Output fields:
   - writer_command / reviewer_command: daemon command readiness
   - bd_focus: focused issue inferred from current branch or latest claim handoff (or none/unavailable)
   - bd_next: next ready open issue from bd (or none/unavailable)
+  - usage_total: agent tokens/cost recorded in .yoke/usage.jsonl
+  - usage_focus: agent tokens/cost recorded for bd_focus (when focused)
   - tool_git / tool_bd / tool_gh: command availability
 
 Usage guidance for agents:
@@ -3590,8 +22349,10 @@ Usage guidance for agents:
   2) If bd_focus is none, prefer yoke claim.
   3) If reviewer_agent_status is missing, use manual yoke review flags.
 
-Example:
+` + formatCommandOptions("status") + `
+Examples:
   yoke status
+  yoke status --workspace ~/src/workspace
 `)
 }
 
@@ -3606,8 +22367,17 @@ Loop priority (each iteration):
   1) Review focused in-review issue (from branch or latest claim), else first review queue issue.
   2) Otherwise run writer command on focused in-progress issue (from branch or latest claim).
   3) Otherwise claim next ready open issue from bd.
-  4) Otherwise idle (sleep and poll again in continuous mode).
-  5) If max iterations are reached without consensus, daemon notifies and leaves PR draft/open.
+  4) Otherwise, with --review-prs, review the oldest open PR labeled yoke:review-me.
+  5) Otherwise idle (sleep and poll again in continuous mode).
+  6) If max iterations are reached without consensus, daemon notifies and leaves PR draft/open.
+
+Workspace mode (--workspace FILE):
+  - FILE lists repositories sharing one bd database, one per line:
+      <name> <path> [label=LABEL]... [path=PREFIX]...
+    Without routes a repository gets label repo:<name> and path prefix <name>.
+  - Each iteration runs the loop above in every repository's checkout with that
+    repository's config, picking only issues routed to it (first matching label,
+    else first repository whose path prefix the issue mentions).
 
 Command contract:
   - Writer command comes from YOKE_WRITER_CMD (or --writer-cmd override).
@@ -3617,17 +22387,12 @@ Command contract:
   - Commands must transition bd workflow state (writer -> submit/review queue, reviewer -> close or in_progress).
     If status does not change, daemon exits with an error to avoid infinite loops.
 
-Options:
-  --once                    Run a single iteration and exit.
-  --interval VALUE          Poll interval for idle loops. Accepts seconds (30) or durations (30s, 1m).
-  --max-iterations N        Stop after N iterations in continuous mode.
-  --writer-cmd CMD          Override writer command for this daemon run.
-  --reviewer-cmd CMD        Override reviewer command for this daemon run.
-
+` + formatCommandOptions("daemon") + `
 Examples:
   yoke daemon --once
   yoke daemon --interval 45s
   yoke daemon --max-iterations 10
+  yoke daemon --workspace ~/src/workspace
 `)
 }
 
@@ -3639,30 +22404,43 @@ Purpose:
   Move an issue into active work and prepare a dedicated issue worktree.
 
 Behavior:
-  - If issue id omitted, picks first issue from bd open+ready list.
-  - If issue id is an epic, runs an epic improvement cycle (writer/reviewer alternating) before task claim.
-  - Improvement cycle pass count defaults to 5 and can be limited with --improvement-passes.
+  - If issue id omitted, picks first issue from bd open+ready list, ordered by
+    --policy or YOKE_CLAIM_POLICY (ready, or priority,oldest,smallest).
+  - If issue id is an epic, runs an epic improvement cycle before task claim, one agent pass per
+    YOKE_EPIC_PASS_PLAN entry (default writer,reviewer,writer,reviewer,writer).
+  - --improvement-passes N runs only the first N passes of the plan.
+  - An interrupted cycle resumes at its first incomplete pass; --restart runs every pass again.
   - Use --improvement-passes 0 to skip improvement passes and continue directly to child-task claim selection.
   - If improvement is already marked complete but clarification tasks have comments, yoke reruns improvement automatically.
   - Clarification tasks with comments are auto-closed before selecting the next child task.
   - Child tasks with unmet blocking dependencies are skipped (both in-progress and ready lists).
+  - Claimable child tasks are ordered by critical path: the head of the longest chain of
+    blocked tasks first, then the task unblocking the most others.
+  - Descendants are scanned level by level and the scan stops at the first level with a claimable child,
+    so large epics claim quickly; claim logs summarize counts instead of listing every issue.
   - Epic improvement reports are saved in .yoke/epic-improvement-reports/<epic-id>/.
   - If issue id is an epic, claims the next ready/in-progress child task in that epic.
   - If an epic has no remaining open child tasks, yoke closes the epic and exits.
   - Runs bd update <issue> --status in_progress.
   - Removes yoke review-queue label if present.
+  - Records the claimant: assigns YOKE_ASSIGNEE when set, otherwise labels yoke:claimed-by=<host>.
+  - With YOKE_LEASE_TTL, also takes a yoke:lease=<holder>@<expiry> lease; a live lease held by
+    another daemon is refused unless --steal is given.
+  - Issues assigned to or claimed by someone else are skipped when picking, and refused when
+    named unless --steal is given.
   - Ensures worktree .yoke/worktrees/<issue> is attached to branch yoke/<issue>.
 
 Inputs:
   issue-id    Optional. Explicit issue id (example uses prefix from YOKE_BD_PREFIX).
 
-Options:
-  --improvement-passes N   Limit epic improvement passes (0-5, default 5; 0 skips).
-
+` + formatCommandOptions("claim") + `
 Examples:
   yoke claim
   yoke claim bd-a1b2
   yoke claim bd-a1b2 --improvement-passes 2
+  yoke claim --policy priority,smallest
+  yoke claim --label backend --type bug
+  yoke claim yk-12 --steal
 
 Side effects:
   - bd status transition to in_progress
@@ -3674,12 +22452,20 @@ Side effects:
 func printSubmitUsage() {
 	fmt.Print(`Usage:
   yoke submit [<prefix>-issue-id] --done "..." --remaining "..." [options]
+  yoke submit [<prefix>-issue-id] --auto-summary [options]
 
 Purpose:
   Handoff implementation from writer to reviewer with explicit task state updates.
 
 Behavior:
-  1) Runs checks (default: .yoke/checks.sh).
+  0) With --auto-summary, the writer agent drafts the handoff fields from the branch
+     diff and commits; at a terminal the draft is shown for confirmation.
+     With --commit (or YOKE_SUBMIT_COMMIT=true), stages and commits outstanding changes.
+     With --sync-base (or YOKE_SYNC_BASE=true), fetches the PR base branch and rebases
+     or merges it (YOKE_SYNC_STRATEGY) into the issue branch; aborts on conflicts
+     with a list of the conflicting files.
+  1) Runs checks (default: .yoke/checks.sh, or YOKE_CHECKS in parallel). Checks that
+     already passed for the same working tree are skipped unless --force-checks.
   2) Writes a handoff comment to the bd issue.
   3) Pushes branch unless --no-push.
   4) Creates or reuses PRs unless --no-pr:
@@ -3688,23 +22474,18 @@ Behavior:
      - Standalone task/epic PRs target YOKE_BASE_BRANCH.
   5) Moves issue into review queue (status blocked + label yoke:in_review).
   6) Posts writer handoff summary comment to the branch PR.
+  With --amend, the issue must already be in the review queue: steps 2 and 6 edit
+  the previous handoff comments in place, and step 5 is skipped.
 
 Inputs:
   issue-id    Optional. If omitted, inferred from current branch name.
 
-Options:
-  --done TEXT          Required. What is complete now.
-  --remaining TEXT     Required. What remains.
-  --decision TEXT      Optional. Key decision made.
-  --uncertain TEXT     Optional. Open uncertainty.
-  --checks CMD         Optional. Override check command/script.
-  --no-push            Do not push branch.
-  --no-pr              Do not create or update PR.
-  --no-pr-comment      Do not post writer handoff comment to PR.
-
+` + formatCommandOptions("submit") + `
 Examples:
   yoke submit bd-a1b2 --done "Added auth flow" --remaining "Add tests"
   yoke submit --done "Refactor complete" --remaining "None" --no-pr
+  yoke submit bd-a1b2 --auto-summary --commit
+  yoke submit bd-a1b2 --amend --done "Addressed lint" --remaining "None"
 `)
 }
 
@@ -3716,27 +22497,578 @@ Purpose:
   Execute reviewer step and finalize review outcome for a bd issue.
 
 Behavior:
-  - If issue id omitted, selects first issue in review queue (blocked + yoke:in_review).
+  - If issue id omitted, selects first issue in review queue (blocked + yoke:in_review),
+    skipping issues labeled yoke:awaiting-approval or yoke:needs-human.
   - Optional reviewer automation can run before final action.
-  - Reviewer automation receives ISSUE_ID, ROOT_DIR, BD_PREFIX, and YOKE_ROLE=reviewer.
+  - --security first runs a security pass (injection, secrets, authz, dependency
+    risks) with .yoke/prompts/security-review.md or the built-in prompt, posts a
+    Security Review PR comment, and labels the issue yoke:security-blocked while
+    critical/high findings stand; approval is refused until a clean pass.
+  - Reviewer automation receives ISSUE_ID, ROOT_DIR, BD_PREFIX, YOKE_ROLE=reviewer,
+    and YOKE_REVIEW_VERDICT_SCHEMA (the JSON verdict format to ask the agent for).
+  - It also receives the change under review: YOKE_REVIEW_DIFF holds git diff
+    <base>...HEAD (with --stat first, YOKE_REVIEW_DIFF_EXCLUDE paths left out,
+    capped at YOKE_REVIEW_DIFF_MAX_BYTES), YOKE_REVIEW_DIFF_FILE a copy on disk,
+    and YOKE_REVIEW_BASE the base it was taken against.
+  - Without --approve/--reject, a verdict the agent printed ({"decision": "approve"
+    or "reject", "summary", "findings" tagged blocker/major/minor/nit, "follow_ups"})
+    decides, unless the agent already moved the issue out of review. The daemon
+    applies verdicts the same way.
+  - A verdict is posted to the PR as a findings list; on approve, minor/nit findings
+    and follow_ups are filed as new tasks. A reject records the summary and
+    blocker/major findings as the reason.
   - Approve requires an open PR on the issue branch, marks draft PR ready, and closes the issue.
+  - Automated approvals (agent verdicts, or runs under YOKE_ROLE) must pass
+    YOKE_APPROVAL_POLICY, and are never applied to issues whose label or type is
+    listed in YOKE_REQUIRE_HUMAN_FOR; otherwise the issue is labeled
+    yoke:awaiting-approval and stays in review until a person approves or rejects it.
   - For epic child tasks, approve fast-forwards epic branch yoke/<epic-id> to the task branch and ensures epic PR.
   - Reject adds a rejection note and returns work to writer path (in_progress, removes yoke:in_review).
+    After YOKE_MAX_REJECTIONS rejections the issue is labeled yoke:needs-human and
+    automation skips it until a person removes the label.
   - Approve/reject/note actions post reviewer update comments to the branch PR.
+  - Approve/reject record the reviewed commit SHA in bd and PR comments.
+  - If commits land on an approved PR before merge, the approval is invalidated and the
+    issue returns to the review queue (checked by yoke review and each daemon iteration).
 
 Inputs:
   issue-id    Optional. Explicit issue id.
 
-Options:
-  --agent              Run YOKE_REVIEW_CMD before final action.
-  --note TEXT          Add reviewer note to bd issue.
-  --approve            Approve issue (bd close).
-  --reject TEXT        Reject issue with reason.
-  --no-pr-comment      Do not post reviewer update comment to PR.
-
+` + formatCommandOptions("review") + `
 Examples:
   yoke review bd-a1b2 --agent --approve
+  yoke review bd-a1b2 --security --agent
   yoke review bd-a1b2 --reject "Missing edge-case test coverage"
   yoke review --note "Verified behavior locally"
 `)
 }
+
+func printEpicUsage() {
+	fmt.Print(`Usage:
+  yoke epic archive <epic-id> [options]
+  yoke epic retro <epic-id>
+
+Purpose:
+  Preserve an epic's decision record outside the bd database, and learn from it.
+
+Behavior:
+  - archive: exports the epic, every descendant issue, all bd comments, linked
+    PR URLs, and saved epic improvement reports.
+  - archive: writes archive.json and archive.md to docs/epics/<epic-id>/ by default.
+  - retro: asks the reviewer agent what went well, what to improve, and which
+    conventions to change, from each child's cycle time and rejection reasons.
+  - retro: comments the report on the epic and saves it as retrospective.md
+    under .yoke/epic-improvement-reports/<epic-id>/ (so archives include it).
+  - With YOKE_EPIC_ARCHIVE=true, yoke claim archives an epic when it closes the
+    epic, after a retrospective when YOKE_EPIC_RETRO=true.
+
+` + formatCommandOptions("epic") + `
+Examples:
+  yoke epic archive bd-a1b2
+  yoke epic archive bd-a1b2 --output archive/bd-a1b2
+  yoke epic retro bd-a1b2
+`)
+}
+
+func printImproveUsage() {
+	fmt.Print(`Usage:
+  yoke improve <epic-id> [options]
+
+Purpose:
+  Refine an epic with the improvement cycle before (or between) implementation work.
+
+Behavior:
+  - Runs the same cycle as yoke claim on an epic: one agent pass per YOKE_EPIC_PASS_PLAN
+    entry, then a reviewer summary commented on the epic. No task is claimed and no
+    worktree is created.
+  - Skips an epic already labeled yoke:epic-improvement-complete unless clarification
+    tasks have new comments; --force runs the cycle again from the first pass, for
+    example after requirements change.
+  - An interrupted cycle resumes at its first incomplete pass.
+  - Reports are saved under .yoke/epic-improvement-reports/<epic-id>/.
+
+` + formatCommandOptions("improve") + `
+Examples:
+  yoke improve bd-a1b2
+  yoke improve bd-a1b2 --passes 2
+  yoke improve bd-a1b2 --force
+`)
+}
+
+func printAbandonUsage() {
+	fmt.Print(`Usage:
+  yoke abandon [<prefix>-issue-id] [options]
+
+Purpose:
+  Release a claimed issue so it can be claimed again later.
+
+Behavior:
+  - If issue id omitted, infers it from the current branch, then the daemon focus.
+  - Requires the issue to be in_progress or in review (blocked + yoke:in_review).
+  - Runs bd update <issue> --status open --remove-label yoke:in_review.
+  - Posts a bd comment recording the previous status, reason, and branch handling.
+  - Clears the daemon focus when it points at the abandoned issue.
+  - Keeps branch yoke/<issue> and its worktree unless asked to remove them.
+
+` + formatCommandOptions("abandon") + `
+Examples:
+  yoke abandon bd-a1b2 --reason "Superseded by bd-c3d4"
+  yoke abandon --delete-branch
+`)
+}
+
+func printResumeUsage() {
+	fmt.Print(`Usage:
+  yoke resume [<prefix>-issue-id]
+
+Purpose:
+  Re-enter work on an issue after a break, a rejection, or a lost worktree.
+
+Behavior:
+  - If issue id omitted, infers it from the current branch, then the daemon focus.
+  - Accepts in_progress issues; issues still in the review queue are moved back to in_progress
+    (refused while the issue is locked for review).
+  - Checks out or recreates worktree .yoke/worktrees/<issue> on branch yoke/<issue>.
+  - Sets the daemon focus to the issue.
+  - Prints the issue, branch, worktree, and writer context: the latest writer handoff plus
+    reviewer rejections and notes since then.
+
+Examples:
+  yoke resume
+  yoke resume bd-a1b2
+`)
+}
+
+func printQueueUsage() {
+	fmt.Print(`Usage:
+  yoke queue [--json]
+
+Purpose:
+  List every issue in the review queue so reviewers can triage.
+
+Behavior:
+  - Lists issues with status blocked + label yoke:in_review, oldest first.
+  - Shows issue id, age in queue, open PR number, branch yoke/<issue>, and title.
+  - Age is measured from the latest writer handoff comment (or the issue's last update).
+
+` + formatCommandOptions("queue") + `
+Examples:
+  yoke queue
+  yoke queue --json
+`)
+}
+
+func printTUIUsage() {
+	fmt.Print(`Usage:
+  yoke tui [options]
+
+Purpose:
+  Watch and drive the workflow from one terminal instead of juggling
+  yoke daemon, yoke queue, and watch bd list.
+
+Behavior:
+  - Shows ready backlog issues yoke may claim, issues in progress, the review queue
+    (oldest first), the latest entries of .yoke/audit.jsonl, and the tail of a live
+    agent log (the selected issue's, else the latest running agent's).
+  - Keys: up/down or j/k select, c claims a backlog issue, a approves and r rejects
+    (asking for a reason) a queued issue, tab cycles the followed agent output,
+    g reloads, q quits.
+  - Actions run yoke claim / yoke review in the normal terminal; press enter to return.
+  - Needs a terminal; --once prints a single frame instead, for scripts and quick looks.
+
+` + formatCommandOptions("tui") + `
+Examples:
+  yoke tui
+  yoke tui --interval 10s
+  COLUMNS=120 yoke tui --once
+`)
+}
+
+func printClarifyUsage() {
+	fmt.Print(`Usage:
+  yoke clarify [--json] [--sync-slack]
+
+Purpose:
+  Show the questions agents are waiting on a person to answer.
+
+Behavior:
+  - Lists every open, in-progress, or blocked task titled "Clarification needed: ...",
+    grouped by epic.
+  - Shows the task id, its epic, whether it has comments yet (answered) or is waiting,
+    and the question.
+  - Answer with yoke answer <task-id> "...", or in the task's Slack thread.
+  - With --sync-slack, first posts new unanswered tasks to Slack and copies thread
+    replies to bd (SLACK_BOT_TOKEN + YOKE_SLACK_CHANNEL, or SLACK_WEBHOOK_URL to post only).
+
+` + formatCommandOptions("clarify") + `
+Examples:
+  yoke clarify
+  yoke clarify --json
+  yoke clarify --sync-slack
+`)
+}
+
+func printAnswerUsage() {
+	fmt.Print(`Usage:
+  yoke answer <clarification-task-id> ["answer" | -]
+
+Purpose:
+  Answer a clarification task without raw bd commands.
+
+Behavior:
+  - Posts the answer as a comment on the task; with - or no answer, reads it from stdin.
+  - Refuses tasks that are closed or not titled "Clarification needed: ...".
+  - The epic's next improvement cycle (yoke improve or yoke claim on the epic) feeds the
+    answer to the agents, and yoke claim then closes the task as clarified-by-comment.
+
+Examples:
+  yoke answer bd-a1b2.3 "use stdin when piped"
+  yoke answer bd-a1b2.3 < answer.md
+`)
+}
+
+func printListUsage() {
+	fmt.Print(`Usage:
+  yoke list [options]
+
+Purpose:
+  One overview of yoke-managed issues, joining bd state with local branch, worktree, and PR state.
+
+Behavior:
+  - Includes in_progress and in-review issues, plus any issue with a yoke/<issue> branch or open PR.
+  - STATE column:
+      claimed     bd in_progress
+      in_review   blocked + yoke:in_review
+      approved    closed in bd with the PR still open
+      orphaned    branch/worktree/PR left behind for an open, closed, or unknown issue
+  - Shows bd status, branch, worktree path, open PR number, and title.
+
+` + formatCommandOptions("list") + `
+Examples:
+  yoke list
+  yoke list --status orphaned
+  yoke list --label yoke:budget-exceeded
+`)
+}
+
+func printMergeUsage() {
+	fmt.Print(`Usage:
+  yoke merge [<prefix>-issue-id] [options]
+
+Purpose:
+  Land approved work after yoke review --approve.
+
+Behavior:
+  - If issue id omitted, infers it from the current branch.
+  - Requires the bd issue to be closed and its PR open and ready (not draft).
+  - Refuses when the PR head moved past the approved commit, or GitHub reports
+    changes requested / review required.
+  - Requires PR checks to be green (--wait polls until they are).
+  - Merges with gh using YOKE_MERGE_STRATEGY (default squash).
+  - Deletes the remote branch, issue worktree, and local branch yoke/<issue>.
+  - Comments on the bd issue with the PR number, strategy, and merge SHA.
+
+` + formatCommandOptions("merge") + `
+Examples:
+  yoke merge bd-a1b2
+  yoke merge --strategy rebase --wait
+`)
+}
+
+func printServeUsage() {
+	fmt.Print(`Usage:
+  yoke serve [options]
+
+Purpose:
+  Let editor extensions show yoke state and trigger submit/review, and let the
+  team watch daemon progress in a browser.
+
+Behavior:
+  - Listens on a loopback address only; every request needs
+    "Authorization: Bearer <token>".
+  - Writes {addr, token, pid} to .yoke/api.json (mode 0600) while running and
+    removes it on exit.
+  - GET /v1/status, GET /v1/issue[?id=ISSUE], POST /v1/submit, POST /v1/review.
+  - GET /v1/pipelines lists agent runs with live output; GET /v1/stream?pipeline=ID
+    is a websocket streaming that run's output as the agent writes it.
+  - GET /v1/queue, GET /v1/epics, GET /v1/epic?id=EPIC (tree and pass reports), and
+    GET /v1/audit[?issue=ID&limit=N] return the review queue, epics, and audit history.
+    See docs/http-api.md for the request and response contract.
+  - GET /#token=TOKEN is an HTML dashboard over those routes; the token stays in
+    the URL fragment, so it is never sent to the server.
+  - --dashboard-addr serves the dashboard and the read routes, but not submit,
+    review, /v1/audit, or /v1/stream, on a second address that may face the
+    network, behind its own token. The startup line prints the link to share.
+  - submit and review run the same yoke commands, one at a time.
+
+` + formatCommandOptions("serve") + `
+Examples:
+  yoke serve
+  YOKE_API_TOKEN=secret yoke serve --addr 127.0.0.1:9000
+  yoke serve --dashboard-addr 0.0.0.0:7718
+`)
+}
+
+func printListenUsage() {
+	fmt.Print(`Usage:
+  yoke listen [options]
+
+Purpose:
+  Bring human activity on GitHub back into bd, so a reviewer can work from the
+  pull request page instead of the yoke CLI.
+
+Behavior:
+  - Accepts GitHub webhook deliveries on any path and verifies each one against
+    X-Hub-Signature-256; a secret is required.
+  - pull_request_review submitted on a yoke/<issue> branch:
+      changes_requested -> yoke review <issue> --reject "<review body>"
+      approved          -> yoke review <issue> --approve (body kept as a note)
+      commented         -> yoke review <issue> --note "<review body>"
+  - issue_comment created on a yoke pull request -> yoke review <issue> --note.
+  - check_suite completed with a failure on a yoke/<issue> branch -> the issue
+    goes back to in_progress, as yoke daemon does when required checks fail.
+  - Reviews and comments count only from OWNER, MEMBER, or COLLABORATOR
+    authors; an approval applies only to the commit the branch is still at.
+  - Approve, reject, and check failures apply only while the issue is in_review.
+    Comments from bots and comments yoke posted itself are ignored.
+  - Actions run with YOKE_ROLE=webhook, so approvals go through
+    YOKE_REQUIRE_HUMAN_FOR and YOKE_APPROVAL_POLICY.
+  - Answers GitHub at once and applies actions one at a time in the background.
+  - Configure the webhook with content type application/json and the events
+    "Pull request reviews", "Issue comments", and "Check suites".
+
+` + formatCommandOptions("listen") + `
+Examples:
+  YOKE_WEBHOOK_SECRET=secret yoke listen --port 7719
+`)
+}
+
+func printCompletionUsage() {
+	fmt.Print(`Usage:
+  yoke completion <bash|zsh|fish>
+
+Purpose:
+  Tab-complete yoke commands, flags, flag values, and issue ids.
+
+Behavior:
+  - Prints a completion script generated from yoke's command and flag help, so it
+    always matches the installed binary; regenerate it after upgrading.
+  - Commands that take an issue id complete open, in-progress, and in-review ids
+    (with titles in zsh and fish) by running yoke completion issues, which lists
+    them from the configured issue backend and prints nothing outside a yoke repo.
+
+Examples:
+  source <(yoke completion bash)
+  yoke completion zsh > "${fpath[1]}/_yoke"
+  yoke completion fish > ~/.config/fish/completions/yoke.fish
+`)
+}
+
+func printAuditReviewUsage() {
+	fmt.Print(`Usage:
+  yoke audit-review [options]
+
+Purpose:
+  Batch audit of work that landed without a full agent review.
+
+Behavior:
+  - Lists yoke/* PRs merged within --since.
+  - Audits every PR whose issue carries yoke:audit-pending (fast-tracked by
+    YOKE_REVIEW_SAMPLE_RATE), plus up to --sample other PRs not yet audited.
+  - Each PR diff is re-reviewed by the audit agent and model.
+  - Every finding is filed as a bd bug that references the PR and issue.
+  - Audited issues get a bd comment with the result and the label yoke:audited;
+    yoke:audit-pending is removed.
+  - Agent output is kept under .yoke/logs/<issue>/.
+
+` + formatCommandOptions("audit-review") + `
+Examples:
+  yoke audit-review --dry-run
+  yoke audit-review --since 7d --model opus
+  yoke audit-review --since 30d --sample 0 --json
+`)
+}
+
+func printCleanupUsage() {
+	fmt.Print(`Usage:
+  yoke cleanup [options]
+
+Purpose:
+  Remove leftovers of finished work.
+
+Behavior:
+  - Finds local yoke/* branches whose bd issue is closed and whose PR was merged
+    (or whose branch is already contained in YOKE_BASE_BRANCH).
+  - Removes each branch's worktree, the local branch, and the remote branch on origin.
+  - With --reports-older-than, removes .yoke/epic-improvement-reports/<epic-id>/
+    directories not modified within the retention window.
+
+` + formatCommandOptions("cleanup") + `
+Examples:
+  yoke cleanup --dry-run
+  yoke cleanup --reports-older-than 30d
+`)
+}
+
+func printLogUsage() {
+	fmt.Print(`Usage:
+  yoke log [<prefix>-issue-id] [options]
+
+Purpose:
+  Answer "who changed this and when" from the append-only audit log.
+
+Behavior:
+  - yoke appends every state-changing action to .yoke/audit.jsonl in the main checkout:
+    bd update/close/create/comments add, git push/merge/worktree add|remove/branch delete,
+    gh pr create/comment/ready/merge/edit/review/close, and agent invocations.
+  - Each entry records timestamp, issue, action, arguments, actor, and result.
+  - With an issue id, only that issue's entries are shown.
+
+` + formatCommandOptions("log") + `
+Examples:
+  yoke log bd-a1b2
+  yoke log --limit 20
+`)
+}
+
+func printOnboardUsage() {
+	fmt.Print(`Usage:
+  yoke onboard [options]
+
+Purpose:
+  Inspect the repository and propose a tailored .yoke setup for review.
+
+Behavior:
+  - Detects build systems (go, npm, cargo, python, make), check/test commands,
+    CI configuration, and contribution guidelines (CONTRIBUTING.md, AGENTS.md, ...).
+  - Generates .yoke/checks.sh, .yoke/conventions.md, .yoke/policy.md, and a
+    .yoke/config.sh with suggested writer/reviewer commands for the configured agents
+    (existing commands are kept).
+  - Commits the files on branch yoke-onboard and opens a draft PR to YOKE_BASE_BRANCH.
+
+` + formatCommandOptions("onboard") + `
+Examples:
+  yoke onboard --dry-run
+  yoke onboard
+`)
+}
+
+func printMetricsUsage() {
+	fmt.Print(`Usage:
+  yoke metrics [<prefix>-issue-id] [--json]
+
+Purpose:
+  Cycle-time statistics per issue and in aggregate.
+
+Behavior:
+  - Reads .yoke/audit.jsonl and bd created/closed timestamps.
+  - Per issue: claim -> first submit, last submit -> approve, lead time (bd created -> closed),
+    reject loops, and daemon role-command runs.
+  - Aggregate: medians of the durations (issues without the event are skipped) and totals.
+
+` + formatCommandOptions("metrics") + `
+Examples:
+  yoke metrics
+  yoke metrics bd-a1b2 --json
+`)
+}
+
+func printNewUsage() {
+	fmt.Print(`Usage:
+  yoke new <template> <directory> [options]
+
+Purpose:
+  Create a greenfield agent-driven project ready for yoke daemon in one command.
+
+Behavior:
+  - Resolves <template> as "blank" (empty project), a git URL (shallow clone),
+    a local directory, or a named directory under YOKE_TEMPLATES_DIR
+    (default: <user config dir>/yoke/templates). Template git history is dropped.
+  - Runs git init on YOKE_BASE_BRANCH (from the template's .yoke/config.sh, default main).
+  - Writes .yoke/config.sh with detected agents and suggested writer/reviewer
+    commands (template values are kept) and a .yoke/checks.sh if the template has none.
+  - Runs bd init and creates a starter epic, then makes the initial commit.
+
+` + formatCommandOptions("new") + `
+Examples:
+  yoke new blank my-service
+  yoke new go-service my-service --epic "Ship the ingest API"
+  yoke new https://github.com/acme/yoke-template.git my-app
+`)
+}
+
+func printSyncBaseUsage() {
+	fmt.Print(`Usage:
+  yoke sync-base [<prefix>-issue-id] [options]
+
+Purpose:
+  Bring the issue's PR base branch into its branch ahead of review.
+
+Behavior:
+  - Fetches the PR base (stack parent, epic branch, or YOKE_BASE_BRANCH) and rebases
+    or merges it into the issue branch (YOKE_SYNC_STRATEGY, default rebase).
+  - Without --resolve-with-agent, conflicts abort the sync with a list of conflicting files.
+  - With --resolve-with-agent, each conflicted step is handed to the writer agent with
+    the conflict hunks and issue context; yoke verifies no conflict markers remain,
+    stages the files, and continues.
+  - Re-runs checks after the branch changes. Auto-resolved files are listed in the
+    next yoke submit handoff comment.
+
+` + formatCommandOptions("sync-base") + `
+Examples:
+  yoke sync-base
+  yoke sync-base bd-a1b2 --resolve-with-agent
+`)
+}
+
+func printConfigUsage() {
+	fmt.Print(`Usage:
+  yoke config lint [options]
+
+Purpose:
+  Catch daemon command misconfigurations before they waste agent runs.
+
+Behavior:
+  - Checks YOKE_WRITER_CMD and YOKE_REVIEW_CMD from .yoke/config.sh. Runs nothing.
+  - Errors: unset commands, unterminated quotes, yoke variables inside single
+    quotes, and references to yoke subcommands that do not exist.
+  - Warnings: unquoted $ISSUE_ID/$ROOT_DIR/..., variables neither yoke nor the
+    environment sets, no $ISSUE_ID reference, and a writer without yoke submit
+    or a reviewer without yoke review --approve/--reject.
+  - Prints each command expanded with the variables the daemon sets.
+  - Exits non-zero when any error is found.
+
+` + formatCommandOptions("config") + `
+Examples:
+  yoke config lint
+  yoke config lint --issue bd-a1b2 --json
+`)
+}
+
+func printEnvUsage() {
+	fmt.Print(`Usage:
+  yoke env <writer|reviewer|check> [<prefix>-issue-id] [--json]
+
+Purpose:
+  Show exactly what a role's command would see, to debug agent commands that
+  behave differently under yoke daemon than in a shell.
+
+Behavior:
+  - writer/reviewer: the daemon command (YOKE_WRITER_CMD/YOKE_REVIEW_CMD), the issue
+    worktree it runs in, and the inherited environment plus ISSUE_ID, ROOT_DIR,
+    YOKE_MAIN_ROOT, BD_PREFIX, YOKE_ROLE, and PATH with the repo bin/ directories.
+    Reviewers also get YOKE_REVIEW_VERDICT_SCHEMA and the review diff variables,
+    built the same way yoke daemon builds them.
+  - check: YOKE_CHECK_CMD (or the YOKE_CHECKS commands) as yoke submit runs it, with the inherited environment.
+  - Duplicate variables are collapsed to the value the command receives.
+  - Values of variables that look like secrets (TOKEN, SECRET, PASSWORD, API_KEY,
+    WEBHOOK, ...) and credentials in URLs are printed as <redacted>.
+  - Infers the issue from the current branch when omitted. Runs no agent or check.
+
+` + formatCommandOptions("env") + `
+Examples:
+  yoke env writer bd-a1b2
+  yoke env reviewer bd-a1b2 --json
+  yoke env check
+`)
+}